@@ -0,0 +1,6 @@
+// Package pogov1 holds the gRPC API of the pogo OCR server, generated from
+// ocr.proto. Regenerate with `just gen` (requires protoc, protoc-gen-go and
+// protoc-gen-go-grpc).
+package pogov1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative pogo/v1/ocr.proto
@@ -0,0 +1,2152 @@
+// The pogo OCR service, served by `pogo serve --grpc-port`.
+//
+// Results mirror the JSON results of the HTTP API (pipeline.OCRImageResult
+// and pipeline.OCRPDFResult). Clients authenticate like HTTP clients when
+// API keys are configured: with an "x-api-key" or
+// "authorization: Bearer <key>" metadata entry.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: pogo/v1/ocr.proto
+
+package pogov1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Options selects models and features for a request. Empty fields use the
+// server defaults.
+type Options struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Server-side model profile, as listed by GET /models; the other options
+	// refine it.
+	Profile string `protobuf:"bytes,10,opt,name=profile,proto3" json:"profile,omitempty"`
+	// Recognizer language, e.g. "en".
+	Language string `protobuf:"bytes,1,opt,name=language,proto3" json:"language,omitempty"`
+	// Language codes whose dictionaries are merged for recognition.
+	DictLangs []string `protobuf:"bytes,2,rep,name=dict_langs,json=dictLangs,proto3" json:"dict_langs,omitempty"`
+	// Dictionary file path on the server; only accepted when the server runs
+	// with --allow-model-paths.
+	Dict string `protobuf:"bytes,3,opt,name=dict,proto3" json:"dict,omitempty"`
+	// Detection model path on the server (see dict).
+	DetModel string `protobuf:"bytes,4,opt,name=det_model,json=detModel,proto3" json:"det_model,omitempty"`
+	// Recognition model path on the server (see dict).
+	RecModel string `protobuf:"bytes,5,opt,name=rec_model,json=recModel,proto3" json:"rec_model,omitempty"`
+	// Enables barcode detection.
+	Barcodes bool `protobuf:"varint,6,opt,name=barcodes,proto3" json:"barcodes,omitempty"`
+	// Comma-separated barcode types, e.g. "qr,ean13".
+	BarcodeTypes string `protobuf:"bytes,7,opt,name=barcode_types,json=barcodeTypes,proto3" json:"barcode_types,omitempty"`
+	// Minimum expected barcode size in pixels.
+	BarcodeMinSize int32 `protobuf:"varint,8,opt,name=barcode_min_size,json=barcodeMinSize,proto3" json:"barcode_min_size,omitempty"`
+	// Target DPI for barcode decoding in PDFs.
+	BarcodeDpi    int32 `protobuf:"varint,9,opt,name=barcode_dpi,json=barcodeDpi,proto3" json:"barcode_dpi,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Options) Reset() {
+	*x = Options{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Options) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Options) ProtoMessage() {}
+
+func (x *Options) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Options.ProtoReflect.Descriptor instead.
+func (*Options) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Options) GetProfile() string {
+	if x != nil {
+		return x.Profile
+	}
+	return ""
+}
+
+func (x *Options) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
+func (x *Options) GetDictLangs() []string {
+	if x != nil {
+		return x.DictLangs
+	}
+	return nil
+}
+
+func (x *Options) GetDict() string {
+	if x != nil {
+		return x.Dict
+	}
+	return ""
+}
+
+func (x *Options) GetDetModel() string {
+	if x != nil {
+		return x.DetModel
+	}
+	return ""
+}
+
+func (x *Options) GetRecModel() string {
+	if x != nil {
+		return x.RecModel
+	}
+	return ""
+}
+
+func (x *Options) GetBarcodes() bool {
+	if x != nil {
+		return x.Barcodes
+	}
+	return false
+}
+
+func (x *Options) GetBarcodeTypes() string {
+	if x != nil {
+		return x.BarcodeTypes
+	}
+	return ""
+}
+
+func (x *Options) GetBarcodeMinSize() int32 {
+	if x != nil {
+		return x.BarcodeMinSize
+	}
+	return 0
+}
+
+func (x *Options) GetBarcodeDpi() int32 {
+	if x != nil {
+		return x.BarcodeDpi
+	}
+	return 0
+}
+
+// PDFOptions controls PDF processing.
+type PDFOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Page range, e.g. "1-3,5"; all pages if empty.
+	Pages string `protobuf:"bytes,1,opt,name=pages,proto3" json:"pages,omitempty"`
+	// Passwords of encrypted PDFs.
+	UserPassword  string `protobuf:"bytes,2,opt,name=user_password,json=userPassword,proto3" json:"user_password,omitempty"`
+	OwnerPassword string `protobuf:"bytes,3,opt,name=owner_password,json=ownerPassword,proto3" json:"owner_password,omitempty"`
+	// Uses embedded vector text where its quality suffices instead of OCR.
+	VectorText bool `protobuf:"varint,4,opt,name=vector_text,json=vectorText,proto3" json:"vector_text,omitempty"`
+	// Combines vector text and OCR.
+	Hybrid bool `protobuf:"varint,5,opt,name=hybrid,proto3" json:"hybrid,omitempty"`
+	// Minimum quality of vector text, in (0, 1]; 0.7 if unset.
+	QualityThreshold float64 `protobuf:"fixed64,6,opt,name=quality_threshold,json=qualityThreshold,proto3" json:"quality_threshold,omitempty"`
+	// Page processing workers; the server default if 0.
+	Workers       int32 `protobuf:"varint,7,opt,name=workers,proto3" json:"workers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PDFOptions) Reset() {
+	*x = PDFOptions{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PDFOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PDFOptions) ProtoMessage() {}
+
+func (x *PDFOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PDFOptions.ProtoReflect.Descriptor instead.
+func (*PDFOptions) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PDFOptions) GetPages() string {
+	if x != nil {
+		return x.Pages
+	}
+	return ""
+}
+
+func (x *PDFOptions) GetUserPassword() string {
+	if x != nil {
+		return x.UserPassword
+	}
+	return ""
+}
+
+func (x *PDFOptions) GetOwnerPassword() string {
+	if x != nil {
+		return x.OwnerPassword
+	}
+	return ""
+}
+
+func (x *PDFOptions) GetVectorText() bool {
+	if x != nil {
+		return x.VectorText
+	}
+	return false
+}
+
+func (x *PDFOptions) GetHybrid() bool {
+	if x != nil {
+		return x.Hybrid
+	}
+	return false
+}
+
+func (x *PDFOptions) GetQualityThreshold() float64 {
+	if x != nil {
+		return x.QualityThreshold
+	}
+	return 0
+}
+
+func (x *PDFOptions) GetWorkers() int32 {
+	if x != nil {
+		return x.Workers
+	}
+	return 0
+}
+
+type RecognizeImageRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Encoded image (PNG, JPEG, ...).
+	Image         []byte   `protobuf:"bytes,1,opt,name=image,proto3" json:"image,omitempty"`
+	Options       *Options `protobuf:"bytes,2,opt,name=options,proto3" json:"options,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecognizeImageRequest) Reset() {
+	*x = RecognizeImageRequest{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecognizeImageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecognizeImageRequest) ProtoMessage() {}
+
+func (x *RecognizeImageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecognizeImageRequest.ProtoReflect.Descriptor instead.
+func (*RecognizeImageRequest) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RecognizeImageRequest) GetImage() []byte {
+	if x != nil {
+		return x.Image
+	}
+	return nil
+}
+
+func (x *RecognizeImageRequest) GetOptions() *Options {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type RecognizeImageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        *ImageResult           `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecognizeImageResponse) Reset() {
+	*x = RecognizeImageResponse{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecognizeImageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecognizeImageResponse) ProtoMessage() {}
+
+func (x *RecognizeImageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecognizeImageResponse.ProtoReflect.Descriptor instead.
+func (*RecognizeImageResponse) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RecognizeImageResponse) GetResult() *ImageResult {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+type RecognizePDFRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Name of the document, reported in the summary.
+	Filename      string      `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Pdf           []byte      `protobuf:"bytes,2,opt,name=pdf,proto3" json:"pdf,omitempty"`
+	Options       *Options    `protobuf:"bytes,3,opt,name=options,proto3" json:"options,omitempty"`
+	PdfOptions    *PDFOptions `protobuf:"bytes,4,opt,name=pdf_options,json=pdfOptions,proto3" json:"pdf_options,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecognizePDFRequest) Reset() {
+	*x = RecognizePDFRequest{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecognizePDFRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecognizePDFRequest) ProtoMessage() {}
+
+func (x *RecognizePDFRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecognizePDFRequest.ProtoReflect.Descriptor instead.
+func (*RecognizePDFRequest) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RecognizePDFRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *RecognizePDFRequest) GetPdf() []byte {
+	if x != nil {
+		return x.Pdf
+	}
+	return nil
+}
+
+func (x *RecognizePDFRequest) GetOptions() *Options {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *RecognizePDFRequest) GetPdfOptions() *PDFOptions {
+	if x != nil {
+		return x.PdfOptions
+	}
+	return nil
+}
+
+// RecognizePDFResponse is a recognized page or, as the last message, the
+// document summary.
+type RecognizePDFResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*RecognizePDFResponse_Page
+	//	*RecognizePDFResponse_Summary
+	Event         isRecognizePDFResponse_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecognizePDFResponse) Reset() {
+	*x = RecognizePDFResponse{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecognizePDFResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecognizePDFResponse) ProtoMessage() {}
+
+func (x *RecognizePDFResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecognizePDFResponse.ProtoReflect.Descriptor instead.
+func (*RecognizePDFResponse) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *RecognizePDFResponse) GetEvent() isRecognizePDFResponse_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *RecognizePDFResponse) GetPage() *PDFPageResult {
+	if x != nil {
+		if x, ok := x.Event.(*RecognizePDFResponse_Page); ok {
+			return x.Page
+		}
+	}
+	return nil
+}
+
+func (x *RecognizePDFResponse) GetSummary() *PDFSummary {
+	if x != nil {
+		if x, ok := x.Event.(*RecognizePDFResponse_Summary); ok {
+			return x.Summary
+		}
+	}
+	return nil
+}
+
+type isRecognizePDFResponse_Event interface {
+	isRecognizePDFResponse_Event()
+}
+
+type RecognizePDFResponse_Page struct {
+	Page *PDFPageResult `protobuf:"bytes,1,opt,name=page,proto3,oneof"`
+}
+
+type RecognizePDFResponse_Summary struct {
+	Summary *PDFSummary `protobuf:"bytes,2,opt,name=summary,proto3,oneof"`
+}
+
+func (*RecognizePDFResponse_Page) isRecognizePDFResponse_Event() {}
+
+func (*RecognizePDFResponse_Summary) isRecognizePDFResponse_Event() {}
+
+// PDFSummary holds the totals of a PDF once all pages are sent.
+type PDFSummary struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Filename     string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	TotalPages   int32                  `protobuf:"varint,2,opt,name=total_pages,json=totalPages,proto3" json:"total_pages,omitempty"`
+	ExtractionNs int64                  `protobuf:"varint,3,opt,name=extraction_ns,json=extractionNs,proto3" json:"extraction_ns,omitempty"`
+	TotalNs      int64                  `protobuf:"varint,4,opt,name=total_ns,json=totalNs,proto3" json:"total_ns,omitempty"`
+	// Set when the processing timeout cut the document short; only the pages
+	// finished before are sent.
+	Truncated     bool `protobuf:"varint,5,opt,name=truncated,proto3" json:"truncated,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PDFSummary) Reset() {
+	*x = PDFSummary{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PDFSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PDFSummary) ProtoMessage() {}
+
+func (x *PDFSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PDFSummary.ProtoReflect.Descriptor instead.
+func (*PDFSummary) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PDFSummary) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *PDFSummary) GetTotalPages() int32 {
+	if x != nil {
+		return x.TotalPages
+	}
+	return 0
+}
+
+func (x *PDFSummary) GetExtractionNs() int64 {
+	if x != nil {
+		return x.ExtractionNs
+	}
+	return 0
+}
+
+func (x *PDFSummary) GetTotalNs() int64 {
+	if x != nil {
+		return x.TotalNs
+	}
+	return 0
+}
+
+func (x *PDFSummary) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+type BatchItem struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Name of the item, echoed in its result.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Types that are valid to be assigned to Content:
+	//
+	//	*BatchItem_Image
+	//	*BatchItem_Pdf
+	Content       isBatchItem_Content `protobuf_oneof:"content"`
+	Options       *Options            `protobuf:"bytes,4,opt,name=options,proto3" json:"options,omitempty"`
+	PdfOptions    *PDFOptions         `protobuf:"bytes,5,opt,name=pdf_options,json=pdfOptions,proto3" json:"pdf_options,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchItem) Reset() {
+	*x = BatchItem{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchItem) ProtoMessage() {}
+
+func (x *BatchItem) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchItem.ProtoReflect.Descriptor instead.
+func (*BatchItem) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *BatchItem) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *BatchItem) GetContent() isBatchItem_Content {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+func (x *BatchItem) GetImage() []byte {
+	if x != nil {
+		if x, ok := x.Content.(*BatchItem_Image); ok {
+			return x.Image
+		}
+	}
+	return nil
+}
+
+func (x *BatchItem) GetPdf() []byte {
+	if x != nil {
+		if x, ok := x.Content.(*BatchItem_Pdf); ok {
+			return x.Pdf
+		}
+	}
+	return nil
+}
+
+func (x *BatchItem) GetOptions() *Options {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *BatchItem) GetPdfOptions() *PDFOptions {
+	if x != nil {
+		return x.PdfOptions
+	}
+	return nil
+}
+
+type isBatchItem_Content interface {
+	isBatchItem_Content()
+}
+
+type BatchItem_Image struct {
+	Image []byte `protobuf:"bytes,2,opt,name=image,proto3,oneof"`
+}
+
+type BatchItem_Pdf struct {
+	Pdf []byte `protobuf:"bytes,3,opt,name=pdf,proto3,oneof"`
+}
+
+func (*BatchItem_Image) isBatchItem_Content() {}
+
+func (*BatchItem_Pdf) isBatchItem_Content() {}
+
+type BatchItemResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Position of the item in the request stream, starting at 0.
+	Index   int32  `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
+	Success bool   `protobuf:"varint,3,opt,name=success,proto3" json:"success,omitempty"`
+	Error   string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	// Types that are valid to be assigned to Result:
+	//
+	//	*BatchItemResult_Image
+	//	*BatchItemResult_Pdf
+	Result          isBatchItemResult_Result `protobuf_oneof:"result"`
+	DurationSeconds float64                  `protobuf:"fixed64,7,opt,name=duration_seconds,json=durationSeconds,proto3" json:"duration_seconds,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *BatchItemResult) Reset() {
+	*x = BatchItemResult{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchItemResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchItemResult) ProtoMessage() {}
+
+func (x *BatchItemResult) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchItemResult.ProtoReflect.Descriptor instead.
+func (*BatchItemResult) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *BatchItemResult) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *BatchItemResult) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *BatchItemResult) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *BatchItemResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *BatchItemResult) GetResult() isBatchItemResult_Result {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *BatchItemResult) GetImage() *ImageResult {
+	if x != nil {
+		if x, ok := x.Result.(*BatchItemResult_Image); ok {
+			return x.Image
+		}
+	}
+	return nil
+}
+
+func (x *BatchItemResult) GetPdf() *PDFResult {
+	if x != nil {
+		if x, ok := x.Result.(*BatchItemResult_Pdf); ok {
+			return x.Pdf
+		}
+	}
+	return nil
+}
+
+func (x *BatchItemResult) GetDurationSeconds() float64 {
+	if x != nil {
+		return x.DurationSeconds
+	}
+	return 0
+}
+
+type isBatchItemResult_Result interface {
+	isBatchItemResult_Result()
+}
+
+type BatchItemResult_Image struct {
+	Image *ImageResult `protobuf:"bytes,5,opt,name=image,proto3,oneof"`
+}
+
+type BatchItemResult_Pdf struct {
+	Pdf *PDFResult `protobuf:"bytes,6,opt,name=pdf,proto3,oneof"`
+}
+
+func (*BatchItemResult_Image) isBatchItemResult_Result() {}
+
+func (*BatchItemResult_Pdf) isBatchItemResult_Result() {}
+
+type Point struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	X             float64                `protobuf:"fixed64,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y             float64                `protobuf:"fixed64,2,opt,name=y,proto3" json:"y,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Point) Reset() {
+	*x = Point{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Point) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Point) ProtoMessage() {}
+
+func (x *Point) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Point.ProtoReflect.Descriptor instead.
+func (*Point) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Point) GetX() float64 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *Point) GetY() float64 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+type Box struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	X             float64                `protobuf:"fixed64,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y             float64                `protobuf:"fixed64,2,opt,name=y,proto3" json:"y,omitempty"`
+	W             float64                `protobuf:"fixed64,3,opt,name=w,proto3" json:"w,omitempty"`
+	H             float64                `protobuf:"fixed64,4,opt,name=h,proto3" json:"h,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Box) Reset() {
+	*x = Box{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Box) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Box) ProtoMessage() {}
+
+func (x *Box) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Box.ProtoReflect.Descriptor instead.
+func (*Box) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Box) GetX() float64 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *Box) GetY() float64 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *Box) GetW() float64 {
+	if x != nil {
+		return x.W
+	}
+	return 0
+}
+
+func (x *Box) GetH() float64 {
+	if x != nil {
+		return x.H
+	}
+	return 0
+}
+
+// PageCoords locates a region on its PDF page, in PDF user space (origin
+// bottom-left) and with a top-left origin.
+type PageCoords struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Polygon        []*Point               `protobuf:"bytes,1,rep,name=polygon,proto3" json:"polygon,omitempty"`
+	Box            *Box                   `protobuf:"bytes,2,opt,name=box,proto3" json:"box,omitempty"`
+	PolygonTopLeft []*Point               `protobuf:"bytes,3,rep,name=polygon_top_left,json=polygonTopLeft,proto3" json:"polygon_top_left,omitempty"`
+	BoxTopLeft     *Box                   `protobuf:"bytes,4,opt,name=box_top_left,json=boxTopLeft,proto3" json:"box_top_left,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *PageCoords) Reset() {
+	*x = PageCoords{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PageCoords) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PageCoords) ProtoMessage() {}
+
+func (x *PageCoords) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PageCoords.ProtoReflect.Descriptor instead.
+func (*PageCoords) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *PageCoords) GetPolygon() []*Point {
+	if x != nil {
+		return x.Polygon
+	}
+	return nil
+}
+
+func (x *PageCoords) GetBox() *Box {
+	if x != nil {
+		return x.Box
+	}
+	return nil
+}
+
+func (x *PageCoords) GetPolygonTopLeft() []*Point {
+	if x != nil {
+		return x.PolygonTopLeft
+	}
+	return nil
+}
+
+func (x *PageCoords) GetBoxTopLeft() *Box {
+	if x != nil {
+		return x.BoxTopLeft
+	}
+	return nil
+}
+
+type Word struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	Confidence    float64                `protobuf:"fixed64,2,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	Box           *Box                   `protobuf:"bytes,3,opt,name=box,proto3" json:"box,omitempty"`
+	Page          *PageCoords            `protobuf:"bytes,4,opt,name=page,proto3" json:"page,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Word) Reset() {
+	*x = Word{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Word) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Word) ProtoMessage() {}
+
+func (x *Word) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Word.ProtoReflect.Descriptor instead.
+func (*Word) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Word) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Word) GetConfidence() float64 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+func (x *Word) GetBox() *Box {
+	if x != nil {
+		return x.Box
+	}
+	return nil
+}
+
+func (x *Word) GetPage() *PageCoords {
+	if x != nil {
+		return x.Page
+	}
+	return nil
+}
+
+type RegionTiming struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	RecognizePreprocessNs int64                  `protobuf:"varint,1,opt,name=recognize_preprocess_ns,json=recognizePreprocessNs,proto3" json:"recognize_preprocess_ns,omitempty"`
+	RecognizeModelNs      int64                  `protobuf:"varint,2,opt,name=recognize_model_ns,json=recognizeModelNs,proto3" json:"recognize_model_ns,omitempty"`
+	RecognizeDecodeNs     int64                  `protobuf:"varint,3,opt,name=recognize_decode_ns,json=recognizeDecodeNs,proto3" json:"recognize_decode_ns,omitempty"`
+	RecognizeTotalNs      int64                  `protobuf:"varint,4,opt,name=recognize_total_ns,json=recognizeTotalNs,proto3" json:"recognize_total_ns,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *RegionTiming) Reset() {
+	*x = RegionTiming{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegionTiming) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegionTiming) ProtoMessage() {}
+
+func (x *RegionTiming) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegionTiming.ProtoReflect.Descriptor instead.
+func (*RegionTiming) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *RegionTiming) GetRecognizePreprocessNs() int64 {
+	if x != nil {
+		return x.RecognizePreprocessNs
+	}
+	return 0
+}
+
+func (x *RegionTiming) GetRecognizeModelNs() int64 {
+	if x != nil {
+		return x.RecognizeModelNs
+	}
+	return 0
+}
+
+func (x *RegionTiming) GetRecognizeDecodeNs() int64 {
+	if x != nil {
+		return x.RecognizeDecodeNs
+	}
+	return 0
+}
+
+func (x *RegionTiming) GetRecognizeTotalNs() int64 {
+	if x != nil {
+		return x.RecognizeTotalNs
+	}
+	return 0
+}
+
+type Region struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Polygon         []*Point               `protobuf:"bytes,1,rep,name=polygon,proto3" json:"polygon,omitempty"`
+	Box             *Box                   `protobuf:"bytes,2,opt,name=box,proto3" json:"box,omitempty"`
+	DetConfidence   float64                `protobuf:"fixed64,3,opt,name=det_confidence,json=detConfidence,proto3" json:"det_confidence,omitempty"`
+	Text            string                 `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	RecConfidence   float64                `protobuf:"fixed64,5,opt,name=rec_confidence,json=recConfidence,proto3" json:"rec_confidence,omitempty"`
+	CharConfidences []float64              `protobuf:"fixed64,6,rep,packed,name=char_confidences,json=charConfidences,proto3" json:"char_confidences,omitempty"`
+	Rotated         bool                   `protobuf:"varint,7,opt,name=rotated,proto3" json:"rotated,omitempty"`
+	Language        string                 `protobuf:"bytes,8,opt,name=language,proto3" json:"language,omitempty"`
+	// PDF results only.
+	Page          *PageCoords   `protobuf:"bytes,9,opt,name=page,proto3" json:"page,omitempty"`
+	Words         []*Word       `protobuf:"bytes,10,rep,name=words,proto3" json:"words,omitempty"`
+	Timing        *RegionTiming `protobuf:"bytes,11,opt,name=timing,proto3" json:"timing,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Region) Reset() {
+	*x = Region{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Region) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Region) ProtoMessage() {}
+
+func (x *Region) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Region.ProtoReflect.Descriptor instead.
+func (*Region) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Region) GetPolygon() []*Point {
+	if x != nil {
+		return x.Polygon
+	}
+	return nil
+}
+
+func (x *Region) GetBox() *Box {
+	if x != nil {
+		return x.Box
+	}
+	return nil
+}
+
+func (x *Region) GetDetConfidence() float64 {
+	if x != nil {
+		return x.DetConfidence
+	}
+	return 0
+}
+
+func (x *Region) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Region) GetRecConfidence() float64 {
+	if x != nil {
+		return x.RecConfidence
+	}
+	return 0
+}
+
+func (x *Region) GetCharConfidences() []float64 {
+	if x != nil {
+		return x.CharConfidences
+	}
+	return nil
+}
+
+func (x *Region) GetRotated() bool {
+	if x != nil {
+		return x.Rotated
+	}
+	return false
+}
+
+func (x *Region) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
+func (x *Region) GetPage() *PageCoords {
+	if x != nil {
+		return x.Page
+	}
+	return nil
+}
+
+func (x *Region) GetWords() []*Word {
+	if x != nil {
+		return x.Words
+	}
+	return nil
+}
+
+func (x *Region) GetTiming() *RegionTiming {
+	if x != nil {
+		return x.Timing
+	}
+	return nil
+}
+
+type Barcode struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Confidence    float64                `protobuf:"fixed64,3,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	Rotation      float64                `protobuf:"fixed64,4,opt,name=rotation,proto3" json:"rotation,omitempty"`
+	Box           *Box                   `protobuf:"bytes,5,opt,name=box,proto3" json:"box,omitempty"`
+	Points        []*Point               `protobuf:"bytes,6,rep,name=points,proto3" json:"points,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Barcode) Reset() {
+	*x = Barcode{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Barcode) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Barcode) ProtoMessage() {}
+
+func (x *Barcode) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Barcode.ProtoReflect.Descriptor instead.
+func (*Barcode) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Barcode) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Barcode) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Barcode) GetConfidence() float64 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+func (x *Barcode) GetRotation() float64 {
+	if x != nil {
+		return x.Rotation
+	}
+	return 0
+}
+
+func (x *Barcode) GetBox() *Box {
+	if x != nil {
+		return x.Box
+	}
+	return nil
+}
+
+func (x *Barcode) GetPoints() []*Point {
+	if x != nil {
+		return x.Points
+	}
+	return nil
+}
+
+type Orientation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Angle         int32                  `protobuf:"varint,1,opt,name=angle,proto3" json:"angle,omitempty"`
+	Confidence    float64                `protobuf:"fixed64,2,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	Applied       bool                   `protobuf:"varint,3,opt,name=applied,proto3" json:"applied,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Orientation) Reset() {
+	*x = Orientation{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Orientation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Orientation) ProtoMessage() {}
+
+func (x *Orientation) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Orientation.ProtoReflect.Descriptor instead.
+func (*Orientation) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *Orientation) GetAngle() int32 {
+	if x != nil {
+		return x.Angle
+	}
+	return 0
+}
+
+func (x *Orientation) GetConfidence() float64 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+func (x *Orientation) GetApplied() bool {
+	if x != nil {
+		return x.Applied
+	}
+	return false
+}
+
+type ImageProcessing struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DetectionNs   int64                  `protobuf:"varint,1,opt,name=detection_ns,json=detectionNs,proto3" json:"detection_ns,omitempty"`
+	RecognitionNs int64                  `protobuf:"varint,2,opt,name=recognition_ns,json=recognitionNs,proto3" json:"recognition_ns,omitempty"`
+	TotalNs       int64                  `protobuf:"varint,3,opt,name=total_ns,json=totalNs,proto3" json:"total_ns,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImageProcessing) Reset() {
+	*x = ImageProcessing{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImageProcessing) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImageProcessing) ProtoMessage() {}
+
+func (x *ImageProcessing) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImageProcessing.ProtoReflect.Descriptor instead.
+func (*ImageProcessing) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ImageProcessing) GetDetectionNs() int64 {
+	if x != nil {
+		return x.DetectionNs
+	}
+	return 0
+}
+
+func (x *ImageProcessing) GetRecognitionNs() int64 {
+	if x != nil {
+		return x.RecognitionNs
+	}
+	return 0
+}
+
+func (x *ImageProcessing) GetTotalNs() int64 {
+	if x != nil {
+		return x.TotalNs
+	}
+	return 0
+}
+
+type ImageResult struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Width            int32                  `protobuf:"varint,1,opt,name=width,proto3" json:"width,omitempty"`
+	Height           int32                  `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	Regions          []*Region              `protobuf:"bytes,3,rep,name=regions,proto3" json:"regions,omitempty"`
+	Barcodes         []*Barcode             `protobuf:"bytes,4,rep,name=barcodes,proto3" json:"barcodes,omitempty"`
+	AvgDetConfidence float64                `protobuf:"fixed64,5,opt,name=avg_det_confidence,json=avgDetConfidence,proto3" json:"avg_det_confidence,omitempty"`
+	Orientation      *Orientation           `protobuf:"bytes,6,opt,name=orientation,proto3" json:"orientation,omitempty"`
+	Processing       *ImageProcessing       `protobuf:"bytes,7,opt,name=processing,proto3" json:"processing,omitempty"`
+	// Set when the processing timeout hit after detection; regions carry no
+	// recognized text.
+	Truncated     bool `protobuf:"varint,8,opt,name=truncated,proto3" json:"truncated,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImageResult) Reset() {
+	*x = ImageResult{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImageResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImageResult) ProtoMessage() {}
+
+func (x *ImageResult) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImageResult.ProtoReflect.Descriptor instead.
+func (*ImageResult) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ImageResult) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *ImageResult) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *ImageResult) GetRegions() []*Region {
+	if x != nil {
+		return x.Regions
+	}
+	return nil
+}
+
+func (x *ImageResult) GetBarcodes() []*Barcode {
+	if x != nil {
+		return x.Barcodes
+	}
+	return nil
+}
+
+func (x *ImageResult) GetAvgDetConfidence() float64 {
+	if x != nil {
+		return x.AvgDetConfidence
+	}
+	return 0
+}
+
+func (x *ImageResult) GetOrientation() *Orientation {
+	if x != nil {
+		return x.Orientation
+	}
+	return nil
+}
+
+func (x *ImageResult) GetProcessing() *ImageProcessing {
+	if x != nil {
+		return x.Processing
+	}
+	return nil
+}
+
+func (x *ImageResult) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+type PDFImageResult struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	ImageIndex int32                  `protobuf:"varint,1,opt,name=image_index,json=imageIndex,proto3" json:"image_index,omitempty"`
+	Width      int32                  `protobuf:"varint,2,opt,name=width,proto3" json:"width,omitempty"`
+	Height     int32                  `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
+	Regions    []*Region              `protobuf:"bytes,4,rep,name=regions,proto3" json:"regions,omitempty"`
+	Barcodes   []*Barcode             `protobuf:"bytes,5,rep,name=barcodes,proto3" json:"barcodes,omitempty"`
+	Confidence float64                `protobuf:"fixed64,6,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	// Maps image pixels to PDF user space: [a b c d e f].
+	Placement     []float64 `protobuf:"fixed64,7,rep,packed,name=placement,proto3" json:"placement,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PDFImageResult) Reset() {
+	*x = PDFImageResult{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PDFImageResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PDFImageResult) ProtoMessage() {}
+
+func (x *PDFImageResult) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PDFImageResult.ProtoReflect.Descriptor instead.
+func (*PDFImageResult) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *PDFImageResult) GetImageIndex() int32 {
+	if x != nil {
+		return x.ImageIndex
+	}
+	return 0
+}
+
+func (x *PDFImageResult) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *PDFImageResult) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *PDFImageResult) GetRegions() []*Region {
+	if x != nil {
+		return x.Regions
+	}
+	return nil
+}
+
+func (x *PDFImageResult) GetBarcodes() []*Barcode {
+	if x != nil {
+		return x.Barcodes
+	}
+	return nil
+}
+
+func (x *PDFImageResult) GetConfidence() float64 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+func (x *PDFImageResult) GetPlacement() []float64 {
+	if x != nil {
+		return x.Placement
+	}
+	return nil
+}
+
+type PDFPageResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageNumber    int32                  `protobuf:"varint,1,opt,name=page_number,json=pageNumber,proto3" json:"page_number,omitempty"`
+	Width         int32                  `protobuf:"varint,2,opt,name=width,proto3" json:"width,omitempty"`
+	Height        int32                  `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
+	WidthPt       float64                `protobuf:"fixed64,4,opt,name=width_pt,json=widthPt,proto3" json:"width_pt,omitempty"`
+	HeightPt      float64                `protobuf:"fixed64,5,opt,name=height_pt,json=heightPt,proto3" json:"height_pt,omitempty"`
+	Rotate        int32                  `protobuf:"varint,6,opt,name=rotate,proto3" json:"rotate,omitempty"`
+	Images        []*PDFImageResult      `protobuf:"bytes,7,rep,name=images,proto3" json:"images,omitempty"`
+	Warnings      []string               `protobuf:"bytes,8,rep,name=warnings,proto3" json:"warnings,omitempty"`
+	TotalNs       int64                  `protobuf:"varint,9,opt,name=total_ns,json=totalNs,proto3" json:"total_ns,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PDFPageResult) Reset() {
+	*x = PDFPageResult{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PDFPageResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PDFPageResult) ProtoMessage() {}
+
+func (x *PDFPageResult) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PDFPageResult.ProtoReflect.Descriptor instead.
+func (*PDFPageResult) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *PDFPageResult) GetPageNumber() int32 {
+	if x != nil {
+		return x.PageNumber
+	}
+	return 0
+}
+
+func (x *PDFPageResult) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *PDFPageResult) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *PDFPageResult) GetWidthPt() float64 {
+	if x != nil {
+		return x.WidthPt
+	}
+	return 0
+}
+
+func (x *PDFPageResult) GetHeightPt() float64 {
+	if x != nil {
+		return x.HeightPt
+	}
+	return 0
+}
+
+func (x *PDFPageResult) GetRotate() int32 {
+	if x != nil {
+		return x.Rotate
+	}
+	return 0
+}
+
+func (x *PDFPageResult) GetImages() []*PDFImageResult {
+	if x != nil {
+		return x.Images
+	}
+	return nil
+}
+
+func (x *PDFPageResult) GetWarnings() []string {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+func (x *PDFPageResult) GetTotalNs() int64 {
+	if x != nil {
+		return x.TotalNs
+	}
+	return 0
+}
+
+type PDFResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	TotalPages    int32                  `protobuf:"varint,2,opt,name=total_pages,json=totalPages,proto3" json:"total_pages,omitempty"`
+	Pages         []*PDFPageResult       `protobuf:"bytes,3,rep,name=pages,proto3" json:"pages,omitempty"`
+	ExtractionNs  int64                  `protobuf:"varint,4,opt,name=extraction_ns,json=extractionNs,proto3" json:"extraction_ns,omitempty"`
+	TotalNs       int64                  `protobuf:"varint,5,opt,name=total_ns,json=totalNs,proto3" json:"total_ns,omitempty"`
+	Truncated     bool                   `protobuf:"varint,6,opt,name=truncated,proto3" json:"truncated,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PDFResult) Reset() {
+	*x = PDFResult{}
+	mi := &file_pogo_v1_ocr_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PDFResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PDFResult) ProtoMessage() {}
+
+func (x *PDFResult) ProtoReflect() protoreflect.Message {
+	mi := &file_pogo_v1_ocr_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PDFResult.ProtoReflect.Descriptor instead.
+func (*PDFResult) Descriptor() ([]byte, []int) {
+	return file_pogo_v1_ocr_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *PDFResult) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *PDFResult) GetTotalPages() int32 {
+	if x != nil {
+		return x.TotalPages
+	}
+	return 0
+}
+
+func (x *PDFResult) GetPages() []*PDFPageResult {
+	if x != nil {
+		return x.Pages
+	}
+	return nil
+}
+
+func (x *PDFResult) GetExtractionNs() int64 {
+	if x != nil {
+		return x.ExtractionNs
+	}
+	return 0
+}
+
+func (x *PDFResult) GetTotalNs() int64 {
+	if x != nil {
+		return x.TotalNs
+	}
+	return 0
+}
+
+func (x *PDFResult) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+var File_pogo_v1_ocr_proto protoreflect.FileDescriptor
+
+const file_pogo_v1_ocr_proto_rawDesc = "" +
+	"\n" +
+	"\x11pogo/v1/ocr.proto\x12\apogo.v1\"\xb8\x02\n" +
+	"\aOptions\x12\x18\n" +
+	"\aprofile\x18\n" +
+	" \x01(\tR\aprofile\x12\x1a\n" +
+	"\blanguage\x18\x01 \x01(\tR\blanguage\x12\x1d\n" +
+	"\n" +
+	"dict_langs\x18\x02 \x03(\tR\tdictLangs\x12\x12\n" +
+	"\x04dict\x18\x03 \x01(\tR\x04dict\x12\x1b\n" +
+	"\tdet_model\x18\x04 \x01(\tR\bdetModel\x12\x1b\n" +
+	"\trec_model\x18\x05 \x01(\tR\brecModel\x12\x1a\n" +
+	"\bbarcodes\x18\x06 \x01(\bR\bbarcodes\x12#\n" +
+	"\rbarcode_types\x18\a \x01(\tR\fbarcodeTypes\x12(\n" +
+	"\x10barcode_min_size\x18\b \x01(\x05R\x0ebarcodeMinSize\x12\x1f\n" +
+	"\vbarcode_dpi\x18\t \x01(\x05R\n" +
+	"barcodeDpi\"\xee\x01\n" +
+	"\n" +
+	"PDFOptions\x12\x14\n" +
+	"\x05pages\x18\x01 \x01(\tR\x05pages\x12#\n" +
+	"\ruser_password\x18\x02 \x01(\tR\fuserPassword\x12%\n" +
+	"\x0eowner_password\x18\x03 \x01(\tR\rownerPassword\x12\x1f\n" +
+	"\vvector_text\x18\x04 \x01(\bR\n" +
+	"vectorText\x12\x16\n" +
+	"\x06hybrid\x18\x05 \x01(\bR\x06hybrid\x12+\n" +
+	"\x11quality_threshold\x18\x06 \x01(\x01R\x10qualityThreshold\x12\x18\n" +
+	"\aworkers\x18\a \x01(\x05R\aworkers\"Y\n" +
+	"\x15RecognizeImageRequest\x12\x14\n" +
+	"\x05image\x18\x01 \x01(\fR\x05image\x12*\n" +
+	"\aoptions\x18\x02 \x01(\v2\x10.pogo.v1.OptionsR\aoptions\"F\n" +
+	"\x16RecognizeImageResponse\x12,\n" +
+	"\x06result\x18\x01 \x01(\v2\x14.pogo.v1.ImageResultR\x06result\"\xa5\x01\n" +
+	"\x13RecognizePDFRequest\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x10\n" +
+	"\x03pdf\x18\x02 \x01(\fR\x03pdf\x12*\n" +
+	"\aoptions\x18\x03 \x01(\v2\x10.pogo.v1.OptionsR\aoptions\x124\n" +
+	"\vpdf_options\x18\x04 \x01(\v2\x13.pogo.v1.PDFOptionsR\n" +
+	"pdfOptions\"~\n" +
+	"\x14RecognizePDFResponse\x12,\n" +
+	"\x04page\x18\x01 \x01(\v2\x16.pogo.v1.PDFPageResultH\x00R\x04page\x12/\n" +
+	"\asummary\x18\x02 \x01(\v2\x13.pogo.v1.PDFSummaryH\x00R\asummaryB\a\n" +
+	"\x05event\"\xa7\x01\n" +
+	"\n" +
+	"PDFSummary\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x1f\n" +
+	"\vtotal_pages\x18\x02 \x01(\x05R\n" +
+	"totalPages\x12#\n" +
+	"\rextraction_ns\x18\x03 \x01(\x03R\fextractionNs\x12\x19\n" +
+	"\btotal_ns\x18\x04 \x01(\x03R\atotalNs\x12\x1c\n" +
+	"\ttruncated\x18\x05 \x01(\bR\ttruncated\"\xb8\x01\n" +
+	"\tBatchItem\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x05image\x18\x02 \x01(\fH\x00R\x05image\x12\x12\n" +
+	"\x03pdf\x18\x03 \x01(\fH\x00R\x03pdf\x12*\n" +
+	"\aoptions\x18\x04 \x01(\v2\x10.pogo.v1.OptionsR\aoptions\x124\n" +
+	"\vpdf_options\x18\x05 \x01(\v2\x13.pogo.v1.PDFOptionsR\n" +
+	"pdfOptionsB\t\n" +
+	"\acontent\"\xf6\x01\n" +
+	"\x0fBatchItemResult\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05index\x18\x02 \x01(\x05R\x05index\x12\x18\n" +
+	"\asuccess\x18\x03 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12,\n" +
+	"\x05image\x18\x05 \x01(\v2\x14.pogo.v1.ImageResultH\x00R\x05image\x12&\n" +
+	"\x03pdf\x18\x06 \x01(\v2\x12.pogo.v1.PDFResultH\x00R\x03pdf\x12)\n" +
+	"\x10duration_seconds\x18\a \x01(\x01R\x0fdurationSecondsB\b\n" +
+	"\x06result\"#\n" +
+	"\x05Point\x12\f\n" +
+	"\x01x\x18\x01 \x01(\x01R\x01x\x12\f\n" +
+	"\x01y\x18\x02 \x01(\x01R\x01y\"=\n" +
+	"\x03Box\x12\f\n" +
+	"\x01x\x18\x01 \x01(\x01R\x01x\x12\f\n" +
+	"\x01y\x18\x02 \x01(\x01R\x01y\x12\f\n" +
+	"\x01w\x18\x03 \x01(\x01R\x01w\x12\f\n" +
+	"\x01h\x18\x04 \x01(\x01R\x01h\"\xc0\x01\n" +
+	"\n" +
+	"PageCoords\x12(\n" +
+	"\apolygon\x18\x01 \x03(\v2\x0e.pogo.v1.PointR\apolygon\x12\x1e\n" +
+	"\x03box\x18\x02 \x01(\v2\f.pogo.v1.BoxR\x03box\x128\n" +
+	"\x10polygon_top_left\x18\x03 \x03(\v2\x0e.pogo.v1.PointR\x0epolygonTopLeft\x12.\n" +
+	"\fbox_top_left\x18\x04 \x01(\v2\f.pogo.v1.BoxR\n" +
+	"boxTopLeft\"\x83\x01\n" +
+	"\x04Word\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\x02 \x01(\x01R\n" +
+	"confidence\x12\x1e\n" +
+	"\x03box\x18\x03 \x01(\v2\f.pogo.v1.BoxR\x03box\x12'\n" +
+	"\x04page\x18\x04 \x01(\v2\x13.pogo.v1.PageCoordsR\x04page\"\xd2\x01\n" +
+	"\fRegionTiming\x126\n" +
+	"\x17recognize_preprocess_ns\x18\x01 \x01(\x03R\x15recognizePreprocessNs\x12,\n" +
+	"\x12recognize_model_ns\x18\x02 \x01(\x03R\x10recognizeModelNs\x12.\n" +
+	"\x13recognize_decode_ns\x18\x03 \x01(\x03R\x11recognizeDecodeNs\x12,\n" +
+	"\x12recognize_total_ns\x18\x04 \x01(\x03R\x10recognizeTotalNs\"\x92\x03\n" +
+	"\x06Region\x12(\n" +
+	"\apolygon\x18\x01 \x03(\v2\x0e.pogo.v1.PointR\apolygon\x12\x1e\n" +
+	"\x03box\x18\x02 \x01(\v2\f.pogo.v1.BoxR\x03box\x12%\n" +
+	"\x0edet_confidence\x18\x03 \x01(\x01R\rdetConfidence\x12\x12\n" +
+	"\x04text\x18\x04 \x01(\tR\x04text\x12%\n" +
+	"\x0erec_confidence\x18\x05 \x01(\x01R\rrecConfidence\x12)\n" +
+	"\x10char_confidences\x18\x06 \x03(\x01R\x0fcharConfidences\x12\x18\n" +
+	"\arotated\x18\a \x01(\bR\arotated\x12\x1a\n" +
+	"\blanguage\x18\b \x01(\tR\blanguage\x12'\n" +
+	"\x04page\x18\t \x01(\v2\x13.pogo.v1.PageCoordsR\x04page\x12#\n" +
+	"\x05words\x18\n" +
+	" \x03(\v2\r.pogo.v1.WordR\x05words\x12-\n" +
+	"\x06timing\x18\v \x01(\v2\x15.pogo.v1.RegionTimingR\x06timing\"\xb7\x01\n" +
+	"\aBarcode\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\x03 \x01(\x01R\n" +
+	"confidence\x12\x1a\n" +
+	"\brotation\x18\x04 \x01(\x01R\brotation\x12\x1e\n" +
+	"\x03box\x18\x05 \x01(\v2\f.pogo.v1.BoxR\x03box\x12&\n" +
+	"\x06points\x18\x06 \x03(\v2\x0e.pogo.v1.PointR\x06points\"]\n" +
+	"\vOrientation\x12\x14\n" +
+	"\x05angle\x18\x01 \x01(\x05R\x05angle\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\x02 \x01(\x01R\n" +
+	"confidence\x12\x18\n" +
+	"\aapplied\x18\x03 \x01(\bR\aapplied\"v\n" +
+	"\x0fImageProcessing\x12!\n" +
+	"\fdetection_ns\x18\x01 \x01(\x03R\vdetectionNs\x12%\n" +
+	"\x0erecognition_ns\x18\x02 \x01(\x03R\rrecognitionNs\x12\x19\n" +
+	"\btotal_ns\x18\x03 \x01(\x03R\atotalNs\"\xd2\x02\n" +
+	"\vImageResult\x12\x14\n" +
+	"\x05width\x18\x01 \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\x02 \x01(\x05R\x06height\x12)\n" +
+	"\aregions\x18\x03 \x03(\v2\x0f.pogo.v1.RegionR\aregions\x12,\n" +
+	"\bbarcodes\x18\x04 \x03(\v2\x10.pogo.v1.BarcodeR\bbarcodes\x12,\n" +
+	"\x12avg_det_confidence\x18\x05 \x01(\x01R\x10avgDetConfidence\x126\n" +
+	"\vorientation\x18\x06 \x01(\v2\x14.pogo.v1.OrientationR\vorientation\x128\n" +
+	"\n" +
+	"processing\x18\a \x01(\v2\x18.pogo.v1.ImageProcessingR\n" +
+	"processing\x12\x1c\n" +
+	"\ttruncated\x18\b \x01(\bR\ttruncated\"\xf6\x01\n" +
+	"\x0ePDFImageResult\x12\x1f\n" +
+	"\vimage_index\x18\x01 \x01(\x05R\n" +
+	"imageIndex\x12\x14\n" +
+	"\x05width\x18\x02 \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\x03 \x01(\x05R\x06height\x12)\n" +
+	"\aregions\x18\x04 \x03(\v2\x0f.pogo.v1.RegionR\aregions\x12,\n" +
+	"\bbarcodes\x18\x05 \x03(\v2\x10.pogo.v1.BarcodeR\bbarcodes\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\x06 \x01(\x01R\n" +
+	"confidence\x12\x1c\n" +
+	"\tplacement\x18\a \x03(\x01R\tplacement\"\x96\x02\n" +
+	"\rPDFPageResult\x12\x1f\n" +
+	"\vpage_number\x18\x01 \x01(\x05R\n" +
+	"pageNumber\x12\x14\n" +
+	"\x05width\x18\x02 \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\x03 \x01(\x05R\x06height\x12\x19\n" +
+	"\bwidth_pt\x18\x04 \x01(\x01R\awidthPt\x12\x1b\n" +
+	"\theight_pt\x18\x05 \x01(\x01R\bheightPt\x12\x16\n" +
+	"\x06rotate\x18\x06 \x01(\x05R\x06rotate\x12/\n" +
+	"\x06images\x18\a \x03(\v2\x17.pogo.v1.PDFImageResultR\x06images\x12\x1a\n" +
+	"\bwarnings\x18\b \x03(\tR\bwarnings\x12\x19\n" +
+	"\btotal_ns\x18\t \x01(\x03R\atotalNs\"\xd4\x01\n" +
+	"\tPDFResult\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x1f\n" +
+	"\vtotal_pages\x18\x02 \x01(\x05R\n" +
+	"totalPages\x12,\n" +
+	"\x05pages\x18\x03 \x03(\v2\x16.pogo.v1.PDFPageResultR\x05pages\x12#\n" +
+	"\rextraction_ns\x18\x04 \x01(\x03R\fextractionNs\x12\x19\n" +
+	"\btotal_ns\x18\x05 \x01(\x03R\atotalNs\x12\x1c\n" +
+	"\ttruncated\x18\x06 \x01(\bR\ttruncated2\xf2\x01\n" +
+	"\n" +
+	"OCRService\x12Q\n" +
+	"\x0eRecognizeImage\x12\x1e.pogo.v1.RecognizeImageRequest\x1a\x1f.pogo.v1.RecognizeImageResponse\x12M\n" +
+	"\fRecognizePDF\x12\x1c.pogo.v1.RecognizePDFRequest\x1a\x1d.pogo.v1.RecognizePDFResponse0\x01\x12B\n" +
+	"\x0eRecognizeBatch\x12\x12.pogo.v1.BatchItem\x1a\x18.pogo.v1.BatchItemResult(\x010\x01B.Z,github.com/MeKo-Tech/pogo/api/pogo/v1;pogov1b\x06proto3"
+
+var (
+	file_pogo_v1_ocr_proto_rawDescOnce sync.Once
+	file_pogo_v1_ocr_proto_rawDescData []byte
+)
+
+func file_pogo_v1_ocr_proto_rawDescGZIP() []byte {
+	file_pogo_v1_ocr_proto_rawDescOnce.Do(func() {
+		file_pogo_v1_ocr_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pogo_v1_ocr_proto_rawDesc), len(file_pogo_v1_ocr_proto_rawDesc)))
+	})
+	return file_pogo_v1_ocr_proto_rawDescData
+}
+
+var file_pogo_v1_ocr_proto_msgTypes = make([]protoimpl.MessageInfo, 22)
+var file_pogo_v1_ocr_proto_goTypes = []any{
+	(*Options)(nil),                // 0: pogo.v1.Options
+	(*PDFOptions)(nil),             // 1: pogo.v1.PDFOptions
+	(*RecognizeImageRequest)(nil),  // 2: pogo.v1.RecognizeImageRequest
+	(*RecognizeImageResponse)(nil), // 3: pogo.v1.RecognizeImageResponse
+	(*RecognizePDFRequest)(nil),    // 4: pogo.v1.RecognizePDFRequest
+	(*RecognizePDFResponse)(nil),   // 5: pogo.v1.RecognizePDFResponse
+	(*PDFSummary)(nil),             // 6: pogo.v1.PDFSummary
+	(*BatchItem)(nil),              // 7: pogo.v1.BatchItem
+	(*BatchItemResult)(nil),        // 8: pogo.v1.BatchItemResult
+	(*Point)(nil),                  // 9: pogo.v1.Point
+	(*Box)(nil),                    // 10: pogo.v1.Box
+	(*PageCoords)(nil),             // 11: pogo.v1.PageCoords
+	(*Word)(nil),                   // 12: pogo.v1.Word
+	(*RegionTiming)(nil),           // 13: pogo.v1.RegionTiming
+	(*Region)(nil),                 // 14: pogo.v1.Region
+	(*Barcode)(nil),                // 15: pogo.v1.Barcode
+	(*Orientation)(nil),            // 16: pogo.v1.Orientation
+	(*ImageProcessing)(nil),        // 17: pogo.v1.ImageProcessing
+	(*ImageResult)(nil),            // 18: pogo.v1.ImageResult
+	(*PDFImageResult)(nil),         // 19: pogo.v1.PDFImageResult
+	(*PDFPageResult)(nil),          // 20: pogo.v1.PDFPageResult
+	(*PDFResult)(nil),              // 21: pogo.v1.PDFResult
+}
+var file_pogo_v1_ocr_proto_depIdxs = []int32{
+	0,  // 0: pogo.v1.RecognizeImageRequest.options:type_name -> pogo.v1.Options
+	18, // 1: pogo.v1.RecognizeImageResponse.result:type_name -> pogo.v1.ImageResult
+	0,  // 2: pogo.v1.RecognizePDFRequest.options:type_name -> pogo.v1.Options
+	1,  // 3: pogo.v1.RecognizePDFRequest.pdf_options:type_name -> pogo.v1.PDFOptions
+	20, // 4: pogo.v1.RecognizePDFResponse.page:type_name -> pogo.v1.PDFPageResult
+	6,  // 5: pogo.v1.RecognizePDFResponse.summary:type_name -> pogo.v1.PDFSummary
+	0,  // 6: pogo.v1.BatchItem.options:type_name -> pogo.v1.Options
+	1,  // 7: pogo.v1.BatchItem.pdf_options:type_name -> pogo.v1.PDFOptions
+	18, // 8: pogo.v1.BatchItemResult.image:type_name -> pogo.v1.ImageResult
+	21, // 9: pogo.v1.BatchItemResult.pdf:type_name -> pogo.v1.PDFResult
+	9,  // 10: pogo.v1.PageCoords.polygon:type_name -> pogo.v1.Point
+	10, // 11: pogo.v1.PageCoords.box:type_name -> pogo.v1.Box
+	9,  // 12: pogo.v1.PageCoords.polygon_top_left:type_name -> pogo.v1.Point
+	10, // 13: pogo.v1.PageCoords.box_top_left:type_name -> pogo.v1.Box
+	10, // 14: pogo.v1.Word.box:type_name -> pogo.v1.Box
+	11, // 15: pogo.v1.Word.page:type_name -> pogo.v1.PageCoords
+	9,  // 16: pogo.v1.Region.polygon:type_name -> pogo.v1.Point
+	10, // 17: pogo.v1.Region.box:type_name -> pogo.v1.Box
+	11, // 18: pogo.v1.Region.page:type_name -> pogo.v1.PageCoords
+	12, // 19: pogo.v1.Region.words:type_name -> pogo.v1.Word
+	13, // 20: pogo.v1.Region.timing:type_name -> pogo.v1.RegionTiming
+	10, // 21: pogo.v1.Barcode.box:type_name -> pogo.v1.Box
+	9,  // 22: pogo.v1.Barcode.points:type_name -> pogo.v1.Point
+	14, // 23: pogo.v1.ImageResult.regions:type_name -> pogo.v1.Region
+	15, // 24: pogo.v1.ImageResult.barcodes:type_name -> pogo.v1.Barcode
+	16, // 25: pogo.v1.ImageResult.orientation:type_name -> pogo.v1.Orientation
+	17, // 26: pogo.v1.ImageResult.processing:type_name -> pogo.v1.ImageProcessing
+	14, // 27: pogo.v1.PDFImageResult.regions:type_name -> pogo.v1.Region
+	15, // 28: pogo.v1.PDFImageResult.barcodes:type_name -> pogo.v1.Barcode
+	19, // 29: pogo.v1.PDFPageResult.images:type_name -> pogo.v1.PDFImageResult
+	20, // 30: pogo.v1.PDFResult.pages:type_name -> pogo.v1.PDFPageResult
+	2,  // 31: pogo.v1.OCRService.RecognizeImage:input_type -> pogo.v1.RecognizeImageRequest
+	4,  // 32: pogo.v1.OCRService.RecognizePDF:input_type -> pogo.v1.RecognizePDFRequest
+	7,  // 33: pogo.v1.OCRService.RecognizeBatch:input_type -> pogo.v1.BatchItem
+	3,  // 34: pogo.v1.OCRService.RecognizeImage:output_type -> pogo.v1.RecognizeImageResponse
+	5,  // 35: pogo.v1.OCRService.RecognizePDF:output_type -> pogo.v1.RecognizePDFResponse
+	8,  // 36: pogo.v1.OCRService.RecognizeBatch:output_type -> pogo.v1.BatchItemResult
+	34, // [34:37] is the sub-list for method output_type
+	31, // [31:34] is the sub-list for method input_type
+	31, // [31:31] is the sub-list for extension type_name
+	31, // [31:31] is the sub-list for extension extendee
+	0,  // [0:31] is the sub-list for field type_name
+}
+
+func init() { file_pogo_v1_ocr_proto_init() }
+func file_pogo_v1_ocr_proto_init() {
+	if File_pogo_v1_ocr_proto != nil {
+		return
+	}
+	file_pogo_v1_ocr_proto_msgTypes[5].OneofWrappers = []any{
+		(*RecognizePDFResponse_Page)(nil),
+		(*RecognizePDFResponse_Summary)(nil),
+	}
+	file_pogo_v1_ocr_proto_msgTypes[7].OneofWrappers = []any{
+		(*BatchItem_Image)(nil),
+		(*BatchItem_Pdf)(nil),
+	}
+	file_pogo_v1_ocr_proto_msgTypes[8].OneofWrappers = []any{
+		(*BatchItemResult_Image)(nil),
+		(*BatchItemResult_Pdf)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pogo_v1_ocr_proto_rawDesc), len(file_pogo_v1_ocr_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   22,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_pogo_v1_ocr_proto_goTypes,
+		DependencyIndexes: file_pogo_v1_ocr_proto_depIdxs,
+		MessageInfos:      file_pogo_v1_ocr_proto_msgTypes,
+	}.Build()
+	File_pogo_v1_ocr_proto = out.File
+	file_pogo_v1_ocr_proto_goTypes = nil
+	file_pogo_v1_ocr_proto_depIdxs = nil
+}
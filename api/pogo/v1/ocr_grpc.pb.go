@@ -0,0 +1,220 @@
+// The pogo OCR service, served by `pogo serve --grpc-port`.
+//
+// Results mirror the JSON results of the HTTP API (pipeline.OCRImageResult
+// and pipeline.OCRPDFResult). Clients authenticate like HTTP clients when
+// API keys are configured: with an "x-api-key" or
+// "authorization: Bearer <key>" metadata entry.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: pogo/v1/ocr.proto
+
+package pogov1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	OCRService_RecognizeImage_FullMethodName = "/pogo.v1.OCRService/RecognizeImage"
+	OCRService_RecognizePDF_FullMethodName   = "/pogo.v1.OCRService/RecognizePDF"
+	OCRService_RecognizeBatch_FullMethodName = "/pogo.v1.OCRService/RecognizeBatch"
+)
+
+// OCRServiceClient is the client API for OCRService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// OCRService recognizes text in images and PDF documents.
+type OCRServiceClient interface {
+	// RecognizeImage OCRs a single image. Requires the image scope.
+	RecognizeImage(ctx context.Context, in *RecognizeImageRequest, opts ...grpc.CallOption) (*RecognizeImageResponse, error)
+	// RecognizePDF OCRs a PDF and streams one message per page as soon as it
+	// is recognized, followed by a summary of the document. Requires the pdf
+	// scope.
+	RecognizePDF(ctx context.Context, in *RecognizePDFRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RecognizePDFResponse], error)
+	// RecognizeBatch OCRs a stream of images and PDFs and answers every item
+	// with one result, in order. Failed items do not end the stream. Requires
+	// the batch scope.
+	RecognizeBatch(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[BatchItem, BatchItemResult], error)
+}
+
+type oCRServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOCRServiceClient(cc grpc.ClientConnInterface) OCRServiceClient {
+	return &oCRServiceClient{cc}
+}
+
+func (c *oCRServiceClient) RecognizeImage(ctx context.Context, in *RecognizeImageRequest, opts ...grpc.CallOption) (*RecognizeImageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecognizeImageResponse)
+	err := c.cc.Invoke(ctx, OCRService_RecognizeImage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oCRServiceClient) RecognizePDF(ctx context.Context, in *RecognizePDFRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RecognizePDFResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &OCRService_ServiceDesc.Streams[0], OCRService_RecognizePDF_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RecognizePDFRequest, RecognizePDFResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OCRService_RecognizePDFClient = grpc.ServerStreamingClient[RecognizePDFResponse]
+
+func (c *oCRServiceClient) RecognizeBatch(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[BatchItem, BatchItemResult], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &OCRService_ServiceDesc.Streams[1], OCRService_RecognizeBatch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[BatchItem, BatchItemResult]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OCRService_RecognizeBatchClient = grpc.BidiStreamingClient[BatchItem, BatchItemResult]
+
+// OCRServiceServer is the server API for OCRService service.
+// All implementations must embed UnimplementedOCRServiceServer
+// for forward compatibility.
+//
+// OCRService recognizes text in images and PDF documents.
+type OCRServiceServer interface {
+	// RecognizeImage OCRs a single image. Requires the image scope.
+	RecognizeImage(context.Context, *RecognizeImageRequest) (*RecognizeImageResponse, error)
+	// RecognizePDF OCRs a PDF and streams one message per page as soon as it
+	// is recognized, followed by a summary of the document. Requires the pdf
+	// scope.
+	RecognizePDF(*RecognizePDFRequest, grpc.ServerStreamingServer[RecognizePDFResponse]) error
+	// RecognizeBatch OCRs a stream of images and PDFs and answers every item
+	// with one result, in order. Failed items do not end the stream. Requires
+	// the batch scope.
+	RecognizeBatch(grpc.BidiStreamingServer[BatchItem, BatchItemResult]) error
+	mustEmbedUnimplementedOCRServiceServer()
+}
+
+// UnimplementedOCRServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedOCRServiceServer struct{}
+
+func (UnimplementedOCRServiceServer) RecognizeImage(context.Context, *RecognizeImageRequest) (*RecognizeImageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RecognizeImage not implemented")
+}
+func (UnimplementedOCRServiceServer) RecognizePDF(*RecognizePDFRequest, grpc.ServerStreamingServer[RecognizePDFResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method RecognizePDF not implemented")
+}
+func (UnimplementedOCRServiceServer) RecognizeBatch(grpc.BidiStreamingServer[BatchItem, BatchItemResult]) error {
+	return status.Errorf(codes.Unimplemented, "method RecognizeBatch not implemented")
+}
+func (UnimplementedOCRServiceServer) mustEmbedUnimplementedOCRServiceServer() {}
+func (UnimplementedOCRServiceServer) testEmbeddedByValue()                    {}
+
+// UnsafeOCRServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OCRServiceServer will
+// result in compilation errors.
+type UnsafeOCRServiceServer interface {
+	mustEmbedUnimplementedOCRServiceServer()
+}
+
+func RegisterOCRServiceServer(s grpc.ServiceRegistrar, srv OCRServiceServer) {
+	// If the following call pancis, it indicates UnimplementedOCRServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&OCRService_ServiceDesc, srv)
+}
+
+func _OCRService_RecognizeImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecognizeImageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OCRServiceServer).RecognizeImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OCRService_RecognizeImage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OCRServiceServer).RecognizeImage(ctx, req.(*RecognizeImageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OCRService_RecognizePDF_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RecognizePDFRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OCRServiceServer).RecognizePDF(m, &grpc.GenericServerStream[RecognizePDFRequest, RecognizePDFResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OCRService_RecognizePDFServer = grpc.ServerStreamingServer[RecognizePDFResponse]
+
+func _OCRService_RecognizeBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(OCRServiceServer).RecognizeBatch(&grpc.GenericServerStream[BatchItem, BatchItemResult]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OCRService_RecognizeBatchServer = grpc.BidiStreamingServer[BatchItem, BatchItemResult]
+
+// OCRService_ServiceDesc is the grpc.ServiceDesc for OCRService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OCRService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pogo.v1.OCRService",
+	HandlerType: (*OCRServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RecognizeImage",
+			Handler:    _OCRService_RecognizeImage_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "RecognizePDF",
+			Handler:       _OCRService_RecognizePDF_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "RecognizeBatch",
+			Handler:       _OCRService_RecognizeBatch_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "pogo/v1/ocr.proto",
+}
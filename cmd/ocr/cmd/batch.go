@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
 	"runtime"
 	"time"
 
@@ -21,11 +24,16 @@ parallel workers and resource management.
 
 Supported formats: JPEG, PNG, BMP, TIFF
 
+Inputs, --output and --overlay-dir may also be S3 locations (s3://bucket/key,
+s3://bucket/prefix/ for a directory). Credentials, region and the endpoint
+of S3-compatible services come from the AWS_* environment variables.
+
 Examples:
   pogo batch *.jpg *.png
   pogo batch images/ --recursive --workers 8
   pogo batch file1.jpg file2.png --format json --output results.json
-  pogo batch images/ --progress --memory-limit 2GB`,
+  pogo batch images/ --progress --memory-limit 2GB
+  pogo batch s3://scans/inbox/ --recursive --format json --output s3://scans/results.json`,
 	Args:         cobra.MinimumNArgs(1),
 	SilenceUsage: true,
 	RunE:         runBatchCommand,
@@ -166,13 +174,22 @@ func runBatchCommand(cmd *cobra.Command, args []string) error {
 		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Processing %d files...\n", len(args))
 	}
 
+	// Stop between images on Ctrl-C or when --timeout expires
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if timeout, _ := cmd.Flags().GetDuration("timeout"); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	// Process batch
-	result, err := batch.ProcessBatch(args, config)
-	if err != nil {
+	result, err := batch.ProcessBatchContext(ctx, args, config)
+	if result == nil {
 		return fmt.Errorf("batch processing failed: %w", err)
 	}
 
-	// Save results
+	// Save results, including the images finished before an interruption
 	if err := result.SaveResults(config.Format, config.OutputFile, config.Quiet); err != nil {
 		return fmt.Errorf("failed to save results: %w", err)
 	}
@@ -180,6 +197,10 @@ func runBatchCommand(cmd *cobra.Command, args []string) error {
 	// Print stats
 	result.PrintStats(config.Quiet)
 
+	if result.Truncated {
+		return fmt.Errorf("batch processing stopped after %d of %d images: %w",
+			len(result.Results), result.TotalFiles, err)
+	}
 	return nil
 }
 
@@ -203,9 +224,9 @@ func init() {
 	batchCmd.Flags().Float64("textline-threshold", 0.0, "text line orientation confidence threshold")
 
 	// Output flags
-	batchCmd.Flags().StringP("format", "f", "text", "output format: text, json, csv")
-	batchCmd.Flags().StringP("output", "o", "", "output file (default: stdout)")
-	batchCmd.Flags().String("overlay-dir", "", "directory to save overlay images")
+	batchCmd.Flags().StringP("format", "f", "text", "output format: text, text-layout, markdown, json, csv, hocr, alto, page")
+	batchCmd.Flags().StringP("output", "o", "", "output file or s3:// location (default: stdout)")
+	batchCmd.Flags().String("overlay-dir", "", "directory or s3:// prefix to save overlay images")
 
 	// Rectification flags (experimental)
 	batchCmd.Flags().Bool("rectify", false, "enable document rectification (experimental)")
@@ -235,6 +256,7 @@ func init() {
 	batchCmd.Flags().Bool("quiet", false, "suppress progress output")
 	batchCmd.Flags().Bool("stats", false, "show processing statistics")
 	batchCmd.Flags().Duration("progress-interval", 500*time.Millisecond, "progress update interval")
+	batchCmd.Flags().Duration("timeout", 0, "stop after this duration and write the results finished so far (0=no limit)")
 
 	// Resource management flags
 	batchCmd.Flags().Bool("adaptive-scaling", false, "enable adaptive worker scaling")
@@ -4,14 +4,18 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"image"
 	"image/color"
 	"image/png"
+	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/MeKo-Tech/pogo/internal/models"
 	"github.com/MeKo-Tech/pogo/internal/onnx"
+	"github.com/MeKo-Tech/pogo/internal/pdf"
 	"github.com/MeKo-Tech/pogo/internal/pipeline"
 	"github.com/MeKo-Tech/pogo/internal/utils"
 	"github.com/spf13/cobra"
@@ -19,9 +23,16 @@ import (
 )
 
 const (
-	outputFormatJSON = "json"
-	outputFormatCSV  = "csv"
-	outputFormatText = "text"
+	outputFormatJSON          = "json"
+	outputFormatCSV           = "csv"
+	outputFormatText          = "text"
+	outputFormatSearchablePDF = "searchable-pdf"
+	outputFormatHOCR          = "hocr"
+	outputFormatALTO          = "alto"
+	outputFormatPageXML       = "page"
+	outputFormatTextLayout    = "text-layout"
+	outputFormatMarkdown      = "markdown"
+	outputFormatNDJSON        = "ndjson"
 )
 
 // imageCmd represents the image command.
@@ -35,7 +46,11 @@ Supported formats: JPEG, PNG, BMP, TIFF
 Examples:
   pogo image photo.jpg
   pogo image *.png --format json
-  pogo image document.jpg --output results.json`,
+  pogo image document.jpg --output results.json
+  pogo image page1.png page2.png --format searchable-pdf -o scan.pdf
+  pogo image page1.png page2.png --format hocr -o scan.hocr
+  pogo image page1.png page2.png --format alto -o scan.xml
+  pogo image page1.png page2.png --format page -o pagexml/`,
 	Args:         cobra.ArbitraryArgs,
 	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -95,7 +110,7 @@ Examples:
 		}
 
 		// Validate output format
-		validFormats := []string{outputFormatText, outputFormatJSON, outputFormatCSV}
+		validFormats := []string{outputFormatText, outputFormatJSON, outputFormatCSV, outputFormatSearchablePDF, outputFormatHOCR, outputFormatALTO, outputFormatPageXML, outputFormatTextLayout, outputFormatMarkdown}
 		isValidFormat := false
 		for _, f := range validFormats {
 			if format == f {
@@ -107,6 +122,10 @@ Examples:
 			return fmt.Errorf("invalid output format: %s (must be one of: %s)", format, strings.Join(validFormats, ", "))
 		}
 
+		if format == outputFormatSearchablePDF && outputFile == "" {
+			return errors.New("searchable-pdf output requires --output")
+		}
+
 		// Validate recognition height
 		if recH < 0 {
 			return fmt.Errorf("invalid recognition height: %d (must be positive)", recH)
@@ -283,6 +302,10 @@ Examples:
 
 		cons := utils.DefaultImageConstraints()
 		var outputs []string
+		var pdfImages []image.Image
+		var pdfPages []pdf.TextLayerPage
+		var layoutResults []*pipeline.OCRImageResult
+		var layoutSources []string
 		for _, pth := range args {
 			if !utils.IsSupportedImage(pth) {
 				return fmt.Errorf("unsupported image format: %s", pth)
@@ -349,6 +372,27 @@ Examples:
 				}
 			}
 			switch format {
+			case outputFormatSearchablePDF:
+				pdfImages = append(pdfImages, img)
+				pdfPages = append(pdfPages, pipeline.ToTextLayerImage(res, len(pdfImages)))
+			case outputFormatHOCR, outputFormatALTO, outputFormatPageXML:
+				layoutResults = append(layoutResults, res)
+				layoutSources = append(layoutSources, meta.Path)
+			case outputFormatTextLayout:
+				s, err := pipeline.ToLayoutTextImage(res)
+				if err != nil {
+					return fmt.Errorf("format text-layout failed: %w", err)
+				}
+				if len(args) > 1 {
+					s = "# " + meta.Path + "\n" + s
+				}
+				outputs = append(outputs, s)
+			case outputFormatMarkdown:
+				s, err := pipeline.ToMarkdownImage(res)
+				if err != nil {
+					return fmt.Errorf("format markdown failed: %w", err)
+				}
+				outputs = append(outputs, s)
 			case outputFormatJSON:
 				obj := struct {
 					File string                   `json:"file"`
@@ -378,6 +422,33 @@ Examples:
 				outputs = append(outputs, s)
 			}
 		}
+		if format == outputFormatSearchablePDF {
+			return writeSearchableImagesPDF(cmd, outputFile, pdfImages, pdfPages)
+		}
+		switch format {
+		case outputFormatHOCR:
+			doc, err := pipeline.ToHOCRImages(layoutResults, layoutSources)
+			if err != nil {
+				return fmt.Errorf("format hocr failed: %w", err)
+			}
+			outputs = append(outputs, doc)
+		case outputFormatALTO:
+			doc, err := pipeline.ToALTOImages(layoutResults, layoutSources, pl.ExportMetadata())
+			if err != nil {
+				return fmt.Errorf("format alto failed: %w", err)
+			}
+			outputs = append(outputs, doc)
+		case outputFormatPageXML:
+			docs := make([]string, len(layoutResults))
+			names := make([]string, len(layoutResults))
+			for i, res := range layoutResults {
+				if docs[i], err = pipeline.ToPageXMLImage(res, layoutSources[i], pl.ExportMetadata()); err != nil {
+					return fmt.Errorf("format page failed: %w", err)
+				}
+				names[i] = pageXMLName(layoutSources[i], 0)
+			}
+			return writePageXML(cmd.OutOrStdout(), outputFile, names, docs)
+		}
 		final := strings.Join(outputs, "")
 		if outputFile != "" {
 			if err := os.WriteFile(outputFile, []byte(final), 0o600); err != nil {
@@ -395,6 +466,75 @@ Examples:
 	},
 }
 
+// writePageXML writes PAGE XML documents, which describe one page each. A
+// single document is written to output, or stdout without it; several are
+// written into output, which must then be an existing directory, as names.
+func writePageXML(w io.Writer, output string, names, docs []string) error {
+	st, err := os.Stat(output)
+	isDir := err == nil && st.IsDir()
+	if len(docs) == 1 && !isDir {
+		if output == "" {
+			_, err := fmt.Fprint(w, docs[0])
+			return err
+		}
+		if err := os.WriteFile(output, []byte(docs[0]), 0o600); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		_, err := fmt.Fprintf(w, "Results written to %s\n", output)
+		return err
+	}
+	if !isDir {
+		return fmt.Errorf("--output must be an existing directory when writing %d PAGE XML documents", len(docs))
+	}
+	for i, doc := range docs {
+		path := filepath.Join(output, names[i])
+		if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		if _, err := fmt.Fprintf(w, "PAGE XML written to %s\n", path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pageXMLName derives the PAGE XML file name for an input, e.g. scan.png ->
+// scan.xml, or scan.pdf -> scan_p3.xml for page 3 of a PDF.
+func pageXMLName(input string, page int) string {
+	base := filepath.Base(input)
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	if page > 0 {
+		name += "_p" + strconv.Itoa(page)
+	}
+	return name + ".xml"
+}
+
+// writeSearchableImagesPDF writes one page per image with an invisible text layer.
+func writeSearchableImagesPDF(cmd *cobra.Command, outputFile string, images []image.Image, pages []pdf.TextLayerPage) error {
+	opts := searchableOptionsFromFlags(cmd)
+	f, err := os.Create(outputFile) //nolint:gosec // G304: Creating output file with user-controlled path
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if err := pdf.WriteSearchablePDFFromImages(f, images, pages, opts); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "Searchable PDF written to %s\n", outputFile)
+	return err
+}
+
+// searchableOptionsFromFlags reads the searchable PDF flags shared by the image and pdf commands.
+func searchableOptionsFromFlags(cmd *cobra.Command) pdf.SearchableOptions {
+	var opts pdf.SearchableOptions
+	opts.PDFA, _ = cmd.Flags().GetBool("pdfa")
+	opts.DPI, _ = cmd.Flags().GetInt("pdf-dpi")
+	return opts
+}
+
 // parseMemorySize parses memory size strings like "2GB", "512MB", "1024".
 func parseMemorySize(s string) (uint64, error) {
 	if s == "" {
@@ -436,8 +576,10 @@ func parseMemorySize(s string) (uint64, error) {
 
 func addImageFlags(cmd *cobra.Command) {
 	// Image-specific flags
-	cmd.Flags().StringP("format", "f", "text", "output format (text, json, csv)")
+	cmd.Flags().StringP("format", "f", "text", "output format (text, text-layout, markdown, json, csv, searchable-pdf, hocr, alto, page)")
 	cmd.Flags().StringP("output", "o", "", "output file (default: stdout)")
+	cmd.Flags().Bool("pdfa", false, "write PDF/A-2b compatible output for searchable-pdf")
+	cmd.Flags().Int("pdf-dpi", pdf.DefaultSearchableDPI, "image resolution used to size searchable-pdf pages")
 	cmd.Flags().Float64("confidence", 0.5, "minimum confidence threshold")
 	cmd.Flags().Bool("detect-orientation", false, "enable document orientation detection")
 	cmd.Flags().Float64("orientation-threshold", 0.7, "orientation confidence threshold (0..1)")
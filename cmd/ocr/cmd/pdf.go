@@ -1,10 +1,14 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -12,6 +16,7 @@ import (
 	"github.com/MeKo-Tech/pogo/internal/detector"
 	"github.com/MeKo-Tech/pogo/internal/models"
 	"github.com/MeKo-Tech/pogo/internal/pdf"
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
 	"github.com/spf13/cobra"
 )
 
@@ -27,7 +32,13 @@ Works best with scanned PDFs or PDFs containing image-based text.
 Examples:
   pogo pdf document.pdf
   pogo pdf *.pdf --format json
-  pogo pdf scan.pdf --pages 1-5`,
+  pogo pdf scan.pdf --pages 1-5
+  pogo pdf scan.pdf --format searchable-pdf -o scan-ocr.pdf
+  pogo pdf scan.pdf --format hocr -o scan.hocr
+  pogo pdf scan.pdf --format alto -o scan.xml
+  pogo pdf scan.pdf --format page -o pagexml/
+  pogo pdf invoice.pdf --format text-layout
+  pogo pdf large.pdf --format ndjson --pdf-max-pages-in-flight 2`,
 	Args:         cobra.ArbitraryArgs,
 	SilenceUsage: true,
 	RunE:         processPDFs,
@@ -37,8 +48,9 @@ func init() {
 	rootCmd.AddCommand(pdfCmd)
 
 	// PDF-specific flags
-	pdfCmd.Flags().StringP("format", "f", "text", "output format (text, json, csv)")
-	pdfCmd.Flags().StringP("output", "o", "", "output file (default: stdout)")
+	pdfCmd.Flags().StringP("format", "f", "text", "output format (text, text-layout, markdown, json, csv, searchable-pdf, hocr, alto, page, ndjson)")
+	pdfCmd.Flags().StringP("output", "o", "", "output file (default: stdout; a directory for searchable-pdf with several inputs and page with several pages)")
+	pdfCmd.Flags().Bool("pdfa", false, "write PDF/A-2b compatible output for searchable-pdf")
 	pdfCmd.Flags().String("pages", "", "page range to process (e.g., '1-5', '1,3,5')")
 	pdfCmd.Flags().Float64("confidence", 0.5, "minimum detection confidence threshold")
 	pdfCmd.Flags().StringP("language", "l", "en", "recognition language")
@@ -90,6 +102,14 @@ func init() {
 	pdfCmd.Flags().Int("barcode-min-size", 0, "minimum expected barcode size in pixels (hint; affects render DPI)")
 	pdfCmd.Flags().Int("barcode-dpi", 150, "target DPI for barcode decoding (page image scaling)")
 	pdfCmd.Flags().Int("pdf-workers", 0, "max worker goroutines for page processing (0=NumCPU)")
+	pdfCmd.Flags().Int("pdf-max-pages-in-flight", 0, "max pages held in memory at once (0=one per worker)")
+
+	// Page rendering
+	pdfCmd.Flags().String("pdf-mode", pdf.PageModeRender, "how pages become OCR input: render (rasterize whole pages) or extract (embedded images only)")
+	pdfCmd.Flags().Int("render-dpi", pdf.DefaultRenderDPI, "resolution for page rendering")
+	pdfCmd.Flags().String("render-command", "",
+		"external rasterizer command line with {input}, {page}, {dpi}, {output} placeholders "+
+			"(e.g. 'mutool draw -q -r {dpi} -o {output} {input} {page}'); default: built-in renderer")
 }
 
 // Ensure pdf help mentions docs for multi-scale.
@@ -162,6 +182,12 @@ type pdfConfig struct {
 	barcodeMinSize int
 	barcodeDPI     int
 	pdfWorkers     int
+	maxInFlight    int
+
+	// Page rendering
+	pdfMode       string
+	renderDPI     int
+	renderCommand string
 }
 
 // configToPDFConfig maps centralized configuration to pdfConfig.
@@ -250,6 +276,11 @@ func configToPDFConfig(centralCfg *config.Config, cmd *cobra.Command) (*pdfConfi
 	// Store into unused field via return path by extending buildEnhancedPDFProcessor
 	// Will be passed to ProcessorConfig.MaxWorkers
 	cfg.pdfWorkers, _ = cmd.Flags().GetInt("pdf-workers")
+	cfg.maxInFlight, _ = cmd.Flags().GetInt("pdf-max-pages-in-flight")
+
+	cfg.pdfMode, _ = cmd.Flags().GetString("pdf-mode")
+	cfg.renderDPI, _ = cmd.Flags().GetInt("render-dpi")
+	cfg.renderCommand, _ = cmd.Flags().GetString("render-command")
 
 	// Multi-scale detection defaults from central config
 	cfg.multiScaleEnabled = centralCfg.Pipeline.Detector.MultiScale.Enabled
@@ -297,6 +328,7 @@ func validatePDFConfig(cfg *pdfConfig) error {
 		validateTextlineThreshold,
 		validateRectifyThresholds,
 		validateMultiScale,
+		validatePageRendering,
 	}
 
 	for _, validator := range validators {
@@ -308,6 +340,28 @@ func validatePDFConfig(cfg *pdfConfig) error {
 	return nil
 }
 
+// validatePageRendering validates the page mode and render resolution.
+func validatePageRendering(cfg *pdfConfig) error {
+	switch cfg.pdfMode {
+	case "", pdf.PageModeRender, pdf.PageModeExtract:
+	default:
+		return fmt.Errorf("invalid pdf-mode: %s (must be %s or %s)", cfg.pdfMode, pdf.PageModeRender, pdf.PageModeExtract)
+	}
+	if cfg.renderDPI < 0 || cfg.renderDPI > 1200 {
+		return fmt.Errorf("invalid render-dpi: %d (must be between 1 and 1200)", cfg.renderDPI)
+	}
+	return nil
+}
+
+// pageRenderer returns the external renderer configured with --render-command, or nil.
+func pageRenderer(cfg *pdfConfig) pdf.PageRenderer {
+	fields := strings.Fields(cfg.renderCommand)
+	if len(fields) == 0 {
+		return nil
+	}
+	return pdf.CommandRenderer{Command: fields[0], Args: fields[1:]}
+}
+
 // validateMultiScale validates multi-scale parameters when enabled.
 func validateMultiScale(cfg *pdfConfig) error {
 	if !cfg.multiScaleEnabled {
@@ -341,9 +395,12 @@ func validateConfidenceThreshold(cfg *pdfConfig) error {
 
 // validateOutputFormat validates the output format.
 func validateOutputFormat(cfg *pdfConfig) error {
-	validFormats := []string{"text", "json", "csv"}
+	validFormats := []string{"text", "json", "csv", outputFormatSearchablePDF, outputFormatHOCR, outputFormatALTO, outputFormatPageXML, outputFormatTextLayout, outputFormatMarkdown, outputFormatNDJSON}
 	for _, f := range validFormats {
 		if cfg.format == f {
+			if f == outputFormatSearchablePDF && cfg.outputFile == "" {
+				return errors.New("searchable-pdf output requires --output")
+			}
 			return nil
 		}
 	}
@@ -410,8 +467,20 @@ func processPDFs(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// NDJSON output is machine-readable from the first line on.
+	if cfg.format == outputFormatNDJSON {
+		return writeNDJSONPDFs(cfg, args)
+	}
+
 	fmt.Printf("Processing %d PDF(s): %v\n", len(args), args)
 
+	switch cfg.format {
+	case outputFormatSearchablePDF:
+		return writeSearchablePDFs(cmd, cfg, args)
+	case outputFormatHOCR, outputFormatALTO, outputFormatPageXML, outputFormatTextLayout, outputFormatMarkdown:
+		return writePipelinePDFs(cfg, args)
+	}
+
 	// Build enhanced PDF processor
 	processor, err := buildEnhancedPDFProcessor(cfg)
 	if err != nil {
@@ -654,6 +723,233 @@ func validateSinglePage(part string) error {
 	return nil
 }
 
+// writeSearchablePDFs runs full OCR (detection and recognition) on every input and
+// writes a copy of each PDF with an invisible text layer.
+func writeSearchablePDFs(cmd *cobra.Command, cfg *pdfConfig, args []string) error {
+	outputs, err := searchablePDFOutputPaths(cfg.outputFile, args)
+	if err != nil {
+		return err
+	}
+
+	pl, err := buildPDFPipeline(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to build OCR pipeline: %w", err)
+	}
+	defer func() { _ = pl.Close() }()
+
+	opts := searchableOptionsFromFlags(cmd)
+	for i, file := range args {
+		res, err := pl.ProcessPDF(file, cfg.pages)
+		if err != nil {
+			return fmt.Errorf("OCR failed for %s: %w", file, err)
+		}
+		f, err := os.Create(outputs[i]) //nolint:gosec // G304: Creating output file with user-controlled path
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		if err := pdf.WriteSearchablePDF(f, file, pipeline.ToTextLayerPDF(res), opts); err != nil {
+			_ = f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		fmt.Printf("Searchable PDF written to %s\n", outputs[i])
+	}
+	return nil
+}
+
+// writePipelinePDFs runs the full OCR pipeline on every input and writes the
+// results in a format that needs recognized text (hocr, alto, page, text-layout,
+// markdown).
+func writePipelinePDFs(cfg *pdfConfig, args []string) error {
+	pl, err := buildPDFPipeline(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to build OCR pipeline: %w", err)
+	}
+	defer func() { _ = pl.Close() }()
+
+	results := make([]*pipeline.OCRPDFResult, 0, len(args))
+	for _, file := range args {
+		res, err := pl.ProcessPDF(file, cfg.pages)
+		if err != nil {
+			return fmt.Errorf("OCR failed for %s: %w", file, err)
+		}
+		results = append(results, res)
+	}
+
+	if cfg.format == outputFormatPageXML {
+		var names, docs []string
+		for _, res := range results {
+			pageDocs, err := pipeline.ToPageXMLPDF(res, pl.ExportMetadata())
+			if err != nil {
+				return fmt.Errorf("failed to format output: %w", err)
+			}
+			for i, doc := range pageDocs {
+				names = append(names, pageXMLName(res.Filename, res.Pages[i].PageNumber))
+				docs = append(docs, doc)
+			}
+		}
+		return writePageXML(os.Stdout, cfg.outputFile, names, docs)
+	}
+	doc, err := formatPipelinePDFs(results, cfg.format, pl.ExportMetadata())
+	if err != nil {
+		return fmt.Errorf("failed to format output: %w", err)
+	}
+	if cfg.outputFile != "" {
+		if err := os.WriteFile(cfg.outputFile, []byte(doc), 0o600); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		fmt.Printf("Results written to %s\n", cfg.outputFile)
+		return nil
+	}
+	fmt.Print(doc)
+	return nil
+}
+
+// writeNDJSONPDFs runs the full OCR pipeline on every input and writes one
+// JSON line per page as soon as it is recognized, followed by a document line
+// per file, so that large PDFs never have to be held in memory as a whole.
+func writeNDJSONPDFs(cfg *pdfConfig, args []string) error {
+	pl, err := buildPDFPipeline(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to build OCR pipeline: %w", err)
+	}
+	defer func() { _ = pl.Close() }()
+
+	out := io.Writer(os.Stdout)
+	if cfg.outputFile != "" {
+		f, err := os.OpenFile(cfg.outputFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		out = f
+	}
+	bw := bufio.NewWriter(out)
+	sw := pipeline.NewPDFStreamWriter(bw)
+
+	for _, file := range args {
+		res, err := pl.ProcessPDFStream(context.Background(), file, cfg.pages, func(page *pipeline.OCRPDFPageResult) error {
+			return sw.WritePage(file, page)
+		})
+		if err != nil {
+			_ = sw.WriteError(file, err)
+			return fmt.Errorf("OCR failed for %s: %w", file, err)
+		}
+		if err := sw.WriteDocument(res); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+	return bw.Flush()
+}
+
+// formatPipelinePDFs formats full pipeline results as hocr, alto, text-layout or
+// markdown; meta describes the pipeline for alto.
+func formatPipelinePDFs(results []*pipeline.OCRPDFResult, format string, meta pipeline.ExportMetadata) (string, error) {
+	switch format {
+	case outputFormatHOCR:
+		return pipeline.ToHOCRPDFs(results)
+	case outputFormatALTO:
+		return pipeline.ToALTOPDFs(results, meta)
+	}
+	render, sep := pipeline.ToLayoutTextPDF, "\f"
+	if format == outputFormatMarkdown {
+		render, sep = pipeline.ToMarkdownPDF, "\n---\n\n"
+	}
+	docs := make([]string, 0, len(results))
+	for _, res := range results {
+		doc, err := render(res)
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, doc)
+	}
+	return strings.Join(docs, sep), nil
+}
+
+// searchablePDFOutputPaths maps inputs to output files. A single input is written
+// to output; several inputs require output to be a directory.
+func searchablePDFOutputPaths(output string, args []string) ([]string, error) {
+	if len(args) == 1 {
+		if st, err := os.Stat(output); err == nil && st.IsDir() {
+			return []string{filepath.Join(output, searchablePDFName(args[0]))}, nil
+		}
+		return []string{output}, nil
+	}
+	st, err := os.Stat(output)
+	if err != nil || !st.IsDir() {
+		return nil, fmt.Errorf("--output must be an existing directory when writing %d searchable PDFs", len(args))
+	}
+	paths := make([]string, len(args))
+	for i, file := range args {
+		paths[i] = filepath.Join(output, searchablePDFName(file))
+	}
+	return paths, nil
+}
+
+// searchablePDFName derives the output file name for an input, e.g. scan.pdf -> scan_ocr.pdf.
+func searchablePDFName(input string) string {
+	base := filepath.Base(input)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + "_ocr.pdf"
+}
+
+// buildPDFPipeline creates a full OCR pipeline from the PDF configuration.
+func buildPDFPipeline(cfg *pdfConfig) (*pipeline.Pipeline, error) {
+	b := pipeline.NewBuilder().WithModelsDir(cfg.modelsDir).WithLanguage(cfg.lang)
+	b = b.WithDetectorThresholds(pipeline.DefaultConfig().Detector.DbThresh, float32(cfg.detConf))
+	if cfg.detModel != "" {
+		b = b.WithDetectorModelPath(cfg.detModel)
+	}
+	if cfg.recModel != "" {
+		b = b.WithRecognizerModelPath(cfg.recModel)
+	}
+	if cfg.recH > 0 {
+		b = b.WithImageHeight(cfg.recH)
+	}
+	if cfg.dictCSV != "" {
+		b = b.WithDictionaryPaths(strings.Split(cfg.dictCSV, ","))
+	}
+	if cfg.dictLangs != "" {
+		if paths := models.GetDictionaryPathsForLanguages(cfg.modelsDir, strings.Split(cfg.dictLangs, ",")); len(paths) > 0 {
+			b = b.WithDictionaryPaths(paths)
+		}
+	}
+	if cfg.filterDictCSV != "" {
+		b = b.WithFilterDictionaryPaths(strings.Split(cfg.filterDictCSV, ","))
+	}
+	if cfg.filterDictLangs != "" {
+		if paths := models.GetDictionaryPathsForLanguages(cfg.modelsDir, strings.Split(cfg.filterDictLangs, ",")); len(paths) > 0 {
+			b = b.WithFilterDictionaryPaths(paths)
+		}
+	}
+	if cfg.detectOrientation {
+		b = b.WithOrientation(true).WithOrientationThreshold(cfg.orientThresh)
+	}
+	if cfg.detectTextline {
+		b = b.WithTextLineOrientation(true).WithTextLineOrientationThreshold(cfg.textlineThresh)
+	}
+	if cfg.rectify {
+		b = b.WithRectification(true).
+			WithRectifyModelPath(cfg.rectifyModel).
+			WithRectifyMaskThreshold(cfg.rectifyMask).
+			WithRectifyOutputHeight(cfg.rectifyHeight).
+			WithRectifyDebugDir(cfg.rectifyDebugDir)
+	}
+	if cfg.multiScaleEnabled {
+		b = b.WithDetectorMultiScale(cfg.msScales).
+			WithDetectorMultiScaleIoU(cfg.msMergeIoU).
+			WithDetectorMultiScaleAdaptive(cfg.msAdaptive, cfg.msMaxLevels, cfg.msMinSide).
+			WithDetectorMultiScaleIncrementalMerge(cfg.msIncremental)
+	}
+	if cfg.pdfWorkers > 0 {
+		b = b.WithMaxGoroutines(cfg.pdfWorkers)
+	}
+	b = b.WithPDFMaxInFlightPages(cfg.maxInFlight)
+	b = b.WithPDFMode(cfg.pdfMode).WithPDFRenderDPI(cfg.renderDPI).WithPDFRenderer(pageRenderer(cfg))
+	return b.Build()
+}
+
 // buildEnhancedPDFProcessor creates an enhanced PDF processor with the given configuration.
 func buildEnhancedPDFProcessor(cfg *pdfConfig) (*pdf.Processor, error) {
 	// Create detector configuration
@@ -712,7 +1008,11 @@ func buildEnhancedPDFProcessor(cfg *pdfConfig) (*pdf.Processor, error) {
 			}
 			return 150
 		}(),
-		MaxWorkers: cfg.pdfWorkers,
+		MaxWorkers:       cfg.pdfWorkers,
+		MaxInFlightPages: cfg.maxInFlight,
+		PageMode:         cfg.pdfMode,
+		RenderDPI:        cfg.renderDPI,
+		Renderer:         pageRenderer(cfg),
 	}
 
 	// Create enhanced processor
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MeKo-Tech/pogo/internal/pdf"
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateOutputFormat_SearchablePDF(t *testing.T) {
+	err := validateOutputFormat(&pdfConfig{format: outputFormatSearchablePDF})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "requires --output")
+
+	require.NoError(t, validateOutputFormat(&pdfConfig{format: outputFormatSearchablePDF, outputFile: "out.pdf"}))
+	require.Error(t, validateOutputFormat(&pdfConfig{format: "xml"}))
+	require.NoError(t, validateOutputFormat(&pdfConfig{format: outputFormatNDJSON}))
+}
+
+func TestSearchablePDFOutputPaths(t *testing.T) {
+	dir := t.TempDir()
+
+	paths, err := searchablePDFOutputPaths("out.pdf", []string{"in/scan.pdf"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"out.pdf"}, paths)
+
+	paths, err = searchablePDFOutputPaths(dir, []string{"in/scan.pdf"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "scan_ocr.pdf")}, paths)
+
+	paths, err = searchablePDFOutputPaths(dir, []string{"a.pdf", "b/c.PDF"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "a_ocr.pdf"), filepath.Join(dir, "c_ocr.pdf")}, paths)
+
+	_, err = searchablePDFOutputPaths("out.pdf", []string{"a.pdf", "b.pdf"})
+	require.Error(t, err)
+}
+
+func TestFormatPipelinePDFs(t *testing.T) {
+	page := func(text string) pipeline.OCRPDFPageResult {
+		return pipeline.OCRPDFPageResult{PageNumber: 1, Width: 100, Height: 100, Images: []pipeline.OCRPDFImageResult{{
+			Width: 100, Height: 100, Regions: []pipeline.OCRRegionResult{
+				{Text: text, Box: struct{ X, Y, W, H int }{X: 0, Y: 0, W: 40, H: 10}},
+			},
+		}}}
+	}
+	results := []*pipeline.OCRPDFResult{
+		{Pages: []pipeline.OCRPDFPageResult{page("one"), page("two")}},
+		{Pages: []pipeline.OCRPDFPageResult{page("three")}},
+	}
+
+	out, err := formatPipelinePDFs(results, outputFormatTextLayout, pipeline.ExportMetadata{})
+	require.NoError(t, err)
+	assert.Equal(t, "one\n\ftwo\n\fthree\n", out)
+
+	out, err = formatPipelinePDFs(results, outputFormatMarkdown, pipeline.ExportMetadata{})
+	require.NoError(t, err)
+	assert.Equal(t, "one\n\n---\n\ntwo\n\n---\n\nthree\n", out)
+
+	out, err = formatPipelinePDFs(results, outputFormatHOCR, pipeline.ExportMetadata{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(out, `class="ocr_page"`))
+
+	out, err = formatPipelinePDFs(results, outputFormatALTO, pipeline.ExportMetadata{RecognitionModel: "rec.onnx"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(out, "<Page "))
+	assert.Contains(t, out, "recognition-model=rec.onnx")
+}
+
+func TestWritePageXML(t *testing.T) {
+	dir := t.TempDir()
+	var out strings.Builder
+
+	// A single document goes to the output file
+	file := filepath.Join(dir, "one.xml")
+	require.NoError(t, writePageXML(&out, file, []string{"scan.xml"}, []string{"<doc/>"}))
+	data, err := os.ReadFile(file) //nolint:gosec // test-controlled path
+	require.NoError(t, err)
+	assert.Equal(t, "<doc/>", string(data))
+
+	// Several documents need a directory
+	names := []string{pageXMLName("in/scan.pdf", 1), pageXMLName("in/scan.pdf", 2)}
+	assert.Equal(t, []string{"scan_p1.xml", "scan_p2.xml"}, names)
+	err = writePageXML(&out, file, names, []string{"<p1/>", "<p2/>"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "existing directory")
+	require.NoError(t, writePageXML(&out, dir, names, []string{"<p1/>", "<p2/>"}))
+	data, err = os.ReadFile(filepath.Join(dir, "scan_p2.xml")) //nolint:gosec // test-controlled path
+	require.NoError(t, err)
+	assert.Equal(t, "<p2/>", string(data))
+
+	out.Reset()
+	require.NoError(t, writePageXML(&out, "", []string{pageXMLName("a.png", 0)}, []string{"<doc/>"}))
+	assert.Equal(t, "<doc/>", out.String())
+}
+
+func TestValidatePageRendering(t *testing.T) {
+	require.NoError(t, validatePageRendering(&pdfConfig{pdfMode: "render", renderDPI: 200}))
+	require.NoError(t, validatePageRendering(&pdfConfig{pdfMode: "extract"}))
+	require.Error(t, validatePageRendering(&pdfConfig{pdfMode: "vector"}))
+	require.Error(t, validatePageRendering(&pdfConfig{pdfMode: "render", renderDPI: 5000}))
+}
+
+func TestPageRenderer(t *testing.T) {
+	assert.Nil(t, pageRenderer(&pdfConfig{}))
+	r := pageRenderer(&pdfConfig{renderCommand: "mutool draw -r {dpi} -o {output} {input} {page}"})
+	assert.Equal(t, pdf.CommandRenderer{
+		Command: "mutool",
+		Args:    []string{"draw", "-r", "{dpi}", "-o", "{output}", "{input}", "{page}"},
+	}, r)
+}
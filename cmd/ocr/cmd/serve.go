@@ -2,18 +2,26 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/MeKo-Tech/pogo/internal/config"
+	"github.com/MeKo-Tech/pogo/internal/jobs"
 	"github.com/MeKo-Tech/pogo/internal/models"
 	"github.com/MeKo-Tech/pogo/internal/pipeline"
 	"github.com/MeKo-Tech/pogo/internal/server"
+	"github.com/MeKo-Tech/pogo/internal/tracing"
+	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/cobra"
 )
 
@@ -26,15 +34,41 @@ var serveCmd = &cobra.Command{
 The server provides the following endpoints:
   POST /ocr/image - Process uploaded images
   GET  /health    - Health check endpoint
-  GET  /models    - List available models
+  GET  /livez, /readyz, /startupz - Probes; add ?verbose for diagnostics
+  GET  /models    - List available models and model profiles
+  POST /jobs      - Queue an asynchronous job (requires --jobs-dir)
+  GET  /usage     - API key usage and limits (requires --api-keys)
+  GET  /admin/pipelines - List cached pipelines (DELETE evicts them)
+  POST /admin/reload    - Reload the pipeline configuration (GET shows status)
+
+With --grpc-port, the gRPC service pogo.v1.OCRService (api/pogo/v1/ocr.proto)
+is served on a second port, with health checking and reflection.
+
+With --trace-exporter, OpenTelemetry spans of requests and pipeline stages are
+exported over OTLP; incoming W3C trace context headers are honored.
+
+Models are loaded and warmed up (--warmup-iterations) after the server starts
+listening; /startupz and /readyz report ready once that is done, and OCR
+requests are answered with 503 until then. A self-test OCRs a built-in image
+every --self-test-interval; /readyz fails while it fails, and /livez after
+--liveness-failures failures in a row.
+
+SIGHUP or POST /admin/reload re-read the config file (--watch-config: any
+change to it) and replace the pipeline with one built from it, warmed up and
+self-tested, without dropping requests: running requests finish on the old
+pipeline, which is closed afterwards. An invalid configuration is rejected and
+the running pipeline kept. Flags keep precedence over the file; server
+settings such as ports need a restart.
 
 Examples:
   pogo serve
   pogo serve --port 8080
-  pogo serve --host 0.0.0.0 --port 3000`,
+  pogo serve --host 0.0.0.0 --port 3000
+  pogo serve --port 8080 --grpc-port 9090`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Get configuration from centralized system (includes CLI flags, config file, env vars, and defaults)
 		cfg := GetConfig()
+		configFile := GetConfigLoader().GetConfigFileUsed()
 
 		// Extract server configuration with CLI flag overrides
 		host := cfg.Server.Host
@@ -108,77 +142,6 @@ Examples:
 			maxDataPerDay, _ = cmd.Flags().GetInt64("max-data-per-day")
 		}
 
-		// Extract pipeline configuration with CLI flag overrides
-		language := cfg.Pipeline.Recognizer.Language
-		if cmd.Flags().Changed("language") {
-			language, _ = cmd.Flags().GetString("language")
-		}
-
-		detModel := cfg.Pipeline.Detector.ModelPath
-		if cmd.Flags().Changed("det-model") {
-			detModel, _ = cmd.Flags().GetString("det-model")
-		}
-
-		recModel := cfg.Pipeline.Recognizer.ModelPath
-		if cmd.Flags().Changed("rec-model") {
-			recModel, _ = cmd.Flags().GetString("rec-model")
-		}
-
-		minDetConf := float64(cfg.Pipeline.Detector.DbBoxThresh)
-		if cmd.Flags().Changed("min-det-conf") {
-			minDetConf, _ = cmd.Flags().GetFloat64("min-det-conf")
-		}
-
-		// Multi-scale detection options
-		msEnabled := cfg.Pipeline.Detector.MultiScale.Enabled
-		if cmd.Flags().Changed("det-multiscale") {
-			msEnabled, _ = cmd.Flags().GetBool("det-multiscale")
-		}
-		msScales := cfg.Pipeline.Detector.MultiScale.Scales
-		if cmd.Flags().Changed("det-scales") {
-			msScales, _ = cmd.Flags().GetFloat64Slice("det-scales")
-		}
-		msMergeIoU := cfg.Pipeline.Detector.MultiScale.MergeIoU
-		if cmd.Flags().Changed("det-merge-iou") {
-			msMergeIoU, _ = cmd.Flags().GetFloat64("det-merge-iou")
-		}
-		msAdaptive := cfg.Pipeline.Detector.MultiScale.Adaptive
-		if cmd.Flags().Changed("det-ms-adaptive") {
-			msAdaptive, _ = cmd.Flags().GetBool("det-ms-adaptive")
-		}
-		msMaxLevels := cfg.Pipeline.Detector.MultiScale.MaxLevels
-		if cmd.Flags().Changed("det-ms-max-levels") {
-			msMaxLevels, _ = cmd.Flags().GetInt("det-ms-max-levels")
-		}
-		msMinSide := cfg.Pipeline.Detector.MultiScale.MinSide
-		if cmd.Flags().Changed("det-ms-min-side") {
-			msMinSide, _ = cmd.Flags().GetInt("det-ms-min-side")
-		}
-		msIncr := cfg.Pipeline.Detector.MultiScale.IncrementalMerge
-		if cmd.Flags().Changed("det-ms-incremental-merge") {
-			msIncr, _ = cmd.Flags().GetBool("det-ms-incremental-merge")
-		}
-
-		orientEnable := cfg.Features.OrientationEnabled
-		if cmd.Flags().Changed("detect-orientation") {
-			orientEnable, _ = cmd.Flags().GetBool("detect-orientation")
-		}
-
-		orientThresh := cfg.Features.OrientationThreshold
-		if cmd.Flags().Changed("orientation-threshold") {
-			orientThresh, _ = cmd.Flags().GetFloat64("orientation-threshold")
-		}
-
-		textlineEnable := cfg.Features.TextlineEnabled
-		if cmd.Flags().Changed("detect-textline") {
-			textlineEnable, _ = cmd.Flags().GetBool("detect-textline")
-		}
-
-		textlineThresh := cfg.Features.TextlineThreshold
-		if cmd.Flags().Changed("textline-threshold") {
-			textlineThresh, _ = cmd.Flags().GetFloat64("textline-threshold")
-		}
-
 		// Barcode DPI default for enhanced PDF path
 		barcodeDPI := 150
 		if cmd.Flags().Changed("barcode-dpi") {
@@ -189,123 +152,117 @@ Examples:
 		if cmd.Flags().Changed("pdf-workers") {
 			pdfWorkers, _ = cmd.Flags().GetInt("pdf-workers")
 		}
-
-		dictCSV := cfg.Pipeline.Recognizer.DictPath
-		if cmd.Flags().Changed("dict") {
-			dictCSV, _ = cmd.Flags().GetString("dict")
+		processingTimeout, _ := cmd.Flags().GetDuration("processing-timeout")
+		maxBatchItems, _ := cmd.Flags().GetInt("max-batch-items")
+		jobsDir, _ := cmd.Flags().GetString("jobs-dir")
+		jobWorkers, _ := cmd.Flags().GetInt("job-workers")
+		jobRetention, _ := cmd.Flags().GetDuration("job-retention")
+		maxQueuedJobs, _ := cmd.Flags().GetInt("max-queued-jobs")
+		webhookSecret, _ := cmd.Flags().GetString("webhook-secret")
+		if webhookSecret == "" {
+			webhookSecret = os.Getenv("POGO_WEBHOOK_SECRET")
+		}
+		publicURL, _ := cmd.Flags().GetString("public-url")
+		webhookAttempts, _ := cmd.Flags().GetInt("webhook-max-attempts")
+		webhookBackoff, _ := cmd.Flags().GetDuration("webhook-backoff")
+		webhookTimeout, _ := cmd.Flags().GetDuration("webhook-timeout")
+		deadLetterPath, _ := cmd.Flags().GetString("webhook-dead-letter")
+		if deadLetterPath == "" && jobsDir != "" {
+			deadLetterPath = filepath.Join(jobsDir, "webhooks-dead-letter.ndjson")
+		}
+		webhookAllowHosts, _ := cmd.Flags().GetStringSlice("webhook-allow-hosts")
+		webhookDenyHosts, _ := cmd.Flags().GetStringSlice("webhook-deny-hosts")
+		webhookNetworks, _ := cmd.Flags().GetStringSlice("webhook-allow-networks")
+		webhookAllowNetworks, err := parseNetworks("webhook-allow-networks", webhookNetworks)
+		if err != nil {
+			return err
+		}
+		rateLimitState, _ := cmd.Flags().GetString("rate-limit-state")
+		rateLimitSnapshot, _ := cmd.Flags().GetDuration("rate-limit-snapshot-interval")
+		var apiKeys *server.APIKeys
+		if apiKeysPath, _ := cmd.Flags().GetString("api-keys"); apiKeysPath != "" {
+			var err error
+			if apiKeys, err = server.LoadAPIKeys(apiKeysPath); err != nil {
+				return err
+			}
+			slog.Info("API key authentication enabled", "keys", apiKeys.Names())
 		}
-
-		dictLangs := cfg.Pipeline.Recognizer.DictLangs
-		if cmd.Flags().Changed("dict-langs") {
-			dictLangs, _ = cmd.Flags().GetString("dict-langs")
+		var modelProfiles *server.ModelProfiles
+		if profilesPath, _ := cmd.Flags().GetString("model-profiles"); profilesPath != "" {
+			var err error
+			if modelProfiles, err = server.LoadModelProfiles(profilesPath); err != nil {
+				return err
+			}
+			slog.Info("Model profiles loaded", "profiles", modelProfiles.Names())
+		}
+		allowModelPaths, _ := cmd.Flags().GetBool("allow-model-paths")
+		maxConcurrent, _ := cmd.Flags().GetInt("max-concurrent")
+		maxConcurrentBulk, _ := cmd.Flags().GetInt("max-concurrent-bulk")
+		queueInteractive, _ := cmd.Flags().GetInt("queue-interactive")
+		queueBulk, _ := cmd.Flags().GetInt("queue-bulk")
+		queueWaitInteractive, _ := cmd.Flags().GetDuration("queue-wait-interactive")
+		queueWaitBulk, _ := cmd.Flags().GetDuration("queue-wait-bulk")
+		memoryLimitMB, _ := cmd.Flags().GetInt64("memory-limit")
+		if maxConcurrent < 0 || maxConcurrentBulk < 0 || queueInteractive < 0 || queueBulk < 0 || memoryLimitMB < 0 {
+			return errors.New("admission control limits must not be negative")
+		}
+		fetchConfig, err := fetchConfigFromFlags(cmd)
+		if err != nil {
+			return err
 		}
-
-		filterDictCSV := cfg.Pipeline.Recognizer.FilterDictPath
-		if cmd.Flags().Changed("filter-dict") {
-			filterDictCSV, _ = cmd.Flags().GetString("filter-dict")
+		traceConfig, err := traceConfigFromFlags(cmd)
+		if err != nil {
+			return err
 		}
-
-		filterDictLangs := cfg.Pipeline.Recognizer.FilterDictLangs
-		if cmd.Flags().Changed("filter-dict-langs") {
-			filterDictLangs, _ = cmd.Flags().GetString("filter-dict-langs")
+		probeConfig, err := probeConfigFromFlags(cmd, cfg.Pipeline.WarmupIterations)
+		if err != nil {
+			return err
+		}
+		pipelineCacheMB, _ := cmd.Flags().GetInt64("pipeline-cache-memory")
+		pipelineCacheIdle, _ := cmd.Flags().GetDuration("pipeline-cache-idle")
+		if pipelineCacheMB < 0 {
+			return fmt.Errorf("invalid pipeline cache memory: %d MB", pipelineCacheMB)
 		}
 
 		// Validate port number
 		if port < 1 || port > 65535 {
 			return fmt.Errorf("invalid port number: %d (must be between 1 and 65535)", port)
 		}
+		grpcPort, _ := cmd.Flags().GetInt("grpc-port")
+		if grpcPort < 0 || grpcPort > 65535 {
+			return fmt.Errorf("invalid gRPC port number: %d (must be between 1 and 65535, or 0 to disable)", grpcPort)
+		}
+		if grpcPort == port {
+			return fmt.Errorf("gRPC port %d is already used for HTTP", grpcPort)
+		}
 
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
-		// Create server configuration
-		// Build pipeline config using centralized configuration
-		pCfg := pipeline.DefaultConfig()
-		pCfg.ModelsDir = cfg.ModelsDir
-		pCfg.Recognizer.Language = language
-		// Barcode config from flags/env
-		if cmd.Flags().Changed("barcodes") || cmd.Flags().Changed("barcode-types") || cmd.Flags().Changed("barcode-min-size") || cfg.Features.BarcodeEnabled || cfg.Features.BarcodeTypes != "" || cfg.Features.BarcodeMinSize > 0 {
-			pCfg.Barcode.Enabled = cfg.Features.BarcodeEnabled
-			if cmd.Flags().Changed("barcodes") {
-				pCfg.Barcode.Enabled, _ = cmd.Flags().GetBool("barcodes")
-			}
-			var typesCSV string
-			if cmd.Flags().Changed("barcode-types") {
-				typesCSV, _ = cmd.Flags().GetString("barcode-types")
-			} else {
-				typesCSV = cfg.Features.BarcodeTypes
-			}
-			if strings.TrimSpace(typesCSV) != "" {
-				pCfg.Barcode.Types = strings.Split(typesCSV, ",")
-			}
-			if cmd.Flags().Changed("barcode-min-size") {
-				pCfg.Barcode.MinSize, _ = cmd.Flags().GetInt("barcode-min-size")
-			} else if cfg.Features.BarcodeMinSize > 0 {
-				pCfg.Barcode.MinSize = cfg.Features.BarcodeMinSize
+		shutdownTracing, err := tracing.Setup(ctx, traceConfig)
+		if err != nil {
+			return fmt.Errorf("failed to set up tracing: %w", err)
+		}
+		defer func() {
+			// Flush the spans of the last requests
+			flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer flushCancel()
+			if err := shutdownTracing(flushCtx); err != nil {
+				slog.Warn("Failed to flush traces", "error", err)
 			}
+		}()
+		if traceConfig.Enabled() {
+			slog.Info("Tracing enabled", "exporter", traceConfig.Exporter, "endpoint", traceConfig.Endpoint)
 		}
 
-		// Allow polygon mode selection
-		polyMode := cfg.Pipeline.Detector.PolygonMode
-		if cmd.Flags().Changed("det-polygon-mode") {
-			polyMode, _ = cmd.Flags().GetString("det-polygon-mode")
-		}
-		if polyMode != "" {
-			pCfg.Detector.PolygonMode = polyMode
-		}
-
-		if detModel != "" {
-			pCfg.Detector.ModelPath = detModel
-		}
-		if recModel != "" {
-			pCfg.Recognizer.ModelPath = recModel
-		}
-		if dictCSV != "" {
-			pCfg.Recognizer.DictPaths = strings.Split(dictCSV, ",")
-		}
-		if dictLangs != "" {
-			pCfg.Recognizer.DictPaths = models.GetDictionaryPathsForLanguages(cfg.ModelsDir, strings.Split(dictLangs, ","))
-		}
-		// Filter dictionary configuration (restricts output characters)
-		if filterDictCSV != "" {
-			pCfg.Recognizer.FilterDictPaths = strings.Split(filterDictCSV, ",")
-		}
-		if filterDictLangs != "" {
-			pCfg.Recognizer.FilterDictPaths = models.GetDictionaryPathsForLanguages(cfg.ModelsDir, strings.Split(filterDictLangs, ","))
-		}
-		if minDetConf > 0 {
-			pCfg.Detector.DbBoxThresh = float32(minDetConf)
-		}
-		// Apply multi-scale detection configuration
-		pCfg.Detector.MultiScale.Enabled = msEnabled
-		if len(msScales) > 0 {
-			pCfg.Detector.MultiScale.Scales = msScales
-		}
-		if msMergeIoU > 0 {
-			pCfg.Detector.MultiScale.MergeIoU = msMergeIoU
-		}
-		pCfg.Detector.MultiScale.Adaptive = msAdaptive
-		if msMaxLevels > 0 {
-			pCfg.Detector.MultiScale.MaxLevels = msMaxLevels
-		}
-		if msMinSide > 0 {
-			pCfg.Detector.MultiScale.MinSide = msMinSide
-		}
-		pCfg.Detector.MultiScale.IncrementalMerge = msIncr
-		pCfg.Orientation.Enabled = orientEnable
-		if orientThresh > 0 {
-			pCfg.Orientation.ConfidenceThreshold = orientThresh
-		}
-		pCfg.TextLineOrientation.Enabled = textlineEnable
-		if textlineThresh > 0 {
-			pCfg.TextLineOrientation.ConfidenceThreshold = textlineThresh
-		}
-
+		// Create server configuration
+		pCfg := servePipelineConfig(cmd, cfg)
 		serverConfig := server.Config{
 			Host:             host,
 			Port:             port,
 			CORSOrigin:       corsOrigin,
 			MaxUploadMB:      int64(maxUploadSize),
+			MaxBatchItems:    maxBatchItems,
 			TimeoutSec:       timeout,
 			PipelineConfig:   pCfg,
 			OverlayEnabled:   overlayEnable,
@@ -317,9 +274,52 @@ Examples:
 				RequestsPerHour:   requestsPerHour,
 				MaxRequestsPerDay: maxRequestsPerDay,
 				MaxDataPerDay:     maxDataPerDay,
+				StatePath:         rateLimitState,
+				SnapshotInterval:  rateLimitSnapshot,
+			},
+			BarcodeDPI:        barcodeDPI,
+			PDFWorkers:        pdfWorkers,
+			ProcessingTimeout: processingTimeout,
+			Jobs: jobs.Config{
+				Dir:       jobsDir,
+				Workers:   jobWorkers,
+				Retention: jobRetention,
+				MaxQueued: maxQueuedJobs,
+			},
+			Webhooks: server.WebhookConfig{
+				Secret:         webhookSecret,
+				PublicURL:      publicURL,
+				MaxAttempts:    webhookAttempts,
+				InitialBackoff: webhookBackoff,
+				Timeout:        webhookTimeout,
+				DeadLetterPath: deadLetterPath,
+
+				AllowedHosts:    webhookAllowHosts,
+				DeniedHosts:     webhookDenyHosts,
+				AllowedNetworks: webhookAllowNetworks,
+			},
+			APIKeys:         apiKeys,
+			ModelProfiles:   modelProfiles,
+			AllowModelPaths: allowModelPaths,
+			Admission: server.AdmissionConfig{
+				MaxConcurrent:      maxConcurrent,
+				MaxConcurrentBulk:  maxConcurrentBulk,
+				InteractiveQueue:   queueInteractive,
+				BulkQueue:          queueBulk,
+				InteractiveMaxWait: queueWaitInteractive,
+				BulkMaxWait:        queueWaitBulk,
+				MemoryLimitBytes:   uint64(memoryLimitMB) * 1024 * 1024,
+			},
+			PipelineCache: server.PipelineCacheConfig{
+				MaxMemoryBytes: pipelineCacheMB * 1024 * 1024,
+				MaxIdle:        pipelineCacheIdle,
+			},
+			Fetch:             fetchConfig,
+			Probes:            probeConfig,
+			StartInBackground: true,
+			ReloadPipelineConfig: func() (pipeline.Config, error) {
+				return reloadPipelineConfig(cmd, configFile)
 			},
-			BarcodeDPI: barcodeDPI,
-			PDFWorkers: pdfWorkers,
 		}
 
 		// Initialize server
@@ -348,6 +348,82 @@ Examples:
 			}
 		}()
 
+		// Serve the gRPC API on its own port, if enabled
+		stopGRPC := func(context.Context) {}
+		if grpcPort > 0 {
+			lis, err := net.Listen("tcp", fmt.Sprintf("%s:%d", host, grpcPort))
+			if err != nil {
+				_ = httpServer.Close()
+				return fmt.Errorf("failed to listen for gRPC: %w", err)
+			}
+			grpcServer := ocrServer.NewGRPCServer()
+			go func() {
+				slog.Info("Starting gRPC server", "host", host, "port", grpcPort)
+				if err := grpcServer.Serve(lis); err != nil {
+					slog.Error("gRPC server error", "error", err)
+					cancel()
+				}
+			}()
+			stopGRPC = func(ctx context.Context) {
+				slog.Info("Shutting down gRPC server")
+				stopped := make(chan struct{})
+				go func() {
+					grpcServer.GracefulStop()
+					close(stopped)
+				}()
+				select {
+				case <-stopped:
+				case <-ctx.Done():
+					grpcServer.Stop()
+				}
+			}
+		}
+
+		// Models load in the background; give up if that fails
+		startErr := make(chan error, 1)
+		go func() {
+			if err := ocrServer.WaitStarted(ctx); err != nil && ctx.Err() == nil {
+				startErr <- err
+				cancel()
+			}
+		}()
+
+		// Reload the pipeline on SIGHUP and, with --watch-config, when the
+		// config file changes; requests arriving meanwhile are coalesced
+		reloads := make(chan string, 1)
+		requestReload := func(trigger string) {
+			select {
+			case reloads <- trigger:
+			default:
+			}
+		}
+		hupChan := make(chan os.Signal, 1)
+		signal.Notify(hupChan, syscall.SIGHUP)
+		defer signal.Stop(hupChan)
+		if watch, _ := cmd.Flags().GetBool("watch-config"); watch {
+			v := GetConfigLoader().GetViper()
+			if configFile == "" {
+				slog.Warn("No config file to watch")
+			} else {
+				v.OnConfigChange(func(e fsnotify.Event) { requestReload("config file changed") })
+				v.WatchConfig()
+				slog.Info("Watching config file", "path", configFile)
+			}
+		}
+		go func() {
+			for {
+				select {
+				case <-hupChan:
+					requestReload("SIGHUP")
+				case trigger := <-reloads:
+					slog.Info("Reloading pipeline configuration", "trigger", trigger)
+					_, _ = ocrServer.Reload(ctx) // the outcome is logged
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
 
@@ -371,6 +447,8 @@ Examples:
 			slog.Info("HTTP server shutdown completed")
 		}
 
+		stopGRPC(shutdownCtx)
+
 		// Clean up OCR server resources
 		slog.Info("Cleaning up server resources")
 		if err := ocrServer.Close(); err != nil {
@@ -380,7 +458,12 @@ Examples:
 		}
 
 		slog.Info("Graceful shutdown completed")
-		return nil
+		select {
+		case err := <-startErr:
+			return fmt.Errorf("failed to initialize server: %w", err)
+		default:
+			return nil
+		}
 	},
 }
 
@@ -390,8 +473,13 @@ func init() {
 	serveCmd.Flags().IntP("port", "p", 8080, "server port")
 	serveCmd.Flags().String("cors-origin", "*", "CORS allowed origins")
 	serveCmd.Flags().Int("max-upload-size", 50, "maximum upload size in MB")
+	serveCmd.Flags().Int("max-batch-items", server.DefaultMaxBatchItems,
+		"maximum images and PDFs in a /ocr/batch request (stream large batches with Accept: application/x-ndjson)")
 	serveCmd.Flags().Int("timeout", 30, "request timeout in seconds")
+	serveCmd.Flags().Duration("processing-timeout", 0,
+		"OCR time limit per request; partial results are returned marked as truncated (0=90% of --timeout)")
 	serveCmd.Flags().Int("shutdown-timeout", 10, "shutdown timeout in seconds")
+	serveCmd.Flags().Int("grpc-port", 0, "port for the gRPC API (0=disabled)")
 	// Pipeline/server customization flags
 	serveCmd.Flags().String("language", "en", "recognizer language for text cleaning")
 	serveCmd.Flags().String("det-model", "", "override detection model path")
@@ -424,6 +512,64 @@ func init() {
 	serveCmd.Flags().Int("requests-per-hour", 1000, "maximum requests per hour per client")
 	serveCmd.Flags().Int("max-requests-per-day", 5000, "maximum requests per day per client")
 	serveCmd.Flags().Int64("max-data-per-day", 100*1024*1024, "maximum data processed per day per client (bytes)")
+	serveCmd.Flags().String("rate-limit-state", "",
+		"file rate limit usage is saved to and restored from, so that daily quotas survive restarts")
+	serveCmd.Flags().Duration("rate-limit-snapshot-interval", server.DefaultUsageSnapshotInterval,
+		"how often rate limit usage is saved to --rate-limit-state")
+	serveCmd.Flags().String("api-keys", "",
+		"API key file or directory of key files (YAML/JSON); requires a key for OCR and job endpoints")
+	serveCmd.Flags().String("model-profiles", "",
+		"YAML/JSON file of named model profiles clients select with the \"profile\" option")
+	serveCmd.Flags().Bool("allow-model-paths", false,
+		"accept model and dictionary file paths from clients (det-model, rec-model, dict)")
+	// Admission control flags
+	serveCmd.Flags().Int("max-concurrent", 0,
+		"OCR requests processed at once; more wait in a queue per lane (0=no limit)")
+	serveCmd.Flags().Int("max-concurrent-bulk", 0,
+		"slots PDF and batch requests may take, keeping the rest for images (0=all)")
+	serveCmd.Flags().Int("queue-interactive", server.DefaultInteractiveQueue, "image requests that may wait for a slot")
+	serveCmd.Flags().Int("queue-bulk", server.DefaultBulkQueue, "PDF and batch requests that may wait for a slot")
+	serveCmd.Flags().Duration("queue-wait-interactive", server.DefaultInteractiveMaxWait,
+		"longest an image request waits for a slot before a 503")
+	serveCmd.Flags().Duration("queue-wait-bulk", server.DefaultBulkMaxWait,
+		"longest a PDF or batch request waits for a slot before a 503")
+	serveCmd.Flags().Int64("memory-limit", 0,
+		"heap size in MB; above 80% of it PDF and batch requests are shed and image requests do not queue (0=disabled)")
+	serveCmd.Flags().Int64("pipeline-cache-memory", server.DefaultPipelineCacheMemoryMB,
+		"estimated memory budget in MB for pipelines cached for requests with their own models or options (0=unlimited)")
+	serveCmd.Flags().Duration("pipeline-cache-idle", server.DefaultPipelineCacheMaxIdle,
+		"evict cached pipelines unused for this long (0=never)")
+	// Remote input flags
+	serveCmd.Flags().Bool("fetch-urls", false, "accept a url instead of file data for images, PDFs, batch items and WebSocket requests")
+	serveCmd.Flags().StringSlice("fetch-schemes", []string{"http", "https"}, "URL schemes inputs may be fetched with")
+	serveCmd.Flags().StringSlice("fetch-allow-hosts", nil,
+		"only fetch from these hosts; a leading *. matches subdomains (default any public host)")
+	serveCmd.Flags().StringSlice("fetch-deny-hosts", nil, "never fetch from these hosts; a leading *. matches subdomains")
+	serveCmd.Flags().StringSlice("fetch-allow-networks", nil,
+		"CIDR ranges fetched from although they are not public (loopback, private and link-local are refused otherwise)")
+	serveCmd.Flags().Int64("fetch-max-size", 0, "maximum size of a fetched file in MB (0=--max-upload-size)")
+	serveCmd.Flags().Duration("fetch-timeout", server.DefaultFetchTimeout, "time limit for fetching a URL, including redirects")
+	serveCmd.Flags().Int("fetch-max-redirects", server.DefaultFetchMaxRedirects, "redirects followed when fetching a URL (0=none)")
+	// Tracing flags
+	serveCmd.Flags().String("trace-exporter", tracing.ExporterNone,
+		"export OpenTelemetry spans of requests and pipeline stages: none, otlp-grpc or otlp-http")
+	serveCmd.Flags().String("trace-endpoint", "",
+		"OTLP collector as host:port or URL (default $OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4317/4318)")
+	serveCmd.Flags().Bool("trace-insecure", false, "connect to a host:port --trace-endpoint without TLS")
+	serveCmd.Flags().Float64("trace-sample-ratio", 1,
+		"fraction of new traces sampled; requests with trace context follow the caller's decision")
+
+	serveCmd.Flags().Bool("watch-config", false,
+		"reload the pipeline when the config file changes (SIGHUP and POST /admin/reload always do)")
+
+	// Startup and probe flags
+	serveCmd.Flags().Int("warmup-iterations", 0,
+		"run the models this many times before reporting ready (default pipeline.warmup_iterations)")
+	serveCmd.Flags().Duration("self-test-interval", server.DefaultSelfTestInterval,
+		"how often to OCR a built-in image to check the pipeline (0=disabled)")
+	serveCmd.Flags().Duration("self-test-timeout", server.DefaultSelfTestTimeout, "time limit for a self-test")
+	serveCmd.Flags().Int("liveness-failures", server.DefaultLivenessFailures,
+		"consecutive failed self-tests after which /livez fails")
 
 	// Barcode flags (optional; server-wide defaults)
 	serveCmd.Flags().Bool("barcodes", false, "enable barcode detection in server pipeline")
@@ -431,6 +577,26 @@ func init() {
 	serveCmd.Flags().Int("barcode-min-size", 0, "minimum expected barcode size in pixels (hint)")
 	serveCmd.Flags().Int("barcode-dpi", 150, "target DPI for PDF barcode decoding (enhanced path)")
 	serveCmd.Flags().Int("pdf-workers", 0, "max worker goroutines for page processing (0=NumCPU)")
+	serveCmd.Flags().Int("pdf-max-pages-in-flight", 0, "max pages of a PDF held in memory at once (0=one per worker)")
+
+	// Asynchronous job queue flags
+	serveCmd.Flags().String("jobs-dir", "", "directory for the persistent job queue; enables the /jobs API")
+	serveCmd.Flags().Int("job-workers", jobs.DefaultWorkers, "number of jobs processed concurrently")
+	serveCmd.Flags().Duration("job-retention", jobs.DefaultRetention, "how long finished jobs and their results are kept")
+	serveCmd.Flags().Int("max-queued-jobs", jobs.DefaultMaxQueued, "maximum number of jobs waiting to be processed")
+	serveCmd.Flags().String("webhook-secret", "",
+		"HMAC-SHA256 key for signing job callbacks (default $POGO_WEBHOOK_SECRET; unsigned if empty)")
+	serveCmd.Flags().String("public-url", "", "externally visible base URL of the server, used for result links in callbacks")
+	serveCmd.Flags().Int("webhook-max-attempts", 5, "delivery attempts per job callback before it is dead-lettered")
+	serveCmd.Flags().Duration("webhook-backoff", time.Second, "delay before the first callback retry; doubles per attempt")
+	serveCmd.Flags().Duration("webhook-timeout", 10*time.Second, "timeout of a single callback attempt")
+	serveCmd.Flags().String("webhook-dead-letter", "",
+		"file undeliverable callbacks are appended to (default <jobs-dir>/webhooks-dead-letter.ndjson)")
+	serveCmd.Flags().StringSlice("webhook-allow-hosts", nil,
+		"only call back to these hosts; a leading *. matches subdomains (default any public host)")
+	serveCmd.Flags().StringSlice("webhook-deny-hosts", nil, "never call back to these hosts; a leading *. matches subdomains")
+	serveCmd.Flags().StringSlice("webhook-allow-networks", nil,
+		"CIDR ranges callbacks may go to although they are not public (loopback, private and link-local are refused otherwise)")
 }
 
 // Ensure server help mentions docs for multi-scale.
@@ -450,3 +616,282 @@ func init() {
 		_, _ = fmt.Fprintln(out, "  See docs/multiscale.md for multi-scale detection options and tuning.")
 	})
 }
+
+// fetchConfigFromFlags reads the remote input policy from the serve flags.
+func fetchConfigFromFlags(cmd *cobra.Command) (server.FetchConfig, error) {
+	var config server.FetchConfig
+	config.Enabled, _ = cmd.Flags().GetBool("fetch-urls")
+	config.Schemes, _ = cmd.Flags().GetStringSlice("fetch-schemes")
+	config.AllowedHosts, _ = cmd.Flags().GetStringSlice("fetch-allow-hosts")
+	config.DeniedHosts, _ = cmd.Flags().GetStringSlice("fetch-deny-hosts")
+	networks, _ := cmd.Flags().GetStringSlice("fetch-allow-networks")
+	var err error
+	if config.AllowedNetworks, err = parseNetworks("fetch-allow-networks", networks); err != nil {
+		return config, err
+	}
+	maxSizeMB, _ := cmd.Flags().GetInt64("fetch-max-size")
+	if maxSizeMB < 0 {
+		return config, fmt.Errorf("invalid fetch max size: %d MB", maxSizeMB)
+	}
+	config.MaxBytes = maxSizeMB * 1024 * 1024
+	config.Timeout, _ = cmd.Flags().GetDuration("fetch-timeout")
+	config.MaxRedirects, _ = cmd.Flags().GetInt("fetch-max-redirects")
+	if config.MaxRedirects == 0 {
+		// 0 means "the default" in FetchConfig but "none" on the command line
+		config.MaxRedirects = -1
+	}
+	return config, nil
+}
+
+// parseNetworks parses the CIDR ranges of the flag name.
+func parseNetworks(name string, networks []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, network := range networks {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(network))
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s entry: %w", name, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// servePipelineConfig returns the configuration of the default pipeline:
+// cfg with the pipeline flags of the serve command applied on top.
+func servePipelineConfig(cmd *cobra.Command, cfg *config.Config) pipeline.Config {
+	// Extract pipeline configuration with CLI flag overrides
+	language := cfg.Pipeline.Recognizer.Language
+	if cmd.Flags().Changed("language") {
+		language, _ = cmd.Flags().GetString("language")
+	}
+
+	detModel := cfg.Pipeline.Detector.ModelPath
+	if cmd.Flags().Changed("det-model") {
+		detModel, _ = cmd.Flags().GetString("det-model")
+	}
+
+	recModel := cfg.Pipeline.Recognizer.ModelPath
+	if cmd.Flags().Changed("rec-model") {
+		recModel, _ = cmd.Flags().GetString("rec-model")
+	}
+
+	minDetConf := float64(cfg.Pipeline.Detector.DbBoxThresh)
+	if cmd.Flags().Changed("min-det-conf") {
+		minDetConf, _ = cmd.Flags().GetFloat64("min-det-conf")
+	}
+
+	// Multi-scale detection options
+	msEnabled := cfg.Pipeline.Detector.MultiScale.Enabled
+	if cmd.Flags().Changed("det-multiscale") {
+		msEnabled, _ = cmd.Flags().GetBool("det-multiscale")
+	}
+	msScales := cfg.Pipeline.Detector.MultiScale.Scales
+	if cmd.Flags().Changed("det-scales") {
+		msScales, _ = cmd.Flags().GetFloat64Slice("det-scales")
+	}
+	msMergeIoU := cfg.Pipeline.Detector.MultiScale.MergeIoU
+	if cmd.Flags().Changed("det-merge-iou") {
+		msMergeIoU, _ = cmd.Flags().GetFloat64("det-merge-iou")
+	}
+	msAdaptive := cfg.Pipeline.Detector.MultiScale.Adaptive
+	if cmd.Flags().Changed("det-ms-adaptive") {
+		msAdaptive, _ = cmd.Flags().GetBool("det-ms-adaptive")
+	}
+	msMaxLevels := cfg.Pipeline.Detector.MultiScale.MaxLevels
+	if cmd.Flags().Changed("det-ms-max-levels") {
+		msMaxLevels, _ = cmd.Flags().GetInt("det-ms-max-levels")
+	}
+	msMinSide := cfg.Pipeline.Detector.MultiScale.MinSide
+	if cmd.Flags().Changed("det-ms-min-side") {
+		msMinSide, _ = cmd.Flags().GetInt("det-ms-min-side")
+	}
+	msIncr := cfg.Pipeline.Detector.MultiScale.IncrementalMerge
+	if cmd.Flags().Changed("det-ms-incremental-merge") {
+		msIncr, _ = cmd.Flags().GetBool("det-ms-incremental-merge")
+	}
+
+	orientEnable := cfg.Features.OrientationEnabled
+	if cmd.Flags().Changed("detect-orientation") {
+		orientEnable, _ = cmd.Flags().GetBool("detect-orientation")
+	}
+
+	orientThresh := cfg.Features.OrientationThreshold
+	if cmd.Flags().Changed("orientation-threshold") {
+		orientThresh, _ = cmd.Flags().GetFloat64("orientation-threshold")
+	}
+
+	textlineEnable := cfg.Features.TextlineEnabled
+	if cmd.Flags().Changed("detect-textline") {
+		textlineEnable, _ = cmd.Flags().GetBool("detect-textline")
+	}
+
+	textlineThresh := cfg.Features.TextlineThreshold
+	if cmd.Flags().Changed("textline-threshold") {
+		textlineThresh, _ = cmd.Flags().GetFloat64("textline-threshold")
+	}
+
+	dictCSV := cfg.Pipeline.Recognizer.DictPath
+	if cmd.Flags().Changed("dict") {
+		dictCSV, _ = cmd.Flags().GetString("dict")
+	}
+
+	dictLangs := cfg.Pipeline.Recognizer.DictLangs
+	if cmd.Flags().Changed("dict-langs") {
+		dictLangs, _ = cmd.Flags().GetString("dict-langs")
+	}
+
+	filterDictCSV := cfg.Pipeline.Recognizer.FilterDictPath
+	if cmd.Flags().Changed("filter-dict") {
+		filterDictCSV, _ = cmd.Flags().GetString("filter-dict")
+	}
+
+	filterDictLangs := cfg.Pipeline.Recognizer.FilterDictLangs
+	if cmd.Flags().Changed("filter-dict-langs") {
+		filterDictLangs, _ = cmd.Flags().GetString("filter-dict-langs")
+	}
+
+	maxInFlight, _ := cmd.Flags().GetInt("pdf-max-pages-in-flight")
+
+	// Build pipeline config using centralized configuration
+	pCfg := pipeline.DefaultConfig()
+	pCfg.ModelsDir = cfg.ModelsDir
+	pCfg.Recognizer.Language = language
+	// Barcode config from flags/env
+	if cmd.Flags().Changed("barcodes") || cmd.Flags().Changed("barcode-types") || cmd.Flags().Changed("barcode-min-size") || cfg.Features.BarcodeEnabled || cfg.Features.BarcodeTypes != "" || cfg.Features.BarcodeMinSize > 0 {
+		pCfg.Barcode.Enabled = cfg.Features.BarcodeEnabled
+		if cmd.Flags().Changed("barcodes") {
+			pCfg.Barcode.Enabled, _ = cmd.Flags().GetBool("barcodes")
+		}
+		var typesCSV string
+		if cmd.Flags().Changed("barcode-types") {
+			typesCSV, _ = cmd.Flags().GetString("barcode-types")
+		} else {
+			typesCSV = cfg.Features.BarcodeTypes
+		}
+		if strings.TrimSpace(typesCSV) != "" {
+			pCfg.Barcode.Types = strings.Split(typesCSV, ",")
+		}
+		if cmd.Flags().Changed("barcode-min-size") {
+			pCfg.Barcode.MinSize, _ = cmd.Flags().GetInt("barcode-min-size")
+		} else if cfg.Features.BarcodeMinSize > 0 {
+			pCfg.Barcode.MinSize = cfg.Features.BarcodeMinSize
+		}
+	}
+
+	// Allow polygon mode selection
+	polyMode := cfg.Pipeline.Detector.PolygonMode
+	if cmd.Flags().Changed("det-polygon-mode") {
+		polyMode, _ = cmd.Flags().GetString("det-polygon-mode")
+	}
+	if polyMode != "" {
+		pCfg.Detector.PolygonMode = polyMode
+	}
+
+	if detModel != "" {
+		pCfg.Detector.ModelPath = detModel
+	}
+	if recModel != "" {
+		pCfg.Recognizer.ModelPath = recModel
+	}
+	if dictCSV != "" {
+		pCfg.Recognizer.DictPaths = strings.Split(dictCSV, ",")
+	}
+	if dictLangs != "" {
+		pCfg.Recognizer.DictPaths = models.GetDictionaryPathsForLanguages(cfg.ModelsDir, strings.Split(dictLangs, ","))
+	}
+	// Filter dictionary configuration (restricts output characters)
+	if filterDictCSV != "" {
+		pCfg.Recognizer.FilterDictPaths = strings.Split(filterDictCSV, ",")
+	}
+	if filterDictLangs != "" {
+		pCfg.Recognizer.FilterDictPaths = models.GetDictionaryPathsForLanguages(cfg.ModelsDir, strings.Split(filterDictLangs, ","))
+	}
+	if minDetConf > 0 {
+		pCfg.Detector.DbBoxThresh = float32(minDetConf)
+	}
+	// Apply multi-scale detection configuration
+	pCfg.Detector.MultiScale.Enabled = msEnabled
+	if len(msScales) > 0 {
+		pCfg.Detector.MultiScale.Scales = msScales
+	}
+	if msMergeIoU > 0 {
+		pCfg.Detector.MultiScale.MergeIoU = msMergeIoU
+	}
+	pCfg.Detector.MultiScale.Adaptive = msAdaptive
+	if msMaxLevels > 0 {
+		pCfg.Detector.MultiScale.MaxLevels = msMaxLevels
+	}
+	if msMinSide > 0 {
+		pCfg.Detector.MultiScale.MinSide = msMinSide
+	}
+	pCfg.Detector.MultiScale.IncrementalMerge = msIncr
+	pCfg.Orientation.Enabled = orientEnable
+	if orientThresh > 0 {
+		pCfg.Orientation.ConfidenceThreshold = orientThresh
+	}
+	pCfg.PDF.MaxInFlightPages = maxInFlight
+	pCfg.TextLineOrientation.Enabled = textlineEnable
+	if textlineThresh > 0 {
+		pCfg.TextLineOrientation.ConfidenceThreshold = textlineThresh
+	}
+	return pCfg
+}
+
+// reloadPipelineConfig re-reads configFile (or searches the default
+// locations when it is empty) and the environment and returns the pipeline
+// configuration of the serve command; flags keep precedence. An invalid
+// configuration is an error. It reads through a fresh viper instance since
+// --watch-config re-reads the global one from its own goroutine.
+func reloadPipelineConfig(cmd *cobra.Command, configFile string) (pipeline.Config, error) {
+	cfg, err := config.NewStandaloneLoader().LoadWithFile(configFile)
+	if err != nil {
+		return pipeline.Config{}, err
+	}
+	if f := cmd.Flag("models-dir"); f != nil && f.Changed {
+		cfg.ModelsDir = f.Value.String()
+	}
+	return servePipelineConfig(cmd, cfg), nil
+}
+
+// probeConfigFromFlags reads the warmup and self-test settings from the
+// serve flags; warmupIterations is the configured default.
+func probeConfigFromFlags(cmd *cobra.Command, warmupIterations int) (server.ProbeConfig, error) {
+	config := server.ProbeConfig{WarmupIterations: warmupIterations}
+	if cmd.Flags().Changed("warmup-iterations") {
+		config.WarmupIterations, _ = cmd.Flags().GetInt("warmup-iterations")
+	}
+	config.SelfTestInterval, _ = cmd.Flags().GetDuration("self-test-interval")
+	config.SelfTestTimeout, _ = cmd.Flags().GetDuration("self-test-timeout")
+	config.LivenessFailures, _ = cmd.Flags().GetInt("liveness-failures")
+	switch {
+	case config.WarmupIterations < 0:
+		return config, fmt.Errorf("invalid warmup iterations: %d", config.WarmupIterations)
+	case config.SelfTestInterval < 0 || config.SelfTestTimeout < 0:
+		return config, errors.New("self-test interval and timeout must not be negative")
+	case config.LivenessFailures < 1:
+		return config, fmt.Errorf("invalid --liveness-failures %d (must be at least 1)", config.LivenessFailures)
+	}
+	if config.SelfTestInterval == 0 {
+		config.SelfTestInterval = -1 // disabled, 0 would select the default
+	}
+	return config, nil
+}
+
+// traceConfigFromFlags reads the span export settings from the serve flags.
+func traceConfigFromFlags(cmd *cobra.Command) (tracing.Config, error) {
+	var config tracing.Config
+	config.Exporter, _ = cmd.Flags().GetString("trace-exporter")
+	switch config.Exporter {
+	case tracing.ExporterNone, tracing.ExporterOTLPGRPC, tracing.ExporterOTLPHTTP:
+	default:
+		return config, fmt.Errorf("invalid --trace-exporter %q (want %s, %s or %s)", config.Exporter,
+			tracing.ExporterNone, tracing.ExporterOTLPGRPC, tracing.ExporterOTLPHTTP)
+	}
+	config.Endpoint, _ = cmd.Flags().GetString("trace-endpoint")
+	config.Insecure, _ = cmd.Flags().GetBool("trace-insecure")
+	config.SampleRatio, _ = cmd.Flags().GetFloat64("trace-sample-ratio")
+	if config.SampleRatio < 0 || config.SampleRatio > 1 {
+		return config, fmt.Errorf("invalid --trace-sample-ratio %g (must be between 0 and 1)", config.SampleRatio)
+	}
+	return config, nil
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadPipelineConfig(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "pogo.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte("pipeline:\n  recognizer:\n    language: de\n"), 0o600))
+
+	cfg, err := reloadPipelineConfig(serveCmd, configFile)
+	require.NoError(t, err)
+	assert.Equal(t, "de", cfg.Recognizer.Language)
+
+	require.NoError(t, os.WriteFile(configFile, []byte("pipeline:\n  detector:\n    db_thresh: 2\n"), 0o600))
+	_, err = reloadPipelineConfig(serveCmd, configFile)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "configuration validation failed")
+}
@@ -3,6 +3,7 @@ package batch
 // Package batch provides batch processing functionality for OCR operations.
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -13,8 +14,15 @@ import (
 
 // ProcessBatch processes a batch of images with the given configuration.
 func ProcessBatch(imagePaths []string, config *Config) (*Result, error) {
+	return ProcessBatchContext(context.Background(), imagePaths, config)
+}
+
+// ProcessBatchContext is like ProcessBatch but stops between images when ctx
+// ends. It then returns the images finished so far in a result marked as
+// truncated together with the context's error.
+func ProcessBatchContext(ctx context.Context, imagePaths []string, config *Config) (*Result, error) {
 	// Discover image files
-	files, err := discoverImageFiles(imagePaths, config.Recursive, config.IncludePatterns, config.ExcludePatterns)
+	files, err := discoverImageFiles(ctx, imagePaths, config.Recursive, config.IncludePatterns, config.ExcludePatterns)
 	if err != nil {
 		return nil, fmt.Errorf("failed to discover image files: %w", err)
 	}
@@ -45,17 +53,20 @@ func ProcessBatch(imagePaths []string, config *Config) (*Result, error) {
 
 	// Process images in parallel
 	startTime := time.Now()
-	results, err := processImagesParallel(pl, files, config.Confidence, config.MinRecConf, config.OverlayDir)
+	results, err := processImagesParallel(ctx, pl, files, config.Confidence, config.MinRecConf, config.OverlayDir)
 	duration := time.Since(startTime)
 
-	if err != nil {
+	if err != nil && ctx.Err() == nil {
 		return nil, fmt.Errorf("batch processing failed: %w", err)
 	}
 
 	return &Result{
 		Results:     results,
-		ImagePaths:  files,
+		ImagePaths:  files[:len(results)],
 		Duration:    duration,
 		WorkerCount: config.Workers,
-	}, nil
+		TotalFiles:  len(files),
+		Truncated:   err != nil,
+		Metadata:    pl.ExportMetadata(),
+	}, err
 }
@@ -1,11 +1,15 @@
 package batch
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/MeKo-Tech/pogo/internal/pipeline"
+	"github.com/MeKo-Tech/pogo/internal/storage"
 )
 
 // Config holds all configuration for batch processing.
@@ -66,22 +70,36 @@ type Result struct {
 	ImagePaths  []string
 	Duration    time.Duration
 	WorkerCount int
+	// TotalFiles is the number of discovered images; with Truncated set,
+	// only the first len(Results) of them were processed.
+	TotalFiles int
+	Truncated  bool
+	// Metadata describes the pipeline in layout exports (alto, page).
+	Metadata pipeline.ExportMetadata
 }
 
 // FormatResults formats the batch processing results in the specified format.
 func (r *Result) FormatResults(format string) (string, error) {
-	return formatBatchResults(r.Results, r.ImagePaths, format)
+	return formatBatchResults(r.Results, r.ImagePaths, format, r.Metadata)
 }
 
-// SaveResults saves the formatted results to a file or stdout.
+// SaveResults saves the formatted results to a file, which may be a storage
+// location such as s3://bucket/results.json, or stdout.
+// PAGE XML describes a single image, so with several images outputFile is a
+// directory that gets one document per image.
 func (r *Result) SaveResults(format, outputFile string, quiet bool) error {
+	if format == "page" && len(r.Results) > 1 {
+		return r.savePageXML(outputFile, quiet)
+	}
 	output, err := r.FormatResults(format)
 	if err != nil {
 		return fmt.Errorf("failed to format results: %w", err)
 	}
 
 	if outputFile != "" {
-		if err := os.WriteFile(outputFile, []byte(output), 0o600); err != nil {
+		// Results are saved even after the batch was interrupted, so the
+		// write does not share the batch's context.
+		if err := storage.WriteFile(context.Background(), outputFile, []byte(output)); err != nil {
 			return fmt.Errorf("failed to write output file: %w", err)
 		}
 		if !quiet {
@@ -94,6 +112,42 @@ func (r *Result) SaveResults(format, outputFile string, quiet bool) error {
 	return nil
 }
 
+// savePageXML writes a PAGE XML document per image into the directory
+// outputDir, named after the image, e.g. scan.png -> scan.xml.
+func (r *Result) savePageXML(outputDir string, quiet bool) error {
+	if outputDir == "" || (!storage.IsRemote(outputDir) && !isLocalDir(outputDir)) ||
+		(storage.IsRemote(outputDir) && !storage.IsDir(outputDir)) {
+		return fmt.Errorf("--output must be an existing directory when writing %d PAGE XML documents", len(r.Results))
+	}
+	used := make(map[string]bool, len(r.Results))
+	for i, res := range r.Results {
+		doc, err := pipeline.ToPageXMLImage(res, r.ImagePaths[i], r.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to format results: %w", err)
+		}
+		// Images of the same name in different directories get numbered
+		base := storage.Base(r.ImagePaths[i])
+		base = strings.TrimSuffix(base, path.Ext(base))
+		name := base + ".xml"
+		if used[name] {
+			name = fmt.Sprintf("%s_%d.xml", base, i+1)
+		}
+		used[name] = true
+		if err := storage.WriteFile(context.Background(), storage.Join(outputDir, name), []byte(doc)); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+	}
+	if !quiet {
+		_, _ = fmt.Fprintf(os.Stdout, "PAGE XML for %d images written to %s\n", len(r.Results), outputDir)
+	}
+	return nil
+}
+
+func isLocalDir(name string) bool {
+	st, err := os.Stat(name)
+	return err == nil && st.IsDir()
+}
+
 // PrintStats prints processing statistics.
 func (r *Result) PrintStats(quiet bool) {
 	if !quiet {
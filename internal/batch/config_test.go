@@ -119,6 +119,41 @@ func TestResult_SaveResults_ToFile(t *testing.T) {
 	assert.Contains(t, string(content), "Test Content")
 }
 
+func TestResult_SaveResults_PageXML(t *testing.T) {
+	tempDir := testutil.CreateTempDir(t)
+	result := &Result{
+		Results: []*pipeline.OCRImageResult{
+			createMockOCRResult("First", 0.90),
+			createMockOCRResult("Second", 0.90),
+			createMockOCRResult("Third", 0.90),
+		},
+		ImagePaths: []string{"/a/scan.png", "/b/other.jpg", "/c/scan.png"},
+		Metadata:   pipeline.ExportMetadata{RecognitionModel: "rec.onnx"},
+	}
+
+	// Several images need a directory
+	err := result.SaveResults("page", filepath.Join(tempDir, "out.xml"), true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "existing directory")
+
+	require.NoError(t, result.SaveResults("page", tempDir, true))
+	for name, text := range map[string]string{"scan.xml": "First", "other.xml": "Second", "scan_3.xml": "Third"} {
+		content, err := os.ReadFile(filepath.Join(tempDir, name)) // #nosec G304 - path is controlled by test
+		require.NoError(t, err, name)
+		assert.Contains(t, string(content), `imageFilename="/`)
+		assert.Contains(t, string(content), text)
+		assert.Contains(t, string(content), `<Label type="recognition-model" value="rec.onnx"/>`)
+	}
+
+	// A single image is written like the other formats
+	result.Results, result.ImagePaths = result.Results[:1], result.ImagePaths[:1]
+	outputFile := filepath.Join(tempDir, "single.xml")
+	require.NoError(t, result.SaveResults("page", outputFile, true))
+	content, err := os.ReadFile(outputFile) // #nosec G304 - outputFile is controlled by test
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "<PcGts")
+}
+
 func TestResult_SaveResults_Stdout(t *testing.T) {
 	result := &Result{
 		Results: []*pipeline.OCRImageResult{
@@ -1,16 +1,32 @@
 package batch
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/MeKo-Tech/pogo/internal/storage"
 )
 
 // discoverImageFiles finds all image files matching the given patterns.
-func discoverImageFiles(args []string, recursive bool, includePatterns, excludePatterns []string) ([]string, error) {
+// Arguments may be local paths or storage locations such as
+// s3://bucket/prefix/, which are listed like directories.
+func discoverImageFiles(ctx context.Context, args []string, recursive bool,
+	includePatterns, excludePatterns []string,
+) ([]string, error) {
 	var imageFiles []string
 
 	for _, arg := range args {
+		if storage.IsRemote(arg) {
+			files, err := discoverRemoteFiles(ctx, arg, recursive, includePatterns, excludePatterns)
+			if err != nil {
+				return nil, err
+			}
+			imageFiles = append(imageFiles, files...)
+			continue
+		}
+
 		info, err := os.Stat(arg)
 		if err != nil {
 			return nil, fmt.Errorf("cannot access %s: %w", arg, err)
@@ -30,6 +46,31 @@ func discoverImageFiles(args []string, recursive bool, includePatterns, excludeP
 	return imageFiles, nil
 }
 
+// discoverRemoteFiles lists a directory location in object storage, or
+// checks the patterns for a single object.
+func discoverRemoteFiles(ctx context.Context, location string, recursive bool,
+	includePatterns, excludePatterns []string,
+) ([]string, error) {
+	if !storage.IsDir(location) {
+		if shouldIncludeFile(location, includePatterns, excludePatterns) {
+			return []string{location}, nil
+		}
+		return nil, nil
+	}
+
+	locations, err := storage.List(ctx, location, recursive)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list %s: %w", location, err)
+	}
+	var files []string
+	for _, loc := range locations {
+		if shouldIncludeFile(loc, includePatterns, excludePatterns) {
+			files = append(files, loc)
+		}
+	}
+	return files, nil
+}
+
 // discoverInDirectory recursively discovers image files in a directory.
 func discoverInDirectory(dir string, recursive bool, includePatterns, excludePatterns []string) ([]string, error) {
 	var files []string
@@ -5,13 +5,14 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/MeKo-Tech/pogo/internal/storage/storagetest"
 	"github.com/MeKo-Tech/pogo/internal/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestDiscoverImageFiles_EmptyArgs(t *testing.T) {
-	files, err := discoverImageFiles([]string{}, false, []string{"*.png"}, []string{})
+	files, err := discoverImageFiles(t.Context(), []string{}, false, []string{"*.png"}, []string{})
 	require.NoError(t, err)
 	assert.Empty(t, files)
 }
@@ -28,7 +29,7 @@ func TestDiscoverImageFiles_SingleFile(t *testing.T) {
 	require.NoError(t, os.WriteFile(txtFile, []byte("text file"), 0o600))
 	require.NoError(t, os.WriteFile(jpgFile, []byte("fake jpg"), 0o600))
 
-	files, err := discoverImageFiles([]string{pngFile, jpgFile}, false, []string{"*.png", "*.jpg"}, []string{})
+	files, err := discoverImageFiles(t.Context(), []string{pngFile, jpgFile}, false, []string{"*.png", "*.jpg"}, []string{})
 	require.NoError(t, err)
 	assert.Len(t, files, 2)
 	assert.Contains(t, files, pngFile)
@@ -47,7 +48,7 @@ func TestDiscoverImageFiles_Directory(t *testing.T) {
 	require.NoError(t, os.WriteFile(jpgFile, []byte("fake jpg"), 0o600))
 	require.NoError(t, os.WriteFile(txtFile, []byte("text"), 0o600))
 
-	files, err := discoverImageFiles([]string{tempDir}, false, []string{"*.png", "*.jpg"}, []string{})
+	files, err := discoverImageFiles(t.Context(), []string{tempDir}, false, []string{"*.png", "*.jpg"}, []string{})
 	require.NoError(t, err)
 	assert.Len(t, files, 2)
 	assert.Contains(t, files, pngFile)
@@ -70,7 +71,7 @@ func TestDiscoverImageFiles_Recursive(t *testing.T) {
 	require.NoError(t, os.WriteFile(subPng, []byte("sub png"), 0o600))
 	require.NoError(t, os.WriteFile(subTxt, []byte("sub txt"), 0o600))
 
-	files, err := discoverImageFiles([]string{tempDir}, true, []string{"*.png"}, []string{})
+	files, err := discoverImageFiles(t.Context(), []string{tempDir}, true, []string{"*.png"}, []string{})
 	require.NoError(t, err)
 	assert.Len(t, files, 2)
 	assert.Contains(t, files, rootPng)
@@ -91,7 +92,7 @@ func TestDiscoverImageFiles_NonRecursive(t *testing.T) {
 	require.NoError(t, os.WriteFile(rootPng, []byte("root png"), 0o600))
 	require.NoError(t, os.WriteFile(subPng, []byte("sub png"), 0o600))
 
-	files, err := discoverImageFiles([]string{tempDir}, false, []string{"*.png"}, []string{})
+	files, err := discoverImageFiles(t.Context(), []string{tempDir}, false, []string{"*.png"}, []string{})
 	require.NoError(t, err)
 	assert.Len(t, files, 1)
 	assert.Contains(t, files, rootPng)
@@ -110,7 +111,7 @@ func TestDiscoverImageFiles_IncludeExcludePatterns(t *testing.T) {
 	require.NoError(t, os.WriteFile(test2Png, []byte("test2"), 0o600))
 	require.NoError(t, os.WriteFile(excludePng, []byte("exclude"), 0o600))
 
-	files, err := discoverImageFiles([]string{tempDir}, false, []string{"*.png"}, []string{"*exclude*"})
+	files, err := discoverImageFiles(t.Context(), []string{tempDir}, false, []string{"*.png"}, []string{"*exclude*"})
 	require.NoError(t, err)
 	assert.Len(t, files, 2)
 	assert.Contains(t, files, test1Png)
@@ -119,12 +120,36 @@ func TestDiscoverImageFiles_IncludeExcludePatterns(t *testing.T) {
 }
 
 func TestDiscoverImageFiles_NonExistentDirectory(t *testing.T) {
-	files, err := discoverImageFiles([]string{"/nonexistent/directory"}, false, []string{"*.png"}, []string{})
+	files, err := discoverImageFiles(t.Context(), []string{"/nonexistent/directory"}, false, []string{"*.png"}, []string{})
 	require.Error(t, err)
 	assert.Nil(t, files)
 	assert.Contains(t, err.Error(), "cannot access")
 }
 
+func TestDiscoverImageFiles_S3(t *testing.T) {
+	srv := storagetest.NewS3Server(t)
+	srv.Configure(t)
+	for _, key := range []string{"scans/a.png", "scans/b.txt", "scans/2024/c.jpg", "other/d.png"} {
+		srv.Put("docs", key, []byte("data"))
+	}
+
+	files, err := discoverImageFiles(t.Context(), []string{"s3://docs/scans/"}, false, []string{"*.png", "*.jpg"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"s3://docs/scans/a.png"}, files)
+
+	files, err = discoverImageFiles(t.Context(), []string{"s3://docs/scans/", "s3://docs/other/d.png"}, true,
+		[]string{"*.png", "*.jpg"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"s3://docs/scans/2024/c.jpg", "s3://docs/scans/a.png", "s3://docs/other/d.png"}, files)
+
+	_, err = discoverImageFiles(t.Context(), []string{"s3://docs/scans/"}, false, nil, nil)
+	require.NoError(t, err)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	_, err = discoverImageFiles(t.Context(), []string{"s3://docs/scans/"}, false, nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot list s3://docs/scans/")
+}
+
 func TestDiscoverInDirectory_EmptyDirectory(t *testing.T) {
 	tempDir := testutil.CreateTempDir(t)
 
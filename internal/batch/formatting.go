@@ -11,12 +11,28 @@ import (
 )
 
 // formatBatchResults formats the batch processing results in the specified format.
-func formatBatchResults(results []*pipeline.OCRImageResult, imagePaths []string, format string) (string, error) {
+// meta describes the pipeline for the alto and page formats.
+func formatBatchResults(results []*pipeline.OCRImageResult, imagePaths []string, format string,
+	meta pipeline.ExportMetadata,
+) (string, error) {
 	switch format {
 	case "json":
 		return formatJSON(results, imagePaths)
 	case "csv":
 		return formatCSV(results, imagePaths)
+	case "hocr":
+		return pipeline.ToHOCRImages(results, imagePaths)
+	case "alto":
+		return pipeline.ToALTOImages(results, imagePaths, meta)
+	case "page":
+		if len(results) != 1 {
+			return "", fmt.Errorf("page format describes a single image, got %d; write them to a directory", len(results))
+		}
+		return pipeline.ToPageXMLImage(results[0], imagePaths[0], meta)
+	case "text-layout":
+		return formatLayoutText(results, imagePaths)
+	case "markdown":
+		return formatMarkdown(results, imagePaths)
 	default: // text
 		return formatText(results, imagePaths)
 	}
@@ -116,3 +132,42 @@ func formatText(results []*pipeline.OCRImageResult, imagePaths []string) (string
 	}
 	return output.String(), nil
 }
+
+// formatLayoutText formats results as layout-preserving text, one section per image.
+func formatLayoutText(results []*pipeline.OCRImageResult, imagePaths []string) (string, error) {
+	var output strings.Builder
+	for i, res := range results {
+		if i > 0 {
+			output.WriteString("\n")
+		}
+		output.WriteString("# " + imagePaths[i] + "\n")
+		if res == nil {
+			continue
+		}
+		text, err := pipeline.ToLayoutTextImage(res)
+		if err != nil {
+			return "", err
+		}
+		output.WriteString(text)
+	}
+	return output.String(), nil
+}
+
+// formatMarkdown formats results as Markdown with one second-level section per image.
+func formatMarkdown(results []*pipeline.OCRImageResult, imagePaths []string) (string, error) {
+	sections := make([]string, 0, len(results))
+	for i, res := range results {
+		section := "## " + imagePaths[i] + "\n"
+		if res != nil {
+			md, err := pipeline.ToMarkdownImage(res)
+			if err != nil {
+				return "", err
+			}
+			if md != "" {
+				section += "\n" + md
+			}
+		}
+		sections = append(sections, section)
+	}
+	return strings.Join(sections, "\n"), nil
+}
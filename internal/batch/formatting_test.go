@@ -16,7 +16,7 @@ func TestFormatBatchResults_Text(t *testing.T) {
 	}
 	imagePaths := []string{"/path/image1.png", "/path/image2.png"}
 
-	output, err := formatBatchResults(results, imagePaths, "text")
+	output, err := formatBatchResults(results, imagePaths, "text", pipeline.ExportMetadata{})
 	require.NoError(t, err)
 
 	assert.Contains(t, output, "# /path/image1.png")
@@ -31,7 +31,7 @@ func TestFormatBatchResults_JSON(t *testing.T) {
 	}
 	imagePaths := []string{"/path/test.png"}
 
-	output, err := formatBatchResults(results, imagePaths, "json")
+	output, err := formatBatchResults(results, imagePaths, "json", pipeline.ExportMetadata{})
 	require.NoError(t, err)
 
 	assert.Contains(t, output, `"file": "/path/test.png"`)
@@ -49,7 +49,7 @@ func TestFormatBatchResults_CSV(t *testing.T) {
 	}
 	imagePaths := []string{"/path/test.png"}
 
-	output, err := formatBatchResults(results, imagePaths, "csv")
+	output, err := formatBatchResults(results, imagePaths, "csv", pipeline.ExportMetadata{})
 	require.NoError(t, err)
 
 	lines := strings.Split(strings.TrimSpace(output), "\n")
@@ -66,11 +66,67 @@ func TestFormatBatchResults_CSV(t *testing.T) {
 	assert.Contains(t, lines[1], "0.850")
 }
 
+func TestFormatBatchResults_HOCR(t *testing.T) {
+	results := []*pipeline.OCRImageResult{
+		createMockOCRResult("Hello World", 0.95),
+		createMockOCRResult("Test Image", 0.88),
+	}
+	imagePaths := []string{"/path/image1.png", "/path/image2.png"}
+
+	output, err := formatBatchResults(results, imagePaths, "hocr", pipeline.ExportMetadata{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, strings.Count(output, `class="ocr_page"`))
+	assert.Contains(t, output, "image &#34;/path/image1.png&#34;")
+	assert.Contains(t, output, ">Hello</span>")
+	assert.Contains(t, output, ">Image</span>")
+}
+
+func TestFormatBatchResults_ALTO(t *testing.T) {
+	results := []*pipeline.OCRImageResult{
+		createMockOCRResult("Hello World", 0.95),
+		createMockOCRResult("Test Image", 0.88),
+	}
+	imagePaths := []string{"/path/image1.png", "/path/image2.png"}
+
+	output, err := formatBatchResults(results, imagePaths, "alto", pipeline.ExportMetadata{DetectionModel: "det.onnx"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, strings.Count(output, "<Page "))
+	assert.Contains(t, output, "detection-model=det.onnx")
+
+	_, err = formatBatchResults(results, imagePaths, "page", pipeline.ExportMetadata{})
+	require.Error(t, err, "PAGE XML describes one image")
+	output, err = formatBatchResults(results[:1], imagePaths[:1], "page", pipeline.ExportMetadata{})
+	require.NoError(t, err)
+	assert.Contains(t, output, `imageFilename="/path/image1.png"`)
+}
+
+func TestFormatBatchResults_TextLayout(t *testing.T) {
+	results := []*pipeline.OCRImageResult{createMockOCRResult("Hello World", 0.95), nil}
+	imagePaths := []string{"/path/image1.png", "/path/image2.png"}
+
+	output, err := formatBatchResults(results, imagePaths, "text-layout", pipeline.ExportMetadata{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "# /path/image1.png\nHello World\n\n# /path/image2.png\n", output)
+}
+
+func TestFormatBatchResults_Markdown(t *testing.T) {
+	results := []*pipeline.OCRImageResult{createMockOCRResult("Hello World", 0.95), nil}
+	imagePaths := []string{"/path/image1.png", "/path/image2.png"}
+
+	output, err := formatBatchResults(results, imagePaths, "markdown", pipeline.ExportMetadata{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "## /path/image1.png\n\nHello World\n\n## /path/image2.png\n", output)
+}
+
 func TestFormatBatchResults_InvalidFormat(t *testing.T) {
 	results := []*pipeline.OCRImageResult{}
 	imagePaths := []string{}
 
-	output, err := formatBatchResults(results, imagePaths, "invalid")
+	output, err := formatBatchResults(results, imagePaths, "invalid", pipeline.ExportMetadata{})
 	require.NoError(t, err)
 	assert.Empty(t, output) // Invalid format defaults to text, empty results produce empty text
 }
@@ -79,7 +135,7 @@ func TestFormatBatchResults_EmptyResults(t *testing.T) {
 	results := []*pipeline.OCRImageResult{}
 	imagePaths := []string{}
 
-	output, err := formatBatchResults(results, imagePaths, "text")
+	output, err := formatBatchResults(results, imagePaths, "text", pipeline.ExportMetadata{})
 	require.NoError(t, err)
 	assert.Empty(t, output)
 }
@@ -1,26 +1,38 @@
 package batch
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"image"
 	"image/color"
 	"image/png"
+	"io"
 	"log/slog"
 	"os"
-	"path/filepath"
+	"path"
 	"strings"
 
 	"github.com/MeKo-Tech/pogo/internal/pipeline"
+	"github.com/MeKo-Tech/pogo/internal/storage"
 	"github.com/MeKo-Tech/pogo/internal/utils"
 )
 
-// loadAndValidateImage loads an image and validates it meets constraints.
-func loadAndValidateImage(path string) (image.Image, utils.ImageMetadata, error) {
+// loadAndValidateImage loads an image from a local path or storage location
+// and validates it meets constraints.
+func loadAndValidateImage(ctx context.Context, path string) (image.Image, utils.ImageMetadata, error) {
 	if !utils.IsSupportedImage(path) {
 		return nil, utils.ImageMetadata{}, fmt.Errorf("unsupported image format: %s", path)
 	}
 
-	img, meta, err := utils.LoadImage(path)
+	var img image.Image
+	var meta utils.ImageMetadata
+	var err error
+	if storage.IsRemote(path) {
+		img, meta, err = loadRemoteImage(ctx, path)
+	} else {
+		img, meta, err = utils.LoadImage(path)
+	}
 	if err != nil {
 		return nil, utils.ImageMetadata{}, fmt.Errorf("failed to load %s: %w", path, err)
 	}
@@ -33,6 +45,33 @@ func loadAndValidateImage(path string) (image.Image, utils.ImageMetadata, error)
 	return img, meta, nil
 }
 
+// loadRemoteImage downloads and decodes an image from object storage.
+func loadRemoteImage(ctx context.Context, location string) (image.Image, utils.ImageMetadata, error) {
+	r, err := storage.Open(ctx, location)
+	if err != nil {
+		return nil, utils.ImageMetadata{}, err
+	}
+	defer func() { _ = r.Close() }()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, utils.ImageMetadata{}, err
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, utils.ImageMetadata{}, &utils.ImageProcessingError{Operation: "decode", Err: err}
+	}
+	b := img.Bounds()
+	return img, utils.ImageMetadata{
+		Path:        location,
+		Format:      format,
+		SizeBytes:   int64(len(data)),
+		Width:       b.Dx(),
+		Height:      b.Dy(),
+		AspectRatio: float64(b.Dx()) / float64(b.Dy()),
+	}, nil
+}
+
 // applyConfidenceFilters applies detection and recognition confidence filters to OCR results.
 func applyConfidenceFilters(res *pipeline.OCRImageResult, confFlag, minRecConf float64) {
 	// Apply detection confidence filter
@@ -65,8 +104,9 @@ func applyConfidenceFilters(res *pipeline.OCRImageResult, confFlag, minRecConf f
 	}
 }
 
-// generateAndSaveOverlay creates an overlay image and saves it to disk.
-func generateAndSaveOverlay(img image.Image, res *pipeline.OCRImageResult,
+// generateAndSaveOverlay creates an overlay image and saves it to overlayDir,
+// a local directory or storage location.
+func generateAndSaveOverlay(ctx context.Context, img image.Image, res *pipeline.OCRImageResult,
 	meta utils.ImageMetadata, overlayDir string,
 ) {
 	ov := pipeline.RenderOverlay(img, res, color.RGBA{255, 0, 0, 255}, color.RGBA{0, 255, 0, 255})
@@ -74,31 +114,39 @@ func generateAndSaveOverlay(img image.Image, res *pipeline.OCRImageResult,
 		return
 	}
 
-	if err := os.MkdirAll(overlayDir, 0o750); err != nil {
-		return
+	if !storage.IsRemote(overlayDir) {
+		if err := os.MkdirAll(overlayDir, 0o750); err != nil {
+			return
+		}
 	}
 
-	base := filepath.Base(meta.Path)
-	outPath := filepath.Join(overlayDir, strings.TrimSuffix(base, filepath.Ext(base))+"_overlay.png")
-	if f, err := os.Create(outPath); err == nil { //nolint:gosec
-		// G304: outPath constructed from CLI overlay-dir flag, expected user input
-		_ = png.Encode(f, ov)
-		_ = f.Close()
+	base := storage.Base(meta.Path)
+	outPath := storage.Join(overlayDir, strings.TrimSuffix(base, path.Ext(base))+"_overlay.png")
+	f, err := storage.Create(ctx, outPath)
+	if err != nil {
+		slog.Warn("failed to save overlay", "file", outPath, "error", err)
+		return
+	}
+	if err := png.Encode(f, ov); err != nil {
+		slog.Warn("failed to encode overlay", "file", outPath, "error", err)
+	}
+	if err := f.Close(); err != nil {
+		slog.Warn("failed to save overlay", "file", outPath, "error", err)
 	}
 }
 
 // processSingleImage processes a single image through the OCR pipeline.
-func processSingleImage(pl *pipeline.Pipeline, path string, confFlag, minRecConf float64,
+func processSingleImage(ctx context.Context, pl *pipeline.Pipeline, path string, confFlag, minRecConf float64,
 	overlayDir string,
 ) (*pipeline.OCRImageResult, error) {
 	// Load and validate image
-	img, meta, err := loadAndValidateImage(path)
+	img, meta, err := loadAndValidateImage(ctx, path)
 	if err != nil {
 		return nil, err
 	}
 
 	// Process with OCR pipeline
-	res, err := pl.ProcessImage(img)
+	res, err := pl.ProcessImageContext(ctx, img)
 	if err != nil {
 		return nil, fmt.Errorf("OCR failed for %s: %w", path, err)
 	}
@@ -108,24 +156,32 @@ func processSingleImage(pl *pipeline.Pipeline, path string, confFlag, minRecConf
 
 	// Generate overlay if requested
 	if overlayDir != "" {
-		generateAndSaveOverlay(img, res, meta, overlayDir)
+		generateAndSaveOverlay(ctx, img, res, meta, overlayDir)
 	}
 
 	return res, nil
 }
 
-// processImagesParallel loads and processes images in parallel.
-func processImagesParallel(pl *pipeline.Pipeline, imagePaths []string,
+// processImagesParallel loads and processes images in parallel. When ctx
+// ends, it returns the results of the images finished so far and the
+// context's error.
+func processImagesParallel(ctx context.Context, pl *pipeline.Pipeline, imagePaths []string,
 	confFlag, minRecConf float64, overlayDir string,
 ) ([]*pipeline.OCRImageResult, error) {
-	imageResults := make([]*pipeline.OCRImageResult, len(imagePaths))
+	imageResults := make([]*pipeline.OCRImageResult, 0, len(imagePaths))
 
-	for i, path := range imagePaths {
-		res, err := processSingleImage(pl, path, confFlag, minRecConf, overlayDir)
+	for _, path := range imagePaths {
+		if err := ctx.Err(); err != nil {
+			return imageResults, err
+		}
+		res, err := processSingleImage(ctx, pl, path, confFlag, minRecConf, overlayDir)
 		if err != nil {
+			if ctx.Err() != nil {
+				return imageResults, ctx.Err()
+			}
 			return nil, err
 		}
-		imageResults[i] = res
+		imageResults = append(imageResults, res)
 	}
 
 	return imageResults, nil
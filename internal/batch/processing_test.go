@@ -1,15 +1,19 @@
 package batch
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"image"
 	"image/color"
 	"image/png"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/MeKo-Tech/pogo/internal/pipeline"
+	"github.com/MeKo-Tech/pogo/internal/storage/storagetest"
 	"github.com/MeKo-Tech/pogo/internal/testutil"
 	"github.com/MeKo-Tech/pogo/internal/utils"
 	"github.com/stretchr/testify/assert"
@@ -36,7 +40,7 @@ func TestLoadAndValidateImage_ValidImage(t *testing.T) {
 	err = png.Encode(file, img)
 	require.NoError(t, err)
 
-	loadedImg, meta, err := loadAndValidateImage(imagePath)
+	loadedImg, meta, err := loadAndValidateImage(t.Context(), imagePath)
 	require.NoError(t, err)
 	require.NotNil(t, loadedImg)
 	assert.Equal(t, imagePath, meta.Path)
@@ -51,7 +55,7 @@ func TestLoadAndValidateImage_UnsupportedFormat(t *testing.T) {
 	err := os.WriteFile(imagePath, []byte("not an image"), 0o600)
 	require.NoError(t, err)
 
-	loadedImg, meta, err := loadAndValidateImage(imagePath)
+	loadedImg, meta, err := loadAndValidateImage(t.Context(), imagePath)
 	require.Error(t, err)
 	assert.Nil(t, loadedImg)
 	assert.Equal(t, utils.ImageMetadata{}, meta)
@@ -59,7 +63,7 @@ func TestLoadAndValidateImage_UnsupportedFormat(t *testing.T) {
 }
 
 func TestLoadAndValidateImage_NonExistentFile(t *testing.T) {
-	loadedImg, meta, err := loadAndValidateImage("/nonexistent/file.png")
+	loadedImg, meta, err := loadAndValidateImage(t.Context(), "/nonexistent/file.png")
 	require.Error(t, err)
 	assert.Nil(t, loadedImg)
 	assert.Equal(t, utils.ImageMetadata{}, meta)
@@ -214,7 +218,7 @@ func TestGenerateAndSaveOverlay_ValidInputs(t *testing.T) {
 		Path: "/test/image.png",
 	}
 
-	generateAndSaveOverlay(img, result, meta, overlayDir)
+	generateAndSaveOverlay(t.Context(), img, result, meta, overlayDir)
 
 	// Check if overlay directory was created
 	assert.True(t, testutil.DirExists(overlayDir))
@@ -225,6 +229,30 @@ func TestGenerateAndSaveOverlay_ValidInputs(t *testing.T) {
 	assert.NotEmpty(t, overlayFiles)
 }
 
+func TestLoadAndSaveOverlay_S3(t *testing.T) {
+	srv := storagetest.NewS3Server(t)
+	srv.Configure(t)
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 20))))
+	srv.Put("docs", "scans/page.png", buf.Bytes())
+
+	img, meta, err := loadAndValidateImage(t.Context(), "s3://docs/scans/page.png")
+	require.NoError(t, err)
+	assert.Equal(t, "s3://docs/scans/page.png", meta.Path)
+	assert.Equal(t, "png", meta.Format)
+	assert.Equal(t, int64(buf.Len()), meta.SizeBytes)
+	assert.Equal(t, 40, meta.Width)
+
+	_, _, err = loadAndValidateImage(t.Context(), "s3://docs/scans/missing.png")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	generateAndSaveOverlay(t.Context(), img, &pipeline.OCRImageResult{}, meta, "s3://docs/overlays/")
+	overlay, ok := srv.Object("docs", "overlays/page_overlay.png")
+	require.True(t, ok)
+	_, err = png.Decode(bytes.NewReader(overlay))
+	require.NoError(t, err)
+}
+
 func TestGenerateAndSaveOverlay_NilOverlay(t *testing.T) {
 	tempDir := testutil.CreateTempDir(t)
 	overlayDir := filepath.Join(tempDir, "overlays")
@@ -234,7 +262,7 @@ func TestGenerateAndSaveOverlay_NilOverlay(t *testing.T) {
 	meta := utils.ImageMetadata{Path: "/test/image.png"}
 
 	// This should create an overlay file even for empty results
-	generateAndSaveOverlay(img, result, meta, overlayDir)
+	generateAndSaveOverlay(t.Context(), img, result, meta, overlayDir)
 
 	overlayFiles, err := filepath.Glob(filepath.Join(overlayDir, "*"))
 	require.NoError(t, err)
@@ -281,7 +309,7 @@ func TestProcessSingleImage_ValidImage(t *testing.T) {
 	pl, err := buildPipeline(config, nil)
 	require.NoError(t, err)
 
-	result, err := processSingleImage(pl, imagePath, 0.3, 0.0, "")
+	result, err := processSingleImage(context.Background(), pl, imagePath, 0.3, 0.0, "")
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	assert.Equal(t, 100, result.Width)
@@ -315,7 +343,7 @@ func TestProcessSingleImage_UnsupportedImage(t *testing.T) {
 	pl, err := buildPipeline(config, nil)
 	require.NoError(t, err)
 
-	result, err := processSingleImage(pl, imagePath, 0.3, 0.0, "")
+	result, err := processSingleImage(context.Background(), pl, imagePath, 0.3, 0.0, "")
 	require.Error(t, err)
 	assert.Nil(t, result)
 	assert.Contains(t, err.Error(), "unsupported image format")
@@ -365,7 +393,7 @@ func TestProcessImagesParallel_ValidImages(t *testing.T) {
 	pl, err := buildPipeline(config, nil)
 	require.NoError(t, err)
 
-	results, err := processImagesParallel(pl, imagePaths, 0.3, 0.0, "")
+	results, err := processImagesParallel(context.Background(), pl, imagePaths, 0.3, 0.0, "")
 	require.NoError(t, err)
 	assert.Len(t, results, 2)
 
@@ -416,8 +444,18 @@ func TestProcessImagesParallel_WithConfidenceFilters(t *testing.T) {
 	require.NoError(t, err)
 
 	// Test with high confidence filters (should still work even if no regions pass)
-	results, err := processImagesParallel(pl, []string{imagePath}, 0.9, 0.9, "")
+	results, err := processImagesParallel(context.Background(), pl, []string{imagePath}, 0.9, 0.9, "")
 	require.NoError(t, err)
 	assert.Len(t, results, 1)
 	assert.NotNil(t, results[0])
 }
+
+func TestProcessImagesParallel_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// No image is loaded once the context has ended.
+	results, err := processImagesParallel(ctx, nil, []string{"a.png", "b.png"}, 0, 0, "")
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, results)
+}
@@ -167,7 +167,7 @@ func (c *Config) validateBasicEnums() error {
 	}
 
 	// Validate output format
-	validFormats := []string{"text", "json", "csv"}
+	validFormats := []string{"text", "text-layout", "markdown", "json", "csv", "searchable-pdf", "hocr", "alto", "page"}
 	if c.Output.Format != "" && !contains(validFormats, c.Output.Format) {
 		return fmt.Errorf("invalid output format: %s (must be one of: %s)", c.Output.Format, strings.Join(validFormats, ", "))
 	}
@@ -317,6 +317,7 @@ func (c *Config) ToPipelineConfig() pipeline.Config {
         Parallel:            c.toParallelConfig(),
         Resource:            c.toResourceConfig(),
         Barcode:             c.toBarcodeConfig(),
+        PDF:                 pipeline.DefaultPDFConfig(),
     }
 }
 
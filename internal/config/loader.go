@@ -29,6 +29,13 @@ func NewLoader() *Loader {
 	return &Loader{v: viper.GetViper()}
 }
 
+// NewStandaloneLoader creates a configuration loader with its own viper
+// instance. It does not see flag bindings, but it can be used while
+// another goroutine reads the global instance.
+func NewStandaloneLoader() *Loader {
+	return &Loader{v: viper.New()}
+}
+
 // Load loads configuration from files, environment variables, and sets defaults.
 // It returns the loaded configuration and any error encountered.
 func (l *Loader) Load() (*Config, error) {
@@ -45,6 +45,29 @@ func TestNewLoader(t *testing.T) {
 	}
 }
 
+// TestNewStandaloneLoader tests that the loader does not share the global viper instance.
+func TestNewStandaloneLoader(t *testing.T) {
+	loader := NewStandaloneLoader()
+	if loader.v == nil || loader.v == viper.GetViper() {
+		t.Fatal("NewStandaloneLoader() must use its own viper instance")
+	}
+
+	configFile := filepath.Join(t.TempDir(), "pogo.yaml")
+	if err := os.WriteFile(configFile, []byte("pipeline:\n  recognizer:\n    language: fr\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := loader.LoadWithFile(configFile)
+	if err != nil {
+		t.Fatalf("LoadWithFile() unexpected error: %v", err)
+	}
+	if cfg.Pipeline.Recognizer.Language != "fr" {
+		t.Errorf("Expected language 'fr', got %s", cfg.Pipeline.Recognizer.Language)
+	}
+	if viper.ConfigFileUsed() == configFile {
+		t.Error("Global viper instance must not be changed")
+	}
+}
+
 // TestLoadWithNoConfigFile tests loading with no config file present.
 func TestLoadWithNoConfigFile(t *testing.T) {
 	clearPogoEnvVars()
@@ -0,0 +1,101 @@
+// Package jobs implements a durable queue of asynchronous OCR jobs. Jobs are
+// kept on the local filesystem, one directory per job, so queued work and
+// finished results survive a restart.
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// Status is the lifecycle state of a job.
+type Status string
+
+// Job statuses. Queued and running jobs are pending; the others are final.
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// Final reports whether the status can no longer change.
+func (s Status) Final() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCanceled
+}
+
+// Errors returned by the queue.
+var (
+	ErrNotFound  = errors.New("job not found")
+	ErrQueueFull = errors.New("job queue is full")
+	ErrClosed    = errors.New("job queue is closed")
+	ErrNoResult  = errors.New("job has no result")
+)
+
+// idLength is the length of a job ID in hex digits.
+const idLength = 16
+
+// Progress reports how far a job has come.
+type Progress struct {
+	// Total is the number of items (images or PDFs) in the job.
+	Total int `json:"total"`
+	// Completed is the number of items finished so far.
+	Completed int `json:"completed"`
+	// Pages is the number of PDF pages finished so far.
+	Pages int `json:"pages,omitempty"`
+}
+
+// Spec describes a job to submit.
+type Spec struct {
+	// Type names the kind of work, e.g. "image" or "pdf".
+	Type string
+	// Items is the number of items in the payload, reported as the
+	// progress total.
+	Items int
+	// CallbackURL, if set, is notified when the job finishes.
+	CallbackURL string
+	// Owner identifies the client that submitted the job, if known.
+	Owner string
+}
+
+// Job describes a queued, running or finished job.
+type Job struct {
+	ID          string     `json:"id"`
+	Type        string     `json:"type"`
+	Status      Status     `json:"status"`
+	Progress    Progress   `json:"progress"`
+	Error       string     `json:"error,omitempty"`
+	CallbackURL string     `json:"callback_url,omitempty"`
+	Owner       string     `json:"owner,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	// ExpiresAt is when a finished job and its result are removed.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// newID returns a random job ID.
+func newID() (string, error) {
+	b := make([]byte, idLength/2)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validID reports whether id has the form of a generated job ID, which also
+// keeps IDs from naming paths outside the queue directory.
+func validID(id string) bool {
+	if len(id) != idLength {
+		return false
+	}
+	for _, c := range id {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
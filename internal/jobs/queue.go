@@ -0,0 +1,414 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults for zero Config fields.
+const (
+	DefaultWorkers   = 1
+	DefaultRetention = 24 * time.Hour
+	DefaultMaxQueued = 100
+)
+
+// Config configures a job queue.
+type Config struct {
+	// Dir is the directory jobs are stored in.
+	Dir string
+	// Workers is the number of jobs processed concurrently.
+	Workers int
+	// Retention is how long finished jobs and their results are kept.
+	Retention time.Duration
+	// MaxQueued limits the number of jobs waiting to be processed.
+	MaxQueued int
+	// OnFinish, if set, is called in its own goroutine whenever a job
+	// reaches a final status, with the result of a succeeded job.
+	OnFinish func(job Job, result []byte)
+}
+
+// ProgressFunc reports the progress of a running job.
+type ProgressFunc func(Progress)
+
+// Handler processes the payload of a job and returns its result. It should
+// stop early when ctx ends, which happens when the job is canceled or the
+// queue is closed.
+type Handler func(ctx context.Context, job Job, payload []byte, progress ProgressFunc) ([]byte, error)
+
+// Queue runs jobs on a fixed number of workers in submission order. Jobs
+// that were queued or running when the queue was closed are run again when
+// it is reopened on the same directory.
+type Queue struct {
+	cfg     Config
+	store   *store
+	handler Handler
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	pending []string
+	running map[string]context.CancelFunc
+	// reserved counts the jobs Submit is storing without holding mu.
+	reserved int
+	closed   bool
+
+	wake chan struct{}
+	ctx  context.Context
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+	now  func() time.Time
+}
+
+// Open loads the jobs stored in cfg.Dir and starts processing them with
+// handler.
+func Open(cfg Config, handler Handler) (*Queue, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("job directory is required")
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultWorkers
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = DefaultRetention
+	}
+	if cfg.MaxQueued <= 0 {
+		cfg.MaxQueued = DefaultMaxQueued
+	}
+
+	st, err := newStore(cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := st.load()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	q := &Queue{
+		cfg:     cfg,
+		store:   st,
+		handler: handler,
+		jobs:    make(map[string]*Job, len(stored)),
+		running: make(map[string]context.CancelFunc),
+		wake:    make(chan struct{}, cfg.Workers),
+		ctx:     ctx,
+		stop:    stop,
+		now:     time.Now,
+	}
+
+	// Requeue unfinished jobs in their original order; jobs that were
+	// running when the process stopped start over.
+	sort.Slice(stored, func(i, j int) bool { return stored[i].CreatedAt.Before(stored[j].CreatedAt) })
+	for _, job := range stored {
+		q.jobs[job.ID] = job
+		if job.Status.Final() {
+			continue
+		}
+		if job.Status == StatusRunning {
+			job.Status = StatusQueued
+			job.StartedAt = nil
+			job.Progress.Completed, job.Progress.Pages = 0, 0
+			if err := st.save(job); err != nil {
+				slog.Warn("Failed to requeue job", "id", job.ID, "error", err)
+			}
+		}
+		q.pending = append(q.pending, job.ID)
+	}
+	q.sweep()
+
+	for range cfg.Workers {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	q.wg.Add(1)
+	go q.janitor()
+	return q, nil
+}
+
+// Submit stores a new job with the given payload and queues it.
+func (q *Queue) Submit(spec Spec, payload []byte) (Job, error) {
+	id, err := newID()
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to generate job id: %w", err)
+	}
+	job := &Job{
+		ID:          id,
+		Type:        spec.Type,
+		Status:      StatusQueued,
+		Progress:    Progress{Total: spec.Items},
+		CallbackURL: spec.CallbackURL,
+		Owner:       spec.Owner,
+		CreatedAt:   q.now().UTC(),
+	}
+
+	// Reserve a slot and store the job without holding the lock, so that
+	// lookups are not blocked by the disk.
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return Job{}, ErrClosed
+	}
+	if len(q.pending)+q.reserved >= q.cfg.MaxQueued {
+		q.mu.Unlock()
+		return Job{}, ErrQueueFull
+	}
+	q.reserved++
+	q.mu.Unlock()
+
+	err = q.store.create(job, payload)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.reserved--
+	if err != nil {
+		return Job{}, err
+	}
+	// A job stored while the queue was closing stays queued on disk and
+	// runs after the next start.
+	q.jobs[id] = job
+	q.pending = append(q.pending, id)
+	select {
+	case q.wake <- struct{}{}:
+	default: // all workers are already signaled
+	}
+	return *job, nil
+}
+
+// Get returns the current state of a job.
+func (q *Queue) Get(id string) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return *job, nil
+}
+
+// Result opens the result of a succeeded job. It returns ErrNoResult if the
+// job has not succeeded (yet).
+func (q *Queue) Result(id string) (io.ReadCloser, error) {
+	q.mu.Lock()
+	job, ok := q.jobs[id]
+	var status Status
+	if ok {
+		status = job.Status
+	}
+	q.mu.Unlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if status != StatusSucceeded {
+		return nil, ErrNoResult
+	}
+	return q.store.openResult(id)
+}
+
+// Cancel stops a queued or running job. A running job is marked as canceled
+// right away; its handler is asked to stop through its context. Canceling a
+// finished job has no effect.
+func (q *Queue) Cancel(id string) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	if job.Status.Final() {
+		return *job, nil
+	}
+	if cancel, running := q.running[id]; running {
+		cancel()
+	} else {
+		q.removePending(id)
+	}
+	q.finish(job, StatusCanceled, "", nil)
+	return *job, nil
+}
+
+// Delete removes a finished job and its result.
+func (q *Queue) Delete(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if !job.Status.Final() {
+		return fmt.Errorf("job %s is %s", id, job.Status)
+	}
+	delete(q.jobs, id)
+	return q.store.remove(id)
+}
+
+// Close stops the workers and waits for them to return. Running jobs are
+// interrupted and stay queued on disk, so they run again after a restart.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	q.mu.Unlock()
+
+	q.stop()
+	q.wg.Wait()
+	return nil
+}
+
+func (q *Queue) worker() {
+	defer q.wg.Done()
+	for {
+		if job, ctx, ok := q.next(); ok {
+			q.run(ctx, job)
+			continue
+		}
+		select {
+		case <-q.wake:
+		case <-q.ctx.Done():
+			return
+		}
+	}
+}
+
+// next takes the oldest queued job and marks it as running.
+func (q *Queue) next() (Job, context.Context, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed || len(q.pending) == 0 {
+		return Job{}, nil, false
+	}
+	id := q.pending[0]
+	q.pending = q.pending[1:]
+	job := q.jobs[id]
+
+	ctx, cancel := context.WithCancel(q.ctx)
+	q.running[id] = cancel
+	started := q.now().UTC()
+	job.Status = StatusRunning
+	job.StartedAt = &started
+	q.save(job)
+	return *job, ctx, true
+}
+
+func (q *Queue) run(ctx context.Context, job Job) {
+	var result []byte
+	payload, err := q.store.payload(job.ID)
+	if err == nil {
+		result, err = q.handler(ctx, job, payload, func(p Progress) { q.setProgress(job.ID, p) })
+	}
+	if err == nil {
+		// A job canceled while its handler ran keeps no result.
+		err = ctx.Err()
+	}
+	if err == nil {
+		err = q.store.writeResult(job.ID, result)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.running[job.ID]()
+	delete(q.running, job.ID)
+	current := q.jobs[job.ID]
+	switch {
+	case current == nil || current.Status != StatusRunning:
+		// Canceled (and possibly deleted) while running; drop a result
+		// written just before the cancellation.
+		if current != nil && err == nil {
+			if err := q.store.removeResult(job.ID); err != nil {
+				slog.Warn("Failed to remove result of canceled job", "id", job.ID, "error", err)
+			}
+		}
+	case err == nil:
+		q.finish(current, StatusSucceeded, "", result)
+	case q.ctx.Err() != nil:
+		// Interrupted by Close: leave it for the next start.
+		current.Status = StatusQueued
+		current.StartedAt = nil
+		current.Progress.Completed, current.Progress.Pages = 0, 0
+		q.save(current)
+	default:
+		q.finish(current, StatusFailed, err.Error(), nil)
+	}
+}
+
+func (q *Queue) setProgress(id string, p Progress) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := q.jobs[id]
+	if job == nil || job.Status != StatusRunning {
+		return
+	}
+	job.Progress = p
+	q.save(job)
+}
+
+// finish moves job to a final status, schedules its removal and calls the
+// OnFinish hook. The caller holds q.mu.
+func (q *Queue) finish(job *Job, status Status, errMsg string, result []byte) {
+	finished := q.now().UTC()
+	expires := finished.Add(q.cfg.Retention)
+	job.Status = status
+	job.Error = errMsg
+	job.FinishedAt = &finished
+	job.ExpiresAt = &expires
+	q.save(job)
+	if q.cfg.OnFinish != nil {
+		go q.cfg.OnFinish(*job, result)
+	}
+}
+
+// save persists job, logging failures: the in-memory state stays
+// authoritative while the process runs. The caller holds q.mu.
+func (q *Queue) save(job *Job) {
+	if err := q.store.save(job); err != nil {
+		slog.Warn("Failed to persist job state", "id", job.ID, "error", err)
+	}
+}
+
+func (q *Queue) removePending(id string) {
+	for i, p := range q.pending {
+		if p == id {
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// janitor periodically removes expired jobs.
+func (q *Queue) janitor() {
+	defer q.wg.Done()
+	ticker := time.NewTicker(min(q.cfg.Retention, time.Minute))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			q.sweep()
+		case <-q.ctx.Done():
+			return
+		}
+	}
+}
+
+// sweep removes finished jobs whose retention has passed.
+func (q *Queue) sweep() {
+	now := q.now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for id, job := range q.jobs {
+		if job.Status.Final() && job.ExpiresAt != nil && now.After(*job.ExpiresAt) {
+			if err := q.store.remove(id); err != nil {
+				slog.Warn("Failed to remove expired job", "id", id, "error", err)
+				continue
+			}
+			delete(q.jobs, id)
+		}
+	}
+}
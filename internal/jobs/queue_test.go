@@ -0,0 +1,269 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitStatus polls until the job reaches want.
+func waitStatus(t *testing.T, q *Queue, id string, want Status) Job {
+	t.Helper()
+	var job Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = q.Get(id)
+		require.NoError(t, err)
+		return job.Status == want
+	}, 5*time.Second, 5*time.Millisecond, "job %s never reached %s", id, want)
+	return job
+}
+
+func readResult(t *testing.T, q *Queue, id string) string {
+	t.Helper()
+	rc, err := q.Result(id)
+	require.NoError(t, err)
+	defer func() { _ = rc.Close() }()
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestQueue_RunsJobs(t *testing.T) {
+	q, err := Open(Config{Dir: t.TempDir()}, func(_ context.Context, job Job, payload []byte, progress ProgressFunc) ([]byte, error) {
+		progress(Progress{Total: 1, Completed: 1, Pages: 3})
+		if string(payload) == "fail" {
+			return nil, errors.New("boom")
+		}
+		return []byte(job.Type + ":" + string(payload)), nil
+	})
+	require.NoError(t, err)
+	defer func() { _ = q.Close() }()
+
+	ok, err := q.Submit(Spec{Type: "image", Items: 1}, []byte("data"))
+	require.NoError(t, err)
+	assert.Equal(t, StatusQueued, ok.Status)
+	assert.Len(t, ok.ID, idLength)
+
+	bad, err := q.Submit(Spec{Type: "pdf", Items: 1}, []byte("fail"))
+	require.NoError(t, err)
+
+	job := waitStatus(t, q, ok.ID, StatusSucceeded)
+	assert.Equal(t, Progress{Total: 1, Completed: 1, Pages: 3}, job.Progress)
+	require.NotNil(t, job.StartedAt)
+	require.NotNil(t, job.FinishedAt)
+	require.NotNil(t, job.ExpiresAt)
+	assert.Equal(t, job.FinishedAt.Add(DefaultRetention), *job.ExpiresAt)
+	assert.Equal(t, "image:data", readResult(t, q, ok.ID))
+
+	job = waitStatus(t, q, bad.ID, StatusFailed)
+	assert.Equal(t, "boom", job.Error)
+	_, err = q.Result(bad.ID)
+	require.ErrorIs(t, err, ErrNoResult)
+
+	_, err = q.Get("0123456789abcdef")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, q.Delete(ok.ID))
+	_, err = q.Get(ok.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+	assert.NoDirExists(t, filepath.Join(q.cfg.Dir, ok.ID))
+}
+
+func TestQueue_Cancel(t *testing.T) {
+	started := make(chan struct{})
+	q, err := Open(Config{Dir: t.TempDir()}, func(ctx context.Context, _ Job, _ []byte, _ ProgressFunc) ([]byte, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	require.NoError(t, err)
+	defer func() { _ = q.Close() }()
+
+	running, err := q.Submit(Spec{Type: "pdf", Items: 1}, nil)
+	require.NoError(t, err)
+	<-started
+	queued, err := q.Submit(Spec{Type: "pdf", Items: 1}, nil)
+	require.NoError(t, err)
+
+	require.Error(t, q.Delete(queued.ID), "pending jobs cannot be deleted")
+
+	job, err := q.Cancel(queued.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCanceled, job.Status)
+
+	job, err = q.Cancel(running.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCanceled, job.Status)
+
+	// The handler has stopped once the worker released the job.
+	require.Eventually(t, func() bool {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		return len(q.running) == 0
+	}, 5*time.Second, 5*time.Millisecond)
+	job, err = q.Get(running.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCanceled, job.Status)
+	_, err = q.Result(running.ID)
+	require.ErrorIs(t, err, ErrNoResult)
+}
+
+func TestQueue_CancelKeepsNoResult(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	q, err := Open(Config{Dir: t.TempDir()}, func(context.Context, Job, []byte, ProgressFunc) ([]byte, error) {
+		close(started)
+		<-release // ignores the cancellation and succeeds
+		return []byte("late"), nil
+	})
+	require.NoError(t, err)
+	defer func() { _ = q.Close() }()
+
+	job, err := q.Submit(Spec{Type: "image", Items: 1}, nil)
+	require.NoError(t, err)
+	<-started
+	_, err = q.Cancel(job.ID)
+	require.NoError(t, err)
+	close(release)
+
+	require.Eventually(t, func() bool {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		return len(q.running) == 0
+	}, 5*time.Second, 5*time.Millisecond)
+	job, err = q.Get(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCanceled, job.Status)
+	assert.NoFileExists(t, filepath.Join(q.cfg.Dir, job.ID, resultFile))
+}
+
+func TestQueue_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	started := make(chan struct{}, 1)
+	q, err := Open(Config{Dir: dir}, func(ctx context.Context, _ Job, _ []byte, progress ProgressFunc) ([]byte, error) {
+		progress(Progress{Total: 1, Pages: 1})
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	require.NoError(t, err)
+
+	interrupted, err := q.Submit(Spec{Type: "pdf", Items: 1}, []byte("a"))
+	require.NoError(t, err)
+	<-started
+	waiting, err := q.Submit(Spec{Type: "image", Items: 1}, []byte("b"))
+	require.NoError(t, err)
+	require.NoError(t, q.Close())
+
+	_, err = q.Submit(Spec{Type: "image", Items: 1}, nil)
+	require.ErrorIs(t, err, ErrClosed)
+
+	var order []string
+	q, err = Open(Config{Dir: dir}, func(_ context.Context, job Job, payload []byte, _ ProgressFunc) ([]byte, error) {
+		order = append(order, job.ID)
+		return payload, nil
+	})
+	require.NoError(t, err)
+	defer func() { _ = q.Close() }()
+
+	job := waitStatus(t, q, waiting.ID, StatusSucceeded)
+	assert.Zero(t, job.Progress.Pages)
+	waitStatus(t, q, interrupted.ID, StatusSucceeded)
+	assert.Equal(t, []string{interrupted.ID, waiting.ID}, order)
+	assert.Equal(t, "a", readResult(t, q, interrupted.ID))
+	assert.Equal(t, "b", readResult(t, q, waiting.ID))
+}
+
+func TestQueue_MaxQueued(t *testing.T) {
+	block := make(chan struct{})
+	q, err := Open(Config{Dir: t.TempDir(), MaxQueued: 1}, func(context.Context, Job, []byte, ProgressFunc) ([]byte, error) {
+		<-block
+		return nil, nil
+	})
+	require.NoError(t, err)
+	defer func() { _ = q.Close() }()
+	defer close(block)
+
+	first, err := q.Submit(Spec{Type: "image", Items: 1}, nil)
+	require.NoError(t, err)
+	waitStatus(t, q, first.ID, StatusRunning)
+
+	_, err = q.Submit(Spec{Type: "image", Items: 1}, nil)
+	require.NoError(t, err)
+	_, err = q.Submit(Spec{Type: "image", Items: 1}, nil)
+	require.ErrorIs(t, err, ErrQueueFull)
+}
+
+func TestQueue_Retention(t *testing.T) {
+	dir := t.TempDir()
+	q, err := Open(Config{Dir: dir, Retention: time.Hour}, func(context.Context, Job, []byte, ProgressFunc) ([]byte, error) {
+		return []byte("{}"), nil
+	})
+	require.NoError(t, err)
+	defer func() { _ = q.Close() }()
+
+	job, err := q.Submit(Spec{Type: "image", Items: 1}, nil)
+	require.NoError(t, err)
+	waitStatus(t, q, job.ID, StatusSucceeded)
+
+	q.sweep()
+	_, err = q.Get(job.ID)
+	require.NoError(t, err, "job is kept within its retention")
+
+	q.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	q.sweep()
+	_, err = q.Get(job.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+	assert.NoDirExists(t, filepath.Join(dir, job.ID))
+}
+
+func TestStore_LoadSkipsIncompleteJobs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "0123456789abcdef"), 0o750))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "not-a-job"), 0o750))
+
+	st, err := newStore(dir)
+	require.NoError(t, err)
+	jobs, err := st.load()
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+	assert.NoDirExists(t, filepath.Join(dir, "0123456789abcdef"))
+	assert.DirExists(t, filepath.Join(dir, "not-a-job"), "foreign directories are left alone")
+}
+
+func TestQueue_OnFinish(t *testing.T) {
+	type finished struct {
+		job    Job
+		result string
+	}
+	done := make(chan finished, 2)
+	q, err := Open(Config{
+		Dir:      t.TempDir(),
+		OnFinish: func(job Job, result []byte) { done <- finished{job, string(result)} },
+	}, func(context.Context, Job, []byte, ProgressFunc) ([]byte, error) {
+		return []byte(`{"ok":true}`), nil
+	})
+	require.NoError(t, err)
+	defer func() { _ = q.Close() }()
+
+	job, err := q.Submit(Spec{Type: "image", Items: 1, CallbackURL: "https://example.com/hook"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/hook", job.CallbackURL)
+
+	select {
+	case f := <-done:
+		assert.Equal(t, job.ID, f.job.ID)
+		assert.Equal(t, StatusSucceeded, f.job.Status)
+		assert.Equal(t, "https://example.com/hook", f.job.CallbackURL)
+		assert.JSONEq(t, `{"ok":true}`, f.result)
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnFinish was not called")
+	}
+}
@@ -0,0 +1,149 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// File names inside a job directory.
+const (
+	jobFile     = "job.json"
+	payloadFile = "payload"
+	resultFile  = "result.json"
+)
+
+// store keeps jobs on disk, one directory per job ID holding the job state,
+// its payload and, once finished, its result.
+type store struct {
+	dir string
+}
+
+func newStore(dir string) (*store, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create job directory: %w", err)
+	}
+	return &store{dir: dir}, nil
+}
+
+func (s *store) path(id, name string) string {
+	return filepath.Join(s.dir, id, name)
+}
+
+// create writes the payload and the initial state of a new job. The state is
+// written last so that a crash in between leaves no half-created job behind.
+func (s *store) create(job *Job, payload []byte) error {
+	if err := os.Mkdir(filepath.Join(s.dir, job.ID), 0o750); err != nil {
+		return fmt.Errorf("failed to create job %s: %w", job.ID, err)
+	}
+	if err := writeFileAtomic(s.path(job.ID, payloadFile), payload); err != nil {
+		_ = s.remove(job.ID)
+		return fmt.Errorf("failed to write payload of job %s: %w", job.ID, err)
+	}
+	if err := s.save(job); err != nil {
+		_ = s.remove(job.ID)
+		return err
+	}
+	return nil
+}
+
+// save writes the current state of a job.
+func (s *store) save(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job %s: %w", job.ID, err)
+	}
+	if err := writeFileAtomic(s.path(job.ID, jobFile), data); err != nil {
+		return fmt.Errorf("failed to save job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// load reads all stored jobs. Directories without a readable state, left by
+// an interrupted create, are removed.
+func (s *store) load() ([]*Job, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job directory: %w", err)
+	}
+	var jobs []*Job
+	for _, e := range entries {
+		if !e.IsDir() || !validID(e.Name()) {
+			continue
+		}
+		data, err := os.ReadFile(s.path(e.Name(), jobFile))
+		var job Job
+		if err == nil {
+			err = json.Unmarshal(data, &job)
+		}
+		if err != nil || job.ID != e.Name() {
+			slog.Warn("Removing unreadable job", "id", e.Name(), "error", err)
+			_ = s.remove(e.Name())
+			continue
+		}
+		jobs = append(jobs, &job)
+	}
+	return jobs, nil
+}
+
+func (s *store) payload(id string) ([]byte, error) {
+	return os.ReadFile(s.path(id, payloadFile))
+}
+
+func (s *store) writeResult(id string, data []byte) error {
+	if err := writeFileAtomic(s.path(id, resultFile), data); err != nil {
+		return fmt.Errorf("failed to write result of job %s: %w", id, err)
+	}
+	return nil
+}
+
+func (s *store) openResult(id string) (io.ReadCloser, error) {
+	f, err := os.Open(s.path(id, resultFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoResult
+	}
+	return f, err
+}
+
+func (s *store) removeResult(id string) error {
+	if err := os.Remove(s.path(id, resultFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove result of job %s: %w", id, err)
+	}
+	return nil
+}
+
+func (s *store) remove(id string) error {
+	return os.RemoveAll(filepath.Join(s.dir, id))
+}
+
+// writeFileAtomic replaces path with data via a temporary file and a rename,
+// so readers never see a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,383 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// bitmap is a bilevel image with one byte per pixel, 1 meaning black.
+type bitmap struct {
+	w, h int
+	pix  []byte
+}
+
+func newBitmap(w, h int) *bitmap {
+	return &bitmap{w: w, h: h, pix: make([]byte, w*h)}
+}
+
+// at returns the pixel at (x, y); pixels outside the bitmap are white.
+func (b *bitmap) at(x, y int) byte {
+	if x < 0 || y < 0 || x >= b.w || y >= b.h {
+		return 0
+	}
+	return b.pix[y*b.w+x]
+}
+
+// bitReader reads a byte slice most significant bit first.
+type bitReader struct {
+	data []byte
+	pos  int // in bits
+}
+
+func (r *bitReader) remaining() int { return len(r.data)*8 - r.pos }
+
+// peek returns the next n (≤ 32) bits, padded with zeros past the end.
+func (r *bitReader) peek(n int) uint32 {
+	var v uint32
+	for i := range n {
+		v <<= 1
+		p := r.pos + i
+		if p/8 < len(r.data) {
+			v |= uint32(r.data[p/8]>>(7-uint(p%8))) & 1
+		}
+	}
+	return v
+}
+
+func (r *bitReader) skip(n int) { r.pos += n }
+
+func (r *bitReader) read(n int) uint32 {
+	v := r.peek(n)
+	r.pos += n
+	return v
+}
+
+func (r *bitReader) align() { r.pos = (r.pos + 7) &^ 7 }
+
+// ccittParams are the CCITTFaxDecode parameters (PDF 32000-1, table 11).
+type ccittParams struct {
+	K                int // < 0: Group 4, 0: Group 3 1-D, > 0: Group 3 mixed 1-D/2-D
+	Columns          int
+	Rows             int // 0 = until the end of the data
+	EndOfLine        bool
+	EncodedByteAlign bool
+	BlackIs1         bool
+}
+
+// Code tables of ITU-T T.4: bit strings and the run lengths (or 2-D modes) they encode.
+var (
+	whiteCodes = map[string]int{
+		"00110101": 0, "000111": 1, "0111": 2, "1000": 3, "1011": 4, "1100": 5, "1110": 6, "1111": 7,
+		"10011": 8, "10100": 9, "00111": 10, "01000": 11, "001000": 12, "000011": 13, "110100": 14,
+		"110101": 15, "101010": 16, "101011": 17, "0100111": 18, "0001100": 19, "0001000": 20,
+		"0010111": 21, "0000011": 22, "0000100": 23, "0101000": 24, "0101011": 25, "0010011": 26,
+		"0100100": 27, "0011000": 28, "00000010": 29, "00000011": 30, "00011010": 31, "00011011": 32,
+		"00010010": 33, "00010011": 34, "00010100": 35, "00010101": 36, "00010110": 37, "00010111": 38,
+		"00101000": 39, "00101001": 40, "00101010": 41, "00101011": 42, "00101100": 43, "00101101": 44,
+		"00000100": 45, "00000101": 46, "00001010": 47, "00001011": 48, "01010010": 49, "01010011": 50,
+		"01010100": 51, "01010101": 52, "00100100": 53, "00100101": 54, "01011000": 55, "01011001": 56,
+		"01011010": 57, "01011011": 58, "01001010": 59, "01001011": 60, "00110010": 61, "00110011": 62,
+		"00110100": 63,
+		"11011":    64, "10010": 128, "010111": 192, "0110111": 256, "00110110": 320, "00110111": 384,
+		"01100100": 448, "01100101": 512, "01101000": 576, "01100111": 640, "011001100": 704,
+		"011001101": 768, "011010010": 832, "011010011": 896, "011010100": 960, "011010101": 1024,
+		"011010110": 1088, "011010111": 1152, "011011000": 1216, "011011001": 1280, "011011010": 1344,
+		"011011011": 1408, "010011000": 1472, "010011001": 1536, "010011010": 1600, "011000": 1664,
+		"010011011": 1728,
+	}
+	blackCodes = map[string]int{
+		"0000110111": 0, "010": 1, "11": 2, "10": 3, "011": 4, "0011": 5, "0010": 6, "00011": 7,
+		"000101": 8, "000100": 9, "0000100": 10, "0000101": 11, "0000111": 12, "00000100": 13,
+		"00000111": 14, "000011000": 15, "0000010111": 16, "0000011000": 17, "0000001000": 18,
+		"00001100111": 19, "00001101000": 20, "00001101100": 21, "00000110111": 22, "00000101000": 23,
+		"00000010111": 24, "00000011000": 25, "000011001010": 26, "000011001011": 27, "000011001100": 28,
+		"000011001101": 29, "000001101000": 30, "000001101001": 31, "000001101010": 32, "000001101011": 33,
+		"000011010010": 34, "000011010011": 35, "000011010100": 36, "000011010101": 37, "000011010110": 38,
+		"000011010111": 39, "000001101100": 40, "000001101101": 41, "000011011010": 42, "000011011011": 43,
+		"000001010100": 44, "000001010101": 45, "000001010110": 46, "000001010111": 47, "000001100100": 48,
+		"000001100101": 49, "000001010010": 50, "000001010011": 51, "000000100100": 52, "000000110111": 53,
+		"000000111000": 54, "000000100111": 55, "000000101000": 56, "000001011000": 57, "000001011001": 58,
+		"000000101011": 59, "000000101100": 60, "000001011010": 61, "000001100110": 62, "000001100111": 63,
+		"0000001111": 64, "000011001000": 128, "000011001001": 192, "000001011011": 256,
+		"000000110011": 320, "000000110100": 384, "000000110101": 448, "0000001101100": 512,
+		"0000001101101": 576, "0000001001010": 640, "0000001001011": 704, "0000001001100": 768,
+		"0000001001101": 832, "0000001110010": 896, "0000001110011": 960, "0000001110100": 1024,
+		"0000001110101": 1088, "0000001110110": 1152, "0000001110111": 1216, "0000001010010": 1280,
+		"0000001010011": 1344, "0000001010100": 1408, "0000001010101": 1472, "0000001011010": 1536,
+		"0000001011011": 1600, "0000001100100": 1664, "0000001100101": 1728,
+	}
+	// extendedMakeupCodes are shared by both colors.
+	extendedMakeupCodes = map[string]int{
+		"00000001000": 1792, "00000001100": 1856, "00000001101": 1920, "000000010010": 1984,
+		"000000010011": 2048, "000000010100": 2112, "000000010101": 2176, "000000010110": 2240,
+		"000000010111": 2304, "000000011100": 2368, "000000011101": 2432, "000000011110": 2496,
+		"000000011111": 2560,
+	}
+	modeCodes = map[string]int{
+		"0001": modePass, "001": modeHorizontal, "1": modeV0,
+		"011": modeVR1, "000011": modeVR2, "0000011": modeVR3,
+		"010": modeVL1, "000010": modeVL2, "0000010": modeVL3,
+	}
+
+	whiteTable = newCodeTable(whiteCodes, extendedMakeupCodes)
+	blackTable = newCodeTable(blackCodes, extendedMakeupCodes)
+	modeTable  = newCodeTable(modeCodes)
+)
+
+// 2-D coding modes; the vertical modes are offset so that mode-modeV0 is a1-b1.
+const (
+	modePass = iota
+	modeHorizontal
+	modeVL3
+	modeVL2
+	modeVL1
+	modeV0
+	modeVR1
+	modeVR2
+	modeVR3
+)
+
+const maxCodeLength = 13
+
+// codeTable maps (length, code) to a value, indexed by code length.
+type codeTable [maxCodeLength + 1]map[uint32]int
+
+func newCodeTable(sets ...map[string]int) *codeTable {
+	var t codeTable
+	for i := range t {
+		t[i] = map[uint32]int{}
+	}
+	for _, set := range sets {
+		for bits, v := range set {
+			var code uint32
+			for _, c := range bits {
+				code = code<<1 | uint32(c-'0')
+			}
+			t[len(bits)][code] = v
+		}
+	}
+	return &t
+}
+
+var errCCITTCode = errors.New("invalid code")
+
+func (r *bitReader) code(t *codeTable) (int, error) {
+	for n := 1; n <= maxCodeLength && n <= r.remaining(); n++ {
+		if v, ok := t[n][r.peek(n)]; ok {
+			r.skip(n)
+			return v, nil
+		}
+	}
+	return 0, errCCITTCode
+}
+
+// run reads a run length: make-up codes followed by a terminating code.
+func (r *bitReader) run(black bool) (int, error) {
+	t := whiteTable
+	if black {
+		t = blackTable
+	}
+	total := 0
+	for {
+		v, err := r.code(t)
+		if err != nil {
+			return 0, err
+		}
+		total += v
+		if v < 64 {
+			return total, nil
+		}
+	}
+}
+
+// eol consumes fill bits and an end-of-line code if present.
+func (r *bitReader) eol() bool {
+	start := r.pos
+	for r.remaining() >= 12 {
+		switch r.peek(12) {
+		case 1:
+			r.skip(12)
+			return true
+		case 0:
+			r.skip(1)
+		default:
+			r.pos = start
+			return false
+		}
+	}
+	r.pos = start
+	return false
+}
+
+// decodeCCITT decodes CCITT Group 3 and Group 4 fax data into a bitmap. When
+// the data ends or turns invalid after at least one row, the rows decoded so
+// far are returned together with the error.
+func decodeCCITT(data []byte, p ccittParams) (*bitmap, error) {
+	w := p.Columns
+	if w <= 0 {
+		w = 1728
+	}
+	br := &bitReader{data: data}
+	var pix []byte
+	ref := []int{w, w} // changing elements of the reference line: all white
+	var cur []int
+	rows := 0
+
+	var err error
+	for (p.Rows <= 0 || rows < p.Rows) && br.remaining() > 0 {
+		// Encoded lines start on a byte boundary: for Group 4 the line itself,
+		// for Group 3 the data following an EOL.
+		if p.EncodedByteAlign && p.K < 0 {
+			br.align()
+		}
+		if br.eol() {
+			// Two EOLs in a row are the end-of-block (RTC/EOFB) marker.
+			if p.K > 0 && br.peek(13) == 0b1000000000001 || p.K <= 0 && br.peek(12) == 1 {
+				break
+			}
+		} else if br.remaining() < 8 && br.peek(br.remaining()) == 0 {
+			break // padding
+		}
+		if p.EncodedByteAlign && p.K >= 0 {
+			br.align()
+		}
+		twoD := p.K < 0
+		if p.K > 0 {
+			twoD = br.read(1) == 0
+		}
+		cur = cur[:0]
+		if twoD {
+			cur, err = decode2DLine(br, ref, cur, w)
+		} else {
+			cur, err = decode1DLine(br, cur, w)
+		}
+		if err != nil {
+			break
+		}
+		row := make([]byte, w)
+		for i := 0; i < len(cur); i += 2 {
+			end := w
+			if i+1 < len(cur) {
+				end = cur[i+1]
+			}
+			for x := cur[i]; x < end; x++ {
+				row[x] = 1
+			}
+		}
+		pix = append(pix, row...)
+		rows++
+		ref = append(ref[:0], cur...)
+	}
+	if rows == 0 {
+		if err == nil {
+			err = errors.New("no data")
+		}
+		return nil, fmt.Errorf("ccitt: %w", err)
+	}
+	bm := &bitmap{w: w, h: rows, pix: pix}
+	if err != nil {
+		return bm, fmt.Errorf("ccitt: row %d: %w", rows+1, err)
+	}
+	return bm, nil
+}
+
+// decode1DLine decodes alternating white and black runs (modified Huffman).
+func decode1DLine(br *bitReader, cur []int, w int) ([]int, error) {
+	pos, black := 0, false
+	for pos < w {
+		n, err := br.run(black)
+		if err != nil {
+			return cur, err
+		}
+		pos = min(pos+n, w)
+		cur = append(cur, pos)
+		black = !black
+	}
+	return cur, nil
+}
+
+// decode2DLine decodes a line relative to the reference line's changing
+// elements (modified READ).
+func decode2DLine(br *bitReader, ref, cur []int, w int) ([]int, error) {
+	refAt := func(i int) int {
+		if i < len(ref) {
+			return ref[i]
+		}
+		return w
+	}
+	a0, color, j := -1, 0, 0
+	for a0 < w {
+		// b1 is the first changing element right of a0 of the opposite
+		// color; changes at even indices turn black.
+		for j < len(ref) && ref[j] <= a0 {
+			j++
+		}
+		if j%2 != color {
+			j++
+		}
+		b1, b2 := refAt(j), refAt(j+1)
+
+		mode, err := br.code(modeTable)
+		if err != nil {
+			if br.peek(7) == 0b0000001 {
+				return cur, errors.New("uncompressed mode not supported")
+			}
+			return cur, err
+		}
+		switch mode {
+		case modePass:
+			a0 = b2
+		case modeHorizontal:
+			r1, err := br.run(color == 1)
+			if err != nil {
+				return cur, err
+			}
+			r2, err := br.run(color == 0)
+			if err != nil {
+				return cur, err
+			}
+			a1 := min(max(a0, 0)+r1, w)
+			a0 = min(a1+r2, w)
+			cur = append(cur, a1, a0)
+		default:
+			a1 := b1 + mode - modeV0
+			if a1 < 0 || a1 > w {
+				return cur, fmt.Errorf("vertical mode out of range (%d)", a1)
+			}
+			if a1 < a0 {
+				a1 = a0
+			}
+			cur = append(cur, a1)
+			a0 = a1
+			color ^= 1
+			// A vertical step to the left can move b1 back past j.
+			for j > 0 && ref[min(j, len(ref))-1] > a0 {
+				j--
+			}
+		}
+	}
+	return cur, nil
+}
+
+// ccittParamsFromDict reads CCITTFaxDecode parameters; height fills in a
+// missing /Rows.
+func ccittParamsFromDict(get func(key string) (int, bool), width, height int) ccittParams {
+	p := ccittParams{Columns: width, Rows: height}
+	if v, ok := get("K"); ok {
+		p.K = v
+	}
+	if v, ok := get("Columns"); ok && v > 0 {
+		p.Columns = v
+	}
+	if v, ok := get("Rows"); ok && v > 0 {
+		p.Rows = v
+	}
+	flag := func(key string) bool {
+		v, ok := get(key)
+		return ok && v != 0
+	}
+	p.EndOfLine = flag("EndOfLine")
+	p.EncodedByteAlign = flag("EncodedByteAlign")
+	p.BlackIs1 = flag("BlackIs1")
+	return p
+}
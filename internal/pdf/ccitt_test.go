@@ -0,0 +1,90 @@
+package pdf
+
+import (
+	"image"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The bw-gopher fixtures are copied from golang.org/x/image/ccitt/testdata
+// (BSD license, The Go Authors): a 153x55 image and its CCITT encodings.
+
+func loadGopher(t *testing.T) *bitmap {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", "bw-gopher.png"))
+	require.NoError(t, err)
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	require.NoError(t, err)
+	b := img.Bounds()
+	bm := newBitmap(b.Dx(), b.Dy())
+	for y := range bm.h {
+		for x := range bm.w {
+			if gray(img, b.Min.X+x, b.Min.Y+y) < 0x80 {
+				bm.pix[y*bm.w+x] = 1
+			}
+		}
+	}
+	return bm
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return data
+}
+
+func TestDecodeCCITT(t *testing.T) {
+	want := loadGopher(t)
+	tests := []struct {
+		file string
+		p    ccittParams
+	}{
+		{"bw-gopher.ccitt_group4", ccittParams{K: -1}},
+		{"bw-gopher.ccitt_group3", ccittParams{K: 0, EndOfLine: true}},
+		{"bw-gopher-aligned.ccitt_group4", ccittParams{K: -1, EncodedByteAlign: true}},
+		{"bw-gopher-aligned.ccitt_group3", ccittParams{K: 0, EncodedByteAlign: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			tt.p.Columns, tt.p.Rows = want.w, want.h
+			got, err := decodeCCITT(readFixture(t, tt.file), tt.p)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestDecodeCCITT_Truncated(t *testing.T) {
+	want := loadGopher(t)
+	p := ccittParams{K: -1, Columns: want.w, Rows: want.h}
+
+	// A stream without the end-of-block marker is complete.
+	got, err := decodeCCITT(readFixture(t, "bw-gopher-truncated0.ccitt_group4"), p)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	// A stream cut short yields the rows decoded so far and an error.
+	data := readFixture(t, "bw-gopher.ccitt_group4")
+	got, err = decodeCCITT(data[:len(data)/2], p)
+	require.Error(t, err)
+	require.NotNil(t, got)
+	require.Positive(t, got.h)
+	assert.Less(t, got.h, want.h)
+	assert.Equal(t, want.pix[:got.w*(got.h-1)], got.pix[:got.w*(got.h-1)])
+}
+
+func TestCCITTParamsFromDict(t *testing.T) {
+	parms := map[string]int{"K": -1, "Columns": 200, "BlackIs1": 1}
+	p := ccittParamsFromDict(func(key string) (int, bool) {
+		v, ok := parms[key]
+		return v, ok
+	}, 100, 50)
+	assert.Equal(t, ccittParams{K: -1, Columns: 200, Rows: 50, BlackIs1: true}, p)
+}
@@ -0,0 +1,301 @@
+package pdf
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// contentToken is a lexical token of a PDF content stream.
+type contentToken struct {
+	kind  contentTokenKind
+	num   float64
+	str   string // name (without '/'), operator, or decoded string bytes
+	array []contentToken
+	dict  map[string]contentToken
+}
+
+type contentTokenKind int
+
+const (
+	tokNumber contentTokenKind = iota
+	tokName
+	tokString
+	tokArray
+	tokDict
+	tokOperator
+	tokBool
+	tokNull
+)
+
+// contentLexer splits a content stream into operands and operators. It
+// understands enough of the PDF object syntax to skip over strings, arrays
+// and dictionaries that appear as operands.
+type contentLexer struct {
+	data []byte
+	pos  int
+}
+
+func newContentLexer(data []byte) *contentLexer { return &contentLexer{data: data} }
+
+func isPDFWhitespace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func (l *contentLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isPDFWhitespace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// next returns the next token, or false at the end of the stream.
+func (l *contentLexer) next() (contentToken, bool) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return contentToken{}, false
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		return contentToken{kind: tokName, str: l.regular()}, true
+	case c == '(':
+		return contentToken{kind: tokString, str: l.literalString()}, true
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		return l.dictionary(), true
+	case c == '<':
+		return contentToken{kind: tokString, str: l.hexString()}, true
+	case c == '[':
+		l.pos++
+		return l.arrayToken(), true
+	case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+		// Stray delimiter; report it as an operator so callers can ignore it.
+		l.pos++
+		return contentToken{kind: tokOperator, str: string(c)}, true
+	}
+	word := l.regular()
+	if word == "" {
+		l.pos++
+		return contentToken{kind: tokOperator, str: string(c)}, true
+	}
+	if n, err := strconv.ParseFloat(word, 64); err == nil && (word[0] == '.' || word[0] == '-' || word[0] == '+' || (word[0] >= '0' && word[0] <= '9')) {
+		return contentToken{kind: tokNumber, num: n}, true
+	}
+	switch word {
+	case "true", "false":
+		return contentToken{kind: tokBool, str: word}, true
+	case "null":
+		return contentToken{kind: tokNull}, true
+	}
+	return contentToken{kind: tokOperator, str: word}, true
+}
+
+// regular reads a run of regular (non-whitespace, non-delimiter) characters.
+func (l *contentLexer) regular() string {
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFWhitespace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+func (l *contentLexer) literalString() string {
+	l.pos++ // '('
+	var b bytes.Buffer
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '\\':
+			if l.pos >= len(l.data) {
+				return b.String()
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case '\r', '\n':
+				// line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					b.WriteByte(byte(v))
+				} else {
+					b.WriteByte(e)
+				}
+			}
+		case '(':
+			depth++
+			b.WriteByte(c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return b.String()
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func (l *contentLexer) hexString() string {
+	l.pos++ // '<'
+	var b bytes.Buffer
+	var hi, n int
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		v := hexValue(l.data[l.pos])
+		l.pos++
+		if v < 0 {
+			continue
+		}
+		if n%2 == 0 {
+			hi = v
+		} else {
+			b.WriteByte(byte(hi<<4 | v))
+		}
+		n++
+	}
+	if n%2 == 1 {
+		b.WriteByte(byte(hi << 4))
+	}
+	l.pos++ // '>'
+	return b.String()
+}
+
+func hexValue(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'a' && c <= 'f':
+		return int(c-'a') + 10
+	case c >= 'A' && c <= 'F':
+		return int(c-'A') + 10
+	}
+	return -1
+}
+
+func (l *contentLexer) arrayToken() contentToken {
+	arr := contentToken{kind: tokArray}
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.data) {
+			return arr
+		}
+		if l.data[l.pos] == ']' {
+			l.pos++
+			return arr
+		}
+		tok, ok := l.next()
+		if !ok {
+			return arr
+		}
+		arr.array = append(arr.array, tok)
+	}
+}
+
+func (l *contentLexer) dictionary() contentToken {
+	d := contentToken{kind: tokDict, dict: map[string]contentToken{}}
+	var key string
+	haveKey := false
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.data) {
+			return d
+		}
+		if l.data[l.pos] == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return d
+		}
+		tok, ok := l.next()
+		if !ok {
+			return d
+		}
+		if !haveKey {
+			key, haveKey = tok.str, tok.kind == tokName
+			continue
+		}
+		d.dict[key] = tok
+		haveKey = false
+	}
+}
+
+// inlineImage reads the key/value pairs of an inline image after BI, then its
+// raw data after ID up to the terminating EI operator.
+func (l *contentLexer) inlineImage() (map[string]contentToken, []byte) {
+	params := map[string]contentToken{}
+	for {
+		tok, ok := l.next()
+		if !ok {
+			return params, nil
+		}
+		if tok.kind == tokOperator && tok.str == "ID" {
+			break
+		}
+		if tok.kind != tokName {
+			continue
+		}
+		val, ok := l.next()
+		if !ok {
+			return params, nil
+		}
+		params[tok.str] = val
+	}
+	// A single whitespace character separates ID from the data.
+	if l.pos < len(l.data) && isPDFWhitespace(l.data[l.pos]) {
+		l.pos++
+	}
+	start := l.pos
+	for i := start; i+1 < len(l.data); i++ {
+		if l.data[i] != 'E' || l.data[i+1] != 'I' {
+			continue
+		}
+		if i > start && !isPDFWhitespace(l.data[i-1]) {
+			continue
+		}
+		if i+2 < len(l.data) && !isPDFWhitespace(l.data[i+2]) && !isPDFDelimiter(l.data[i+2]) {
+			continue
+		}
+		end := i
+		if end > start && isPDFWhitespace(l.data[end-1]) {
+			end--
+		}
+		l.pos = i + 2
+		return params, l.data[start:end]
+	}
+	l.pos = len(l.data)
+	return params, l.data[start:]
+}
@@ -223,7 +223,7 @@ func (h *HybridProcessor) mergeByAppending(result *HybridResult) {
 			}
 		} else {
 			// Fallback: create OCRRegions from DetectedRegions (no text available)
-			for _, detRegion := range ocrResult.Regions {
+			for i, detRegion := range ocrResult.Regions {
 				if detRegion.Confidence >= h.config.MinOCRConfidence {
 					// Create OCRRegion from DetectedRegion
 					ocrRegion := OCRRegion{
@@ -237,6 +237,9 @@ func (h *HybridProcessor) mergeByAppending(result *HybridResult) {
 						Text:          "", // No text available from detector only
 						RecConfidence: 0,  // No recognition performed
 					}
+					if i < len(ocrResult.PageRegions) {
+						ocrRegion.Page = &ocrResult.PageRegions[i]
+					}
 					result.MergedRegions = append(result.MergedRegions, ocrRegion)
 				}
 			}
@@ -0,0 +1,101 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// iccTag is a single tagged element of an ICC profile.
+type iccTag struct {
+	sig  string
+	data []byte
+}
+
+// srgbProfile builds a compact ICC v2 display profile approximating sRGB
+// (D50-adapted primaries, gamma 2.2). It is used as the PDF/A output intent.
+func srgbProfile() []byte {
+	tags := []iccTag{
+		{"desc", iccTextDescription("sRGB IEC61966-2.1")},
+		{"cprt", iccText("No copyright, use freely")},
+		{"wtpt", iccXYZ(0.9642, 1.0, 0.8249)},
+		{"rXYZ", iccXYZ(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", iccXYZ(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", iccXYZ(0.1431, 0.0606, 0.7141)},
+		{"rTRC", iccGammaCurve(2.2)},
+		{"gTRC", iccGammaCurve(2.2)},
+		{"bTRC", iccGammaCurve(2.2)},
+	}
+
+	const headerSize = 128
+	offset := headerSize + 4 + 12*len(tags)
+	var table, data bytes.Buffer
+	_ = binary.Write(&table, binary.BigEndian, uint32(len(tags)))
+	for _, t := range tags {
+		table.WriteString(t.sig)
+		_ = binary.Write(&table, binary.BigEndian, uint32(offset+data.Len()))
+		_ = binary.Write(&table, binary.BigEndian, uint32(len(t.data)))
+		data.Write(t.data)
+		for data.Len()%4 != 0 {
+			data.WriteByte(0)
+		}
+	}
+
+	size := headerSize + table.Len() + data.Len()
+	header := make([]byte, headerSize)
+	binary.BigEndian.PutUint32(header[0:], uint32(size))
+	binary.BigEndian.PutUint32(header[8:], 0x02100000) // version 2.1
+	copy(header[12:], "mntr")
+	copy(header[16:], "RGB ")
+	copy(header[20:], "XYZ ")
+	binary.BigEndian.PutUint16(header[24:], 2000) // creation date: 2000-01-01
+	binary.BigEndian.PutUint16(header[26:], 1)
+	binary.BigEndian.PutUint16(header[28:], 1)
+	copy(header[36:], "acsp")
+	copy(header[68:], iccXYZ(0.9642, 1.0, 0.8249)[8:]) // PCS illuminant (D50)
+
+	out := make([]byte, 0, size)
+	out = append(out, header...)
+	out = append(out, table.Bytes()...)
+	return append(out, data.Bytes()...)
+}
+
+// iccS15Fixed16 encodes v as a signed 15.16 fixed point number.
+func iccS15Fixed16(v float64) uint32 {
+	return uint32(int32(math.Round(v * 65536)))
+}
+
+func iccXYZ(x, y, z float64) []byte {
+	b := make([]byte, 20)
+	copy(b, "XYZ ")
+	binary.BigEndian.PutUint32(b[8:], iccS15Fixed16(x))
+	binary.BigEndian.PutUint32(b[12:], iccS15Fixed16(y))
+	binary.BigEndian.PutUint32(b[16:], iccS15Fixed16(z))
+	return b
+}
+
+func iccGammaCurve(gamma float64) []byte {
+	b := make([]byte, 14)
+	copy(b, "curv")
+	binary.BigEndian.PutUint32(b[8:], 1)
+	binary.BigEndian.PutUint16(b[12:], uint16(math.Round(gamma*256)))
+	return b
+}
+
+func iccText(s string) []byte {
+	b := make([]byte, 8, 8+len(s)+1)
+	copy(b, "text")
+	b = append(b, s...)
+	return append(b, 0)
+}
+
+// iccTextDescription encodes a v2 textDescriptionType with ASCII text only.
+func iccTextDescription(s string) []byte {
+	b := make([]byte, 12, 12+len(s)+1+79)
+	copy(b, "desc")
+	binary.BigEndian.PutUint32(b[8:], uint32(len(s)+1))
+	b = append(b, s...)
+	b = append(b, 0)
+	// Unicode language code and count, ScriptCode code and count, 67-byte ScriptCode string.
+	return append(b, make([]byte, 4+4+2+1+67)...)
+}
@@ -0,0 +1,453 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// JBIG2 segment types (ITU-T T.88, 7.3).
+const (
+	jbig2SymbolDictionary           = 0
+	jbig2IntermediateTextRegion     = 4
+	jbig2ImmediateTextRegion        = 6
+	jbig2ImmediateLosslessText      = 7
+	jbig2PatternDictionary          = 16
+	jbig2IntermediateHalftone       = 20
+	jbig2ImmediateHalftone          = 22
+	jbig2ImmediateLosslessHalftone  = 23
+	jbig2IntermediateGeneric        = 36
+	jbig2ImmediateGeneric           = 38
+	jbig2ImmediateLosslessGeneric   = 39
+	jbig2IntermediateRefinement     = 40
+	jbig2ImmediateRefinement        = 42
+	jbig2ImmediateLosslessRefinemnt = 43
+	jbig2PageInformation            = 48
+	jbig2EndOfPage                  = 49
+	jbig2EndOfStripe                = 50
+	jbig2EndOfFile                  = 51
+)
+
+// jbig2Unsupported names the region types decodeJBIG2 does not implement.
+var jbig2Unsupported = map[int]string{
+	jbig2IntermediateTextRegion:     "text",
+	jbig2ImmediateTextRegion:        "text",
+	jbig2ImmediateLosslessText:      "text",
+	jbig2IntermediateHalftone:       "halftone",
+	jbig2ImmediateHalftone:          "halftone",
+	jbig2ImmediateLosslessHalftone:  "halftone",
+	jbig2IntermediateRefinement:     "refinement",
+	jbig2ImmediateRefinement:        "refinement",
+	jbig2ImmediateLosslessRefinemnt: "refinement",
+}
+
+type jbig2Segment struct {
+	number uint32
+	kind   int
+	data   []byte
+	// unknownLength is set for immediate generic regions whose length is
+	// only given by the row count trailing the data.
+	unknownLength bool
+}
+
+// decodeJBIG2 decodes a JBIG2Decode stream (PDF 32000-1, 7.4.7): the
+// segments of the optional JBIG2Globals stream followed by those of the
+// image. Generic regions, arithmetic or MMR coded, are supported; symbol
+// (text), halftone and refinement regions are reported as errors.
+func decodeJBIG2(data, globals []byte) (*bitmap, error) {
+	segments, err := parseJBIG2Segments(globals)
+	if err != nil {
+		return nil, fmt.Errorf("jbig2 globals: %w", err)
+	}
+	page, err := parseJBIG2Segments(data)
+	if err != nil {
+		return nil, fmt.Errorf("jbig2: %w", err)
+	}
+	segments = append(segments, page...)
+
+	var bm *bitmap
+	striped := false
+	for _, seg := range segments {
+		if name, ok := jbig2Unsupported[seg.kind]; ok {
+			return nil, fmt.Errorf("jbig2: %s regions are not supported", name)
+		}
+		switch seg.kind {
+		case jbig2PageInformation:
+			if len(seg.data) < 17 {
+				return nil, errors.New("jbig2: short page information segment")
+			}
+			w, h := binary.BigEndian.Uint32(seg.data), binary.BigEndian.Uint32(seg.data[4:])
+			if h == 0xffffffff {
+				striped, h = true, 0
+			}
+			if w == 0 || w > 1<<16 || h > 1<<16 {
+				return nil, fmt.Errorf("jbig2: unsupported page size %dx%d", w, h)
+			}
+			bm = newBitmap(int(w), int(h))
+			if seg.data[16]&0x04 != 0 {
+				for i := range bm.pix {
+					bm.pix[i] = 1
+				}
+			}
+		case jbig2EndOfStripe:
+			if bm != nil && striped && len(seg.data) >= 4 {
+				bm.grow(int(binary.BigEndian.Uint32(seg.data)) + 1)
+			}
+		case jbig2ImmediateGeneric, jbig2ImmediateLosslessGeneric:
+			if bm == nil {
+				return nil, errors.New("jbig2: region before page information")
+			}
+			region, x, y, op, err := decodeJBIG2GenericSegment(seg)
+			if err != nil {
+				return nil, err
+			}
+			if striped {
+				bm.grow(y + region.h)
+			}
+			bm.compose(region, x, y, op)
+		case jbig2EndOfPage, jbig2EndOfFile:
+			if bm == nil {
+				return nil, errors.New("jbig2: no page information")
+			}
+			return bm, nil
+		}
+	}
+	if bm == nil {
+		return nil, errors.New("jbig2: no page information")
+	}
+	return bm, nil
+}
+
+// parseJBIG2Segments splits embedded (headerless, sequential) JBIG2 data
+// into segments.
+func parseJBIG2Segments(data []byte) ([]jbig2Segment, error) {
+	var segments []jbig2Segment
+	for pos := 0; pos < len(data); {
+		if len(data)-pos < 11 {
+			return nil, errors.New("truncated segment header")
+		}
+		seg := jbig2Segment{number: binary.BigEndian.Uint32(data[pos:])}
+		flags := data[pos+4]
+		seg.kind = int(flags & 0x3f)
+		p := pos + 5
+
+		refs := int(data[p] >> 5)
+		if refs == 7 {
+			if len(data)-p < 4 {
+				return nil, errors.New("truncated segment header")
+			}
+			refs = int(binary.BigEndian.Uint32(data[p:]) & 0x1fffffff)
+			p += 4 + (refs+8)/8
+		} else {
+			p++
+		}
+		refSize := 4
+		switch {
+		case seg.number <= 256:
+			refSize = 1
+		case seg.number <= 65536:
+			refSize = 2
+		}
+		p += refs * refSize
+		if flags&0x40 != 0 {
+			p += 4
+		} else {
+			p++
+		}
+		if p+4 > len(data) {
+			return nil, errors.New("truncated segment header")
+		}
+		length := binary.BigEndian.Uint32(data[p:])
+		p += 4
+
+		if length == 0xffffffff {
+			n, err := jbig2UnknownLength(data[p:])
+			if err != nil {
+				return nil, fmt.Errorf("segment %d: %w", seg.number, err)
+			}
+			length, seg.unknownLength = uint32(n), true
+		}
+		if uint64(p)+uint64(length) > uint64(len(data)) {
+			return nil, fmt.Errorf("segment %d: data exceeds stream", seg.number)
+		}
+		seg.data = data[p : p+int(length)]
+		segments = append(segments, seg)
+		pos = p + int(length)
+	}
+	return segments, nil
+}
+
+// jbig2UnknownLength finds the end of an immediate generic region of unknown
+// length: its coded data ends with 0xFFAC (arithmetic) or 0x0000 (MMR),
+// followed by the 4-byte row count.
+func jbig2UnknownLength(data []byte) (int, error) {
+	if len(data) < 18 {
+		return 0, errors.New("truncated generic region")
+	}
+	marker := []byte{0xff, 0xac}
+	if data[17]&1 != 0 {
+		marker = []byte{0x00, 0x00}
+	}
+	i := bytes.Index(data[18:], marker)
+	if i < 0 || 18+i+6 > len(data) {
+		return 0, errors.New("end of generic region not found")
+	}
+	return 18 + i + 6, nil
+}
+
+// decodeJBIG2GenericSegment decodes a generic region segment and returns the
+// region with its position and external combination operator.
+func decodeJBIG2GenericSegment(seg jbig2Segment) (*bitmap, int, int, int, error) {
+	d := seg.data
+	if len(d) < 18 {
+		return nil, 0, 0, 0, errors.New("jbig2: short generic region segment")
+	}
+	w, h := binary.BigEndian.Uint32(d), binary.BigEndian.Uint32(d[4:])
+	x, y := int(binary.BigEndian.Uint32(d[8:])), int(binary.BigEndian.Uint32(d[12:]))
+	op := int(d[16] & 7)
+	flags := d[17]
+	mmr := flags&1 != 0
+	template := int(flags>>1) & 3
+	tpgdon := flags&0x08 != 0
+	d = d[18:]
+
+	var at []int8
+	if !mmr {
+		n := 2
+		if template == 0 {
+			n = 8
+		}
+		if len(d) < n {
+			return nil, 0, 0, 0, errors.New("jbig2: short generic region segment")
+		}
+		for _, b := range d[:n] {
+			at = append(at, int8(b))
+		}
+		d = d[n:]
+	}
+	if seg.unknownLength {
+		h = binary.BigEndian.Uint32(d[len(d)-4:])
+		d = d[:len(d)-6]
+	}
+	if w == 0 || h == 0 || w > 1<<16 || h > 1<<16 {
+		return nil, 0, 0, 0, fmt.Errorf("jbig2: unsupported region size %dx%d", w, h)
+	}
+
+	var region *bitmap
+	var err error
+	if mmr {
+		region, err = decodeCCITT(d, ccittParams{K: -1, Columns: int(w), Rows: int(h)})
+		if region != nil && region.h < int(h) {
+			region.grow(int(h))
+		}
+	} else {
+		region = decodeGenericRegion(d, int(w), int(h), template, tpgdon, at)
+	}
+	if region == nil {
+		return nil, 0, 0, 0, fmt.Errorf("jbig2: generic region: %w", err)
+	}
+	return region, x, y, op, nil
+}
+
+// grow extends the bitmap with white rows up to height h.
+func (b *bitmap) grow(h int) {
+	if h > b.h && h <= 1<<16 {
+		b.pix = append(b.pix, make([]byte, (h-b.h)*b.w)...)
+		b.h = h
+	}
+}
+
+// compose combines src into b at (x, y) with a JBIG2 combination operator
+// (0 OR, 1 AND, 2 XOR, 3 XNOR, 4 REPLACE).
+func (b *bitmap) compose(src *bitmap, x, y, op int) {
+	for sy := range src.h {
+		dy := y + sy
+		if dy < 0 || dy >= b.h {
+			continue
+		}
+		for sx := range src.w {
+			dx := x + sx
+			if dx < 0 || dx >= b.w {
+				continue
+			}
+			s, d := src.pix[sy*src.w+sx], &b.pix[dy*b.w+dx]
+			switch op {
+			case 0:
+				*d |= s
+			case 1:
+				*d &= s
+			case 2:
+				*d ^= s
+			case 3:
+				*d = 1 ^ (*d ^ s)
+			default:
+				*d = s
+			}
+		}
+	}
+}
+
+// sltpContexts are the contexts of the typical-prediction bit per template.
+var sltpContexts = [4]int{0x9b25, 0x0795, 0x00e5, 0x0195}
+
+// decodeGenericRegion runs the arithmetic generic region decoding procedure
+// (T.88, 6.2.5). at holds the adaptive template pixel offsets as x, y pairs.
+func decodeGenericRegion(data []byte, w, h, template int, tpgdon bool, at []int8) *bitmap {
+	bm := newBitmap(w, h)
+	dec := newMQDecoder(data)
+	cx := make([]byte, 1<<16)
+	ltp := 0
+	for y := range h {
+		if tpgdon {
+			ltp ^= dec.decode(cx, sltpContexts[template])
+			if ltp == 1 {
+				if y > 0 {
+					copy(bm.pix[y*w:(y+1)*w], bm.pix[(y-1)*w:y*w])
+				}
+				continue
+			}
+		}
+		for x := range w {
+			bm.pix[y*w+x] = byte(dec.decode(cx, genericContext(bm, x, y, template, at)))
+		}
+	}
+	return bm
+}
+
+// mqState is a row of the probability estimation table (T.88, table E.1).
+type mqState struct {
+	qe         uint32
+	nmps, nlps uint8
+	switchMPS  bool
+}
+
+var mqTable = [47]mqState{
+	{0x5601, 1, 1, true}, {0x3401, 2, 6, false}, {0x1801, 3, 9, false}, {0x0AC1, 4, 12, false},
+	{0x0521, 5, 29, false}, {0x0221, 38, 33, false}, {0x5601, 7, 6, true}, {0x5401, 8, 14, false},
+	{0x4801, 9, 14, false}, {0x3801, 10, 14, false}, {0x3001, 11, 17, false}, {0x2401, 12, 18, false},
+	{0x1C01, 13, 20, false}, {0x1601, 29, 21, false}, {0x5601, 15, 14, true}, {0x5401, 16, 14, false},
+	{0x5101, 17, 15, false}, {0x4801, 18, 16, false}, {0x3801, 19, 17, false}, {0x3401, 20, 18, false},
+	{0x3001, 21, 19, false}, {0x2801, 22, 19, false}, {0x2401, 23, 20, false}, {0x2201, 24, 21, false},
+	{0x1C01, 25, 22, false}, {0x1801, 26, 23, false}, {0x1601, 27, 24, false}, {0x1401, 28, 25, false},
+	{0x1201, 29, 26, false}, {0x1101, 30, 27, false}, {0x0AC1, 31, 28, false}, {0x09C1, 32, 29, false},
+	{0x08A1, 33, 30, false}, {0x0521, 34, 31, false}, {0x0441, 35, 32, false}, {0x02A1, 36, 33, false},
+	{0x0221, 37, 34, false}, {0x0141, 38, 35, false}, {0x0111, 39, 36, false}, {0x0085, 40, 37, false},
+	{0x0049, 41, 38, false}, {0x0025, 42, 39, false}, {0x0015, 43, 40, false}, {0x0009, 44, 41, false},
+	{0x0005, 45, 42, false}, {0x0001, 45, 43, false}, {0x5601, 46, 46, false},
+}
+
+// mqDecoder is the MQ arithmetic decoder of T.88 annex E.
+type mqDecoder struct {
+	data []byte
+	bp   int
+	c    uint32
+	a    uint32
+	ct   int
+}
+
+func newMQDecoder(data []byte) *mqDecoder {
+	d := &mqDecoder{data: data}
+	d.c = uint32(d.byteAt(0)) << 16
+	d.byteIn()
+	d.c <<= 7
+	d.ct -= 7
+	d.a = 0x8000
+	return d
+}
+
+// byteAt returns data[i], or 0xFF past the end as the standard prescribes.
+func (d *mqDecoder) byteAt(i int) byte {
+	if i < len(d.data) {
+		return d.data[i]
+	}
+	return 0xff
+}
+
+func (d *mqDecoder) byteIn() {
+	if d.byteAt(d.bp) == 0xff {
+		if d.byteAt(d.bp+1) > 0x8f {
+			d.c += 0xff00
+			d.ct = 8
+		} else {
+			d.bp++
+			d.c += uint32(d.byteAt(d.bp)) << 9
+			d.ct = 7
+		}
+	} else {
+		d.bp++
+		d.c += uint32(d.byteAt(d.bp)) << 8
+		d.ct = 8
+	}
+}
+
+// decode returns the next bit in context cx[i], whose state is stored as
+// table index << 1 | MPS.
+func (d *mqDecoder) decode(cx []byte, i int) int {
+	idx, mps := cx[i]>>1, int(cx[i]&1)
+	st := mqTable[idx]
+	d.a -= st.qe
+	var bit int
+	if d.c>>16 < st.qe {
+		// LPS exchange
+		if d.a < st.qe {
+			bit, idx = mps, st.nmps
+		} else {
+			bit = 1 - mps
+			if st.switchMPS {
+				mps = bit
+			}
+			idx = st.nlps
+		}
+		d.a = st.qe
+	} else {
+		d.c -= st.qe << 16
+		if d.a&0x8000 != 0 {
+			return mps
+		}
+		// MPS exchange
+		if d.a < st.qe {
+			bit = 1 - mps
+			if st.switchMPS {
+				mps = bit
+			}
+			idx = st.nlps
+		} else {
+			bit, idx = mps, st.nmps
+		}
+	}
+	for d.a&0x8000 == 0 {
+		if d.ct == 0 {
+			d.byteIn()
+		}
+		d.a <<= 1
+		d.c <<= 1
+		d.ct--
+	}
+	cx[i] = idx<<1 | byte(mps)
+	return bit
+}
+
+// genericContext forms the context of pixel (x, y) from its already decoded
+// neighbours, with the bit layout sltpContexts refers to.
+func genericContext(bm *bitmap, x, y, template int, at []int8) int {
+	p := func(dx, dy int) int { return int(bm.at(x+dx, y+dy)) }
+	a := func(i int) int { return p(int(at[2*i]), int(at[2*i+1])) }
+	switch template {
+	case 0:
+		return p(-1, 0) | p(-2, 0)<<1 | p(-3, 0)<<2 | p(-4, 0)<<3 | a(0)<<4 |
+			p(2, -1)<<5 | p(1, -1)<<6 | p(0, -1)<<7 | p(-1, -1)<<8 | p(-2, -1)<<9 |
+			a(1)<<10 | a(2)<<11 | p(1, -2)<<12 | p(0, -2)<<13 | p(-1, -2)<<14 | a(3)<<15
+	case 1:
+		return p(-1, 0) | p(-2, 0)<<1 | p(-3, 0)<<2 | a(0)<<3 |
+			p(2, -1)<<4 | p(1, -1)<<5 | p(0, -1)<<6 | p(-1, -1)<<7 | p(-2, -1)<<8 |
+			p(2, -2)<<9 | p(1, -2)<<10 | p(0, -2)<<11 | p(-1, -2)<<12
+	case 2:
+		return p(-1, 0) | p(-2, 0)<<1 | a(0)<<2 |
+			p(1, -1)<<3 | p(0, -1)<<4 | p(-1, -1)<<5 | p(-2, -1)<<6 |
+			p(1, -2)<<7 | p(0, -2)<<8 | p(-1, -2)<<9
+	default:
+		return p(-1, 0) | p(-2, 0)<<1 | p(-3, 0)<<2 | p(-4, 0)<<3 | a(0)<<4 |
+			p(1, -1)<<5 | p(0, -1)<<6 | p(-1, -1)<<7 | p(-2, -1)<<8 | p(-3, -1)<<9
+	}
+}
@@ -0,0 +1,295 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMQDecoder_TestSequence(t *testing.T) {
+	// T.88 annex H.2: 256 bits decoded in a single context.
+	in, err := hex.DecodeString("84C73BFCE1A14304022000" + "00410DBB86F4317FFF88FF37471ADB6ADFFFAC")
+	require.NoError(t, err)
+	want := "00020051000000C00352872AAAAAAAAA82C02000FCD79EF6BF7FED904F46A3BF"
+
+	d := newMQDecoder(in)
+	cx := make([]byte, 1)
+	out := make([]byte, 32)
+	for i := range 256 {
+		out[i/8] |= byte(d.decode(cx, 0)) << (7 - uint(i%8))
+	}
+	assert.Equal(t, want, strings.ToUpper(hex.EncodeToString(out)))
+}
+
+// mqEncoder is the MQ arithmetic encoder of T.88 annex E.2, used to build
+// test streams.
+type mqEncoder struct {
+	a, c uint32
+	ct   int
+	out  []byte // out[0] is the dummy byte preceding the stream
+}
+
+func newMQEncoder() *mqEncoder {
+	return &mqEncoder{a: 0x8000, ct: 12, out: []byte{0}}
+}
+
+func (e *mqEncoder) encode(cx []byte, i, bit int) {
+	idx, mps := cx[i]>>1, int(cx[i]&1)
+	st := mqTable[idx]
+	e.a -= st.qe
+	if bit == mps {
+		if e.a&0x8000 != 0 {
+			e.c += st.qe
+			return
+		}
+		if e.a < st.qe {
+			e.a = st.qe
+		} else {
+			e.c += st.qe
+		}
+		idx = st.nmps
+	} else {
+		if e.a < st.qe {
+			e.c += st.qe
+		} else {
+			e.a = st.qe
+		}
+		if st.switchMPS {
+			mps = 1 - mps
+		}
+		idx = st.nlps
+	}
+	cx[i] = idx<<1 | byte(mps)
+	e.renorm()
+}
+
+func (e *mqEncoder) renorm() {
+	for {
+		e.a <<= 1
+		e.c <<= 1
+		e.ct--
+		if e.ct == 0 {
+			e.byteOut()
+		}
+		if e.a&0x8000 != 0 {
+			return
+		}
+	}
+}
+
+func (e *mqEncoder) byteOut() {
+	last := len(e.out) - 1
+	if e.out[last] == 0xff {
+		e.out = append(e.out, byte(e.c>>20))
+		e.c &= 0xfffff
+		e.ct = 7
+		return
+	}
+	if e.c >= 0x8000000 {
+		e.out[last]++
+		if e.out[last] == 0xff {
+			e.c &= 0x7ffffff
+			e.out = append(e.out, byte(e.c>>20))
+			e.c &= 0xfffff
+			e.ct = 7
+			return
+		}
+	}
+	e.out = append(e.out, byte(e.c>>19))
+	e.c &= 0x7ffff
+	e.ct = 8
+}
+
+func (e *mqEncoder) flush() []byte {
+	temp := e.c + e.a
+	e.c |= 0xffff
+	if e.c >= temp {
+		e.c -= 0x8000
+	}
+	e.c <<= e.ct
+	e.byteOut()
+	e.c <<= e.ct
+	e.byteOut()
+	if e.out[len(e.out)-1] != 0xff {
+		e.out = append(e.out, 0xff)
+	}
+	return append(e.out[1:], 0xac)
+}
+
+// referenceContext forms generic region contexts the way T.88 figures 3-6
+// draw them: template pixels ordered by row, then column, most significant
+// first. Only the nominal adaptive pixel positions are supported.
+func referenceContext(bm *bitmap, x, y, template int) int {
+	templates := [4][][2]int{
+		{{-1, -2}, {0, -2}, {1, -2}, {-2, -1}, {-1, -1}, {0, -1}, {1, -1}, {2, -1}, {-4, 0}, {-3, 0}, {-2, 0}, {-1, 0},
+			{3, -1}, {-3, -1}, {2, -2}, {-2, -2}},
+		{{-1, -2}, {0, -2}, {1, -2}, {2, -2}, {-2, -1}, {-1, -1}, {0, -1}, {1, -1}, {2, -1}, {-3, 0}, {-2, 0}, {-1, 0},
+			{3, -1}},
+		{{-1, -2}, {0, -2}, {1, -2}, {-2, -1}, {-1, -1}, {0, -1}, {1, -1}, {-2, 0}, {-1, 0}, {2, -1}},
+		{{-3, -1}, {-2, -1}, {-1, -1}, {0, -1}, {1, -1}, {-4, 0}, {-3, 0}, {-2, 0}, {-1, 0}, {2, -1}},
+	}
+	pts := append([][2]int(nil), templates[template]...)
+	sort.Slice(pts, func(i, j int) bool {
+		if pts[i][1] != pts[j][1] {
+			return pts[i][1] < pts[j][1]
+		}
+		return pts[i][0] < pts[j][0]
+	})
+	c := 0
+	for _, p := range pts {
+		c = c<<1 | int(bm.at(x+p[0], y+p[1]))
+	}
+	return c
+}
+
+var nominalAT = [4][]int8{{3, -1, -3, -1, 2, -2, -2, -2}, {3, -1}, {2, -1}, {2, -1}}
+
+func encodeGenericRegion(bm *bitmap, template int, tpgdon bool) []byte {
+	e := newMQEncoder()
+	cx := make([]byte, 1<<16)
+	ltp := 0
+	for y := range bm.h {
+		if tpgdon {
+			same := 0
+			if y > 0 && bytes.Equal(bm.pix[y*bm.w:(y+1)*bm.w], bm.pix[(y-1)*bm.w:y*bm.w]) {
+				same = 1
+			}
+			e.encode(cx, sltpContexts[template], same^ltp)
+			ltp = same
+			if same == 1 {
+				continue
+			}
+		}
+		for x := range bm.w {
+			e.encode(cx, referenceContext(bm, x, y, template), int(bm.pix[y*bm.w+x]))
+		}
+	}
+	return e.flush()
+}
+
+// jbig2Stream builds embedded JBIG2 data: a page information segment, the
+// given generic regions and an end-of-page segment.
+func jbig2Stream(w, h int, regions ...[]byte) []byte {
+	var buf bytes.Buffer
+	segment := func(kind int, data []byte) {
+		_ = binary.Write(&buf, binary.BigEndian, uint32(buf.Len()))
+		buf.Write([]byte{byte(kind), 0, 1})
+		_ = binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+		buf.Write(data)
+	}
+	page := binary.BigEndian.AppendUint32(nil, uint32(w))
+	page = binary.BigEndian.AppendUint32(page, uint32(h))
+	page = append(page, make([]byte, 11)...)
+	segment(jbig2PageInformation, page)
+	for _, r := range regions {
+		segment(jbig2ImmediateLosslessGeneric, r)
+	}
+	segment(jbig2EndOfPage, nil)
+	return buf.Bytes()
+}
+
+// genericRegionData builds the data of a generic region segment placed at
+// (x, y) with the REPLACE operator.
+func genericRegionData(w, h, x, y int, flags byte, at []int8, coded []byte) []byte {
+	d := binary.BigEndian.AppendUint32(nil, uint32(w))
+	d = binary.BigEndian.AppendUint32(d, uint32(h))
+	d = binary.BigEndian.AppendUint32(d, uint32(x))
+	d = binary.BigEndian.AppendUint32(d, uint32(y))
+	d = append(d, 4, flags)
+	for _, v := range at {
+		d = append(d, byte(v))
+	}
+	return append(d, coded...)
+}
+
+func TestDecodeJBIG2_GenericRegion(t *testing.T) {
+	want := loadGopher(t)
+	for template := range 4 {
+		for _, tpgdon := range []bool{false, true} {
+			flags := byte(template << 1)
+			if tpgdon {
+				flags |= 0x08
+			}
+			region := genericRegionData(want.w, want.h, 0, 0, flags, nominalAT[template],
+				encodeGenericRegion(want, template, tpgdon))
+			got, err := decodeJBIG2(jbig2Stream(want.w, want.h, region), nil)
+			require.NoError(t, err, "template %d tpgdon %v", template, tpgdon)
+			assert.Equal(t, want, got, "template %d tpgdon %v", template, tpgdon)
+		}
+	}
+}
+
+func TestDecodeJBIG2_MMRRegion(t *testing.T) {
+	want := loadGopher(t)
+	// The page is larger than the region, which is placed at (10, 5).
+	region := genericRegionData(want.w, want.h, 10, 5, 0x01, nil, readFixture(t, "bw-gopher.ccitt_group4"))
+	got, err := decodeJBIG2(jbig2Stream(want.w+20, want.h+10, region), nil)
+	require.NoError(t, err)
+	require.Equal(t, want.w+20, got.w)
+	for y := range got.h {
+		for x := range got.w {
+			require.Equal(t, want.at(x-10, y-5), got.at(x, y), "pixel %d,%d", x, y)
+		}
+	}
+}
+
+func TestDecodeJBIG2_Unsupported(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write([]byte{0, 0, 0, 0, jbig2ImmediateTextRegion, 0, 1, 0, 0, 0, 0})
+	_, err := decodeJBIG2(buf.Bytes(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "text regions are not supported")
+
+	_, err = decodeJBIG2([]byte{0, 0, 0}, nil)
+	require.Error(t, err)
+}
+
+func TestBitmapCompose(t *testing.T) {
+	src := &bitmap{w: 4, h: 1, pix: []byte{0, 0, 1, 1}}
+	for op, want := range [][]byte{{0, 1, 1, 1}, {0, 0, 0, 1}, {0, 1, 1, 0}, {1, 0, 0, 1}, {0, 0, 1, 1}} {
+		dst := &bitmap{w: 4, h: 1, pix: []byte{0, 1, 0, 1}}
+		dst.compose(src, 0, 0, op)
+		assert.Equal(t, want, dst.pix, "op %d", op)
+	}
+}
+
+func TestExtractPlacedImages_Bilevel(t *testing.T) {
+	want := loadGopher(t)
+	g4 := readFixture(t, "bw-gopher.ccitt_group4")
+	ccitt := fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 1 "+
+		"/Filter /CCITTFaxDecode /DecodeParms << /K -1 /Columns %d >> /Length %d >>\nstream\n%s\nendstream",
+		want.w, want.h, want.w, len(g4), g4)
+	region := genericRegionData(want.w, want.h, 0, 0, 0, nominalAT[0], encodeGenericRegion(want, 0, false))
+	jb := jbig2Stream(want.w, want.h, region)
+	mask := fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ImageMask true "+
+		"/Filter /JBIG2Decode /Length %d >>\nstream\n%s\nendstream", want.w, want.h, len(jb), jb)
+	text := []byte{0, 0, 0, 0, jbig2ImmediateTextRegion, 0, 1, 0, 0, 0, 0}
+	unsupported := fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width 8 /Height 8 /ColorSpace /DeviceGray /BitsPerComponent 1 "+
+		"/Filter /JBIG2Decode /Length %d >>\nstream\n%s\nendstream", len(text), text)
+	path := buildTestPDF(t, "/MediaBox [0 0 200 100]", "<< /XObject << /Im1 5 0 R /Im2 6 0 R /Im3 7 0 R >> >>",
+		"q 153 0 0 55 0 0 cm /Im1 Do /Im2 Do /Im3 Do Q", ccitt, mask, unsupported)
+
+	sets, err := ExtractPlacedImages(path, "")
+	require.NoError(t, err)
+	set := sets[1]
+	require.Len(t, set.Images, 2)
+	for i, pi := range set.Images {
+		require.Equal(t, image.Rect(0, 0, want.w, want.h), pi.Image.Bounds())
+		for y := range want.h {
+			for x := range want.w {
+				black := gray(pi.Image, x, y) < 0x80
+				require.Equal(t, want.at(x, y) == 1, black, "image %d pixel %d,%d", i, x, y)
+			}
+		}
+	}
+	require.Len(t, set.Warnings, 1)
+	assert.Contains(t, set.Warnings[0], "image Im3: jbig2: text regions are not supported")
+}
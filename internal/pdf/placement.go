@@ -0,0 +1,180 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// PageImage is an image to OCR together with its position on the page.
+type PageImage struct {
+	Image image.Image
+	// Placement maps image pixels (origin top-left, y down) to PDF user space
+	// (points, origin bottom-left). For embedded images it is the image's CTM
+	// combined with the flip into its unit square; for rendered pages it is
+	// the inverse of the render transform.
+	Placement Matrix
+}
+
+// PageSet holds the images to OCR for one page and the page geometry needed
+// to map OCR coordinates back onto the page.
+type PageSet struct {
+	PageNumber int
+	// Box is the visible page area (crop box, or media box) in PDF points.
+	Box types.Rectangle
+	// Rotate is the page's /Rotate value normalized to 0, 90, 180 or 270.
+	Rotate   int
+	Images   []PageImage
+	Warnings []string
+}
+
+// Size returns the displayed page size in points, with the rotation applied.
+func (s *PageSet) Size() (float64, float64) {
+	if s.Rotate == 90 || s.Rotate == 270 {
+		return s.Box.Height(), s.Box.Width()
+	}
+	return s.Box.Width(), s.Box.Height()
+}
+
+// Mapper returns the coordinate mapper for the i-th image of the page.
+func (s *PageSet) Mapper(i int) PageMapper {
+	return NewPageMapper(s.Images[i].Placement, s.Box, s.Rotate)
+}
+
+// PagePoint is a point in page space (PDF points).
+type PagePoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// PageBox is an axis-aligned rectangle in page space (PDF points). X and Y
+// are its corner nearest to the origin of the coordinate system it is given in.
+type PageBox struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	W float64 `json:"w"`
+	H float64 `json:"h"`
+}
+
+// PageCoords locates a region on its PDF page in two conventions:
+//   - Polygon and Box are in PDF user space, origin bottom-left, as used by
+//     PDF tools and annotations.
+//   - PolygonTopLeft and BoxTopLeft are relative to the top-left corner of
+//     the page as displayed (crop box and /Rotate applied), y growing down.
+type PageCoords struct {
+	Polygon        []PagePoint `json:"polygon"`
+	Box            PageBox     `json:"box"`
+	PolygonTopLeft []PagePoint `json:"polygon_top_left"`
+	BoxTopLeft     PageBox     `json:"box_top_left"`
+}
+
+// PageMapper maps image pixel coordinates of an OCRed image to page space.
+type PageMapper struct {
+	toPage Matrix
+	toView Matrix
+}
+
+// NewPageMapper returns a mapper for an image with the given placement on a
+// page with the given visible box and rotation.
+func NewPageMapper(placement Matrix, box types.Rectangle, rotate int) PageMapper {
+	geo := pageGeometry{box: box, rotate: normalizeRotation(rotate)}
+	return PageMapper{
+		toPage: placement,
+		toView: placement.Multiply(boxTransform(geo, box.Width(), box.Height())),
+	}
+}
+
+// Map converts a polygon in image pixels to page coordinates.
+func (m PageMapper) Map(poly []PagePoint) PageCoords {
+	c := PageCoords{
+		Polygon:        make([]PagePoint, len(poly)),
+		PolygonTopLeft: make([]PagePoint, len(poly)),
+	}
+	for i, p := range poly {
+		x, y := m.toPage.Apply(p.X, p.Y)
+		c.Polygon[i] = PagePoint{X: x, Y: y}
+		x, y = m.toView.Apply(p.X, p.Y)
+		c.PolygonTopLeft[i] = PagePoint{X: x, Y: y}
+	}
+	c.Box = boundingPageBox(c.Polygon)
+	c.BoxTopLeft = boundingPageBox(c.PolygonTopLeft)
+	return c
+}
+
+// MapBox converts an axis-aligned box in image pixels to page coordinates.
+func (m PageMapper) MapBox(x, y, w, h float64) PageCoords {
+	return m.Map([]PagePoint{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}})
+}
+
+func boundingPageBox(pts []PagePoint) PageBox {
+	if len(pts) == 0 {
+		return PageBox{}
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range pts {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+	return PageBox{X: minX, Y: minY, W: maxX - minX, H: maxY - minY}
+}
+
+// PageSets returns the images to OCR for each selected page with their
+// placement: one rendered image per page, or in PageModeExtract the images
+// drawn by the page content, in drawing order. Use OpenPages to load one
+// page at a time instead.
+func PageSets(filename, pageRange, mode string, opts RenderOptions) (map[int]*PageSet, error) {
+	l, err := OpenPages(filename, pageRange, mode, opts)
+	if err != nil {
+		return nil, err
+	}
+	sets := make(map[int]*PageSet, len(l.Pages()))
+	for _, n := range l.Pages() {
+		set, err := l.Load(n)
+		if err != nil {
+			return nil, err
+		}
+		sets[n] = set
+	}
+	return sets, nil
+}
+
+// ExtractPlacedImages interprets the content of the selected pages (empty =
+// all) and returns every image they draw, including inline images and images
+// inside form XObjects, together with its placement on the page.
+func ExtractPlacedImages(filename, pageRange string) (map[int]*PageSet, error) {
+	return PageSets(filename, pageRange, PageModeExtract, RenderOptions{})
+}
+
+// collectPage runs the page content in collect mode with the CTM starting
+// at user space.
+func (r *pageRasterizer) collectPage(pageNr int) (*PageSet, error) {
+	d, res, geo, err := readPageGeometry(r.ctx, pageNr)
+	if err != nil {
+		return nil, err
+	}
+	content, err := r.ctx.PageContent(d, pageNr)
+	if err != nil && !errors.Is(err, model.ErrNoContent) {
+		return nil, fmt.Errorf("page %d: %w", pageNr, err)
+	}
+	r.placed, r.warnings = nil, nil
+	gs := graphicsState{
+		ctm:       IdentityMatrix,
+		fill:      paint{color: color.NRGBA{A: 255}},
+		stroke:    paint{color: color.NRGBA{A: 255}},
+		lineWidth: 1,
+	}
+	r.execute(content, res, gs, 0)
+	return &PageSet{
+		PageNumber: pageNr,
+		Box:        geo.box,
+		Rotate:     geo.rotate,
+		Images:     r.placed,
+		Warnings:   r.warnings,
+	}, nil
+}
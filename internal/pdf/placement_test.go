@@ -0,0 +1,90 @@
+package pdf
+
+import (
+	"image"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractPlacedImages(t *testing.T) {
+	// The same 2x1 image drawn twice, once inside a form XObject.
+	img := "<< /Type /XObject /Subtype /Image /Width 2 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8 /Length 2 >>\nstream\n\x00\xff\nendstream"
+	form := "<< /Type /XObject /Subtype /Form /BBox [0 0 1 1] /Matrix [10 0 0 10 0 0] /Resources << /XObject << /Im1 5 0 R >> >> /Length 7 >>\nstream\n/Im1 Do\nendstream"
+	path := buildTestPDF(t, "/MediaBox [0 0 200 100]", "<< /XObject << /Im1 5 0 R /Fm1 6 0 R >> >>",
+		"q 100 0 0 50 50 25 cm /Im1 Do Q q 1 0 0 1 5 5 cm /Fm1 Do Q", img, form)
+
+	sets, err := ExtractPlacedImages(path, "")
+	require.NoError(t, err)
+	require.Contains(t, sets, 1)
+	set := sets[1]
+	require.Len(t, set.Images, 2)
+	assert.Equal(t, image.Rect(0, 0, 2, 1), set.Images[0].Image.Bounds())
+	assert.Empty(t, set.Warnings)
+
+	// Pixel (0,0) is the top-left corner of the image: (50,75) on the page.
+	x, y := set.Images[0].Placement.Apply(0, 0)
+	assert.InDelta(t, 50.0, x, 1e-9)
+	assert.InDelta(t, 75.0, y, 1e-9)
+	x, y = set.Images[0].Placement.Apply(2, 1)
+	assert.InDelta(t, 150.0, x, 1e-9)
+	assert.InDelta(t, 25.0, y, 1e-9)
+
+	x, y = set.Images[1].Placement.Apply(2, 1)
+	assert.InDelta(t, 15.0, x, 1e-9)
+	assert.InDelta(t, 5.0, y, 1e-9)
+
+	images, err := PageImages(path, "", PageModeExtract, RenderOptions{})
+	require.NoError(t, err)
+	assert.Len(t, images[1], 2)
+}
+
+func TestPageSets_RenderPlacement(t *testing.T) {
+	path := buildTestPDF(t, "/MediaBox [0 0 200 100] /Rotate 90", "<< >>", "0 0 20 20 re f")
+	sets, err := PageSets(path, "", PageModeRender, RenderOptions{DPI: 144})
+	require.NoError(t, err)
+	set := sets[1]
+	require.Len(t, set.Images, 1)
+	assert.Equal(t, 90, set.Rotate)
+	w, h := set.Size()
+	assert.InDelta(t, 100.0, w, 1e-9)
+	assert.InDelta(t, 200.0, h, 1e-9)
+
+	// The rendered page is 200x400 pixels; its top-left pixel shows the
+	// bottom-left corner of the unrotated page.
+	x, y := set.Images[0].Placement.Apply(0, 0)
+	assert.InDelta(t, 0.0, x, 1e-9)
+	assert.InDelta(t, 0.0, y, 1e-9)
+
+	c := set.Mapper(0).MapBox(0, 0, 20, 40)
+	assert.Equal(t, PageBox{X: 0, Y: 0, W: 20, H: 10}, c.Box)
+	assert.Equal(t, PageBox{X: 0, Y: 0, W: 10, H: 20}, c.BoxTopLeft)
+
+	_, err = PageSets(path, "", "vector", RenderOptions{})
+	require.Error(t, err)
+}
+
+func TestPageMapper(t *testing.T) {
+	// A 100x50 pixel image covering the crop box [50 0 150 100] at 0.5 px/pt.
+	box := *types.NewRectangle(50, 0, 150, 100)
+	placement := Matrix{2, 0, 0, -2, 50, 100}
+
+	tests := []struct {
+		rotate  int
+		topLeft PageBox
+	}{
+		{0, PageBox{X: 20, Y: 20, W: 20, H: 10}},
+		{90, PageBox{X: 70, Y: 20, W: 10, H: 20}},
+		{180, PageBox{X: 60, Y: 70, W: 20, H: 10}},
+		{270, PageBox{X: 20, Y: 60, W: 10, H: 20}},
+	}
+	for _, tt := range tests {
+		m := NewPageMapper(placement, box, tt.rotate)
+		c := m.Map([]PagePoint{{10, 10}, {20, 10}, {20, 15}, {10, 15}})
+		assert.Equal(t, PageBox{X: 70, Y: 70, W: 20, H: 10}, c.Box, "rotate %d", tt.rotate)
+		assert.Equal(t, tt.topLeft, c.BoxTopLeft, "rotate %d", tt.rotate)
+		assert.Len(t, c.PolygonTopLeft, 4)
+	}
+}
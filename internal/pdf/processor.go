@@ -6,14 +6,10 @@ import (
 	"image"
 	"math"
 	"os"
-	"runtime"
 	"strconv"
 	"strings"
 	"time"
 
-	"sort"
-	"sync"
-
 	ibar "github.com/MeKo-Tech/pogo/internal/barcode"
 	"github.com/MeKo-Tech/pogo/internal/detector"
 	"github.com/disintegration/imaging"
@@ -42,6 +38,15 @@ type ProcessorConfig struct {
 	BarcodeTargetDPI int
 	// Concurrency controls
 	MaxWorkers int
+	// MaxInFlightPages caps the pages held in memory while streaming,
+	// including finished pages waiting to be emitted in order (0 = MaxWorkers).
+	MaxInFlightPages int
+
+	// Page images: PageModeRender (default) rasterizes whole pages,
+	// PageModeExtract OCRs the embedded images.
+	PageMode  string
+	RenderDPI int          // 0 = DefaultRenderDPI
+	Renderer  PageRenderer // nil = NativeRenderer
 }
 
 // DefaultProcessorConfig returns the default processor configuration.
@@ -55,6 +60,8 @@ func DefaultProcessorConfig() *ProcessorConfig {
 		AllowPasswordPrompt: false,
 		BarcodeTargetDPI:    150,
 		MaxWorkers:          0, // 0 = auto (NumCPU)
+		PageMode:            PageModeRender,
+		RenderDPI:           DefaultRenderDPI,
 	}
 }
 
@@ -110,34 +117,87 @@ func (p *Processor) ProcessFile(filename string, pageRange string) (*DocumentRes
 // ProcessFileWithCredentials processes a PDF file with optional password credentials.
 func (p *Processor) ProcessFileWithCredentials(filename string, pageRange string,
 	creds *PasswordCredentials,
+) (*DocumentResult, error) {
+	return p.ProcessFileContext(context.Background(), filename, pageRange, creds)
+}
+
+// ProcessFileContext processes a PDF file with optional password credentials
+// and stops between pages and images when ctx ends. In that case it returns
+// the pages finished so far in a result marked as truncated together with
+// the context's error.
+func (p *Processor) ProcessFileContext(ctx context.Context, filename string, pageRange string,
+	creds *PasswordCredentials,
+) (*DocumentResult, error) {
+	var pages []PageResult
+	result, err := p.ProcessFileStream(ctx, filename, pageRange, creds,
+		func(page *PageResult) error {
+			pages = append(pages, *page)
+			return nil
+		})
+	if result != nil {
+		result.Pages = pages
+	}
+	return result, err
+}
+
+// ProcessFileStream processes a PDF file page by page and calls emit with
+// each page result in page order as soon as it is available. At most
+// MaxInFlightPages pages are held in memory at a time. The returned result
+// carries the totals but no pages. If emit returns an error, processing
+// stops and that error is returned. If ctx ends first, the totals of the
+// pages emitted so far are returned, marked as truncated, along with the
+// error.
+func (p *Processor) ProcessFileStream(ctx context.Context, filename string, pageRange string,
+	creds *PasswordCredentials, emit func(*PageResult) error,
 ) (*DocumentResult, error) {
 	startTime := time.Now()
+	defer p.cleanupTempFiles()
 
 	workingFilename, err := p.handlePasswordProtection(filename, creds)
 	if err != nil {
 		return nil, err
 	}
 
-	pageAnalyses, err := p.analyzePagesIfEnabled(workingFilename, pageRange)
-	if err != nil {
-		// Continue with OCR-only if analysis fails
-		pageAnalyses = make(map[int]*PageAnalysis)
-	}
-
-	pageImages, extractTime, err := p.extractImagesFromPDF(workingFilename, pageRange)
+	loader, err := OpenPages(workingFilename, pageRange, p.config.PageMode, RenderOptions{
+		DPI:      p.config.RenderDPI,
+		Renderer: p.config.Renderer,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	pages, totalDetectionTime, totalVectorTime, err := p.processAllPages(pageImages, pageAnalyses, workingFilename)
-	if err != nil {
+	type pageOut struct {
+		res      *PageResult
+		det, vec time.Duration
+	}
+	var totalDetectionTime, totalVectorTime time.Duration
+	emitted := 0
+	err = StreamPages(ctx, loader, StreamOptions{Workers: p.config.MaxWorkers, MaxInFlight: p.config.MaxInFlightPages},
+		func(ctx context.Context, set *PageSet) (pageOut, error) {
+			// Pages are analyzed as they are processed.
+			pr, dt, vt, err := p.processPageEnhanced(ctx, set.PageNumber, set, nil, workingFilename)
+			if err != nil {
+				return pageOut{}, fmt.Errorf("failed to process page %d: %w", set.PageNumber, err)
+			}
+			return pageOut{res: pr, det: dt, vec: vt}, nil
+		},
+		func(_ int, out pageOut) error {
+			if out.res == nil {
+				return nil // skipped
+			}
+			totalDetectionTime += out.det
+			totalVectorTime += out.vec
+			emitted++
+			return emit(out.res)
+		})
+	if err != nil && ctx.Err() == nil {
 		return nil, err
 	}
 
-	result := p.createDocumentResult(filename, pages, extractTime, totalDetectionTime, totalVectorTime, startTime)
-
-	p.cleanupTempFiles()
-	return result, nil
+	result := p.createDocumentResult(filename, nil, loader.LoadTime(), totalDetectionTime, totalVectorTime, startTime)
+	result.TotalPages = emitted
+	result.Truncated = err != nil
+	return result, err
 }
 
 // handlePasswordProtection handles decryption of password-protected PDFs.
@@ -167,120 +227,6 @@ func (p *Processor) handlePasswordProtection(filename string, creds *PasswordCre
 	return workingFilename, nil
 }
 
-// analyzePagesIfEnabled performs page analysis if vector text is enabled.
-func (p *Processor) analyzePagesIfEnabled(filename, pageRange string) (map[int]*PageAnalysis, error) {
-	if !p.config.EnableVectorText {
-		return make(map[int]*PageAnalysis), nil
-	}
-
-	return p.analyzer.AnalyzePages(filename, pageRange)
-}
-
-// extractImagesFromPDF extracts images from the PDF and returns timing.
-func (p *Processor) extractImagesFromPDF(filename, pageRange string) (map[int][]image.Image, time.Duration, error) {
-	extractStart := time.Now()
-	pageImages, err := ExtractImages(filename, pageRange)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to extract images from PDF: %w", err)
-	}
-	extractTime := time.Since(extractStart)
-	return pageImages, extractTime, nil
-}
-
-// processAllPages processes all pages combining images and analysis.
-func (p *Processor) processAllPages(pageImages map[int][]image.Image, pageAnalyses map[int]*PageAnalysis,
-	filename string,
-) ([]PageResult, time.Duration, time.Duration, error) {
-	allPageNums := p.collectAllPageNumbers(pageImages, pageAnalyses)
-	// Convert map to slice
-	pageList := make([]int, 0, len(allPageNums))
-	for n := range allPageNums {
-		pageList = append(pageList, n)
-	}
-
-	type out struct {
-		page int
-		res  *PageResult
-		det  time.Duration
-		vec  time.Duration
-		err  error
-	}
-
-	// Determine workers
-	workers := p.config.MaxWorkers
-	if workers <= 0 {
-		workers = runtime.NumCPU()
-	}
-	if workers > len(pageList) {
-		workers = len(pageList)
-	}
-	if workers < 1 {
-		workers = 1
-	}
-
-	jobs := make(chan int, len(pageList))
-	results := make(chan out, len(pageList))
-
-	// Start workers
-	var wg sync.WaitGroup
-	for range workers {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for pageNum := range jobs {
-				pr, dt, vt, err := p.processPageEnhanced(pageNum, pageImages[pageNum], pageAnalyses[pageNum], filename)
-				results <- out{page: pageNum, res: pr, det: dt, vec: vt, err: err}
-			}
-		}()
-	}
-
-	for _, n := range pageList {
-		jobs <- n
-	}
-	close(jobs)
-
-	go func() { wg.Wait(); close(results) }()
-
-	pages := make([]PageResult, 0, len(pageList))
-	var totalDetectionTime, totalVectorTime time.Duration
-	var firstErr error
-	m := make(map[int]PageResult)
-	for r := range results {
-		if r.err != nil && firstErr == nil {
-			firstErr = fmt.Errorf("failed to process page %d: %w", r.page, r.err)
-		}
-		if r.res != nil {
-			m[r.page] = *r.res
-			totalDetectionTime += r.det
-			totalVectorTime += r.vec
-		}
-	}
-	if firstErr != nil {
-		return nil, 0, 0, firstErr
-	}
-	sort.Ints(pageList)
-	for _, n := range pageList {
-		if pr, ok := m[n]; ok {
-			pages = append(pages, pr)
-		}
-	}
-	return pages, totalDetectionTime, totalVectorTime, nil
-}
-
-// collectAllPageNumbers combines page numbers from images and analyses.
-func (p *Processor) collectAllPageNumbers(pageImages map[int][]image.Image,
-	pageAnalyses map[int]*PageAnalysis,
-) map[int]bool {
-	allPageNums := make(map[int]bool)
-	for pageNum := range pageImages {
-		allPageNums[pageNum] = true
-	}
-	for pageNum := range pageAnalyses {
-		allPageNums[pageNum] = true
-	}
-	return allPageNums
-}
-
 // createDocumentResult creates the final document result with timing information.
 func (p *Processor) createDocumentResult(filename string, pages []PageResult, extractTime,
 	totalDetectionTime, totalVectorTime time.Duration, startTime time.Time,
@@ -301,7 +247,7 @@ func (p *Processor) createDocumentResult(filename string, pages []PageResult, ex
 }
 
 // processPageEnhanced processes a single PDF page with enhanced capabilities (vector text + OCR).
-func (p *Processor) processPageEnhanced(pageNum int, images []image.Image, analysis *PageAnalysis,
+func (p *Processor) processPageEnhanced(ctx context.Context, pageNum int, set *PageSet, analysis *PageAnalysis,
 	filename string,
 ) (*PageResult, time.Duration, time.Duration, error) {
 	var totalDetectionTime, totalVectorTime time.Duration
@@ -327,8 +273,11 @@ func (p *Processor) processPageEnhanced(pageNum int, images []image.Image, analy
 		pagePointsW = vectorExtraction.Metadata.PageWidth
 		pagePointsH = vectorExtraction.Metadata.PageHeight
 	}
-	imageResults, pageWidth, pageHeight, totalDetectionTime = p.processImagesWithOCRIfNeeded(
-		strategy, images, pageWidth, pageHeight, totalDetectionTime, pagePointsW, pagePointsH)
+	imageResults, pageWidth, pageHeight, totalDetectionTime = p.processImagesWithOCRIfNeeded(ctx,
+		strategy, set, pageWidth, pageHeight, totalDetectionTime, pagePointsW, pagePointsH)
+	if err := ctx.Err(); err != nil {
+		return nil, 0, 0, err
+	}
 
 	// Set page dimensions from vector text if no images
 	if pageWidth == 0 && pageHeight == 0 && vectorExtraction != nil {
@@ -337,8 +286,14 @@ func (p *Processor) processPageEnhanced(pageNum int, images []image.Image, analy
 	}
 
 	// Create and return page result
-	return p.createPageResult(pageNum, pageWidth, pageHeight, imageResults,
+	pageResult, detTime, vecTime, err := p.createPageResult(pageNum, pageWidth, pageHeight, imageResults,
 		totalDetectionTime, totalVectorTime, strategy, vectorExtraction)
+	if pageResult != nil && set != nil {
+		pageResult.WidthPt, pageResult.HeightPt = set.Size()
+		pageResult.Rotate = set.Rotate
+		pageResult.Warnings = set.Warnings
+	}
+	return pageResult, detTime, vecTime, err
 }
 
 // determineProcessingStrategy determines the appropriate processing strategy for the page.
@@ -492,10 +447,14 @@ func (p *Processor) extractVectorTextIfNeeded(strategy ProcessingStrategy, analy
 }
 
 // processImagesWithOCRIfNeeded processes images with OCR if the strategy requires it.
-func (p *Processor) processImagesWithOCRIfNeeded(strategy ProcessingStrategy, images []image.Image,
+func (p *Processor) processImagesWithOCRIfNeeded(ctx context.Context, strategy ProcessingStrategy, set *PageSet,
 	pageWidth, pageHeight int, totalDetectionTime time.Duration,
 	pagePointsW, pagePointsH float64,
 ) ([]ImageResult, int, int, time.Duration) {
+	var images []PageImage
+	if set != nil {
+		images = set.Images
+	}
 	if p.shouldSkipOCRProcessing(strategy, images) {
 		return nil, pageWidth, pageHeight, totalDetectionTime
 	}
@@ -505,12 +464,17 @@ func (p *Processor) processImagesWithOCRIfNeeded(strategy ProcessingStrategy, im
 	currentPageHeight := pageHeight
 	currentDetectionTime := totalDetectionTime
 
-	for i, img := range images {
-		currentPageWidth, currentPageHeight = p.updatePageDimensions(img, currentPageWidth, currentPageHeight)
+	for i, pi := range images {
+		if ctx.Err() != nil {
+			break // the caller drops the incomplete page
+		}
+		currentPageWidth, currentPageHeight = p.updatePageDimensions(pi.Image, currentPageWidth, currentPageHeight)
 
 		if p.shouldPerformOCRDetection(strategy) {
-			imageResult, detectionTime := p.performOCRDetection(img, i, pagePointsW, pagePointsH)
+			imageResult, detectionTime := p.performOCRDetection(pi.Image, i, pagePointsW, pagePointsH)
 			currentDetectionTime += detectionTime
+			imageResult.Placement = &pi.Placement
+			imageResult.PageRegions = placeDetectedRegions(imageResult.Regions, set.Mapper(i))
 			imageResults = append(imageResults, imageResult)
 		}
 	}
@@ -518,8 +482,28 @@ func (p *Processor) processImagesWithOCRIfNeeded(strategy ProcessingStrategy, im
 	return imageResults, currentPageWidth, currentPageHeight, currentDetectionTime
 }
 
+// placeDetectedRegions maps detected regions to page coordinates.
+func placeDetectedRegions(regions []detector.DetectedRegion, m PageMapper) []PageCoords {
+	if len(regions) == 0 {
+		return nil
+	}
+	out := make([]PageCoords, len(regions))
+	for i, r := range regions {
+		if len(r.Polygon) == 0 {
+			out[i] = m.MapBox(r.Box.MinX, r.Box.MinY, r.Box.MaxX-r.Box.MinX, r.Box.MaxY-r.Box.MinY)
+			continue
+		}
+		poly := make([]PagePoint, len(r.Polygon))
+		for j, pt := range r.Polygon {
+			poly[j] = PagePoint{X: pt.X, Y: pt.Y}
+		}
+		out[i] = m.Map(poly)
+	}
+	return out
+}
+
 // shouldSkipOCRProcessing determines if OCR processing should be skipped.
-func (p *Processor) shouldSkipOCRProcessing(strategy ProcessingStrategy, images []image.Image) bool {
+func (p *Processor) shouldSkipOCRProcessing(strategy ProcessingStrategy, images []PageImage) bool {
 	return strategy != StrategyOCR && strategy != StrategyHybrid && len(images) == 0
 }
 
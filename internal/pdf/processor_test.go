@@ -1,6 +1,7 @@
 package pdf
 
 import (
+	"context"
 	"image"
 	"image/color"
 	"testing"
@@ -237,6 +238,20 @@ func TestProcessor_ProcessFile_ErrorCases(t *testing.T) {
 	})
 }
 
+func TestProcessor_ProcessFileContext_Canceled(t *testing.T) {
+	path := buildMultiPagePDF(t, 3)
+	processor := NewProcessorWithConfig(nil, &ProcessorConfig{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err := processor.ProcessFileContext(ctx, path, "", nil)
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, result)
+	assert.True(t, result.Truncated)
+	assert.Len(t, result.Pages, result.TotalPages)
+	assert.Less(t, result.TotalPages, 3)
+}
+
 func TestProcessor_ProcessFiles(t *testing.T) {
 	processor := NewProcessor(nil)
 
@@ -0,0 +1,1039 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/filter"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+	_ "golang.org/x/image/tiff" // pdfcpu extracts CMYK images as TIFF
+	"golang.org/x/image/vector"
+)
+
+// maxFormDepth bounds form XObject nesting to guard against reference cycles.
+const maxFormDepth = 12
+
+// colorSpaceKind is how operands of the color operators are interpreted.
+type colorSpaceKind int
+
+const (
+	csGray colorSpaceKind = iota
+	csRGB
+	csCMYK
+	csTint    // Separation/DeviceN: 0 = no ink, 1 = full ink
+	csIndexed // palette lookup is not evaluated; painted black
+	csPattern // patterns and shadings are not painted
+)
+
+type paint struct {
+	space colorSpaceKind
+	color color.NRGBA
+}
+
+func (p *paint) set(ops []float64) {
+	clamp := func(v float64) uint8 { return uint8(math.Round(math.Max(0, math.Min(1, v)) * 255)) }
+	switch {
+	case p.space == csPattern:
+	case p.space == csIndexed:
+		p.color = color.NRGBA{A: p.color.A}
+	case p.space == csTint && len(ops) > 0:
+		v := clamp(1 - ops[0])
+		p.color = color.NRGBA{v, v, v, p.color.A}
+	case len(ops) >= 4:
+		r, g, b := color.CMYKToRGB(clamp(ops[0]), clamp(ops[1]), clamp(ops[2]), clamp(ops[3]))
+		p.color = color.NRGBA{r, g, b, p.color.A}
+	case len(ops) >= 3:
+		p.color = color.NRGBA{clamp(ops[0]), clamp(ops[1]), clamp(ops[2]), p.color.A}
+	case len(ops) >= 1:
+		v := clamp(ops[0])
+		p.color = color.NRGBA{v, v, v, p.color.A}
+	}
+}
+
+// clipRegion is the area painting is restricted to: a device rectangle and
+// an optional coverage mask (with the same bounds) for non-rectangular clips.
+type clipRegion struct {
+	rect image.Rectangle
+	mask *image.Alpha
+}
+
+type graphicsState struct {
+	ctm       Matrix
+	fill      paint
+	stroke    paint
+	lineWidth float64
+	clip      clipRegion
+}
+
+type point struct{ x, y float64 }
+
+type subpath struct {
+	pts    []point
+	closed bool
+}
+
+type decodedImage struct {
+	img     image.Image
+	stencil *image.Alpha // set for /ImageMask images, painted with the fill color
+}
+
+// pageRasterizer executes content streams onto an RGBA canvas. In collect
+// mode nothing is painted; images are recorded with their placement instead.
+type pageRasterizer struct {
+	ctx      *model.Context
+	canvas   *image.RGBA
+	images   map[int]decodedImage
+	failed   map[int]bool
+	warnings []string
+	ras      vector.Rasterizer
+	collect  bool
+	placed   []PageImage
+}
+
+func newPageRasterizer(ctx *model.Context) *pageRasterizer {
+	return &pageRasterizer{ctx: ctx, images: map[int]decodedImage{}, failed: map[int]bool{}}
+}
+
+func (r *pageRasterizer) renderPage(pageNr int, dpi float64) (*RenderedPage, error) {
+	d, res, geo, err := readPageGeometry(r.ctx, pageNr)
+	if err != nil {
+		return nil, err
+	}
+	pw, ph, effDPI := pagePixelSize(geo, dpi)
+	m := pageTransform(geo, pw, ph)
+	if geo.rotate == 90 || geo.rotate == 270 {
+		pw, ph = ph, pw
+	}
+
+	r.canvas = image.NewRGBA(image.Rect(0, 0, pw, ph))
+	draw.Draw(r.canvas, r.canvas.Bounds(), image.White, image.Point{}, draw.Src)
+	r.warnings = nil
+
+	content, err := r.ctx.PageContent(d, pageNr)
+	if err != nil && !errors.Is(err, model.ErrNoContent) {
+		return nil, fmt.Errorf("page %d: %w", pageNr, err)
+	}
+
+	gs := graphicsState{
+		ctm:       m,
+		fill:      paint{color: color.NRGBA{A: 255}},
+		stroke:    paint{color: color.NRGBA{A: 255}},
+		lineWidth: 1,
+		clip:      clipRegion{rect: r.canvas.Bounds()},
+	}
+	r.execute(content, res, gs, 0)
+
+	return &RenderedPage{
+		PageNumber: pageNr,
+		Image:      r.canvas,
+		DPI:        effDPI,
+		Box:        geo.box,
+		Rotate:     geo.rotate,
+		Transform:  m,
+		Warnings:   r.warnings,
+	}, nil
+}
+
+func (r *pageRasterizer) warn(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	for _, w := range r.warnings {
+		if w == msg {
+			return
+		}
+	}
+	r.warnings = append(r.warnings, msg)
+}
+
+// execute interprets a content stream. Operators that do not affect the
+// raster (text, marked content, compatibility sections) are ignored.
+func (r *pageRasterizer) execute(content []byte, res types.Dict, gs graphicsState, depth int) {
+	lex := newContentLexer(content)
+	var stack []graphicsState
+	var operands []contentToken
+	var path []subpath
+	var cur, start point // current point and subpath start, in user space
+	pendingClip := false
+
+	nums := func(n int) ([]float64, bool) {
+		if len(operands) < n {
+			return nil, false
+		}
+		out := make([]float64, n)
+		for i, t := range operands[len(operands)-n:] {
+			if t.kind != tokNumber {
+				return nil, false
+			}
+			out[i] = t.num
+		}
+		return out, true
+	}
+	trailingNums := func() []float64 {
+		var out []float64
+		for _, t := range operands {
+			if t.kind == tokNumber {
+				out = append(out, t.num)
+			}
+		}
+		return out
+	}
+	lastName := func() string {
+		if len(operands) > 0 && operands[len(operands)-1].kind == tokName {
+			return operands[len(operands)-1].str
+		}
+		return ""
+	}
+	addPoint := func(p point) {
+		x, y := gs.ctm.Apply(p.x, p.y)
+		sp := &path[len(path)-1]
+		sp.pts = append(sp.pts, point{x, y})
+	}
+	moveTo := func(p point) {
+		path = append(path, subpath{})
+		addPoint(p)
+		cur, start = p, p
+	}
+	ensureSubpath := func() {
+		if len(path) == 0 {
+			moveTo(cur)
+		}
+	}
+	curveTo := func(p1, p2, p3 point) {
+		ensureSubpath()
+		x0, y0 := gs.ctm.Apply(cur.x, cur.y)
+		x1, y1 := gs.ctm.Apply(p1.x, p1.y)
+		x2, y2 := gs.ctm.Apply(p2.x, p2.y)
+		x3, y3 := gs.ctm.Apply(p3.x, p3.y)
+		length := math.Hypot(x1-x0, y1-y0) + math.Hypot(x2-x1, y2-y1) + math.Hypot(x3-x2, y3-y2)
+		steps := int(math.Min(64, math.Max(4, length/4)))
+		sp := &path[len(path)-1]
+		for i := 1; i <= steps; i++ {
+			t := float64(i) / float64(steps)
+			u := 1 - t
+			a, b, c, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+			sp.pts = append(sp.pts, point{a*x0 + b*x1 + c*x2 + d*x3, a*y0 + b*y1 + c*y2 + d*y3})
+		}
+		cur = p3
+	}
+	finishPath := func(fill, stroke, closePath bool) {
+		if closePath && len(path) > 0 {
+			path[len(path)-1].closed = true
+		}
+		if fill {
+			r.fillPath(path, gs)
+		}
+		if stroke {
+			r.strokePath(path, gs)
+		}
+		if pendingClip {
+			gs.clip = r.intersectClip(gs.clip, path)
+			pendingClip = false
+		}
+		path = nil
+	}
+
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			return
+		}
+		if tok.kind != tokOperator {
+			operands = append(operands, tok)
+			continue
+		}
+		switch tok.str {
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if n := len(stack); n > 0 {
+				gs, stack = stack[n-1], stack[:n-1]
+			}
+		case "cm":
+			if v, ok := nums(6); ok {
+				gs.ctm = Matrix{v[0], v[1], v[2], v[3], v[4], v[5]}.Multiply(gs.ctm)
+			}
+		case "w":
+			if v, ok := nums(1); ok {
+				gs.lineWidth = v[0]
+			}
+		case "gs":
+			r.applyExtGState(res, lastName(), &gs)
+		case "g":
+			gs.fill.space = csGray
+			gs.fill.set(trailingNums())
+		case "G":
+			gs.stroke.space = csGray
+			gs.stroke.set(trailingNums())
+		case "rg":
+			gs.fill.space = csRGB
+			gs.fill.set(trailingNums())
+		case "RG":
+			gs.stroke.space = csRGB
+			gs.stroke.set(trailingNums())
+		case "k":
+			gs.fill.space = csCMYK
+			gs.fill.set(trailingNums())
+		case "K":
+			gs.stroke.space = csCMYK
+			gs.stroke.set(trailingNums())
+		case "cs":
+			gs.fill.space = r.colorSpace(res, lastName())
+			gs.fill.set(initialColor(gs.fill.space))
+		case "CS":
+			gs.stroke.space = r.colorSpace(res, lastName())
+			gs.stroke.set(initialColor(gs.stroke.space))
+		case "sc", "scn":
+			gs.fill.set(trailingNums())
+		case "SC", "SCN":
+			gs.stroke.set(trailingNums())
+		case "m":
+			if v, ok := nums(2); ok {
+				moveTo(point{v[0], v[1]})
+			}
+		case "l":
+			if v, ok := nums(2); ok {
+				ensureSubpath()
+				cur = point{v[0], v[1]}
+				addPoint(cur)
+			}
+		case "c":
+			if v, ok := nums(6); ok {
+				curveTo(point{v[0], v[1]}, point{v[2], v[3]}, point{v[4], v[5]})
+			}
+		case "v":
+			if v, ok := nums(4); ok {
+				curveTo(cur, point{v[0], v[1]}, point{v[2], v[3]})
+			}
+		case "y":
+			if v, ok := nums(4); ok {
+				p := point{v[2], v[3]}
+				curveTo(point{v[0], v[1]}, p, p)
+			}
+		case "h":
+			if len(path) > 0 {
+				path[len(path)-1].closed = true
+				cur = start
+			}
+		case "re":
+			if v, ok := nums(4); ok {
+				x, y, w, h := v[0], v[1], v[2], v[3]
+				moveTo(point{x, y})
+				addPoint(point{x + w, y})
+				addPoint(point{x + w, y + h})
+				addPoint(point{x, y + h})
+				path[len(path)-1].closed = true
+			}
+		case "f", "F", "f*":
+			finishPath(true, false, false)
+		case "S":
+			finishPath(false, true, false)
+		case "s":
+			finishPath(false, true, true)
+		case "B", "B*":
+			finishPath(true, true, false)
+		case "b", "b*":
+			finishPath(true, true, true)
+		case "n":
+			finishPath(false, false, false)
+		case "W", "W*":
+			pendingClip = true
+		case "Do":
+			r.drawXObject(res, lastName(), gs, depth)
+		case "BI":
+			params, data := lex.inlineImage()
+			r.drawInlineImage(res, params, data, gs)
+		case "sh":
+			if r.collect {
+				break
+			}
+			r.warn("shading %q not rendered", lastName())
+		}
+		operands = operands[:0]
+	}
+}
+
+func initialColor(space colorSpaceKind) []float64 {
+	switch space {
+	case csCMYK:
+		return []float64{0, 0, 0, 1}
+	case csTint:
+		return []float64{1}
+	default:
+		return []float64{0}
+	}
+}
+
+// colorSpace classifies a color space given by name, resolving resource names.
+func (r *pageRasterizer) colorSpace(res types.Dict, name string) colorSpaceKind {
+	switch name {
+	case "DeviceGray", "CalGray", "G":
+		return csGray
+	case "DeviceRGB", "CalRGB", "Lab", "RGB":
+		return csRGB
+	case "DeviceCMYK", "CMYK":
+		return csCMYK
+	case "Pattern":
+		return csPattern
+	}
+	obj := r.resource(res, "ColorSpace", name)
+	return r.colorSpaceObject(obj)
+}
+
+func (r *pageRasterizer) colorSpaceObject(obj types.Object) colorSpaceKind {
+	obj, _ = r.ctx.Dereference(obj)
+	switch o := obj.(type) {
+	case types.Name:
+		return r.colorSpace(nil, o.Value())
+	case types.Array:
+		if len(o) == 0 {
+			return csGray
+		}
+		family, _ := r.ctx.Dereference(o[0])
+		name, _ := family.(types.Name)
+		switch name.Value() {
+		case "Separation", "DeviceN":
+			return csTint
+		case "Indexed", "I":
+			return csIndexed
+		case "Pattern":
+			return csPattern
+		case "ICCBased":
+			if len(o) > 1 {
+				if sd, _, err := r.ctx.DereferenceStreamDict(o[1]); err == nil && sd != nil {
+					if n := sd.IntEntry("N"); n != nil {
+						switch *n {
+						case 3:
+							return csRGB
+						case 4:
+							return csCMYK
+						}
+					}
+				}
+			}
+			return csGray
+		default:
+			return r.colorSpace(nil, name.Value())
+		}
+	}
+	return csGray
+}
+
+// resource looks up a named entry in a resource category (XObject, ExtGState, ...).
+func (r *pageRasterizer) resource(res types.Dict, category, name string) types.Object {
+	if res == nil || name == "" {
+		return nil
+	}
+	cat, err := r.ctx.DereferenceDict(res[category])
+	if err != nil || cat == nil {
+		return nil
+	}
+	return cat[name]
+}
+
+func (r *pageRasterizer) applyExtGState(res types.Dict, name string, gs *graphicsState) {
+	d, err := r.ctx.DereferenceDict(r.resource(res, "ExtGState", name))
+	if err != nil || d == nil {
+		return
+	}
+	alpha := func(key string, p *paint) {
+		if v, err := r.ctx.DereferenceNumber(d[key]); err == nil && d[key] != nil {
+			p.color.A = uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+		}
+	}
+	alpha("ca", &gs.fill)
+	alpha("CA", &gs.stroke)
+	if v, err := r.ctx.DereferenceNumber(d["LW"]); err == nil && d["LW"] != nil {
+		gs.lineWidth = v
+	}
+}
+
+// coverage rasterizes subpaths (device space) into an alpha mask covering their bounding box.
+func (r *pageRasterizer) coverage(paths []subpath, bounds image.Rectangle) *image.Alpha {
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, sp := range paths {
+		for _, p := range sp.pts {
+			minX, minY = math.Min(minX, p.x), math.Min(minY, p.y)
+			maxX, maxY = math.Max(maxX, p.x), math.Max(maxY, p.y)
+		}
+	}
+	if math.IsInf(minX, 0) {
+		return nil
+	}
+	box := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY))).Intersect(bounds)
+	if box.Empty() {
+		return nil
+	}
+	ox, oy := float32(box.Min.X), float32(box.Min.Y)
+	r.ras.Reset(box.Dx(), box.Dy())
+	for _, sp := range paths {
+		if len(sp.pts) < 2 {
+			continue
+		}
+		r.ras.MoveTo(float32(sp.pts[0].x)-ox, float32(sp.pts[0].y)-oy)
+		for _, p := range sp.pts[1:] {
+			r.ras.LineTo(float32(p.x)-ox, float32(p.y)-oy)
+		}
+		r.ras.ClosePath()
+	}
+	mask := image.NewAlpha(image.Rect(0, 0, box.Dx(), box.Dy()))
+	r.ras.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+	mask.Rect = box
+	return mask
+}
+
+// applyClip restricts a coverage mask to the clip region in place.
+func applyClip(mask *image.Alpha, clip clipRegion) *image.Alpha {
+	box := mask.Rect.Intersect(clip.rect)
+	if box.Empty() {
+		return nil
+	}
+	mask = mask.SubImage(box).(*image.Alpha)
+	if clip.mask == nil {
+		return mask
+	}
+	for y := box.Min.Y; y < box.Max.Y; y++ {
+		for x := box.Min.X; x < box.Max.X; x++ {
+			i := mask.PixOffset(x, y)
+			mask.Pix[i] = uint8(uint16(mask.Pix[i]) * uint16(clip.mask.AlphaAt(x, y).A) / 255)
+		}
+	}
+	return mask
+}
+
+func (r *pageRasterizer) paintMask(mask *image.Alpha, p paint, clip clipRegion) {
+	if mask == nil || p.space == csPattern || p.color.A == 0 {
+		return
+	}
+	if mask = applyClip(mask, clip); mask == nil {
+		return
+	}
+	draw.DrawMask(r.canvas, mask.Rect, image.NewUniform(p.color), image.Point{}, mask, mask.Rect.Min, draw.Over)
+}
+
+// fillPath fills with the nonzero winding rule; even-odd fills are approximated by it.
+func (r *pageRasterizer) fillPath(paths []subpath, gs graphicsState) {
+	if r.collect {
+		return
+	}
+	r.paintMask(r.coverage(paths, r.canvas.Bounds()), gs.fill, gs.clip)
+}
+
+// strokePath approximates a stroke by one quad per segment plus square joins,
+// which is accurate enough for rules, boxes and outlined glyphs.
+func (r *pageRasterizer) strokePath(paths []subpath, gs graphicsState) {
+	if r.collect {
+		return
+	}
+	scale := math.Sqrt(math.Abs(gs.ctm[0]*gs.ctm[3] - gs.ctm[1]*gs.ctm[2]))
+	hw := math.Max(1, gs.lineWidth*scale) / 2
+	var quads []subpath
+	square := func(p point) {
+		quads = append(quads, subpath{pts: []point{{p.x - hw, p.y + hw}, {p.x + hw, p.y + hw}, {p.x + hw, p.y - hw}, {p.x - hw, p.y - hw}}})
+	}
+	for _, sp := range paths {
+		pts := sp.pts
+		if sp.closed && len(pts) > 1 {
+			pts = append(pts[:len(pts):len(pts)], pts[0])
+		}
+		for i, p := range pts {
+			square(p)
+			if i == 0 {
+				continue
+			}
+			q := pts[i-1]
+			dx, dy := p.x-q.x, p.y-q.y
+			l := math.Hypot(dx, dy)
+			if l == 0 {
+				continue
+			}
+			nx, ny := -dy/l*hw, dx/l*hw
+			quads = append(quads, subpath{pts: []point{{q.x + nx, q.y + ny}, {p.x + nx, p.y + ny}, {p.x - nx, p.y - ny}, {q.x - nx, q.y - ny}}})
+		}
+	}
+	r.paintMask(r.coverage(quads, r.canvas.Bounds()), gs.stroke, gs.clip)
+}
+
+// intersectClip narrows the clip region by a path. Axis-aligned rectangles
+// stay rectangles; other shapes produce a coverage mask.
+func (r *pageRasterizer) intersectClip(clip clipRegion, paths []subpath) clipRegion {
+	if r.collect {
+		return clip
+	}
+	if rect, ok := axisAlignedRect(paths); ok {
+		clip.rect = clip.rect.Intersect(rect)
+		if clip.mask != nil {
+			clip.mask = clip.mask.SubImage(clip.rect).(*image.Alpha)
+		}
+		return clip
+	}
+	mask := r.coverage(paths, clip.rect)
+	if mask == nil {
+		return clipRegion{}
+	}
+	if mask = applyClip(mask, clip); mask == nil {
+		return clipRegion{}
+	}
+	return clipRegion{rect: mask.Rect, mask: mask}
+}
+
+func axisAlignedRect(paths []subpath) (image.Rectangle, bool) {
+	if len(paths) != 1 {
+		return image.Rectangle{}, false
+	}
+	pts := paths[0].pts
+	if len(pts) == 5 && pts[4] == pts[0] {
+		pts = pts[:4]
+	}
+	if len(pts) != 4 {
+		return image.Rectangle{}, false
+	}
+	const eps = 1e-6
+	for i := range pts {
+		a, b := pts[i], pts[(i+1)%4]
+		if math.Abs(a.x-b.x) > eps && math.Abs(a.y-b.y) > eps {
+			return image.Rectangle{}, false
+		}
+	}
+	minX := math.Min(math.Min(pts[0].x, pts[1].x), math.Min(pts[2].x, pts[3].x))
+	maxX := math.Max(math.Max(pts[0].x, pts[1].x), math.Max(pts[2].x, pts[3].x))
+	minY := math.Min(math.Min(pts[0].y, pts[1].y), math.Min(pts[2].y, pts[3].y))
+	maxY := math.Max(math.Max(pts[0].y, pts[1].y), math.Max(pts[2].y, pts[3].y))
+	return image.Rect(int(math.Round(minX)), int(math.Round(minY)), int(math.Round(maxX)), int(math.Round(maxY))), true
+}
+
+func (r *pageRasterizer) drawXObject(res types.Dict, name string, gs graphicsState, depth int) {
+	obj := r.resource(res, "XObject", name)
+	if obj == nil {
+		return
+	}
+	objNr := 0
+	if ref, ok := obj.(types.IndirectRef); ok {
+		objNr = ref.ObjectNumber.Value()
+	}
+	sd, _, err := r.ctx.DereferenceStreamDict(obj)
+	if err != nil || sd == nil {
+		r.warn("xobject %s: %v", name, err)
+		return
+	}
+	switch st := sd.Subtype(); {
+	case st != nil && *st == "Image":
+		r.drawImage(sd, objNr, name, gs)
+	case st != nil && *st == "Form":
+		r.drawForm(sd, res, name, gs, depth)
+	}
+}
+
+func (r *pageRasterizer) drawForm(sd *types.StreamDict, parentRes types.Dict, name string, gs graphicsState, depth int) {
+	if depth >= maxFormDepth {
+		r.warn("form %s: nesting deeper than %d levels", name, maxFormDepth)
+		return
+	}
+	if err := sd.Decode(); err != nil {
+		r.warn("form %s: %v", name, err)
+		return
+	}
+	if m := sd.ArrayEntry("Matrix"); len(m) == 6 {
+		var v Matrix
+		for i, o := range m {
+			v[i], _ = r.ctx.DereferenceNumber(o)
+		}
+		gs.ctm = v.Multiply(gs.ctm)
+	}
+	if bbox := sd.ArrayEntry("BBox"); len(bbox) == 4 {
+		var v [4]float64
+		for i, o := range bbox {
+			v[i], _ = r.ctx.DereferenceNumber(o)
+		}
+		var sp subpath
+		for _, p := range []point{{v[0], v[1]}, {v[2], v[1]}, {v[2], v[3]}, {v[0], v[3]}} {
+			x, y := gs.ctm.Apply(p.x, p.y)
+			sp.pts = append(sp.pts, point{x, y})
+		}
+		gs.clip = r.intersectClip(gs.clip, []subpath{sp})
+	}
+	res := parentRes
+	if d, err := r.ctx.DereferenceDict(sd.Dict["Resources"]); err == nil && d != nil {
+		res = d
+	}
+	r.execute(sd.Content, res, gs, depth+1)
+}
+
+// drawImage paints an image XObject into the unit square of the current CTM.
+func (r *pageRasterizer) drawImage(sd *types.StreamDict, objNr int, name string, gs graphicsState) {
+	if objNr > 0 && r.failed[objNr] {
+		return
+	}
+	img, ok := r.images[objNr]
+	if !ok || objNr == 0 {
+		var err error
+		img, err = r.decodeImage(sd, objNr, name)
+		if err != nil {
+			r.warn("image %s: %v", name, err)
+			if objNr > 0 {
+				r.failed[objNr] = true
+			}
+			return
+		}
+		if objNr > 0 {
+			r.images[objNr] = img
+		}
+	}
+	r.composite(img, gs)
+}
+
+func (r *pageRasterizer) composite(img decodedImage, gs graphicsState) {
+	src := img.img
+	if img.stencil != nil {
+		if gs.fill.space == csPattern {
+			return
+		}
+		src = stencilImage(img.stencil, gs.fill.color)
+	}
+	b := src.Bounds()
+	if b.Empty() {
+		return
+	}
+	// Image space maps the image onto the unit square with row 0 at the top.
+	m := Matrix{1 / float64(b.Dx()), 0, 0, -1 / float64(b.Dy()), 0, 1}.Multiply(gs.ctm)
+	if r.collect {
+		if img.stencil != nil {
+			src = flattenOnWhite(src)
+		}
+		r.placed = append(r.placed, PageImage{Image: src, Placement: m})
+		return
+	}
+	if gs.clip.rect.Empty() {
+		return
+	}
+	s2d := f64.Aff3{m[0], m[2], m[4], m[1], m[3], m[5]}
+	opts := &draw.Options{DstMask: gs.clip.rect}
+	if gs.clip.mask != nil {
+		opts.DstMask = gs.clip.mask
+	}
+	var interp draw.Transformer = draw.BiLinear
+	if math.Abs(m[0]*m[3]-m[1]*m[2]) >= 1 {
+		// Upscaling: the cheaper approximation is indistinguishable.
+		interp = draw.ApproxBiLinear
+	}
+	interp.Transform(r.canvas, s2d, src, b, draw.Over, opts)
+}
+
+// flattenOnWhite composites a transparent image onto a white background.
+func flattenOnWhite(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(out, out.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(out, out.Bounds(), src, b.Min, draw.Over)
+	return out
+}
+
+func stencilImage(mask *image.Alpha, c color.NRGBA) *image.NRGBA {
+	out := image.NewNRGBA(mask.Bounds())
+	for i, a := range mask.Pix {
+		out.Pix[4*i], out.Pix[4*i+1], out.Pix[4*i+2] = c.R, c.G, c.B
+		out.Pix[4*i+3] = uint8(uint16(a) * uint16(c.A) / 255)
+	}
+	return out
+}
+
+// decodeImage decodes an image stream with pdfcpu; stencil masks and
+// bilevel (CCITT, JBIG2) images, which pdfcpu does not render, are decoded
+// here.
+func (r *pageRasterizer) decodeImage(sd *types.StreamDict, objNr int, name string) (decodedImage, error) {
+	if n := len(sd.FilterPipeline); n > 0 {
+		switch sd.FilterPipeline[n-1].Name {
+		case filter.CCITTFax, filter.JBIG2:
+			return r.decodeBilevel(sd, name)
+		}
+	}
+	if im := sd.BooleanEntry("ImageMask"); im != nil && *im {
+		mask, err := r.decodeStencil(sd)
+		return decodedImage{stencil: mask}, err
+	}
+	extracted, err := pdfcpu.ExtractImage(r.ctx, sd, false, name, objNr, false)
+	if err != nil {
+		return decodedImage{}, err
+	}
+	if extracted == nil || extracted.Reader == nil {
+		return decodedImage{}, fmt.Errorf("unsupported encoding (%s)", imageFilters(sd))
+	}
+	if extracted.FileType == "jpx" {
+		return decodedImage{}, errors.New("unsupported encoding (JPXDecode)")
+	}
+	img, _, err := image.Decode(extracted.Reader)
+	if err != nil {
+		return decodedImage{}, fmt.Errorf("decode %s: %w", extracted.FileType, err)
+	}
+	return decodedImage{img: img}, nil
+}
+
+func imageFilters(sd *types.StreamDict) string {
+	if len(sd.FilterPipeline) == 0 {
+		return "no filter"
+	}
+	names := make([]string, len(sd.FilterPipeline))
+	for i, f := range sd.FilterPipeline {
+		names[i] = f.Name
+	}
+	return strings.Join(names, ",")
+}
+
+// decodeStencil unpacks a 1-bit /ImageMask into coverage: by default a 0
+// sample paints, a /Decode of [1 0] inverts that.
+func (r *pageRasterizer) decodeStencil(sd *types.StreamDict) (*image.Alpha, error) {
+	w, h := r.intEntry(sd.Dict, "Width"), r.intEntry(sd.Dict, "Height")
+	if w <= 0 || h <= 0 {
+		return nil, errors.New("image mask without dimensions")
+	}
+	if len(sd.FilterPipeline) == 0 {
+		sd.Content = sd.Raw
+	} else if err := sd.Decode(); err != nil {
+		return nil, fmt.Errorf("image mask (%s): %w", imageFilters(sd), err)
+	}
+	paintBit := byte(0)
+	if dec := sd.ArrayEntry("Decode"); len(dec) == 2 {
+		if v, err := r.ctx.DereferenceNumber(dec[0]); err == nil && v == 1 {
+			paintBit = 1
+		}
+	}
+	stride := (w + 7) / 8
+	if len(sd.Content) < stride*h {
+		return nil, fmt.Errorf("image mask data too short: %d < %d bytes", len(sd.Content), stride*h)
+	}
+	mask := image.NewAlpha(image.Rect(0, 0, w, h))
+	for y := range h {
+		row := sd.Content[y*stride:]
+		for x := range w {
+			if (row[x/8]>>(7-uint(x%8)))&1 == paintBit {
+				mask.Pix[y*mask.Stride+x] = 0xff
+			}
+		}
+	}
+	return mask, nil
+}
+
+// decodeBilevel decodes an image whose last filter is CCITTFaxDecode or
+// JBIG2Decode. A bitmap that is cut short by corrupt or truncated data is
+// still used, with a warning; the missing rows stay white.
+func (r *pageRasterizer) decodeBilevel(sd *types.StreamDict, name string) (decodedImage, error) {
+	w, h := r.intEntry(sd.Dict, "Width"), r.intEntry(sd.Dict, "Height")
+	if w <= 0 || h <= 0 {
+		return decodedImage{}, errors.New("image without dimensions")
+	}
+	last := sd.FilterPipeline[len(sd.FilterPipeline)-1]
+	data := sd.Raw
+	if len(sd.FilterPipeline) > 1 {
+		pre := *sd
+		pre.FilterPipeline = sd.FilterPipeline[:len(sd.FilterPipeline)-1]
+		pre.Content = nil
+		if err := pre.Decode(); err != nil {
+			return decodedImage{}, fmt.Errorf("decode %s: %w", imageFilters(&pre), err)
+		}
+		data = pre.Content
+	}
+
+	var bm *bitmap
+	var err error
+	// blackSample is the sample value of a black pixel before /Decode.
+	blackSample := byte(0)
+	switch last.Name {
+	case filter.CCITTFax:
+		p := ccittParamsFromDict(func(key string) (int, bool) {
+			return r.parmInt(last.DecodeParms, key)
+		}, w, h)
+		if p.BlackIs1 {
+			blackSample = 1
+		}
+		bm, err = decodeCCITT(data, p)
+	case filter.JBIG2:
+		var globals []byte
+		if obj, ok := last.DecodeParms["JBIG2Globals"]; ok {
+			gsd, _, gerr := r.ctx.DereferenceStreamDict(obj)
+			if gerr != nil || gsd == nil {
+				return decodedImage{}, fmt.Errorf("jbig2 globals: %v", gerr)
+			}
+			if gerr := gsd.Decode(); gerr != nil {
+				return decodedImage{}, fmt.Errorf("jbig2 globals: %w", gerr)
+			}
+			globals = gsd.Content
+		}
+		bm, err = decodeJBIG2(data, globals)
+	}
+	if bm == nil {
+		return decodedImage{}, err
+	}
+	if err != nil {
+		r.warn("image %s: incomplete: %v", name, err)
+	}
+
+	invert := false
+	if dec := sd.ArrayEntry("Decode"); len(dec) == 2 {
+		if v, derr := r.ctx.DereferenceNumber(dec[0]); derr == nil && v == 1 {
+			invert = true
+		}
+	}
+	sample := func(x, y int) byte {
+		s := 1 - blackSample
+		if bm.at(x, y) == 1 {
+			s = blackSample
+		}
+		if invert {
+			s = 1 - s
+		}
+		return s
+	}
+
+	rect := image.Rect(0, 0, w, h)
+	if im := sd.BooleanEntry("ImageMask"); im != nil && *im {
+		// After /Decode a 0 sample paints.
+		mask := image.NewAlpha(rect)
+		for y := range h {
+			for x := range w {
+				if sample(x, y) == 0 {
+					mask.Pix[y*mask.Stride+x] = 0xff
+				}
+			}
+		}
+		return decodedImage{stencil: mask}, nil
+	}
+	gray := image.NewGray(rect)
+	for y := range h {
+		for x := range w {
+			gray.Pix[y*gray.Stride+x] = 0xff * sample(x, y)
+		}
+	}
+	return decodedImage{img: gray}, nil
+}
+
+// parmInt reads an integer or boolean (as 0 or 1) filter parameter.
+func (r *pageRasterizer) parmInt(parms types.Dict, key string) (int, bool) {
+	obj, ok := parms[key]
+	if !ok {
+		return 0, false
+	}
+	obj, err := r.ctx.Dereference(obj)
+	if err != nil {
+		return 0, false
+	}
+	switch v := obj.(type) {
+	case types.Integer:
+		return int(v), true
+	case types.Float:
+		return int(v), true
+	case types.Boolean:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func (r *pageRasterizer) intEntry(d types.Dict, key string) int {
+	v, err := r.ctx.DereferenceNumber(d[key])
+	if err != nil {
+		return 0
+	}
+	return int(v)
+}
+
+// inlineImageKeys expands the abbreviated keys allowed in inline images.
+var inlineImageKeys = map[string]string{
+	"BPC": "BitsPerComponent", "CS": "ColorSpace", "D": "Decode", "DP": "DecodeParms",
+	"F": "Filter", "H": "Height", "W": "Width", "IM": "ImageMask", "I": "Interpolate",
+}
+
+// inlineImageNames expands abbreviated color space and filter names.
+var inlineImageNames = map[string]string{
+	"G": "DeviceGray", "RGB": "DeviceRGB", "CMYK": "DeviceCMYK", "I": "Indexed",
+	"AHx": "ASCIIHexDecode", "A85": "ASCII85Decode", "LZW": "LZWDecode", "Fl": "FlateDecode",
+	"RL": "RunLengthDecode", "CCF": "CCITTFaxDecode", "DCT": "DCTDecode",
+}
+
+func (r *pageRasterizer) drawInlineImage(res types.Dict, params map[string]contentToken, data []byte, gs graphicsState) {
+	d := types.Dict{}
+	for k, v := range params {
+		if full, ok := inlineImageKeys[k]; ok {
+			k = full
+		}
+		d[k] = inlineObject(v)
+	}
+	// Inline images may name a color space from the resources.
+	if cs, ok := d["ColorSpace"].(types.Name); ok && !strings.HasPrefix(cs.Value(), "Device") && cs.Value() != "Indexed" {
+		if obj := r.resource(res, "ColorSpace", cs.Value()); obj != nil {
+			d["ColorSpace"] = obj
+		}
+	}
+
+	var filters []types.PDFFilter
+	var parms []types.Object
+	switch p := d["DecodeParms"].(type) {
+	case types.Dict:
+		parms = []types.Object{p}
+	case types.Array:
+		parms = p
+	}
+	addFilter := func(i int, name types.Name) {
+		f := types.PDFFilter{Name: name.Value()}
+		if i < len(parms) {
+			f.DecodeParms, _ = parms[i].(types.Dict)
+		}
+		filters = append(filters, f)
+	}
+	switch f := d["Filter"].(type) {
+	case types.Name:
+		addFilter(0, f)
+	case types.Array:
+		for i, o := range f {
+			if n, ok := o.(types.Name); ok {
+				addFilter(i, n)
+			}
+		}
+	}
+
+	sd := types.NewStreamDict(d, 0, nil, nil, filters)
+	sd.Raw = data
+	sd.InsertName("Type", "XObject")
+	sd.InsertName("Subtype", "Image")
+	r.drawImage(&sd, 0, "inline", gs)
+}
+
+// inlineObject converts an inline image operand to a pdfcpu object.
+func inlineObject(t contentToken) types.Object {
+	switch t.kind {
+	case tokNumber:
+		if t.num == math.Trunc(t.num) {
+			return types.Integer(int(t.num))
+		}
+		return types.Float(t.num)
+	case tokName:
+		if full, ok := inlineImageNames[t.str]; ok {
+			return types.Name(full)
+		}
+		return types.Name(t.str)
+	case tokBool:
+		return types.Boolean(t.str == "true")
+	case tokString:
+		return types.StringLiteral(t.str)
+	case tokArray:
+		arr := make(types.Array, len(t.array))
+		for i, e := range t.array {
+			arr[i] = inlineObject(e)
+		}
+		return arr
+	case tokDict:
+		d := types.Dict{}
+		for k, v := range t.dict {
+			d[k] = inlineObject(v)
+		}
+		return d
+	}
+	return nil
+}
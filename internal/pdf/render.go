@@ -0,0 +1,346 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Page modes select how PDF pages become images for OCR.
+const (
+	// PageModeRender rasterizes whole pages.
+	PageModeRender = "render"
+	// PageModeExtract uses the embedded image XObjects of each page as they are stored.
+	PageModeExtract = "extract"
+)
+
+const (
+	// DefaultRenderDPI is the resolution pages are rendered at when none is given.
+	DefaultRenderDPI = 200
+	// maxRenderPixels caps the canvas size; larger pages are rendered at a lower DPI.
+	maxRenderPixels = 40_000_000
+)
+
+// Matrix is a PDF transformation matrix [a b c d e f] mapping (x, y) to
+// (a*x + c*y + e, b*x + d*y + f).
+type Matrix [6]float64
+
+// IdentityMatrix is the identity transformation.
+var IdentityMatrix = Matrix{1, 0, 0, 1, 0, 0}
+
+// Multiply returns the transformation that applies m first and then n.
+func (m Matrix) Multiply(n Matrix) Matrix {
+	return Matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// Apply transforms the point (x, y).
+func (m Matrix) Apply(x, y float64) (float64, float64) {
+	return m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]
+}
+
+// Invert returns the inverse transformation, or false if m is singular.
+func (m Matrix) Invert() (Matrix, bool) {
+	det := m[0]*m[3] - m[1]*m[2]
+	if det == 0 || math.IsNaN(det) {
+		return Matrix{}, false
+	}
+	return Matrix{
+		m[3] / det,
+		-m[1] / det,
+		-m[2] / det,
+		m[0] / det,
+		(m[2]*m[5] - m[3]*m[4]) / det,
+		(m[1]*m[4] - m[0]*m[5]) / det,
+	}, true
+}
+
+// RenderedPage is a PDF page rasterized at a given resolution.
+type RenderedPage struct {
+	PageNumber int
+	Image      image.Image
+	// DPI is the effective resolution; it can be lower than requested for very large pages.
+	DPI float64
+	// Box is the visible page area (crop box, or media box) in PDF points.
+	Box types.Rectangle
+	// Rotate is the page's /Rotate value normalized to 0, 90, 180 or 270.
+	Rotate int
+	// Transform maps PDF user space (points, origin bottom-left) to image pixels.
+	Transform Matrix
+	// Warnings lists content that could not be rendered, e.g. unsupported image encodings.
+	Warnings []string
+}
+
+// PageRenderer rasterizes PDF pages. A nil or empty page list renders all pages.
+type PageRenderer interface {
+	RenderPages(filename string, pages []int, dpi int) (map[int]*RenderedPage, error)
+}
+
+// contextRenderer is implemented by renderers that can reuse a PDF that has
+// already been read, so that rendering page by page does not parse the
+// document again for every page.
+type contextRenderer interface {
+	renderContext(ctx *model.Context, filename string, pages []int, dpi int) (map[int]*RenderedPage, error)
+}
+
+// RenderOptions controls RenderPages.
+type RenderOptions struct {
+	// DPI is the target resolution (0 = DefaultRenderDPI).
+	DPI int
+	// Renderer rasterizes the pages (nil = NativeRenderer).
+	Renderer PageRenderer
+}
+
+// RenderPages renders the pages of a PDF selected by pageRange (empty = all).
+func RenderPages(filename string, pageRange string, opts RenderOptions) (map[int]*RenderedPage, error) {
+	pageNumbers, err := parsePageRange(pageRange)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page range %q: %w", pageRange, err)
+	}
+
+	pageCount, err := api.PageCountFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page count: %w", err)
+	}
+
+	validPages := validateAndFilterPages(pageNumbers, pageCount)
+	if shouldReturnEmpty(pageNumbers, validPages) {
+		return make(map[int]*RenderedPage), nil
+	}
+	if len(validPages) == 0 {
+		validPages = make([]int, pageCount)
+		for i := range validPages {
+			validPages[i] = i + 1
+		}
+	}
+
+	dpi := opts.DPI
+	if dpi <= 0 {
+		dpi = DefaultRenderDPI
+	}
+	renderer := opts.Renderer
+	if renderer == nil {
+		renderer = NativeRenderer{}
+	}
+	return renderer.RenderPages(filename, validPages, dpi)
+}
+
+// PageImages returns the images to OCR for each selected page: one rendered
+// image per page, or the embedded images in PageModeExtract. Use PageSets to
+// also get their placement on the page.
+func PageImages(filename, pageRange, mode string, opts RenderOptions) (map[int][]image.Image, error) {
+	sets, err := PageSets(filename, pageRange, mode, opts)
+	if err != nil {
+		return nil, err
+	}
+	pageImages := make(map[int][]image.Image, len(sets))
+	for n, set := range sets {
+		images := make([]image.Image, len(set.Images))
+		for i, pi := range set.Images {
+			images[i] = pi.Image
+		}
+		pageImages[n] = images
+	}
+	return pageImages, nil
+}
+
+// pageGeometry describes the visible area and orientation of a page.
+type pageGeometry struct {
+	box    types.Rectangle
+	rotate int
+}
+
+// readPageGeometry returns the page dictionary, its (possibly inherited)
+// resources, and the crop box (falling back to the media box) and rotation.
+func readPageGeometry(ctx *model.Context, pageNr int) (types.Dict, types.Dict, pageGeometry, error) {
+	d, _, inh, err := ctx.PageDict(pageNr, false)
+	if err != nil {
+		return nil, nil, pageGeometry{}, fmt.Errorf("page %d: %w", pageNr, err)
+	}
+	if d == nil || inh == nil {
+		return nil, nil, pageGeometry{}, fmt.Errorf("page %d: missing page dictionary", pageNr)
+	}
+	geo := pageGeometry{box: *types.RectForFormat("Letter"), rotate: normalizeRotation(inh.Rotate)}
+	if inh.MediaBox != nil {
+		geo.box = *inh.MediaBox
+	}
+	if inh.CropBox != nil {
+		if crop := intersectRect(*inh.CropBox, geo.box); crop.Width() > 0 && crop.Height() > 0 {
+			geo.box = crop
+		}
+	}
+	return d, inh.Resources, geo, nil
+}
+
+func intersectRect(a, b types.Rectangle) types.Rectangle {
+	return *types.NewRectangle(
+		math.Max(a.LL.X, b.LL.X), math.Max(a.LL.Y, b.LL.Y),
+		math.Min(a.UR.X, b.UR.X), math.Min(a.UR.Y, b.UR.Y),
+	)
+}
+
+func normalizeRotation(rotate int) int {
+	r := ((rotate % 360) + 360) % 360
+	return r / 90 * 90
+}
+
+// pagePixelSize returns the unrotated pixel size of a page rendered at dpi,
+// lowering the resolution when the canvas would exceed maxRenderPixels.
+func pagePixelSize(geo pageGeometry, dpi float64) (int, int, float64) {
+	w, h := geo.box.Width(), geo.box.Height()
+	if pixels := w * h * dpi * dpi / (72 * 72); pixels > maxRenderPixels {
+		dpi *= math.Sqrt(maxRenderPixels / pixels)
+	}
+	return max(1, int(math.Round(w*dpi/72))), max(1, int(math.Round(h*dpi/72))), dpi
+}
+
+// pageTransform returns the matrix mapping user space to a pw x ph pixel
+// canvas (unrotated size): the visible box is scaled, flipped so that y grows
+// downwards, and rotated clockwise by the page rotation.
+func pageTransform(geo pageGeometry, pw, ph int) Matrix {
+	return boxTransform(geo, float64(pw), float64(ph))
+}
+
+// boxTransform is pageTransform for a canvas of any (unrotated) size W x H.
+func boxTransform(geo pageGeometry, W, H float64) Matrix {
+	sx, sy := W/geo.box.Width(), H/geo.box.Height()
+	m := Matrix{sx, 0, 0, -sy, -geo.box.LL.X * sx, H + geo.box.LL.Y*sy}
+	switch geo.rotate {
+	case 90:
+		m = m.Multiply(Matrix{0, 1, -1, 0, H, 0})
+	case 180:
+		m = m.Multiply(Matrix{-1, 0, 0, -1, W, H})
+	case 270:
+		m = m.Multiply(Matrix{0, -1, 1, 0, 0, W})
+	}
+	return m
+}
+
+// NativeRenderer is a pure-Go page renderer. It composites image XObjects
+// (including inline images, stencil masks and images inside form XObjects)
+// at their CTM placement and fills and strokes vector paths, honoring clip
+// paths, the crop box and /Rotate. Text drawn with fonts is not rasterized;
+// use a CommandRenderer when such text must be OCRed as well.
+type NativeRenderer struct{}
+
+// RenderPages implements PageRenderer.
+func (NativeRenderer) RenderPages(filename string, pages []int, dpi int) (map[int]*RenderedPage, error) {
+	ctx, err := api.ReadContextFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	if len(pages) == 0 {
+		for i := 1; i <= ctx.PageCount; i++ {
+			pages = append(pages, i)
+		}
+	}
+	out := make(map[int]*RenderedPage, len(pages))
+	r := newPageRasterizer(ctx)
+	for _, n := range pages {
+		rp, err := r.renderPage(n, float64(dpi))
+		if err != nil {
+			return nil, err
+		}
+		out[n] = rp
+	}
+	return out, nil
+}
+
+// CommandRenderer delegates rasterization to an external program that writes
+// one PNG per invocation. Args may contain the placeholders {input}, {page},
+// {dpi} and {output}, for example:
+//
+//	CommandRenderer{Command: "mutool", Args: []string{"draw", "-q", "-r", "{dpi}", "-o", "{output}", "{input}", "{page}"}}
+type CommandRenderer struct {
+	Command string
+	Args    []string
+}
+
+// RenderPages implements PageRenderer.
+func (c CommandRenderer) RenderPages(filename string, pages []int, dpi int) (map[int]*RenderedPage, error) {
+	if c.Command == "" {
+		return nil, errors.New("render command not configured")
+	}
+	ctx, err := api.ReadContextFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	return c.renderContext(ctx, filename, pages, dpi)
+}
+
+// renderContext is RenderPages for a PDF that has already been read into
+// ctx; only the page geometry is taken from it.
+func (c CommandRenderer) renderContext(ctx *model.Context, filename string, pages []int, dpi int) (map[int]*RenderedPage, error) {
+	if c.Command == "" {
+		return nil, errors.New("render command not configured")
+	}
+	if len(pages) == 0 {
+		for i := 1; i <= ctx.PageCount; i++ {
+			pages = append(pages, i)
+		}
+	}
+
+	tempDir, err := os.MkdirTemp("", "pdf-render-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	out := make(map[int]*RenderedPage, len(pages))
+	for _, n := range pages {
+		_, _, geo, err := readPageGeometry(ctx, n)
+		if err != nil {
+			return nil, err
+		}
+		output := filepath.Join(tempDir, fmt.Sprintf("page-%d.png", n))
+		args := make([]string, len(c.Args))
+		for i, a := range c.Args {
+			args[i] = strings.NewReplacer(
+				"{input}", filename,
+				"{page}", strconv.Itoa(n),
+				"{dpi}", strconv.Itoa(dpi),
+				"{output}", output,
+			).Replace(a)
+		}
+		// #nosec G204 -- the command is operator configuration, not request input.
+		if msg, err := exec.Command(c.Command, args...).CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("render page %d with %s: %w: %s", n, c.Command, err, strings.TrimSpace(string(msg)))
+		}
+		img, err := loadImageFile(output)
+		if err != nil {
+			return nil, fmt.Errorf("render page %d: %w", n, err)
+		}
+
+		// Derive the transform from the pixel size the tool actually produced.
+		b := img.Bounds()
+		pw, ph := b.Dx(), b.Dy()
+		if geo.rotate == 90 || geo.rotate == 270 {
+			pw, ph = ph, pw
+		}
+		out[n] = &RenderedPage{
+			PageNumber: n,
+			Image:      img,
+			DPI:        float64(pw) * 72 / geo.box.Width(),
+			Box:        geo.box,
+			Rotate:     geo.rotate,
+			Transform:  pageTransform(geo, pw, ph),
+		}
+	}
+	return out, nil
+}
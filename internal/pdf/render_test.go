@@ -0,0 +1,243 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildTestPDF writes a single-page PDF. pageAttrs is inserted into the page
+// dictionary, resources is the /Resources dictionary and extra holds further
+// objects numbered from 5 on.
+func buildTestPDF(t *testing.T, pageAttrs, resources, content string, extra ...string) string {
+	t.Helper()
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R %s /Resources %s /Contents 4 0 R >>", pageAttrs, resources),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+	}
+	objects = append(objects, extra...)
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	path := filepath.Join(t.TempDir(), "test.pdf")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+	return path
+}
+
+func renderTestPage(t *testing.T, path string, dpi int) *RenderedPage {
+	t.Helper()
+	pages, err := RenderPages(path, "", RenderOptions{DPI: dpi})
+	require.NoError(t, err)
+	require.Contains(t, pages, 1)
+	return pages[1]
+}
+
+func gray(img image.Image, x, y int) uint8 {
+	return color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y
+}
+
+func TestRenderPages_FillPath(t *testing.T) {
+	path := buildTestPDF(t, "/MediaBox [0 0 200 100]", "<< >>", "0 g 10 10 50 30 re f")
+	page := renderTestPage(t, path, 72)
+
+	assert.Equal(t, image.Rect(0, 0, 200, 100), page.Image.Bounds())
+	assert.InDelta(t, 72.0, page.DPI, 1e-9)
+	assert.Equal(t, uint8(0), gray(page.Image, 35, 75)) // user (35,25)
+	assert.Equal(t, uint8(255), gray(page.Image, 150, 50))
+	assert.Equal(t, uint8(255), gray(page.Image, 35, 20))
+}
+
+func TestRenderPages_ImagePlacement(t *testing.T) {
+	// 2x1 DeviceGray image: black then white, scaled to 100x50pt at (50,25).
+	img := "<< /Type /XObject /Subtype /Image /Width 2 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8 /Length 2 >>\nstream\n\x00\xff\nendstream"
+	path := buildTestPDF(t, "/MediaBox [0 0 200 100]", "<< /XObject << /Im1 5 0 R >> >>",
+		"q 100 0 0 50 50 25 cm /Im1 Do Q", img)
+	page := renderTestPage(t, path, 144)
+
+	assert.Equal(t, image.Rect(0, 0, 400, 200), page.Image.Bounds())
+	assert.Less(t, gray(page.Image, 120, 100), uint8(32))     // left half of the image
+	assert.Greater(t, gray(page.Image, 260, 100), uint8(223)) // right half
+	assert.Equal(t, uint8(255), gray(page.Image, 120, 30))    // above the image
+	assert.Equal(t, uint8(255), gray(page.Image, 60, 100))    // left of the image
+	assert.Empty(t, page.Warnings)
+
+	x, y := page.Transform.Apply(50, 75)
+	assert.InDelta(t, 100.0, x, 1e-9)
+	assert.InDelta(t, 50.0, y, 1e-9)
+}
+
+func TestRenderPages_Rotate(t *testing.T) {
+	tests := []struct {
+		rotate int
+		w, h   int
+		x, y   int // device pixel inside the filled bottom-left corner of the page
+	}{
+		{0, 200, 100, 5, 95},
+		{90, 100, 200, 5, 5},
+		{180, 200, 100, 195, 5},
+		{270, 100, 200, 95, 195},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.rotate), func(t *testing.T) {
+			path := buildTestPDF(t, fmt.Sprintf("/MediaBox [0 0 200 100] /Rotate %d", tt.rotate), "<< >>", "0 0 20 20 re f")
+			page := renderTestPage(t, path, 72)
+			assert.Equal(t, tt.rotate, page.Rotate)
+			assert.Equal(t, image.Rect(0, 0, tt.w, tt.h), page.Image.Bounds())
+			assert.Equal(t, uint8(0), gray(page.Image, tt.x, tt.y))
+
+			x, y := page.Transform.Apply(10, 10)
+			assert.InDelta(t, float64(tt.x), x, 5.1)
+			assert.InDelta(t, float64(tt.y), y, 5.1)
+		})
+	}
+}
+
+func TestRenderPages_CropBox(t *testing.T) {
+	path := buildTestPDF(t, "/MediaBox [0 0 200 100] /CropBox [50 0 150 100]", "<< >>", "60 40 10 20 re f")
+	page := renderTestPage(t, path, 72)
+
+	assert.Equal(t, image.Rect(0, 0, 100, 100), page.Image.Bounds())
+	assert.Equal(t, 50.0, page.Box.LL.X)
+	assert.Equal(t, uint8(0), gray(page.Image, 15, 50))
+	assert.Equal(t, uint8(255), gray(page.Image, 5, 50))
+}
+
+func TestRenderPages_ClipAndForm(t *testing.T) {
+	formContent := "0 g 0 0 50 50 re f"
+	form := fmt.Sprintf("<< /Type /XObject /Subtype /Form /BBox [0 0 10 10] /Matrix [2 0 0 2 0 0] /Length %d >>\nstream\n%s\nendstream", len(formContent), formContent)
+	path := buildTestPDF(t, "/MediaBox [0 0 200 100]", "<< /XObject << /Fm1 5 0 R >> >>",
+		"q 0 0 100 100 re W n 1 0 0 rg 0 0 200 100 re f Q q 1 0 0 1 120 10 cm /Fm1 Do Q", form)
+	page := renderTestPage(t, path, 72)
+
+	r, g, b, _ := page.Image.At(50, 50).RGBA()
+	assert.Equal(t, []uint32{0xffff, 0, 0}, []uint32{r, g, b}) // red inside the clip
+	assert.Equal(t, uint8(255), gray(page.Image, 110, 50))     // clipped away
+	// The form fills 50x50 but its 10x10 bbox, scaled by 2, clips it to 20x20pt.
+	assert.Equal(t, uint8(0), gray(page.Image, 130, 80))
+	assert.Equal(t, uint8(255), gray(page.Image, 145, 80))
+}
+
+func TestRenderPages_InlineStencilMask(t *testing.T) {
+	// 8x1 stencil: the first four samples are 0 and therefore painted.
+	content := "0 0 1 rg q 80 0 0 10 0 0 cm BI /W 8 /H 1 /IM true /BPC 1 ID \x0f EI Q"
+	path := buildTestPDF(t, "/MediaBox [0 0 100 10]", "<< >>", content)
+	page := renderTestPage(t, path, 72)
+
+	_, _, b, _ := page.Image.At(15, 5).RGBA()
+	r, _, _, _ := page.Image.At(15, 5).RGBA()
+	assert.Equal(t, uint32(0xffff), b)
+	assert.Equal(t, uint32(0), r)
+	assert.Equal(t, uint8(255), gray(page.Image, 65, 5))
+	assert.Equal(t, uint8(255), gray(page.Image, 90, 5))
+}
+
+func TestRenderPages_UnsupportedImageWarns(t *testing.T) {
+	img := "<< /Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 1 /Filter /JBIG2Decode /Length 1 >>\nstream\n\x00\nendstream"
+	path := buildTestPDF(t, "/MediaBox [0 0 10 10]", "<< /XObject << /Im1 5 0 R >> >>", "10 0 0 10 0 0 cm /Im1 Do", img)
+	page := renderTestPage(t, path, 72)
+	require.Len(t, page.Warnings, 1)
+	assert.Contains(t, page.Warnings[0], "Im1")
+}
+
+func TestRenderPages_PageRange(t *testing.T) {
+	path := buildTestPDF(t, "/MediaBox [0 0 10 10]", "<< >>", "")
+	pages, err := RenderPages(path, "2-3", RenderOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, pages)
+
+	_, err = RenderPages(path, "x", RenderOptions{})
+	require.Error(t, err)
+
+	pages, err = RenderPages(path, "1", RenderOptions{})
+	require.NoError(t, err)
+	require.Contains(t, pages, 1)
+	assert.InDelta(t, float64(DefaultRenderDPI), pages[1].DPI, 1e-9)
+}
+
+func TestPagePixelSize_LimitsLargePages(t *testing.T) {
+	geo := pageGeometry{box: *types.NewRectangle(0, 0, 14400, 14400)} // 200 x 200 inches
+	w, h, dpi := pagePixelSize(geo, 300)
+	assert.LessOrEqual(t, w*h, maxRenderPixels+2*w)
+	assert.Less(t, dpi, 300.0)
+}
+
+func TestCommandRenderer_Errors(t *testing.T) {
+	path := buildTestPDF(t, "/MediaBox [0 0 10 10]", "<< >>", "")
+	_, err := CommandRenderer{}.RenderPages(path, nil, 72)
+	require.Error(t, err)
+
+	_, err = CommandRenderer{Command: "false"}.RenderPages(path, []int{1}, 72)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "render page 1")
+}
+
+func TestMatrix(t *testing.T) {
+	m := Matrix{2, 0, 0, 3, 10, 20}.Multiply(Matrix{0, 1, -1, 0, 5, 0})
+	x, y := m.Apply(1, 1)
+	assert.InDelta(t, -18.0, x, 1e-9)
+	assert.InDelta(t, 12.0, y, 1e-9)
+
+	inv, ok := m.Invert()
+	require.True(t, ok)
+	x, y = inv.Apply(x, y)
+	assert.InDelta(t, 1.0, x, 1e-9)
+	assert.InDelta(t, 1.0, y, 1e-9)
+
+	_, ok = Matrix{}.Invert()
+	assert.False(t, ok)
+}
+
+func TestContentLexer(t *testing.T) {
+	lex := newContentLexer([]byte("1 -2.5 /Name (a\\(b\\)c) <4142> [1 /X] << /K 2 >> % comment\nTj"))
+	var kinds []contentTokenKind
+	var toks []contentToken
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			break
+		}
+		kinds = append(kinds, tok.kind)
+		toks = append(toks, tok)
+	}
+	assert.Equal(t, []contentTokenKind{tokNumber, tokNumber, tokName, tokString, tokString, tokArray, tokDict, tokOperator}, kinds)
+	assert.InDelta(t, -2.5, toks[1].num, 1e-9)
+	assert.Equal(t, "Name", toks[2].str)
+	assert.Equal(t, "a(b)c", toks[3].str)
+	assert.Equal(t, "AB", toks[4].str)
+	assert.Len(t, toks[5].array, 2)
+	assert.InDelta(t, 2.0, toks[6].dict["K"].num, 1e-9)
+	assert.Equal(t, "Tj", toks[7].str)
+}
+
+func TestContentLexer_InlineImage(t *testing.T) {
+	lex := newContentLexer([]byte("/W 2 /H 1 /CS /G ID \x00EI\x01 EI Q"))
+	params, data := lex.inlineImage()
+	assert.InDelta(t, 2.0, params["W"].num, 1e-9)
+	assert.Equal(t, "G", params["CS"].str)
+	assert.Equal(t, []byte("\x00EI\x01"), data)
+	tok, ok := lex.next()
+	require.True(t, ok)
+	assert.Equal(t, "Q", tok.str)
+}
@@ -10,11 +10,17 @@ type PageResult struct {
 	PageNumber       int                `json:"page_number"`
 	Width            int                `json:"width"`
 	Height           int                `json:"height"`
+	WidthPt          float64            `json:"width_pt,omitempty"` // Displayed page size in points
+	HeightPt         float64            `json:"height_pt,omitempty"`
+	Rotate           int                `json:"rotate,omitempty"`
 	Images           []ImageResult      `json:"images"`
 	Processing       ProcessingInfo     `json:"processing"`
 	Strategy         ProcessingStrategy `json:"strategy,omitempty"`          // Processing strategy used
 	VectorExtraction *TextExtraction    `json:"vector_extraction,omitempty"` // Vector text extraction results
 	HybridResult     *HybridResult      `json:"hybrid_result,omitempty"`     // Hybrid processing results
+	// Warnings lists page content that could not be decoded or rendered,
+	// e.g. images in unsupported encodings, and so was not OCRed.
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 // ImageResult represents OCR results for a single image extracted from a PDF page.
@@ -24,6 +30,10 @@ type ImageResult struct {
     Height     int                       `json:"height"`
     Regions    []detector.DetectedRegion `json:"regions"`
     Confidence float64                   `json:"confidence"`
+    // Placement maps image pixels to PDF user space (points, origin bottom-left).
+    Placement *Matrix `json:"placement,omitempty"`
+    // PageRegions locates each entry of Regions on the page (same order).
+    PageRegions []PageCoords `json:"page_regions,omitempty"`
     // Enriched OCR output (optional; present when processed via full pipeline)
     OCRRegions []OCRRegion `json:"ocr_regions,omitempty"`
     Text       string      `json:"text,omitempty"`
@@ -36,6 +46,9 @@ type DocumentResult struct {
 	TotalPages int            `json:"total_pages"`
 	Pages      []PageResult   `json:"pages"`
 	Processing ProcessingInfo `json:"processing"`
+	// Truncated marks a partial result: processing was canceled or hit its
+	// deadline, and only the pages finished before that are included.
+	Truncated bool `json:"truncated,omitempty"`
 }
 
 // ProcessingInfo contains timing and performance information.
@@ -54,6 +67,16 @@ type OCRRegion struct {
 	Text          string                   `json:"text"`
 	RecConfidence float64                  `json:"rec_confidence"`
 	Language      string                   `json:"language,omitempty"`
+	Page          *PageCoords              `json:"page,omitempty"`
+	Words         []OCRWord                `json:"words,omitempty"`
+}
+
+// OCRWord is a word of an OCRRegion; Box is in image pixels.
+type OCRWord struct {
+	Text       string                       `json:"text"`
+	Confidence float64                      `json:"confidence"`
+	Box        struct{ X, Y, W, H float64 } `json:"box"`
+	Page       *PageCoords                  `json:"page,omitempty"`
 }
 
 // Barcode mirrors pipeline barcode output in PDF results.
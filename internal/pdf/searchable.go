@@ -0,0 +1,480 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+const (
+	// DefaultSearchableDPI is the resolution assumed for image inputs when sizing pages.
+	DefaultSearchableDPI = 300
+
+	// textLayerFontName is the resource name of the invisible text layer font.
+	textLayerFontName = "PogoOCR"
+
+	// textLayerGlyphWidth is the default glyph width (in 1/1000 em) of the text layer font.
+	textLayerGlyphWidth = 500
+)
+
+// TextLayerWord is a piece of recognized text positioned in the coordinate
+// space of its TextLayerPage (origin top-left, y down).
+type TextLayerWord struct {
+	Text string
+	X    float64
+	Y    float64
+	W    float64
+	H    float64
+}
+
+// TextLayerPage holds the recognized text for a single output page.
+// Width and Height describe the space the word boxes refer to, such as image
+// pixels or page points; that space is stretched over the page as displayed
+// (visible box with /Rotate applied) when the text layer is written.
+type TextLayerPage struct {
+	PageNumber int
+	Width      float64
+	Height     float64
+	Words      []TextLayerWord
+}
+
+// SearchableOptions controls how searchable PDFs are written.
+type SearchableOptions struct {
+	// PDFA adds the XMP metadata and sRGB output intent required by PDF/A-2b.
+	PDFA bool
+	// DPI is the resolution used to size pages created from images (0 = DefaultSearchableDPI).
+	DPI int
+	// Title is written into the document metadata when set.
+	Title string
+}
+
+// WriteSearchablePDF copies the PDF at inputPath to w and overlays an invisible
+// text layer on every page listed in pages.
+func WriteSearchablePDF(w io.Writer, inputPath string, pages []TextLayerPage, opts SearchableOptions) error {
+	ctx, err := api.ReadContextFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read PDF %s: %w", inputPath, err)
+	}
+	if err := addTextLayers(ctx, pages); err != nil {
+		return err
+	}
+	return writeSearchableContext(ctx, w, opts)
+}
+
+// WriteSearchablePDFFromImages writes a new PDF to w with one page per image and
+// an invisible text layer taken from the page with the matching number (1-based).
+func WriteSearchablePDFFromImages(w io.Writer, images []image.Image, pages []TextLayerPage, opts SearchableOptions) error {
+	if len(images) == 0 {
+		return errors.New("no images provided")
+	}
+	dpi := opts.DPI
+	if dpi <= 0 {
+		dpi = DefaultSearchableDPI
+	}
+
+	ctx, err := pdfcpu.CreateContextWithXRefTable(nil, types.PaperSize["A4"])
+	if err != nil {
+		return fmt.Errorf("failed to create PDF: %w", err)
+	}
+	pagesIndRef, err := ctx.Pages()
+	if err != nil {
+		return fmt.Errorf("failed to create PDF: %w", err)
+	}
+	pagesDict, err := ctx.DereferenceDict(*pagesIndRef)
+	if err != nil {
+		return fmt.Errorf("failed to create PDF: %w", err)
+	}
+
+	for i, img := range images {
+		indRef, err := newImagePage(ctx.XRefTable, pagesIndRef, img, dpi)
+		if err != nil {
+			return fmt.Errorf("failed to add image %d: %w", i+1, err)
+		}
+		if err := model.AppendPageTree(indRef, 1, pagesDict); err != nil {
+			return fmt.Errorf("failed to add image %d: %w", i+1, err)
+		}
+		ctx.PageCount++
+	}
+
+	if err := addTextLayers(ctx, pages); err != nil {
+		return err
+	}
+	return writeSearchableContext(ctx, w, opts)
+}
+
+// newImagePage creates a page that shows img scaled to its physical size at dpi.
+func newImagePage(xRefTable *model.XRefTable, parent *types.IndirectRef, img image.Image, dpi int) (*types.IndirectRef, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	imgRes, err := model.CreateImageResources(xRefTable, &buf, false, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(imgRes) == 0 {
+		return nil, errors.New("image could not be embedded")
+	}
+	res := imgRes[0]
+
+	resIndRef, err := xRefTable.IndRefForNewObject(types.Dict(map[string]types.Object{
+		"ProcSet": types.NewNameArray("PDF", "Text", "ImageB", "ImageC", "ImageI"),
+		"XObject": types.Dict(map[string]types.Object{res.Res.ID: *res.Res.IndRef}),
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	w := float64(res.Width) * 72 / float64(dpi)
+	h := float64(res.Height) * 72 / float64(dpi)
+	content := fmt.Sprintf("q %.4f 0 0 %.4f 0 0 cm /%s Do Q", w, h, res.Res.ID)
+	contentsIndRef, err := xRefTable.StreamDictIndRef([]byte(content))
+	if err != nil {
+		return nil, err
+	}
+
+	return xRefTable.IndRefForNewObject(types.Dict(map[string]types.Object{
+		"Type":      types.Name("Page"),
+		"Parent":    *parent,
+		"MediaBox":  types.RectForDim(w, h).Array(),
+		"Resources": *resIndRef,
+		"Contents":  *contentsIndRef,
+	}))
+}
+
+// addTextLayers overlays the words of every page onto the corresponding PDF page.
+func addTextLayers(ctx *model.Context, pages []TextLayerPage) error {
+	var fontIndRef *types.IndirectRef
+	for _, page := range pages {
+		if len(page.Words) == 0 || page.Width <= 0 || page.Height <= 0 {
+			continue
+		}
+		if page.PageNumber < 1 || page.PageNumber > ctx.PageCount {
+			return fmt.Errorf("text layer page %d out of range (document has %d pages)", page.PageNumber, ctx.PageCount)
+		}
+		if fontIndRef == nil {
+			var err error
+			if fontIndRef, err = newTextLayerFont(ctx.XRefTable); err != nil {
+				return fmt.Errorf("failed to create text layer font: %w", err)
+			}
+		}
+		if err := addTextLayer(ctx, page, fontIndRef); err != nil {
+			return fmt.Errorf("failed to add text layer to page %d: %w", page.PageNumber, err)
+		}
+	}
+	return nil
+}
+
+// addTextLayer appends the invisible text for one page. The existing content is
+// wrapped in q/Q so that the text layer is drawn in default user space.
+func addTextLayer(ctx *model.Context, page TextLayerPage, fontIndRef *types.IndirectRef) error {
+	xRefTable := ctx.XRefTable
+	pageDict, resources, geo, err := readPageGeometry(ctx, page.PageNumber)
+	if err != nil {
+		return err
+	}
+
+	if err := addFontResource(xRefTable, pageDict, resources, fontIndRef); err != nil {
+		return err
+	}
+
+	text := textLayerContent(page, geo)
+
+	// Prefer a single merged content stream; several simple readers do not
+	// handle content arrays. Fall back to an array if the content can't be decoded.
+	original, err := xRefTable.PageContent(pageDict, page.PageNumber)
+	if err == nil || errors.Is(err, model.ErrNoContent) {
+		content := make([]byte, 0, len(original)+len(text)+4)
+		content = append(content, "q\n"...)
+		content = append(content, original...)
+		content = append(content, '\n')
+		content = append(content, text...)
+		merged, err := xRefTable.StreamDictIndRef(content)
+		if err != nil {
+			return err
+		}
+		pageDict["Contents"] = *merged
+		return nil
+	}
+
+	prefix, err := xRefTable.StreamDictIndRef([]byte("q\n"))
+	if err != nil {
+		return err
+	}
+	textRef, err := xRefTable.StreamDictIndRef(text)
+	if err != nil {
+		return err
+	}
+	contents := types.Array{*prefix}
+	obj, err := xRefTable.Dereference(pageDict["Contents"])
+	if err != nil {
+		return err
+	}
+	if arr, ok := obj.(types.Array); ok {
+		contents = append(contents, arr...)
+	} else {
+		contents = append(contents, pageDict["Contents"])
+	}
+	pageDict["Contents"] = append(contents, *textRef)
+	return nil
+}
+
+// addFontResource registers the text layer font in the page's own resources,
+// materializing inherited resources so that other pages are not affected.
+func addFontResource(xRefTable *model.XRefTable, pageDict, inherited types.Dict, fontIndRef *types.IndirectRef) error {
+	res := inherited
+	if obj, found := pageDict.Find("Resources"); found {
+		d, err := xRefTable.DereferenceDict(obj)
+		if err != nil {
+			return err
+		}
+		res = d
+	}
+	resources := types.NewDict()
+	if res != nil {
+		resources = res.Clone().(types.Dict)
+	}
+
+	fonts := types.NewDict()
+	if obj, found := resources.Find("Font"); found {
+		d, err := xRefTable.DereferenceDict(obj)
+		if err != nil {
+			return err
+		}
+		if d != nil {
+			fonts = d.Clone().(types.Dict)
+		}
+	}
+	fonts[textLayerFontName] = *fontIndRef
+	resources["Font"] = fonts
+	pageDict["Resources"] = resources
+	return nil
+}
+
+// textLayerContent renders the content stream drawing the page's words with
+// text render mode 3 (invisible), each stretched horizontally to its box.
+// Words are laid out upright on the page as displayed and mapped back into
+// user space, so they follow the page rotation.
+func textLayerContent(page TextLayerPage, geo pageGeometry) []byte {
+	displayW, displayH := geo.box.Width(), geo.box.Height()
+	if geo.rotate == 90 || geo.rotate == 270 {
+		displayW, displayH = displayH, displayW
+	}
+	sx := displayW / page.Width
+	sy := displayH / page.Height
+	toUser, _ := boxTransform(geo, geo.box.Width(), geo.box.Height()).Invert()
+
+	var b strings.Builder
+	// Close the q opened before the original content to get back to default user space.
+	b.WriteString("Q\nBT\n3 Tr\n")
+	for _, word := range page.Words {
+		text := strings.TrimSpace(word.Text)
+		if text == "" || word.W <= 0 || word.H <= 0 {
+			continue
+		}
+		size := word.H * sy
+		width := word.W * sx
+		// Baseline at the bottom left of the box, text space y pointing up.
+		tm := Matrix{1, 0, 0, -1, word.X * sx, (word.Y + word.H) * sy}.Multiply(toUser)
+		natural := float64(utf8.RuneCountInString(text)) * size * textLayerGlyphWidth / 1000
+		scale := 100.0
+		if natural > 0 {
+			scale = 100 * width / natural
+		}
+		fmt.Fprintf(&b, "/%s %.2f Tf\n%.2f Tz\n%s %s %s %s %.2f %.2f Tm\n<%s> Tj\n",
+			textLayerFontName, size, scale, textMatrixNumber(tm[0]), textMatrixNumber(tm[1]),
+			textMatrixNumber(tm[2]), textMatrixNumber(tm[3]), tm[4], tm[5], encodeTextLayerString(text))
+	}
+	b.WriteString("ET\n")
+	return []byte(b.String())
+}
+
+// textMatrixNumber formats a rotation component of a text matrix, which is
+// -1, 0 or 1 for the page rotations PDF allows.
+func textMatrixNumber(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e4)/1e4+0, 'f', -1, 64)
+}
+
+// encodeTextLayerString encodes text as big-endian 2-byte CIDs (CID = BMP code point).
+func encodeTextLayerString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r > 0xFFFF {
+			r = utf8.RuneError
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
+
+// newTextLayerFont creates a glyph-less Type0 font whose CIDs are Unicode BMP
+// code points. The font program is not embedded, which PDF/A permits for text
+// that is never rendered.
+func newTextLayerFont(xRefTable *model.XRefTable) (*types.IndirectRef, error) {
+	toUnicode, err := xRefTable.StreamDictIndRef(identityToUnicodeCMap())
+	if err != nil {
+		return nil, err
+	}
+
+	descriptor, err := xRefTable.IndRefForNewObject(types.Dict(map[string]types.Object{
+		"Type":        types.Name("FontDescriptor"),
+		"FontName":    types.Name("GlyphLessFont"),
+		"Flags":       types.Integer(5),
+		"FontBBox":    types.NewIntegerArray(0, 0, textLayerGlyphWidth, 1000),
+		"ItalicAngle": types.Integer(0),
+		"Ascent":      types.Integer(1000),
+		"Descent":     types.Integer(0),
+		"CapHeight":   types.Integer(1000),
+		"StemV":       types.Integer(80),
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	cidFont, err := xRefTable.IndRefForNewObject(types.Dict(map[string]types.Object{
+		"Type":     types.Name("Font"),
+		"Subtype":  types.Name("CIDFontType2"),
+		"BaseFont": types.Name("GlyphLessFont"),
+		"CIDSystemInfo": types.Dict(map[string]types.Object{
+			"Registry":   types.StringLiteral("Adobe"),
+			"Ordering":   types.StringLiteral("Identity"),
+			"Supplement": types.Integer(0),
+		}),
+		"FontDescriptor": *descriptor,
+		"DW":             types.Integer(textLayerGlyphWidth),
+		"CIDToGIDMap":    types.Name("Identity"),
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	return xRefTable.IndRefForNewObject(types.Dict(map[string]types.Object{
+		"Type":            types.Name("Font"),
+		"Subtype":         types.Name("Type0"),
+		"BaseFont":        types.Name("GlyphLessFont"),
+		"Encoding":        types.Name("Identity-H"),
+		"DescendantFonts": types.Array{*cidFont},
+		"ToUnicode":       *toUnicode,
+	}))
+}
+
+// identityToUnicodeCMap maps every 2-byte code to the Unicode code point of the same value.
+func identityToUnicodeCMap() []byte {
+	var b strings.Builder
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	b.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	b.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	b.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	// bfrange entries may not cross a high byte boundary and a block holds at most 100 entries.
+	for start := 0; start < 256; start += 100 {
+		end := min(start+100, 256)
+		fmt.Fprintf(&b, "%d beginbfrange\n", end-start)
+		for hi := start; hi < end; hi++ {
+			fmt.Fprintf(&b, "<%02X00> <%02XFF> <%02X00>\n", hi, hi, hi)
+		}
+		b.WriteString("endbfrange\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return []byte(b.String())
+}
+
+// writeSearchableContext applies the PDF/A additions if requested and writes ctx to w.
+func writeSearchableContext(ctx *model.Context, w io.Writer, opts SearchableOptions) error {
+	if opts.PDFA {
+		if err := addPDFAMetadata(ctx.XRefTable, opts.Title); err != nil {
+			return fmt.Errorf("failed to add PDF/A metadata: %w", err)
+		}
+	}
+	if err := api.WriteContext(ctx, w); err != nil {
+		return fmt.Errorf("failed to write searchable PDF: %w", err)
+	}
+	return nil
+}
+
+// addPDFAMetadata adds the XMP packet declaring PDF/A-2b conformance and an sRGB output intent.
+func addPDFAMetadata(xRefTable *model.XRefTable, title string) error {
+	root, err := xRefTable.Catalog()
+	if err != nil {
+		return err
+	}
+
+	// Metadata streams must not be compressed in PDF/A.
+	sd := types.NewStreamDict(types.Dict(map[string]types.Object{
+		"Type":    types.Name("Metadata"),
+		"Subtype": types.Name("XML"),
+	}), 0, nil, nil, nil)
+	sd.Content = xmpMetadata(title, time.Now())
+	if err := sd.Encode(); err != nil {
+		return err
+	}
+	metadata, err := xRefTable.IndRefForNewObject(sd)
+	if err != nil {
+		return err
+	}
+	root["Metadata"] = *metadata
+
+	icc, err := xRefTable.NewStreamDictForBuf(srgbProfile())
+	if err != nil {
+		return err
+	}
+	icc.Insert("N", types.Integer(3))
+	if err := icc.Encode(); err != nil {
+		return err
+	}
+	profile, err := xRefTable.IndRefForNewObject(*icc)
+	if err != nil {
+		return err
+	}
+	root["OutputIntents"] = types.Array{types.Dict(map[string]types.Object{
+		"Type":                      types.Name("OutputIntent"),
+		"S":                         types.Name("GTS_PDFA1"),
+		"OutputConditionIdentifier": types.StringLiteral("sRGB IEC61966-2.1"),
+		"Info":                      types.StringLiteral("sRGB IEC61966-2.1"),
+		"DestOutputProfile":         *profile,
+	})}
+	return nil
+}
+
+// xmpMetadata returns an XMP packet declaring PDF/A-2b conformance.
+func xmpMetadata(title string, now time.Time) []byte {
+	ts := now.UTC().Format(time.RFC3339)
+	var titleXML string
+	if title != "" {
+		var esc bytes.Buffer
+		_ = xml.EscapeText(&esc, []byte(title))
+		titleXML = `<dc:title><rdf:Alt><rdf:li xml:lang="x-default">` + esc.String() + `</rdf:li></rdf:Alt></dc:title>`
+	}
+	return []byte(`<?xpacket begin="` + "\ufeff" + `" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description rdf:about=""
+ xmlns:pdfaid="http://www.aiim.org/pdfa/ns/id/"
+ xmlns:dc="http://purl.org/dc/elements/1.1/"
+ xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+ xmlns:pdf="http://ns.adobe.com/pdf/1.3/">
+<pdfaid:part>2</pdfaid:part>
+<pdfaid:conformance>B</pdfaid:conformance>
+<xmp:CreatorTool>pogo</xmp:CreatorTool>
+<xmp:CreateDate>` + ts + `</xmp:CreateDate>
+<xmp:ModifyDate>` + ts + `</xmp:ModifyDate>
+<pdf:Producer>pogo</pdf:Producer>
+` + titleXML + `
+</rdf:Description>
+</rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`)
+}
@@ -0,0 +1,184 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func searchableTestImage(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.White)
+		}
+	}
+	return img
+}
+
+func TestWriteSearchablePDFFromImages(t *testing.T) {
+	images := []image.Image{searchableTestImage(600, 300), searchableTestImage(300, 600)}
+	pages := []TextLayerPage{
+		{PageNumber: 1, Width: 600, Height: 300, Words: []TextLayerWord{
+			{Text: "Hello", X: 30, Y: 40, W: 200, H: 50},
+			{Text: "Grüße", X: 300, Y: 40, W: 200, H: 50},
+		}},
+		{PageNumber: 2, Width: 300, Height: 600, Words: []TextLayerWord{
+			{Text: "World", X: 10, Y: 500, W: 150, H: 40},
+		}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteSearchablePDFFromImages(&buf, images, pages, SearchableOptions{DPI: 150}))
+
+	out := filepath.Join(t.TempDir(), "searchable.pdf")
+	require.NoError(t, os.WriteFile(out, buf.Bytes(), 0o600))
+	require.NoError(t, api.ValidateFile(out, nil))
+
+	n, err := api.PageCountFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	dims, err := api.PageDimsFile(out)
+	require.NoError(t, err)
+	require.Len(t, dims, 2)
+	assert.InDelta(t, 288.0, dims[0].Width, 0.01) // 600px at 150 DPI
+	assert.InDelta(t, 144.0, dims[0].Height, 0.01)
+
+	extractions, err := NewVectorTextExtractor(0).ExtractText(out, "")
+	require.NoError(t, err)
+	require.Contains(t, extractions, 1)
+	require.Contains(t, extractions, 2)
+	assert.Contains(t, extractions[1].Text, "Hello")
+	assert.Contains(t, extractions[2].Text, "World")
+}
+
+func TestWriteSearchablePDF_OverlaysExistingDocument(t *testing.T) {
+	input := filepath.Join("..", "..", "testdata", "documents", "multipage.pdf")
+	if _, err := os.Stat(input); err != nil {
+		t.Skip("multipage.pdf test document not available")
+	}
+	pageCount, err := api.PageCountFile(input)
+	require.NoError(t, err)
+
+	pages := []TextLayerPage{{PageNumber: pageCount, Width: 100, Height: 100, Words: []TextLayerWord{
+		{Text: "Searchable", X: 10, Y: 10, W: 80, H: 10},
+	}}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteSearchablePDF(&buf, input, pages, SearchableOptions{PDFA: true, Title: "Test & Co"}))
+
+	out := filepath.Join(t.TempDir(), "overlay.pdf")
+	require.NoError(t, os.WriteFile(out, buf.Bytes(), 0o600))
+	require.NoError(t, api.ValidateFile(out, nil))
+
+	n, err := api.PageCountFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, pageCount, n)
+
+	extractions, err := NewVectorTextExtractor(0).ExtractText(out, "")
+	require.NoError(t, err)
+	require.Contains(t, extractions, pageCount)
+	assert.Contains(t, extractions[pageCount].Text, "Searchable")
+
+	ctx, err := api.ReadContextFile(out)
+	require.NoError(t, err)
+	root, err := ctx.Catalog()
+	require.NoError(t, err)
+	assert.NotNil(t, root["Metadata"])
+	assert.NotNil(t, root["OutputIntents"])
+}
+
+func TestWriteSearchablePDF_PageOutOfRange(t *testing.T) {
+	pages := []TextLayerPage{{PageNumber: 3, Width: 10, Height: 10, Words: []TextLayerWord{
+		{Text: "x", W: 1, H: 1},
+	}}}
+	var buf bytes.Buffer
+	err := WriteSearchablePDFFromImages(&buf, []image.Image{searchableTestImage(10, 10)}, pages, SearchableOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out of range")
+}
+
+func TestTextLayerContent(t *testing.T) {
+	page := TextLayerPage{Width: 200, Height: 100, Words: []TextLayerWord{
+		{Text: "AB", X: 20, Y: 10, W: 40, H: 20},
+		{Text: "   ", X: 0, Y: 0, W: 10, H: 10},
+	}}
+	content := string(textLayerContent(page, pageGeometry{box: *types.RectForDim(100, 50)}))
+
+	assert.Contains(t, content, "3 Tr")
+	// Box maps to x=10, bottom y=50-(10+20)/2=35, size 10, width 20 -> Tz 100*20/(2*10*0.5).
+	assert.Contains(t, content, "/PogoOCR 10.00 Tf")
+	assert.Contains(t, content, "200.00 Tz")
+	assert.Contains(t, content, "1 0 0 1 10.00 35.00 Tm")
+	assert.Contains(t, content, "<00410042> Tj")
+	assert.Equal(t, 1, strings.Count(content, "Tj"))
+}
+
+func TestTextLayerContent_RotatedPage(t *testing.T) {
+	// A 100x50 pt page shown rotated by 90 degrees is 50 pt wide and 100 pt high.
+	page := TextLayerPage{Width: 50, Height: 100, Words: []TextLayerWord{
+		{Text: "AB", X: 10, Y: 20, W: 20, H: 10},
+	}}
+	content := string(textLayerContent(page, pageGeometry{box: *types.RectForDim(100, 50), rotate: 90}))
+
+	// The displayed baseline start (10, 30) is user space (30, 10); the text
+	// runs up the unrotated page so that it reads left to right when shown.
+	assert.Contains(t, content, "/PogoOCR 10.00 Tf")
+	assert.Contains(t, content, "200.00 Tz")
+	assert.Contains(t, content, "0 1 -1 0 30.00 10.00 Tm")
+
+	content = string(textLayerContent(page, pageGeometry{box: *types.RectForDim(100, 50), rotate: 270}))
+	assert.Contains(t, content, "0 -1 1 0 70.00 40.00 Tm")
+}
+
+func TestWriteSearchablePDF_RotatedPage(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	require.NoError(t, WriteSearchablePDFFromImages(&buf, []image.Image{searchableTestImage(200, 100)}, nil, SearchableOptions{DPI: 144}))
+	plain := filepath.Join(dir, "plain.pdf")
+	require.NoError(t, os.WriteFile(plain, buf.Bytes(), 0o600))
+	rotated := filepath.Join(dir, "rotated.pdf")
+	require.NoError(t, api.RotateFile(plain, rotated, 90, nil, nil))
+
+	pages := []TextLayerPage{{PageNumber: 1, Width: 50, Height: 100, Words: []TextLayerWord{
+		{Text: "Sideways", X: 10, Y: 20, W: 30, H: 10},
+	}}}
+	buf.Reset()
+	require.NoError(t, WriteSearchablePDF(&buf, rotated, pages, SearchableOptions{}))
+
+	out := filepath.Join(dir, "searchable.pdf")
+	require.NoError(t, os.WriteFile(out, buf.Bytes(), 0o600))
+	ctx, err := api.ReadContextFile(out)
+	require.NoError(t, err)
+	d, _, _, err := ctx.PageDict(1, false)
+	require.NoError(t, err)
+	content, err := ctx.PageContent(d, 1)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "0 1 -1 0 30.00 10.00 Tm")
+}
+
+func TestEncodeTextLayerString(t *testing.T) {
+	assert.Equal(t, "00480069", encodeTextLayerString("Hi"))
+	assert.Equal(t, "00FC", encodeTextLayerString("ü"))
+	assert.Equal(t, "FFFD", encodeTextLayerString("😀"))
+}
+
+func TestSRGBProfile(t *testing.T) {
+	p := srgbProfile()
+	require.Greater(t, len(p), 128)
+	assert.Equal(t, uint32(len(p)), binary.BigEndian.Uint32(p[0:4]))
+	assert.Equal(t, "acsp", string(p[36:40]))
+	assert.Equal(t, "RGB ", string(p[16:20]))
+	assert.Equal(t, uint32(9), binary.BigEndian.Uint32(p[128:132]))
+}
@@ -0,0 +1,283 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans of this package.
+const tracerName = "github.com/MeKo-Tech/pogo/internal/pdf"
+
+// PageLoader produces the page sets of a PDF one page at a time, so that a
+// document never has to be held in memory as a whole. It is not safe for
+// concurrent use.
+type PageLoader struct {
+	filename string
+	mode     string
+	dpi      int
+	renderer PageRenderer
+	pages    []int
+	ctx      *model.Context
+	r        *pageRasterizer
+	loadTime time.Duration
+}
+
+// OpenPages prepares loading the pages selected by pageRange (empty = all)
+// in the given page mode. Pages are loaded in ascending order, each once.
+func OpenPages(filename, pageRange, mode string, opts RenderOptions) (*PageLoader, error) {
+	switch mode {
+	case "":
+		mode = PageModeRender
+	case PageModeRender, PageModeExtract:
+	default:
+		return nil, fmt.Errorf("invalid page mode %q (want %s or %s)", mode, PageModeRender, PageModeExtract)
+	}
+	pageNumbers, err := parsePageRange(pageRange)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page range %q: %w", pageRange, err)
+	}
+	ctx, err := api.ReadContextFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	validPages := validateAndFilterPages(pageNumbers, ctx.PageCount)
+	if len(pageNumbers) == 0 {
+		for i := 1; i <= ctx.PageCount; i++ {
+			validPages = append(validPages, i)
+		}
+	}
+	slices.Sort(validPages)
+
+	dpi := opts.DPI
+	if dpi <= 0 {
+		dpi = DefaultRenderDPI
+	}
+	l := &PageLoader{
+		filename: filename,
+		mode:     mode,
+		dpi:      dpi,
+		renderer: opts.Renderer,
+		pages:    slices.Compact(validPages),
+		ctx:      ctx,
+		r:        newPageRasterizer(ctx),
+	}
+	if _, native := l.renderer.(NativeRenderer); native {
+		l.renderer = nil
+	}
+	l.r.collect = mode == PageModeExtract
+	return l, nil
+}
+
+// Pages returns the page numbers the loader produces, in order.
+func (l *PageLoader) Pages() []int {
+	return l.pages
+}
+
+// LoadTime returns the total time spent in Load so far.
+func (l *PageLoader) LoadTime() time.Duration {
+	return l.loadTime
+}
+
+// Load renders page pageNr, or extracts its images in PageModeExtract.
+func (l *PageLoader) Load(pageNr int) (*PageSet, error) {
+	start := time.Now()
+	defer func() { l.loadTime += time.Since(start) }()
+
+	// Decoded images are only reused within a page, which keeps the cache
+	// from growing with the document.
+	clear(l.r.images)
+	clear(l.r.failed)
+
+	if l.mode == PageModeExtract {
+		return l.r.collectPage(pageNr)
+	}
+	var page *RenderedPage
+	if l.renderer == nil {
+		rp, err := l.r.renderPage(pageNr, float64(l.dpi))
+		if err != nil {
+			return nil, err
+		}
+		page = rp
+	} else {
+		var rendered map[int]*RenderedPage
+		var err error
+		if cr, ok := l.renderer.(contextRenderer); ok {
+			rendered, err = cr.renderContext(l.ctx, l.filename, []int{pageNr}, l.dpi)
+		} else {
+			rendered, err = l.renderer.RenderPages(l.filename, []int{pageNr}, l.dpi)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to render PDF pages: %w", err)
+		}
+		if page = rendered[pageNr]; page == nil {
+			return nil, fmt.Errorf("page %d: renderer returned no image", pageNr)
+		}
+	}
+	return renderedPageSet(page)
+}
+
+// loadTraced is Load in a span that is a child of the span in ctx.
+func (l *PageLoader) loadTraced(ctx context.Context, pageNr int) (*PageSet, error) {
+	_, span := otel.Tracer(tracerName).Start(ctx, "pdf.load_page", trace.WithAttributes(
+		attribute.Int("pdf.page", pageNr),
+		attribute.String("pdf.mode", l.mode),
+	))
+	defer span.End()
+	set, err := l.Load(pageNr)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("pdf.images", len(set.Images)))
+	return set, nil
+}
+
+// renderedPageSet wraps a rendered page; its placement is the inverse of
+// the render transform.
+func renderedPageSet(page *RenderedPage) (*PageSet, error) {
+	placement, ok := page.Transform.Invert()
+	if !ok {
+		return nil, fmt.Errorf("page %d: singular render transform", page.PageNumber)
+	}
+	return &PageSet{
+		PageNumber: page.PageNumber,
+		Box:        page.Box,
+		Rotate:     page.Rotate,
+		Images:     []PageImage{{Image: page.Image, Placement: placement}},
+		Warnings:   page.Warnings,
+	}, nil
+}
+
+// StreamOptions bounds the concurrency and memory of StreamPages.
+type StreamOptions struct {
+	// Workers is the number of pages processed concurrently (0 = NumCPU).
+	Workers int
+	// MaxInFlight caps the pages that are loaded but not yet emitted,
+	// including finished pages waiting for an earlier one (0 = Workers).
+	MaxInFlight int
+}
+
+// StreamPages loads the pages of l one at a time, runs process on up to
+// Workers of them concurrently and calls emit with each result in page
+// order as soon as it and all earlier pages are done. At most MaxInFlight
+// pages are held at any time. emit runs on the calling goroutine.
+//
+// The first error, from loading, processing or emitting a page, or the
+// cancellation of ctx, stops the stream and is returned; pages emitted
+// before it stay emitted. StreamPages returns once all workers have exited.
+func StreamPages[R any](ctx context.Context, l *PageLoader, opts StreamOptions,
+	process func(ctx context.Context, set *PageSet) (R, error),
+	emit func(pageNum int, res R) error,
+) error {
+	pages := l.Pages()
+	if len(pages) == 0 {
+		return ctx.Err()
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = max(1, min(workers, len(pages)))
+	inFlight := opts.MaxInFlight
+	if inFlight <= 0 {
+		inFlight = workers
+	}
+	workers = min(workers, inFlight)
+
+	type job struct {
+		idx int
+		set *PageSet
+	}
+	type result struct {
+		idx int
+		res R
+		err error
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	// Every loaded page holds a slot until it is emitted, so results never
+	// exceeds inFlight entries and sends to it do not block.
+	slots := make(chan struct{}, inFlight)
+	jobs := make(chan job)
+	results := make(chan result, inFlight)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		for idx, n := range pages {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			set, err := l.loadTraced(ctx, n)
+			if err != nil {
+				results <- result{idx: idx, err: err}
+				return
+			}
+			select {
+			case jobs <- job{idx: idx, set: set}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if err := ctx.Err(); err != nil {
+					results <- result{idx: j.idx, err: err}
+					continue
+				}
+				res, err := process(ctx, j.set)
+				results <- result{idx: j.idx, res: res, err: err}
+			}
+		}()
+	}
+
+	pending := make(map[int]result, inFlight)
+	for next := 0; next < len(pages); {
+		if r, ok := pending[next]; ok {
+			delete(pending, next)
+			if r.err != nil {
+				return r.err
+			}
+			if err := emit(pages[next], r.res); err != nil {
+				return err
+			}
+			<-slots
+			next++
+			continue
+		}
+		select {
+		case r := <-results:
+			pending[r.idx] = r
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
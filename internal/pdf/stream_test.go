@@ -0,0 +1,177 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MeKo-Tech/pogo/internal/tracing/tracingtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+// buildMultiPagePDF writes a PDF with one 100x50 pixel image page per page.
+func buildMultiPagePDF(t *testing.T, pages int) string {
+	t.Helper()
+	images := make([]image.Image, pages)
+	for i := range images {
+		images[i] = image.NewGray(image.Rect(0, 0, 100, 50))
+	}
+	var buf bytes.Buffer
+	require.NoError(t, WriteSearchablePDFFromImages(&buf, images, nil, SearchableOptions{DPI: 72}))
+	path := filepath.Join(t.TempDir(), "pages.pdf")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+	return path
+}
+
+func TestOpenPages(t *testing.T) {
+	path := buildMultiPagePDF(t, 4)
+
+	l, err := OpenPages(path, "3,1-2,3,9", PageModeRender, RenderOptions{DPI: 72})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, l.Pages())
+	set, err := l.Load(2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, set.PageNumber)
+	require.Len(t, set.Images, 1)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), set.Images[0].Image.Bounds())
+	assert.Positive(t, l.LoadTime())
+
+	l, err = OpenPages(path, "", PageModeExtract, RenderOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 4}, l.Pages())
+
+	_, err = OpenPages(path, "", "vector", RenderOptions{})
+	require.Error(t, err)
+	_, err = OpenPages(path, "x", "", RenderOptions{})
+	require.Error(t, err)
+}
+
+func TestPageLoader_CommandRendererReusesDocument(t *testing.T) {
+	path := buildMultiPagePDF(t, 3)
+	pagePNG := filepath.Join(t.TempDir(), "page.png")
+	f, err := os.Create(pagePNG)
+	require.NoError(t, err)
+	require.NoError(t, png.Encode(f, image.NewGray(image.Rect(0, 0, 200, 100))))
+	require.NoError(t, f.Close())
+
+	renderer := CommandRenderer{Command: "cp", Args: []string{pagePNG, "{output}"}}
+	l, err := OpenPages(path, "", PageModeRender, RenderOptions{DPI: 144, Renderer: renderer})
+	require.NoError(t, err)
+
+	// Pages are rendered from the document read by OpenPages, not by
+	// reading the file again.
+	require.NoError(t, os.WriteFile(path, []byte("not a PDF"), 0o600))
+	for _, n := range l.Pages() {
+		set, err := l.Load(n)
+		require.NoError(t, err)
+		assert.Equal(t, n, set.PageNumber)
+		require.Len(t, set.Images, 1)
+		x, y := set.Images[0].Placement.Apply(200, 100)
+		assert.InDelta(t, 100.0, x, 1e-9)
+		assert.InDelta(t, 0.0, y, 1e-9)
+	}
+}
+
+func TestStreamPages_OrderAndBound(t *testing.T) {
+	l, err := OpenPages(buildMultiPagePDF(t, 8), "", PageModeRender, RenderOptions{DPI: 36})
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	held, maxHeld := 0, 0
+	var order []int
+	err = StreamPages(context.Background(), l, StreamOptions{Workers: 4, MaxInFlight: 3},
+		func(_ context.Context, set *PageSet) (int, error) {
+			mu.Lock()
+			held++
+			maxHeld = max(maxHeld, held)
+			mu.Unlock()
+			// Later pages finish first.
+			time.Sleep(time.Duration(9-set.PageNumber) * time.Millisecond)
+			return set.PageNumber * 10, nil
+		},
+		func(pageNum int, res int) error {
+			mu.Lock()
+			held--
+			mu.Unlock()
+			assert.Equal(t, pageNum*10, res)
+			order = append(order, pageNum)
+			return nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8}, order)
+	assert.LessOrEqual(t, maxHeld, 3)
+}
+
+func TestStreamPages_Errors(t *testing.T) {
+	path := buildMultiPagePDF(t, 5)
+	open := func() *PageLoader {
+		l, err := OpenPages(path, "", PageModeRender, RenderOptions{DPI: 36})
+		require.NoError(t, err)
+		return l
+	}
+	process := func(_ context.Context, set *PageSet) (int, error) {
+		if set.PageNumber == 3 {
+			return 0, errors.New("boom")
+		}
+		return set.PageNumber, nil
+	}
+
+	// Pages before the failing one are still emitted.
+	var emitted []int
+	err := StreamPages(context.Background(), open(), StreamOptions{Workers: 2}, process,
+		func(pageNum int, _ int) error {
+			emitted = append(emitted, pageNum)
+			return nil
+		})
+	require.EqualError(t, err, "boom")
+	assert.Equal(t, []int{1, 2}, emitted)
+
+	stop := errors.New("stop")
+	err = StreamPages(context.Background(), open(), StreamOptions{}, process,
+		func(int, int) error { return stop })
+	require.ErrorIs(t, err, stop)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = StreamPages(ctx, open(), StreamOptions{}, process, func(int, int) error { return nil })
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestStreamPages_Spans(t *testing.T) {
+	spans := tracingtest.Install(t)
+	l, err := OpenPages(buildMultiPagePDF(t, 3), "2-3", PageModeExtract, RenderOptions{})
+	require.NoError(t, err)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "document")
+	err = StreamPages(ctx, l, StreamOptions{Workers: 2},
+		func(_ context.Context, set *PageSet) (int, error) { return set.PageNumber, nil },
+		func(int, int) error { return nil })
+	require.NoError(t, err)
+	parent.End()
+
+	var pages []int64
+	for _, s := range spans.GetSpans() {
+		if s.Name != "pdf.load_page" {
+			continue
+		}
+		assert.Equal(t, parent.SpanContext().SpanID(), s.Parent.SpanID())
+		for _, kv := range s.Attributes {
+			switch kv.Key {
+			case "pdf.page":
+				pages = append(pages, kv.Value.AsInt64())
+			case "pdf.images":
+				assert.Equal(t, int64(1), kv.Value.AsInt64())
+			}
+		}
+	}
+	assert.Equal(t, []int64{2, 3}, pages)
+}
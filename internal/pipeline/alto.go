@@ -0,0 +1,151 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// altoNamespace is the ALTO v4 namespace; documents declare schema version 4.4.
+const altoNamespace = "http://www.loc.gov/standards/alto/ns-v4#"
+
+// ToALTOImage serializes a single image result as an ALTO v4 document.
+// source is recorded as the source image file name and may be empty.
+func ToALTOImage(res *OCRImageResult, source string, meta ExportMetadata) (string, error) {
+	if res == nil {
+		return "", errors.New("nil result")
+	}
+	return ToALTOImages([]*OCRImageResult{res}, []string{source}, meta)
+}
+
+// ToALTOImages serializes several image results as one ALTO document with one Page per image.
+func ToALTOImages(results []*OCRImageResult, sources []string, meta ExportMetadata) (string, error) {
+	return renderALTO(imageLayoutPages(results, sources), meta), nil
+}
+
+// ToALTOPDF serializes a PDF result as ALTO with one Page per processed PDF page.
+// Coordinates are pixels on the page as displayed, at the resolution of its
+// sharpest image.
+func ToALTOPDF(res *OCRPDFResult, meta ExportMetadata) (string, error) {
+	if res == nil {
+		return "", errors.New("nil result")
+	}
+	return ToALTOPDFs([]*OCRPDFResult{res}, meta)
+}
+
+// ToALTOPDFs serializes several PDF results as one ALTO document with one Page per
+// processed PDF page.
+func ToALTOPDFs(results []*OCRPDFResult, meta ExportMetadata) (string, error) {
+	return renderALTO(pdfLayoutPages(results), meta), nil
+}
+
+func renderALTO(pages []layoutPage, meta ExportMetadata) string {
+	meta = meta.withDefaults()
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<alto xmlns="` + altoNamespace + `" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" ` +
+		`xsi:schemaLocation="` + altoNamespace + ` http://www.loc.gov/standards/alto/v4/alto-4-4.xsd" SCHEMAVERSION="4.4">
+  <Description>
+    <MeasurementUnit>pixel</MeasurementUnit>
+`)
+	if source := commonSource(pages); source != "" {
+		fmt.Fprintf(&b, "    <sourceImageInformation>\n      <fileName>%s</fileName>\n    </sourceImageInformation>\n",
+			html.EscapeString(source))
+	}
+	b.WriteString("    <Processing ID=\"proc_1\">\n")
+	b.WriteString("      <processingCategory>contentGeneration</processingCategory>\n")
+	fmt.Fprintf(&b, "      <processingDateTime>%s</processingDateTime>\n", meta.Created.Format(time.RFC3339))
+	b.WriteString("      <processingStepDescription>text detection and recognition</processingStepDescription>\n")
+	if settings := meta.modelSettings(); settings != "" {
+		fmt.Fprintf(&b, "      <processingStepSettings>%s</processingStepSettings>\n", html.EscapeString(settings))
+	}
+	b.WriteString("      <processingSoftware>\n")
+	fmt.Fprintf(&b, "        <softwareName>%s</softwareName>\n", html.EscapeString(meta.Software))
+	if meta.Version != "" {
+		fmt.Fprintf(&b, "        <softwareVersion>%s</softwareVersion>\n", html.EscapeString(meta.Version))
+	}
+	b.WriteString("      </processingSoftware>\n    </Processing>\n  </Description>\n  <Layout>\n")
+	for i, page := range pages {
+		writeALTOPage(&b, i+1, page)
+	}
+	b.WriteString("  </Layout>\n</alto>\n")
+	return b.String()
+}
+
+func writeALTOPage(b *strings.Builder, pageNo int, page layoutPage) {
+	fmt.Fprintf(b, "    <Page ID=\"page_%d\" PHYSICAL_IMG_NR=\"%d\" WIDTH=\"%d\" HEIGHT=\"%d\" PROCESSING=\"proc_1\">\n",
+		pageNo, pageNo, page.width, page.height)
+	fmt.Fprintf(b, "      <PrintSpace ID=\"page_%d_ps\" HPOS=\"0\" VPOS=\"0\" WIDTH=\"%d\" HEIGHT=\"%d\">\n",
+		pageNo, page.width, page.height)
+
+	lineNo := 0
+	for blockNo, set := range page.regions {
+		var lines strings.Builder
+		bx0, by0, bx1, by1 := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+		for _, r := range set.regions {
+			words := splitRegionWords(r)
+			if len(words) == 0 {
+				continue
+			}
+			lineNo++
+			x0, y0 := float64(r.Box.X)*set.sx, float64(r.Box.Y)*set.sy
+			x1, y1 := float64(r.Box.X+r.Box.W)*set.sx, float64(r.Box.Y+r.Box.H)*set.sy
+			bx0, by0 = math.Min(bx0, x0), math.Min(by0, y0)
+			bx1, by1 = math.Max(bx1, x1), math.Max(by1, y1)
+			writeALTOLine(&lines, fmt.Sprintf("%d_%d", pageNo, lineNo), r, words, set.sx, set.sy)
+		}
+		if lines.Len() == 0 {
+			continue
+		}
+		fmt.Fprintf(b, "        <TextBlock ID=\"block_%d_%d\" %s>\n", pageNo, blockNo+1, altoBox(bx0, by0, bx1-bx0, by1-by0))
+		b.WriteString(lines.String())
+		b.WriteString("        </TextBlock>\n")
+	}
+	b.WriteString("      </PrintSpace>\n    </Page>\n")
+}
+
+func writeALTOLine(b *strings.Builder, id string, r OCRRegionResult, words []regionWord, sx, sy float64) {
+	var lang string
+	if languageTag.MatchString(r.Language) {
+		lang = fmt.Sprintf(" LANG=\"%s\"", r.Language)
+	}
+	fmt.Fprintf(b, "          <TextLine ID=\"line_%s\" %s%s>\n", id,
+		altoBox(float64(r.Box.X)*sx, float64(r.Box.Y)*sy, float64(r.Box.W)*sx, float64(r.Box.H)*sy), lang)
+	fmt.Fprintf(b, "            <Shape><Polygon POINTS=\"%s\"/></Shape>\n", formatPoints(regionPolygon(r, sx, sy)))
+	for i, w := range words {
+		if i > 0 {
+			prev := words[i-1]
+			gapX := (prev.X + prev.W) * sx
+			fmt.Fprintf(b, "            <SP HPOS=\"%d\" VPOS=\"%d\" WIDTH=\"%d\"/>\n",
+				roundInt(gapX), roundInt(w.Y*sy), roundInt(w.X*sx-gapX))
+		}
+		fmt.Fprintf(b, "            <String ID=\"string_%s_%d\" CONTENT=\"%s\" %s WC=\"%.3f\"/>\n",
+			id, i+1, html.EscapeString(w.Text), altoBox(w.X*sx, w.Y*sy, w.W*sx, w.H*sy),
+			math.Max(0, math.Min(1, wordConfidence(r, w))))
+	}
+	b.WriteString("          </TextLine>\n")
+}
+
+func altoBox(x, y, w, h float64) string {
+	return fmt.Sprintf("HPOS=\"%d\" VPOS=\"%d\" WIDTH=\"%d\" HEIGHT=\"%d\"", roundInt(x), roundInt(y), roundInt(w), roundInt(h))
+}
+
+// languageTag matches values accepted by xsd:language.
+var languageTag = regexp.MustCompile(`^[a-zA-Z]{1,8}(-[a-zA-Z0-9]{1,8})*$`)
+
+// commonSource returns the source shared by all pages, or "" when they differ.
+func commonSource(pages []layoutPage) string {
+	if len(pages) == 0 {
+		return ""
+	}
+	for _, p := range pages[1:] {
+		if p.source != pages[0].source {
+			return ""
+		}
+	}
+	return pages[0].source
+}
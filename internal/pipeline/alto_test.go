@@ -0,0 +1,123 @@
+package pipeline
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type altoTestDoc struct {
+	Description struct {
+		MeasurementUnit string `xml:"MeasurementUnit"`
+		FileName        string `xml:"sourceImageInformation>fileName"`
+		Settings        string `xml:"Processing>processingStepSettings"`
+		Software        string `xml:"Processing>processingSoftware>softwareName"`
+		Version         string `xml:"Processing>processingSoftware>softwareVersion"`
+	} `xml:"Description"`
+	Pages []struct {
+		ID     string `xml:"ID,attr"`
+		Width  int    `xml:"WIDTH,attr"`
+		Blocks []struct {
+			Lines []struct {
+				HPOS    int    `xml:"HPOS,attr"`
+				Lang    string `xml:"LANG,attr"`
+				Polygon struct {
+					Points string `xml:"POINTS,attr"`
+				} `xml:"Shape>Polygon"`
+				Strings []struct {
+					Content string  `xml:"CONTENT,attr"`
+					HPOS    int     `xml:"HPOS,attr"`
+					Width   int     `xml:"WIDTH,attr"`
+					WC      float64 `xml:"WC,attr"`
+				} `xml:"String"`
+				Spaces []struct {
+					HPOS int `xml:"HPOS,attr"`
+				} `xml:"SP"`
+			} `xml:"TextLine"`
+		} `xml:"PrintSpace>TextBlock"`
+	} `xml:"Layout>Page"`
+}
+
+func TestToALTOImage(t *testing.T) {
+	res := sampleResult()
+	res.Regions[0].Text = "Hi & bye"
+	res.Regions[0].Language = "en"
+	res.Regions[0].CharConfidences = []float64{1, 0.8, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5}
+
+	doc, err := ToALTOImage(res, "scan.png", testExportMetadata())
+	require.NoError(t, err)
+	validateAgainstSchema(t, "alto-4-4-subset.xsd", doc)
+
+	var alto altoTestDoc
+	require.NoError(t, xml.Unmarshal([]byte(doc), &alto))
+	assert.Equal(t, "pixel", alto.Description.MeasurementUnit)
+	assert.Equal(t, "scan.png", alto.Description.FileName)
+	assert.Equal(t, "pogo", alto.Description.Software)
+	assert.Equal(t, "1.2.3", alto.Description.Version)
+	assert.Contains(t, alto.Description.Settings, "recognition-model=rec.onnx")
+
+	require.Len(t, alto.Pages, 1)
+	assert.Equal(t, 200, alto.Pages[0].Width)
+	require.Len(t, alto.Pages[0].Blocks, 1)
+	lines := alto.Pages[0].Blocks[0].Lines
+	require.Len(t, lines, 2)
+	assert.Equal(t, "en", lines[0].Lang)
+	assert.Equal(t, "10,10 60,10 60,30 10,30", lines[0].Polygon.Points)
+	require.Len(t, lines[0].Strings, 3)
+	assert.Equal(t, "Hi", lines[0].Strings[0].Content)
+	assert.InDelta(t, 0.9, lines[0].Strings[0].WC, 1e-9)
+	assert.Equal(t, "&", lines[0].Strings[1].Content)
+	assert.Len(t, lines[0].Spaces, 2)
+	assert.Equal(t, lines[0].Strings[0].HPOS+lines[0].Strings[0].Width, lines[0].Spaces[0].HPOS)
+}
+
+func TestToALTOPDF(t *testing.T) {
+	res := &OCRPDFResult{Filename: "doc.pdf", Pages: []OCRPDFPageResult{
+		{PageNumber: 1, Width: 400, Height: 200, Images: []OCRPDFImageResult{{Width: 200, Height: 100, Regions: sampleResult().Regions}}},
+		{PageNumber: 2, Width: 400, Height: 200},
+	}}
+
+	doc, err := ToALTOPDF(res, testExportMetadata())
+	require.NoError(t, err)
+	validateAgainstSchema(t, "alto-4-4-subset.xsd", doc)
+
+	var alto altoTestDoc
+	require.NoError(t, xml.Unmarshal([]byte(doc), &alto))
+	assert.Equal(t, "doc.pdf", alto.Description.FileName)
+	require.Len(t, alto.Pages, 2)
+	assert.Equal(t, "page_2", alto.Pages[1].ID)
+	assert.Empty(t, alto.Pages[1].Blocks)
+	lines := alto.Pages[0].Blocks[0].Lines
+	assert.Equal(t, 20, lines[0].HPOS)
+	assert.Equal(t, "20,20 120,20 120,60 20,60", lines[0].Polygon.Points)
+
+	_, err = ToALTOPDF(nil, ExportMetadata{})
+	assert.Error(t, err)
+}
+
+func TestToALTOImages_DistinctSources(t *testing.T) {
+	doc, err := ToALTOImages([]*OCRImageResult{sampleResult(), sampleResult()}, []string{"a.png", "b.png"}, ExportMetadata{})
+	require.NoError(t, err)
+	validateAgainstSchema(t, "alto-4-4-subset.xsd", doc)
+	assert.NotContains(t, doc, "sourceImageInformation")
+	assert.Contains(t, doc, `ID="line_2_1"`)
+}
+
+func TestToALTOPDFs(t *testing.T) {
+	page := OCRPDFPageResult{PageNumber: 1, Width: 400, Height: 200}
+	results := []*OCRPDFResult{
+		{Filename: "a.pdf", Pages: []OCRPDFPageResult{page, page}},
+		{Filename: "b.pdf", Pages: []OCRPDFPageResult{page}},
+	}
+	doc, err := ToALTOPDFs(results, ExportMetadata{})
+	require.NoError(t, err)
+	validateAgainstSchema(t, "alto-4-4-subset.xsd", doc)
+
+	var alto altoTestDoc
+	require.NoError(t, xml.Unmarshal([]byte(doc), &alto))
+	require.Len(t, alto.Pages, 3)
+	assert.Equal(t, "page_3", alto.Pages[2].ID)
+	assert.Empty(t, alto.Description.FileName, "the pages come from different files")
+}
@@ -0,0 +1,130 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"strings"
+)
+
+// ToHOCRImage serializes a single image result as an hOCR document.
+// source is recorded as the page image name and may be empty.
+func ToHOCRImage(res *OCRImageResult, source string) (string, error) {
+	if res == nil {
+		return "", errors.New("nil result")
+	}
+	return ToHOCRImages([]*OCRImageResult{res}, []string{source})
+}
+
+// ToHOCRImages serializes several image results as one hOCR document with one ocr_page per image.
+func ToHOCRImages(results []*OCRImageResult, sources []string) (string, error) {
+	return renderHOCR(imageLayoutPages(results, sources)), nil
+}
+
+// ToHOCRPDF serializes a PDF result as hOCR with one ocr_page per PDF page.
+func ToHOCRPDF(res *OCRPDFResult) (string, error) {
+	if res == nil {
+		return "", errors.New("nil result")
+	}
+	return ToHOCRPDFs([]*OCRPDFResult{res})
+}
+
+// ToHOCRPDFs serializes several PDF results as one hOCR document. Regions are
+// placed by their page coordinates in the page's pixel space.
+func ToHOCRPDFs(results []*OCRPDFResult) (string, error) {
+	return renderHOCR(pdfLayoutPages(results)), nil
+}
+
+func renderHOCR(pages []layoutPage) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="en" lang="en">
+ <head>
+  <title></title>
+  <meta http-equiv="Content-Type" content="text/html;charset=utf-8"/>
+  <meta name="ocr-system" content="pogo"/>
+  <meta name="ocr-capabilities" content="ocr_page ocr_line ocrx_word ocrp_wconf ocrp_lang"/>
+ </head>
+ <body>
+`)
+	for i, page := range pages {
+		writeHOCRPage(&b, i, page)
+	}
+	b.WriteString(" </body>\n</html>\n")
+	return b.String()
+}
+
+func writeHOCRPage(b *strings.Builder, index int, page layoutPage) {
+	pageNo := index + 1
+	title := fmt.Sprintf("bbox 0 0 %d %d; ppageno %d", page.width, page.height, index)
+	if page.source != "" {
+		title = fmt.Sprintf("image %q; %s", page.source, title)
+	}
+	fmt.Fprintf(b, "  <div class=\"ocr_page\" id=\"page_%d\" title=\"%s\">\n", pageNo, html.EscapeString(title))
+
+	lineNo := 0
+	for _, set := range page.regions {
+		for _, r := range set.regions {
+			words := splitRegionWords(r)
+			if len(words) == 0 {
+				continue
+			}
+			lineNo++
+			writeHOCRLine(b, pageNo, lineNo, r, words, set.sx, set.sy)
+		}
+	}
+	b.WriteString("  </div>\n")
+}
+
+func writeHOCRLine(b *strings.Builder, pageNo, lineNo int, r OCRRegionResult, words []regionWord, sx, sy float64) {
+	x0, y0 := float64(r.Box.X)*sx, float64(r.Box.Y)*sy
+	x1, y1 := float64(r.Box.X+r.Box.W)*sx, float64(r.Box.Y+r.Box.H)*sy
+	slope, offset := hocrBaseline(r, sx, sy)
+	title := fmt.Sprintf("%s; baseline %.3f %d; x_size %d; x_wconf %d",
+		hocrBBox(x0, y0, x1, y1), slope, offset, int(math.Round(y1-y0)), hocrConfidence(r.RecConfidence))
+
+	fmt.Fprintf(b, "   <span class=\"ocr_line\" id=\"line_%d_%d\" title=\"%s\"%s>", pageNo, lineNo, title, hocrLang(r.Language))
+	for i, w := range words {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		wTitle := fmt.Sprintf("%s; x_wconf %d",
+			hocrBBox(w.X*sx, w.Y*sy, (w.X+w.W)*sx, (w.Y+w.H)*sy), hocrConfidence(wordConfidence(r, w)))
+		fmt.Fprintf(b, "<span class=\"ocrx_word\" id=\"word_%d_%d_%d\" title=\"%s\">%s</span>",
+			pageNo, lineNo, i+1, wTitle, html.EscapeString(w.Text))
+	}
+	b.WriteString("</span>\n")
+}
+
+func hocrBBox(x0, y0, x1, y1 float64) string {
+	return fmt.Sprintf("bbox %d %d %d %d",
+		int(math.Round(x0)), int(math.Round(y0)), int(math.Round(x1)), int(math.Round(y1)))
+}
+
+func hocrConfidence(c float64) int {
+	return int(math.Round(math.Max(0, math.Min(1, c)) * 100))
+}
+
+func hocrLang(lang string) string {
+	if lang == "" {
+		return ""
+	}
+	return fmt.Sprintf(" lang=\"%s\"", html.EscapeString(lang))
+}
+
+// hocrBaseline estimates the hOCR baseline (slope and offset from the bottom-left
+// corner of the line box). The slope follows the polygon's top edge when available;
+// the offset assumes descenders take the bottom fifth of the line height.
+func hocrBaseline(r OCRRegionResult, sx, sy float64) (float64, int) {
+	var slope float64
+	if len(r.Polygon) >= 2 {
+		dx := (r.Polygon[1].X - r.Polygon[0].X) * sx
+		dy := (r.Polygon[1].Y - r.Polygon[0].Y) * sy
+		if dx > 0 {
+			slope = dy / dx
+		}
+	}
+	return slope, -int(math.Round(float64(r.Box.H) * sy * 0.2))
+}
@@ -0,0 +1,89 @@
+package pipeline
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hocrElements parses an hOCR document and returns the title of every element by class.
+func hocrElements(t *testing.T, doc string) map[string][]string {
+	t.Helper()
+	dec := xml.NewDecoder(strings.NewReader(doc))
+	out := map[string][]string{}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if se, ok := tok.(xml.StartElement); ok {
+			var class, title string
+			for _, a := range se.Attr {
+				switch a.Name.Local {
+				case "class":
+					class = a.Value
+				case "title":
+					title = a.Value
+				}
+			}
+			if class != "" {
+				out[class] = append(out[class], title)
+			}
+		}
+	}
+	return out
+}
+
+func TestToHOCRImage(t *testing.T) {
+	res := sampleResult()
+	res.Regions[0].Text = "Hello <there>"
+	res.Regions[0].Language = "en"
+	res.Regions[0].Polygon = []struct{ X, Y float64 }{{10, 10}, {60, 15}, {60, 35}, {10, 30}}
+
+	doc, err := ToHOCRImage(res, "scan.png")
+	require.NoError(t, err)
+	assert.Contains(t, doc, `<meta name="ocr-system" content="pogo"/>`)
+	assert.Contains(t, doc, "Hello</span>")
+	assert.Contains(t, doc, "&lt;there&gt;")
+	assert.Contains(t, doc, `lang="en"`)
+
+	el := hocrElements(t, doc)
+	require.Len(t, el["ocr_page"], 1)
+	assert.Equal(t, `image "scan.png"; bbox 0 0 200 100; ppageno 0`, el["ocr_page"][0])
+	require.Len(t, el["ocr_line"], 2)
+	assert.Equal(t, "bbox 10 10 60 30; baseline 0.100 -4; x_size 20; x_wconf 90", el["ocr_line"][0])
+	require.Len(t, el["ocrx_word"], 3)
+	assert.Equal(t, "bbox 10 10 29 30; x_wconf 90", el["ocrx_word"][0])
+	assert.Equal(t, "bbox 70 40 140 60; x_wconf 85", el["ocrx_word"][2])
+}
+
+func TestToHOCRImages_MultiplePages(t *testing.T) {
+	doc, err := ToHOCRImages([]*OCRImageResult{sampleResult(), nil, sampleResult()}, []string{"a.png", "b.png", "c.png"})
+	require.NoError(t, err)
+	el := hocrElements(t, doc)
+	require.Len(t, el["ocr_page"], 2)
+	assert.Contains(t, el["ocr_page"][1], `image "c.png"`)
+	assert.Contains(t, el["ocr_page"][1], "ppageno 1")
+	assert.Contains(t, doc, `id="word_2_1_1"`)
+}
+
+func TestToHOCRPDF(t *testing.T) {
+	res := &OCRPDFResult{Filename: "doc.pdf", Pages: []OCRPDFPageResult{{
+		PageNumber: 1, Width: 400, Height: 200,
+		Images: []OCRPDFImageResult{{Width: 200, Height: 100, Regions: sampleResult().Regions}},
+	}}}
+	doc, err := ToHOCRPDF(res)
+	require.NoError(t, err)
+	el := hocrElements(t, doc)
+	require.Len(t, el["ocr_page"], 1)
+	assert.Equal(t, `image "doc.pdf"; bbox 0 0 400 200; ppageno 0`, el["ocr_page"][0])
+	assert.True(t, strings.HasPrefix(el["ocr_line"][0], "bbox 20 20 120 60;"))
+
+	_, err = ToHOCRPDF(nil)
+	require.Error(t, err)
+}
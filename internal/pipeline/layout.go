@@ -0,0 +1,210 @@
+package pipeline
+
+import (
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// layoutPage is one page of a layout document (hOCR, ALTO, PAGE). Region
+// coordinates are scaled by sx/sy into the page's pixel space.
+type layoutPage struct {
+	source  string
+	width   int
+	height  int
+	regions []layoutRegionSet
+}
+
+type layoutRegionSet struct {
+	regions []OCRRegionResult
+	sx, sy  float64
+}
+
+// imageLayoutPages returns one page per non-nil image result.
+func imageLayoutPages(results []*OCRImageResult, sources []string) []layoutPage {
+	pages := make([]layoutPage, 0, len(results))
+	for i, res := range results {
+		if res == nil {
+			continue
+		}
+		var source string
+		if i < len(sources) {
+			source = sources[i]
+		}
+		pages = append(pages, layoutPage{
+			source:  source,
+			width:   res.Width,
+			height:  res.Height,
+			regions: []layoutRegionSet{{regions: res.Regions, sx: 1, sy: 1}},
+		})
+	}
+	return pages
+}
+
+// pdfLayoutPages returns one page per processed PDF page. Regions are placed
+// by their page coordinates in a pixel space that covers the page as displayed
+// at the resolution of its sharpest image. Results without page coordinates
+// fall back to assuming that every image covers its page, scaled into the
+// pixel space of its largest image.
+func pdfLayoutPages(results []*OCRPDFResult) []layoutPage {
+	var pages []layoutPage
+	for _, res := range results {
+		if res == nil {
+			continue
+		}
+		for _, p := range res.Pages {
+			pages = append(pages, pdfLayoutPage(res.Filename, p))
+		}
+	}
+	return pages
+}
+
+func pdfLayoutPage(source string, p OCRPDFPageResult) layoutPage {
+	page := layoutPage{source: source, width: p.Width, height: p.Height}
+	scale := pagePixelScale(p)
+	if scale > 0 {
+		page.width = max(1, roundInt(p.WidthPt*scale))
+		page.height = max(1, roundInt(p.HeightPt*scale))
+	}
+	for _, img := range p.Images {
+		if img.Width <= 0 || img.Height <= 0 {
+			continue
+		}
+		sx := float64(page.width) / float64(img.Width)
+		sy := float64(page.height) / float64(img.Height)
+		if scale <= 0 {
+			page.regions = append(page.regions, layoutRegionSet{regions: img.Regions, sx: sx, sy: sy})
+			continue
+		}
+		set := layoutRegionSet{regions: make([]OCRRegionResult, len(img.Regions)), sx: 1, sy: 1}
+		for i, r := range img.Regions {
+			set.regions[i] = placedLayoutRegion(r, scale, sx, sy)
+		}
+		page.regions = append(page.regions, set)
+	}
+	return page
+}
+
+// pagePixelScale returns the pixels per point of the sharpest image on the
+// page, or 0 if the page size or the image placements are unknown.
+func pagePixelScale(p OCRPDFPageResult) float64 {
+	if p.WidthPt <= 0 || p.HeightPt <= 0 {
+		return 0
+	}
+	var scale float64
+	for _, img := range p.Images {
+		if img.Placement == nil {
+			continue
+		}
+		if pt := math.Hypot(img.Placement[0], img.Placement[1]); pt > 0 {
+			scale = math.Max(scale, 1/pt)
+		}
+	}
+	return scale
+}
+
+// placedLayoutRegion returns r with its polygon and box moved to its page
+// coordinates, scaled to page pixels. Regions that were not placed are
+// scaled by sx/sy as if their image covered the page.
+func placedLayoutRegion(r OCRRegionResult, scale, sx, sy float64) OCRRegionResult {
+	if r.Page == nil {
+		r.Polygon = regionPolygon(r, sx, sy)
+		r.Box.X, r.Box.Y = roundInt(float64(r.Box.X)*sx), roundInt(float64(r.Box.Y)*sy)
+		r.Box.W, r.Box.H = roundInt(float64(r.Box.W)*sx), roundInt(float64(r.Box.H)*sy)
+		return r
+	}
+	r.Polygon = make([]struct{ X, Y float64 }, len(r.Page.PolygonTopLeft))
+	for i, pt := range r.Page.PolygonTopLeft {
+		r.Polygon[i].X, r.Polygon[i].Y = pt.X*scale, pt.Y*scale
+	}
+	box := r.Page.BoxTopLeft
+	r.Box.X, r.Box.Y = roundInt(box.X*scale), roundInt(box.Y*scale)
+	r.Box.W, r.Box.H = roundInt(box.W*scale), roundInt(box.H*scale)
+	return r
+}
+
+// regionPolygon returns the region outline scaled into page space. The
+// detection polygon is used when present, otherwise the bounding box corners.
+func regionPolygon(r OCRRegionResult, sx, sy float64) []struct{ X, Y float64 } {
+	if len(r.Polygon) >= 3 {
+		pts := make([]struct{ X, Y float64 }, len(r.Polygon))
+		for i, p := range r.Polygon {
+			pts[i].X, pts[i].Y = p.X*sx, p.Y*sy
+		}
+		return pts
+	}
+	return rectPoints(float64(r.Box.X)*sx, float64(r.Box.Y)*sy, float64(r.Box.X+r.Box.W)*sx, float64(r.Box.Y+r.Box.H)*sy)
+}
+
+// rectPoints returns the corners of a rectangle, clockwise from the top left.
+func rectPoints(x0, y0, x1, y1 float64) []struct{ X, Y float64 } {
+	return []struct{ X, Y float64 }{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}}
+}
+
+// formatPoints renders points as "x1,y1 x2,y2 ..." with non-negative integer
+// coordinates, as used by both ALTO and PAGE polygons.
+func formatPoints(pts []struct{ X, Y float64 }) string {
+	parts := make([]string, len(pts))
+	for i, p := range pts {
+		parts[i] = strconv.Itoa(roundInt(p.X)) + "," + strconv.Itoa(roundInt(p.Y))
+	}
+	return strings.Join(parts, " ")
+}
+
+// roundInt rounds v to the nearest non-negative integer.
+func roundInt(v float64) int {
+	return int(math.Round(math.Max(0, v)))
+}
+
+// ExportMetadata describes how a layout document (ALTO, PAGE) was produced.
+type ExportMetadata struct {
+	Software         string
+	Version          string
+	DetectionModel   string
+	RecognitionModel string
+	Created          time.Time
+}
+
+// ExportMetadata returns processing metadata for layout exports, naming the
+// detection and recognition models this pipeline was built with.
+func (p *Pipeline) ExportMetadata() ExportMetadata {
+	return ExportMetadata{
+		Software:         "pogo",
+		DetectionModel:   modelName(p.cfg.Detector.ModelPath),
+		RecognitionModel: modelName(p.cfg.Recognizer.ModelPath),
+		Created:          time.Now(),
+	}
+}
+
+func modelName(path string) string {
+	if path == "" {
+		return ""
+	}
+	return filepath.Base(path)
+}
+
+// withDefaults fills in the software name and creation time when unset.
+func (m ExportMetadata) withDefaults() ExportMetadata {
+	if m.Software == "" {
+		m.Software = "pogo"
+	}
+	if m.Created.IsZero() {
+		m.Created = time.Now()
+	}
+	m.Created = m.Created.UTC().Truncate(time.Second)
+	return m
+}
+
+// modelSettings summarizes the models as "key=value" pairs for processing metadata.
+func (m ExportMetadata) modelSettings() string {
+	var parts []string
+	if m.DetectionModel != "" {
+		parts = append(parts, "detection-model="+m.DetectionModel)
+	}
+	if m.RecognitionModel != "" {
+		parts = append(parts, "recognition-model="+m.RecognitionModel)
+	}
+	return strings.Join(parts, "; ")
+}
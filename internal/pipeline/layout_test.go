@@ -0,0 +1,101 @@
+package pipeline
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MeKo-Tech/pogo/internal/pdf"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validateAgainstSchema validates doc against a bundled XSD from testdata/schemas
+// using xmllint. Validation is left out when xmllint is not installed; the rest
+// of the test still runs.
+func validateAgainstSchema(t *testing.T, schema, doc string) {
+	t.Helper()
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Log("xmllint not available, skipping schema validation")
+		return
+	}
+	path := filepath.Join(t.TempDir(), "doc.xml")
+	require.NoError(t, os.WriteFile(path, []byte(doc), 0o600))
+	xsd := filepath.Join("..", "..", "testdata", "schemas", schema)
+	out, err := exec.Command(xmllint, "--noout", "--schema", xsd, path).CombinedOutput() //nolint:gosec // test-controlled paths
+	require.NoError(t, err, "schema validation failed:\n%s\n%s", out, doc)
+}
+
+func testExportMetadata() ExportMetadata {
+	return ExportMetadata{
+		Version:          "1.2.3",
+		DetectionModel:   "det.onnx",
+		RecognitionModel: "rec.onnx",
+		Created:          time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+	}
+}
+
+func TestRegionPolygon(t *testing.T) {
+	r := sampleResult().Regions[0]
+	assert.Equal(t, "20,20 120,20 120,60 20,60", formatPoints(regionPolygon(r, 2, 2)))
+
+	r.Polygon = nil
+	assert.Equal(t, "10,10 60,10 60,30 10,30", formatPoints(regionPolygon(r, 1, 1)))
+}
+
+func TestPDFLayoutPages_PlacedImages(t *testing.T) {
+	// A 200x100 pt page showing a 200x200 px image on its left half (2 px/pt)
+	// and a 100x50 px image in its bottom right quarter (1 px/pt).
+	box := *types.RectForDim(200, 100)
+	left := placedPDFImage(200, 200, sampleResult().Regions[:1], pdf.Matrix{0.5, 0, 0, -0.5, 0, 100}, box, 0)
+	right := placedPDFImage(100, 50, sampleResult().Regions[:1], pdf.Matrix{1, 0, 0, -1, 100, 50}, box, 0)
+	res := &OCRPDFResult{Filename: "doc.pdf", Pages: []OCRPDFPageResult{{
+		PageNumber: 1, Width: 200, Height: 200, WidthPt: 200, HeightPt: 100,
+		Images: []OCRPDFImageResult{left, right},
+	}}}
+
+	pages := pdfLayoutPages([]*OCRPDFResult{res})
+	require.Len(t, pages, 1)
+	page := pages[0]
+	assert.Equal(t, 400, page.width)
+	assert.Equal(t, 200, page.height)
+	require.Len(t, page.regions, 2)
+	assert.Equal(t, "10,10 60,10 60,30 10,30", formatPoints(regionPolygon(page.regions[0].regions[0], page.regions[0].sx, page.regions[0].sy)))
+	assert.Equal(t, "220,120 320,120 320,160 220,160", formatPoints(regionPolygon(page.regions[1].regions[0], page.regions[1].sx, page.regions[1].sy)))
+
+	doc, err := ToHOCRPDF(res)
+	require.NoError(t, err)
+	el := hocrElements(t, doc)
+	assert.Equal(t, `image "doc.pdf"; bbox 0 0 400 200; ppageno 0`, el["ocr_page"][0])
+	require.Len(t, el["ocr_line"], 2)
+	assert.True(t, strings.HasPrefix(el["ocr_line"][0], "bbox 10 10 60 30;"), el["ocr_line"][0])
+	assert.True(t, strings.HasPrefix(el["ocr_line"][1], "bbox 220 120 320 160;"), el["ocr_line"][1])
+}
+
+func TestPDFLayoutPages_RotatedPage(t *testing.T) {
+	// A 200x100 pt page with /Rotate 90 rendered at 144 DPI is 200x400 px.
+	img := placedPDFImage(200, 400, sampleResult().Regions[:1], pdf.Matrix{0, 0.5, 0.5, 0, 0, 0}, *types.RectForDim(200, 100), 90)
+	res := &OCRPDFResult{Pages: []OCRPDFPageResult{{
+		PageNumber: 1, Width: 200, Height: 400, WidthPt: 100, HeightPt: 200, Rotate: 90,
+		Images: []OCRPDFImageResult{img},
+	}}}
+
+	pages := pdfLayoutPages([]*OCRPDFResult{res})
+	require.Len(t, pages, 1)
+	assert.Equal(t, 200, pages[0].width)
+	assert.Equal(t, 400, pages[0].height)
+	r := pages[0].regions[0].regions[0]
+	assert.Equal(t, struct{ X, Y, W, H int }{X: 10, Y: 10, W: 50, H: 20}, r.Box)
+}
+
+func TestExportMetadataDefaults(t *testing.T) {
+	meta := ExportMetadata{}.withDefaults()
+	assert.Equal(t, "pogo", meta.Software)
+	assert.False(t, meta.Created.IsZero())
+	assert.Equal(t, "detection-model=det.onnx; recognition-model=rec.onnx", testExportMetadata().modelSettings())
+}
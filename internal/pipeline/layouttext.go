@@ -0,0 +1,161 @@
+package pipeline
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// layoutTextItem is a region's text positioned in page space.
+type layoutTextItem struct {
+	text       string
+	x, y, w, h float64
+	centerY    float64
+	runeCount  int
+}
+
+// ToLayoutTextImage renders an image result as plain text that preserves the
+// page layout, similar to `pdftotext -layout`.
+func ToLayoutTextImage(res *OCRImageResult) (string, error) {
+	if res == nil {
+		return "", errors.New("nil result")
+	}
+	return renderLayoutText(imageLayoutPages([]*OCRImageResult{res}, nil)[0]), nil
+}
+
+// ToLayoutTextPDF renders every processed page with ToLayoutTextImage semantics,
+// separating pages with a form feed.
+func ToLayoutTextPDF(res *OCRPDFResult) (string, error) {
+	if res == nil {
+		return "", errors.New("nil result")
+	}
+	pages := pdfLayoutPages([]*OCRPDFResult{res})
+	out := make([]string, len(pages))
+	for i, page := range pages {
+		out[i] = renderLayoutText(page)
+	}
+	return strings.Join(out, "\f"), nil
+}
+
+// renderLayoutText places region text on a character grid. Columns are derived
+// from the median character width and rows from the median line height, so
+// horizontal alignment and vertical gaps between lines are kept.
+func renderLayoutText(page layoutPage) string {
+	items := layoutTextItems(page)
+	if len(items) == 0 {
+		return ""
+	}
+	charW, lineH := layoutTextMetrics(items)
+
+	rows, rowY := groupLayoutRows(items, lineH)
+
+	minCol := math.MaxInt
+	for _, it := range items {
+		minCol = min(minCol, int(math.Round(it.x/charW)))
+	}
+
+	var b strings.Builder
+	for i, row := range rows {
+		if i > 0 {
+			gap := int(math.Round((rowY[i]-rowY[i-1])/lineH)) - 1
+			b.WriteString(strings.Repeat("\n", max(gap, 0)+1))
+		}
+		var line []rune
+		for _, it := range row {
+			col := int(math.Round(it.x/charW)) - minCol
+			if len(line) > 0 && col <= len(line) {
+				col = len(line) + 1
+			}
+			for len(line) < col {
+				line = append(line, ' ')
+			}
+			line = append(line, []rune(it.text)...)
+		}
+		b.WriteString(strings.TrimRight(string(line), " "))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// groupLayoutRows groups items into rows by vertical center: an item joins the
+// previous row when its center is within half a line height of the row's mean
+// center. Rows are returned top to bottom with their items sorted left to right.
+func groupLayoutRows(items []layoutTextItem, lineH float64) ([][]layoutTextItem, []float64) {
+	sort.SliceStable(items, func(i, j int) bool { return items[i].centerY < items[j].centerY })
+	var rows [][]layoutTextItem
+	var rowY []float64
+	for _, it := range items {
+		n := len(rows)
+		if n > 0 && math.Abs(it.centerY-rowY[n-1]) <= lineH/2 {
+			rows[n-1] = append(rows[n-1], it)
+			rowY[n-1] += (it.centerY - rowY[n-1]) / float64(len(rows[n-1]))
+			continue
+		}
+		rows = append(rows, []layoutTextItem{it})
+		rowY = append(rowY, it.centerY)
+	}
+	for _, row := range rows {
+		sort.SliceStable(row, func(a, c int) bool { return row[a].x < row[c].x })
+	}
+	return rows, rowY
+}
+
+func layoutTextItems(page layoutPage) []layoutTextItem {
+	var items []layoutTextItem
+	for _, set := range page.regions {
+		for _, r := range set.regions {
+			text := strings.TrimSpace(r.Text)
+			if text == "" {
+				continue
+			}
+			y := float64(r.Box.Y) * set.sy
+			h := float64(r.Box.H) * set.sy
+			items = append(items, layoutTextItem{
+				text:      text,
+				x:         float64(r.Box.X) * set.sx,
+				y:         y,
+				w:         float64(r.Box.W) * set.sx,
+				h:         h,
+				centerY:   y + h/2,
+				runeCount: utf8.RuneCountInString(text),
+			})
+		}
+	}
+	return items
+}
+
+// layoutTextMetrics returns the median character width and median line height.
+func layoutTextMetrics(items []layoutTextItem) (float64, float64) {
+	widths := make([]float64, 0, len(items))
+	heights := make([]float64, 0, len(items))
+	for _, it := range items {
+		if it.w > 0 {
+			widths = append(widths, it.w/float64(it.runeCount))
+		}
+		if it.h > 0 {
+			heights = append(heights, it.h)
+		}
+	}
+	charW, lineH := median(widths), median(heights)
+	if charW <= 0 {
+		charW = 1
+	}
+	if lineH <= 0 {
+		lineH = 1
+	}
+	return charW, lineH
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2
+	}
+	return values[mid]
+}
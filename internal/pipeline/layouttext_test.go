@@ -0,0 +1,67 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func layoutRegion(text string, x, y, w, h int) OCRRegionResult {
+	return OCRRegionResult{Text: text, Box: struct{ X, Y, W, H int }{X: x, Y: y, W: w, H: h}}
+}
+
+func TestToLayoutTextImage_PreservesColumns(t *testing.T) {
+	// 10px per character, 20px line height; a two-column table with a blank row gap.
+	res := &OCRImageResult{Width: 400, Height: 200, Regions: []OCRRegionResult{
+		layoutRegion("Price", 200, 10, 50, 20),
+		layoutRegion("Item", 20, 12, 40, 20),
+		layoutRegion("Apple", 20, 32, 50, 20),
+		layoutRegion("1.20", 200, 30, 40, 20),
+		layoutRegion("Total", 20, 92, 50, 20),
+		layoutRegion("   ", 300, 92, 30, 20),
+	}}
+
+	out, err := ToLayoutTextImage(res)
+	require.NoError(t, err)
+	expected := "Item              Price\n" +
+		"Apple             1.20\n" +
+		"\n" +
+		"\n" +
+		"Total\n"
+	assert.Equal(t, expected, out)
+}
+
+func TestToLayoutTextImage_OverlappingRegionsStaySeparated(t *testing.T) {
+	res := &OCRImageResult{Width: 200, Height: 50, Regions: []OCRRegionResult{
+		layoutRegion("Hello", 0, 0, 50, 20),
+		layoutRegion("World", 40, 0, 50, 20),
+	}}
+	out, err := ToLayoutTextImage(res)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello World\n", out)
+}
+
+func TestToLayoutTextImage_Empty(t *testing.T) {
+	out, err := ToLayoutTextImage(&OCRImageResult{Width: 10, Height: 10})
+	require.NoError(t, err)
+	assert.Empty(t, out)
+
+	_, err = ToLayoutTextImage(nil)
+	assert.Error(t, err)
+}
+
+func TestToLayoutTextPDF_FormFeedBetweenPages(t *testing.T) {
+	res := &OCRPDFResult{Pages: []OCRPDFPageResult{
+		{PageNumber: 1, Width: 400, Height: 100, Images: []OCRPDFImageResult{{Width: 200, Height: 50, Regions: []OCRRegionResult{
+			layoutRegion("A", 10, 5, 5, 10),
+			layoutRegion("B", 60, 5, 5, 10),
+		}}}},
+		{PageNumber: 2, Width: 100, Height: 100, Images: []OCRPDFImageResult{{Width: 100, Height: 100, Regions: []OCRRegionResult{
+			layoutRegion("Second", 10, 10, 60, 20),
+		}}}},
+	}}
+	out, err := ToLayoutTextPDF(res)
+	require.NoError(t, err)
+	assert.Equal(t, "A         B\n\fSecond\n", out)
+}
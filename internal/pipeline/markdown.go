@@ -0,0 +1,210 @@
+package pipeline
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Heading thresholds: a single-item row whose height exceeds the page's median
+// line height by these ratios becomes a level 1, 2 or 3 heading.
+var markdownHeadingRatios = []float64{1.8, 1.4, 1.2}
+
+const (
+	// markdownHeadingMaxRunes keeps long, tall lines (e.g. large-print body text) out of headings.
+	markdownHeadingMaxRunes = 80
+	// markdownParagraphGap is the vertical gap, in line heights, that starts a new paragraph.
+	markdownParagraphGap = 0.75
+)
+
+var (
+	markdownBulletPrefix   = regexp.MustCompile(`^[-*•●▪◦·–]\s+`)
+	markdownNumberedPrefix = regexp.MustCompile(`^(\d{1,3})[.)]\s+`)
+)
+
+// markdownRow is a visual line of text on a page.
+type markdownRow struct {
+	items       []layoutTextItem
+	top, bottom float64
+	height      float64
+}
+
+func (r markdownRow) text() string {
+	parts := make([]string, len(r.items))
+	for i, it := range r.items {
+		parts[i] = it.text
+	}
+	return strings.Join(parts, " ")
+}
+
+// ToMarkdownImage renders an image result as Markdown. Headings are inferred
+// from relative text height, list items from line prefixes, paragraphs from
+// vertical spacing and tables from rows with aligned columns.
+func ToMarkdownImage(res *OCRImageResult) (string, error) {
+	if res == nil {
+		return "", errors.New("nil result")
+	}
+	return renderMarkdown(imageLayoutPages([]*OCRImageResult{res}, nil)[0]), nil
+}
+
+// ToMarkdownPDF renders every processed page as Markdown, separating pages with a
+// horizontal rule.
+func ToMarkdownPDF(res *OCRPDFResult) (string, error) {
+	if res == nil {
+		return "", errors.New("nil result")
+	}
+	pages := pdfLayoutPages([]*OCRPDFResult{res})
+	out := make([]string, 0, len(pages))
+	for _, page := range pages {
+		if md := renderMarkdown(page); md != "" {
+			out = append(out, md)
+		}
+	}
+	return strings.Join(out, "\n---\n\n"), nil
+}
+
+func renderMarkdown(page layoutPage) string {
+	items := layoutTextItems(page)
+	if len(items) == 0 {
+		return ""
+	}
+	_, lineH := layoutTextMetrics(items)
+	grouped, _ := groupLayoutRows(items, lineH)
+	rows := make([]markdownRow, len(grouped))
+	for i, g := range grouped {
+		row := markdownRow{items: g, top: math.Inf(1), bottom: math.Inf(-1)}
+		heights := make([]float64, len(g))
+		for j, it := range g {
+			row.top = math.Min(row.top, it.y)
+			row.bottom = math.Max(row.bottom, it.y+it.h)
+			heights[j] = it.h
+		}
+		row.height = median(heights)
+		rows[i] = row
+	}
+
+	var blocks []string
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, strings.Join(paragraph, " "))
+			paragraph = nil
+		}
+	}
+	for i := 0; i < len(rows); i++ {
+		row := rows[i]
+		if n := markdownTableRun(rows[i:]); n >= 2 {
+			flush()
+			blocks = append(blocks, renderMarkdownTable(rows[i:i+n]))
+			i += n - 1
+			continue
+		}
+		text := row.text()
+		if level := markdownHeadingLevel(row, text, lineH); level > 0 {
+			flush()
+			blocks = append(blocks, strings.Repeat("#", level)+" "+text)
+			continue
+		}
+		if item, ok := markdownListItem(text); ok {
+			flush()
+			// Consecutive list items form one list without blank lines in between.
+			if n := len(blocks); n > 0 && i > 0 && isMarkdownListBlock(blocks[n-1]) && rows[i-1].bottom+markdownParagraphGap*lineH >= row.top {
+				blocks[n-1] += "\n" + item
+			} else {
+				blocks = append(blocks, item)
+			}
+			continue
+		}
+		if len(paragraph) > 0 && row.top-rows[i-1].bottom > markdownParagraphGap*lineH {
+			flush()
+		}
+		paragraph = append(paragraph, escapeMarkdownLine(text))
+	}
+	flush()
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+func markdownHeadingLevel(row markdownRow, text string, lineH float64) int {
+	if len(row.items) != 1 || len([]rune(text)) > markdownHeadingMaxRunes {
+		return 0
+	}
+	for i, ratio := range markdownHeadingRatios {
+		if row.height >= ratio*lineH {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// markdownListItem converts a line with a bullet or number prefix to a Markdown list item.
+func markdownListItem(text string) (string, bool) {
+	if loc := markdownBulletPrefix.FindStringIndex(text); loc != nil {
+		return "- " + text[loc[1]:], true
+	}
+	if m := markdownNumberedPrefix.FindStringSubmatchIndex(text); m != nil {
+		n, _ := strconv.Atoi(text[m[2]:m[3]])
+		return strconv.Itoa(n) + ". " + text[m[1]:], true
+	}
+	return "", false
+}
+
+func isMarkdownListBlock(block string) bool {
+	last := block[strings.LastIndex(block, "\n")+1:]
+	return strings.HasPrefix(last, "- ") || markdownNumberedPrefix.MatchString(last)
+}
+
+// markdownTableRun returns how many leading rows form a table: at least two
+// items per row, the same number of items in every row, and each column
+// horizontally overlapping the corresponding column of the first row.
+func markdownTableRun(rows []markdownRow) int {
+	cols := len(rows[0].items)
+	if cols < 2 {
+		return 0
+	}
+	n := 1
+	for ; n < len(rows); n++ {
+		if len(rows[n].items) != cols {
+			break
+		}
+		aligned := true
+		for c, it := range rows[n].items {
+			ref := rows[0].items[c]
+			if it.x > ref.x+ref.w || ref.x > it.x+it.w {
+				aligned = false
+				break
+			}
+		}
+		if !aligned {
+			break
+		}
+	}
+	return n
+}
+
+func renderMarkdownTable(rows []markdownRow) string {
+	var b strings.Builder
+	for i, row := range rows {
+		b.WriteString("|")
+		for _, it := range row.items {
+			b.WriteString(" " + strings.ReplaceAll(it.text, "|", `\|`) + " |")
+		}
+		if i == 0 {
+			b.WriteString("\n|" + strings.Repeat(" --- |", len(row.items)))
+		}
+		if i < len(rows)-1 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// escapeMarkdownLine escapes characters that would otherwise start a Markdown
+// block construct at the beginning of a paragraph line.
+func escapeMarkdownLine(text string) string {
+	if strings.HasPrefix(text, "#") || strings.HasPrefix(text, ">") || strings.HasPrefix(text, "|") {
+		return `\` + text
+	}
+	return text
+}
@@ -0,0 +1,60 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToMarkdownImage(t *testing.T) {
+	res := &OCRImageResult{Width: 600, Height: 800, Regions: []OCRRegionResult{
+		layoutRegion("Quarterly Report", 20, 10, 300, 40),
+		layoutRegion("This report covers", 20, 70, 180, 20),
+		layoutRegion("the third quarter.", 20, 92, 180, 20),
+		layoutRegion("# of units sold rose.", 20, 150, 200, 20),
+		layoutRegion("• Revenue up", 20, 200, 120, 20),
+		layoutRegion("• Costs down", 20, 222, 120, 20),
+		layoutRegion("2) Hiring | paused", 20, 270, 180, 20),
+		layoutRegion("Region", 20, 320, 60, 20),
+		layoutRegion("Sales", 300, 320, 50, 20),
+		layoutRegion("North", 22, 342, 50, 20),
+		layoutRegion("1,200", 305, 342, 50, 20),
+		layoutRegion("South", 20, 364, 50, 20),
+		layoutRegion("9|00", 300, 364, 40, 20),
+		layoutRegion("Notes", 20, 420, 70, 24),
+	}}
+
+	out, err := ToMarkdownImage(res)
+	require.NoError(t, err)
+	expected := "# Quarterly Report\n\n" +
+		"This report covers the third quarter.\n\n" +
+		"\\# of units sold rose.\n\n" +
+		"- Revenue up\n- Costs down\n\n" +
+		"2. Hiring | paused\n\n" +
+		"| Region | Sales |\n| --- | --- |\n| North | 1,200 |\n| South | 9\\|00 |\n\n" +
+		"### Notes\n"
+	assert.Equal(t, expected, out)
+}
+
+func TestToMarkdownImage_Empty(t *testing.T) {
+	out, err := ToMarkdownImage(&OCRImageResult{Width: 10, Height: 10})
+	require.NoError(t, err)
+	assert.Empty(t, out)
+
+	_, err = ToMarkdownImage(nil)
+	assert.Error(t, err)
+}
+
+func TestToMarkdownPDF(t *testing.T) {
+	page := func(text string) OCRPDFPageResult {
+		return OCRPDFPageResult{Width: 200, Height: 200, Images: []OCRPDFImageResult{{
+			Width: 100, Height: 100, Regions: []OCRRegionResult{layoutRegion(text, 10, 10, 50, 10)},
+		}}}
+	}
+	res := &OCRPDFResult{Pages: []OCRPDFPageResult{page("First"), {Width: 200, Height: 200}, page("Second")}}
+
+	out, err := ToMarkdownPDF(res)
+	require.NoError(t, err)
+	assert.Equal(t, "First\n\n---\n\nSecond\n", out)
+}
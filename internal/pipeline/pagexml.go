@@ -0,0 +1,141 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"strings"
+	"time"
+)
+
+// pageXMLNamespace is the PAGE content schema namespace (version 2019-07-15).
+const pageXMLNamespace = "http://schema.primaresearch.org/PAGE/gts/pagecontent/2019-07-15"
+
+// ToPageXMLImage serializes a single image result as a PAGE XML document.
+// source is recorded as the page's image file name.
+func ToPageXMLImage(res *OCRImageResult, source string, meta ExportMetadata) (string, error) {
+	if res == nil {
+		return "", errors.New("nil result")
+	}
+	pages := imageLayoutPages([]*OCRImageResult{res}, []string{source})
+	return renderPageXML(pages[0], meta), nil
+}
+
+// ToPageXMLPDF serializes a PDF result as PAGE XML. PAGE describes a single page
+// per document, so one document is returned per processed PDF page; the image file
+// name is the PDF file name with a "#page=N" fragment.
+func ToPageXMLPDF(res *OCRPDFResult, meta ExportMetadata) ([]string, error) {
+	if res == nil {
+		return nil, errors.New("nil result")
+	}
+	pages := pdfLayoutPages([]*OCRPDFResult{res})
+	docs := make([]string, len(pages))
+	for i, page := range pages {
+		page.source = fmt.Sprintf("%s#page=%d", page.source, res.Pages[i].PageNumber)
+		docs[i] = renderPageXML(page, meta)
+	}
+	return docs, nil
+}
+
+func renderPageXML(page layoutPage, meta ExportMetadata) string {
+	meta = meta.withDefaults()
+	creator := meta.Software
+	if meta.Version != "" {
+		creator += " " + meta.Version
+	}
+	created := meta.Created.Format(time.RFC3339)
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<PcGts xmlns="` + pageXMLNamespace + `" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" ` +
+		`xsi:schemaLocation="` + pageXMLNamespace + ` ` + pageXMLNamespace + `/pagecontent.xsd">
+  <Metadata>
+`)
+	fmt.Fprintf(&b, "    <Creator>%s</Creator>\n    <Created>%s</Created>\n    <LastChange>%s</LastChange>\n",
+		html.EscapeString(creator), created, created)
+	fmt.Fprintf(&b, "    <MetadataItem type=\"processingStep\" name=\"ocr\" value=\"%s\" date=\"%s\">\n",
+		html.EscapeString(meta.Software), created)
+	b.WriteString("      <Labels>\n")
+	fmt.Fprintf(&b, "        <Label type=\"measurement-unit\" value=\"pixel\"/>\n")
+	if meta.DetectionModel != "" {
+		fmt.Fprintf(&b, "        <Label type=\"detection-model\" value=\"%s\"/>\n", html.EscapeString(meta.DetectionModel))
+	}
+	if meta.RecognitionModel != "" {
+		fmt.Fprintf(&b, "        <Label type=\"recognition-model\" value=\"%s\"/>\n", html.EscapeString(meta.RecognitionModel))
+	}
+	b.WriteString("      </Labels>\n    </MetadataItem>\n  </Metadata>\n")
+
+	fmt.Fprintf(&b, "  <Page imageFilename=\"%s\" imageWidth=\"%d\" imageHeight=\"%d\">\n",
+		html.EscapeString(page.source), page.width, page.height)
+	for regionNo, set := range page.regions {
+		writePageXMLRegion(&b, fmt.Sprintf("r%d", regionNo+1), set)
+	}
+	b.WriteString("  </Page>\n</PcGts>\n")
+	return b.String()
+}
+
+func writePageXMLRegion(b *strings.Builder, id string, set layoutRegionSet) {
+	var lines strings.Builder
+	var texts []string
+	var confSum float64
+	x0, y0, x1, y1 := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, r := range set.regions {
+		words := splitRegionWords(r)
+		if len(words) == 0 {
+			continue
+		}
+		pts := regionPolygon(r, set.sx, set.sy)
+		for _, p := range pts {
+			x0, y0 = math.Min(x0, p.X), math.Min(y0, p.Y)
+			x1, y1 = math.Max(x1, p.X), math.Max(y1, p.Y)
+		}
+		text := strings.TrimSpace(r.Text)
+		texts = append(texts, text)
+		confSum += r.RecConfidence
+		writePageXMLLine(&lines, fmt.Sprintf("%s_l%d", id, len(texts)), r, text, pts, words, set.sx, set.sy)
+	}
+	if len(texts) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "    <TextRegion id=\"%s\" type=\"paragraph\">\n", id)
+	fmt.Fprintf(b, "      <Coords points=\"%s\"/>\n", formatPoints(rectPoints(x0, y0, x1, y1)))
+	b.WriteString(lines.String())
+	writePageXMLTextEquiv(b, "      ", strings.Join(texts, "\n"), confSum/float64(len(texts)))
+	b.WriteString("    </TextRegion>\n")
+}
+
+func writePageXMLLine(b *strings.Builder, id string, r OCRRegionResult, text string,
+	pts []struct{ X, Y float64 }, words []regionWord, sx, sy float64,
+) {
+	fmt.Fprintf(b, "      <TextLine id=\"%s\">\n", id)
+	fmt.Fprintf(b, "        <Coords points=\"%s\"/>\n", formatPoints(pts))
+	fmt.Fprintf(b, "        <Baseline points=\"%s\"/>\n", formatPoints(pageXMLBaseline(r, sx, sy)))
+	for i, w := range words {
+		fmt.Fprintf(b, "        <Word id=\"%s_w%d\">\n", id, i+1)
+		fmt.Fprintf(b, "          <Coords points=\"%s\"/>\n",
+			formatPoints(rectPoints(w.X*sx, w.Y*sy, (w.X+w.W)*sx, (w.Y+w.H)*sy)))
+		writePageXMLTextEquiv(b, "          ", w.Text, wordConfidence(r, w))
+		b.WriteString("        </Word>\n")
+	}
+	writePageXMLTextEquiv(b, "        ", text, r.RecConfidence)
+	b.WriteString("      </TextLine>\n")
+}
+
+func writePageXMLTextEquiv(b *strings.Builder, indent, text string, conf float64) {
+	fmt.Fprintf(b, "%s<TextEquiv conf=\"%.3f\">\n%s  <Unicode>%s</Unicode>\n%s</TextEquiv>\n",
+		indent, math.Max(0, math.Min(1, conf)), indent, html.EscapeString(text), indent)
+}
+
+// pageXMLBaseline follows the bottom edge of a four-point detection polygon, or
+// the bottom of the bounding box otherwise.
+func pageXMLBaseline(r OCRRegionResult, sx, sy float64) []struct{ X, Y float64 } {
+	if len(r.Polygon) == 4 {
+		return []struct{ X, Y float64 }{
+			{r.Polygon[3].X * sx, r.Polygon[3].Y * sy},
+			{r.Polygon[2].X * sx, r.Polygon[2].Y * sy},
+		}
+	}
+	y := float64(r.Box.Y+r.Box.H) * sy
+	return []struct{ X, Y float64 }{{float64(r.Box.X) * sx, y}, {float64(r.Box.X+r.Box.W) * sx, y}}
+}
@@ -0,0 +1,102 @@
+package pipeline
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pageXMLTestPoints struct {
+	Points string `xml:"points,attr"`
+}
+
+type pageXMLTestDoc struct {
+	Metadata struct {
+		Creator string `xml:"Creator"`
+		Created string `xml:"Created"`
+		Labels  []struct {
+			Type  string `xml:"type,attr"`
+			Value string `xml:"value,attr"`
+		} `xml:"MetadataItem>Labels>Label"`
+	} `xml:"Metadata"`
+	Page struct {
+		ImageFilename string `xml:"imageFilename,attr"`
+		ImageWidth    int    `xml:"imageWidth,attr"`
+		Regions       []struct {
+			Coords pageXMLTestPoints `xml:"Coords"`
+			Lines  []struct {
+				Coords   pageXMLTestPoints `xml:"Coords"`
+				Baseline pageXMLTestPoints `xml:"Baseline"`
+				Words    []struct {
+					Coords    pageXMLTestPoints `xml:"Coords"`
+					TextEquiv struct {
+						Conf    float64 `xml:"conf,attr"`
+						Unicode string  `xml:"Unicode"`
+					} `xml:"TextEquiv"`
+				} `xml:"Word"`
+				Unicode string `xml:"TextEquiv>Unicode"`
+			} `xml:"TextLine"`
+			Unicode string `xml:"TextEquiv>Unicode"`
+		} `xml:"TextRegion"`
+	} `xml:"Page"`
+}
+
+func TestToPageXMLImage(t *testing.T) {
+	res := sampleResult()
+	res.Regions[1].Text = "World <2>"
+	res.Regions[1].Polygon = []struct{ X, Y float64 }{{70, 38}, {140, 40}, {140, 62}, {70, 60}}
+
+	doc, err := ToPageXMLImage(res, "scan.png", testExportMetadata())
+	require.NoError(t, err)
+	validateAgainstSchema(t, "pagecontent-2019-07-15-subset.xsd", doc)
+
+	var pc pageXMLTestDoc
+	require.NoError(t, xml.Unmarshal([]byte(doc), &pc))
+	assert.Equal(t, "pogo 1.2.3", pc.Metadata.Creator)
+	assert.Equal(t, "2024-05-06T07:08:09Z", pc.Metadata.Created)
+	assert.Contains(t, pc.Metadata.Labels, struct {
+		Type  string `xml:"type,attr"`
+		Value string `xml:"value,attr"`
+	}{Type: "detection-model", Value: "det.onnx"})
+
+	assert.Equal(t, "scan.png", pc.Page.ImageFilename)
+	assert.Equal(t, 200, pc.Page.ImageWidth)
+	require.Len(t, pc.Page.Regions, 1)
+	region := pc.Page.Regions[0]
+	assert.Equal(t, "10,10 140,10 140,62 10,62", region.Coords.Points)
+	assert.Equal(t, "Hello\nWorld <2>", region.Unicode)
+	require.Len(t, region.Lines, 2)
+	line := region.Lines[1]
+	assert.Equal(t, "70,38 140,40 140,62 70,60", line.Coords.Points)
+	assert.Equal(t, "70,60 140,62", line.Baseline.Points)
+	require.Len(t, line.Words, 2)
+	assert.Equal(t, "<2>", line.Words[1].TextEquiv.Unicode)
+	assert.InDelta(t, 0.85, line.Words[1].TextEquiv.Conf, 1e-9)
+	assert.Equal(t, "World <2>", line.Unicode)
+}
+
+func TestToPageXMLPDF(t *testing.T) {
+	res := &OCRPDFResult{Filename: "doc.pdf", Pages: []OCRPDFPageResult{
+		{PageNumber: 3, Width: 400, Height: 200, Images: []OCRPDFImageResult{{Width: 200, Height: 100, Regions: sampleResult().Regions}}},
+		{PageNumber: 4, Width: 400, Height: 200},
+	}}
+
+	docs, err := ToPageXMLPDF(res, testExportMetadata())
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	for _, doc := range docs {
+		validateAgainstSchema(t, "pagecontent-2019-07-15-subset.xsd", doc)
+	}
+
+	var pc pageXMLTestDoc
+	require.NoError(t, xml.Unmarshal([]byte(docs[0]), &pc))
+	assert.Equal(t, "doc.pdf#page=3", pc.Page.ImageFilename)
+	require.Len(t, pc.Page.Regions, 1)
+	assert.Equal(t, "20,20 120,20 120,60 20,60", pc.Page.Regions[0].Lines[0].Coords.Points)
+	assert.Equal(t, "20,20 120,20 120,60 20,60", pc.Page.Regions[0].Lines[0].Words[0].Coords.Points)
+
+	_, err = ToPageXMLImage(nil, "", ExportMetadata{})
+	assert.Error(t, err)
+}
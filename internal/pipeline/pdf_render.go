@@ -0,0 +1,104 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/MeKo-Tech/pogo/internal/pdf"
+)
+
+// PDF input modes.
+const (
+	// PDFModeRender rasterizes whole pages and OCRs them as rendered.
+	PDFModeRender = pdf.PageModeRender
+	// PDFModeExtract OCRs the embedded images of each page individually.
+	PDFModeExtract = pdf.PageModeExtract
+)
+
+// PDFConfig controls how PDF pages are turned into images for OCR.
+type PDFConfig struct {
+	Mode     string           // PDFModeRender (default) or PDFModeExtract
+	DPI      int              // render resolution (0 = pdf.DefaultRenderDPI)
+	Renderer pdf.PageRenderer // nil = pure-Go pdf.NativeRenderer
+	// MaxInFlightPages caps the pages held in memory while a PDF is
+	// processed (0 = one per worker).
+	MaxInFlightPages int
+}
+
+// DefaultPDFConfig returns the default PDF config (native page rendering).
+func DefaultPDFConfig() PDFConfig {
+	return PDFConfig{Mode: PDFModeRender, DPI: pdf.DefaultRenderDPI}
+}
+
+// WithPDFMode selects page rendering or embedded-image extraction for PDFs.
+func (b *Builder) WithPDFMode(mode string) *Builder {
+	if mode != "" {
+		b.cfg.PDF.Mode = mode
+	}
+	return b
+}
+
+// WithPDFRenderDPI sets the resolution PDF pages are rendered at.
+func (b *Builder) WithPDFRenderDPI(dpi int) *Builder {
+	if dpi > 0 {
+		b.cfg.PDF.DPI = dpi
+	}
+	return b
+}
+
+// WithPDFRenderer replaces the native page renderer, e.g. with a pdf.CommandRenderer.
+func (b *Builder) WithPDFRenderer(r pdf.PageRenderer) *Builder {
+	b.cfg.PDF.Renderer = r
+	return b
+}
+
+// WithPDFMaxInFlightPages bounds how many PDF pages are loaded at once.
+func (b *Builder) WithPDFMaxInFlightPages(n int) *Builder {
+	if n > 0 {
+		b.cfg.PDF.MaxInFlightPages = n
+	}
+	return b
+}
+
+func validatePDFConfig(cfg PDFConfig) error {
+	switch cfg.Mode {
+	case "", PDFModeRender, PDFModeExtract:
+		return nil
+	}
+	return fmt.Errorf("invalid PDF mode %q (want %s or %s)", cfg.Mode, PDFModeRender, PDFModeExtract)
+}
+
+// openPDFPages prepares loading the selected pages one at a time.
+func (p *Pipeline) openPDFPages(filename, pageRange string) (*pdf.PageLoader, error) {
+	return pdf.OpenPages(filename, pageRange, p.cfg.PDF.Mode, pdf.RenderOptions{
+		DPI:      p.cfg.PDF.DPI,
+		Renderer: p.cfg.PDF.Renderer,
+	})
+}
+
+// placeRegions adds page coordinates to the regions of an OCRed PDF image and
+// splits them into words, which are placed on the page as well.
+func placeRegions(regions []OCRRegionResult, m pdf.PageMapper) {
+	for i := range regions {
+		r := &regions[i]
+		var coords pdf.PageCoords
+		if len(r.Polygon) > 0 {
+			poly := make([]pdf.PagePoint, len(r.Polygon))
+			for j, pt := range r.Polygon {
+				poly[j] = pdf.PagePoint{X: pt.X, Y: pt.Y}
+			}
+			coords = m.Map(poly)
+		} else {
+			coords = m.MapBox(float64(r.Box.X), float64(r.Box.Y), float64(r.Box.W), float64(r.Box.H))
+		}
+		r.Page = &coords
+
+		words := splitRegionWords(*r)
+		r.Words = make([]OCRWordResult, 0, len(words))
+		for _, w := range words {
+			wc := m.MapBox(w.X, w.Y, w.W, w.H)
+			word := OCRWordResult{Text: w.Text, Confidence: wordConfidence(*r, w), Page: &wc}
+			word.Box.X, word.Box.Y, word.Box.W, word.Box.H = w.X, w.Y, w.W, w.H
+			r.Words = append(r.Words, word)
+		}
+	}
+}
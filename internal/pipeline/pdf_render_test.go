@@ -0,0 +1,104 @@
+package pipeline
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MeKo-Tech/pogo/internal/pdf"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder_PDFOptions(t *testing.T) {
+	b := NewBuilder()
+	assert.Equal(t, PDFModeRender, b.Config().PDF.Mode)
+	assert.Equal(t, pdf.DefaultRenderDPI, b.Config().PDF.DPI)
+
+	renderer := pdf.CommandRenderer{Command: "mutool"}
+	b.WithPDFMode(PDFModeExtract).WithPDFRenderDPI(300).WithPDFRenderer(renderer).WithPDFMaxInFlightPages(4)
+	cfg := b.Config()
+	assert.Equal(t, PDFModeExtract, cfg.PDF.Mode)
+	assert.Equal(t, 300, cfg.PDF.DPI)
+	assert.Equal(t, renderer, cfg.PDF.Renderer)
+	assert.Equal(t, 4, cfg.PDF.MaxInFlightPages)
+
+	b.WithPDFRenderDPI(0).WithPDFMode("").WithPDFMaxInFlightPages(0)
+	assert.Equal(t, 300, b.Config().PDF.DPI)
+	assert.Equal(t, 4, b.Config().PDF.MaxInFlightPages)
+	assert.Equal(t, PDFModeExtract, b.Config().PDF.Mode)
+
+	require.NoError(t, b.validateConfiguration())
+	b.WithPDFMode("vector")
+	require.Error(t, b.validateConfiguration())
+}
+
+func TestPipeline_openPDFPages(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 300, 150))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	img.SetGray(10, 10, color.Gray{})
+	var buf bytes.Buffer
+	require.NoError(t, pdf.WriteSearchablePDFFromImages(&buf, []image.Image{img}, nil, pdf.SearchableOptions{DPI: 150}))
+	path := filepath.Join(t.TempDir(), "page.pdf")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+
+	p := &Pipeline{cfg: DefaultConfig()}
+	p.cfg.PDF.DPI = 100
+	loadPage := func() *pdf.PageSet {
+		l, err := p.openPDFPages(path, "")
+		require.NoError(t, err)
+		require.Equal(t, []int{1}, l.Pages())
+		set, err := l.Load(1)
+		require.NoError(t, err)
+		require.Len(t, set.Images, 1)
+		return set
+	}
+	// 300px at 150 DPI is 2 inches wide: 200px when rendered at 100 DPI.
+	rendered := loadPage().Images[0]
+	assert.Equal(t, image.Rect(0, 0, 200, 100), rendered.Image.Bounds())
+	x, y := rendered.Placement.Apply(200, 100)
+	assert.InDelta(t, 144, x, 1e-6)
+	assert.InDelta(t, 0, y, 1e-6)
+
+	p.cfg.PDF.Mode = PDFModeExtract
+	extracted := loadPage().Images[0]
+	assert.Equal(t, 300, extracted.Image.Bounds().Dx())
+	x, y = extracted.Placement.Apply(0, 0)
+	assert.InDelta(t, 0, x, 1e-6)
+	assert.InDelta(t, 72, y, 1e-6)
+}
+
+func TestPlaceRegions(t *testing.T) {
+	// A 200x100 pixel image covering a 144x72 pt page.
+	placement := pdf.Matrix{0.72, 0, 0, -0.72, 0, 72}
+	m := pdf.NewPageMapper(placement, *types.NewRectangle(0, 0, 144, 72), 0)
+
+	r := OCRRegionResult{Text: "ab cd", RecConfidence: 0.9}
+	r.Box = struct{ X, Y, W, H int }{X: 0, Y: 50, W: 100, H: 20}
+	r.Polygon = []struct{ X, Y float64 }{{0, 50}, {100, 50}, {100, 70}, {0, 70}}
+	regions := []OCRRegionResult{r}
+	placeRegions(regions, m)
+
+	page := regions[0].Page
+	require.NotNil(t, page)
+	assert.InDelta(t, 0, page.Box.X, 1e-6)
+	assert.InDelta(t, 72-70*0.72, page.Box.Y, 1e-6)
+	assert.InDelta(t, 72, page.Box.W, 1e-6)
+	assert.InDelta(t, 14.4, page.Box.H, 1e-6)
+	assert.InDelta(t, 36, page.BoxTopLeft.Y, 1e-6)
+	assert.Len(t, page.Polygon, 4)
+
+	require.Len(t, regions[0].Words, 2)
+	cd := regions[0].Words[1]
+	assert.Equal(t, "cd", cd.Text)
+	assert.InDelta(t, 0.9, cd.Confidence, 1e-9)
+	require.NotNil(t, cd.Page)
+	assert.InDelta(t, 60*0.72, cd.Page.BoxTopLeft.X, 1e-6)
+	assert.InDelta(t, 40*0.72, cd.Page.BoxTopLeft.W, 1e-6)
+}
@@ -0,0 +1,68 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// PDF stream event types.
+const (
+	PDFEventPage     = "page"
+	PDFEventDocument = "document"
+	PDFEventError    = "error"
+)
+
+// PDFStreamEvent is one line of an NDJSON PDF result stream. A stream holds a
+// "page" event per page in page order, followed by one "document" event with
+// the totals (without pages) or an "error" event if processing failed.
+type PDFStreamEvent struct {
+	Type     string            `json:"type"`
+	Filename string            `json:"filename"`
+	Page     *OCRPDFPageResult `json:"page,omitempty"`
+	Document *OCRPDFResult     `json:"document,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// PDFStreamWriter writes PDF stream events as newline-delimited JSON. The
+// underlying writer is flushed after every event if it supports flushing,
+// e.g. a bufio.Writer or an http.ResponseWriter.
+type PDFStreamWriter struct {
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewPDFStreamWriter creates a stream writer for w.
+func NewPDFStreamWriter(w io.Writer) *PDFStreamWriter {
+	return &PDFStreamWriter{w: w, enc: json.NewEncoder(w)}
+}
+
+// WritePage writes a "page" event.
+func (sw *PDFStreamWriter) WritePage(filename string, page *OCRPDFPageResult) error {
+	return sw.write(PDFStreamEvent{Type: PDFEventPage, Filename: filename, Page: page})
+}
+
+// WriteDocument writes the closing "document" event of a file. Pages are
+// omitted since they have already been streamed.
+func (sw *PDFStreamWriter) WriteDocument(res *OCRPDFResult) error {
+	doc := *res
+	doc.Pages = nil
+	return sw.write(PDFStreamEvent{Type: PDFEventDocument, Filename: res.Filename, Document: &doc})
+}
+
+// WriteError writes an "error" event for a file that could not be finished.
+func (sw *PDFStreamWriter) WriteError(filename string, err error) error {
+	return sw.write(PDFStreamEvent{Type: PDFEventError, Filename: filename, Error: err.Error()})
+}
+
+func (sw *PDFStreamWriter) write(ev PDFStreamEvent) error {
+	if err := sw.enc.Encode(ev); err != nil {
+		return err
+	}
+	switch f := sw.w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package pipeline
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPDFStreamWriter(t *testing.T) {
+	var buf bytes.Buffer
+	bw := bufio.NewWriterSize(&buf, 4096)
+	sw := NewPDFStreamWriter(bw)
+
+	require.NoError(t, sw.WritePage("a.pdf", &OCRPDFPageResult{PageNumber: 1}))
+	// Every event is flushed through to the underlying writer.
+	assert.Equal(t, 1, strings.Count(buf.String(), "\n"))
+
+	doc := &OCRPDFResult{Filename: "a.pdf", TotalPages: 1, Pages: []OCRPDFPageResult{{PageNumber: 1}}}
+	require.NoError(t, sw.WriteDocument(doc))
+	assert.Len(t, doc.Pages, 1, "the caller's result is not modified")
+	require.NoError(t, sw.WriteError("b.pdf", errors.New("boom")))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	var events []PDFStreamEvent
+	for _, line := range lines {
+		var ev PDFStreamEvent
+		require.NoError(t, json.Unmarshal([]byte(line), &ev))
+		events = append(events, ev)
+	}
+	assert.Equal(t, PDFEventPage, events[0].Type)
+	assert.Equal(t, 1, events[0].Page.PageNumber)
+	assert.Equal(t, PDFEventDocument, events[1].Type)
+	assert.Equal(t, "a.pdf", events[1].Filename)
+	assert.Equal(t, 1, events[1].Document.TotalPages)
+	assert.Empty(t, events[1].Document.Pages)
+	assert.Equal(t, PDFStreamEvent{Type: PDFEventError, Filename: "b.pdf", Error: "boom"}, events[2])
+	assert.NotContains(t, lines[0], `"document"`)
+}
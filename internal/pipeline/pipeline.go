@@ -30,6 +30,9 @@ type Config struct {
 
     // Barcode detection configuration
     Barcode BarcodeConfig
+
+    // PDF page rendering configuration
+    PDF PDFConfig
 }
 
 // DefaultConfig returns a default pipeline config with component defaults.
@@ -46,6 +49,7 @@ func DefaultConfig() Config {
         Parallel:            DefaultParallelConfig(),
         Resource:            DefaultResourceConfig(),
         Barcode:             DefaultBarcodeConfig(),
+        PDF:                 DefaultPDFConfig(),
     }
 }
 
@@ -442,6 +446,13 @@ func (b *Builder) WithGPUMemoryLimit(limitBytes uint64) *Builder {
 // Config returns a copy of the current config.
 func (b *Builder) Config() Config { return b.cfg }
 
+// WithConfig replaces the whole configuration, e.g. with one derived from
+// DefaultConfig. Model paths are resolved against ModelsDir on Build.
+func (b *Builder) WithConfig(cfg Config) *Builder {
+	b.cfg = cfg
+	return b
+}
+
 // Validate checks that model files exist and configuration looks sane.
 func (b *Builder) Validate() error {
 	// Ensure model paths are updated for the current models dir
@@ -505,7 +516,7 @@ func (b *Builder) validateConfiguration() error {
 	if b.cfg.Recognizer.ImageHeight <= 0 {
 		return errors.New("recognizer image height must be > 0")
 	}
-	return nil
+	return validatePDFConfig(b.cfg.PDF)
 }
 
 // Pipeline wires together the detector and recognizer.
@@ -616,18 +627,28 @@ func (b *Builder) setupRectification(p *Pipeline) {
 }
 
 func (b *Builder) performWarmup(p *Pipeline) error {
-	if b.cfg.WarmupIterations > 0 {
-		slog.Debug("Starting model warmup", "iterations", b.cfg.WarmupIterations)
-		if err := p.Detector.Warmup(b.cfg.WarmupIterations); err != nil {
-			_ = p.Close()
-			return fmt.Errorf("detector warmup failed: %w", err)
-		}
-		if err := p.Recognizer.Warmup(b.cfg.WarmupIterations); err != nil {
-			_ = p.Close()
-			return fmt.Errorf("recognizer warmup failed: %w", err)
-		}
-		slog.Debug("Model warmup completed", "iterations", b.cfg.WarmupIterations)
+	if err := p.Warmup(b.cfg.WarmupIterations); err != nil {
+		_ = p.Close()
+		return err
+	}
+	return nil
+}
+
+// Warmup runs the detector and recognizer iterations times on dummy input,
+// so that the first requests do not pay for lazy initialization of the
+// inference sessions.
+func (p *Pipeline) Warmup(iterations int) error {
+	if iterations <= 0 {
+		return nil
+	}
+	slog.Debug("Starting model warmup", "iterations", iterations)
+	if err := p.Detector.Warmup(iterations); err != nil {
+		return fmt.Errorf("detector warmup failed: %w", err)
+	}
+	if err := p.Recognizer.Warmup(iterations); err != nil {
+		return fmt.Errorf("recognizer warmup failed: %w", err)
 	}
+	slog.Debug("Model warmup completed", "iterations", iterations)
 	return nil
 }
 
@@ -291,3 +291,15 @@ func TestPipeline_Info_MapShape(t *testing.T) {
 	assert.False(t, hasDet)
 	assert.False(t, hasRec)
 }
+
+func TestBuilder_WithConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Recognizer.Language = "de"
+	cfg.Barcode.Enabled = true
+	cfg.Detector.DbThresh = 0.42
+
+	got := NewBuilder().WithConfig(cfg).Config()
+	assert.Equal(t, "de", got.Recognizer.Language)
+	assert.True(t, got.Barcode.Enabled)
+	assert.InDelta(t, 0.42, got.Detector.DbThresh, 1e-6)
+}
@@ -6,6 +6,8 @@ import (
 	"image"
 	"log/slog"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ProcessImage runs detection then recognition on a single image.
@@ -14,7 +16,9 @@ func (p *Pipeline) ProcessImage(img image.Image) (*OCRImageResult, error) {
 }
 
 // ProcessImageContext is like ProcessImage but allows cancellation via context.
-func (p *Pipeline) ProcessImageContext(ctx context.Context, img image.Image) (*OCRImageResult, error) {
+// If the context ends after detection, it returns the partial result marked
+// as truncated together with the context's error.
+func (p *Pipeline) ProcessImageContext(ctx context.Context, img image.Image) (result *OCRImageResult, err error) {
 	if p == nil || p.Detector == nil || p.Recognizer == nil {
 		return nil, errors.New("pipeline not initialized")
 	}
@@ -24,6 +28,9 @@ func (p *Pipeline) ProcessImageContext(ctx context.Context, img image.Image) (*O
 
 	bounds := img.Bounds()
 	slog.Debug("Starting image processing", "width", bounds.Dx(), "height", bounds.Dy())
+	ctx, span := startSpan(ctx, "pipeline.process_image",
+		attribute.Int("image.width", bounds.Dx()), attribute.Int("image.height", bounds.Dy()))
+	defer func() { endSpan(span, err) }()
 
 	totalStart := time.Now()
 
@@ -45,15 +52,22 @@ func (p *Pipeline) ProcessImageContext(ctx context.Context, img image.Image) (*O
 		return nil, err
 	}
 
-	// Perform recognition
+	// Perform recognition. If the context ends first, the detected regions
+	// are returned without text as a truncated result.
 	recResults, recNs, err := p.performRecognition(ctx, working, regions)
 	if err != nil {
-		return nil, err
+		if ctx.Err() == nil {
+			return nil, err
+		}
+		result = p.buildImageResult(img, regions, nil, appliedAngle, appliedConf, detNs, 0,
+			time.Since(totalStart).Nanoseconds())
+		result.Truncated = true
+		return result, err
 	}
 
 	// Build final result
 	totalNs := time.Since(totalStart).Nanoseconds()
-	result := p.buildImageResult(img, regions, recResults, appliedAngle, appliedConf, detNs, recNs, totalNs)
+	result = p.buildImageResult(img, regions, recResults, appliedAngle, appliedConf, detNs, recNs, totalNs)
 
 	slog.Debug("Image processing completed",
 		"total_duration_ms", result.Processing.TotalNs/1000000,
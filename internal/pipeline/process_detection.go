@@ -9,6 +9,7 @@ import (
 
 	"github.com/MeKo-Tech/pogo/internal/detector"
 	"github.com/MeKo-Tech/pogo/internal/recognizer"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // performDetection runs text detection on the image.
@@ -17,11 +18,16 @@ func (p *Pipeline) performDetection(ctx context.Context, img image.Image) ([]det
 		return nil, 0, err
 	}
 	slog.Debug("Starting text detection")
+	_, span := startSpan(ctx, "pipeline.detect")
 	detStart := time.Now()
 	regions, err := p.Detector.DetectRegions(img)
 	if err != nil {
-		return nil, 0, fmt.Errorf("detection failed: %w", err)
+		err = fmt.Errorf("detection failed: %w", err)
+		endSpan(span, err)
+		return nil, 0, err
 	}
+	span.SetAttributes(attribute.Int("detector.regions", len(regions)))
+	endSpan(span, nil)
 	detNs := time.Since(detStart).Nanoseconds()
 	slog.Debug("Text detection completed", "regions_found", len(regions), "duration_ms", detNs/1000000)
 	return regions, detNs, nil
@@ -38,11 +44,16 @@ func (p *Pipeline) performRecognition(ctx context.Context, img image.Image,
 			return nil, 0, err
 		}
 		slog.Debug("Starting text recognition", "regions_count", len(regions))
+		// All regions of an image are recognized as one batch
+		_, span := startSpan(ctx, "pipeline.recognize", attribute.Int("recognizer.batch_size", len(regions)))
 		var err error
 		recResults, err = p.Recognizer.RecognizeBatch(img, regions)
 		if err != nil {
-			return nil, 0, fmt.Errorf("recognition failed: %w", err)
+			err = fmt.Errorf("recognition failed: %w", err)
+			endSpan(span, err)
+			return nil, 0, err
 		}
+		endSpan(span, nil)
 		slog.Debug("Text recognition completed", "duration_ms", time.Since(recStart).Nanoseconds()/1000000)
 	} else {
 		slog.Debug("No text regions detected, skipping recognition")
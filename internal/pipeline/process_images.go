@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"image"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ProcessImages processes multiple images sequentially and returns results.
@@ -16,6 +18,14 @@ func (p *Pipeline) ProcessImagesContext(ctx context.Context, images []image.Imag
 	if len(images) == 0 {
 		return nil, errors.New("no images provided")
 	}
+	ctx, span := startSpan(ctx, "pipeline.process_images", attribute.Int("images", len(images)))
+	results, err := p.processImages(ctx, images)
+	endSpan(span, err)
+	return results, err
+}
+
+// processImages is ProcessImagesContext within its span.
+func (p *Pipeline) processImages(ctx context.Context, images []image.Image) ([]*OCRImageResult, error) {
 
 	orientationResults, workingImages, err := p.prepareOrientation(ctx, images)
 	if err != nil {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/MeKo-Tech/pogo/internal/orientation"
 	"github.com/MeKo-Tech/pogo/internal/utils"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // applyOrientationDetection applies orientation detection and rotation if enabled.
@@ -21,7 +22,11 @@ func (p *Pipeline) applyOrientationDetection(ctx context.Context, img image.Imag
 			return nil, 0, 0, err
 		}
 		slog.Debug("Running orientation detection")
-		if res, err := p.Orienter.Predict(img); err == nil {
+		_, span := startSpan(ctx, "pipeline.orientation")
+		res, err := p.Orienter.Predict(img)
+		if err == nil {
+			span.SetAttributes(attribute.Int("orientation.angle", res.Angle),
+				attribute.Float64("orientation.confidence", res.Confidence))
 			appliedConf = res.Confidence
 			switch res.Angle {
 			case 90:
@@ -43,6 +48,7 @@ func (p *Pipeline) applyOrientationDetection(ctx context.Context, img image.Imag
 		} else {
 			slog.Debug("Orientation detection failed", "error", err)
 		}
+		endSpan(span, err)
 	}
 
 	return working, appliedAngle, appliedConf, nil
@@ -55,7 +61,11 @@ func (p *Pipeline) applyRectification(ctx context.Context, img image.Image) (ima
 			return nil, err
 		}
 		slog.Debug("Running document rectification")
-		if rxImg, err := p.Rectifier.Apply(img); err == nil && rxImg != nil {
+		_, span := startSpan(ctx, "pipeline.rectify")
+		rxImg, err := p.Rectifier.Apply(img)
+		span.SetAttributes(attribute.Bool("rectify.applied", err == nil && rxImg != nil))
+		endSpan(span, err)
+		if err == nil && rxImg != nil {
 			slog.Debug("Document rectification applied")
 			return rxImg, nil
 		} else if err != nil {
@@ -81,7 +91,9 @@ func (p *Pipeline) prepareOrientation(ctx context.Context,
 	}
 
 	// Use batch orientation detection for better performance
+	_, span := startSpan(ctx, "pipeline.orientation", attribute.Int("images", len(images)))
 	results, err := p.Orienter.BatchPredict(images)
+	endSpan(span, err)
 	if err != nil {
 		slog.Debug("Batch orientation detection failed, falling back to individual processing", "error", err)
 		// Fall back to individual processing
@@ -135,7 +147,12 @@ func (p *Pipeline) processImagesWithOrientation(ctx context.Context, originalIma
 // processSingleImage processes a single image through the full OCR pipeline.
 func (p *Pipeline) processSingleImage(ctx context.Context, originalImg, workingImg image.Image,
     orientationResult orientation.Result,
-) (*OCRImageResult, error) {
+) (res *OCRImageResult, err error) {
+	ob := originalImg.Bounds()
+	ctx, span := startSpan(ctx, "pipeline.process_image",
+		attribute.Int("image.width", ob.Dx()), attribute.Int("image.height", ob.Dy()))
+	defer func() { endSpan(span, err) }()
+
 	// Apply rectification to working image
 	rectifiedImg, err := p.applyRectification(ctx, workingImg)
 	if err != nil {
@@ -159,8 +176,10 @@ func (p *Pipeline) processSingleImage(ctx context.Context, originalImg, workingI
     var _barNs int64
     if p.barcodeDecoder != nil && p.cfg.Barcode.Enabled {
         // Use original bounds and detected orientation for coordinate mapping back
-        ob := originalImg.Bounds()
-        bs, ns, berr := p.barcodeDecoder.Decode(ctx, rectifiedImg, p.cfg.Barcode, orientationResult.Angle, ob.Dx(), ob.Dy())
+        bctx, bspan := startSpan(ctx, "pipeline.barcodes")
+        bs, ns, berr := p.barcodeDecoder.Decode(bctx, rectifiedImg, p.cfg.Barcode, orientationResult.Angle, ob.Dx(), ob.Dy())
+        bspan.SetAttributes(attribute.Int("barcodes", len(bs)))
+        endSpan(bspan, berr)
         _ = berr // silent if decoder absent/unavailable
         barcodes, _barNs = bs, ns
         _ = _barNs
@@ -170,7 +189,7 @@ func (p *Pipeline) processSingleImage(ctx context.Context, originalImg, workingI
     totalNs := detNs + recNs // Simplified total for batch processing
     appliedAngle := orientationResult.Angle
     appliedConf := orientationResult.Confidence
-    res := p.buildImageResult(originalImg, regions, recResults, appliedAngle, appliedConf, detNs, recNs, totalNs)
+    res = p.buildImageResult(originalImg, regions, recResults, appliedAngle, appliedConf, detNs, recNs, totalNs)
     if len(barcodes) > 0 {
         res.Barcodes = barcodes
     }
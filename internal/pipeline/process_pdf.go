@@ -4,13 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"image"
-	"runtime"
-	"sort"
-	"sync"
 	"time"
 
 	"github.com/MeKo-Tech/pogo/internal/pdf"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ProcessPDF processes a PDF file and returns OCR results for all pages.
@@ -19,7 +16,31 @@ func (p *Pipeline) ProcessPDF(filename string, pageRange string) (*OCRPDFResult,
 }
 
 // ProcessPDFContext processes a PDF file with context cancellation support.
+// If the context ends before all pages are done, it returns the pages
+// finished so far in a result marked as truncated together with the
+// context's error.
 func (p *Pipeline) ProcessPDFContext(ctx context.Context, filename string, pageRange string) (*OCRPDFResult, error) {
+	var pages []OCRPDFPageResult
+	result, err := p.ProcessPDFStream(ctx, filename, pageRange, func(page *OCRPDFPageResult) error {
+		pages = append(pages, *page)
+		return nil
+	})
+	if result != nil {
+		result.Pages = pages
+	}
+	return result, err
+}
+
+// ProcessPDFStream renders (or extracts) and OCRs a PDF one page at a time and
+// calls emit with each page result in page order as soon as it is ready. At
+// most PDF.MaxInFlightPages pages are held in memory at once. The returned
+// result carries the totals but no pages. If emit returns an error,
+// processing stops and that error is returned. If ctx ends first, the totals
+// of the pages emitted so far are returned, marked as truncated, along with
+// the error.
+func (p *Pipeline) ProcessPDFStream(ctx context.Context, filename string, pageRange string,
+	emit func(*OCRPDFPageResult) error,
+) (_ *OCRPDFResult, err error) {
 	if filename == "" {
 		return nil, errors.New("filename cannot be empty")
 	}
@@ -28,111 +49,81 @@ func (p *Pipeline) ProcessPDFContext(ctx context.Context, filename string, pageR
 	}
 
 	totalStart := time.Now()
+	ctx, span := startSpan(ctx, "pipeline.process_pdf")
+	defer func() { endSpan(span, err) }()
 
-	// Extract images from PDF
-	extractStart := time.Now()
-	pageImages, err := pdf.ExtractImages(filename, pageRange)
+	loader, err := p.openPDFPages(filename, pageRange)
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract images from PDF: %w", err)
-	}
-	extractNs := time.Since(extractStart).Nanoseconds()
-
-	// Process each page concurrently with a worker pool
-	type job struct {
-		page   int
-		images []image.Image
-	}
-	type out struct {
-		page int
-		res  *OCRPDFPageResult
-		err  error
-	}
-
-	// Collect page numbers to keep order stable
-	pageList := make([]int, 0, len(pageImages))
-	for n := range pageImages {
-		pageList = append(pageList, n)
+		return nil, err
 	}
-
-	jobs := make(chan job, len(pageImages))
-	results := make(chan out, len(pageImages))
+	span.SetAttributes(attribute.Int("pdf.pages", len(loader.Pages())))
 
 	// Decide workers: use Resource.MaxGoroutines if set, else NumCPU
-	workers := p.cfg.Resource.MaxGoroutines
-	if workers <= 0 {
-		workers = runtime.NumCPU()
-	}
-	if workers > len(pageList) {
-		workers = len(pageList)
-	}
-	if workers < 1 {
-		workers = 1
-	}
-
-	var wg sync.WaitGroup
-	for range workers {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for j := range jobs {
-				if err := ctx.Err(); err != nil {
-					results <- out{page: j.page, res: nil, err: err}
-					continue
-				}
-				r, err := p.processPDFPage(ctx, j.page, j.images)
-				results <- out{page: j.page, res: r, err: err}
-			}
-		}()
-	}
-
-	for _, n := range pageList {
-		jobs <- job{page: n, images: pageImages[n]}
-	}
-	close(jobs)
-	go func() { wg.Wait(); close(results) }()
-
-	pageMap := make(map[int]*OCRPDFPageResult)
-	for r := range results {
-		if r.err != nil {
-			return nil, r.err
+	opts := pdf.StreamOptions{
+		Workers:     p.cfg.Resource.MaxGoroutines,
+		MaxInFlight: p.cfg.PDF.MaxInFlightPages,
+	}
+	progress := ProgressFromContext(ctx)
+	pageProgress, _ := progress.(PageProgressCallback)
+	total := len(loader.Pages())
+	if progress != nil {
+		progress.OnStart(total)
+	}
+	count := 0
+	err = pdf.StreamPages(ctx, loader, opts, func(ctx context.Context, set *pdf.PageSet) (*OCRPDFPageResult, error) {
+		if pageProgress != nil {
+			pageProgress.OnPageStart(set.PageNumber)
 		}
-		pageMap[r.page] = r.res
-	}
-
-	sort.Ints(pageList)
-	pages := make([]OCRPDFPageResult, 0, len(pageList))
-	for _, n := range pageList {
-		if pr, ok := pageMap[n]; ok {
-			pages = append(pages, *pr)
+		return p.processPDFPage(ctx, set.PageNumber, set)
+	}, func(_ int, page *OCRPDFPageResult) error {
+		count++
+		if err := emit(page); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress.OnProgress(count, total)
+		}
+		return nil
+	})
+	if progress != nil {
+		if err != nil {
+			progress.OnError(count, err)
+		} else {
+			progress.OnComplete()
 		}
 	}
-
-	totalNs := time.Since(totalStart).Nanoseconds()
+	if err != nil && ctx.Err() == nil {
+		return nil, err
+	}
 
 	result := &OCRPDFResult{
 		Filename:   filename,
-		TotalPages: len(pages),
-		Pages:      pages,
+		TotalPages: count,
 		Processing: struct {
 			ExtractionNs int64 `json:"extraction_ns"`
 			TotalNs      int64 `json:"total_ns"`
 		}{
-			ExtractionNs: extractNs,
-			TotalNs:      totalNs,
+			ExtractionNs: loader.LoadTime().Nanoseconds(),
+			TotalNs:      time.Since(totalStart).Nanoseconds(),
 		},
+		Truncated: err != nil,
 	}
 
-	return result, nil
+	return result, err
 }
 
 // processPDFPage processes all images from a single PDF page.
-func (p *Pipeline) processPDFPage(ctx context.Context, pageNum int, images []image.Image) (*OCRPDFPageResult, error) {
+func (p *Pipeline) processPDFPage(ctx context.Context, pageNum int, set *pdf.PageSet) (_ *OCRPDFPageResult, err error) {
 	pageStart := time.Now()
+	ctx, span := startSpan(ctx, "pipeline.pdf_page",
+		attribute.Int("pdf.page", pageNum), attribute.Int("pdf.images", len(set.Images)))
+	defer func() { endSpan(span, err) }()
 
-	imageResults := make([]OCRPDFImageResult, 0, len(images))
+	imageResults := make([]OCRPDFImageResult, 0, len(set.Images))
 	var pageWidth, pageHeight int
 
-	for i, img := range images {
+	for i, pi := range set.Images {
+		img := pi.Image
 		if err := ctx.Err(); err != nil {
 			return nil, err
 		}
@@ -161,18 +152,25 @@ func (p *Pipeline) processPDFPage(ctx context.Context, pageNum int, images []ima
 			Regions:    ocrResult.Regions,
 			Barcodes:   ocrResult.Barcodes,
 			Confidence: ocrResult.AvgDetConf,
+			Placement:  &pi.Placement,
 		}
+		placeRegions(imageResult.Regions, set.Mapper(i))
 
 		imageResults = append(imageResults, imageResult)
 	}
 
 	pageNs := time.Since(pageStart).Nanoseconds()
 
+	widthPt, heightPt := set.Size()
 	pageResult := &OCRPDFPageResult{
 		PageNumber: pageNum,
 		Width:      pageWidth,
 		Height:     pageHeight,
+		WidthPt:    widthPt,
+		HeightPt:   heightPt,
+		Rotate:     set.Rotate,
 		Images:     imageResults,
+		Warnings:   set.Warnings,
 		Processing: struct {
 			TotalNs int64 `json:"total_ns"`
 		}{
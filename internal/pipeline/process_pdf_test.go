@@ -9,7 +9,9 @@ import (
 	"time"
 
 	"github.com/MeKo-Tech/pogo/internal/orientation"
+	"github.com/MeKo-Tech/pogo/internal/pdf"
 	"github.com/MeKo-Tech/pogo/internal/testutil"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -73,7 +75,9 @@ func TestProcessPDF(t *testing.T) {
 			result, err := p.ProcessPDF(tt.filename, tt.pageRange)
 
 			if tt.expectError {
-				require.Error(t, err)
+				require.ErrorIs(t, err, context.Canceled)
+				require.NotNil(t, result, "canceled processing returns the partial result")
+				assert.True(t, result.Truncated)
 				return
 			}
 
@@ -185,6 +189,15 @@ func TestProcessPDFContext_NilChecks(t *testing.T) {
 	}
 }
 
+// testPageSet wraps images as a Letter-sized page, each image placed with a 1:1 scale.
+func testPageSet(pageNum int, images []image.Image) *pdf.PageSet {
+	set := &pdf.PageSet{PageNumber: pageNum, Box: *types.RectForFormat("Letter")}
+	for _, img := range images {
+		set.Images = append(set.Images, pdf.PageImage{Image: img, Placement: pdf.Matrix{1, 0, 0, -1, 0, 792}})
+	}
+	return set
+}
+
 // TestProcessPDFPage tests the processPDFPage method.
 func TestProcessPDFPage(t *testing.T) {
 	b := NewBuilder()
@@ -243,7 +256,7 @@ func TestProcessPDFPage(t *testing.T) {
 			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 			defer cancel()
 
-			result, err := p.processPDFPage(ctx, tt.pageNum, images)
+			result, err := p.processPDFPage(ctx, tt.pageNum, testPageSet(tt.pageNum, images))
 
 			if tt.expectError {
 				require.Error(t, err)
@@ -299,7 +312,7 @@ func TestProcessPDFPage_ContextCancellation(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	_, err = p.processPDFPage(ctx, 1, images)
+	_, err = p.processPDFPage(ctx, 1, testPageSet(1, images))
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "context")
 }
@@ -316,7 +329,9 @@ func TestProcessPDFPage_EmptyImages(t *testing.T) {
 	}()
 
 	ctx := context.Background()
-	result, err := p.processPDFPage(ctx, 1, []image.Image{})
+	set := testPageSet(1, nil)
+	set.Warnings = []string{"image Im1: jbig2: text regions are not supported"}
+	result, err := p.processPDFPage(ctx, 1, set)
 	if err != nil {
 		t.Skipf("page processing failed (runtime deps): %v", err)
 	}
@@ -325,6 +340,7 @@ func TestProcessPDFPage_EmptyImages(t *testing.T) {
 	require.NotNil(t, result)
 	assert.Equal(t, 1, result.PageNumber)
 	assert.Empty(t, result.Images)
+	assert.Equal(t, set.Warnings, result.Warnings)
 }
 
 // TestProcessImagesWithOrientation tests the processImagesWithOrientation method.
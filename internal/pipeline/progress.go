@@ -26,6 +26,33 @@ type ProgressCallback interface {
 	OnError(current int, err error)
 }
 
+// PageProgressCallback is a ProgressCallback that is also told when OCR of
+// a PDF page starts. Pages are processed concurrently, so OnPageStart may be
+// called from several goroutines at once.
+type PageProgressCallback interface {
+	ProgressCallback
+
+	// OnPageStart is called when OCR of a page begins.
+	OnPageStart(pageNum int)
+}
+
+type progressContextKey struct{}
+
+// ContextWithProgress returns a copy of ctx that reports the progress of the
+// PDF processed with it to callback: OnStart with the number of pages,
+// OnProgress as each page is emitted, and OnComplete or OnError at the end.
+// Unlike Config.Parallel.ProgressCallback it applies to a single call, so
+// that a shared pipeline can report to each caller separately.
+func ContextWithProgress(ctx context.Context, callback ProgressCallback) context.Context {
+	return context.WithValue(ctx, progressContextKey{}, callback)
+}
+
+// ProgressFromContext returns the callback set by ContextWithProgress, or nil.
+func ProgressFromContext(ctx context.Context) ProgressCallback {
+	callback, _ := ctx.Value(progressContextKey{}).(ProgressCallback)
+	return callback
+}
+
 // NoOpProgressCallback implements ProgressCallback but does nothing.
 // Useful as a default when no progress reporting is needed.
 type NoOpProgressCallback struct{}
@@ -2,6 +2,7 @@ package pipeline
 
 import (
 	"bytes"
+	"context"
 	"log/slog"
 	"testing"
 	"time"
@@ -18,6 +19,14 @@ func TestNoOpProgressCallback(t *testing.T) {
 	callback.OnError(3, assert.AnError)
 }
 
+func TestContextWithProgress(t *testing.T) {
+	assert.Nil(t, ProgressFromContext(context.Background()))
+
+	callback := NewConsoleProgressCallback(&bytes.Buffer{}, "")
+	ctx := ContextWithProgress(context.Background(), callback)
+	assert.Same(t, callback, ProgressFromContext(ctx))
+}
+
 func TestConsoleProgressCallback(t *testing.T) {
 	var buf bytes.Buffer
 	callback := NewConsoleProgressCallback(&buf, "Test: ")
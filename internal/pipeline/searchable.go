@@ -0,0 +1,81 @@
+package pipeline
+
+import "github.com/MeKo-Tech/pogo/internal/pdf"
+
+// ToTextLayerImage converts an image result into a text layer page for
+// searchable PDF output. Words are positioned in the image's pixel space.
+func ToTextLayerImage(res *OCRImageResult, pageNumber int) pdf.TextLayerPage {
+	page := pdf.TextLayerPage{PageNumber: pageNumber}
+	if res == nil {
+		return page
+	}
+	page.Width = float64(res.Width)
+	page.Height = float64(res.Height)
+	page.Words = appendTextLayerWords(nil, res.Regions, 1, 1)
+	return page
+}
+
+// ToTextLayerPDF converts a PDF result into one text layer page per processed page.
+// Words are positioned by their page coordinates, in points on the page as
+// displayed. Results without page coordinates fall back to assuming that every
+// image covers its page.
+func ToTextLayerPDF(res *OCRPDFResult) []pdf.TextLayerPage {
+	if res == nil {
+		return nil
+	}
+	pages := make([]pdf.TextLayerPage, 0, len(res.Pages))
+	for _, p := range res.Pages {
+		page := pdf.TextLayerPage{PageNumber: p.PageNumber, Width: p.WidthPt, Height: p.HeightPt}
+		if p.WidthPt <= 0 || p.HeightPt <= 0 {
+			page.Width, page.Height = float64(p.Width), float64(p.Height)
+		}
+		for _, img := range p.Images {
+			if img.Width <= 0 || img.Height <= 0 {
+				continue
+			}
+			sx := page.Width / float64(img.Width)
+			sy := page.Height / float64(img.Height)
+			for _, r := range img.Regions {
+				if words, ok := placedTextLayerWords(r); ok && p.WidthPt > 0 {
+					page.Words = append(page.Words, words...)
+					continue
+				}
+				page.Words = appendTextLayerWords(page.Words, []OCRRegionResult{r}, sx, sy)
+			}
+		}
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// placedTextLayerWords returns the words of r at their page coordinates, or
+// false if r has not been placed on its page.
+func placedTextLayerWords(r OCRRegionResult) ([]pdf.TextLayerWord, bool) {
+	if len(r.Words) == 0 {
+		return nil, false
+	}
+	words := make([]pdf.TextLayerWord, 0, len(r.Words))
+	for _, w := range r.Words {
+		if w.Page == nil {
+			return nil, false
+		}
+		box := w.Page.BoxTopLeft
+		words = append(words, pdf.TextLayerWord{Text: w.Text, X: box.X, Y: box.Y, W: box.W, H: box.H})
+	}
+	return words, true
+}
+
+func appendTextLayerWords(words []pdf.TextLayerWord, regions []OCRRegionResult, sx, sy float64) []pdf.TextLayerWord {
+	for _, r := range regions {
+		for _, w := range splitRegionWords(r) {
+			words = append(words, pdf.TextLayerWord{
+				Text: w.Text,
+				X:    w.X * sx,
+				Y:    w.Y * sy,
+				W:    w.W * sx,
+				H:    w.H * sy,
+			})
+		}
+	}
+	return words
+}
@@ -0,0 +1,90 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/MeKo-Tech/pogo/internal/pdf"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToTextLayerImage(t *testing.T) {
+	page := ToTextLayerImage(sampleResult(), 3)
+	assert.Equal(t, 3, page.PageNumber)
+	assert.InDelta(t, 200.0, page.Width, 1e-9)
+	assert.InDelta(t, 100.0, page.Height, 1e-9)
+	require.Len(t, page.Words, 2)
+	assert.Equal(t, "Hello", page.Words[0].Text)
+	assert.InDelta(t, 10.0, page.Words[0].X, 1e-9)
+	assert.InDelta(t, 50.0, page.Words[0].W, 1e-9)
+}
+
+func TestToTextLayerPDF_ScalesImagesToPage(t *testing.T) {
+	res := &OCRPDFResult{Pages: []OCRPDFPageResult{{
+		PageNumber: 2,
+		Width:      200,
+		Height:     100,
+		Images: []OCRPDFImageResult{
+			{Width: 200, Height: 100, Regions: sampleResult().Regions[:1]},
+			{Width: 100, Height: 50, Regions: sampleResult().Regions[1:]},
+		},
+	}}}
+	pages := ToTextLayerPDF(res)
+	require.Len(t, pages, 1)
+	assert.Equal(t, 2, pages[0].PageNumber)
+	require.Len(t, pages[0].Words, 2)
+	assert.InDelta(t, 140.0, pages[0].Words[1].X, 1e-9)
+	assert.InDelta(t, 80.0, pages[0].Words[1].Y, 1e-9)
+	assert.InDelta(t, 140.0, pages[0].Words[1].W, 1e-9)
+}
+
+// placedPDFImage returns an image result whose regions are placed on a page
+// with the given box and rotation, as processing a PDF does.
+func placedPDFImage(w, h int, regions []OCRRegionResult, placement pdf.Matrix, box types.Rectangle, rotate int) OCRPDFImageResult {
+	regions = append([]OCRRegionResult(nil), regions...)
+	placeRegions(regions, pdf.NewPageMapper(placement, box, rotate))
+	return OCRPDFImageResult{Width: w, Height: h, Regions: regions, Placement: &placement}
+}
+
+func TestToTextLayerPDF_RotatedPage(t *testing.T) {
+	// A 200x100 pt page with /Rotate 90 is shown 100 pt wide and 200 pt high;
+	// rendered at 144 DPI its image is 200x400 px and maps pixel (x, y) to
+	// user space (y/2, x/2).
+	img := placedPDFImage(200, 400, sampleResult().Regions[:1], pdf.Matrix{0, 0.5, 0.5, 0, 0, 0}, *types.RectForDim(200, 100), 90)
+	res := &OCRPDFResult{Pages: []OCRPDFPageResult{{
+		PageNumber: 1, Width: 200, Height: 400, WidthPt: 100, HeightPt: 200, Rotate: 90,
+		Images: []OCRPDFImageResult{img},
+	}}}
+
+	pages := ToTextLayerPDF(res)
+	require.Len(t, pages, 1)
+	assert.InDelta(t, 100.0, pages[0].Width, 1e-9)
+	assert.InDelta(t, 200.0, pages[0].Height, 1e-9)
+	require.Len(t, pages[0].Words, 1)
+	w := pages[0].Words[0]
+	assert.Equal(t, "Hello", w.Text)
+	assert.InDelta(t, 5.0, w.X, 1e-9)
+	assert.InDelta(t, 5.0, w.Y, 1e-9)
+	assert.InDelta(t, 25.0, w.W, 1e-9)
+	assert.InDelta(t, 10.0, w.H, 1e-9)
+}
+
+func TestToTextLayerPDF_PartialImage(t *testing.T) {
+	// A 100x50 px image drawn at half size into the bottom right quarter
+	// of a 200x100 pt page.
+	img := placedPDFImage(100, 50, sampleResult().Regions[:1], pdf.Matrix{0.5, 0, 0, -0.5, 100, 25}, *types.RectForDim(200, 100), 0)
+	res := &OCRPDFResult{Pages: []OCRPDFPageResult{{
+		PageNumber: 1, Width: 100, Height: 50, WidthPt: 200, HeightPt: 100,
+		Images: []OCRPDFImageResult{img},
+	}}}
+
+	pages := ToTextLayerPDF(res)
+	require.Len(t, pages, 1)
+	require.Len(t, pages[0].Words, 1)
+	w := pages[0].Words[0]
+	assert.InDelta(t, 105.0, w.X, 1e-9)
+	assert.InDelta(t, 80.0, w.Y, 1e-9)
+	assert.InDelta(t, 25.0, w.W, 1e-9)
+	assert.InDelta(t, 10.0, w.H, 1e-9)
+}
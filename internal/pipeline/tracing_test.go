@@ -0,0 +1,75 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/MeKo-Tech/pogo/internal/testutil"
+	"github.com/MeKo-Tech/pogo/internal/tracing/tracingtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestEndSpan(t *testing.T) {
+	spans := tracingtest.Install(t)
+
+	ctx, parent := startSpan(context.Background(), "parent")
+	_, child := startSpan(ctx, "child")
+	endSpan(child, errors.New("boom"))
+	endSpan(parent, nil)
+
+	got := spans.GetSpans()
+	require.Len(t, got, 2)
+	assert.Equal(t, "child", got[0].Name)
+	assert.Equal(t, parent.SpanContext().SpanID(), got[0].Parent.SpanID())
+	assert.Equal(t, codes.Error, got[0].Status.Code)
+	assert.Equal(t, "boom", got[0].Status.Description)
+	require.Len(t, got[0].Events, 1, "the error is recorded")
+	assert.Equal(t, codes.Unset, got[1].Status.Code)
+}
+
+func TestProcessImages_Spans(t *testing.T) {
+	p, err := NewBuilder().Build()
+	if err != nil {
+		t.Skipf("pipeline build failed: %v", err)
+	}
+	defer func() { _ = p.Close() }()
+
+	cfg := testutil.DefaultTestImageConfig()
+	cfg.Size = testutil.ImageSize{Width: 300, Height: 100}
+	cfg.Text = "Tracing Test"
+	cfg.Background = color.White
+	cfg.Foreground = color.Black
+	img, err := testutil.GenerateTextImage(cfg)
+	require.NoError(t, err)
+
+	spans := tracingtest.Install(t)
+	_, err = p.ProcessImageContext(context.Background(), img)
+	require.NoError(t, err)
+	_, err = p.ProcessImagesContext(context.Background(), []image.Image{img, img})
+	require.NoError(t, err)
+
+	got := spans.GetSpans()
+	root := tracingtest.Find(got, "pipeline.process_image")
+	require.NotNil(t, root)
+	assert.False(t, root.Parent.IsValid(), "a call without a span in its context starts a trace")
+	for _, name := range []string{"pipeline.detect", "pipeline.recognize"} {
+		span := tracingtest.Find(got, name)
+		require.NotNil(t, span, name)
+		assert.Equal(t, root.SpanContext.SpanID(), span.Parent.SpanID(), name)
+	}
+
+	batch := tracingtest.Find(got, "pipeline.process_images")
+	require.NotNil(t, batch)
+	var images int
+	for _, s := range got {
+		if s.Name == "pipeline.process_image" && s.Parent.SpanID() == batch.SpanContext.SpanID() {
+			images++
+		}
+	}
+	assert.Equal(t, 2, images)
+}
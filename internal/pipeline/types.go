@@ -1,5 +1,7 @@
 package pipeline
 
+import "github.com/MeKo-Tech/pogo/internal/pdf"
+
 // OCRRegionResult combines detection geometry with recognition output.
 type OCRRegionResult struct {
 	// Geometry and detection
@@ -14,6 +16,10 @@ type OCRRegionResult struct {
 	Rotated         bool      `json:"rotated"`
 	Language        string    `json:"language,omitempty"`
 
+	// PDF placement (PDF results only): the region and its words on the page
+	Page  *pdf.PageCoords `json:"page,omitempty"`
+	Words []OCRWordResult `json:"words,omitempty"`
+
 	// Timing
 	Timing struct {
 		RecognizePreprocessNs int64 `json:"recognize_preprocess_ns"`
@@ -23,6 +29,15 @@ type OCRRegionResult struct {
 	} `json:"timing"`
 }
 
+// OCRWordResult is a word of a recognized region. Box is in image pixels and
+// estimated from the character positions within the line.
+type OCRWordResult struct {
+	Text       string                       `json:"text"`
+	Confidence float64                      `json:"confidence"`
+	Box        struct{ X, Y, W, H float64 } `json:"box"`
+	Page       *pdf.PageCoords              `json:"page,omitempty"`
+}
+
 // OCRImageResult is the per-image aggregated OCR output.
 type OCRImageResult struct {
     Width       int               `json:"width"`
@@ -40,6 +55,9 @@ type OCRImageResult struct {
 		RecognitionNs int64 `json:"recognition_ns"`
 		TotalNs       int64 `json:"total_ns"`
 	} `json:"processing"`
+	// Truncated marks a partial result: processing was canceled or hit its
+	// deadline after detection, so regions carry no recognized text.
+	Truncated bool `json:"truncated,omitempty"`
 }
 
 // OCRPDFResult represents the OCR result for a PDF document.
@@ -51,6 +69,9 @@ type OCRPDFResult struct {
 		ExtractionNs int64 `json:"extraction_ns"`
 		TotalNs      int64 `json:"total_ns"`
 	} `json:"processing"`
+	// Truncated marks a partial result: processing was canceled or hit its
+	// deadline, and only the pages finished before that are included.
+	Truncated bool `json:"truncated,omitempty"`
 }
 
 // OCRPDFPageResult represents OCR results for a single PDF page.
@@ -58,7 +79,13 @@ type OCRPDFPageResult struct {
 	PageNumber int                 `json:"page_number"`
 	Width      int                 `json:"width"`
 	Height     int                 `json:"height"`
+	// Displayed page size in points (crop box with /Rotate applied) and rotation
+	WidthPt    float64             `json:"width_pt,omitempty"`
+	HeightPt   float64             `json:"height_pt,omitempty"`
+	Rotate     int                 `json:"rotate,omitempty"`
 	Images     []OCRPDFImageResult `json:"images"`
+	// Warnings lists page content that could not be decoded or rendered.
+	Warnings   []string `json:"warnings,omitempty"`
 	Processing struct {
 		TotalNs int64 `json:"total_ns"`
 	} `json:"processing"`
@@ -72,6 +99,8 @@ type OCRPDFImageResult struct {
     Regions    []OCRRegionResult `json:"regions"`
     Barcodes   []BarcodeResult   `json:"barcodes,omitempty"`
     Confidence float64           `json:"confidence"`
+    // Placement maps image pixels to PDF user space (points, origin bottom-left).
+    Placement  *pdf.Matrix       `json:"placement,omitempty"`
 }
 
 // BarcodeResult represents a decoded barcode in image coordinates.
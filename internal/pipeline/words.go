@@ -0,0 +1,75 @@
+package pipeline
+
+import "unicode"
+
+// regionWord is a single word of a recognized region with an estimated bounding box.
+// Start and End are rune offsets into the region's text.
+type regionWord struct {
+	Text  string
+	X     float64
+	Y     float64
+	W     float64
+	H     float64
+	Start int
+	End   int
+}
+
+// splitRegionWords splits a region's text on whitespace. The recognizer reports
+// one box per line, so word boxes are estimated by distributing the region width
+// proportionally to the character positions within the line.
+func splitRegionWords(r OCRRegionResult) []regionWord {
+	runes := []rune(r.Text)
+	lo, hi := 0, len(runes)
+	for lo < hi && unicode.IsSpace(runes[lo]) {
+		lo++
+	}
+	for hi > lo && unicode.IsSpace(runes[hi-1]) {
+		hi--
+	}
+	if lo == hi {
+		return nil
+	}
+	charW := float64(r.Box.W) / float64(hi-lo)
+
+	var words []regionWord
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		words = append(words, regionWord{
+			Text:  string(runes[start:end]),
+			X:     float64(r.Box.X) + float64(start-lo)*charW,
+			Y:     float64(r.Box.Y),
+			W:     float64(end-start) * charW,
+			H:     float64(r.Box.H),
+			Start: start,
+			End:   end,
+		})
+		start = -1
+	}
+	for i := lo; i < hi; i++ {
+		if unicode.IsSpace(runes[i]) {
+			flush(i)
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	flush(hi)
+	return words
+}
+
+// wordConfidence returns the mean character confidence of w when per-character
+// confidences are available for the region, otherwise the region confidence.
+func wordConfidence(r OCRRegionResult, w regionWord) float64 {
+	if len(r.CharConfidences) != len([]rune(r.Text)) || w.End <= w.Start {
+		return r.RecConfidence
+	}
+	var sum float64
+	for _, c := range r.CharConfidences[w.Start:w.End] {
+		sum += c
+	}
+	return sum / float64(w.End-w.Start)
+}
@@ -0,0 +1,35 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitRegionWords(t *testing.T) {
+	r := OCRRegionResult{Text: " ab  cd ", Box: struct{ X, Y, W, H int }{X: 10, Y: 5, W: 60, H: 12}}
+	words := splitRegionWords(r)
+	require.Len(t, words, 2)
+	// "ab  cd" has 6 runes -> 10px each.
+	assert.Equal(t, regionWord{Text: "ab", X: 10, Y: 5, W: 20, H: 12, Start: 1, End: 3}, words[0])
+	assert.Equal(t, regionWord{Text: "cd", X: 50, Y: 5, W: 20, H: 12, Start: 5, End: 7}, words[1])
+
+	assert.Empty(t, splitRegionWords(OCRRegionResult{Text: "   "}))
+}
+
+func TestWordConfidence(t *testing.T) {
+	r := OCRRegionResult{
+		Text:            "ab cd",
+		RecConfidence:   0.5,
+		CharConfidences: []float64{0.9, 0.7, 1.0, 0.6, 0.4},
+		Box:             struct{ X, Y, W, H int }{W: 50, H: 10},
+	}
+	words := splitRegionWords(r)
+	require.Len(t, words, 2)
+	assert.InDelta(t, 0.8, wordConfidence(r, words[0]), 1e-9)
+	assert.InDelta(t, 0.5, wordConfidence(r, words[1]), 1e-9)
+
+	r.CharConfidences = r.CharConfidences[:2]
+	assert.InDelta(t, 0.5, wordConfidence(r, words[0]), 1e-9)
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// PipelinesResponse is the response of the pipeline cache admin endpoint.
+type PipelinesResponse struct {
+	Pipelines []CachedPipelineInfo `json:"pipelines"`
+	Count     int                  `json:"count"`
+	// EstimatedMemoryBytes is the estimated memory of all cached pipelines
+	// and MaxMemoryBytes the budget for it (0 = no limit).
+	EstimatedMemoryBytes int64   `json:"estimated_memory_bytes"`
+	MaxMemoryBytes       int64   `json:"max_memory_bytes"`
+	MaxIdleSeconds       float64 `json:"max_idle_seconds"`
+}
+
+// EvictResponse reports how many pipelines were evicted.
+type EvictResponse struct {
+	Evicted int `json:"evicted"`
+}
+
+// adminPipelinesHandler lists the cached pipelines (GET) or evicts all of
+// them (DELETE).
+func (s *Server) adminPipelinesHandler(w http.ResponseWriter, r *http.Request) {
+	if s.pipelineCache == nil {
+		s.writeErrorResponse(w, "Pipeline cache not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		pipelines := s.pipelineCache.Pipelines()
+		s.writeAdminJSON(w, PipelinesResponse{
+			Pipelines:            pipelines,
+			Count:                len(pipelines),
+			EstimatedMemoryBytes: s.pipelineCache.MemoryBytes(),
+			MaxMemoryBytes:       s.pipelineCache.config.MaxMemoryBytes,
+			MaxIdleSeconds:       s.pipelineCache.config.MaxIdle.Seconds(),
+		})
+	case http.MethodDelete:
+		s.writeAdminJSON(w, EvictResponse{Evicted: s.pipelineCache.EvictAll()})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// adminPipelineHandler evicts a cached pipeline (DELETE). Pipelines in use
+// are closed once their requests finish.
+func (s *Server) adminPipelineHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.pipelineCache == nil {
+		s.writeErrorResponse(w, "Pipeline cache not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	if !s.pipelineCache.Evict(r.PathValue("id")) {
+		s.writeErrorResponse(w, "Pipeline not found", http.StatusNotFound)
+		return
+	}
+	s.writeAdminJSON(w, EvictResponse{Evicted: 1})
+}
+
+func (s *Server) writeAdminJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding admin response: %v\n", err)
+	}
+}
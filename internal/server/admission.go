@@ -0,0 +1,342 @@
+package server
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+)
+
+// Priority lanes of admission control. Interactive requests are single
+// images a client waits for; bulk requests are PDFs and batches.
+const (
+	LaneInteractive = "interactive"
+	LaneBulk        = "bulk"
+)
+
+// Admission control defaults.
+const (
+	DefaultInteractiveQueue   = 100
+	DefaultBulkQueue          = 20
+	DefaultInteractiveMaxWait = 5 * time.Second
+	DefaultBulkMaxWait        = 15 * time.Second
+
+	// pressureCheckInterval is how long a memory pressure reading is reused.
+	pressureCheckInterval = time.Second
+)
+
+var (
+	errQueueFull      = errors.New("server is at capacity, try again later")
+	errQueueTimeout   = errors.New("timed out waiting for a processing slot, try again later")
+	errMemoryPressure = errors.New("server is low on memory, try again later")
+)
+
+// AdmissionConfig bounds the OCR requests processed at once. Requests over
+// the limit wait in a queue per lane; when a slot frees up, interactive
+// requests go before bulk ones.
+type AdmissionConfig struct {
+	// MaxConcurrent is the number of requests processed at once across both
+	// lanes (0 = no limit). Admission control is enabled by this limit or by
+	// MemoryLimitBytes.
+	MaxConcurrent int
+	// MaxConcurrentBulk caps the slots bulk requests may take, keeping the
+	// rest for interactive ones (0 = MaxConcurrent).
+	MaxConcurrentBulk int
+	// InteractiveQueue and BulkQueue bound the requests waiting per lane;
+	// further requests are rejected right away (0 = no waiting).
+	InteractiveQueue int
+	BulkQueue        int
+	// InteractiveMaxWait and BulkMaxWait bound the time a request waits for
+	// a slot before it is rejected.
+	InteractiveMaxWait time.Duration
+	BulkMaxWait        time.Duration
+	// MemoryLimitBytes enables load shedding under memory pressure: while
+	// the heap is above the ResourceManager's threshold of this limit, bulk
+	// requests are rejected and interactive ones only run in free slots
+	// (0 = disabled).
+	MemoryLimitBytes uint64
+}
+
+// admissionError is a shed request; retryAfter is a hint for clients.
+type admissionError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *admissionError) Error() string { return e.err.Error() }
+func (e *admissionError) Unwrap() error { return e.err }
+
+// admissionWaiter is a request waiting for a slot.
+type admissionWaiter struct {
+	ready   chan struct{}
+	granted bool
+	elem    *list.Element
+}
+
+// admissionController admits requests into a bounded number of slots.
+type admissionController struct {
+	config AdmissionConfig
+	// pressure reports memory pressure, nil without a memory limit.
+	pressure func() bool
+
+	mu          sync.Mutex
+	running     int
+	runningBulk int
+	queues      map[string]*list.List
+
+	pressureMu      sync.Mutex
+	pressureAt      time.Time
+	pressureReading bool
+}
+
+// newAdmissionController creates a controller for config, filling in
+// defaults. resources, if set, reports memory pressure.
+func newAdmissionController(config AdmissionConfig, resources *pipeline.ResourceManager) *admissionController {
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = math.MaxInt
+	}
+	if config.MaxConcurrentBulk <= 0 || config.MaxConcurrentBulk > config.MaxConcurrent {
+		config.MaxConcurrentBulk = config.MaxConcurrent
+	}
+	if config.InteractiveMaxWait <= 0 {
+		config.InteractiveMaxWait = DefaultInteractiveMaxWait
+	}
+	if config.BulkMaxWait <= 0 {
+		config.BulkMaxWait = DefaultBulkMaxWait
+	}
+	a := &admissionController{
+		config: config,
+		queues: map[string]*list.List{LaneInteractive: list.New(), LaneBulk: list.New()},
+	}
+	if resources != nil {
+		a.pressure = resources.ShouldThrottle
+	}
+	return a
+}
+
+// admit waits for a slot in lane and returns the function that frees it. It
+// fails with an *admissionError when the request is shed, or with the
+// context's error.
+func (a *admissionController) admit(ctx context.Context, lane string) (func(), error) {
+	start := time.Now()
+	underPressure := a.underPressure()
+	if underPressure && lane == LaneBulk {
+		return nil, a.reject(lane, errMemoryPressure, "memory_pressure")
+	}
+
+	a.mu.Lock()
+	queue := a.queues[lane]
+	if queue.Len() == 0 && a.hasSlotLocked(lane) {
+		a.takeSlotLocked(lane)
+		a.mu.Unlock()
+		admissionWaitSeconds.WithLabelValues(lane).Observe(0)
+		return a.releaseFunc(lane), nil
+	}
+	// Under memory pressure nothing queues up behind the running requests.
+	if underPressure {
+		a.mu.Unlock()
+		return nil, a.reject(lane, errMemoryPressure, "memory_pressure")
+	}
+	if queue.Len() >= a.queueSize(lane) {
+		a.mu.Unlock()
+		return nil, a.reject(lane, errQueueFull, "queue_full")
+	}
+	w := &admissionWaiter{ready: make(chan struct{})}
+	w.elem = queue.PushBack(w)
+	admissionQueueDepth.WithLabelValues(lane).Set(float64(queue.Len()))
+	a.mu.Unlock()
+
+	timer := time.NewTimer(a.maxWait(lane))
+	defer timer.Stop()
+	var err error
+	select {
+	case <-w.ready:
+	case <-timer.C:
+		err = a.reject(lane, errQueueTimeout, "timeout")
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		a.mu.Lock()
+		granted := w.granted
+		if !granted {
+			queue.Remove(w.elem)
+			admissionQueueDepth.WithLabelValues(lane).Set(float64(queue.Len()))
+		}
+		a.mu.Unlock()
+		if !granted {
+			return nil, err
+		}
+		// The slot was granted as the wait ended; take it after all.
+	}
+	admissionWaitSeconds.WithLabelValues(lane).Observe(time.Since(start).Seconds())
+	return a.releaseFunc(lane), nil
+}
+
+// LaneStatus is the admission state of a lane.
+type LaneStatus struct {
+	Running   int `json:"running"`
+	Queued    int `json:"queued"`
+	QueueSize int `json:"queue_size"`
+	// Saturated means a new request of the lane would be shed right now:
+	// no slot is free and the queue is full, or memory is under pressure.
+	Saturated bool `json:"saturated"`
+}
+
+// status returns the state of both lanes and whether memory is under
+// pressure.
+func (a *admissionController) status() (map[string]LaneStatus, bool) {
+	underPressure := a.underPressure()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	lanes := make(map[string]LaneStatus, len(a.queues))
+	for lane, queue := range a.queues {
+		running := a.running - a.runningBulk
+		if lane == LaneBulk {
+			running = a.runningBulk
+		}
+		free := queue.Len() == 0 && a.hasSlotLocked(lane)
+		saturated := !free && (underPressure || queue.Len() >= a.queueSize(lane))
+		if lane == LaneBulk && underPressure {
+			saturated = true
+		}
+		lanes[lane] = LaneStatus{
+			Running:   running,
+			Queued:    queue.Len(),
+			QueueSize: a.queueSize(lane),
+			Saturated: saturated,
+		}
+	}
+	return lanes, underPressure
+}
+
+// reject counts a shed request and returns its error.
+func (a *admissionController) reject(lane string, err error, reason string) error {
+	admissionRejections.WithLabelValues(lane, reason).Inc()
+	return &admissionError{err: err, retryAfter: a.maxWait(lane)}
+}
+
+func (a *admissionController) hasSlotLocked(lane string) bool {
+	if a.running >= a.config.MaxConcurrent {
+		return false
+	}
+	return lane != LaneBulk || a.runningBulk < a.config.MaxConcurrentBulk
+}
+
+func (a *admissionController) takeSlotLocked(lane string) {
+	a.running++
+	if lane == LaneBulk {
+		a.runningBulk++
+	}
+	admissionInFlight.WithLabelValues(lane).Inc()
+}
+
+// releaseFunc returns the function freeing a slot of lane, which hands it
+// to the next waiting request, interactive ones first.
+func (a *admissionController) releaseFunc(lane string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			a.running--
+			if lane == LaneBulk {
+				a.runningBulk--
+			}
+			admissionInFlight.WithLabelValues(lane).Dec()
+			a.dispatchLocked()
+		})
+	}
+}
+
+// dispatchLocked grants free slots to waiting requests in priority order.
+func (a *admissionController) dispatchLocked() {
+	for _, lane := range []string{LaneInteractive, LaneBulk} {
+		queue := a.queues[lane]
+		for queue.Len() > 0 && a.hasSlotLocked(lane) {
+			w, _ := queue.Remove(queue.Front()).(*admissionWaiter)
+			w.granted = true
+			a.takeSlotLocked(lane)
+			close(w.ready)
+		}
+		admissionQueueDepth.WithLabelValues(lane).Set(float64(queue.Len()))
+	}
+}
+
+// underPressure reports memory pressure, reusing a reading for
+// pressureCheckInterval so that a burst does not sample (and log) it for
+// every request.
+func (a *admissionController) underPressure() bool {
+	if a.pressure == nil {
+		return false
+	}
+	a.pressureMu.Lock()
+	defer a.pressureMu.Unlock()
+	if time.Since(a.pressureAt) >= pressureCheckInterval {
+		a.pressureReading = a.pressure()
+		a.pressureAt = time.Now()
+	}
+	return a.pressureReading
+}
+
+func (a *admissionController) queueSize(lane string) int {
+	if lane == LaneBulk {
+		return a.config.BulkQueue
+	}
+	return a.config.InteractiveQueue
+}
+
+func (a *admissionController) maxWait(lane string) time.Duration {
+	if lane == LaneBulk {
+		return a.config.BulkMaxWait
+	}
+	return a.config.InteractiveMaxWait
+}
+
+// queueWaitContextKey carries the time a request waited for admission.
+type queueWaitContextKey struct{}
+
+// queueWait returns the time the request of ctx waited for admission.
+func queueWait(ctx context.Context) time.Duration {
+	d, _ := ctx.Value(queueWaitContextKey{}).(time.Duration)
+	return d
+}
+
+// admissionMiddleware admits requests through the admission controller,
+// answering 503 with Retry-After when they are shed.
+func (s *Server) admissionMiddleware(lane string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.probes.checkStarted(); err != nil {
+			w.Header().Set("Retry-After", strconv.Itoa(int(startupRetryAfter.Seconds())))
+			s.writeErrorResponse(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if s.admission == nil {
+			next(w, r)
+			return
+		}
+
+		start := time.Now()
+		release, err := s.admission.admit(r.Context(), lane)
+		if err != nil {
+			var ae *admissionError
+			if errors.As(err, &ae) {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(ae.retryAfter.Seconds()))))
+				s.writeErrorResponse(w, ae.Error(), http.StatusServiceUnavailable)
+			}
+			// Otherwise the client went away while waiting.
+			return
+		}
+		defer release()
+
+		ctx := context.WithValue(r.Context(), queueWaitContextKey{}, time.Since(start))
+		next(w, r.WithContext(ctx))
+	}
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func admitAsync(a *admissionController, ctx context.Context, lane string) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		release, err := a.admit(ctx, lane)
+		if err == nil {
+			release()
+		}
+		done <- err
+	}()
+	return done
+}
+
+// waitQueued waits until n requests wait in lane.
+func waitQueued(t *testing.T, a *admissionController, lane string, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		return a.queues[lane].Len() == n
+	}, time.Second, time.Millisecond)
+}
+
+func TestAdmission_PriorityLanes(t *testing.T) {
+	a := newAdmissionController(AdmissionConfig{
+		MaxConcurrent: 1, InteractiveQueue: 5, BulkQueue: 5, BulkMaxWait: time.Minute, InteractiveMaxWait: time.Minute,
+	}, nil)
+
+	release, err := a.admit(context.Background(), LaneBulk)
+	require.NoError(t, err)
+
+	// A bulk request queued first still goes after an interactive one.
+	var order []string
+	bulkRelease := make(chan func(), 1)
+	go func() {
+		r, err := a.admit(context.Background(), LaneBulk)
+		if err == nil {
+			bulkRelease <- r
+		}
+	}()
+	waitQueued(t, a, LaneBulk, 1)
+	interactive := make(chan func(), 1)
+	go func() {
+		r, err := a.admit(context.Background(), LaneInteractive)
+		if err == nil {
+			interactive <- r
+		}
+	}()
+	waitQueued(t, a, LaneInteractive, 1)
+
+	release()
+	select {
+	case r := <-interactive:
+		order = append(order, LaneInteractive)
+		r()
+	case r := <-bulkRelease:
+		order = append(order, LaneBulk)
+		r()
+	case <-time.After(time.Second):
+		t.Fatal("no request admitted")
+	}
+	select {
+	case r := <-bulkRelease:
+		order = append(order, LaneBulk)
+		r()
+	case <-time.After(time.Second):
+		t.Fatal("bulk request not admitted")
+	}
+	assert.Equal(t, []string{LaneInteractive, LaneBulk}, order)
+}
+
+func TestAdmission_BulkSlots(t *testing.T) {
+	a := newAdmissionController(AdmissionConfig{MaxConcurrent: 2, MaxConcurrentBulk: 1}, nil)
+
+	release, err := a.admit(context.Background(), LaneBulk)
+	require.NoError(t, err)
+	defer release()
+
+	_, err = a.admit(context.Background(), LaneBulk)
+	require.ErrorIs(t, err, errQueueFull, "bulk may not take the last slot")
+	imgRelease, err := a.admit(context.Background(), LaneInteractive)
+	require.NoError(t, err)
+	imgRelease()
+}
+
+func TestAdmission_Shedding(t *testing.T) {
+	a := newAdmissionController(AdmissionConfig{
+		MaxConcurrent: 1, InteractiveQueue: 1, InteractiveMaxWait: 20 * time.Millisecond,
+	}, nil)
+	release, err := a.admit(context.Background(), LaneInteractive)
+	require.NoError(t, err)
+	defer release()
+
+	waiting := admitAsync(a, context.Background(), LaneInteractive)
+	waitQueued(t, a, LaneInteractive, 1)
+
+	_, err = a.admit(context.Background(), LaneInteractive)
+	require.ErrorIs(t, err, errQueueFull)
+	var ae *admissionError
+	require.ErrorAs(t, err, &ae)
+	assert.Equal(t, 20*time.Millisecond, ae.retryAfter)
+
+	require.ErrorIs(t, <-waiting, errQueueTimeout)
+	waitQueued(t, a, LaneInteractive, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waiting = admitAsync(a, ctx, LaneInteractive)
+	waitQueued(t, a, LaneInteractive, 1)
+	cancel()
+	require.ErrorIs(t, <-waiting, context.Canceled)
+}
+
+func TestAdmission_MemoryPressure(t *testing.T) {
+	a := newAdmissionController(AdmissionConfig{MaxConcurrent: 1, InteractiveQueue: 5}, nil)
+	a.pressure = func() bool { return true }
+
+	_, err := a.admit(context.Background(), LaneBulk)
+	require.ErrorIs(t, err, errMemoryPressure)
+
+	release, err := a.admit(context.Background(), LaneInteractive)
+	require.NoError(t, err, "interactive requests still run in free slots")
+	defer release()
+	_, err = a.admit(context.Background(), LaneInteractive)
+	require.ErrorIs(t, err, errMemoryPressure, "but do not queue")
+}
+
+func TestAdmissionMiddleware(t *testing.T) {
+	s := &Server{admission: newAdmissionController(AdmissionConfig{MaxConcurrent: 1, BulkMaxWait: 2 * time.Second}, nil)}
+	release, err := s.admission.admit(context.Background(), LaneBulk)
+	require.NoError(t, err)
+
+	var waited time.Duration
+	h := s.admissionMiddleware(LaneBulk, func(w http.ResponseWriter, r *http.Request) {
+		waited = queueWait(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPost, "/ocr/pdf", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+
+	release()
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPost, "/ocr/pdf", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Less(t, waited, time.Second)
+}
+
+func TestProcessingContext_QueueWait(t *testing.T) {
+	s := &Server{processingTimeout: time.Minute}
+	parent := context.WithValue(context.Background(), queueWaitContextKey{}, 50*time.Second)
+	ctx, cancel := s.processingContext(parent)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(10*time.Second), deadline, time.Second,
+		"waiting for admission counts against the processing timeout")
+	assert.False(t, errors.Is(ctx.Err(), context.DeadlineExceeded))
+}
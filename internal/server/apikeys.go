@@ -0,0 +1,281 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// API key scopes. The admin scope includes all others.
+const (
+	ScopeImage = "image"
+	ScopePDF   = "pdf"
+	ScopeBatch = "batch"
+	ScopeAdmin = "admin"
+)
+
+var validScopes = []string{ScopeImage, ScopePDF, ScopeBatch, ScopeAdmin}
+
+// apiKeyHeader is the alternative to an "Authorization: Bearer" header.
+const apiKeyHeader = "X-API-Key"
+
+var (
+	errMissingAPIKey = errors.New("missing API key")
+	errInvalidAPIKey = errors.New("invalid API key")
+)
+
+// APIKey is a client credential with its scopes and limits. Zero limits
+// inherit the server-wide rate limits.
+type APIKey struct {
+	// Name identifies the key in logs, metrics and job ownership.
+	Name string `yaml:"name"`
+	// Key is the secret in plain text; alternatively KeySHA256 holds its
+	// hex-encoded SHA-256 hash so that the secret is not stored.
+	Key       string   `yaml:"key"`
+	KeySHA256 string   `yaml:"key_sha256"`
+	Scopes    []string `yaml:"scopes"`
+
+	RequestsPerMinute int   `yaml:"requests_per_minute"`
+	RequestsPerHour   int   `yaml:"requests_per_hour"`
+	MaxRequestsPerDay int   `yaml:"max_requests_per_day"`
+	MaxDataPerDay     int64 `yaml:"max_data_per_day"` // in bytes
+}
+
+// HasScope reports whether the key grants scope. A nil key, as seen when
+// authentication is disabled, has every scope.
+func (k *APIKey) HasScope(scope string) bool {
+	return k == nil || slices.Contains(k.Scopes, scope) || slices.Contains(k.Scopes, ScopeAdmin)
+}
+
+// limits returns the rate limits of the key, falling back to defaults.
+func (k *APIKey) limits(defaults RateLimits) RateLimits {
+	limits := defaults
+	if k.RequestsPerMinute > 0 {
+		limits.RequestsPerMinute = k.RequestsPerMinute
+	}
+	if k.RequestsPerHour > 0 {
+		limits.RequestsPerHour = k.RequestsPerHour
+	}
+	if k.MaxRequestsPerDay > 0 {
+		limits.MaxRequestsPerDay = k.MaxRequestsPerDay
+	}
+	if k.MaxDataPerDay > 0 {
+		limits.MaxDataPerDay = k.MaxDataPerDay
+	}
+	return limits
+}
+
+// APIKeys is a set of API keys, looked up by the hash of their secret.
+type APIKeys struct {
+	byHash map[[sha256.Size]byte]*APIKey
+	names  []string
+}
+
+// apiKeysFile is the format of an API key file, YAML or JSON.
+type apiKeysFile struct {
+	Keys []APIKey `yaml:"keys"`
+}
+
+// LoadAPIKeys reads API keys from a YAML or JSON file with a "keys" list, or
+// from all such files (*.yaml, *.yml, *.json) in a directory.
+func LoadAPIKeys(path string) (*APIKeys, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys: %w", err)
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read API key directory: %w", err)
+		}
+		for _, e := range entries {
+			switch strings.ToLower(filepath.Ext(e.Name())) {
+			case ".yaml", ".yml", ".json":
+				if !e.IsDir() {
+					files = append(files, filepath.Join(path, e.Name()))
+				}
+			}
+		}
+	}
+
+	var keys []APIKey
+	for _, file := range files {
+		data, err := os.ReadFile(file) //nolint:gosec // G304: path from server configuration
+		if err != nil {
+			return nil, fmt.Errorf("failed to read API key file: %w", err)
+		}
+		var f apiKeysFile
+		if err := yaml.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("failed to parse API key file %s: %w", file, err)
+		}
+		keys = append(keys, f.Keys...)
+	}
+	apiKeys, err := NewAPIKeys(keys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API keys in %s: %w", path, err)
+	}
+	return apiKeys, nil
+}
+
+// NewAPIKeys validates keys and builds a key set. Names and secrets must be
+// unique and every key needs at least one known scope.
+func NewAPIKeys(keys []APIKey) (*APIKeys, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no API keys defined")
+	}
+	set := &APIKeys{byHash: make(map[[sha256.Size]byte]*APIKey, len(keys))}
+	seen := make(map[string]bool, len(keys))
+	for i := range keys {
+		key := keys[i]
+		if key.Name == "" {
+			return nil, fmt.Errorf("API key %d has no name", i+1)
+		}
+		if seen[key.Name] {
+			return nil, fmt.Errorf("duplicate API key name %q", key.Name)
+		}
+		seen[key.Name] = true
+
+		hash, err := key.hash()
+		if err != nil {
+			return nil, fmt.Errorf("API key %q: %w", key.Name, err)
+		}
+		if _, dup := set.byHash[hash]; dup {
+			return nil, fmt.Errorf("API key %q reuses the secret of another key", key.Name)
+		}
+		if len(key.Scopes) == 0 {
+			return nil, fmt.Errorf("API key %q has no scopes", key.Name)
+		}
+		for _, scope := range key.Scopes {
+			if !slices.Contains(validScopes, scope) {
+				return nil, fmt.Errorf("API key %q has unknown scope %q (valid: %s)",
+					key.Name, scope, strings.Join(validScopes, ", "))
+			}
+		}
+
+		// Do not keep the secret in memory longer than needed.
+		key.Key, key.KeySHA256 = "", ""
+		set.byHash[hash] = &key
+		set.names = append(set.names, key.Name)
+	}
+	sort.Strings(set.names)
+	return set, nil
+}
+
+// hash returns the SHA-256 hash of the key's secret.
+func (k *APIKey) hash() ([sha256.Size]byte, error) {
+	var hash [sha256.Size]byte
+	switch {
+	case k.Key != "" && k.KeySHA256 != "":
+		return hash, errors.New("set either key or key_sha256, not both")
+	case k.Key != "":
+		return sha256.Sum256([]byte(k.Key)), nil
+	case k.KeySHA256 != "":
+		b, err := hex.DecodeString(k.KeySHA256)
+		if err != nil || len(b) != sha256.Size {
+			return hash, errors.New("key_sha256 must be a hex-encoded SHA-256 hash")
+		}
+		copy(hash[:], b)
+		return hash, nil
+	default:
+		return hash, errors.New("no key or key_sha256 set")
+	}
+}
+
+// Names returns the names of all keys, sorted.
+func (ks *APIKeys) Names() []string {
+	return slices.Clone(ks.names)
+}
+
+// named returns the key with the given name, or nil if there is none.
+func (ks *APIKeys) named(name string) *APIKey {
+	for _, key := range ks.byHash {
+		if key.Name == name {
+			return key
+		}
+	}
+	return nil
+}
+
+// authenticate returns the key presented by a request, either as a bearer
+// token or in the X-API-Key header.
+func (ks *APIKeys) authenticate(r *http.Request) (*APIKey, error) {
+	return ks.lookup(r.Header.Get(apiKeyHeader), r.Header.Get("Authorization"))
+}
+
+// lookup returns the key of the given X-API-Key and Authorization values.
+func (ks *APIKeys) lookup(secret, auth string) (*APIKey, error) {
+	if secret == "" && auth != "" {
+		scheme, token, ok := strings.Cut(auth, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return nil, errInvalidAPIKey
+		}
+		secret = strings.TrimSpace(token)
+	}
+	if secret == "" {
+		return nil, errMissingAPIKey
+	}
+	// Looking up the hash rather than the secret keeps the comparison from
+	// leaking the secret through timing.
+	key, ok := ks.byHash[sha256.Sum256([]byte(secret))]
+	if !ok {
+		return nil, errInvalidAPIKey
+	}
+	return key, nil
+}
+
+type apiKeyContextKey struct{}
+
+// apiKeyFromContext returns the authenticated API key of a request, or nil
+// when authentication is disabled.
+func apiKeyFromContext(ctx context.Context) *APIKey {
+	key, _ := ctx.Value(apiKeyContextKey{}).(*APIKey)
+	return key
+}
+
+// authMiddleware authenticates requests by API key when keys are configured
+// and requires the given scope, if any.
+func (s *Server) authMiddleware(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.apiKeys == nil {
+			next(w, r)
+			return
+		}
+
+		key, err := s.apiKeys.authenticate(r)
+		if err != nil {
+			reason := "invalid"
+			if errors.Is(err, errMissingAPIKey) {
+				reason = "missing"
+			}
+			authFailures.WithLabelValues(reason).Inc()
+			w.Header().Set("WWW-Authenticate", `Bearer realm="pogo"`)
+			s.writeErrorResponse(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if scope != "" && !key.HasScope(scope) {
+			apiKeyRejections.WithLabelValues(key.Name, "scope").Inc()
+			s.writeErrorResponse(w, fmt.Sprintf("API key lacks the %q scope", scope), http.StatusForbidden)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key)))
+	}
+}
+
+// canAccessJob reports whether the key of a request may see a job: its
+// owner and admins can, and everyone when authentication is disabled.
+func canAccessJob(key *APIKey, owner string) bool {
+	return key == nil || key.Name == owner || key.HasScope(ScopeAdmin)
+}
@@ -0,0 +1,232 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MeKo-Tech/pogo/internal/jobs"
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAPIKeys(t *testing.T) {
+	hash := sha256.Sum256([]byte("hashed-secret"))
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.yaml"), []byte(`
+keys:
+  - name: alice
+    key: alice-secret
+    scopes: [image, pdf]
+    requests_per_minute: 5
+    max_data_per_day: 1048576
+`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.json"),
+		[]byte(`{"keys":[{"name":"bob","key_sha256":"`+hex.EncodeToString(hash[:])+`","scopes":["admin"]}]}`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("ignored"), 0o600))
+
+	keys, err := LoadAPIKeys(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, keys.Names())
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer alice-secret")
+	key, err := keys.authenticate(req)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", key.Name)
+	assert.Equal(t, 5, key.RequestsPerMinute)
+	assert.Empty(t, key.Key, "the secret is not kept")
+	assert.True(t, key.HasScope(ScopePDF))
+	assert.False(t, key.HasScope(ScopeBatch))
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(apiKeyHeader, "hashed-secret")
+	key, err = keys.authenticate(req)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", key.Name)
+	assert.True(t, key.HasScope(ScopeBatch), "admin has every scope")
+
+	_, err = LoadAPIKeys(filepath.Join(dir, "missing.yaml"))
+	require.Error(t, err)
+}
+
+func TestNewAPIKeys_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []APIKey
+		want string
+	}{
+		{"empty", nil, "no API keys"},
+		{"no name", []APIKey{{Key: "k", Scopes: []string{ScopeImage}}}, "has no name"},
+		{"no secret", []APIKey{{Name: "a", Scopes: []string{ScopeImage}}}, "no key"},
+		{"both secrets", []APIKey{{Name: "a", Key: "k", KeySHA256: "00", Scopes: []string{ScopeImage}}}, "not both"},
+		{"bad hash", []APIKey{{Name: "a", KeySHA256: "xyz", Scopes: []string{ScopeImage}}}, "hex-encoded"},
+		{"no scopes", []APIKey{{Name: "a", Key: "k"}}, "no scopes"},
+		{"unknown scope", []APIKey{{Name: "a", Key: "k", Scopes: []string{"everything"}}}, "unknown scope"},
+		{"duplicate name", []APIKey{
+			{Name: "a", Key: "k1", Scopes: []string{ScopeImage}},
+			{Name: "a", Key: "k2", Scopes: []string{ScopeImage}},
+		}, "duplicate"},
+		{"shared secret", []APIKey{
+			{Name: "a", Key: "k", Scopes: []string{ScopeImage}},
+			{Name: "b", Key: "k", Scopes: []string{ScopeImage}},
+		}, "reuses"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAPIKeys(tt.keys)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+// newAuthTestServer returns a server requiring the keys "img" (image scope,
+// 2 requests per minute), "pdf" (pdf scope) and "root" (admin).
+func newAuthTestServer(t *testing.T) (*Server, http.Handler) {
+	t.Helper()
+	keys, err := NewAPIKeys([]APIKey{
+		{Name: "img", Key: "img-secret", Scopes: []string{ScopeImage}, RequestsPerMinute: 2},
+		{Name: "pdf", Key: "pdf-secret", Scopes: []string{ScopePDF}},
+		{Name: "root", Key: "root-secret", Scopes: []string{ScopeAdmin}},
+	})
+	require.NoError(t, err)
+	s := &Server{
+		pipeline:    &mockPipelineForTesting{processImageResult: &pipeline.OCRImageResult{Width: 20, Height: 20}},
+		maxUploadMB: 10,
+		apiKeys:     keys,
+		rateLimiter: NewRateLimiter(0, 0, 0, 0),
+	}
+	q, err := jobs.Open(jobs.Config{Dir: t.TempDir()}, s.runJob)
+	require.NoError(t, err)
+	s.jobs = q
+	t.Cleanup(func() { _ = s.Close() })
+
+	mux := http.NewServeMux()
+	s.SetupRoutes(mux)
+	return s, mux
+}
+
+func TestAuthMiddleware_ImageEndpoint(t *testing.T) {
+	_, h := newAuthTestServer(t)
+	imgData, err := encodeImageToPNG(createTestImage(20, 20))
+	require.NoError(t, err)
+
+	do := func(header, value string) *httptest.ResponseRecorder {
+		req, err := createMultipartFormRequest(imgData, "a.png", nil)
+		require.NoError(t, err)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+
+	assert.Equal(t, http.StatusUnauthorized, do("Authorization", "Bearer wrong").Code)
+	assert.Equal(t, http.StatusUnauthorized, do("Authorization", "Basic aW1nOg==").Code)
+	assert.Equal(t, http.StatusForbidden, do(apiKeyHeader, "pdf-secret").Code)
+
+	w = do("Authorization", "Bearer img-secret")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("X-RateLimit-Reset"))
+
+	w = do(apiKeyHeader, "img-secret")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+
+	w = do(apiKeyHeader, "img-secret")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// Limits are per key: the admin key is not affected.
+	assert.Equal(t, http.StatusOK, do(apiKeyHeader, "root-secret").Code)
+}
+
+func TestAuthMiddleware_JobOwnership(t *testing.T) {
+	_, h := newAuthTestServer(t)
+	imgData, err := encodeImageToPNG(createTestImage(20, 20))
+	require.NoError(t, err)
+	body, err := json.Marshal(BatchOCRRequest{Images: []BatchImageRequest{{Name: "a.png", Data: imgData}}})
+	require.NoError(t, err)
+
+	do := func(method, path, secret string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(apiKeyHeader, secret)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/jobs", "pdf-secret", body)
+	assert.Equal(t, http.StatusForbidden, w.Code, "an image job needs the image scope")
+
+	w = do(http.MethodPost, "/jobs", "img-secret", body)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var job jobs.Job
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, "img", job.Owner)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/jobs/"+job.ID, "img-secret", nil).Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/jobs/"+job.ID, "root-secret", nil).Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/jobs/"+job.ID, "pdf-secret", nil).Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/jobs/"+job.ID+"/result", "pdf-secret", nil).Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/jobs/"+job.ID, "pdf-secret", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/jobs/"+job.ID, "", nil).Code)
+}
+
+func TestUsageHandler(t *testing.T) {
+	_, h := newAuthTestServer(t)
+	imgData, err := encodeImageToPNG(createTestImage(20, 20))
+	require.NoError(t, err)
+	req, err := createMultipartFormRequest(imgData, "a.png", nil)
+	require.NoError(t, err)
+	req.Header.Set(apiKeyHeader, "img-secret")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	usage := func(secret string) UsageResponse {
+		req := httptest.NewRequest(http.MethodGet, "/usage", nil)
+		req.Header.Set(apiKeyHeader, secret)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp UsageResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	resp := usage("img-secret")
+	require.Len(t, resp.Keys, 1, "keys only see their own usage")
+	assert.Equal(t, "img", resp.Keys[0].Key)
+	assert.Equal(t, 2, resp.Keys[0].RequestsPerMinute)
+	assert.Equal(t, 1, resp.Keys[0].RequestsToday)
+	assert.Equal(t, req.ContentLength, resp.Keys[0].DataToday)
+
+	resp = usage("root-secret")
+	require.Len(t, resp.Keys, 3)
+	assert.Equal(t, []string{"img", "pdf", "root"}, []string{resp.Keys[0].Key, resp.Keys[1].Key, resp.Keys[2].Key})
+}
+
+func TestAPIKey_Limits(t *testing.T) {
+	defaults := RateLimits{RequestsPerMinute: 60, RequestsPerHour: 1000, MaxRequestsPerDay: 5000, MaxDataPerDay: 100}
+	key := &APIKey{RequestsPerMinute: 10, MaxDataPerDay: 1 << 30}
+	assert.Equal(t, RateLimits{RequestsPerMinute: 10, RequestsPerHour: 1000, MaxRequestsPerDay: 5000, MaxDataPerDay: 1 << 30},
+		key.limits(defaults))
+
+	var none *APIKey
+	assert.True(t, none.HasScope(ScopeAdmin), "no key means authentication is disabled")
+}
@@ -1,14 +1,17 @@
 package server
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"image"
+	"io"
 	"net/http"
 	"os"
 	"strings"
 	"time"
+
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
 )
 
 // BatchOCRRequest represents a batch OCR request.
@@ -18,17 +21,21 @@ type BatchOCRRequest struct {
 	Format string              `json:"format,omitempty"`
 }
 
-// BatchImageRequest represents a single image in a batch request.
+// BatchImageRequest represents a single image in a batch request. The image
+// is given as Data or referenced by URL.
 type BatchImageRequest struct {
 	Name    string                 `json:"name"`
 	Data    []byte                 `json:"data"`
+	URL     string                 `json:"url,omitempty"`
 	Options map[string]interface{} `json:"options,omitempty"`
 }
 
-// BatchPDFRequest represents a single PDF in a batch request.
+// BatchPDFRequest represents a single PDF in a batch request. The PDF is
+// given as Data or referenced by URL.
 type BatchPDFRequest struct {
 	Name    string                 `json:"name"`
 	Data    []byte                 `json:"data"`
+	URL     string                 `json:"url,omitempty"`
 	Pages   string                 `json:"pages,omitempty"`
 	Options map[string]interface{} `json:"options,omitempty"`
 }
@@ -39,6 +46,9 @@ type BatchOCRResponse struct {
 	Results []BatchOCRResult       `json:"results,omitempty"`
 	Error   string                 `json:"error,omitempty"`
 	Summary BatchProcessingSummary `json:"summary"`
+	// Truncated is set when the processing timeout cut the batch short:
+	// items it interrupted hold partial results, later ones were skipped.
+	Truncated bool `json:"truncated,omitempty"`
 }
 
 // BatchOCRResult represents a single result in batch processing.
@@ -60,16 +70,61 @@ type BatchProcessingSummary struct {
 	AvgItemTime   float64 `json:"avg_item_time_seconds"`
 }
 
-// ocrBatchHandler processes batch OCR requests.
+// DefaultMaxBatchItems is the default limit of images and PDFs in a batch
+// request.
+const DefaultMaxBatchItems = 10
+
+// Batch stream event types.
+const (
+	BatchEventPage    = "page"
+	BatchEventResult  = "result"
+	BatchEventSummary = "summary"
+)
+
+// BatchStreamEvent is one line of an NDJSON batch response. A stream holds a
+// "result" event per item as it completes, in item order (images first, then
+// PDFs), followed by one "summary" event. For the default JSON format, PDF
+// pages are sent as "page" events as they complete and left out of the
+// PDF's result.
+type BatchStreamEvent struct {
+	Type string `json:"type"`
+	// Index is the item's position in the batch, images first.
+	Index     *int                       `json:"index,omitempty"`
+	Name      string                     `json:"name,omitempty"`
+	Page      *pipeline.OCRPDFPageResult `json:"page,omitempty"`
+	Result    *BatchOCRResult            `json:"result,omitempty"`
+	Success   bool                       `json:"success,omitempty"`
+	Summary   *BatchProcessingSummary    `json:"summary,omitempty"`
+	Truncated bool                       `json:"truncated,omitempty"`
+}
+
+// ocrBatchHandler processes batch OCR requests. With "Accept:
+// application/x-ndjson" or format "ndjson" the results are streamed as they
+// complete instead of being sent as one response.
 func (s *Server) ocrBatchHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Parse JSON request body
-	var req BatchOCRRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	// Parse JSON request body item by item, so that an oversized batch is
+	// refused before the rest of it is read
+	if s.maxUploadMB > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, s.batchBodyLimit())
+	}
+	req, err := decodeBatchRequest(r.Body, s.batchItemLimit(), s.maxUploadMB*1024*1024)
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, errBatchTooManyItems):
+		s.writeErrorResponse(w, fmt.Sprintf("Batch size too large (maximum %d items)", s.batchItemLimit()), http.StatusBadRequest)
+		return
+	case errors.Is(err, errBatchItemTooLarge):
+		s.writeErrorResponse(w, fmt.Sprintf("Batch item too large (maximum %d MB)", s.maxUploadMB), http.StatusRequestEntityTooLarge)
+		return
+	case errors.As(err, &maxBytesErr):
+		s.writeErrorResponse(w, "Batch request too large", http.StatusRequestEntityTooLarge)
+		return
+	case err != nil:
 		s.writeErrorResponse(w, fmt.Sprintf("Failed to parse JSON request: %v", err), http.StatusBadRequest)
 		return
 	}
@@ -80,36 +135,35 @@ func (s *Server) ocrBatchHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	totalItems := len(req.Images) + len(req.PDFs)
-	if totalItems > 10 { // Limit batch size
-		s.writeErrorResponse(w, "Batch size too large (maximum 10 items)", http.StatusBadRequest)
-		return
-	}
-
 	// Check if pipeline is available
-	if s.pipeline == nil {
+	if s.defaultPipeline() == nil {
 		s.writeErrorResponse(w, "OCR pipeline not initialized", http.StatusServiceUnavailable)
 		return
 	}
 
 	// Process batch
-	start := time.Now()
-	results, summary := s.processBatchRequest(req)
-	totalDuration := time.Since(start)
+	ctx, cancel := s.processingContext(r.Context())
+	defer cancel()
 
-	summary.TotalDuration = totalDuration.Seconds()
-	if summary.TotalItems > 0 {
-		summary.AvgItemTime = summary.TotalDuration / float64(summary.TotalItems)
+	if req.Format == formatNDJSON || acceptsNDJSON(r) {
+		s.streamBatchResponse(ctx, w, req)
+		return
 	}
 
+	results, summary := s.processBatchRequest(ctx, req, nil)
+
 	// Record batch metrics
 	ocrRequestsTotal.WithLabelValues("batch", "success").Inc()
-	ocrProcessingDuration.WithLabelValues("batch").Observe(totalDuration.Seconds())
+	ocrProcessingDuration.WithLabelValues("batch").Observe(summary.TotalDuration)
 
 	response := BatchOCRResponse{
-		Success: summary.Failed == 0,
-		Results: results,
-		Summary: summary,
+		Success:   summary.Failed == 0,
+		Results:   results,
+		Summary:   summary,
+		Truncated: ctx.Err() != nil,
+	}
+	if response.Truncated {
+		markTruncated(w)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -118,52 +172,354 @@ func (s *Server) ocrBatchHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// processBatchRequest processes all items in a batch request.
-func (s *Server) processBatchRequest(req BatchOCRRequest) ([]BatchOCRResult, BatchProcessingSummary) {
+// batchItemLimit returns the maximum number of items in a batch request.
+func (s *Server) batchItemLimit() int {
+	if s.maxBatchItems > 0 {
+		return s.maxBatchItems
+	}
+	return DefaultMaxBatchItems
+}
+
+// batchItemOverhead is the room for the name, options and JSON syntax of a
+// batch item in the request body limit.
+const batchItemOverhead = 64 << 10
+
+// batchBodyLimit returns the maximum size of a batch request body: every
+// item may carry an upload of the maximum size, base64 encoded.
+func (s *Server) batchBodyLimit() int64 {
+	perItem := s.maxUploadMB*1024*1024*4/3 + batchItemOverhead
+	return int64(s.batchItemLimit()) * perItem
+}
+
+var (
+	errBatchTooManyItems = errors.New("too many batch items")
+	errBatchItemTooLarge = errors.New("batch item too large")
+)
+
+// decodeBatchRequest decodes a batch request one item at a time. It stops
+// with errBatchTooManyItems at the first item beyond maxItems, and with
+// errBatchItemTooLarge at an item whose data exceeds maxItemBytes (if set).
+func decodeBatchRequest(body io.Reader, maxItems int, maxItemBytes int64) (BatchOCRRequest, error) {
+	var req BatchOCRRequest
+	dec := json.NewDecoder(body)
+	checkItem := func(data []byte) error {
+		if len(req.Images)+len(req.PDFs) > maxItems {
+			return errBatchTooManyItems
+		}
+		if maxItemBytes > 0 && int64(len(data)) > maxItemBytes {
+			return errBatchItemTooLarge
+		}
+		return nil
+	}
+
+	if err := expectJSONDelim(dec, '{'); err != nil {
+		return req, err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return req, err
+		}
+		key, _ := tok.(string)
+		// Keys match case-insensitively, like json.Unmarshal
+		switch strings.ToLower(key) {
+		case "images":
+			err = decodeJSONArray(dec, func() error {
+				var item BatchImageRequest
+				if err := dec.Decode(&item); err != nil {
+					return err
+				}
+				req.Images = append(req.Images, item)
+				return checkItem(item.Data)
+			})
+		case "pdfs":
+			err = decodeJSONArray(dec, func() error {
+				var item BatchPDFRequest
+				if err := dec.Decode(&item); err != nil {
+					return err
+				}
+				req.PDFs = append(req.PDFs, item)
+				return checkItem(item.Data)
+			})
+		case "format":
+			err = dec.Decode(&req.Format)
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+		if err != nil {
+			return req, err
+		}
+	}
+	return req, expectJSONDelim(dec, '}')
+}
+
+// decodeJSONArray calls decodeItem for every element of the array at the
+// decoder's position; null is an empty array.
+func decodeJSONArray(dec *json.Decoder, decodeItem func() error) error {
+	tok, err := dec.Token()
+	if err != nil || tok == nil {
+		return err
+	}
+	if tok != json.Delim('[') {
+		return fmt.Errorf("expected an array, got %v", tok)
+	}
+	for dec.More() {
+		if err := decodeItem(); err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token()
+	return err
+}
+
+func expectJSONDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("expected %v, got %v", delim, tok)
+	}
+	return nil
+}
+
+// streamBatchResponse processes the batch and sends every result as an
+// NDJSON line as soon as its item completes, followed by a summary line.
+// Results are not kept, and the data of an item is dropped once it is
+// processed, so memory does not grow with the batch.
+func (s *Server) streamBatchResponse(ctx context.Context, w http.ResponseWriter, req BatchOCRRequest) {
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	fw := flushWriter{w, http.NewResponseController(w)}
+	enc := json.NewEncoder(fw)
+
+	// After a failed write the client is gone; processing stops as its
+	// request context ends.
+	var writeErr error
+	write := func(ev BatchStreamEvent) {
+		if writeErr != nil {
+			return
+		}
+		if writeErr = enc.Encode(ev); writeErr == nil {
+			writeErr = fw.Flush()
+		}
+		if writeErr != nil {
+			fmt.Fprintf(os.Stderr, "Error writing batch stream: %v\n", writeErr)
+		}
+	}
+
+	var onPage func(int, string, *pipeline.OCRPDFPageResult)
+	if !isDocumentFormat(req.Format) {
+		onPage = func(index int, name string, page *pipeline.OCRPDFPageResult) {
+			write(BatchStreamEvent{Type: BatchEventPage, Index: &index, Name: name, Page: page})
+		}
+	}
+	summary := s.streamBatchRequest(ctx, req, nil, onPage, func(index int, result BatchOCRResult) {
+		write(BatchStreamEvent{Type: BatchEventResult, Index: &index, Result: &result})
+	})
+
+	ocrRequestsTotal.WithLabelValues("batch", "success").Inc()
+	ocrProcessingDuration.WithLabelValues("batch").Observe(summary.TotalDuration)
+	write(BatchStreamEvent{
+		Type:      BatchEventSummary,
+		Success:   summary.Failed == 0,
+		Summary:   &summary,
+		Truncated: ctx.Err() != nil,
+	})
+}
+
+// batchProgress receives the number of items and PDF pages finished so far.
+type batchProgress func(items, pages int)
+
+// processBatchRequest processes all items in a batch request. Items reached
+// after ctx has ended are reported as failed without being processed. If
+// progress is not nil, it is called after every item and PDF page.
+func (s *Server) processBatchRequest(ctx context.Context, req BatchOCRRequest,
+	progress batchProgress,
+) ([]BatchOCRResult, BatchProcessingSummary) {
 	results := make([]BatchOCRResult, 0, len(req.Images)+len(req.PDFs))
+	summary := s.streamBatchRequest(ctx, req, progress, nil, func(_ int, result BatchOCRResult) {
+		results = append(results, result)
+	})
+	return results, summary
+}
+
+// streamBatchRequest processes all items in a batch request like
+// processBatchRequest, passing each result to emit as its item completes
+// instead of collecting them. The data of processed items is released. If
+// onPage is not nil, it receives the pages of PDF items as they complete,
+// and the PDF results passed to emit leave them out.
+func (s *Server) streamBatchRequest(ctx context.Context, req BatchOCRRequest, progress batchProgress,
+	onPage func(index int, name string, page *pipeline.OCRPDFPageResult),
+	emit func(index int, result BatchOCRResult),
+) BatchProcessingSummary {
+	start := time.Now()
 	summary := BatchProcessingSummary{
 		TotalItems: len(req.Images) + len(req.PDFs),
 	}
 
-	// Process images
-	for _, imgReq := range req.Images {
-		result := s.processBatchImage(imgReq)
-		results = append(results, result)
+	var items, pages int
+	add := func(result BatchOCRResult) {
+		emit(items, result)
+		items++
 		if result.Success {
 			summary.Successful++
 		} else {
 			summary.Failed++
 		}
+		if progress != nil {
+			progress(items, pages)
+		}
+	}
+
+	// Process images
+	for i := range req.Images {
+		add(applyBatchFormat(s.processBatchImage(ctx, req.Images[i]), req.Format))
+		req.Images[i].Data = nil
 	}
 
 	// Process PDFs
-	for _, pdfReq := range req.PDFs {
-		result := s.processBatchPDF(pdfReq)
-		results = append(results, result)
-		if result.Success {
-			summary.Successful++
-		} else {
-			summary.Failed++
+	for i := range req.PDFs {
+		name := req.PDFs[i].Name
+		var pageFunc func(*pipeline.OCRPDFPageResult)
+		if progress != nil || onPage != nil {
+			index := items
+			pageFunc = func(page *pipeline.OCRPDFPageResult) {
+				pages++
+				if onPage != nil {
+					onPage(index, name, page)
+				}
+				if progress != nil {
+					progress(items, pages)
+				}
+			}
 		}
+		add(applyBatchFormat(s.processBatchPDF(ctx, req.PDFs[i], pageFunc, onPage == nil), req.Format))
+		req.PDFs[i].Data = nil
 	}
 
-	return results, summary
+	summary.TotalDuration = time.Since(start).Seconds()
+	if summary.TotalItems > 0 {
+		summary.AvgItemTime = summary.TotalDuration / float64(summary.TotalItems)
+	}
+	return summary
+}
+
+// PageXMLDocument is the PAGE XML document of a PDF page. PAGE describes a
+// single page per document, so PDF results have one per page.
+type PageXMLDocument struct {
+	PageNumber int    `json:"page_number"`
+	Document   string `json:"document"`
+}
+
+// isDocumentFormat reports whether results in format are rendered as
+// documents rather than returned as result objects.
+func isDocumentFormat(format string) bool {
+	switch format {
+	case formatHOCR, formatALTO, formatPageXML, formatMarkdown:
+		return true
+	}
+	return false
+}
+
+// applyBatchFormat converts a successful result into the requested document format.
+// Structured formats (json, the default) keep the result object as is.
+func applyBatchFormat(result BatchOCRResult, format string) BatchOCRResult {
+	if !result.Success || !isDocumentFormat(format) {
+		return result
+	}
+	var doc interface{}
+	var err error
+	switch res := result.Result.(type) {
+	case *pipeline.OCRImageResult:
+		doc, err = imageDocument(res, result.Name, format)
+	case *pipeline.OCRPDFResult:
+		doc, err = pdfDocument(res, format)
+	default:
+		return result
+	}
+	if err != nil {
+		result.Success = false
+		result.Result = nil
+		result.Error = fmt.Sprintf("formatting failed: %v", err)
+		return result
+	}
+	result.Result = doc
+	return result
+}
+
+// imageDocument renders an image result in a document format; source names
+// the image in the formats that record it.
+func imageDocument(res *pipeline.OCRImageResult, source, format string) (string, error) {
+	switch format {
+	case formatHOCR:
+		return pipeline.ToHOCRImage(res, source)
+	case formatALTO:
+		return pipeline.ToALTOImage(res, source, pipeline.ExportMetadata{})
+	case formatPageXML:
+		return pipeline.ToPageXMLImage(res, source, pipeline.ExportMetadata{})
+	default:
+		return pipeline.ToMarkdownImage(res)
+	}
+}
+
+// pdfDocument renders a PDF result in a document format: a string, or the
+// PAGE XML documents of its pages.
+func pdfDocument(res *pipeline.OCRPDFResult, format string) (interface{}, error) {
+	switch format {
+	case formatHOCR:
+		return pipeline.ToHOCRPDF(res)
+	case formatALTO:
+		return pipeline.ToALTOPDF(res, pipeline.ExportMetadata{})
+	case formatPageXML:
+		return pageXMLDocuments(res)
+	default:
+		return pipeline.ToMarkdownPDF(res)
+	}
+}
+
+// pageXMLDocuments renders a PDF result as one PAGE XML document per page.
+func pageXMLDocuments(res *pipeline.OCRPDFResult) ([]PageXMLDocument, error) {
+	docs, err := pipeline.ToPageXMLPDF(res, pipeline.ExportMetadata{})
+	if err != nil {
+		return nil, err
+	}
+	pages := make([]PageXMLDocument, len(docs))
+	for i, doc := range docs {
+		pages[i] = PageXMLDocument{PageNumber: res.Pages[i].PageNumber, Document: doc}
+	}
+	return pages, nil
 }
 
 // processBatchImage processes a single image in a batch request.
-func (s *Server) processBatchImage(req BatchImageRequest) BatchOCRResult {
+func (s *Server) processBatchImage(ctx context.Context, req BatchImageRequest) BatchOCRResult {
 	result := BatchOCRResult{
 		Type: "image",
 		Name: req.Name,
 	}
 
+	if err := ctx.Err(); err != nil {
+		result.Error = fmt.Sprintf("Not processed: %v", err)
+		return result
+	}
+
+	if len(req.Data) == 0 && req.URL != "" {
+		fetched, err := s.fetchInput(ctx, req.URL)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		req.Data = fetched.Data
+	}
 	if len(req.Data) == 0 {
 		result.Error = "No image data provided"
 		return result
 	}
 
 	// Decode image
-	img, _, err := image.Decode(bytes.NewReader(req.Data))
+	img, err := decodeImage(ctx, req.Data)
 	if err != nil {
 		result.Error = fmt.Sprintf("Failed to decode image: %v", err)
 		return result
@@ -181,12 +537,12 @@ func (s *Server) processBatchImage(req BatchImageRequest) BatchOCRResult {
 
 	// Process image
 	start := time.Now()
-	ocrResult, err := pipeline.ProcessImage(img)
+	ocrResult, err := pipeline.ProcessImageContext(ctx, img)
 	duration := time.Since(start)
 
 	result.Duration = duration.Seconds()
 
-	if err != nil {
+	if err != nil && (ocrResult == nil || !ocrResult.Truncated) {
 		result.Error = fmt.Sprintf("OCR processing failed: %v", err)
 		return result
 	}
@@ -205,13 +561,34 @@ func (s *Server) processBatchImage(req BatchImageRequest) BatchOCRResult {
 	return result
 }
 
-// processBatchPDF processes a single PDF in a batch request.
-func (s *Server) processBatchPDF(req BatchPDFRequest) BatchOCRResult {
+// processBatchPDF processes a single PDF in a batch request. onPage, if not
+// nil, is called as each page completes. The result holds the pages only if
+// keepPages is set.
+func (s *Server) processBatchPDF(ctx context.Context, req BatchPDFRequest,
+	onPage func(*pipeline.OCRPDFPageResult), keepPages bool,
+) BatchOCRResult {
 	result := BatchOCRResult{
 		Type: "pdf",
 		Name: req.Name,
 	}
 
+	if err := ctx.Err(); err != nil {
+		result.Error = fmt.Sprintf("Not processed: %v", err)
+		return result
+	}
+
+	if len(req.Data) == 0 && req.URL != "" {
+		fetched, err := s.fetchInput(ctx, req.URL)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		if !fetched.isPDF() {
+			result.Error = fmt.Sprintf("%v (got %s, expected a PDF)", errFetchUnsupported, fetched.ContentType)
+			return result
+		}
+		req.Data = fetched.Data
+	}
 	if len(req.Data) == 0 {
 		result.Error = "No PDF data provided"
 		return result
@@ -233,12 +610,13 @@ func (s *Server) processBatchPDF(req BatchPDFRequest) BatchOCRResult {
 		result.Error = fmt.Sprintf("Failed to write PDF data: %v", err)
 		return result
 	}
+	req.Data = nil
 
 	// Extract request configuration from options
 	reqConfig := s.extractBatchConfig(req.Options)
 
 	// Get pipeline for this request
-	pipeline, err := s.getPipelineForRequest(reqConfig)
+	pl, err := s.getPipelineForRequest(reqConfig)
 	if err != nil {
 		result.Error = fmt.Sprintf("Failed to create pipeline: %v", err)
 		return result
@@ -246,12 +624,31 @@ func (s *Server) processBatchPDF(req BatchPDFRequest) BatchOCRResult {
 
 	// Process PDF
 	start := time.Now()
-	ocrResult, err := pipeline.ProcessPDF(tempFile.Name(), req.Pages)
+	var pages []pipeline.OCRPDFPageResult
+	var totalTextLength, totalRegions int
+	ocrResult, err := pl.ProcessPDFStream(ctx, tempFile.Name(), req.Pages, func(page *pipeline.OCRPDFPageResult) error {
+		for _, img := range page.Images {
+			totalRegions += len(img.Regions)
+			for _, region := range img.Regions {
+				totalTextLength += len(region.Text)
+			}
+		}
+		if keepPages {
+			pages = append(pages, *page)
+		}
+		if onPage != nil {
+			onPage(page)
+		}
+		return nil
+	})
+	if ocrResult != nil {
+		ocrResult.Pages = pages
+	}
 	duration := time.Since(start)
 
 	result.Duration = duration.Seconds()
 
-	if err != nil {
+	if err != nil && (ocrResult == nil || !ocrResult.Truncated) {
 		result.Error = fmt.Sprintf("PDF OCR processing failed: %v", err)
 		return result
 	}
@@ -260,15 +657,6 @@ func (s *Server) processBatchPDF(req BatchPDFRequest) BatchOCRResult {
 	result.Result = ocrResult
 
 	// Record individual metrics
-	var totalTextLength, totalRegions int
-	for _, page := range ocrResult.Pages {
-		for _, img := range page.Images {
-			totalRegions += len(img.Regions)
-			for _, region := range img.Regions {
-				totalTextLength += len(region.Text)
-			}
-		}
-	}
 	ocrTextLength.WithLabelValues("batch_pdf").Observe(float64(totalTextLength))
 	ocrRegionsDetected.WithLabelValues("batch_pdf").Observe(float64(totalRegions))
 
@@ -285,6 +673,7 @@ func (s *Server) extractBatchConfig(options map[string]interface{}) *RequestConf
 
 	// Extract string values
 	stringFields := map[string]*string{
+		"profile":   &config.Profile,
 		"language":  &config.Language,
 		"dict":      &config.DictPath,
 		"det-model": &config.DetModel,
@@ -0,0 +1,215 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyBatchFormat(t *testing.T) {
+	img := &pipeline.OCRImageResult{Width: 100, Height: 50, Regions: []pipeline.OCRRegionResult{
+		{Text: "Hello", RecConfidence: 0.9, Box: struct{ X, Y, W, H int }{X: 5, Y: 5, W: 50, H: 20}},
+	}}
+
+	t.Run("hocr image", func(t *testing.T) {
+		out := applyBatchFormat(BatchOCRResult{Name: "a.png", Success: true, Result: img}, formatHOCR)
+		require.True(t, out.Success)
+		doc, ok := out.Result.(string)
+		require.True(t, ok)
+		assert.Contains(t, doc, `image &#34;a.png&#34;`)
+		assert.Contains(t, doc, ">Hello</span>")
+	})
+
+	t.Run("hocr pdf", func(t *testing.T) {
+		pdfRes := &pipeline.OCRPDFResult{Pages: []pipeline.OCRPDFPageResult{{PageNumber: 1, Width: 100, Height: 50}}}
+		out := applyBatchFormat(BatchOCRResult{Success: true, Result: pdfRes}, formatHOCR)
+		doc, ok := out.Result.(string)
+		require.True(t, ok)
+		assert.Contains(t, doc, `class="ocr_page"`)
+	})
+
+	t.Run("alto image", func(t *testing.T) {
+		out := applyBatchFormat(BatchOCRResult{Name: "a.png", Success: true, Result: img}, formatALTO)
+		doc, ok := out.Result.(string)
+		require.True(t, ok)
+		assert.Contains(t, doc, "<fileName>a.png</fileName>")
+		assert.Contains(t, doc, `CONTENT="Hello"`)
+	})
+
+	t.Run("page pdf", func(t *testing.T) {
+		pdfRes := &pipeline.OCRPDFResult{Filename: "doc.pdf", Pages: []pipeline.OCRPDFPageResult{
+			{PageNumber: 2, Width: 100, Height: 50}, {PageNumber: 3, Width: 100, Height: 50},
+		}}
+		out := applyBatchFormat(BatchOCRResult{Success: true, Result: pdfRes}, formatPageXML)
+		docs, ok := out.Result.([]PageXMLDocument)
+		require.True(t, ok)
+		require.Len(t, docs, 2)
+		assert.Equal(t, 3, docs[1].PageNumber)
+		assert.Contains(t, docs[1].Document, `imageFilename="doc.pdf#page=3"`)
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		out := applyBatchFormat(BatchOCRResult{Success: true, Result: img}, formatMarkdown)
+		assert.Equal(t, "Hello\n", out.Result)
+	})
+
+	t.Run("json keeps result", func(t *testing.T) {
+		out := applyBatchFormat(BatchOCRResult{Success: true, Result: img}, "json")
+		assert.Same(t, img, out.Result)
+	})
+
+	t.Run("failed items are untouched", func(t *testing.T) {
+		out := applyBatchFormat(BatchOCRResult{Success: false, Error: "boom"}, formatHOCR)
+		assert.Nil(t, out.Result)
+		assert.Equal(t, "boom", out.Error)
+	})
+}
+
+func newBatchRequest(t *testing.T, images, pdfs int) *http.Request {
+	t.Helper()
+	imgData, err := encodeImageToPNG(createTestImage(20, 20))
+	require.NoError(t, err)
+	var batch BatchOCRRequest
+	for i := range images {
+		batch.Images = append(batch.Images, BatchImageRequest{Name: fmt.Sprintf("%d.png", i), Data: imgData})
+	}
+	for i := range pdfs {
+		batch.PDFs = append(batch.PDFs, BatchPDFRequest{Name: fmt.Sprintf("%d.pdf", i), Data: []byte("%PDF-1.4")})
+	}
+	body, err := json.Marshal(batch)
+	require.NoError(t, err)
+	return httptest.NewRequest(http.MethodPost, "/ocr/batch", bytes.NewReader(body))
+}
+
+func TestServer_ocrBatchHandler_MaxItems(t *testing.T) {
+	server := &Server{pipeline: &mockPipelineForTesting{processImageResult: &pipeline.OCRImageResult{}}}
+
+	w := httptest.NewRecorder()
+	server.ocrBatchHandler(w, newBatchRequest(t, DefaultMaxBatchItems+1, 0))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "maximum 10 items")
+
+	server.maxBatchItems = 20
+	w = httptest.NewRecorder()
+	server.ocrBatchHandler(w, newBatchRequest(t, DefaultMaxBatchItems+1, 0))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestServer_ocrBatchHandler_BodyLimits(t *testing.T) {
+	server := &Server{pipeline: &mockPipelineForTesting{processImageResult: &pipeline.OCRImageResult{}},
+		maxUploadMB: 1, maxBatchItems: 2}
+	item := func(size int) string {
+		data, err := json.Marshal(BatchImageRequest{Name: "a.png", Data: make([]byte, size)})
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	tests := []struct {
+		name     string
+		body     io.Reader
+		wantCode int
+		wantErr  string
+	}{
+		{
+			name:     "item above the upload limit",
+			body:     strings.NewReader(`{"images":[` + item(1<<20+1) + `]}`),
+			wantCode: http.StatusRequestEntityTooLarge,
+			wantErr:  "maximum 1 MB",
+		},
+		{
+			name:     "body above the batch limit",
+			body:     strings.NewReader(`{"format":"json","padding":"` + strings.Repeat("x", 3<<20) + `"}`),
+			wantCode: http.StatusRequestEntityTooLarge,
+			wantErr:  "Batch request too large",
+		},
+		{
+			// The rest of the body is not read once the limit is exceeded
+			name: "too many items",
+			body: io.MultiReader(strings.NewReader(`{"images":[`+item(10)+`,`+item(10)+`],"pdfs":[`+item(10)+`,`),
+				iotest.ErrReader(errors.New("read past the item limit"))),
+			wantCode: http.StatusBadRequest,
+			wantErr:  "maximum 2 items",
+		},
+		{
+			name:     "not an object",
+			body:     strings.NewReader(`[]`),
+			wantCode: http.StatusBadRequest,
+			wantErr:  "Failed to parse JSON request",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			server.ocrBatchHandler(w, httptest.NewRequest(http.MethodPost, "/ocr/batch", tt.body))
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.wantErr)
+		})
+	}
+}
+
+func TestDecodeBatchRequest(t *testing.T) {
+	req, err := decodeBatchRequest(strings.NewReader(
+		`{"Images":[{"name":"a.png","data":"aGk="}],"pdfs":null,"format":"hocr","extra":{"x":[1]}}`), 2, 0)
+	require.NoError(t, err)
+	require.Len(t, req.Images, 1)
+	assert.Equal(t, []byte("hi"), req.Images[0].Data)
+	assert.Empty(t, req.PDFs)
+	assert.Equal(t, formatHOCR, req.Format)
+
+	_, err = decodeBatchRequest(strings.NewReader(`{"images":{}}`), 2, 0)
+	require.Error(t, err)
+}
+
+func TestServer_ocrBatchHandler_NDJSON(t *testing.T) {
+	pdfRes := &pipeline.OCRPDFResult{TotalPages: 2, Pages: []pipeline.OCRPDFPageResult{{PageNumber: 1}, {PageNumber: 2}}}
+	server := &Server{pipeline: &mockPipelineForTesting{
+		processImageResult: &pipeline.OCRImageResult{},
+		processPDFResult:   pdfRes,
+	}}
+
+	req := newBatchRequest(t, 1, 1)
+	req.Header.Set("Accept", "application/x-ndjson, application/json;q=0.5")
+	w := httptest.NewRecorder()
+	server.ocrBatchHandler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.True(t, w.Flushed)
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	require.Len(t, lines, 5)
+
+	events := make([]BatchStreamEvent, len(lines))
+	for i, line := range lines {
+		require.NoError(t, json.Unmarshal([]byte(line), &events[i]))
+	}
+	assert.Equal(t, BatchEventResult, events[0].Type)
+	assert.Equal(t, 0, *events[0].Index)
+	assert.Equal(t, "0.png", events[0].Result.Name)
+
+	for i, ev := range events[1:3] {
+		assert.Equal(t, BatchEventPage, ev.Type)
+		assert.Equal(t, 1, *ev.Index)
+		assert.Equal(t, "0.pdf", ev.Name)
+		assert.Equal(t, i+1, ev.Page.PageNumber)
+	}
+
+	assert.Equal(t, BatchEventResult, events[3].Type)
+	assert.Equal(t, 1, *events[3].Index)
+	assert.True(t, events[3].Result.Success)
+	assert.NotContains(t, lines[3], "page_number", "pages were already streamed")
+
+	assert.Equal(t, BatchEventSummary, events[4].Type)
+	assert.True(t, events[4].Success)
+	assert.Equal(t, 2, events[4].Summary.Successful)
+}
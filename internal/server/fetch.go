@@ -0,0 +1,395 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Remote input defaults.
+const (
+	DefaultFetchTimeout      = 30 * time.Second
+	DefaultFetchMaxRedirects = 3
+
+	fetchDialTimeout = 10 * time.Second
+	fetchUserAgent   = "pogo-fetch/1"
+)
+
+var (
+	errFetchDisabled    = errors.New("URL inputs are not enabled on this server")
+	errFetchBlocked     = errors.New("URL not allowed")
+	errFetchTooLarge    = errors.New("remote file too large")
+	errFetchUnsupported = errors.New("remote file is not a supported image or PDF")
+	errFetchFailed      = errors.New("failed to fetch URL")
+	errFetchTimeout     = errors.New("timed out")
+)
+
+// specialNetworks are address ranges that are not public but not covered by
+// the netip.Addr predicates either.
+var specialNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, may reach IPv4 hosts
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local-use NAT64
+	netip.MustParsePrefix("2002::/16"),       // 6to4, may reach IPv4 hosts
+	netip.MustParsePrefix("2001::/32"),       // Teredo
+	netip.MustParsePrefix("100::/64"),        // discard-only
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+}
+
+// FetchConfig enables inputs referenced by URL instead of uploaded, and sets
+// the policy for fetching them. Every address the server connects to is
+// checked, including those of redirects and of every DNS answer, so that a
+// name resolving to an internal address is refused as well.
+type FetchConfig struct {
+	// Enabled lets requests pass a url instead of file data.
+	Enabled bool
+	// Schemes are the allowed URL schemes (default http and https).
+	Schemes []string
+	// AllowedHosts, if not empty, are the only hosts fetched from;
+	// DeniedHosts are never fetched from. Entries match a host name or IP
+	// literal exactly, or with a leading "*." any subdomain of a name.
+	AllowedHosts []string
+	DeniedHosts  []string
+	// AllowedNetworks are address ranges fetched from although they are not
+	// public, e.g. the network of internal document storage. Otherwise
+	// loopback, private, link-local and other special-purpose addresses
+	// are refused.
+	AllowedNetworks []netip.Prefix
+	// MaxBytes limits the size of a fetched file (0 = the upload limit).
+	MaxBytes int64
+	// Timeout bounds a fetch, including redirects and reading the body
+	// (0 = DefaultFetchTimeout).
+	Timeout time.Duration
+	// MaxRedirects limits the redirects followed (0 = DefaultFetchMaxRedirects,
+	// negative = none).
+	MaxRedirects int
+}
+
+// fetchedFile is an input fetched from a URL.
+type fetchedFile struct {
+	// Name is the last path element of the final URL.
+	Name string
+	Data []byte
+	// ContentType is sniffed from the data; the server's claim is ignored.
+	ContentType string
+}
+
+func (f *fetchedFile) isPDF() bool {
+	return f.ContentType == "application/pdf"
+}
+
+// remoteFetcher downloads inputs referenced by URL under a FetchConfig.
+type remoteFetcher struct {
+	config FetchConfig
+	client *http.Client
+}
+
+// newRemoteFetcher creates a fetcher for config, filling in defaults;
+// maxUploadBytes is the default size limit.
+func newRemoteFetcher(config FetchConfig, maxUploadBytes int64) *remoteFetcher {
+	if len(config.Schemes) == 0 {
+		config.Schemes = []string{"http", "https"}
+	}
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = maxUploadBytes
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultFetchTimeout
+	}
+	if config.MaxRedirects == 0 {
+		config.MaxRedirects = DefaultFetchMaxRedirects
+	}
+
+	f := &remoteFetcher{config: config}
+	dialer := &net.Dialer{Timeout: fetchDialTimeout, Control: f.checkDial}
+	f.client = &http.Client{
+		Transport: &http.Transport{
+			// No proxy: it would hide the address actually connected to.
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   fetchDialTimeout,
+			ResponseHeaderTimeout: config.Timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+			ForceAttemptHTTP2:     true,
+		},
+		Timeout:       config.Timeout,
+		CheckRedirect: f.checkRedirect,
+	}
+	return f
+}
+
+// fetch downloads the image or PDF at rawURL.
+func (f *remoteFetcher) fetch(ctx context.Context, rawURL string) (*fetchedFile, error) {
+	file, err := f.get(ctx, rawURL)
+	remoteFetchTotal.WithLabelValues(fetchResult(err)).Inc()
+	if err != nil {
+		return nil, err
+	}
+	remoteFetchBytes.Observe(float64(len(file.Data)))
+	return file, nil
+}
+
+func (f *remoteFetcher) get(ctx context.Context, rawURL string) (*fetchedFile, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid URL", errFetchBlocked)
+	}
+	if err := f.checkURL(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid URL", errFetchBlocked)
+	}
+	req.Header.Set("User-Agent", fetchUserAgent)
+	req.Header.Set("Accept", "image/*, application/pdf")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		// Policy errors come wrapped in a *url.Error; keep them
+		// distinguishable without echoing the URL, which may hold secrets.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			if urlErr.Timeout() {
+				return nil, fmt.Errorf("%w: %w", errFetchFailed, errFetchTimeout)
+			}
+			err = urlErr.Err
+		}
+		if errors.Is(err, errFetchBlocked) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", errFetchFailed, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: server answered %s", errFetchFailed, resp.Status)
+	}
+	if resp.ContentLength > f.config.MaxBytes {
+		return nil, fmt.Errorf("%w (maximum %d bytes)", errFetchTooLarge, f.config.MaxBytes)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.config.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errFetchFailed, err)
+	}
+	if int64(len(data)) > f.config.MaxBytes {
+		return nil, fmt.Errorf("%w (maximum %d bytes)", errFetchTooLarge, f.config.MaxBytes)
+	}
+
+	file := &fetchedFile{Name: path.Base(resp.Request.URL.Path), Data: data, ContentType: sniffContentType(data)}
+	if file.Name == "/" || file.Name == "." {
+		file.Name = resp.Request.URL.Hostname()
+	}
+	if !file.isPDF() && !strings.HasPrefix(file.ContentType, "image/") {
+		return nil, fmt.Errorf("%w (got %s)", errFetchUnsupported, file.ContentType)
+	}
+	return file, nil
+}
+
+// checkURL applies the scheme and host lists to u. IP literals are checked
+// against the network policy right away; names are checked as they are
+// dialed.
+func (f *remoteFetcher) checkURL(u *url.URL) error {
+	if !slices.Contains(f.config.Schemes, strings.ToLower(u.Scheme)) {
+		return fmt.Errorf("%w: scheme %q is not allowed", errFetchBlocked, u.Scheme)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: credentials in URLs are not allowed", errFetchBlocked)
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return fmt.Errorf("%w: URL has no host", errFetchBlocked)
+	}
+	if matchHost(f.config.DeniedHosts, host) {
+		return fmt.Errorf("%w: host %s is denied", errFetchBlocked, host)
+	}
+	if len(f.config.AllowedHosts) > 0 && !matchHost(f.config.AllowedHosts, host) {
+		return fmt.Errorf("%w: host %s is not allowed", errFetchBlocked, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return f.checkAddr(addr)
+	}
+	return nil
+}
+
+// checkRedirect applies the URL policy to every redirect and limits their
+// number.
+func (f *remoteFetcher) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > f.config.MaxRedirects {
+		return fmt.Errorf("%w: more than %d redirects", errFetchBlocked, max(f.config.MaxRedirects, 0))
+	}
+	return f.checkURL(req.URL)
+}
+
+// checkDial applies the network policy to the resolved address of every
+// connection.
+func (f *remoteFetcher) checkDial(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFetchBlocked, err)
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFetchBlocked, err)
+	}
+	return f.checkAddr(addr)
+}
+
+// checkAddr allows public addresses and those of AllowedNetworks.
+func (f *remoteFetcher) checkAddr(addr netip.Addr) error {
+	addr = addr.Unmap().WithZone("")
+	for _, network := range f.config.AllowedNetworks {
+		if network.Contains(addr) {
+			return nil
+		}
+	}
+	if !isPublicAddr(addr) {
+		return fmt.Errorf("%w: %s is not a public address", errFetchBlocked, addr)
+	}
+	return nil
+}
+
+// isPublicAddr reports whether addr is a globally routable unicast address.
+func isPublicAddr(addr netip.Addr) bool {
+	if !addr.IsValid() || !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, network := range specialNetworks {
+		if network.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchHost reports whether host matches one of patterns: a host name or IP
+// literal, or "*.example.com" for any subdomain of example.com.
+func matchHost(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(strings.ToLower(pattern), ".")
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffContentType determines the type of a fetched file from its content:
+// PDFs by their signature, images by the registered decoders.
+func sniffContentType(data []byte) string {
+	if bytes.HasPrefix(data, []byte("%PDF-")) {
+		return "application/pdf"
+	}
+	if _, format, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		return "image/" + format
+	}
+	return http.DetectContentType(data)
+}
+
+// fetchResult is the metrics label of a fetch outcome.
+func fetchResult(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, errFetchBlocked):
+		return "blocked"
+	case errors.Is(err, errFetchTooLarge):
+		return "too_large"
+	case errors.Is(err, errFetchUnsupported):
+		return "unsupported"
+	default:
+		return "error"
+	}
+}
+
+// fetchErrorStatus maps a fetch error to the HTTP status of the response.
+func fetchErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errFetchTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, errFetchUnsupported):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, errFetchTimeout), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, errFetchFailed):
+		return http.StatusBadGateway
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// fetchInput fetches a request input referenced by URL and counts it against
+// the data quota of the requesting client; an exceeded quota is a
+// *QuotaExceededError.
+func (s *Server) fetchInput(ctx context.Context, rawURL string) (*fetchedFile, error) {
+	if s.fetcher == nil {
+		return nil, errFetchDisabled
+	}
+	file, err := s.fetcher.fetch(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.chargeClientData(ctx, int64(len(file.Data))); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// writeFetchError reports a failed fetch; an exceeded quota is reported like
+// the rate limit middleware does.
+func (s *Server) writeFetchError(w http.ResponseWriter, err error) {
+	var quota *QuotaExceededError
+	if errors.As(err, &quota) {
+		s.handleRateLimitError(w, err)
+		return
+	}
+	s.writeErrorResponse(w, err.Error(), fetchErrorStatus(err))
+}
+
+// fetchPDFToTempFile fetches a PDF referenced by URL into a temporary file,
+// which the caller removes. It returns the file and the name of the PDF in
+// the URL.
+func (s *Server) fetchPDFToTempFile(ctx context.Context, rawURL string) (string, string, error) {
+	file, err := s.fetchInput(ctx, rawURL)
+	if err != nil {
+		return "", "", err
+	}
+	if !file.isPDF() {
+		return "", "", fmt.Errorf("%w (got %s, expected a PDF)", errFetchUnsupported, file.ContentType)
+	}
+	tempFile, err := os.CreateTemp("", "fetch-*.pdf")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer func() { _ = tempFile.Close() }()
+	if _, err := tempFile.Write(file.Data); err != nil {
+		_ = os.Remove(tempFile.Name())
+		return "", "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	return tempFile.Name(), file.Name, nil
+}
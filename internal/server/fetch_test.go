@@ -0,0 +1,291 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+
+// newFetchTestServer serves a PNG at /image.png, a PDF at /doc.pdf, HTML at
+// /page.html and redirects /redirect?to= to another URL.
+func newFetchTestServer(t *testing.T) (*httptest.Server, []byte) {
+	t.Helper()
+	png, err := encodeImageToPNG(createTestImage(20, 10))
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/image.png", func(w http.ResponseWriter, r *http.Request) {
+		// The claimed type is ignored in favor of the content
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(png)
+	})
+	mux.HandleFunc("/doc.pdf", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("%PDF-1.4\n"))
+	})
+	mux.HandleFunc("/page.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("<html><body>not an image</body></html>"))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Query().Get("to"), http.StatusFound)
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		// Without a Content-Length the limit applies while reading
+		w.(http.Flusher).Flush()
+		_, _ = w.Write(append(png, make([]byte, 4096)...))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, png
+}
+
+func TestRemoteFetcher_Fetch(t *testing.T) {
+	srv, png := newFetchTestServer(t)
+	f := newRemoteFetcher(FetchConfig{AllowedNetworks: loopback}, 1024*1024)
+
+	file, err := f.fetch(t.Context(), srv.URL+"/image.png")
+	require.NoError(t, err)
+	assert.Equal(t, png, file.Data)
+	assert.Equal(t, "image/png", file.ContentType)
+	assert.Equal(t, "image.png", file.Name)
+
+	file, err = f.fetch(t.Context(), srv.URL+"/doc.pdf")
+	require.NoError(t, err)
+	assert.True(t, file.isPDF())
+
+	_, err = f.fetch(t.Context(), srv.URL+"/page.html")
+	require.ErrorIs(t, err, errFetchUnsupported)
+	assert.Equal(t, http.StatusUnsupportedMediaType, fetchErrorStatus(err))
+
+	_, err = f.fetch(t.Context(), srv.URL+"/missing")
+	require.ErrorIs(t, err, errFetchFailed)
+	assert.Equal(t, http.StatusBadGateway, fetchErrorStatus(err))
+}
+
+func TestRemoteFetcher_Policy(t *testing.T) {
+	srv, _ := newFetchTestServer(t)
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		config FetchConfig
+		url    string
+	}{
+		{"loopback blocked by default", FetchConfig{}, srv.URL + "/image.png"},
+		{"name resolving to loopback", FetchConfig{}, "http://localhost:" + u.Port() + "/image.png"},
+		{"scheme", FetchConfig{AllowedNetworks: loopback}, "file:///etc/passwd"},
+		{"scheme not configured", FetchConfig{Schemes: []string{"https"}, AllowedNetworks: loopback}, srv.URL + "/image.png"},
+		{"credentials", FetchConfig{AllowedNetworks: loopback}, "http://user:pass@" + u.Host + "/image.png"},
+		{"denied host", FetchConfig{AllowedNetworks: loopback, DeniedHosts: []string{"127.0.0.1"}}, srv.URL + "/image.png"},
+		{"host not allowed", FetchConfig{AllowedNetworks: loopback, AllowedHosts: []string{"*.example.com"}}, srv.URL + "/image.png"},
+		{"private address", FetchConfig{AllowedNetworks: loopback}, "http://10.0.0.1/image.png"},
+		{"link-local address", FetchConfig{AllowedNetworks: loopback}, "http://169.254.169.254/latest/meta-data"},
+		{"mapped IPv6 address", FetchConfig{}, "http://[::ffff:127.0.0.1]:" + u.Port() + "/image.png"},
+		{"redirect to blocked address", FetchConfig{AllowedNetworks: loopback, DeniedHosts: []string{"localhost"}},
+			srv.URL + "/redirect?to=" + url.QueryEscape("http://localhost:"+u.Port()+"/image.png")},
+		{"redirects disabled", FetchConfig{AllowedNetworks: loopback, MaxRedirects: -1},
+			srv.URL + "/redirect?to=/image.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newRemoteFetcher(tt.config, 1024*1024)
+			_, err := f.fetch(t.Context(), tt.url)
+			require.ErrorIs(t, err, errFetchBlocked)
+			assert.Equal(t, http.StatusBadRequest, fetchErrorStatus(err))
+		})
+	}
+
+	t.Run("allowed host and redirect", func(t *testing.T) {
+		f := newRemoteFetcher(FetchConfig{AllowedNetworks: loopback, AllowedHosts: []string{"127.0.0.1"}}, 1024*1024)
+		file, err := f.fetch(t.Context(), srv.URL+"/redirect?to=/image.png")
+		require.NoError(t, err)
+		assert.Equal(t, "image/png", file.ContentType)
+	})
+
+	t.Run("redirect limit", func(t *testing.T) {
+		f := newRemoteFetcher(FetchConfig{AllowedNetworks: loopback, MaxRedirects: 1}, 1024*1024)
+		twice := srv.URL + "/redirect?to=" + url.QueryEscape("/redirect?to=/image.png")
+		_, err := f.fetch(t.Context(), twice)
+		require.ErrorIs(t, err, errFetchBlocked)
+		assert.Contains(t, err.Error(), "more than 1 redirects")
+	})
+}
+
+func TestRemoteFetcher_Limits(t *testing.T) {
+	srv, png := newFetchTestServer(t)
+
+	f := newRemoteFetcher(FetchConfig{AllowedNetworks: loopback, MaxBytes: int64(len(png)) - 1}, 1024*1024)
+	_, err := f.fetch(t.Context(), srv.URL+"/image.png")
+	require.ErrorIs(t, err, errFetchTooLarge, "Content-Length above the limit")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, fetchErrorStatus(err))
+
+	f = newRemoteFetcher(FetchConfig{AllowedNetworks: loopback, MaxBytes: int64(len(png)) + 100}, 1024*1024)
+	_, err = f.fetch(t.Context(), srv.URL+"/large")
+	require.ErrorIs(t, err, errFetchTooLarge, "body above the limit")
+
+	f = newRemoteFetcher(FetchConfig{AllowedNetworks: loopback, Timeout: 50 * time.Millisecond}, 1024*1024)
+	_, err = f.fetch(t.Context(), srv.URL+"/slow")
+	require.ErrorIs(t, err, errFetchTimeout)
+	assert.Equal(t, http.StatusGatewayTimeout, fetchErrorStatus(err))
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"8.8.8.8":              true,
+		"2606:4700::1111":      true,
+		"127.0.0.1":            false,
+		"10.1.2.3":             false,
+		"172.16.0.1":           false,
+		"192.168.1.1":          false,
+		"169.254.169.254":      false,
+		"100.64.0.1":           false,
+		"0.0.0.0":              false,
+		"255.255.255.255":      false,
+		"224.0.0.1":            false,
+		"::1":                  false,
+		"fe80::1":              false,
+		"fd00::1":              false,
+		"64:ff9b::7f00:1":      false,
+		"2002:7f00:1::":        false,
+		"::ffff:127.0.0.1":     false,
+		"::ffff:8.8.8.8":       true,
+		"2001:db8::1":          false,
+		"ff02::1":              false,
+		"198.18.0.1":           false,
+		"192.0.0.8":            false,
+		"203.0.113.10":         false,
+		"1.1.1.1":              true,
+		"2a00:1450:4001::200e": true,
+	} {
+		assert.Equal(t, public, isPublicAddr(netip.MustParseAddr(addr).Unmap()), addr)
+	}
+}
+
+func TestMatchHost(t *testing.T) {
+	patterns := []string{"Docs.Example.com.", "*.cdn.example.net"}
+	assert.True(t, matchHost(patterns, "docs.example.com"))
+	assert.True(t, matchHost(patterns, "a.b.cdn.example.net"))
+	assert.False(t, matchHost(patterns, "cdn.example.net"))
+	assert.False(t, matchHost(patterns, "evildocs.example.com"))
+	assert.False(t, matchHost(patterns, "xcdn.example.net"))
+}
+
+func TestServer_URLInputs(t *testing.T) {
+	srv, _ := newFetchTestServer(t)
+	fetcher := newRemoteFetcher(FetchConfig{AllowedNetworks: loopback}, 1024*1024)
+	form := func(path, rawURL string) *http.Request {
+		values := url.Values{"url": {rawURL}, "enable-vector-text": {"false"}}
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req
+	}
+
+	t.Run("image", func(t *testing.T) {
+		server := &Server{
+			pipeline:    &mockPipelineForTesting{processImageResult: &pipeline.OCRImageResult{}},
+			maxUploadMB: 1,
+			fetcher:     fetcher,
+		}
+		w := httptest.NewRecorder()
+		server.ocrImageHandler(w, form("/ocr/image", srv.URL+"/image.png"))
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = httptest.NewRecorder()
+		server.ocrImageHandler(w, form("/ocr/image", srv.URL+"/page.html"))
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		server := &Server{pipeline: &mockPipelineForTesting{}, maxUploadMB: 1}
+		w := httptest.NewRecorder()
+		server.ocrImageHandler(w, form("/ocr/image", srv.URL+"/image.png"))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "not enabled")
+	})
+
+	t.Run("pdf", func(t *testing.T) {
+		server := &Server{
+			pipeline:    &mockPipelineForTesting{processPDFResult: &pipeline.OCRPDFResult{TotalPages: 1}},
+			maxUploadMB: 1,
+			fetcher:     fetcher,
+		}
+		w := httptest.NewRecorder()
+		server.ocrPdfHandler(w, form("/ocr/pdf", srv.URL+"/doc.pdf"))
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = httptest.NewRecorder()
+		server.ocrPdfHandler(w, form("/ocr/pdf", srv.URL+"/image.png"))
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		server := &Server{
+			pipeline: &mockPipelineForTesting{processImageResult: &pipeline.OCRImageResult{}},
+			fetcher:  fetcher,
+		}
+		body, err := json.Marshal(BatchOCRRequest{Images: []BatchImageRequest{
+			{Name: "remote", URL: srv.URL + "/image.png"},
+			{Name: "blocked", URL: "http://169.254.169.254/"},
+		}})
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		server.ocrBatchHandler(w, httptest.NewRequest(http.MethodPost, "/ocr/batch", bytes.NewReader(body)))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp BatchOCRResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Results, 2)
+		assert.True(t, resp.Results[0].Success, resp.Results[0].Error)
+		assert.False(t, resp.Results[1].Success)
+		assert.Contains(t, resp.Results[1].Error, "not a public address")
+	})
+}
+
+func TestServer_URLInputQuota(t *testing.T) {
+	srv, png := newFetchTestServer(t)
+	form := url.Values{"url": {srv.URL + "/image.png"}}.Encode()
+	// Two forms fit the quota, two forms and two images do not.
+	quota := int64(2*len(form) + len(png))
+	server := &Server{
+		pipeline:    &mockPipelineForTesting{processImageResult: &pipeline.OCRImageResult{}},
+		maxUploadMB: 1,
+		fetcher:     newRemoteFetcher(FetchConfig{AllowedNetworks: loopback}, 1024*1024),
+		rateLimiter: NewRateLimiter(0, 0, 0, quota),
+	}
+	handler := server.rateLimitMiddleware(server.ocrImageHandler)
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/ocr/image", strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	w := send()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	usage := server.rateLimiter.GetUsage("192.0.2.1")
+	assert.Equal(t, int64(len(form)+len(png)), usage.dataToday, "fetched input is counted")
+
+	w = send()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "quota_exceeded")
+	assert.Equal(t, "data", w.Header().Get("X-Quota-Type"))
+}
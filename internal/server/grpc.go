@@ -0,0 +1,496 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	pogov1 "github.com/MeKo-Tech/pogo/api/pogo/v1"
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// grpcScopes maps the OCR methods to the API key scope they require. Other
+// methods, such as health checks and reflection, are open.
+var grpcScopes = map[string]string{
+	pogov1.OCRService_RecognizeImage_FullMethodName: ScopeImage,
+	pogov1.OCRService_RecognizePDF_FullMethodName:   ScopePDF,
+	pogov1.OCRService_RecognizeBatch_FullMethodName: ScopeBatch,
+}
+
+// grpcLanes maps the OCR methods to their admission lane. A batch stream
+// holds one slot while it is open.
+var grpcLanes = map[string]string{
+	pogov1.OCRService_RecognizeImage_FullMethodName: LaneInteractive,
+	pogov1.OCRService_RecognizePDF_FullMethodName:   LaneBulk,
+	pogov1.OCRService_RecognizeBatch_FullMethodName: LaneBulk,
+}
+
+// grpcMessageOverhead is allowed on top of the upload limit for the fields
+// around the file in a request message.
+const grpcMessageOverhead = 64 * 1024
+
+// NewGRPCServer returns a gRPC server with the OCR service, health checking
+// and reflection registered. It shares pipelines, validation, API keys and
+// rate limits with the HTTP API; opts are added to the server's own options.
+func (s *Server) NewGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	serverOpts := []grpc.ServerOption{
+		// Starts a span per call that continues the caller's trace
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(s.grpcUnaryInterceptor),
+		grpc.ChainStreamInterceptor(s.grpcStreamInterceptor),
+	}
+	if s.maxUploadMB > 0 {
+		maxMsg := int(s.maxUploadMB*1024*1024) + grpcMessageOverhead
+		serverOpts = append(serverOpts, grpc.MaxRecvMsgSize(maxMsg))
+	}
+	gs := grpc.NewServer(append(serverOpts, opts...)...)
+
+	pogov1.RegisterOCRServiceServer(gs, &grpcService{s: s})
+	hs := health.NewServer()
+	s.reportGRPCHealth(hs)
+	healthpb.RegisterHealthServer(gs, hs)
+	reflection.Register(gs)
+	return gs
+}
+
+// reportGRPCHealth sets the OCR service to serving once the default
+// pipeline is started; it stays not serving if startup fails.
+func (s *Server) reportGRPCHealth(hs *health.Server) {
+	service := pogov1.OCRService_ServiceDesc.ServiceName
+	if s.probes.checkStarted() == nil {
+		hs.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+		return
+	}
+	hs.SetServingStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+	go func() {
+		if s.probes.waitStarted(context.Background()) == nil {
+			hs.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+		}
+	}()
+}
+
+// grpcAuthenticate authenticates a call to an OCR method by the API key in
+// its metadata, when keys are configured, and adds the key to the context.
+func (s *Server) grpcAuthenticate(ctx context.Context, method string) (context.Context, error) {
+	scope, ok := grpcScopes[method]
+	if !ok || s.apiKeys == nil {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(name string) string {
+		if v := md.Get(name); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	key, err := s.apiKeys.lookup(first(strings.ToLower(apiKeyHeader)), first("authorization"))
+	if err != nil {
+		reason := "invalid"
+		if errors.Is(err, errMissingAPIKey) {
+			reason = "missing"
+		}
+		authFailures.WithLabelValues(reason).Inc()
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if !key.HasScope(scope) {
+		apiKeyRejections.WithLabelValues(key.Name, "scope").Inc()
+		return nil, status.Errorf(codes.PermissionDenied, "API key lacks the %q scope", scope)
+	}
+	return context.WithValue(ctx, apiKeyContextKey{}, key), nil
+}
+
+// grpcCheckLimits counts a request message of dataSize bytes against the
+// rate limits of the caller.
+func (s *Server) grpcCheckLimits(ctx context.Context, dataSize int64) error {
+	if s.rateLimiter == nil {
+		return nil
+	}
+	clientID := "unknown"
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		clientID = p.Addr.String()
+		if host, _, err := net.SplitHostPort(clientID); err == nil {
+			clientID = host
+		}
+	}
+	if _, err := s.checkClientLimits(clientID, apiKeyFromContext(ctx), dataSize); err != nil {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return nil
+}
+
+// grpcAdmit admits a call through admission control and returns its
+// context and the function ending its admission. Shed calls fail with
+// Unavailable.
+func (s *Server) grpcAdmit(ctx context.Context, method string) (context.Context, func(), error) {
+	lane, ok := grpcLanes[method]
+	if !ok {
+		return ctx, func() {}, nil
+	}
+	if err := s.probes.checkStarted(); err != nil {
+		return nil, nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if s.admission == nil {
+		return ctx, func() {}, nil
+	}
+	start := time.Now()
+	release, err := s.admission.admit(ctx, lane)
+	if err != nil {
+		var ae *admissionError
+		if errors.As(err, &ae) {
+			return nil, nil, status.Error(codes.Unavailable, ae.Error())
+		}
+		return nil, nil, status.FromContextError(err).Err()
+	}
+	return context.WithValue(ctx, queueWaitContextKey{}, time.Since(start)), release, nil
+}
+
+func (s *Server) grpcUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	ctx, err := s.grpcAuthenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := grpcScopes[info.FullMethod]; ok {
+		msg, _ := req.(proto.Message)
+		if err := s.grpcCheckLimits(ctx, int64(proto.Size(msg))); err != nil {
+			return nil, err
+		}
+	}
+	ctx, release, err := s.grpcAdmit(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return handler(ctx, req)
+}
+
+func (s *Server) grpcStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx, err := s.grpcAuthenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	ctx, release, err := s.grpcAdmit(ctx, info.FullMethod)
+	if err != nil {
+		return err
+	}
+	defer release()
+	_, limited := grpcScopes[info.FullMethod]
+	return handler(srv, &grpcServerStream{ServerStream: ss, ctx: ctx, s: s, limited: limited})
+}
+
+// grpcServerStream carries the authenticated context of a stream and counts
+// every received message as a request against the caller's rate limits, so
+// that a batch stream is limited like the equivalent unary calls.
+type grpcServerStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	s       *Server
+	limited bool
+}
+
+func (ss *grpcServerStream) Context() context.Context {
+	return ss.ctx
+}
+
+func (ss *grpcServerStream) RecvMsg(m any) error {
+	if err := ss.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if !ss.limited {
+		return nil
+	}
+	msg, _ := m.(proto.Message)
+	return ss.s.grpcCheckLimits(ss.ctx, int64(proto.Size(msg)))
+}
+
+// grpcService implements the gRPC OCR service on top of the server's
+// pipelines.
+type grpcService struct {
+	pogov1.UnimplementedOCRServiceServer
+	s *Server
+}
+
+// RecognizeImage OCRs a single image.
+func (g *grpcService) RecognizeImage(ctx context.Context,
+	req *pogov1.RecognizeImageRequest,
+) (*pogov1.RecognizeImageResponse, error) {
+	res, err := g.recognizeImage(ctx, "image", req.GetImage(), req.GetOptions())
+	if err != nil {
+		ocrRequestsTotal.WithLabelValues("image", "error").Inc()
+		return nil, err
+	}
+	return &pogov1.RecognizeImageResponse{Result: imageResultToProto(res)}, nil
+}
+
+// recognizeImage decodes and OCRs an image under the processing timeout and
+// records the metrics of a result under the given request type. A partial
+// result is returned without error, marked as truncated.
+func (g *grpcService) recognizeImage(ctx context.Context, requestType string, data []byte,
+	opts *pogov1.Options,
+) (*pipeline.OCRImageResult, error) {
+	if len(data) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no image data provided")
+	}
+	uploadSizeBytes.Observe(float64(len(data)))
+	img, err := decodeImage(ctx, data)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid image format: %v", err)
+	}
+	reqConfig, err := g.requestConfig(opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	pl, err := g.s.getPipelineForRequest(reqConfig)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create pipeline: %v", err)
+	}
+
+	ctx, cancel := g.s.processingContext(ctx)
+	defer cancel()
+	start := time.Now()
+	res, err := pl.ProcessImageContext(ctx, img)
+	if err != nil && (res == nil || !res.Truncated) {
+		return nil, status.Errorf(processingErrorCode(err), "OCR processing failed: %v", err)
+	}
+
+	if res.Truncated {
+		ocrRequestsTotal.WithLabelValues(requestType, "truncated").Inc()
+	} else {
+		ocrRequestsTotal.WithLabelValues(requestType, "success").Inc()
+	}
+	ocrProcessingDuration.WithLabelValues(requestType).Observe(time.Since(start).Seconds())
+	var textLength int
+	for _, region := range res.Regions {
+		textLength += len(region.Text)
+	}
+	ocrTextLength.WithLabelValues(requestType).Observe(float64(textLength))
+	ocrRegionsDetected.WithLabelValues(requestType).Observe(float64(len(res.Regions)))
+	return res, nil
+}
+
+// RecognizePDF OCRs a PDF and sends every page as soon as it is recognized,
+// followed by the document summary. Pages of encrypted PDFs and of vector
+// text extraction are sent once the whole document is processed.
+func (g *grpcService) RecognizePDF(req *pogov1.RecognizePDFRequest,
+	stream grpc.ServerStreamingServer[pogov1.RecognizePDFResponse],
+) error {
+	res, err := g.recognizePDF(stream.Context(), "pdf", req.GetPdf(), req.GetOptions(), req.GetPdfOptions(),
+		func(page *pipeline.OCRPDFPageResult) error {
+			return stream.Send(&pogov1.RecognizePDFResponse{
+				Event: &pogov1.RecognizePDFResponse_Page{Page: pdfPageToProto(page)},
+			})
+		})
+	if err != nil {
+		ocrRequestsTotal.WithLabelValues("pdf", "error").Inc()
+		return err
+	}
+	res.Filename = req.GetFilename()
+	return stream.Send(&pogov1.RecognizePDFResponse{
+		Event: &pogov1.RecognizePDFResponse_Summary{Summary: pdfSummaryToProto(res)},
+	})
+}
+
+// recognizePDF OCRs a PDF under the processing timeout like recognizeImage
+// and calls emit with every page in order, if emit is not nil; otherwise the
+// pages are returned in the result.
+func (g *grpcService) recognizePDF(ctx context.Context, requestType string, data []byte, opts *pogov1.Options,
+	pdfOpts *pogov1.PDFOptions, emit func(*pipeline.OCRPDFPageResult) error,
+) (*pipeline.OCRPDFResult, error) {
+	if len(data) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no PDF data provided")
+	}
+	uploadSizeBytes.Observe(float64(len(data)))
+	reqConfig, err := g.requestConfig(opts, pdfOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	tempFile, err := os.CreateTemp("", "grpc_pdf_*.pdf")
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create temporary file: %v", err)
+	}
+	defer func() {
+		_ = tempFile.Close()
+		_ = os.Remove(tempFile.Name())
+	}()
+	if _, err := tempFile.Write(data); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to write temporary file: %v", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to write temporary file: %v", err)
+	}
+
+	needsEnhanced := reqConfig.UserPassword != "" || reqConfig.OwnerPassword != "" ||
+		reqConfig.EnableVectorText || reqConfig.EnableHybrid
+	var pl pipelineInterface
+	if !needsEnhanced {
+		if pl, err = g.s.getPipelineForRequest(reqConfig); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to create pipeline: %v", err)
+		}
+	}
+
+	ctx, cancel := g.s.processingContext(ctx)
+	defer cancel()
+	start := time.Now()
+	var textLength, regions int
+	count := func(page *pipeline.OCRPDFPageResult) {
+		for _, img := range page.Images {
+			regions += len(img.Regions)
+			for _, region := range img.Regions {
+				textLength += len(region.Text)
+			}
+		}
+	}
+
+	var res *pipeline.OCRPDFResult
+	switch {
+	case needsEnhanced:
+		res, err = g.s.processEnhancedPDF(ctx, tempFile.Name(), pdfOpts.GetPages(), reqConfig)
+		if res != nil && emit != nil && (err == nil || res.Truncated) {
+			for i := range res.Pages {
+				count(&res.Pages[i])
+				if sendErr := emit(&res.Pages[i]); sendErr != nil {
+					return nil, sendErr
+				}
+			}
+			res.Pages = nil
+		}
+	case emit != nil:
+		res, err = pl.ProcessPDFStream(ctx, tempFile.Name(), pdfOpts.GetPages(), func(page *pipeline.OCRPDFPageResult) error {
+			count(page)
+			return emit(page)
+		})
+	default:
+		res, err = pl.ProcessPDFContext(ctx, tempFile.Name(), pdfOpts.GetPages())
+	}
+	if err != nil && (res == nil || !res.Truncated) {
+		if _, ok := status.FromError(err); ok {
+			// A failed send; the stream is gone.
+			return nil, err
+		}
+		return nil, status.Errorf(processingErrorCode(err), "OCR processing failed: %v", err)
+	}
+
+	if res.Truncated {
+		ocrRequestsTotal.WithLabelValues(requestType, "truncated").Inc()
+	} else {
+		ocrRequestsTotal.WithLabelValues(requestType, "success").Inc()
+	}
+	ocrProcessingDuration.WithLabelValues(requestType).Observe(time.Since(start).Seconds())
+	for i := range res.Pages {
+		count(&res.Pages[i])
+	}
+	ocrTextLength.WithLabelValues(requestType).Observe(float64(textLength))
+	ocrRegionsDetected.WithLabelValues(requestType).Observe(float64(regions))
+	return res, nil
+}
+
+// RecognizeBatch answers every item of the request stream with its result.
+// Items are processed in order, each under its own processing timeout; a
+// failed item is reported in its result and does not end the stream.
+func (g *grpcService) RecognizeBatch(stream grpc.BidiStreamingServer[pogov1.BatchItem, pogov1.BatchItemResult]) error {
+	for index := int32(0); ; index++ {
+		item, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		result := &pogov1.BatchItemResult{Name: item.GetName(), Index: index}
+		start := time.Now()
+		switch content := item.GetContent().(type) {
+		case *pogov1.BatchItem_Image:
+			var res *pipeline.OCRImageResult
+			if res, err = g.recognizeImage(stream.Context(), "batch", content.Image, item.GetOptions()); err == nil {
+				result.Result = &pogov1.BatchItemResult_Image{Image: imageResultToProto(res)}
+			}
+		case *pogov1.BatchItem_Pdf:
+			var res *pipeline.OCRPDFResult
+			if res, err = g.recognizePDF(stream.Context(), "batch", content.Pdf, item.GetOptions(), item.GetPdfOptions(), nil); err == nil {
+				res.Filename = item.GetName()
+				result.Result = &pogov1.BatchItemResult_Pdf{Pdf: pdfResultToProto(res)}
+			}
+		default:
+			err = status.Error(codes.InvalidArgument, "no image or PDF provided")
+		}
+		result.DurationSeconds = time.Since(start).Seconds()
+		result.Success = err == nil
+		if err != nil {
+			result.Error = status.Convert(err).Message()
+			ocrRequestsTotal.WithLabelValues("batch", "error").Inc()
+		}
+
+		if err := stream.Send(result); err != nil {
+			return err
+		}
+	}
+}
+
+// requestConfig builds and validates the request configuration of a call;
+// pdfOpts is nil for images.
+func (g *grpcService) requestConfig(opts *pogov1.Options, pdfOpts *pogov1.PDFOptions) (*RequestConfig, error) {
+	reqConfig := &RequestConfig{
+		Profile:        opts.GetProfile(),
+		Language:       opts.GetLanguage(),
+		DictPath:       opts.GetDict(),
+		DetModel:       opts.GetDetModel(),
+		RecModel:       opts.GetRecModel(),
+		EnableBarcodes: opts.GetBarcodes(),
+		BarcodeTypes:   opts.GetBarcodeTypes(),
+		BarcodeMinSize: int(opts.GetBarcodeMinSize()),
+		BarcodeDPI:     int(opts.GetBarcodeDpi()),
+	}
+	for _, lang := range opts.GetDictLangs() {
+		reqConfig.DictLangs = append(reqConfig.DictLangs, strings.TrimSpace(lang))
+	}
+	if pdfOpts != nil {
+		reqConfig.UserPassword = pdfOpts.GetUserPassword()
+		reqConfig.OwnerPassword = pdfOpts.GetOwnerPassword()
+		reqConfig.EnableVectorText = pdfOpts.GetVectorText()
+		reqConfig.EnableHybrid = pdfOpts.GetHybrid()
+		reqConfig.PDFWorkers = int(pdfOpts.GetWorkers())
+		reqConfig.QualityThreshold = 0.7 // Default threshold
+		if q := pdfOpts.GetQualityThreshold(); q > 0 && q <= 1.0 {
+			reqConfig.QualityThreshold = q
+		}
+	}
+	if err := reqConfig.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid options: %v", err)
+	}
+	if err := g.s.checkModelSelection(reqConfig); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid options: %v", err)
+	}
+	return reqConfig, nil
+}
+
+// processingErrorCode maps an OCR error to a status code, like
+// processingErrorStatus does for HTTP.
+func processingErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	}
+	return codes.Internal
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	pogov1 "github.com/MeKo-Tech/pogo/api/pogo/v1"
+	"github.com/MeKo-Tech/pogo/internal/pdf"
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+)
+
+// Conversions of pipeline results to their gRPC messages. Field for field,
+// the messages match the JSON results of the HTTP API.
+
+func imageResultToProto(res *pipeline.OCRImageResult) *pogov1.ImageResult {
+	if res == nil {
+		return nil
+	}
+	return &pogov1.ImageResult{
+		Width:            int32(res.Width),
+		Height:           int32(res.Height),
+		Regions:          regionsToProto(res.Regions),
+		Barcodes:         barcodesToProto(res.Barcodes),
+		AvgDetConfidence: res.AvgDetConf,
+		Orientation: &pogov1.Orientation{
+			Angle:      int32(res.Orientation.Angle),
+			Confidence: res.Orientation.Confidence,
+			Applied:    res.Orientation.Applied,
+		},
+		Processing: &pogov1.ImageProcessing{
+			DetectionNs:   res.Processing.DetectionNs,
+			RecognitionNs: res.Processing.RecognitionNs,
+			TotalNs:       res.Processing.TotalNs,
+		},
+		Truncated: res.Truncated,
+	}
+}
+
+func pdfResultToProto(res *pipeline.OCRPDFResult) *pogov1.PDFResult {
+	if res == nil {
+		return nil
+	}
+	out := &pogov1.PDFResult{
+		Filename:     res.Filename,
+		TotalPages:   int32(res.TotalPages),
+		Pages:        make([]*pogov1.PDFPageResult, 0, len(res.Pages)),
+		ExtractionNs: res.Processing.ExtractionNs,
+		TotalNs:      res.Processing.TotalNs,
+		Truncated:    res.Truncated,
+	}
+	for i := range res.Pages {
+		out.Pages = append(out.Pages, pdfPageToProto(&res.Pages[i]))
+	}
+	return out
+}
+
+// pdfSummaryToProto returns the totals of a PDF result, without its pages.
+func pdfSummaryToProto(res *pipeline.OCRPDFResult) *pogov1.PDFSummary {
+	return &pogov1.PDFSummary{
+		Filename:     res.Filename,
+		TotalPages:   int32(res.TotalPages),
+		ExtractionNs: res.Processing.ExtractionNs,
+		TotalNs:      res.Processing.TotalNs,
+		Truncated:    res.Truncated,
+	}
+}
+
+func pdfPageToProto(page *pipeline.OCRPDFPageResult) *pogov1.PDFPageResult {
+	out := &pogov1.PDFPageResult{
+		PageNumber: int32(page.PageNumber),
+		Width:      int32(page.Width),
+		Height:     int32(page.Height),
+		WidthPt:    page.WidthPt,
+		HeightPt:   page.HeightPt,
+		Rotate:     int32(page.Rotate),
+		Images:     make([]*pogov1.PDFImageResult, 0, len(page.Images)),
+		Warnings:   page.Warnings,
+		TotalNs:    page.Processing.TotalNs,
+	}
+	for _, img := range page.Images {
+		pi := &pogov1.PDFImageResult{
+			ImageIndex: int32(img.ImageIndex),
+			Width:      int32(img.Width),
+			Height:     int32(img.Height),
+			Regions:    regionsToProto(img.Regions),
+			Barcodes:   barcodesToProto(img.Barcodes),
+			Confidence: img.Confidence,
+		}
+		if img.Placement != nil {
+			pi.Placement = img.Placement[:]
+		}
+		out.Images = append(out.Images, pi)
+	}
+	return out
+}
+
+func regionsToProto(regions []pipeline.OCRRegionResult) []*pogov1.Region {
+	out := make([]*pogov1.Region, 0, len(regions))
+	for _, r := range regions {
+		region := &pogov1.Region{
+			Polygon:         make([]*pogov1.Point, 0, len(r.Polygon)),
+			Box:             intBoxToProto(r.Box.X, r.Box.Y, r.Box.W, r.Box.H),
+			DetConfidence:   r.DetConfidence,
+			Text:            r.Text,
+			RecConfidence:   r.RecConfidence,
+			CharConfidences: r.CharConfidences,
+			Rotated:         r.Rotated,
+			Language:        r.Language,
+			Page:            pageCoordsToProto(r.Page),
+			Timing: &pogov1.RegionTiming{
+				RecognizePreprocessNs: r.Timing.RecognizePreprocessNs,
+				RecognizeModelNs:      r.Timing.RecognizeModelNs,
+				RecognizeDecodeNs:     r.Timing.RecognizeDecodeNs,
+				RecognizeTotalNs:      r.Timing.RecognizeTotalNs,
+			},
+		}
+		for _, p := range r.Polygon {
+			region.Polygon = append(region.Polygon, &pogov1.Point{X: p.X, Y: p.Y})
+		}
+		for _, w := range r.Words {
+			region.Words = append(region.Words, &pogov1.Word{
+				Text:       w.Text,
+				Confidence: w.Confidence,
+				Box:        &pogov1.Box{X: w.Box.X, Y: w.Box.Y, W: w.Box.W, H: w.Box.H},
+				Page:       pageCoordsToProto(w.Page),
+			})
+		}
+		out = append(out, region)
+	}
+	return out
+}
+
+func barcodesToProto(barcodes []pipeline.BarcodeResult) []*pogov1.Barcode {
+	if len(barcodes) == 0 {
+		return nil
+	}
+	out := make([]*pogov1.Barcode, 0, len(barcodes))
+	for _, b := range barcodes {
+		barcode := &pogov1.Barcode{
+			Type:       b.Type,
+			Value:      b.Value,
+			Confidence: b.Confidence,
+			Rotation:   b.Rotation,
+			Box:        intBoxToProto(b.Box.X, b.Box.Y, b.Box.W, b.Box.H),
+		}
+		for _, p := range b.Points {
+			barcode.Points = append(barcode.Points, &pogov1.Point{X: float64(p.X), Y: float64(p.Y)})
+		}
+		out = append(out, barcode)
+	}
+	return out
+}
+
+func pageCoordsToProto(c *pdf.PageCoords) *pogov1.PageCoords {
+	if c == nil {
+		return nil
+	}
+	return &pogov1.PageCoords{
+		Polygon:        pagePointsToProto(c.Polygon),
+		Box:            &pogov1.Box{X: c.Box.X, Y: c.Box.Y, W: c.Box.W, H: c.Box.H},
+		PolygonTopLeft: pagePointsToProto(c.PolygonTopLeft),
+		BoxTopLeft:     &pogov1.Box{X: c.BoxTopLeft.X, Y: c.BoxTopLeft.Y, W: c.BoxTopLeft.W, H: c.BoxTopLeft.H},
+	}
+}
+
+func pagePointsToProto(points []pdf.PagePoint) []*pogov1.Point {
+	out := make([]*pogov1.Point, 0, len(points))
+	for _, p := range points {
+		out = append(out, &pogov1.Point{X: p.X, Y: p.Y})
+	}
+	return out
+}
+
+func intBoxToProto(x, y, w, h int) *pogov1.Box {
+	return &pogov1.Box{X: float64(x), Y: float64(y), W: float64(w), H: float64(h)}
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	pogov1 "github.com/MeKo-Tech/pogo/api/pogo/v1"
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newGRPCTestClient serves the gRPC API of s in memory and returns a client
+// connection to it.
+func newGRPCTestClient(t *testing.T, s *Server) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := s.NewGRPCServer()
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func newGRPCTestServer() *Server {
+	imgRes := &pipeline.OCRImageResult{Width: 20, Height: 20, Regions: []pipeline.OCRRegionResult{{Text: "hello", RecConfidence: 0.9}}}
+	imgRes.Regions[0].Polygon = []struct{ X, Y float64 }{{1, 2}, {3, 2}, {3, 4}, {1, 4}}
+	pdfRes := &pipeline.OCRPDFResult{TotalPages: 2, Pages: []pipeline.OCRPDFPageResult{
+		{PageNumber: 1, Images: []pipeline.OCRPDFImageResult{{Regions: []pipeline.OCRRegionResult{{Text: "one"}}}}},
+		{PageNumber: 2, Images: []pipeline.OCRPDFImageResult{{Regions: []pipeline.OCRRegionResult{{Text: "two"}}}}},
+	}}
+	return &Server{
+		pipeline:    &mockPipelineForTesting{processImageResult: imgRes, processPDFResult: pdfRes},
+		maxUploadMB: 10,
+	}
+}
+
+func TestGRPC_RecognizeImage(t *testing.T) {
+	client := pogov1.NewOCRServiceClient(newGRPCTestClient(t, newGRPCTestServer()))
+	imgData, err := encodeImageToPNG(createTestImage(20, 20))
+	require.NoError(t, err)
+
+	resp, err := client.RecognizeImage(context.Background(), &pogov1.RecognizeImageRequest{Image: imgData})
+	require.NoError(t, err)
+	res := resp.GetResult()
+	assert.Equal(t, int32(20), res.GetWidth())
+	require.Len(t, res.GetRegions(), 1)
+	assert.Equal(t, "hello", res.GetRegions()[0].GetText())
+	assert.Len(t, res.GetRegions()[0].GetPolygon(), 4)
+
+	_, err = client.RecognizeImage(context.Background(), &pogov1.RecognizeImageRequest{Image: []byte("not an image")})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.RecognizeImage(context.Background(), &pogov1.RecognizeImageRequest{
+		Image:   imgData,
+		Options: &pogov1.Options{Language: "en$"},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "options are validated like HTTP requests")
+
+	_, err = client.RecognizeImage(context.Background(), &pogov1.RecognizeImageRequest{
+		Image:   imgData,
+		Options: &pogov1.Options{DetModel: "/tmp/det.onnx"},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "model paths need --allow-model-paths")
+}
+
+func TestGRPC_RecognizePDF(t *testing.T) {
+	client := pogov1.NewOCRServiceClient(newGRPCTestClient(t, newGRPCTestServer()))
+	stream, err := client.RecognizePDF(context.Background(), &pogov1.RecognizePDFRequest{
+		Filename: "doc.pdf",
+		Pdf:      []byte("%PDF-1.4"),
+	})
+	require.NoError(t, err)
+
+	var pages []int32
+	var summary *pogov1.PDFSummary
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if page := msg.GetPage(); page != nil {
+			require.Nil(t, summary, "the summary is the last message")
+			pages = append(pages, page.GetPageNumber())
+		} else {
+			summary = msg.GetSummary()
+		}
+	}
+	assert.Equal(t, []int32{1, 2}, pages)
+	require.NotNil(t, summary)
+	assert.Equal(t, "doc.pdf", summary.GetFilename())
+	assert.Equal(t, int32(2), summary.GetTotalPages())
+	assert.False(t, summary.GetTruncated())
+}
+
+func TestGRPC_RecognizeBatch(t *testing.T) {
+	client := pogov1.NewOCRServiceClient(newGRPCTestClient(t, newGRPCTestServer()))
+	imgData, err := encodeImageToPNG(createTestImage(20, 20))
+	require.NoError(t, err)
+
+	stream, err := client.RecognizeBatch(context.Background())
+	require.NoError(t, err)
+	items := []*pogov1.BatchItem{
+		{Name: "a.png", Content: &pogov1.BatchItem_Image{Image: imgData}},
+		{Name: "empty"},
+		{Name: "b.pdf", Content: &pogov1.BatchItem_Pdf{Pdf: []byte("%PDF-1.4")}},
+	}
+	for _, item := range items {
+		require.NoError(t, stream.Send(item))
+	}
+	require.NoError(t, stream.CloseSend())
+
+	var results []*pogov1.BatchItemResult
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		results = append(results, res)
+	}
+	require.Len(t, results, 3)
+
+	assert.True(t, results[0].GetSuccess())
+	assert.Equal(t, "hello", results[0].GetImage().GetRegions()[0].GetText())
+	assert.False(t, results[1].GetSuccess(), "a failed item does not end the stream")
+	assert.Equal(t, int32(1), results[1].GetIndex())
+	assert.NotEmpty(t, results[1].GetError())
+	assert.True(t, results[2].GetSuccess())
+	assert.Equal(t, "b.pdf", results[2].GetPdf().GetFilename())
+	assert.Len(t, results[2].GetPdf().GetPages(), 2)
+}
+
+func TestGRPC_Authentication(t *testing.T) {
+	s, _ := newAuthTestServer(t)
+	conn := newGRPCTestClient(t, s)
+	client := pogov1.NewOCRServiceClient(conn)
+	imgData, err := encodeImageToPNG(createTestImage(20, 20))
+	require.NoError(t, err)
+	req := &pogov1.RecognizeImageRequest{Image: imgData}
+
+	_, err = client.RecognizeImage(context.Background(), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "pdf-secret")
+	_, err = client.RecognizeImage(ctx, req)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer img-secret")
+	_, err = client.RecognizeImage(ctx, req)
+	require.NoError(t, err)
+	_, err = client.RecognizeImage(ctx, req)
+	require.NoError(t, err)
+	_, err = client.RecognizeImage(ctx, req)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "calls count against the key's rate limits")
+
+	// Health checks need no key.
+	health, err := healthpb.NewHealthClient(conn).Check(context.Background(),
+		&healthpb.HealthCheckRequest{Service: pogov1.OCRService_ServiceDesc.ServiceName})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, health.GetStatus())
+}
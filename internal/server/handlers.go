@@ -12,7 +12,16 @@ import (
 )
 
 const (
-	formatText = "text"
+	formatText          = "text"
+	formatSearchablePDF = "searchable-pdf"
+	formatHOCR          = "hocr"
+	formatALTO          = "alto"
+	formatPageXML       = "page"
+	formatMarkdown      = "markdown"
+	formatNDJSON        = "ndjson"
+
+	ndjsonContentType = "application/x-ndjson"
+	xmlContentType    = "application/xml; charset=utf-8"
 )
 
 // healthHandler returns server health status.
@@ -53,8 +62,14 @@ func (s *Server) modelsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := ModelsResponse{
-		Models: modelList,
-		Count:  len(modelList),
+		Models:   modelList,
+		Count:    len(modelList),
+		Profiles: make([]ProfileInfo, 0, len(s.profiles.Names())),
+	}
+	for _, name := range s.profiles.Names() {
+		p, _ := s.profiles.Get(name)
+		info := ProfileInfo{Name: p.Name, Description: p.Description, Language: p.Language, DictLangs: p.DictLangs}
+		response.Profiles = append(response.Profiles, info)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1,7 +1,6 @@
 package server
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -25,6 +24,8 @@ const (
 
 // RequestConfig holds per-request configuration overrides.
 type RequestConfig struct {
+	// Profile selects a server-side model profile by name.
+	Profile   string
 	Language  string
 	DictLangs []string
 	DictPath  string
@@ -74,6 +75,12 @@ func validatePath(path string, fieldName string) error {
 
 // Validate validates the request configuration parameters.
 func (c *RequestConfig) Validate() error {
+	if c.Profile != "" {
+		if err := validateProfileName(c.Profile); err != nil {
+			return err
+		}
+	}
+
 	// Validate language code
 	if c.Language != "" {
 		if err := validateLanguageCode(c.Language); err != nil {
@@ -135,18 +142,25 @@ func (s *Server) ocrImageHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Run full OCR pipeline with timing
+	ctx, cancel := s.processingContext(r.Context())
+	defer cancel()
 	start := time.Now()
-	res, err := pipeline.ProcessImage(img)
+	res, err := pipeline.ProcessImageContext(ctx, img)
 	duration := time.Since(start)
 
-	if err != nil {
+	if err != nil && (res == nil || !res.Truncated) {
 		ocrRequestsTotal.WithLabelValues("image", "error").Inc()
-		s.writeErrorResponse(w, fmt.Sprintf("OCR processing failed: %v", err), http.StatusInternalServerError)
+		s.writeErrorResponse(w, fmt.Sprintf("OCR processing failed: %v", err), processingErrorStatus(err))
 		return
 	}
 
-	// Record successful metrics
-	ocrRequestsTotal.WithLabelValues("image", "success").Inc()
+	// Record successful metrics; a partial result is sent like a full one
+	if res.Truncated {
+		ocrRequestsTotal.WithLabelValues("image", "truncated").Inc()
+		markTruncated(w)
+	} else {
+		ocrRequestsTotal.WithLabelValues("image", "success").Inc()
+	}
 	ocrProcessingDuration.WithLabelValues("image").Observe(duration.Seconds())
 
 	// Calculate total text length from all regions
@@ -165,39 +179,49 @@ func (s *Server) parseImageRequest(w http.ResponseWriter, r *http.Request) (imag
 	// Set content length limit
 	r.Body = http.MaxBytesReader(w, r.Body, s.maxUploadMB*1024*1024)
 
-	// Parse multipart form
+	// Parse multipart form; requests passing a url may be URL-encoded
 	err := r.ParseMultipartForm(s.maxUploadMB * 1024 * 1024)
-	if err != nil {
+	if err != nil && !errors.Is(err, http.ErrNotMultipart) {
 		s.writeErrorResponse(w, "Failed to parse form data", http.StatusBadRequest)
 		return nil, nil, err
 	}
 
-	// Get uploaded file
+	// Get the uploaded file, or fetch the image the url field references
+	var imageData []byte
 	file, header, err := r.FormFile("image")
-	if err != nil {
-		s.writeErrorResponse(w, "No image file provided", http.StatusBadRequest)
-		return nil, nil, err
-	}
-	defer func() { _ = file.Close() }()
-
-	// Validate file size
-	if header.Size > s.maxUploadMB*1024*1024 {
-		s.writeErrorResponse(w, "File too large", http.StatusRequestEntityTooLarge)
-		return nil, nil, err
-	}
+	switch {
+	case err == nil:
+		defer func() { _ = file.Close() }()
+
+		// Validate file size
+		if header.Size > s.maxUploadMB*1024*1024 {
+			s.writeErrorResponse(w, "File too large", http.StatusRequestEntityTooLarge)
+			return nil, nil, errors.New("file too large")
+		}
 
-	// Record upload size metric
-	uploadSizeBytes.Observe(float64(header.Size))
+		// Record upload size metric
+		uploadSizeBytes.Observe(float64(header.Size))
 
-	// Read file content
-	imageData, err := io.ReadAll(file)
-	if err != nil {
-		s.writeErrorResponse(w, "Failed to read image data", http.StatusInternalServerError)
+		// Read file content
+		imageData, err = io.ReadAll(file)
+		if err != nil {
+			s.writeErrorResponse(w, "Failed to read image data", http.StatusInternalServerError)
+			return nil, nil, err
+		}
+	case r.FormValue("url") != "":
+		fetched, err := s.fetchInput(r.Context(), r.FormValue("url"))
+		if err != nil {
+			s.writeFetchError(w, err)
+			return nil, nil, err
+		}
+		imageData = fetched.Data
+	default:
+		s.writeErrorResponse(w, "No image file provided", http.StatusBadRequest)
 		return nil, nil, err
 	}
 
 	// Decode image
-	img, _, err := image.Decode(bytes.NewReader(imageData))
+	img, err := decodeImage(r.Context(), imageData)
 	if err != nil {
 		s.writeErrorResponse(w, "Invalid image format", http.StatusBadRequest)
 		return nil, nil, err
@@ -205,6 +229,7 @@ func (s *Server) parseImageRequest(w http.ResponseWriter, r *http.Request) (imag
 
 	// Extract request configuration
 	reqConfig := &RequestConfig{
+		Profile:  r.FormValue("profile"),
 		Language: r.FormValue("language"),
 		DictPath: r.FormValue("dict"),
 		DetModel: r.FormValue("det-model"),
@@ -242,6 +267,10 @@ func (s *Server) parseImageRequest(w http.ResponseWriter, r *http.Request) (imag
 		s.writeErrorResponse(w, fmt.Sprintf("Invalid request parameters: %v", err), http.StatusBadRequest)
 		return nil, nil, err
 	}
+	if err := s.checkModelSelection(reqConfig); err != nil {
+		s.writeErrorResponse(w, fmt.Sprintf("Invalid request parameters: %v", err), http.StatusBadRequest)
+		return nil, nil, err
+	}
 
 	return img, reqConfig, nil
 }
@@ -265,6 +294,14 @@ func (s *Server) writeImageResponse(
 		s.writeTextResponse(w, res)
 	case "overlay":
 		s.handleOverlayOutput(w, r, img, res)
+	case formatSearchablePDF:
+		s.writeSearchableImageResponse(w, r, img, res)
+	case formatHOCR:
+		s.writeDocumentResponse(w, "text/html; charset=utf-8", func() (string, error) { return pipeline.ToHOCRImage(res, "") })
+	case formatALTO, formatPageXML:
+		s.writeDocumentResponse(w, xmlContentType, func() (string, error) { return imageDocument(res, "", format) })
+	case formatMarkdown:
+		s.writeDocumentResponse(w, "text/markdown; charset=utf-8", func() (string, error) { return pipeline.ToMarkdownImage(res) })
 	default:
 		// Check for overlay parameter
 		if r.FormValue("overlay") == "1" {
@@ -295,6 +332,17 @@ func (s *Server) writeTextResponse(w http.ResponseWriter, res *pipeline.OCRImage
 	_, _ = w.Write([]byte(textStr))
 }
 
+// writeDocumentResponse writes a text document (hOCR, Markdown) produced by render.
+func (s *Server) writeDocumentResponse(w http.ResponseWriter, contentType string, render func() (string, error)) {
+	doc, err := render()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("formatting failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write([]byte(doc))
+}
+
 func (s *Server) writeJSONResponse(w http.ResponseWriter, res *pipeline.OCRImageResult) {
 	w.Header().Set("Content-Type", "application/json")
 	obj := struct {
@@ -366,24 +414,29 @@ func (s *Server) handleOverlayOutput(
 // getPipelineForRequest returns a pipeline configured for the specific request.
 // Creates or retrieves a cached pipeline based on the request configuration.
 func (s *Server) getPipelineForRequest(reqConfig *RequestConfig) (pipelineInterface, error) {
-	// If no custom configuration is requested, use the default pipeline
-	hasCustomConfig := reqConfig.DetModel != "" || reqConfig.RecModel != "" ||
-		reqConfig.Language != "" || reqConfig.DictPath != "" || len(reqConfig.DictLangs) > 0
-
-	if !hasCustomConfig && s.pipeline != nil {
-		return s.pipeline, nil
+	if err := s.probes.checkStarted(); err != nil {
+		return nil, err
+	}
+	if err := s.checkModelSelection(reqConfig); err != nil {
+		return nil, err
 	}
 
 	// If pipeline cache is not available, return error
+	defaultPipeline, baseConfig := s.defaultPipelineConfig()
 	if s.pipelineCache == nil {
-		if s.pipeline != nil {
-			return s.pipeline, nil
+		if defaultPipeline != nil {
+			return defaultPipeline, nil
 		}
 		return nil, errors.New("OCR pipeline not initialized")
 	}
 
 	// Start with base configuration and apply overrides
-	config := s.applyRequestOverrides(s.baseConfig, reqConfig)
+	config := s.applyRequestOverrides(baseConfig, reqConfig)
+
+	// Requests whose options change nothing use the default pipeline
+	if defaultPipeline != nil && configKey(config) == configKey(baseConfig) {
+		return defaultPipeline, nil
+	}
 
 	// Get or create cached pipeline
 	return s.pipelineCache.GetOrCreate(config)
@@ -393,6 +446,11 @@ func (s *Server) getPipelineForRequest(reqConfig *RequestConfig) (pipelineInterf
 func (s *Server) applyRequestOverrides(baseConfig pipeline.Config, reqConfig *RequestConfig) pipeline.Config {
 	config := baseConfig
 
+	// Start from the selected profile; the options below refine it
+	if profile, ok := s.profiles.Get(reqConfig.Profile); ok {
+		config = profile.apply(config)
+	}
+
 	// Override detector model if specified
 	if reqConfig.DetModel != "" {
 		config.Detector.ModelPath = reqConfig.DetModel
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -335,3 +336,73 @@ func TestRequestConfig_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_ocrImageHandler_HOCR(t *testing.T) {
+	res := &pipeline.OCRImageResult{Width: 100, Height: 50}
+	res.Regions = []pipeline.OCRRegionResult{{Text: "Hello <World>", RecConfidence: 0.9, Box: struct{ X, Y, W, H int }{X: 5, Y: 5, W: 60, H: 20}}}
+	server := &Server{pipeline: &mockPipelineForTesting{processImageResult: res}, maxUploadMB: 10}
+
+	imgData, err := encodeImageToPNG(createTestImage(100, 50))
+	require.NoError(t, err)
+	req, err := createMultipartFormRequest(imgData, "test.png", map[string]string{"format": "hocr"})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+
+	server.ocrImageHandler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	assert.Contains(t, body, `class="ocr_page"`)
+	assert.Contains(t, body, `class="ocr_line"`)
+	assert.Contains(t, body, ">&lt;World&gt;</span>")
+}
+
+func TestServer_ocrImageHandler_LayoutXML(t *testing.T) {
+	res := &pipeline.OCRImageResult{Width: 100, Height: 50}
+	res.Regions = []pipeline.OCRRegionResult{{Text: "Hello", RecConfidence: 0.9, Box: struct{ X, Y, W, H int }{X: 5, Y: 5, W: 60, H: 20}}}
+	server := &Server{pipeline: &mockPipelineForTesting{processImageResult: res}, maxUploadMB: 10}
+
+	tests := map[string]string{
+		"alto": `<alto xmlns="http://www.loc.gov/standards/alto/ns-v4#"`,
+		"page": `<Page imageFilename=""`,
+	}
+	for format, want := range tests {
+		t.Run(format, func(t *testing.T) {
+			imgData, err := encodeImageToPNG(createTestImage(100, 50))
+			require.NoError(t, err)
+			req, err := createMultipartFormRequest(imgData, "test.png", map[string]string{"format": format})
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+
+			server.ocrImageHandler(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.Equal(t, xmlContentType, w.Header().Get("Content-Type"))
+			assert.Contains(t, w.Body.String(), want)
+			assert.Contains(t, w.Body.String(), "Hello")
+		})
+	}
+}
+
+func TestServer_ocrImageHandler_Markdown(t *testing.T) {
+	res := &pipeline.OCRImageResult{Width: 200, Height: 100}
+	res.Regions = []pipeline.OCRRegionResult{
+		{Text: "Title", Box: struct{ X, Y, W, H int }{X: 5, Y: 5, W: 100, H: 40}},
+		{Text: "- item", Box: struct{ X, Y, W, H int }{X: 5, Y: 50, W: 60, H: 20}},
+		{Text: "body", Box: struct{ X, Y, W, H int }{X: 5, Y: 75, W: 40, H: 20}},
+	}
+	server := &Server{pipeline: &mockPipelineForTesting{processImageResult: res}, maxUploadMB: 10}
+
+	imgData, err := encodeImageToPNG(createTestImage(200, 100))
+	require.NoError(t, err)
+	req, err := createMultipartFormRequest(imgData, "test.png", map[string]string{"format": "markdown"})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+
+	server.ocrImageHandler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "text/markdown; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "# Title\n\n- item\n\nbody\n", w.Body.String())
+}
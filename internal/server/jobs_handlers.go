@@ -0,0 +1,304 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MeKo-Tech/pogo/internal/jobs"
+)
+
+// maxJobItems limits the number of images and PDFs in one job.
+const maxJobItems = 100
+
+// Job types.
+const (
+	jobTypeImage = "image"
+	jobTypePDF   = "pdf"
+	jobTypeBatch = "batch"
+)
+
+// jobOptionFields are the form fields of a job upload passed on as options.
+var jobOptionFields = []string{"profile", "language", "dict", "dict-langs", "det-model", "rec-model"}
+
+// JobRequest is the JSON body of a job submission: a batch request with an
+// optional callback URL notified when the job finishes.
+type JobRequest struct {
+	BatchOCRRequest
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// jobsHandler enqueues a job. The body is either a job request (JSON) or a
+// multipart upload of a single image or PDF in the "file" field.
+func (s *Server) jobsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.jobs == nil {
+		s.writeErrorResponse(w, "Job queue not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxUploadMB*1024*1024)
+	var req JobRequest
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		req, err = s.parseJobUpload(r)
+	} else if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err = fmt.Errorf("failed to parse JSON request: %w", err)
+	}
+	if err == nil && req.CallbackURL != "" {
+		if s.webhooks == nil {
+			err = errors.New("callback_url is not supported by this server")
+		} else {
+			err = s.webhooks.validateCallbackURL(req.CallbackURL)
+		}
+	}
+	if err != nil {
+		s.writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	totalItems := len(req.Images) + len(req.PDFs)
+	if totalItems == 0 {
+		s.writeErrorResponse(w, "No images or PDFs provided in job request", http.StatusBadRequest)
+		return
+	}
+	if totalItems > maxJobItems {
+		s.writeErrorResponse(w, fmt.Sprintf("Job too large (maximum %d items)", maxJobItems), http.StatusBadRequest)
+		return
+	}
+
+	// A job needs the scope of the endpoint that would process it directly
+	typ := jobType(req.BatchOCRRequest)
+	key := apiKeyFromContext(r.Context())
+	if !key.HasScope(typ) {
+		apiKeyRejections.WithLabelValues(key.Name, "scope").Inc()
+		s.writeErrorResponse(w, fmt.Sprintf("API key lacks the %q scope", typ), http.StatusForbidden)
+		return
+	}
+	var owner string
+	if key != nil {
+		owner = key.Name
+	}
+
+	payload, err := json.Marshal(req.BatchOCRRequest)
+	if err != nil {
+		s.writeErrorResponse(w, fmt.Sprintf("Failed to encode job: %v", err), http.StatusInternalServerError)
+		return
+	}
+	job, err := s.jobs.Submit(jobs.Spec{
+		Type:        typ,
+		Items:       totalItems,
+		CallbackURL: req.CallbackURL,
+		Owner:       owner,
+	}, payload)
+	switch {
+	case errors.Is(err, jobs.ErrQueueFull), errors.Is(err, jobs.ErrClosed):
+		s.writeErrorResponse(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		s.writeErrorResponse(w, fmt.Sprintf("Failed to enqueue job: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	s.writeJob(w, job, http.StatusAccepted)
+}
+
+// parseJobUpload turns a multipart upload into a job request with one item.
+func (s *Server) parseJobUpload(r *http.Request) (JobRequest, error) {
+	var req JobRequest
+	if err := r.ParseMultipartForm(s.maxUploadMB * 1024 * 1024); err != nil {
+		return req, errors.New("failed to parse form data")
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return req, errors.New("no file provided")
+	}
+	defer func() { _ = file.Close() }()
+	uploadSizeBytes.Observe(float64(header.Size))
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return req, errors.New("failed to read uploaded file")
+	}
+
+	options := make(map[string]interface{})
+	for _, key := range jobOptionFields {
+		if v := r.FormValue(key); v != "" {
+			options[key] = v
+		}
+	}
+	req.Format = r.FormValue("format")
+	req.CallbackURL = r.FormValue("callback_url")
+	if isPDFUpload(header.Filename, data) {
+		req.PDFs = []BatchPDFRequest{{Name: header.Filename, Data: data, Pages: r.FormValue("pages"), Options: options}}
+	} else {
+		req.Images = []BatchImageRequest{{Name: header.Filename, Data: data, Options: options}}
+	}
+	return req, nil
+}
+
+// isPDFUpload reports whether an upload is a PDF, by extension or signature.
+func isPDFUpload(filename string, data []byte) bool {
+	return strings.EqualFold(filepath.Ext(filename), ".pdf") || bytes.HasPrefix(data, []byte("%PDF-"))
+}
+
+// jobType names a job after its only item, or "batch" for several items.
+func jobType(req BatchOCRRequest) string {
+	switch {
+	case len(req.Images)+len(req.PDFs) > 1:
+		return jobTypeBatch
+	case len(req.PDFs) == 1:
+		return jobTypePDF
+	default:
+		return jobTypeImage
+	}
+}
+
+// jobHandler returns the status of a job (GET) or cancels it (DELETE).
+// Deleting a finished job removes it along with its result.
+func (s *Server) jobHandler(w http.ResponseWriter, r *http.Request) {
+	if s.jobs == nil {
+		s.writeErrorResponse(w, "Job queue not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	id := r.PathValue("id")
+
+	switch r.Method {
+	case http.MethodGet:
+		job, err := s.getJob(r, id)
+		if err != nil {
+			s.writeJobError(w, err)
+			return
+		}
+		s.writeJob(w, job, http.StatusOK)
+	case http.MethodDelete:
+		job, err := s.getJob(r, id)
+		if err != nil {
+			s.writeJobError(w, err)
+			return
+		}
+		if job.Status.Final() {
+			if err := s.jobs.Delete(id); err != nil {
+				s.writeJobError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		job, err = s.jobs.Cancel(id)
+		if err != nil {
+			s.writeJobError(w, err)
+			return
+		}
+		s.writeJob(w, job, http.StatusOK)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// jobResultHandler returns the result of a succeeded job, a batch response
+// with one result per item.
+func (s *Server) jobResultHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.jobs == nil {
+		s.writeErrorResponse(w, "Job queue not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	id := r.PathValue("id")
+	job, err := s.getJob(r, id)
+	if err != nil {
+		s.writeJobError(w, err)
+		return
+	}
+
+	result, err := s.jobs.Result(id)
+	if errors.Is(err, jobs.ErrNoResult) {
+		s.writeErrorResponse(w, fmt.Sprintf("Job is %s, no result available", job.Status), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		s.writeJobError(w, err)
+		return
+	}
+	defer func() { _ = result.Close() }()
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := io.Copy(w, result); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing job result: %v\n", err)
+	}
+}
+
+// getJob returns a job if the client of the request may access it. Jobs of
+// other API keys are reported as not found.
+func (s *Server) getJob(r *http.Request, id string) (jobs.Job, error) {
+	job, err := s.jobs.Get(id)
+	if err == nil && !canAccessJob(apiKeyFromContext(r.Context()), job.Owner) {
+		return jobs.Job{}, jobs.ErrNotFound
+	}
+	return job, err
+}
+
+func (s *Server) writeJob(w http.ResponseWriter, job jobs.Job, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding job response: %v\n", err)
+	}
+}
+
+func (s *Server) writeJobError(w http.ResponseWriter, err error) {
+	if errors.Is(err, jobs.ErrNotFound) {
+		s.writeErrorResponse(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	s.writeErrorResponse(w, err.Error(), http.StatusInternalServerError)
+}
+
+// runJob processes a queued job: its payload is a batch request and its
+// result the batch response. Jobs are not bound by the processing timeout.
+func (s *Server) runJob(ctx context.Context, job jobs.Job, payload []byte, progress jobs.ProgressFunc) ([]byte, error) {
+	var req BatchOCRRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return nil, fmt.Errorf("invalid job payload: %w", err)
+	}
+	// Jobs recovered at startup wait for the default pipeline
+	if err := s.probes.waitStarted(ctx); err != nil {
+		return nil, err
+	}
+	// Inputs fetched by URL are counted against the key that submitted the job
+	if s.rateLimiter != nil && s.apiKeys != nil && job.Owner != "" {
+		if key := s.apiKeys.named(job.Owner); key != nil {
+			ctx = withClient(context.WithValue(ctx, apiKeyContextKey{}, key), "")
+		}
+	}
+
+	results, summary := s.processBatchRequest(ctx, req, func(items, pages int) {
+		progress(jobs.Progress{Total: job.Progress.Total, Completed: items, Pages: pages})
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ocrRequestsTotal.WithLabelValues("job", "success").Inc()
+	ocrProcessingDuration.WithLabelValues("job").Observe(summary.TotalDuration)
+
+	return json.Marshal(BatchOCRResponse{
+		Success: summary.Failed == 0,
+		Results: results,
+		Summary: summary,
+	})
+}
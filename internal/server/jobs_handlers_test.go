@@ -0,0 +1,177 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MeKo-Tech/pogo/internal/jobs"
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingPipeline blocks image processing until its context ends.
+type blockingPipeline struct {
+	mockPipelineForTesting
+	started chan struct{}
+}
+
+func (b *blockingPipeline) ProcessImageContext(ctx context.Context, _ image.Image) (*pipeline.OCRImageResult, error) {
+	close(b.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// newJobsTestServer returns a server with a job queue and its routes.
+func newJobsTestServer(t *testing.T, pl pipelineInterface) (*Server, http.Handler) {
+	t.Helper()
+	s := &Server{pipeline: pl, maxUploadMB: 10}
+	q, err := jobs.Open(jobs.Config{Dir: t.TempDir()}, s.runJob)
+	require.NoError(t, err)
+	s.jobs = q
+	t.Cleanup(func() { _ = s.Close() })
+
+	mux := http.NewServeMux()
+	s.SetupRoutes(mux)
+	return s, mux
+}
+
+func doJobRequest(t *testing.T, h http.Handler, method, path string, body []byte, contentType string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func waitJob(t *testing.T, h http.Handler, id string, want jobs.Status) jobs.Job {
+	t.Helper()
+	var job jobs.Job
+	require.Eventually(t, func() bool {
+		w := doJobRequest(t, h, http.MethodGet, "/jobs/"+id, nil, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+		return job.Status == want
+	}, 5*time.Second, 10*time.Millisecond)
+	return job
+}
+
+func TestJobsAPI_BatchJob(t *testing.T) {
+	pdfRes := &pipeline.OCRPDFResult{TotalPages: 2, Pages: []pipeline.OCRPDFPageResult{{PageNumber: 1}, {PageNumber: 2}}}
+	_, h := newJobsTestServer(t, &mockPipelineForTesting{
+		processImageResult: &pipeline.OCRImageResult{Width: 20, Height: 20},
+		processPDFResult:   pdfRes,
+	})
+
+	imgData, err := encodeImageToPNG(createTestImage(20, 20))
+	require.NoError(t, err)
+	body, err := json.Marshal(BatchOCRRequest{
+		Images: []BatchImageRequest{{Name: "a.png", Data: imgData}},
+		PDFs:   []BatchPDFRequest{{Name: "b.pdf", Data: []byte("%PDF-1.4")}},
+	})
+	require.NoError(t, err)
+
+	w := doJobRequest(t, h, http.MethodPost, "/jobs", body, "application/json")
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var job jobs.Job
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, "/jobs/"+job.ID, w.Header().Get("Location"))
+	assert.Equal(t, jobTypeBatch, job.Type)
+	assert.Equal(t, 2, job.Progress.Total)
+
+	job = waitJob(t, h, job.ID, jobs.StatusSucceeded)
+	assert.Equal(t, jobs.Progress{Total: 2, Completed: 2, Pages: 2}, job.Progress)
+
+	w = doJobRequest(t, h, http.MethodGet, "/jobs/"+job.ID+"/result", nil, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Success bool `json:"success"`
+		Results []struct {
+			Type    string          `json:"type"`
+			Success bool            `json:"success"`
+			Result  json.RawMessage `json:"result"`
+		} `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Success)
+	require.Len(t, resp.Results, 2)
+	assert.Equal(t, "pdf", resp.Results[1].Type)
+	assert.Contains(t, string(resp.Results[1].Result), `"page_number":2`)
+
+	// Deleting a finished job removes it.
+	w = doJobRequest(t, h, http.MethodDelete, "/jobs/"+job.ID, nil, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = doJobRequest(t, h, http.MethodGet, "/jobs/"+job.ID, nil, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestJobsAPI_UploadAndCancel(t *testing.T) {
+	pl := &blockingPipeline{started: make(chan struct{})}
+	_, h := newJobsTestServer(t, pl)
+
+	imgData, err := encodeImageToPNG(createTestImage(20, 20))
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, err := mw.CreateFormFile("file", "scan.png")
+	require.NoError(t, err)
+	_, err = part.Write(imgData)
+	require.NoError(t, err)
+	require.NoError(t, mw.WriteField("language", "de"))
+	require.NoError(t, mw.Close())
+
+	w := doJobRequest(t, h, http.MethodPost, "/jobs", buf.Bytes(), mw.FormDataContentType())
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var job jobs.Job
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, jobTypeImage, job.Type)
+
+	<-pl.started
+	w = doJobRequest(t, h, http.MethodGet, "/jobs/"+job.ID+"/result", nil, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "running")
+
+	w = doJobRequest(t, h, http.MethodDelete, "/jobs/"+job.ID, nil, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, jobs.StatusCanceled, job.Status)
+}
+
+func TestJobsAPI_Errors(t *testing.T) {
+	_, h := newJobsTestServer(t, &mockPipelineForTesting{})
+
+	w := doJobRequest(t, h, http.MethodPost, "/jobs", []byte(`{}`), "application/json")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doJobRequest(t, h, http.MethodPost, "/jobs", []byte(`not json`), "application/json")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doJobRequest(t, h, http.MethodGet, "/jobs/0123456789abcdef", nil, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doJobRequest(t, h, http.MethodGet, "/jobs/0123456789abcdef/result", nil, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Without a job directory the API is disabled.
+	s := &Server{pipeline: &mockPipelineForTesting{}, maxUploadMB: 10}
+	mux := http.NewServeMux()
+	s.SetupRoutes(mux)
+	w = doJobRequest(t, mux, http.MethodPost, "/jobs", []byte(`{}`), "application/json")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestIsPDFUpload(t *testing.T) {
+	assert.True(t, isPDFUpload("doc.PDF", nil))
+	assert.True(t, isPDFUpload("upload", []byte("%PDF-1.7\n")))
+	assert.False(t, isPDFUpload("scan.png", []byte("\x89PNG")))
+}
@@ -69,6 +69,104 @@ var (
 		[]string{"type"}, // type: requests_per_minute, requests_per_hour, max_requests_per_day, max_data_per_day
 	)
 
+	// API key metrics, labeled by key name.
+	authFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pogo_auth_failures_total",
+			Help: "Total number of requests rejected for a missing or invalid API key",
+		},
+		[]string{"reason"}, // reason: missing, invalid
+	)
+
+	apiKeyRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pogo_api_key_requests_total",
+			Help: "Total number of requests admitted per API key",
+		},
+		[]string{"key"},
+	)
+
+	apiKeyDataBytes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pogo_api_key_data_bytes_total",
+			Help: "Total request data counted against the quota of each API key",
+		},
+		[]string{"key"},
+	)
+
+	apiKeyRejections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pogo_api_key_rejections_total",
+			Help: "Total number of requests rejected per API key",
+		},
+		[]string{"key", "reason"}, // reason: scope, rate_limit, quota
+	)
+
+	// Pipeline cache metrics.
+	pipelineCacheLookups = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pogo_pipeline_cache_lookups_total",
+			Help: "Total number of pipeline cache lookups",
+		},
+		[]string{"result"}, // result: hit, miss
+	)
+
+	pipelineCacheEvictions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pogo_pipeline_cache_evictions_total",
+			Help: "Total number of pipelines evicted from the cache",
+		},
+		[]string{"reason"}, // reason: memory, idle, admin
+	)
+
+	pipelineCacheEntries = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "pogo_pipeline_cache_entries",
+			Help: "Number of cached pipelines",
+		},
+	)
+
+	pipelineCacheMemoryBytes = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "pogo_pipeline_cache_memory_bytes",
+			Help: "Estimated memory of the cached pipelines in bytes",
+		},
+	)
+
+	// Admission control metrics, labeled by lane (interactive, bulk).
+	admissionQueueDepth = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "pogo_admission_queue_depth",
+			Help: "Number of requests waiting for a processing slot",
+		},
+		[]string{"lane"},
+	)
+
+	admissionInFlight = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "pogo_admission_in_flight",
+			Help: "Number of requests holding a processing slot",
+		},
+		[]string{"lane"},
+	)
+
+	admissionWaitSeconds = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "pogo_admission_wait_seconds",
+			Help:    "Time admitted requests waited for a processing slot in seconds",
+			Buckets: []float64{0, .01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"lane"},
+	)
+
+	admissionRejections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pogo_admission_rejections_total",
+			Help: "Total number of requests shed by admission control",
+		},
+		[]string{"lane", "reason"}, // reason: queue_full, timeout, memory_pressure
+	)
+
 	// File upload metrics.
 	uploadSizeBytes = promauto.NewHistogram(
 		prometheus.HistogramOpts{
@@ -78,6 +176,23 @@ var (
 		},
 	)
 
+	// Remote input metrics.
+	remoteFetchTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pogo_remote_fetch_total",
+			Help: "Total number of inputs fetched from URLs by outcome",
+		},
+		[]string{"result"}, // result: success, blocked, too_large, unsupported, error
+	)
+
+	remoteFetchBytes = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "pogo_remote_fetch_size_bytes",
+			Help:    "Size of inputs fetched from URLs in bytes",
+			Buckets: []float64{1024, 10 * 1024, 100 * 1024, 1024 * 1024, 10 * 1024 * 1024, 50 * 1024 * 1024, 100 * 1024 * 1024},
+		},
+	)
+
 	// WebSocket metrics.
 	websocketConnections = promauto.NewGauge(
 		prometheus.GaugeOpts{
@@ -93,4 +208,55 @@ var (
 		},
 		[]string{"direction"}, // direction: sent, received
 	)
+
+	// Webhook metrics.
+	webhookDeliveriesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pogo_webhook_deliveries_total",
+			Help: "Total number of webhook notifications by final outcome",
+		},
+		[]string{"status"}, // status: delivered, dead_lettered
+	)
+
+	webhookAttemptsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pogo_webhook_attempts_total",
+			Help: "Total number of webhook delivery attempts",
+		},
+		[]string{"status"}, // status: success, error
+	)
+
+	webhookAttemptDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "pogo_webhook_attempt_duration_seconds",
+			Help:    "Duration of webhook delivery attempts in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
+	// Self-test metrics.
+	selfTestTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pogo_self_test_total",
+			Help: "Total number of OCR self-tests of the default pipeline",
+		},
+		[]string{"result"}, // result: success, failure
+	)
+
+	selfTestDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "pogo_self_test_duration_seconds",
+			Help:    "Duration of OCR self-tests in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
+	// Reload metrics.
+	pipelineReloadsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pogo_pipeline_reloads_total",
+			Help: "Total number of default pipeline reloads by result",
+		},
+		[]string{"result"}, // result: success, failure
+	)
 )
@@ -1,10 +1,13 @@
 package server
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"net"
 	"net/http"
 	"strconv"
@@ -23,12 +26,28 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush streamed responses.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Hijack hands the connection over to WebSocket handlers.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := http.NewResponseController(rw.ResponseWriter).Hijack()
+	if err == nil {
+		rw.statusCode = http.StatusSwitchingProtocols
+	}
+	return conn, buf, err
+}
+
 // corsMiddleware adds CORS headers to responses.
 func (s *Server) corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", s.corsOrigin)
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+		w.Header().Set("Access-Control-Expose-Headers", "X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Retry-After")
 		// Cache preflight results for a day to reduce OPTIONS traffic
 		w.Header().Set("Access-Control-Max-Age", "86400")
 
@@ -44,13 +63,20 @@ func (s *Server) corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		next(rw, r)
 		duration := time.Since(start)
 
-		// Record metrics
-		httpRequestsTotal.WithLabelValues(r.Method, r.URL.Path, http.StatusText(rw.statusCode)).Inc()
-		httpRequestDuration.WithLabelValues(r.Method, r.URL.Path).Observe(duration.Seconds())
+		// Record metrics, labeled by route pattern so that IDs in paths
+		// (e.g. /jobs/{id}) do not create a series per request
+		endpoint := r.URL.Path
+		if r.Pattern != "" {
+			endpoint = r.Pattern
+		}
+		httpRequestsTotal.WithLabelValues(r.Method, endpoint, http.StatusText(rw.statusCode)).Inc()
+		httpRequestDuration.WithLabelValues(r.Method, endpoint).Observe(duration.Seconds())
 	}
 }
 
-// rateLimitMiddleware enforces rate limiting and quotas.
+// rateLimitMiddleware enforces rate limiting and quotas. Requests with an API
+// key are counted against the key and its own limits, others against the
+// client IP.
 func (s *Server) rateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Skip rate limiting if not configured
@@ -59,34 +85,108 @@ func (s *Server) rateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		// Get user identifier (IP address for now, could be extended to use API keys)
-		userID := getClientIP(r)
-
 		// Get content length for data quota checking
 		var dataSize int64
 		if r.ContentLength > 0 {
 			dataSize = r.ContentLength
 		}
 
-		// Check rate limits
-		if err := s.rateLimiter.CheckRateLimit(userID, dataSize); err != nil {
-			// Record rate limit hit
-			{
-				var e *RateLimitError
-				var e1 *QuotaExceededError
-				switch {
-				case errors.As(err, &e):
-					rateLimitHits.WithLabelValues(e.Type).Inc()
-				case errors.As(err, &e1):
-					rateLimitHits.WithLabelValues(e1.Type).Inc()
-				}
-			}
+		clientID := getClientIP(r)
+		status, err := s.checkClientLimits(clientID, apiKeyFromContext(r.Context()), dataSize)
+		setRateLimitHeaders(w, status)
+		if err != nil {
 			s.handleRateLimitError(w, err)
 			return
 		}
 
-		next(w, r)
+		next(w, r.WithContext(withClient(r.Context(), clientID)))
+	}
+}
+
+type clientContextKey struct{}
+
+// withClient records the client address a request is counted against, so
+// that input fetched on its behalf is counted as well.
+func withClient(ctx context.Context, clientID string) context.Context {
+	return context.WithValue(ctx, clientContextKey{}, clientID)
+}
+
+// chargeClientData counts dataSize bytes of input fetched for a request
+// against the data quota of its API key or client address. Requests that
+// were not counted by the rate limiter are not charged.
+func (s *Server) chargeClientData(ctx context.Context, dataSize int64) error {
+	clientID, ok := ctx.Value(clientContextKey{}).(string)
+	if s.rateLimiter == nil || !ok {
+		return nil
+	}
+	userID := clientID
+	limits := s.rateLimiter.Limits()
+	key := apiKeyFromContext(ctx)
+	if key != nil {
+		userID = "key:" + key.Name
+		limits = key.limits(limits)
+	}
+
+	if err := s.rateLimiter.ChargeData(userID, dataSize, limits); err != nil {
+		var e *QuotaExceededError
+		if errors.As(err, &e) {
+			rateLimitHits.WithLabelValues(e.Type).Inc()
+		}
+		if key != nil {
+			apiKeyRejections.WithLabelValues(key.Name, "quota").Inc()
+		}
+		return err
+	}
+	if key != nil {
+		apiKeyDataBytes.WithLabelValues(key.Name).Add(float64(dataSize))
+	}
+	return nil
+}
+
+// checkClientLimits counts a request of dataSize bytes against the limits of
+// its API key or, without a key, of the client address.
+func (s *Server) checkClientLimits(clientID string, key *APIKey, dataSize int64) (RateLimitStatus, error) {
+	userID := clientID
+	limits := s.rateLimiter.Limits()
+	if key != nil {
+		userID = "key:" + key.Name
+		limits = key.limits(limits)
+	}
+
+	status, err := s.rateLimiter.CheckLimits(userID, dataSize, limits)
+	if err != nil {
+		// Record rate limit hit
+		var e *RateLimitError
+		var e1 *QuotaExceededError
+		switch {
+		case errors.As(err, &e):
+			rateLimitHits.WithLabelValues(e.Type).Inc()
+			if key != nil {
+				apiKeyRejections.WithLabelValues(key.Name, "rate_limit").Inc()
+			}
+		case errors.As(err, &e1):
+			rateLimitHits.WithLabelValues(e1.Type).Inc()
+			if key != nil {
+				apiKeyRejections.WithLabelValues(key.Name, "quota").Inc()
+			}
+		}
+		return status, err
+	}
+	if key != nil {
+		apiKeyRequests.WithLabelValues(key.Name).Inc()
+		apiKeyDataBytes.WithLabelValues(key.Name).Add(float64(dataSize))
+	}
+	return status, nil
+}
+
+// setRateLimitHeaders reports the tightest request limit of a client.
+func setRateLimitHeaders(w http.ResponseWriter, status RateLimitStatus) {
+	if status.Limit == 0 {
+		return
 	}
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(status.Limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(status.Remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(status.Reset.Seconds()))))
 }
 
 // handleRateLimitError handles rate limit and quota errors.
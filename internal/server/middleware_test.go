@@ -88,7 +88,7 @@ func TestServer_CORSMiddleware(t *testing.T) {
 			// Check CORS headers
 			assert.Equal(t, tt.expectedCORS, w.Header().Get("Access-Control-Allow-Origin"))
 			assert.Equal(t, "GET, POST, PUT, DELETE, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
-			assert.Equal(t, "Content-Type, Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+			assert.Equal(t, "Content-Type, Authorization, X-API-Key", w.Header().Get("Access-Control-Allow-Headers"))
 
 			// Check if next handler was called
 			assert.Equal(t, tt.shouldCallNext, nextCalled)
@@ -105,7 +105,7 @@ func TestServer_CORSMiddleware_HeadersSet(t *testing.T) {
 		// Next handler should see CORS headers already set
 		assert.Equal(t, "https://myapp.com", w.Header().Get("Access-Control-Allow-Origin"))
 		assert.Equal(t, "GET, POST, PUT, DELETE, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
-		assert.Equal(t, "Content-Type, Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "Content-Type, Authorization, X-API-Key", w.Header().Get("Access-Control-Allow-Headers"))
 		w.WriteHeader(http.StatusOK)
 	})
 
@@ -141,7 +141,7 @@ func TestServer_CORSMiddleware_OptionsOnly(t *testing.T) {
 	// Verify CORS headers are still set for OPTIONS
 	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
 	assert.Equal(t, "GET, POST, PUT, DELETE, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
-	assert.Equal(t, "Content-Type, Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "Content-Type, Authorization, X-API-Key", w.Header().Get("Access-Control-Allow-Headers"))
 }
 
 func TestServer_CORSMiddleware_ErrorInNext(t *testing.T) {
@@ -1,7 +1,9 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -30,37 +32,69 @@ func (s *Server) ocrPdfHandler(w http.ResponseWriter, r *http.Request) {
 		ocrRequestsTotal.WithLabelValues("pdf", "error").Inc()
 		return // error already written
 	}
-	defer func() { _ = file.Close() }()
 
-	// Save uploaded file to temporary location
-	tempFile, err := s.saveUploadedFile(file, header)
-	if err != nil {
-		s.writeErrorResponse(w, fmt.Sprintf("Failed to save uploaded file: %v", err), http.StatusInternalServerError)
-		ocrRequestsTotal.WithLabelValues("pdf", "error").Inc()
-		return
+	// Save uploaded file, or fetch the PDF the url field references, to a
+	// temporary location. Results name the PDF as the client did, not by
+	// the temporary file.
+	var tempFile, filename string
+	if file != nil {
+		defer func() { _ = file.Close() }()
+		filename = header.Filename
+		tempFile, err = s.saveUploadedFile(file, header)
+		if err != nil {
+			s.writeErrorResponse(w, fmt.Sprintf("Failed to save uploaded file: %v", err), http.StatusInternalServerError)
+			ocrRequestsTotal.WithLabelValues("pdf", "error").Inc()
+			return
+		}
+	} else {
+		tempFile, filename, err = s.fetchPDFToTempFile(r.Context(), r.FormValue("url"))
+		if err != nil {
+			s.writeFetchError(w, err)
+			ocrRequestsTotal.WithLabelValues("pdf", "error").Inc()
+			return
+		}
 	}
 	defer func() { _ = os.Remove(tempFile) }() // Clean up temp file
 
 	// Process PDF and handle response
-	s.processPdfAndRespond(w, r, tempFile, pageRange, reqConfig)
+	s.processPdfAndRespond(w, r, tempFile, filename, pageRange, reqConfig)
 }
 
 // processPdfAndRespond handles PDF processing and response formatting.
+// tempFile is the PDF to process, filename the name results report for it.
 func (s *Server) processPdfAndRespond(w http.ResponseWriter, r *http.Request,
-	tempFile, pageRange string, reqConfig *RequestConfig,
+	tempFile, filename, pageRange string, reqConfig *RequestConfig,
 ) {
 	// Check if we need enhanced PDF processing (passwords or vector text)
 	needsEnhanced := reqConfig.UserPassword != "" || reqConfig.OwnerPassword != "" ||
 		reqConfig.EnableVectorText || reqConfig.EnableHybrid
 
+	// Searchable PDFs need recognized text for every page, which only the full pipeline provides.
+	// Streamed results and progress events come from the pipeline as well.
+	output, sse := requestFormat(r), requestsSSE(r)
+	if sse {
+		output = "sse"
+	}
+	if output == formatSearchablePDF || output == formatNDJSON || sse {
+		if reqConfig.UserPassword != "" || reqConfig.OwnerPassword != "" {
+			s.writeErrorResponse(w, output+" output is not supported for encrypted PDFs", http.StatusBadRequest)
+			ocrRequestsTotal.WithLabelValues("pdf", "error").Inc()
+			return
+		}
+		needsEnhanced = false
+	}
+
 	var res *pipeline.OCRPDFResult
 	var duration time.Duration
 	var err error
 
+	ctx, cancel := s.processingContext(r.Context())
+	defer cancel()
+
 	if needsEnhanced {
 		// Use enhanced PDF processor
 		start := time.Now()
-		res, err = s.processEnhancedPDF(tempFile, pageRange, reqConfig)
+		res, err = s.processEnhancedPDF(ctx, tempFile, pageRange, reqConfig)
 		duration = time.Since(start)
 	} else {
 		// Use standard pipeline processing
@@ -71,19 +105,35 @@ func (s *Server) processPdfAndRespond(w http.ResponseWriter, r *http.Request,
 			return
 		}
 
+		if sse {
+			s.streamPdfEvents(ctx, w, pipeline, tempFile, filename, pageRange)
+			return
+		}
+		if requestFormat(r) == formatNDJSON {
+			s.streamPdfResponse(ctx, w, pipeline, tempFile, filename, pageRange)
+			return
+		}
+
 		start := time.Now()
-		res, err = pipeline.ProcessPDF(tempFile, pageRange)
+		res, err = pipeline.ProcessPDFContext(ctx, tempFile, pageRange)
 		duration = time.Since(start)
 	}
 
-	if err != nil {
+	if err != nil && (res == nil || !res.Truncated) {
 		ocrRequestsTotal.WithLabelValues("pdf", "error").Inc()
-		s.writeErrorResponse(w, fmt.Sprintf("OCR processing failed: %v", err), http.StatusInternalServerError)
+		s.writeErrorResponse(w, fmt.Sprintf("OCR processing failed: %v", err), processingErrorStatus(err))
 		return
 	}
+	res.Filename = filename
 
-	// Record successful metrics
-	ocrRequestsTotal.WithLabelValues("pdf", "success").Inc()
+	// Record successful metrics; the pages finished before a deadline are
+	// sent like a full result
+	if res.Truncated {
+		ocrRequestsTotal.WithLabelValues("pdf", "truncated").Inc()
+		markTruncated(w)
+	} else {
+		ocrRequestsTotal.WithLabelValues("pdf", "success").Inc()
+	}
 	ocrProcessingDuration.WithLabelValues("pdf").Observe(duration.Seconds())
 
 	// Calculate total text length and regions from all pages
@@ -101,7 +151,67 @@ func (s *Server) processPdfAndRespond(w http.ResponseWriter, r *http.Request,
 	ocrRegionsDetected.WithLabelValues("pdf").Observe(float64(totalRegions))
 
 	// Format and send response
-	s.writePdfResponse(w, r, res)
+	s.writePdfResponse(w, r, res, tempFile)
+}
+
+// streamPdfResponse OCRs the PDF page by page and sends every page as an
+// NDJSON line as soon as it is recognized, followed by a document line with
+// the totals. Once the first line is out the status code is fixed, so a
+// failure is reported as a final error line, and a deadline as a document
+// line marked as truncated.
+func (s *Server) streamPdfResponse(ctx context.Context, w http.ResponseWriter, pl pipelineInterface,
+	tempFile, filename, pageRange string,
+) {
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	sw := pipeline.NewPDFStreamWriter(flushWriter{w, http.NewResponseController(w)})
+
+	var textLength, regions int
+	start := time.Now()
+	res, err := pl.ProcessPDFStream(ctx, tempFile, pageRange, func(page *pipeline.OCRPDFPageResult) error {
+		for _, img := range page.Images {
+			regions += len(img.Regions)
+			for _, region := range img.Regions {
+				textLength += len(region.Text)
+			}
+		}
+		return sw.WritePage(filename, page)
+	})
+	if err != nil && (res == nil || !res.Truncated) {
+		ocrRequestsTotal.WithLabelValues("pdf", "error").Inc()
+		if writeErr := sw.WriteError(filename, err); writeErr != nil {
+			fmt.Fprintf(os.Stderr, "Error writing PDF stream: %v\n", writeErr)
+		}
+		return
+	}
+
+	if res.Truncated {
+		ocrRequestsTotal.WithLabelValues("pdf", "truncated").Inc()
+	} else {
+		ocrRequestsTotal.WithLabelValues("pdf", "success").Inc()
+	}
+	ocrProcessingDuration.WithLabelValues("pdf").Observe(time.Since(start).Seconds())
+	ocrTextLength.WithLabelValues("pdf").Observe(float64(textLength))
+	ocrRegionsDetected.WithLabelValues("pdf").Observe(float64(regions))
+	res.Filename = filename
+	if err := sw.WriteDocument(res); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing PDF stream: %v\n", err)
+	}
+}
+
+// flushWriter flushes a response after every write where the connection
+// supports it.
+type flushWriter struct {
+	io.Writer
+	rc *http.ResponseController
+}
+
+func (f flushWriter) Flush() error {
+	if err := f.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
 }
 
 func (s *Server) parsePdfRequest(
@@ -111,35 +221,40 @@ func (s *Server) parsePdfRequest(
 	// Set content length limit
 	r.Body = http.MaxBytesReader(w, r.Body, s.maxUploadMB*1024*1024)
 
-	// Parse multipart form
+	// Parse multipart form; requests passing a url may be URL-encoded
 	err := r.ParseMultipartForm(s.maxUploadMB * 1024 * 1024)
-	if err != nil {
+	if err != nil && !errors.Is(err, http.ErrNotMultipart) {
 		s.handleFormParseError(w, err)
 		return nil, nil, "", nil, err
 	}
 
-	// Get uploaded file
+	// Get uploaded file; without one, the url field references the PDF
 	file, header, err := r.FormFile("pdf")
-	if err != nil {
+	if err != nil && r.FormValue("url") == "" {
 		s.writeErrorResponse(w, "No PDF file provided", http.StatusBadRequest)
 		return nil, nil, "", nil, err
 	}
+	if err == nil {
+		// Validate file size
+		if header.Size > s.maxUploadMB*1024*1024 {
+			_ = file.Close()
+			s.writeErrorResponse(w, "File too large", http.StatusRequestEntityTooLarge)
+			return nil, nil, "", nil, errors.New("file too large")
+		}
 
-	// Validate file size
-	if header.Size > s.maxUploadMB*1024*1024 {
-		s.writeErrorResponse(w, "File too large", http.StatusRequestEntityTooLarge)
-		return nil, nil, "", nil, err
+		// Record upload size metric
+		uploadSizeBytes.Observe(float64(header.Size))
 	}
 
-	// Record upload size metric
-	uploadSizeBytes.Observe(float64(header.Size))
-
 	// Get page range parameter
 	pageRange := r.FormValue("pages")
 
 	// Extract and validate request configuration
 	reqConfig, err := s.parseRequestConfig(r)
 	if err != nil {
+		if file != nil {
+			_ = file.Close()
+		}
 		s.writeErrorResponse(w, fmt.Sprintf("Invalid request parameters: %v", err), http.StatusBadRequest)
 		return nil, nil, "", nil, err
 	}
@@ -150,6 +265,7 @@ func (s *Server) parsePdfRequest(
 // parseRequestConfig extracts and validates request configuration from form values.
 func (s *Server) parseRequestConfig(r *http.Request) (*RequestConfig, error) {
     reqConfig := &RequestConfig{
+        Profile:  r.FormValue("profile"),
         Language: r.FormValue("language"),
         DictPath: r.FormValue("dict"),
         DetModel: r.FormValue("det-model"),
@@ -207,6 +323,9 @@ func (s *Server) parseRequestConfig(r *http.Request) (*RequestConfig, error) {
 	if err := reqConfig.Validate(); err != nil {
 		return nil, err
 	}
+	if err := s.checkModelSelection(reqConfig); err != nil {
+		return nil, err
+	}
 
 	return reqConfig, nil
 }
@@ -221,17 +340,29 @@ func (s *Server) handleFormParseError(w http.ResponseWriter, err error) {
 	}
 }
 
-func (s *Server) writePdfResponse(w http.ResponseWriter, r *http.Request, res *pipeline.OCRPDFResult) {
-	// Determine output format: default json; allow 'format' in query or form
-	format := r.FormValue("format")
-	if format == "" {
-		format = r.URL.Query().Get("format")
-	}
-
-	if format == formatText {
+// writePdfResponse writes the PDF OCR result; sourceFile is the uploaded PDF,
+// used as the base document for searchable PDF output.
+func (s *Server) writePdfResponse(w http.ResponseWriter, r *http.Request, res *pipeline.OCRPDFResult, sourceFile string) {
+	switch requestFormat(r) {
+	case formatText:
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		s.writePDFTextResponse(w, res)
 		return
+	case formatSearchablePDF:
+		s.writeSearchablePDFResponse(w, r, res, sourceFile)
+		return
+	case formatHOCR:
+		s.writeDocumentResponse(w, "text/html; charset=utf-8", func() (string, error) { return pipeline.ToHOCRPDF(res) })
+		return
+	case formatALTO:
+		s.writeDocumentResponse(w, xmlContentType, func() (string, error) { return pipeline.ToALTOPDF(res, pipeline.ExportMetadata{}) })
+		return
+	case formatPageXML:
+		s.writePageXMLResponse(w, res)
+		return
+	case formatMarkdown:
+		s.writeDocumentResponse(w, "text/markdown; charset=utf-8", func() (string, error) { return pipeline.ToMarkdownPDF(res) })
+		return
 	}
 
 	// default: json
@@ -244,6 +375,23 @@ func (s *Server) writePdfResponse(w http.ResponseWriter, r *http.Request, res *p
 	}
 }
 
+// writePageXMLResponse writes the PAGE XML documents of a PDF result, one
+// per page, as JSON.
+func (s *Server) writePageXMLResponse(w http.ResponseWriter, res *pipeline.OCRPDFResult) {
+	docs, err := pageXMLDocuments(res)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("formatting failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	obj := struct {
+		Pages []PageXMLDocument `json:"pages"`
+	}{Pages: docs}
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding PAGE XML response: %v\n", err)
+	}
+}
+
 // writePDFTextResponse writes a plain text representation of PDF OCR results.
 func (s *Server) writePDFTextResponse(w http.ResponseWriter, result *pipeline.OCRPDFResult) {
 	var output strings.Builder
@@ -300,22 +448,24 @@ func (s *Server) saveUploadedFile(file multipart.File, header *multipart.FileHea
 }
 
 // processEnhancedPDF processes a PDF using the enhanced processor with password and vector text support.
-func (s *Server) processEnhancedPDF(filename, pageRange string,
+func (s *Server) processEnhancedPDF(ctx context.Context, filename, pageRange string,
     reqConfig *RequestConfig,
 ) (*pipeline.OCRPDFResult, error) {
-	// Create detector for enhanced PDF processor
+	// Create detector for enhanced PDF processor, with the models and
+	// thresholds of the request's profile and options
+	_, baseConfig := s.defaultPipelineConfig()
+	// Resolve the default model before the request may replace it
+	baseConfig.Detector.UpdateModelPath(baseConfig.ModelsDir)
+	baseConfig = s.applyRequestOverrides(baseConfig, reqConfig)
 	detectorConfig := detector.Config{
-		ModelPath:    s.baseConfig.Detector.ModelPath,
-		NumThreads:   s.baseConfig.Detector.NumThreads,
-		DbThresh:     s.baseConfig.Detector.DbThresh,
-		DbBoxThresh:  s.baseConfig.Detector.DbBoxThresh,
-		UseNMS:       s.baseConfig.Detector.UseNMS,
-		NMSThreshold: s.baseConfig.Detector.NMSThreshold,
+		ModelPath:    baseConfig.Detector.ModelPath,
+		NumThreads:   baseConfig.Detector.NumThreads,
+		DbThresh:     baseConfig.Detector.DbThresh,
+		DbBoxThresh:  baseConfig.Detector.DbBoxThresh,
+		UseNMS:       baseConfig.Detector.UseNMS,
+		NMSThreshold: baseConfig.Detector.NMSThreshold,
 	}
 
-	// Update model paths if needed
-	detectorConfig.UpdateModelPath(s.baseConfig.ModelsDir)
-
 	det, err := detector.NewDetector(detectorConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create detector: %w", err)
@@ -335,6 +485,7 @@ func (s *Server) processEnhancedPDF(filename, pageRange string,
         BarcodeMinSize:      reqConfig.BarcodeMinSize,
         BarcodeTargetDPI:    func() int { if reqConfig.BarcodeDPI > 0 { return reqConfig.BarcodeDPI }; if s.barcodeDPI > 0 { return s.barcodeDPI }; return 150 }(),
         MaxWorkers:          func() int { if reqConfig.PDFWorkers > 0 { return reqConfig.PDFWorkers }; if s.pdfWorkers > 0 { return s.pdfWorkers }; return 0 }(),
+        MaxInFlightPages:    baseConfig.PDF.MaxInFlightPages,
     }
 
 	// Create enhanced PDF processor
@@ -350,19 +501,16 @@ func (s *Server) processEnhancedPDF(filename, pageRange string,
 	}
 
 	// Process the PDF with enhanced features
-	var result *pdf.DocumentResult
-	if creds != nil {
-		result, err = processor.ProcessFileWithCredentials(filename, pageRange, creds)
-	} else {
-		result, err = processor.ProcessFile(filename, pageRange)
-	}
-
+	result, err := processor.ProcessFileContext(ctx, filename, pageRange, creds)
 	if err != nil {
-		return nil, fmt.Errorf("enhanced PDF processing failed: %w", err)
+		err = fmt.Errorf("enhanced PDF processing failed: %w", err)
+		if result == nil {
+			return nil, err
+		}
 	}
 
 	// Convert enhanced result to pipeline format for compatibility
-	return s.convertEnhancedResultToPipeline(result), nil
+	return s.convertEnhancedResultToPipeline(result), err
 }
 
 // convertEnhancedResultToPipeline converts enhanced PDF results to pipeline format for API compatibility.
@@ -370,6 +518,7 @@ func (s *Server) convertEnhancedResultToPipeline(result *pdf.DocumentResult) *pi
 	pipelineResult := &pipeline.OCRPDFResult{
 		Filename:   result.Filename,
 		TotalPages: result.TotalPages,
+		Truncated:  result.Truncated,
 		Processing: struct {
 			ExtractionNs int64 `json:"extraction_ns"`
 			TotalNs      int64 `json:"total_ns"`
@@ -385,6 +534,10 @@ func (s *Server) convertEnhancedResultToPipeline(result *pdf.DocumentResult) *pi
 			PageNumber: page.PageNumber,
 			Width:      page.Width,
 			Height:     page.Height,
+			WidthPt:    page.WidthPt,
+			HeightPt:   page.HeightPt,
+			Rotate:     page.Rotate,
+			Warnings:   page.Warnings,
 			Processing: struct {
 				TotalNs int64 `json:"total_ns"`
 			}{
@@ -399,6 +552,7 @@ func (s *Server) convertEnhancedResultToPipeline(result *pdf.DocumentResult) *pi
 				Width:      img.Width,
 				Height:     img.Height,
 				Confidence: img.Confidence,
+				Placement:  img.Placement,
 			}
 
 			// Convert OCR regions to pipeline format
@@ -416,6 +570,12 @@ func (s *Server) convertEnhancedResultToPipeline(result *pdf.DocumentResult) *pi
 						Text:          region.Text,
 						RecConfidence: region.RecConfidence,
 						Language:      region.Language,
+						Page:          region.Page,
+					}
+					for _, w := range region.Words {
+						word := pipeline.OCRWordResult{Text: w.Text, Confidence: w.Confidence, Page: w.Page}
+						word.Box.X, word.Box.Y, word.Box.W, word.Box.H = w.Box.X, w.Box.Y, w.Box.W, w.Box.H
+						pipelineRegion.Words = append(pipelineRegion.Words, word)
 					}
 
 					// Convert polygon points
@@ -430,7 +590,7 @@ func (s *Server) convertEnhancedResultToPipeline(result *pdf.DocumentResult) *pi
 				}
 			} else {
 				// Fall back to basic detected regions
-				for _, region := range img.Regions {
+				for i, region := range img.Regions {
 					pipelineRegion := pipeline.OCRRegionResult{
 						Box: struct{ X, Y, W, H int }{
 							X: int(region.Box.MinX),
@@ -442,6 +602,9 @@ func (s *Server) convertEnhancedResultToPipeline(result *pdf.DocumentResult) *pi
 						Text:          "", // No text available from detection only
 						RecConfidence: 0,  // No recognition performed
 					}
+					if i < len(img.PageRegions) {
+						pipelineRegion.Page = &img.PageRegions[i]
+					}
 
 					// Convert polygon points if available
 					for _, point := range region.Polygon {
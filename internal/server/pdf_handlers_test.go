@@ -491,3 +491,162 @@ func TestServer_ocrPdfHandler_PipelineError(t *testing.T) {
 	assert.Equal(t, false, response["success"])
 	assert.Contains(t, response["error"], "OCR processing failed")
 }
+
+func TestServer_ocrPdfHandler_HOCR(t *testing.T) {
+	res := &pipeline.OCRPDFResult{TotalPages: 1, Pages: []pipeline.OCRPDFPageResult{{
+		PageNumber: 1, Width: 200, Height: 200,
+		Images: []pipeline.OCRPDFImageResult{{Width: 100, Height: 100, Regions: []pipeline.OCRRegionResult{
+			{Text: "Hello World", RecConfidence: 0.8, Box: struct{ X, Y, W, H int }{X: 10, Y: 10, W: 80, H: 10}},
+		}}},
+	}}}
+	server := &Server{pipeline: &mockPipelineForTesting{processPDFResult: res}, maxUploadMB: 10}
+
+	req, err := createMultipartPDFFormRequest([]byte("%PDF-1.4"), "doc.pdf", map[string]string{
+		"format":             "hocr",
+		"enable-vector-text": "false",
+	})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+
+	server.ocrPdfHandler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	assert.Contains(t, body, "bbox 0 0 200 200")
+	assert.Contains(t, body, "bbox 20 20 180 40")
+}
+
+func TestServer_ocrPdfHandler_LayoutXML(t *testing.T) {
+	page := func(n int, text string) pipeline.OCRPDFPageResult {
+		return pipeline.OCRPDFPageResult{PageNumber: n, Width: 200, Height: 200,
+			Images: []pipeline.OCRPDFImageResult{{Width: 200, Height: 200, Regions: []pipeline.OCRRegionResult{
+				{Text: text, RecConfidence: 0.8, Box: struct{ X, Y, W, H int }{X: 10, Y: 10, W: 80, H: 10}},
+			}}}}
+	}
+	res := &pipeline.OCRPDFResult{TotalPages: 2, Pages: []pipeline.OCRPDFPageResult{page(1, "one"), page(2, "two")}}
+	server := &Server{pipeline: &mockPipelineForTesting{processPDFResult: res}, maxUploadMB: 10}
+	request := func(format string) *httptest.ResponseRecorder {
+		req, err := createMultipartPDFFormRequest([]byte("%PDF-1.4"), "doc.pdf", map[string]string{
+			"format":             format,
+			"enable-vector-text": "false",
+		})
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		server.ocrPdfHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return w
+	}
+
+	t.Run("alto", func(t *testing.T) {
+		w := request("alto")
+		assert.Equal(t, xmlContentType, w.Header().Get("Content-Type"))
+		body := w.Body.String()
+		assert.Equal(t, 2, strings.Count(body, "<Page "), "one ALTO page per PDF page")
+		assert.Contains(t, body, `CONTENT="two"`)
+	})
+
+	t.Run("page", func(t *testing.T) {
+		w := request("page")
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		var resp struct {
+			Pages []PageXMLDocument `json:"pages"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		require.Len(t, resp.Pages, 2)
+		assert.Equal(t, 2, resp.Pages[1].PageNumber)
+		assert.Contains(t, resp.Pages[1].Document, `<PcGts xmlns="http://schema.primaresearch.org/PAGE/gts/pagecontent/2019-07-15"`)
+		assert.Contains(t, resp.Pages[1].Document, "two")
+	})
+}
+
+func TestServer_ocrPdfHandler_NDJSON(t *testing.T) {
+	page := func(n int, text string) pipeline.OCRPDFPageResult {
+		return pipeline.OCRPDFPageResult{PageNumber: n, Width: 200, Height: 200,
+			Images: []pipeline.OCRPDFImageResult{{Regions: []pipeline.OCRRegionResult{{Text: text}}}}}
+	}
+	// The pipeline names the PDF by the temporary upload file
+	res := &pipeline.OCRPDFResult{Filename: "/tmp/upload-1.pdf", TotalPages: 2,
+		Pages: []pipeline.OCRPDFPageResult{page(1, "one"), page(2, "two")}}
+
+	tests := []struct {
+		name  string
+		err   error
+		types []string
+	}{
+		{"success", nil, []string{pipeline.PDFEventPage, pipeline.PDFEventPage, pipeline.PDFEventDocument}},
+		{"error", errors.New("page 3: broken"), []string{pipeline.PDFEventPage, pipeline.PDFEventPage, pipeline.PDFEventError}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &Server{pipeline: &mockPipelineForTesting{processPDFResult: res, processPDFError: tt.err}, maxUploadMB: 10}
+			req, err := createMultipartPDFFormRequest([]byte("%PDF-1.4"), "scan.pdf", map[string]string{
+				"format":             "ndjson",
+				"enable-vector-text": "false",
+			})
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+
+			server.ocrPdfHandler(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+			assert.True(t, w.Flushed)
+			lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+			require.Len(t, lines, len(tt.types))
+			for i, line := range lines {
+				var ev pipeline.PDFStreamEvent
+				require.NoError(t, json.Unmarshal([]byte(line), &ev))
+				assert.Equal(t, tt.types[i], ev.Type)
+				assert.Equal(t, "scan.pdf", ev.Filename, "the name of the upload, not the temporary file")
+				switch ev.Type {
+				case pipeline.PDFEventPage:
+					require.NotNil(t, ev.Page)
+					assert.Equal(t, i+1, ev.Page.PageNumber)
+				case pipeline.PDFEventDocument:
+					require.NotNil(t, ev.Document)
+					assert.Equal(t, 2, ev.Document.TotalPages)
+					assert.Equal(t, "scan.pdf", ev.Document.Filename)
+					assert.Empty(t, ev.Document.Pages)
+				case pipeline.PDFEventError:
+					assert.Equal(t, "page 3: broken", ev.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_ocrPdfHandler_NDJSONEncrypted(t *testing.T) {
+	server := &Server{pipeline: &mockPipelineForTesting{}, maxUploadMB: 10}
+	req, err := createMultipartPDFFormRequest([]byte("%PDF-1.4"), "doc.pdf", map[string]string{
+		"format":        "ndjson",
+		"user-password": "secret",
+	})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+
+	server.ocrPdfHandler(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "ndjson output is not supported for encrypted PDFs")
+}
+
+func TestServer_ocrPdfHandler_AcceptNDJSON(t *testing.T) {
+	res := &pipeline.OCRPDFResult{Filename: "doc.pdf", TotalPages: 1, Pages: []pipeline.OCRPDFPageResult{{PageNumber: 1}}}
+	server := &Server{pipeline: &mockPipelineForTesting{processPDFResult: res}, maxUploadMB: 10}
+	req, err := createMultipartPDFFormRequest([]byte("%PDF-1.4"), "doc.pdf", map[string]string{
+		"enable-vector-text": "false",
+	})
+	require.NoError(t, err)
+	req.Header.Set("Accept", "application/x-ndjson")
+	w := httptest.NewRecorder()
+
+	server.ocrPdfHandler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"type":"page"`)
+	assert.Contains(t, lines[1], `"type":"document"`)
+}
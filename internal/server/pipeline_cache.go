@@ -0,0 +1,537 @@
+package server
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+)
+
+// Pipeline cache defaults.
+const (
+	DefaultPipelineCacheMemoryMB = 2048
+	DefaultPipelineCacheMaxIdle  = 30 * time.Minute
+
+	// modelMemoryFactor approximates the memory of an inference session from
+	// the size of its model file: the weights plus the runtime's buffers.
+	modelMemoryFactor = 3
+)
+
+var errPipelineCacheClosed = errors.New("pipeline cache closed")
+
+// PipelineCacheConfig bounds the pipelines cached for requests that select
+// their own models or options.
+type PipelineCacheConfig struct {
+	// MaxMemoryBytes is the budget for the estimated memory of all cached
+	// pipelines; the least recently used ones are evicted to stay within it.
+	// A single pipeline above the budget is still cached. 0 = no limit.
+	MaxMemoryBytes int64
+	// MaxIdle evicts pipelines that were not used for this long (0 = never).
+	MaxIdle time.Duration
+}
+
+// cacheEntry is a cached pipeline. Its fields other than key and ready are
+// guarded by the cache mutex.
+type cacheEntry struct {
+	key string
+	// config is the requested configuration and resolved the one the
+	// pipeline was built with, model paths included.
+	config   pipeline.Config
+	resolved pipeline.Config
+	pipeline pipelineInterface
+	// ready is closed once the pipeline is built or failed with err.
+	ready chan struct{}
+	err   error
+
+	memoryBytes int64
+	hits        int64
+	// active counts the calls using the pipeline; an evicted pipeline is
+	// closed when the last one returns.
+	active   int
+	evicted  bool
+	created  time.Time
+	lastUsed time.Time
+	elem     *list.Element
+}
+
+// PipelineCache caches pipelines by configuration to avoid recreating them.
+// Pipelines are evicted least recently used first when their estimated
+// memory exceeds the budget, and when they are idle for too long.
+type PipelineCache struct {
+	config PipelineCacheConfig
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	// lru holds the built entries, most recently used first.
+	lru         *list.List
+	memoryBytes int64
+	closed      bool
+
+	// create builds a pipeline and estimate its memory; replaced in tests.
+	create   func(pipeline.Config) (pipelineInterface, error)
+	estimate func(pipeline.Config) int64
+	now      func() time.Time
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewPipelineCache creates a new pipeline cache. With config.MaxIdle set,
+// idle pipelines are evicted in the background until Close.
+func NewPipelineCache(config PipelineCacheConfig) *PipelineCache {
+	c := &PipelineCache{
+		config:   config,
+		entries:  make(map[string]*cacheEntry),
+		lru:      list.New(),
+		create:   createPipeline,
+		estimate: estimatePipelineMemory,
+		now:      time.Now,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if config.MaxIdle > 0 {
+		go c.run(min(config.MaxIdle, time.Minute))
+	} else {
+		close(c.done)
+	}
+	return c
+}
+
+// GetOrCreate gets a cached pipeline or creates a new one for the given
+// config. The returned pipeline stays valid after eviction: it is closed
+// only once running calls return, and later calls load it again.
+func (c *PipelineCache) GetOrCreate(config pipeline.Config) (pipelineInterface, error) {
+	e, err := c.get(config)
+	if err != nil {
+		return nil, err
+	}
+	c.release(e)
+	return &cachedPipeline{cache: c, entry: e}, nil
+}
+
+// get returns the entry for config, building its pipeline if needed, and
+// holds it for the caller until release. Concurrent requests for the same
+// config wait for one build.
+func (c *PipelineCache) get(config pipeline.Config) (*cacheEntry, error) {
+	key := configKey(config)
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, errPipelineCacheClosed
+	}
+	if e, ok := c.entries[key]; ok {
+		e.active++
+		e.hits++
+		if e.elem != nil {
+			c.touchLocked(e)
+		}
+		c.mu.Unlock()
+		pipelineCacheLookups.WithLabelValues("hit").Inc()
+
+		<-e.ready
+		if e.err != nil {
+			c.release(e)
+			return nil, e.err
+		}
+		return e, nil
+	}
+	now := c.now()
+	e := &cacheEntry{
+		key: key, config: config, resolved: config,
+		ready: make(chan struct{}), active: 1, created: now, lastUsed: now,
+	}
+	c.entries[key] = e
+	c.mu.Unlock()
+	pipelineCacheLookups.WithLabelValues("miss").Inc()
+
+	// Build outside the lock: loading models takes a while.
+	pl, err := c.create(config)
+	if err == nil {
+		if cfg, ok := pl.(interface{ Config() pipeline.Config }); ok {
+			e.resolved = cfg.Config()
+		}
+		e.memoryBytes = c.estimate(e.resolved)
+	}
+
+	c.mu.Lock()
+	if err == nil && c.closed {
+		err = errPipelineCacheClosed
+		_ = pl.Close()
+	}
+	if err != nil {
+		delete(c.entries, key)
+		e.err = err
+		e.evicted = true
+		e.active = 0
+		c.mu.Unlock()
+		close(e.ready)
+		return nil, err
+	}
+	e.pipeline = pl
+	e.elem = c.lru.PushFront(e)
+	c.memoryBytes += e.memoryBytes
+	evicted := c.evictOverBudgetLocked(e)
+	c.updateMetricsLocked()
+	c.mu.Unlock()
+	close(e.ready)
+
+	slog.Info("Pipeline cached", "id", key, "estimated_memory_bytes", e.memoryBytes)
+	closeEntries(evicted)
+	return e, nil
+}
+
+// acquire holds e for a call, or, if it was evicted, the entry of its
+// config, which is loaded again.
+func (c *PipelineCache) acquire(e *cacheEntry) (*cacheEntry, error) {
+	c.mu.Lock()
+	if !e.evicted {
+		e.active++
+		c.touchLocked(e)
+		c.mu.Unlock()
+		return e, nil
+	}
+	c.mu.Unlock()
+	return c.get(e.config)
+}
+
+// release ends a call holding e and closes e if it was evicted meanwhile.
+func (c *PipelineCache) release(e *cacheEntry) {
+	c.mu.Lock()
+	if e.active > 0 {
+		e.active--
+	}
+	e.lastUsed = c.now()
+	closeNow := e.evicted && e.active == 0 && e.pipeline != nil
+	c.mu.Unlock()
+	if closeNow {
+		closeEntries([]*cacheEntry{e})
+	}
+}
+
+// touchLocked marks e as the most recently used entry.
+func (c *PipelineCache) touchLocked(e *cacheEntry) {
+	e.lastUsed = c.now()
+	c.lru.MoveToFront(e.elem)
+}
+
+// removeLocked evicts a built entry and reports whether it can be closed
+// right away, i.e. no call is using it.
+func (c *PipelineCache) removeLocked(e *cacheEntry, reason string) bool {
+	delete(c.entries, e.key)
+	c.lru.Remove(e.elem)
+	c.memoryBytes -= e.memoryBytes
+	e.evicted = true
+	pipelineCacheEvictions.WithLabelValues(reason).Inc()
+	slog.Info("Pipeline evicted from cache", "id", e.key, "reason", reason, "hits", e.hits)
+	return e.active == 0
+}
+
+// evictOverBudgetLocked evicts the least recently used entries other than
+// keep until the cache is within its memory budget, returning the entries
+// to close.
+func (c *PipelineCache) evictOverBudgetLocked(keep *cacheEntry) []*cacheEntry {
+	var toClose []*cacheEntry
+	for c.config.MaxMemoryBytes > 0 && c.memoryBytes > c.config.MaxMemoryBytes {
+		e, _ := c.lru.Back().Value.(*cacheEntry)
+		if e == keep {
+			break
+		}
+		if c.removeLocked(e, "memory") {
+			toClose = append(toClose, e)
+		}
+	}
+	return toClose
+}
+
+// EvictIdle evicts the pipelines unused for longer than the configured
+// idle time and returns how many were evicted.
+func (c *PipelineCache) EvictIdle() int {
+	if c.config.MaxIdle <= 0 {
+		return 0
+	}
+	c.mu.Lock()
+	now := c.now()
+	var evicted, toClose []*cacheEntry
+	for elem := c.lru.Back(); elem != nil; elem = elem.Prev() {
+		e, _ := elem.Value.(*cacheEntry)
+		if e.active == 0 && now.Sub(e.lastUsed) >= c.config.MaxIdle {
+			evicted = append(evicted, e)
+		}
+	}
+	for _, e := range evicted {
+		if c.removeLocked(e, "idle") {
+			toClose = append(toClose, e)
+		}
+	}
+	c.updateMetricsLocked()
+	c.mu.Unlock()
+	closeEntries(toClose)
+	return len(evicted)
+}
+
+// Evict evicts the pipeline with the given ID and reports whether it was
+// cached. A pipeline in use is closed once its calls return.
+func (c *PipelineCache) Evict(id string) bool {
+	c.mu.Lock()
+	e, ok := c.entries[id]
+	if !ok || e.elem == nil {
+		c.mu.Unlock()
+		return false
+	}
+	closeNow := c.removeLocked(e, "admin")
+	c.updateMetricsLocked()
+	c.mu.Unlock()
+	if closeNow {
+		closeEntries([]*cacheEntry{e})
+	}
+	return true
+}
+
+// EvictAll evicts all cached pipelines and returns how many there were.
+func (c *PipelineCache) EvictAll() int {
+	c.mu.Lock()
+	var evicted, toClose []*cacheEntry
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		e, _ := elem.Value.(*cacheEntry)
+		evicted = append(evicted, e)
+	}
+	for _, e := range evicted {
+		if c.removeLocked(e, "admin") {
+			toClose = append(toClose, e)
+		}
+	}
+	c.updateMetricsLocked()
+	c.mu.Unlock()
+	closeEntries(toClose)
+	return len(evicted)
+}
+
+// CachedPipelineInfo describes a cached pipeline.
+type CachedPipelineInfo struct {
+	ID                   string    `json:"id"`
+	Language             string    `json:"language"`
+	DetModel             string    `json:"det_model"`
+	RecModel             string    `json:"rec_model"`
+	DictPaths            []string  `json:"dict_paths,omitempty"`
+	Barcodes             bool      `json:"barcodes"`
+	Hits                 int64     `json:"hits"`
+	InUse                int       `json:"in_use"`
+	EstimatedMemoryBytes int64     `json:"estimated_memory_bytes"`
+	CreatedAt            time.Time `json:"created_at"`
+	LastUsedAt           time.Time `json:"last_used_at"`
+}
+
+// Pipelines describes the cached pipelines, most recently used first.
+func (c *PipelineCache) Pipelines() []CachedPipelineInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	infos := make([]CachedPipelineInfo, 0, c.lru.Len())
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		e, _ := elem.Value.(*cacheEntry)
+		cfg := e.resolved
+		dictPaths := cfg.Recognizer.DictPaths
+		if len(dictPaths) == 0 && cfg.Recognizer.DictPath != "" {
+			dictPaths = []string{cfg.Recognizer.DictPath}
+		}
+		infos = append(infos, CachedPipelineInfo{
+			ID:                   e.key,
+			Language:             cfg.Recognizer.Language,
+			DetModel:             cfg.Detector.ModelPath,
+			RecModel:             cfg.Recognizer.ModelPath,
+			DictPaths:            dictPaths,
+			Barcodes:             cfg.Barcode.Enabled,
+			Hits:                 e.hits,
+			InUse:                e.active,
+			EstimatedMemoryBytes: e.memoryBytes,
+			CreatedAt:            e.created,
+			LastUsedAt:           e.lastUsed,
+		})
+	}
+	return infos
+}
+
+// MemoryBytes returns the estimated memory of all cached pipelines.
+func (c *PipelineCache) MemoryBytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.memoryBytes
+}
+
+func (c *PipelineCache) updateMetricsLocked() {
+	pipelineCacheEntries.Set(float64(c.lru.Len()))
+	pipelineCacheMemoryBytes.Set(float64(c.memoryBytes))
+}
+
+func (c *PipelineCache) run(interval time.Duration) {
+	defer close(c.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.EvictIdle()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// Close stops idle eviction and closes all cached pipelines.
+func (c *PipelineCache) Close() error {
+	c.closeOnce.Do(func() { close(c.stop) })
+	<-c.done
+
+	c.mu.Lock()
+	var entries []*cacheEntry
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		e, _ := elem.Value.(*cacheEntry)
+		entries = append(entries, e)
+	}
+	c.entries = make(map[string]*cacheEntry)
+	c.lru.Init()
+	c.memoryBytes = 0
+	c.closed = true
+	c.updateMetricsLocked()
+	c.mu.Unlock()
+
+	var firstErr error
+	for _, e := range entries {
+		if err := e.pipeline.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func closeEntries(entries []*cacheEntry) {
+	for _, e := range entries {
+		if err := e.pipeline.Close(); err != nil {
+			slog.Warn("Failed to close evicted pipeline", "id", e.key, "error", err)
+		}
+	}
+}
+
+// cachedPipeline is a pipeline handed out by the cache. Each call holds the
+// cached pipeline so that eviction cannot close it underneath; after
+// eviction the next call loads it again.
+type cachedPipeline struct {
+	cache *PipelineCache
+	entry *cacheEntry
+}
+
+func (p *cachedPipeline) ProcessImageContext(ctx context.Context, img image.Image) (*pipeline.OCRImageResult, error) {
+	e, err := p.cache.acquire(p.entry)
+	if err != nil {
+		return nil, err
+	}
+	defer p.cache.release(e)
+	return e.pipeline.ProcessImageContext(ctx, img)
+}
+
+func (p *cachedPipeline) ProcessPDFContext(ctx context.Context, filename string, pageRange string) (*pipeline.OCRPDFResult, error) {
+	e, err := p.cache.acquire(p.entry)
+	if err != nil {
+		return nil, err
+	}
+	defer p.cache.release(e)
+	return e.pipeline.ProcessPDFContext(ctx, filename, pageRange)
+}
+
+func (p *cachedPipeline) ProcessPDFStream(ctx context.Context, filename string, pageRange string,
+	emit func(*pipeline.OCRPDFPageResult) error,
+) (*pipeline.OCRPDFResult, error) {
+	e, err := p.cache.acquire(p.entry)
+	if err != nil {
+		return nil, err
+	}
+	defer p.cache.release(e)
+	return e.pipeline.ProcessPDFStream(ctx, filename, pageRange, emit)
+}
+
+// Close does nothing: cached pipelines are closed by the cache.
+func (p *cachedPipeline) Close() error { return nil }
+
+// createPipeline creates a new pipeline with the given configuration.
+func createPipeline(config pipeline.Config) (pipelineInterface, error) {
+	return pipeline.NewBuilder().WithConfig(config).Build()
+}
+
+// estimatePipelineMemory estimates the memory of a pipeline from the sizes
+// of the model files it loads.
+func estimatePipelineMemory(config pipeline.Config) int64 {
+	paths := []string{config.Detector.ModelPath, config.Recognizer.ModelPath}
+	if config.Orientation.Enabled || config.EnableOrientation {
+		paths = append(paths, config.Orientation.ModelPath)
+	}
+	if config.TextLineOrientation.Enabled {
+		paths = append(paths, config.TextLineOrientation.ModelPath)
+	}
+	if config.Rectification.Enabled {
+		paths = append(paths, config.Rectification.ModelPath)
+	}
+	var total int64
+	for _, path := range paths {
+		if fi, err := os.Stat(path); err == nil && path != "" {
+			total += fi.Size() * modelMemoryFactor
+		}
+	}
+	return total
+}
+
+// configKey returns a key that identifies every setting of config, so that
+// pipelines are only shared by requests that would build identical ones.
+// Callbacks (funcs, channels and interfaces) are not settings and ignored.
+func configKey(config pipeline.Config) string {
+	h := sha256.New()
+	writeConfigValue(h, "", reflect.ValueOf(config))
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// writeConfigValue writes v as "path=value" lines, recursing into structs,
+// pointers, slices and maps (in key order), so that equal configurations
+// always produce the same output.
+func writeConfigValue(w io.Writer, path string, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := range t.NumField() {
+			writeConfigValue(w, path+"."+t.Field(i).Name, v.Field(i))
+		}
+	case reflect.Pointer:
+		if v.IsNil() {
+			_, _ = fmt.Fprintf(w, "%s=nil\n", path)
+			return
+		}
+		writeConfigValue(w, path, v.Elem())
+	case reflect.Slice, reflect.Array:
+		_, _ = fmt.Fprintf(w, "%s#=%d\n", path, v.Len())
+		for i := range v.Len() {
+			writeConfigValue(w, path+"["+strconv.Itoa(i)+"]", v.Index(i))
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		_, _ = fmt.Fprintf(w, "%s#=%d\n", path, v.Len())
+		for _, k := range keys {
+			writeConfigValue(w, path+"["+fmt.Sprint(k)+"]", v.MapIndex(k))
+		}
+	case reflect.Func, reflect.Chan, reflect.Interface, reflect.UnsafePointer, reflect.Invalid:
+	default:
+		_, _ = fmt.Fprintf(w, "%s=%v\n", path, v)
+	}
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// closeTrackingPipeline records whether it was closed.
+type closeTrackingPipeline struct {
+	mockPipelineForTesting
+	closed atomic.Bool
+}
+
+func (p *closeTrackingPipeline) Close() error {
+	p.closed.Store(true)
+	return nil
+}
+
+// newTestPipelineCache returns a cache building closeTrackingPipelines of
+// 100 bytes each, and the pipelines built so far.
+func newTestPipelineCache(t *testing.T, config PipelineCacheConfig) (*PipelineCache, *[]*closeTrackingPipeline) {
+	t.Helper()
+	c := NewPipelineCache(config)
+	t.Cleanup(func() { _ = c.Close() })
+	var built []*closeTrackingPipeline
+	c.create = func(pipeline.Config) (pipelineInterface, error) {
+		p := &closeTrackingPipeline{mockPipelineForTesting: mockPipelineForTesting{
+			processImageResult: &pipeline.OCRImageResult{},
+		}}
+		built = append(built, p)
+		return p, nil
+	}
+	c.estimate = func(pipeline.Config) int64 { return 100 }
+	return c, &built
+}
+
+func languageConfig(lang string) pipeline.Config {
+	config := pipeline.DefaultConfig()
+	config.Recognizer.Language = lang
+	return config
+}
+
+func TestConfigKey(t *testing.T) {
+	base := pipeline.DefaultConfig()
+	assert.Equal(t, configKey(base), configKey(pipeline.DefaultConfig()), "keys are deterministic")
+
+	withHandler := base
+	withHandler.Parallel.ErrorHandler = func(int, image.Image, error) {}
+	assert.Equal(t, configKey(base), configKey(withHandler), "callbacks are not settings")
+
+	changes := map[string]func(*pipeline.Config){
+		"barcodes":      func(c *pipeline.Config) { c.Barcode.Enabled = true },
+		"barcode types": func(c *pipeline.Config) { c.Barcode.Types = []string{"qr"} },
+		"orientation":   func(c *pipeline.Config) { c.Orientation.Enabled = true },
+		"textline":      func(c *pipeline.Config) { c.TextLineOrientation.Enabled = true },
+		"filter dicts":  func(c *pipeline.Config) { c.Recognizer.FilterDictPaths = []string{"latin.txt"} },
+		"multi-scale":   func(c *pipeline.Config) { c.Detector.MultiScale.Scales = []float64{1, 0.5} },
+		"pdf":           func(c *pipeline.Config) { c.PDF.MaxInFlightPages = 3 },
+	}
+	for name, change := range changes {
+		config := base
+		change(&config)
+		assert.NotEqual(t, configKey(base), configKey(config), name)
+	}
+}
+
+func TestPipelineCache_GetOrCreate(t *testing.T) {
+	c, built := newTestPipelineCache(t, PipelineCacheConfig{})
+
+	de, err := c.GetOrCreate(languageConfig("de"))
+	require.NoError(t, err)
+	_, err = c.GetOrCreate(languageConfig("de"))
+	require.NoError(t, err)
+	_, err = c.GetOrCreate(languageConfig("fr"))
+	require.NoError(t, err)
+	assert.Len(t, *built, 2)
+
+	_, err = de.ProcessImageContext(context.Background(), createTestImage(10, 10))
+	require.NoError(t, err)
+	require.NoError(t, de.Close())
+	assert.False(t, (*built)[0].closed.Load(), "the cache owns its pipelines")
+
+	infos := c.Pipelines()
+	require.Len(t, infos, 2)
+	assert.Equal(t, "de", infos[0].Language, "most recently used first")
+	assert.Equal(t, int64(1), infos[0].Hits)
+	assert.Equal(t, int64(100), infos[0].EstimatedMemoryBytes)
+	assert.Equal(t, int64(200), c.MemoryBytes())
+
+	require.NoError(t, c.Close())
+	assert.True(t, (*built)[0].closed.Load())
+	assert.True(t, (*built)[1].closed.Load())
+	_, err = c.GetOrCreate(languageConfig("de"))
+	require.ErrorIs(t, err, errPipelineCacheClosed)
+}
+
+func TestPipelineCache_MemoryBudget(t *testing.T) {
+	c, built := newTestPipelineCache(t, PipelineCacheConfig{MaxMemoryBytes: 250})
+
+	for _, lang := range []string{"de", "fr"} {
+		_, err := c.GetOrCreate(languageConfig(lang))
+		require.NoError(t, err)
+	}
+	// Use "de" again so that "fr" is the least recently used
+	_, err := c.GetOrCreate(languageConfig("de"))
+	require.NoError(t, err)
+	_, err = c.GetOrCreate(languageConfig("it"))
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(200), c.MemoryBytes())
+	assert.False(t, (*built)[0].closed.Load())
+	assert.True(t, (*built)[1].closed.Load(), "fr is evicted")
+	assert.False(t, (*built)[2].closed.Load())
+	var langs []string
+	for _, info := range c.Pipelines() {
+		langs = append(langs, info.Language)
+	}
+	assert.Equal(t, []string{"it", "de"}, langs)
+}
+
+func TestPipelineCache_EvictIdle(t *testing.T) {
+	c, built := newTestPipelineCache(t, PipelineCacheConfig{MaxIdle: time.Hour})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	_, err := c.GetOrCreate(languageConfig("de"))
+	require.NoError(t, err)
+	now = now.Add(40 * time.Minute)
+	_, err = c.GetOrCreate(languageConfig("fr"))
+	require.NoError(t, err)
+
+	now = now.Add(30 * time.Minute)
+	assert.Equal(t, 1, c.EvictIdle())
+	assert.True(t, (*built)[0].closed.Load())
+	require.Len(t, c.Pipelines(), 1)
+	assert.Equal(t, "fr", c.Pipelines()[0].Language)
+}
+
+func TestPipelineCache_EvictInUse(t *testing.T) {
+	c, built := newTestPipelineCache(t, PipelineCacheConfig{})
+	pl, err := c.GetOrCreate(languageConfig("de"))
+	require.NoError(t, err)
+
+	// Hold the pipeline as a running call would
+	e, err := c.acquire(pl.(*cachedPipeline).entry)
+	require.NoError(t, err)
+	id := c.Pipelines()[0].ID
+	assert.True(t, c.Evict(id))
+	assert.False(t, c.Evict(id))
+	assert.False(t, (*built)[0].closed.Load(), "closed only once the call returns")
+	c.release(e)
+	assert.True(t, (*built)[0].closed.Load())
+
+	// The handle loads the pipeline again
+	_, err = pl.ProcessImageContext(context.Background(), createTestImage(10, 10))
+	require.NoError(t, err)
+	assert.Len(t, *built, 2)
+	assert.Len(t, c.Pipelines(), 1)
+}
+
+func TestServer_GetPipelineForRequest_UsesCache(t *testing.T) {
+	c, built := newTestPipelineCache(t, PipelineCacheConfig{})
+	s := &Server{
+		pipeline:        &mockPipelineForTesting{},
+		pipelineCache:   c,
+		baseConfig:      languageConfig("en"),
+		allowModelPaths: true,
+	}
+
+	pl, err := s.getPipelineForRequest(&RequestConfig{Language: "en"})
+	require.NoError(t, err)
+	assert.Same(t, s.pipeline, pl, "options matching the defaults use the default pipeline")
+
+	_, err = s.getPipelineForRequest(&RequestConfig{EnableBarcodes: true})
+	require.NoError(t, err)
+	_, err = s.getPipelineForRequest(&RequestConfig{Language: "de"})
+	require.NoError(t, err)
+	assert.Len(t, *built, 2, "barcode settings get their own pipeline")
+}
+
+func TestAdminPipelinesHandler(t *testing.T) {
+	s, h := newAuthTestServer(t)
+	c, _ := newTestPipelineCache(t, PipelineCacheConfig{MaxMemoryBytes: 1000})
+	s.pipelineCache = c
+	_, err := c.GetOrCreate(languageConfig("de"))
+	require.NoError(t, err)
+
+	do := func(method, path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(apiKeyHeader, key)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/admin/pipelines", "img-secret").Code)
+
+	w := do(http.MethodGet, "/admin/pipelines", "root-secret")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp PipelinesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 1, resp.Count)
+	assert.Equal(t, "de", resp.Pipelines[0].Language)
+	assert.Equal(t, int64(100), resp.EstimatedMemoryBytes)
+	assert.Equal(t, int64(1000), resp.MaxMemoryBytes)
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/admin/pipelines/unknown", "root-secret").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/admin/pipelines/"+resp.Pipelines[0].ID, "root-secret").Code)
+	assert.Empty(t, c.Pipelines())
+
+	_, err = c.GetOrCreate(languageConfig("fr"))
+	require.NoError(t, err)
+	w = do(http.MethodDelete, "/admin/pipelines", "root-secret")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"evicted":1}`, w.Body.String())
+}
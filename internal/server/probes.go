@@ -0,0 +1,561 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Probe defaults.
+const (
+	DefaultSelfTestInterval = time.Minute
+	DefaultSelfTestTimeout  = 30 * time.Second
+	DefaultLivenessFailures = 3
+
+	// startupRetryAfter is the Retry-After hint for requests rejected while
+	// the server is starting.
+	startupRetryAfter = 5 * time.Second
+)
+
+// Startup phases, as reported by /startupz.
+const (
+	PhaseLoading = "loading_models"
+	PhaseWarmup  = "warming_up"
+	PhaseStarted = "started"
+	PhaseFailed  = "failed"
+)
+
+var errStarting = errors.New("server is starting, try again later")
+
+// ProbeConfig configures the startup of the default pipeline and the checks
+// behind /livez, /readyz and /startupz.
+type ProbeConfig struct {
+	// WarmupIterations runs the models of the default pipeline this many
+	// times on dummy input before the server reports ready (0 = no warmup).
+	WarmupIterations int
+	// SelfTestInterval is how often the default pipeline OCRs a built-in
+	// image to verify that it still works. The server is not ready while
+	// the last run failed (0 = DefaultSelfTestInterval, negative = no
+	// self-test).
+	SelfTestInterval time.Duration
+	// SelfTestTimeout bounds a self-test run (0 = DefaultSelfTestTimeout).
+	SelfTestTimeout time.Duration
+	// LivenessFailures is the number of consecutive failed self-tests after
+	// which the server is no longer live either, so that it gets restarted
+	// (0 = DefaultLivenessFailures).
+	LivenessFailures int
+}
+
+// ProbeResponse is the body of the probe endpoints. Only Status and Time
+// are set unless the request asks for ?verbose.
+type ProbeResponse struct {
+	Status    string                `json:"status"`
+	Time      string                `json:"time"`
+	Checks    []ProbeCheck          `json:"checks,omitempty"`
+	Startup   *StartupStatus        `json:"startup,omitempty"`
+	SelfTest  *SelfTestStatus       `json:"self_test,omitempty"`
+	Admission map[string]LaneStatus `json:"admission,omitempty"`
+}
+
+// ProbeCheck is the outcome of one check of a probe.
+type ProbeCheck struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+// StartupStatus describes the startup of the default pipeline.
+type StartupStatus struct {
+	Phase            string     `json:"phase"`
+	Error            string     `json:"error,omitempty"`
+	StartedAt        time.Time  `json:"started_at"`
+	ReadyAt          *time.Time `json:"ready_at,omitempty"`
+	WarmupIterations int        `json:"warmup_iterations"`
+	WarmupMs         int64      `json:"warmup_ms"`
+}
+
+// SelfTestStatus describes the periodic self-test.
+type SelfTestStatus struct {
+	Enabled             bool       `json:"enabled"`
+	IntervalSec         float64    `json:"interval_sec,omitempty"`
+	Runs                int        `json:"runs"`
+	Failures            int        `json:"failures"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastRun             *time.Time `json:"last_run,omitempty"`
+	LastDurationMs      int64      `json:"last_duration_ms,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	// LastText is the text recognized in the built-in image.
+	LastText string `json:"last_text,omitempty"`
+}
+
+// probeState tracks the startup of the default pipeline and the results of
+// the self-test. A nil *probeState belongs to a server whose pipeline was
+// set directly: it is started, without warmup or self-test.
+type probeState struct {
+	config ProbeConfig
+
+	// started is closed when startup ends, successfully or not.
+	started chan struct{}
+	stop    chan struct{}
+	// done is closed when the self-test loop has exited.
+	done     chan struct{}
+	stopOnce sync.Once
+
+	mu        sync.Mutex
+	phase     string
+	startErr  error
+	startedAt time.Time
+	readyAt   time.Time
+	warmup    time.Duration
+	selfTest  SelfTestStatus
+}
+
+func newProbeState(config ProbeConfig) *probeState {
+	if config.SelfTestInterval == 0 {
+		config.SelfTestInterval = DefaultSelfTestInterval
+	}
+	if config.SelfTestTimeout <= 0 {
+		config.SelfTestTimeout = DefaultSelfTestTimeout
+	}
+	if config.LivenessFailures <= 0 {
+		config.LivenessFailures = DefaultLivenessFailures
+	}
+	return &probeState{
+		config:    config,
+		started:   make(chan struct{}),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		phase:     PhaseLoading,
+		startedAt: time.Now(),
+		selfTest: SelfTestStatus{
+			Enabled:     config.SelfTestInterval > 0,
+			IntervalSec: max(config.SelfTestInterval, 0).Seconds(),
+		},
+	}
+}
+
+// load builds the default pipeline and warms it up.
+func (p *probeState) load(build func() (pipelineInterface, error)) (pipelineInterface, error) {
+	pl, err := build()
+	if err != nil {
+		return nil, err
+	}
+	if n := p.config.WarmupIterations; n > 0 {
+		p.setPhase(PhaseWarmup)
+		slog.Info("Warming up models", "iterations", n)
+	}
+	d, err := warmupPipeline(pl, p.config.WarmupIterations)
+	if err != nil {
+		_ = pl.Close()
+		return nil, err
+	}
+	p.mu.Lock()
+	p.warmup = d
+	p.mu.Unlock()
+	return pl, nil
+}
+
+// warmupPipeline runs the models of pl iterations times, if pl supports
+// warmup, and returns how long that took.
+func warmupPipeline(pl pipelineInterface, iterations int) (time.Duration, error) {
+	w, ok := pl.(interface{ Warmup(iterations int) error })
+	if iterations <= 0 || !ok {
+		return 0, nil
+	}
+	start := time.Now()
+	err := w.Warmup(iterations)
+	return time.Since(start), err
+}
+
+func (p *probeState) setPhase(phase string) {
+	p.mu.Lock()
+	p.phase = phase
+	p.mu.Unlock()
+}
+
+// finishStartup records the outcome of startup.
+func (p *probeState) finishStartup(err error) {
+	p.mu.Lock()
+	if err != nil {
+		p.phase, p.startErr = PhaseFailed, err
+	} else {
+		p.phase, p.readyAt = PhaseStarted, time.Now()
+	}
+	p.mu.Unlock()
+	close(p.started)
+}
+
+// checkStarted returns nil once startup succeeded, errStarting while it is
+// in progress and the startup error if it failed.
+func (p *probeState) checkStarted() error {
+	if p == nil {
+		return nil
+	}
+	select {
+	case <-p.started:
+	default:
+		return errStarting
+	}
+	if p.startErr != nil {
+		return fmt.Errorf("server failed to start: %w", p.startErr)
+	}
+	return nil
+}
+
+// waitStarted waits for startup to end and returns its error.
+func (p *probeState) waitStarted(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	select {
+	case <-p.started:
+		return p.checkStarted()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// startup returns the startup status.
+func (p *probeState) startup() StartupStatus {
+	if p == nil {
+		return StartupStatus{Phase: PhaseStarted}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := StartupStatus{
+		Phase:            p.phase,
+		StartedAt:        p.startedAt,
+		WarmupIterations: max(p.config.WarmupIterations, 0),
+		WarmupMs:         p.warmup.Milliseconds(),
+	}
+	if p.startErr != nil {
+		status.Error = p.startErr.Error()
+	}
+	if !p.readyAt.IsZero() {
+		readyAt := p.readyAt
+		status.ReadyAt = &readyAt
+	}
+	return status
+}
+
+// selfTestStatus returns a copy of the self-test results.
+func (p *probeState) selfTestStatus() SelfTestStatus {
+	if p == nil {
+		return SelfTestStatus{}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.selfTest
+}
+
+// recordSelfTest records the outcome of a self-test run.
+func (p *probeState) recordSelfTest(at time.Time, d time.Duration, text string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := &p.selfTest
+	st.Runs++
+	st.LastRun = &at
+	st.LastDurationMs = d.Milliseconds()
+	st.LastText = text
+	if err != nil {
+		st.Failures++
+		st.ConsecutiveFailures++
+		st.LastError = err.Error()
+		return
+	}
+	st.ConsecutiveFailures = 0
+	st.LastError = ""
+}
+
+// close stops the self-test loop and waits for it and for startup.
+func (p *probeState) close() {
+	if p == nil {
+		return
+	}
+	p.stopOnce.Do(func() { close(p.stop) })
+	<-p.started
+	<-p.done
+}
+
+// start builds and warms up the default pipeline, then starts the
+// self-test. It returns the startup error, if any.
+func (s *Server) start(build func() (pipelineInterface, error)) error {
+	pl, err := s.probes.load(build)
+	if err != nil {
+		close(s.probes.done)
+		s.probes.finishStartup(err)
+		return err
+	}
+	_, config := s.defaultPipelineConfig()
+	s.setPipeline(pl, config)
+	s.probes.finishStartup(nil)
+	s.startSelfTest()
+	return nil
+}
+
+// startSelfTest runs the self-test right away and then every
+// SelfTestInterval until the server is closed.
+func (s *Server) startSelfTest() {
+	p := s.probes
+	if p.config.SelfTestInterval <= 0 {
+		close(p.done)
+		return
+	}
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(p.config.SelfTestInterval)
+		defer ticker.Stop()
+		for {
+			s.runSelfTest()
+			select {
+			case <-ticker.C:
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// runSelfTest OCRs the built-in image with the default pipeline and
+// records the outcome.
+func (s *Server) runSelfTest() {
+	pl := s.defaultPipeline()
+	start := time.Now()
+	var text string
+	err := errors.New("OCR pipeline not initialized")
+	if pl != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), s.probes.config.SelfTestTimeout)
+		text, err = selfTestOCR(ctx, pl)
+		cancel()
+	}
+	d := time.Since(start)
+	s.probes.recordSelfTest(start, d, text, err)
+
+	result := "success"
+	if err != nil {
+		result = "failure"
+		slog.Warn("OCR self-test failed", "error", err, "duration", d)
+	}
+	selfTestTotal.WithLabelValues(result).Inc()
+	selfTestDuration.Observe(d.Seconds())
+}
+
+// selfTestOCR runs pl on the built-in image and returns the recognized
+// text. A truncated result counts as a failure: the pipeline did not finish
+// in time, and so does text that is not close to selfTestText.
+func selfTestOCR(ctx context.Context, pl pipelineInterface) (string, error) {
+	res, err := pl.ProcessImageContext(ctx, selfTestImage())
+	if err != nil {
+		return "", err
+	}
+	if res == nil {
+		return "", errors.New("pipeline returned no result")
+	}
+	texts := make([]string, 0, len(res.Regions))
+	for _, r := range res.Regions {
+		texts = append(texts, r.Text)
+	}
+	text := strings.Join(texts, " ")
+	if res.Truncated {
+		return text, errors.New("self-test timed out")
+	}
+	if editDistance(normalizeSelfTestText(text), normalizeSelfTestText(selfTestText)) > selfTestMaxEdits {
+		return text, fmt.Errorf("self-test recognized %q instead of %q", text, selfTestText)
+	}
+	return text, nil
+}
+
+// selfTestText is the text of the built-in self-test image.
+const selfTestText = "POGO 0123456789"
+
+// selfTestMaxEdits is how many characters the recognized text may differ
+// in from selfTestText, ignoring case and spaces; models may confuse O and
+// 0, for example.
+const selfTestMaxEdits = 2
+
+func normalizeSelfTestText(text string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(text), ""))
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+// selfTestImage is black text on white, scaled up from the 7x13 bitmap
+// font to a size the detector handles well.
+var selfTestImage = sync.OnceValue(func() image.Image {
+	const margin, scale = 8, 4
+	face := basicfont.Face7x13
+	width := font.MeasureString(face, selfTestText).Ceil() + 2*margin
+	height := face.Height + 2*margin
+	small := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(small, small.Bounds(), image.White, image.Point{}, draw.Src)
+	d := &font.Drawer{
+		Dst:  small,
+		Src:  image.NewUniform(color.Black),
+		Face: face,
+		Dot:  fixed.P(margin, margin+face.Ascent),
+	}
+	d.DrawString(selfTestText)
+
+	img := image.NewRGBA(image.Rect(0, 0, width*scale, height*scale))
+	xdraw.NearestNeighbor.Scale(img, img.Bounds(), small, small.Bounds(), draw.Src, nil)
+	return img
+})
+
+// startupChecks returns the checks of /startupz.
+func (s *Server) startupChecks() []ProbeCheck {
+	st := s.probes.startup()
+	check := ProbeCheck{Name: "startup", OK: st.Phase == PhaseStarted, Message: st.Phase}
+	if st.Error != "" {
+		check.Message = st.Phase + ": " + st.Error
+	}
+	return []ProbeCheck{check}
+}
+
+// livenessChecks returns the checks of /livez: the server is restarted
+// when they fail, so only failures a restart can fix count.
+func (s *Server) livenessChecks() []ProbeCheck {
+	checks := []ProbeCheck{{Name: "ping", OK: true}}
+	st := s.probes.startup()
+	if st.Phase == PhaseFailed {
+		checks = append(checks, ProbeCheck{Name: "startup", Message: st.Error})
+	}
+	if s.probes != nil {
+		selfTest := s.probes.selfTestStatus()
+		limit := s.probes.config.LivenessFailures
+		checks = append(checks, ProbeCheck{
+			Name:    "self_test",
+			OK:      selfTest.ConsecutiveFailures < limit,
+			Message: fmt.Sprintf("%d of %d consecutive failures", selfTest.ConsecutiveFailures, limit),
+		})
+	}
+	return checks
+}
+
+// readinessChecks returns the checks of /readyz.
+func (s *Server) readinessChecks() []ProbeCheck {
+	checks := s.startupChecks()
+
+	pipelineCheck := ProbeCheck{Name: "pipeline", OK: s.defaultPipeline() != nil}
+	if !pipelineCheck.OK {
+		pipelineCheck.Message = "not initialized"
+	}
+	checks = append(checks, pipelineCheck)
+
+	st := s.probes.startup()
+	warmup := ProbeCheck{Name: "warmup", OK: true, Message: "disabled"}
+	if st.WarmupIterations > 0 {
+		warmup.OK = st.Phase == PhaseStarted
+		warmup.Message = "pending"
+		if warmup.OK {
+			warmup.Message = fmt.Sprintf("%d iterations in %dms", st.WarmupIterations, st.WarmupMs)
+		}
+	}
+	checks = append(checks, warmup)
+
+	selfTest := s.probes.selfTestStatus()
+	selfTestCheck := ProbeCheck{Name: "self_test", OK: true, Message: "disabled"}
+	switch {
+	case !selfTest.Enabled:
+	case selfTest.Runs == 0:
+		selfTestCheck.OK, selfTestCheck.Message = false, "pending"
+	case selfTest.LastError != "":
+		selfTestCheck.OK, selfTestCheck.Message = false, selfTest.LastError
+	default:
+		selfTestCheck.Message = fmt.Sprintf("passed in %dms", selfTest.LastDurationMs)
+	}
+	checks = append(checks, selfTestCheck)
+
+	admission := ProbeCheck{Name: "admission", OK: true, Message: "disabled"}
+	if s.admission != nil {
+		lanes, underPressure := s.admission.status()
+		admission.Message = "accepting requests"
+		if lanes[LaneInteractive].Saturated {
+			admission.OK, admission.Message = false, "interactive lane saturated"
+		} else if underPressure {
+			admission.Message = "memory pressure, bulk requests are shed"
+		}
+	}
+	return append(checks, admission)
+}
+
+// probeHandler serves a probe: 200 if all checks pass, 503 otherwise.
+// With ?verbose the body includes the checks and the state behind them.
+func (s *Server) probeHandler(okStatus, failStatus string, checks func() []ProbeCheck) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		results := checks()
+		response := ProbeResponse{Status: okStatus, Time: time.Now().UTC().Format(time.RFC3339)}
+		code := http.StatusOK
+		for _, c := range results {
+			if !c.OK {
+				response.Status, code = failStatus, http.StatusServiceUnavailable
+				break
+			}
+		}
+		if isVerbose(r) {
+			response.Checks = results
+			startup := s.probes.startup()
+			response.Startup = &startup
+			selfTest := s.probes.selfTestStatus()
+			response.SelfTest = &selfTest
+			if s.admission != nil {
+				response.Admission, _ = s.admission.status()
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			slog.Debug("Failed to write probe response", "error", err)
+		}
+	}
+}
+
+// isVerbose reports whether the request asks for details with ?verbose,
+// ?verbose=1 or ?verbose=true.
+func isVerbose(r *http.Request) bool {
+	v, ok := r.URL.Query()["verbose"]
+	if !ok {
+		return false
+	}
+	return len(v) == 0 || v[0] == "" || v[0] == "1" || strings.EqualFold(v[0], stringTrue)
+}
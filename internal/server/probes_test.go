@@ -0,0 +1,288 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pogov1 "github.com/MeKo-Tech/pogo/api/pogo/v1"
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// warmupMockPipeline counts warmup iterations.
+type warmupMockPipeline struct {
+	mockPipelineForTesting
+	warmups atomic.Int32
+}
+
+func (m *warmupMockPipeline) Warmup(iterations int) error {
+	m.warmups.Add(int32(iterations))
+	return nil
+}
+
+func selfTestResult() *pipeline.OCRImageResult {
+	return &pipeline.OCRImageResult{Regions: []pipeline.OCRRegionResult{{Text: "POGO"}, {Text: "0123456789"}}}
+}
+
+// newProbeTestServer starts a server in the background like NewServer
+// with StartInBackground, using build for the default pipeline.
+func newProbeTestServer(t *testing.T, config ProbeConfig, build func() (pipelineInterface, error)) (*Server, *http.ServeMux) {
+	t.Helper()
+	s := &Server{maxUploadMB: 10, probes: newProbeState(config)}
+	go func() { _ = s.start(build) }()
+	t.Cleanup(func() { _ = s.Close() })
+	mux := http.NewServeMux()
+	s.SetupRoutes(mux)
+	return s, mux
+}
+
+func getProbe(t *testing.T, mux *http.ServeMux, target string) (int, ProbeResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	var resp ProbeResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp), target)
+	return w.Code, resp
+}
+
+func findCheck(checks []ProbeCheck, name string) *ProbeCheck {
+	for i := range checks {
+		if checks[i].Name == name {
+			return &checks[i]
+		}
+	}
+	return nil
+}
+
+func TestProbes_Startup(t *testing.T) {
+	release := make(chan struct{})
+	mock := &warmupMockPipeline{mockPipelineForTesting: mockPipelineForTesting{processImageResult: selfTestResult()}}
+	s, mux := newProbeTestServer(t, ProbeConfig{WarmupIterations: 3}, func() (pipelineInterface, error) {
+		<-release
+		return mock, nil
+	})
+
+	// While models load, only liveness passes and OCR requests are refused
+	code, resp := getProbe(t, mux, "/startupz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "not_started", resp.Status)
+	assert.Nil(t, resp.Checks, "details only with ?verbose")
+	code, resp = getProbe(t, mux, "/readyz?verbose")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, PhaseLoading, resp.Startup.Phase)
+	assert.False(t, findCheck(resp.Checks, "pipeline").OK)
+	assert.False(t, findCheck(resp.Checks, "warmup").OK)
+	code, _ = getProbe(t, mux, "/livez")
+	assert.Equal(t, http.StatusOK, code)
+
+	imgData, err := encodeImageToPNG(createTestImage(20, 20))
+	require.NoError(t, err)
+	req, err := createMultipartFormRequest(imgData, "a.png", nil)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "5", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), errStarting.Error())
+
+	close(release)
+	require.NoError(t, s.WaitStarted(context.Background()))
+	assert.Equal(t, int32(3), mock.warmups.Load())
+
+	// The first self-test runs right after startup
+	require.Eventually(t, func() bool {
+		code, _ := getProbe(t, mux, "/readyz")
+		return code == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+	code, resp = getProbe(t, mux, "/readyz?verbose=1")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", resp.Status)
+	assert.Equal(t, PhaseStarted, resp.Startup.Phase)
+	assert.NotNil(t, resp.Startup.ReadyAt)
+	assert.Equal(t, 3, resp.Startup.WarmupIterations)
+	assert.Equal(t, "POGO 0123456789", resp.SelfTest.LastText)
+	for _, c := range resp.Checks {
+		assert.True(t, c.OK, c.Name)
+	}
+	code, resp = getProbe(t, mux, "/startupz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "started", resp.Status)
+
+	req, err = createMultipartFormRequest(imgData, "a.png", nil)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestProbes_GRPCStartup(t *testing.T) {
+	release := make(chan struct{})
+	s, _ := newProbeTestServer(t, ProbeConfig{SelfTestInterval: -1}, func() (pipelineInterface, error) {
+		<-release
+		return newGRPCTestServer().pipeline, nil
+	})
+	conn := newGRPCTestClient(t, s)
+	checkHealth := func() healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := healthpb.NewHealthClient(conn).Check(context.Background(),
+			&healthpb.HealthCheckRequest{Service: pogov1.OCRService_ServiceDesc.ServiceName})
+		require.NoError(t, err)
+		return resp.GetStatus()
+	}
+	imgData, err := encodeImageToPNG(createTestImage(20, 20))
+	require.NoError(t, err)
+	client := pogov1.NewOCRServiceClient(conn)
+
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, checkHealth())
+	_, err = client.RecognizeImage(context.Background(), &pogov1.RecognizeImageRequest{Image: imgData})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	close(release)
+	require.NoError(t, s.WaitStarted(context.Background()))
+	assert.Eventually(t, func() bool { return checkHealth() == healthpb.HealthCheckResponse_SERVING },
+		5*time.Second, 10*time.Millisecond)
+	_, err = client.RecognizeImage(context.Background(), &pogov1.RecognizeImageRequest{Image: imgData})
+	assert.NoError(t, err)
+}
+
+func TestProbes_StartupFailure(t *testing.T) {
+	s, mux := newProbeTestServer(t, ProbeConfig{}, func() (pipelineInterface, error) {
+		return nil, errors.New("model not found")
+	})
+	err := s.WaitStarted(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "model not found")
+
+	code, resp := getProbe(t, mux, "/startupz?verbose")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, PhaseFailed, resp.Startup.Phase)
+	assert.Equal(t, "model not found", resp.Startup.Error)
+	code, _ = getProbe(t, mux, "/livez")
+	assert.Equal(t, http.StatusServiceUnavailable, code, "a restart may fix a failed startup")
+	code, _ = getProbe(t, mux, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	_, err = s.getPipelineForRequest(&RequestConfig{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server failed to start")
+}
+
+func TestProbes_SelfTestFailure(t *testing.T) {
+	mock := &mockPipelineForTesting{processImageError: errors.New("onnx session broken")}
+	s, mux := newProbeTestServer(t, ProbeConfig{SelfTestInterval: 5 * time.Millisecond, LivenessFailures: 2},
+		func() (pipelineInterface, error) { return mock, nil })
+	require.NoError(t, s.WaitStarted(context.Background()))
+
+	require.Eventually(t, func() bool {
+		code, _ := getProbe(t, mux, "/livez")
+		return code == http.StatusServiceUnavailable
+	}, 5*time.Second, 5*time.Millisecond)
+
+	code, resp := getProbe(t, mux, "/readyz?verbose=true")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "not_ready", resp.Status)
+	check := findCheck(resp.Checks, "self_test")
+	require.NotNil(t, check)
+	assert.False(t, check.OK)
+	assert.Equal(t, "onnx session broken", check.Message)
+	assert.GreaterOrEqual(t, resp.SelfTest.ConsecutiveFailures, 2)
+
+	code, resp = getProbe(t, mux, "/livez?verbose")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "dead", resp.Status)
+	assert.False(t, findCheck(resp.Checks, "self_test").OK)
+}
+
+func TestProbes_SelfTestWrongText(t *testing.T) {
+	tests := []struct {
+		name string
+		text []string
+	}{
+		{"garbage", []string{"lorem", "ipsum"}},
+		{"empty", nil},
+		{"digits missing", []string{"POGO"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &pipeline.OCRImageResult{}
+			for _, text := range tt.text {
+				res.Regions = append(res.Regions, pipeline.OCRRegionResult{Text: text})
+			}
+			mock := &mockPipelineForTesting{processImageResult: res}
+			s, mux := newProbeTestServer(t, ProbeConfig{SelfTestInterval: time.Hour},
+				func() (pipelineInterface, error) { return mock, nil })
+			require.NoError(t, s.WaitStarted(context.Background()))
+
+			require.Eventually(t, func() bool { return s.probes.selfTestStatus().Runs > 0 },
+				5*time.Second, 5*time.Millisecond)
+			code, resp := getProbe(t, mux, "/readyz?verbose")
+			assert.Equal(t, http.StatusServiceUnavailable, code)
+			check := findCheck(resp.Checks, "self_test")
+			require.NotNil(t, check)
+			assert.False(t, check.OK)
+			assert.Contains(t, check.Message, `instead of "POGO 0123456789"`)
+		})
+	}
+}
+
+func TestSelfTestOCR_Tolerance(t *testing.T) {
+	for text, ok := range map[string]bool{
+		"POGO 0123456789":  true,
+		"pogo 0123456789":  true,
+		"P0GO 012345 6789": true,
+		"P0G0 0I23456789":  false,
+		"0123456789":       false,
+	} {
+		mock := &mockPipelineForTesting{processImageResult: &pipeline.OCRImageResult{
+			Regions: []pipeline.OCRRegionResult{{Text: text}},
+		}}
+		got, err := selfTestOCR(context.Background(), mock)
+		assert.Equal(t, text, got)
+		assert.Equal(t, ok, err == nil, "%q: %v", text, err)
+	}
+}
+
+func TestProbes_AdmissionSaturated(t *testing.T) {
+	s := &Server{
+		pipeline:  &mockPipelineForTesting{},
+		admission: newAdmissionController(AdmissionConfig{MaxConcurrent: 1}, nil),
+	}
+	mux := http.NewServeMux()
+	s.SetupRoutes(mux)
+
+	code, resp := getProbe(t, mux, "/readyz?verbose")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "disabled", findCheck(resp.Checks, "self_test").Message)
+	assert.False(t, resp.Admission[LaneInteractive].Saturated)
+
+	release, err := s.admission.admit(context.Background(), LaneInteractive)
+	require.NoError(t, err)
+	code, resp = getProbe(t, mux, "/readyz?verbose")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, findCheck(resp.Checks, "admission").OK)
+	assert.Equal(t, LaneStatus{Running: 1, Saturated: true}, resp.Admission[LaneInteractive])
+
+	// Liveness does not depend on load
+	code, _ = getProbe(t, mux, "/livez")
+	assert.Equal(t, http.StatusOK, code)
+
+	release()
+	code, _ = getProbe(t, mux, "/readyz?verbose=0")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestSelfTestImage(t *testing.T) {
+	img := selfTestImage()
+	b := img.Bounds()
+	assert.Greater(t, b.Dx(), 4*b.Dy(), "a single line of text")
+	assert.Equal(t, img, selfTestImage(), "the image is built once")
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/MeKo-Tech/pogo/internal/models"
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	errUnknownProfile       = errors.New("unknown model profile")
+	errModelPathsNotAllowed = errors.New("model and dictionary paths are not accepted; select a model profile instead")
+)
+
+// ModelProfile is a named model setup clients select instead of sending
+// file paths. Paths are as seen by the server; unset fields keep the server
+// defaults.
+type ModelProfile struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Language    string `yaml:"language"`
+
+	DetModel string `yaml:"det_model"`
+	RecModel string `yaml:"rec_model"`
+	// Dicts are merged for recognition; DictLangs selects the bundled
+	// dictionaries of languages instead.
+	Dicts     []string `yaml:"dicts"`
+	DictLangs []string `yaml:"dict_langs"`
+	// FilterDicts and FilterDictLangs restrict the output characters.
+	FilterDicts     []string `yaml:"filter_dicts"`
+	FilterDictLangs []string `yaml:"filter_dict_langs"`
+
+	// Detection thresholds (0 = server default).
+	DetThresh    float32 `yaml:"det_thresh"`
+	DetBoxThresh float32 `yaml:"det_box_thresh"`
+}
+
+// apply returns config with the models and thresholds of the profile.
+func (p *ModelProfile) apply(config pipeline.Config) pipeline.Config {
+	if p.Language != "" {
+		config.Recognizer.Language = p.Language
+	}
+	if p.DetModel != "" {
+		config.Detector.ModelPath = p.DetModel
+	}
+	if p.RecModel != "" {
+		config.Recognizer.ModelPath = p.RecModel
+	}
+	switch {
+	case len(p.Dicts) > 0:
+		config.Recognizer.DictPaths = p.Dicts
+		config.Recognizer.DictPath = ""
+	case len(p.DictLangs) > 0:
+		config.Recognizer.DictPaths = models.GetDictionaryPathsForLanguages(config.ModelsDir, p.DictLangs)
+		config.Recognizer.DictPath = ""
+	}
+	switch {
+	case len(p.FilterDicts) > 0:
+		config.Recognizer.FilterDictPaths = p.FilterDicts
+		config.Recognizer.FilterDictPath = ""
+	case len(p.FilterDictLangs) > 0:
+		config.Recognizer.FilterDictPaths = models.GetDictionaryPathsForLanguages(config.ModelsDir, p.FilterDictLangs)
+		config.Recognizer.FilterDictPath = ""
+	}
+	if p.DetThresh > 0 {
+		config.Detector.DbThresh = p.DetThresh
+	}
+	if p.DetBoxThresh > 0 {
+		config.Detector.DbBoxThresh = p.DetBoxThresh
+	}
+	return config
+}
+
+// validate checks the profile for mistakes that would only show when a
+// client first selects it.
+func (p *ModelProfile) validate() error {
+	if err := validateProfileName(p.Name); err != nil {
+		return err
+	}
+	if p.Language != "" {
+		if err := validateLanguageCode(p.Language); err != nil {
+			return fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+	}
+	if len(p.Dicts) > 0 && len(p.DictLangs) > 0 {
+		return fmt.Errorf("profile %q: set either dicts or dict_langs, not both", p.Name)
+	}
+	if len(p.FilterDicts) > 0 && len(p.FilterDictLangs) > 0 {
+		return fmt.Errorf("profile %q: set either filter_dicts or filter_dict_langs, not both", p.Name)
+	}
+	for _, lang := range slices.Concat(p.DictLangs, p.FilterDictLangs) {
+		if err := validateLanguageCode(lang); err != nil {
+			return fmt.Errorf("profile %q: dictionary %w", p.Name, err)
+		}
+	}
+	if p.DetThresh < 0 || p.DetThresh > 1 || p.DetBoxThresh < 0 || p.DetBoxThresh > 1 {
+		return fmt.Errorf("profile %q: detection thresholds must be between 0 and 1", p.Name)
+	}
+	return nil
+}
+
+// ModelProfiles is a set of model profiles by name.
+type ModelProfiles struct {
+	byName map[string]*ModelProfile
+	names  []string
+}
+
+// modelProfilesFile is the format of a model profile file, YAML or JSON.
+type modelProfilesFile struct {
+	Profiles []ModelProfile `yaml:"profiles"`
+}
+
+// LoadModelProfiles reads model profiles from a YAML or JSON file with a
+// "profiles" list.
+func LoadModelProfiles(path string) (*ModelProfiles, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model profiles: %w", err)
+	}
+	var f modelProfilesFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse model profiles %s: %w", path, err)
+	}
+	profiles, err := NewModelProfiles(f.Profiles)
+	if err != nil {
+		return nil, fmt.Errorf("invalid model profiles in %s: %w", path, err)
+	}
+	return profiles, nil
+}
+
+// NewModelProfiles validates profiles and builds a profile set. Names must
+// be unique.
+func NewModelProfiles(profiles []ModelProfile) (*ModelProfiles, error) {
+	if len(profiles) == 0 {
+		return nil, errors.New("no model profiles defined")
+	}
+	set := &ModelProfiles{byName: make(map[string]*ModelProfile, len(profiles))}
+	for i := range profiles {
+		p := profiles[i]
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("model profile %d: %w", i+1, err)
+		}
+		if _, dup := set.byName[p.Name]; dup {
+			return nil, fmt.Errorf("duplicate model profile name %q", p.Name)
+		}
+		set.byName[p.Name] = &p
+		set.names = append(set.names, p.Name)
+	}
+	sort.Strings(set.names)
+	return set, nil
+}
+
+// Names returns the names of all profiles, sorted.
+func (ps *ModelProfiles) Names() []string {
+	if ps == nil {
+		return nil
+	}
+	return slices.Clone(ps.names)
+}
+
+// Get returns the profile with the given name.
+func (ps *ModelProfiles) Get(name string) (*ModelProfile, bool) {
+	if ps == nil {
+		return nil, false
+	}
+	p, ok := ps.byName[name]
+	return p, ok
+}
+
+// validateProfileName checks that a profile name is a short identifier such
+// as "de-invoice".
+func validateProfileName(name string) error {
+	if name == "" {
+		return errors.New("profile name is empty")
+	}
+	if len(name) > 64 {
+		return fmt.Errorf("profile name too long: %s", name)
+	}
+	for _, r := range strings.ToLower(name) {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' && r != '.' {
+			return fmt.Errorf("invalid profile name: %s", name)
+		}
+	}
+	return nil
+}
+
+// checkModelSelection checks the profile and model paths of a request
+// against the server's profiles and path policy.
+func (s *Server) checkModelSelection(c *RequestConfig) error {
+	if !s.allowModelPaths && (c.DetModel != "" || c.RecModel != "" || c.DictPath != "") {
+		return errModelPathsNotAllowed
+	}
+	if c.Profile != "" {
+		if _, ok := s.profiles.Get(c.Profile); !ok {
+			if names := s.profiles.Names(); len(names) > 0 {
+				return fmt.Errorf("%w %q (available: %s)", errUnknownProfile, c.Profile, strings.Join(names, ", "))
+			}
+			return fmt.Errorf("%w %q", errUnknownProfile, c.Profile)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newProfilesTestServer(t *testing.T) *Server {
+	t.Helper()
+	profiles, err := NewModelProfiles([]ModelProfile{
+		{Name: "en-default", Description: "English documents"},
+		{Name: "de-invoice", Language: "de", RecModel: "/models/de_rec.onnx", DictLangs: []string{"de"}, DetThresh: 0.4},
+	})
+	require.NoError(t, err)
+	return &Server{
+		pipeline:    &mockPipelineForTesting{processImageResult: &pipeline.OCRImageResult{}},
+		maxUploadMB: 10,
+		profiles:    profiles,
+	}
+}
+
+func TestLoadModelProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+profiles:
+  - name: de-invoice
+    description: German invoices
+    language: de
+    rec_model: /models/de_rec.onnx
+    dict_langs: [de]
+  - name: en-default
+`), 0o600))
+
+	profiles, err := LoadModelProfiles(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"de-invoice", "en-default"}, profiles.Names())
+	p, ok := profiles.Get("de-invoice")
+	require.True(t, ok)
+	assert.Equal(t, "/models/de_rec.onnx", p.RecModel)
+	assert.Equal(t, []string{"de"}, p.DictLangs)
+
+	_, err = LoadModelProfiles(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.Error(t, err)
+}
+
+func TestNewModelProfiles_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles []ModelProfile
+	}{
+		{"none", nil},
+		{"empty name", []ModelProfile{{}}},
+		{"bad name", []ModelProfile{{Name: "../etc"}}},
+		{"duplicate", []ModelProfile{{Name: "a"}, {Name: "a"}}},
+		{"bad language", []ModelProfile{{Name: "a", Language: "en$"}}},
+		{"dicts and dict langs", []ModelProfile{{Name: "a", Dicts: []string{"d.txt"}, DictLangs: []string{"en"}}}},
+		{"threshold", []ModelProfile{{Name: "a", DetThresh: 1.5}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewModelProfiles(tt.profiles)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestServer_CheckModelSelection(t *testing.T) {
+	s := newProfilesTestServer(t)
+
+	require.NoError(t, s.checkModelSelection(&RequestConfig{Profile: "de-invoice", Language: "de"}))
+	require.NoError(t, s.checkModelSelection(&RequestConfig{}))
+
+	err := s.checkModelSelection(&RequestConfig{DetModel: "/tmp/det.onnx"})
+	require.ErrorIs(t, err, errModelPathsNotAllowed)
+	err = s.checkModelSelection(&RequestConfig{DictPath: "/etc/passwd"})
+	require.ErrorIs(t, err, errModelPathsNotAllowed)
+
+	err = s.checkModelSelection(&RequestConfig{Profile: "fr"})
+	require.ErrorIs(t, err, errUnknownProfile)
+	assert.Contains(t, err.Error(), "de-invoice, en-default")
+
+	s.allowModelPaths = true
+	require.NoError(t, s.checkModelSelection(&RequestConfig{DetModel: "/tmp/det.onnx"}))
+}
+
+func TestServer_ApplyRequestOverrides_Profile(t *testing.T) {
+	s := newProfilesTestServer(t)
+	base := pipeline.DefaultConfig()
+
+	config := s.applyRequestOverrides(base, &RequestConfig{Profile: "de-invoice"})
+	assert.Equal(t, "de", config.Recognizer.Language)
+	assert.Equal(t, "/models/de_rec.onnx", config.Recognizer.ModelPath)
+	assert.NotEmpty(t, config.Recognizer.DictPaths)
+	assert.InDelta(t, 0.4, config.Detector.DbThresh, 1e-6)
+	assert.Equal(t, base.Detector.ModelPath, config.Detector.ModelPath)
+
+	config = s.applyRequestOverrides(base, &RequestConfig{Profile: "de-invoice", Language: "en"})
+	assert.Equal(t, "en", config.Recognizer.Language, "request options refine the profile")
+}
+
+func TestOCRImageHandler_RejectsModelPaths(t *testing.T) {
+	s := newProfilesTestServer(t)
+	imageData, err := encodeImageToPNG(createTestImage(20, 20))
+	require.NoError(t, err)
+
+	for _, fields := range []map[string]string{
+		{"det-model": "/tmp/det.onnx"},
+		{"profile": "unknown"},
+	} {
+		req, err := createMultipartFormRequest(imageData, "test.png", fields)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		s.ocrImageHandler(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "fields %v", fields)
+	}
+}
+
+func TestModelsHandler_Profiles(t *testing.T) {
+	s := newProfilesTestServer(t)
+	w := httptest.NewRecorder()
+	s.modelsHandler(w, httptest.NewRequest(http.MethodGet, "/models", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response ModelsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Profiles, 2)
+	assert.Equal(t, "de-invoice", response.Profiles[0].Name)
+	assert.Equal(t, "de", response.Profiles[0].Language)
+	assert.NotContains(t, w.Body.String(), "de_rec.onnx", "model paths are not exposed")
+}
+
+func TestOCRPdfHandler_ProfileWithVectorText(t *testing.T) {
+	profiles, err := NewModelProfiles([]ModelProfile{{Name: "invoice", DetModel: "/models/invoice_det.onnx"}})
+	require.NoError(t, err)
+	s := &Server{pipeline: &mockPipelineForTesting{}, maxUploadMB: 10, profiles: profiles}
+
+	// Vector text is on by default, which takes the enhanced PDF path
+	req, err := createMultipartPDFFormRequest([]byte("%PDF-1.4"), "doc.pdf", map[string]string{"profile": "invoice"})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	s.ocrPdfHandler(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "/models/invoice_det.onnx", "the detector of the profile is used")
+}
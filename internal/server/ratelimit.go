@@ -19,7 +19,7 @@ type RateLimiter struct {
 	maxDataPerDay     int64 // in bytes
 
 	// Storage for tracking usage
-	userRequests map[string]*UserUsage
+	store UsageStore
 }
 
 // UserUsage tracks usage for a specific user/IP.
@@ -37,47 +37,148 @@ type UserUsage struct {
 	dayStartTime    time.Time
 }
 
-// NewRateLimiter creates a new rate limiter with the given limits.
+// NewRateLimiter creates a new rate limiter with the given limits that keeps
+// usage in memory.
 func NewRateLimiter(requestsPerMinute, requestsPerHour, maxRequestsPerDay int, maxDataPerDay int64) *RateLimiter {
+	return NewRateLimiterWithStore(RateLimits{
+		RequestsPerMinute: requestsPerMinute,
+		RequestsPerHour:   requestsPerHour,
+		MaxRequestsPerDay: maxRequestsPerDay,
+		MaxDataPerDay:     maxDataPerDay,
+	}, NewMemoryUsageStore(DefaultUsageSweepInterval))
+}
+
+// NewRateLimiterWithStore creates a rate limiter that keeps usage in store.
+func NewRateLimiterWithStore(limits RateLimits, store UsageStore) *RateLimiter {
 	return &RateLimiter{
-		requestsPerMinute: requestsPerMinute,
-		requestsPerHour:   requestsPerHour,
-		maxRequestsPerDay: maxRequestsPerDay,
-		maxDataPerDay:     maxDataPerDay,
-		userRequests:      make(map[string]*UserUsage),
+		requestsPerMinute: limits.RequestsPerMinute,
+		requestsPerHour:   limits.RequestsPerHour,
+		maxRequestsPerDay: limits.MaxRequestsPerDay,
+		maxDataPerDay:     limits.MaxDataPerDay,
+		store:             store,
+	}
+}
+
+// Close releases the usage store, persisting its state if it is durable.
+func (rl *RateLimiter) Close() error {
+	return rl.store.Close()
+}
+
+// RateLimits are the limits applied to one user; zero fields are unlimited.
+type RateLimits struct {
+	RequestsPerMinute int
+	RequestsPerHour   int
+	MaxRequestsPerDay int
+	MaxDataPerDay     int64 // in bytes
+}
+
+// RateLimitStatus describes the tightest request limit of a user after a
+// check, as reported in rate-limit response headers. Limit is 0 when no
+// request limit applies.
+type RateLimitStatus struct {
+	Limit     int
+	Remaining int
+	Reset     time.Duration // time until the window resets
+}
+
+// Limits returns the limiter's default limits.
+func (rl *RateLimiter) Limits() RateLimits {
+	return RateLimits{
+		RequestsPerMinute: rl.requestsPerMinute,
+		RequestsPerHour:   rl.requestsPerHour,
+		MaxRequestsPerDay: rl.maxRequestsPerDay,
+		MaxDataPerDay:     rl.maxDataPerDay,
 	}
 }
 
 // CheckRateLimit checks if a request from the given user/IP is allowed.
 func (rl *RateLimiter) CheckRateLimit(userID string, dataSize int64) error {
+	_, err := rl.CheckLimits(userID, dataSize, rl.Limits())
+	return err
+}
+
+// CheckLimits checks a request from the given user against limits instead of
+// the limiter's defaults and returns the user's status afterwards.
+func (rl *RateLimiter) CheckLimits(userID string, dataSize int64, limits RateLimits) (RateLimitStatus, error) {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
 	now := time.Now()
 	usage := rl.getOrCreateUserUsage(userID, now)
+	defer func() { rl.store.Put(userID, *usage) }()
 
 	rl.resetCountersIfNeeded(usage, now)
 
 	// Check rate limits
-	if err := rl.checkRateLimits(usage, now); err != nil {
-		return err
+	if err := rl.checkRateLimits(usage, limits, now); err != nil {
+		return rateLimitStatus(usage, limits, now), err
 	}
 
 	// Check daily quotas
-	if err := rl.checkDailyQuotas(usage, dataSize, now); err != nil {
-		return err
+	if err := rl.checkDailyQuotas(usage, limits, dataSize, now); err != nil {
+		return rateLimitStatus(usage, limits, now), err
 	}
 
 	// Update usage counters
 	rl.updateUsageCounters(usage, dataSize, now)
 
+	return rateLimitStatus(usage, limits, now), nil
+}
+
+// ChargeData counts dataSize bytes against the daily data quota of a user
+// without counting a request, for input a request refers to instead of
+// carrying it, such as a file fetched by URL.
+func (rl *RateLimiter) ChargeData(userID string, dataSize int64, limits RateLimits) error {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	usage := rl.getOrCreateUserUsage(userID, now)
+	defer func() { rl.store.Put(userID, *usage) }()
+
+	rl.resetCountersIfNeeded(usage, now)
+	if limits.MaxDataPerDay > 0 && usage.dataToday+dataSize > limits.MaxDataPerDay {
+		return &QuotaExceededError{
+			Type:   "data",
+			Limit:  limits.MaxDataPerDay,
+			Used:   usage.dataToday,
+			Resets: nextDay(now),
+		}
+	}
+	usage.dataToday += dataSize
 	return nil
 }
 
+// rateLimitStatus reports the first request limit set, from the per-minute
+// limit to the daily quota.
+func rateLimitStatus(usage *UserUsage, limits RateLimits, now time.Time) RateLimitStatus {
+	var limit, used int
+	var reset time.Duration
+	switch {
+	case limits.RequestsPerMinute > 0:
+		limit, used = limits.RequestsPerMinute, usage.requestsLastMinute
+		reset = time.Minute - now.Sub(usage.lastRequestTime)
+	case limits.RequestsPerHour > 0:
+		limit, used = limits.RequestsPerHour, usage.requestsLastHour
+		reset = time.Hour - now.Sub(usage.lastRequestTime)
+	case limits.MaxRequestsPerDay > 0:
+		limit, used = limits.MaxRequestsPerDay, usage.requestsToday
+		reset = nextDay(now).Sub(now)
+	default:
+		return RateLimitStatus{}
+	}
+	return RateLimitStatus{Limit: limit, Remaining: max(limit-used, 0), Reset: max(reset, 0)}
+}
+
+// nextDay returns the start of the day after now, when daily quotas reset.
+func nextDay(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+}
+
 // resetCountersIfNeeded resets usage counters when time periods change.
 func (rl *RateLimiter) resetCountersIfNeeded(usage *UserUsage, now time.Time) {
 	// Reset counters if day has changed
-	if now.Day() != usage.dayStartTime.Day() || now.Month() != usage.dayStartTime.Month() {
+	if dayChanged(usage.dayStartTime, now) {
 		usage.requestsToday = 0
 		usage.dataToday = 0
 		usage.dayStartTime = now
@@ -92,20 +193,32 @@ func (rl *RateLimiter) resetCountersIfNeeded(usage *UserUsage, now time.Time) {
 	}
 }
 
+// dayChanged reports whether now is on a later day than start, which resets
+// the daily quotas.
+func dayChanged(start, now time.Time) bool {
+	return now.Day() != start.Day() || now.Month() != start.Month()
+}
+
+// expired reports whether all counters of usage would be reset at now, so
+// that forgetting it loses nothing.
+func (u *UserUsage) expired(now time.Time) bool {
+	return now.Sub(u.lastRequestTime) >= time.Hour && dayChanged(u.dayStartTime, now)
+}
+
 // checkRateLimits checks minute and hour rate limits.
-func (rl *RateLimiter) checkRateLimits(usage *UserUsage, now time.Time) error {
-	if rl.requestsPerMinute > 0 && usage.requestsLastMinute >= rl.requestsPerMinute {
+func (rl *RateLimiter) checkRateLimits(usage *UserUsage, limits RateLimits, now time.Time) error {
+	if limits.RequestsPerMinute > 0 && usage.requestsLastMinute >= limits.RequestsPerMinute {
 		return &RateLimitError{
 			Type:       "minute",
-			Limit:      rl.requestsPerMinute,
+			Limit:      limits.RequestsPerMinute,
 			RetryAfter: time.Minute - now.Sub(usage.lastRequestTime),
 		}
 	}
 
-	if rl.requestsPerHour > 0 && usage.requestsLastHour >= rl.requestsPerHour {
+	if limits.RequestsPerHour > 0 && usage.requestsLastHour >= limits.RequestsPerHour {
 		return &RateLimitError{
 			Type:       "hour",
-			Limit:      rl.requestsPerHour,
+			Limit:      limits.RequestsPerHour,
 			RetryAfter: time.Hour - now.Sub(usage.lastRequestTime),
 		}
 	}
@@ -114,22 +227,22 @@ func (rl *RateLimiter) checkRateLimits(usage *UserUsage, now time.Time) error {
 }
 
 // checkDailyQuotas checks daily request and data quotas.
-func (rl *RateLimiter) checkDailyQuotas(usage *UserUsage, dataSize int64, now time.Time) error {
-	if rl.maxRequestsPerDay > 0 && usage.requestsToday >= rl.maxRequestsPerDay {
+func (rl *RateLimiter) checkDailyQuotas(usage *UserUsage, limits RateLimits, dataSize int64, now time.Time) error {
+	if limits.MaxRequestsPerDay > 0 && usage.requestsToday >= limits.MaxRequestsPerDay {
 		return &QuotaExceededError{
 			Type:   "requests",
-			Limit:  int64(rl.maxRequestsPerDay),
+			Limit:  int64(limits.MaxRequestsPerDay),
 			Used:   int64(usage.requestsToday),
-			Resets: time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location()),
+			Resets: nextDay(now),
 		}
 	}
 
-	if rl.maxDataPerDay > 0 && usage.dataToday+dataSize > rl.maxDataPerDay {
+	if limits.MaxDataPerDay > 0 && usage.dataToday+dataSize > limits.MaxDataPerDay {
 		return &QuotaExceededError{
 			Type:   "data",
-			Limit:  rl.maxDataPerDay,
+			Limit:  limits.MaxDataPerDay,
 			Used:   usage.dataToday,
-			Resets: time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location()),
+			Resets: nextDay(now),
 		}
 	}
 
@@ -145,17 +258,17 @@ func (rl *RateLimiter) updateUsageCounters(usage *UserUsage, dataSize int64, now
 	usage.lastRequestTime = now
 }
 
-// getOrCreateUserUsage gets or creates usage tracking for a user.
+// getOrCreateUserUsage gets or creates usage tracking for a user. Changes
+// must be written back to the store.
 func (rl *RateLimiter) getOrCreateUserUsage(userID string, now time.Time) *UserUsage {
-	usage, exists := rl.userRequests[userID]
+	usage, exists := rl.store.Get(userID)
 	if !exists {
-		usage = &UserUsage{
+		usage = UserUsage{
 			lastRequestTime: now,
 			dayStartTime:    now,
 		}
-		rl.userRequests[userID] = usage
 	}
-	return usage
+	return &usage
 }
 
 // GetUsage returns current usage statistics for a user.
@@ -163,18 +276,9 @@ func (rl *RateLimiter) GetUsage(userID string) *UserUsage {
 	rl.mu.RLock()
 	defer rl.mu.RUnlock()
 
-	if usage, exists := rl.userRequests[userID]; exists {
-		// Return a copy to avoid race conditions
-		return &UserUsage{
-			requestsLastMinute: usage.requestsLastMinute,
-			requestsLastHour:   usage.requestsLastHour,
-			requestsToday:      usage.requestsToday,
-			dataToday:          usage.dataToday,
-			lastRequestTime:    usage.lastRequestTime,
-			dayStartTime:       usage.dayStartTime,
-		}
-	}
-	return &UserUsage{}
+	// The store returns a copy, so there is no race with later updates
+	usage, _ := rl.store.Get(userID)
+	return &usage
 }
 
 // RateLimitError represents a rate limit violation.
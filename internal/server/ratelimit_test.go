@@ -19,7 +19,7 @@ func TestNewRateLimiter(t *testing.T) {
 	assert.Equal(t, 100, rl.requestsPerHour)
 	assert.Equal(t, 1000, rl.maxRequestsPerDay)
 	assert.Equal(t, int64(1024*1024), rl.maxDataPerDay)
-	assert.NotNil(t, rl.userRequests)
+	assert.NotNil(t, rl.store)
 }
 
 func TestRateLimiter_CheckRateLimit_NoLimits(t *testing.T) {
@@ -146,8 +146,9 @@ func TestRateLimiter_CheckRateLimit_TimeReset(t *testing.T) {
 
 	// Manually reset the last request time to more than a minute ago
 	rl.mu.Lock()
-	if usage, exists := rl.userRequests[userID]; exists {
+	if usage, exists := rl.store.Get(userID); exists {
 		usage.lastRequestTime = time.Now().Add(-2 * time.Minute)
+		rl.store.Put(userID, usage)
 	}
 	rl.mu.Unlock()
 
@@ -171,8 +172,9 @@ func TestRateLimiter_CheckRateLimit_DayReset(t *testing.T) {
 
 	// Manually reset the day start time to yesterday
 	rl.mu.Lock()
-	if usage, exists := rl.userRequests[userID]; exists {
+	if usage, exists := rl.store.Get(userID); exists {
 		usage.dayStartTime = time.Now().AddDate(0, 0, -1)
+		rl.store.Put(userID, usage)
 	}
 	rl.mu.Unlock()
 
@@ -248,6 +250,52 @@ func TestRateLimiter_MultipleUsers(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestRateLimiter_CheckLimits(t *testing.T) {
+	rl := NewRateLimiter(1, 0, 0, 0)
+
+	// Per-call limits replace the defaults.
+	limits := RateLimits{RequestsPerMinute: 3, MaxDataPerDay: 100}
+	status, err := rl.CheckLimits(testUserID, 60, limits)
+	require.NoError(t, err)
+	assert.Equal(t, RateLimitStatus{Limit: 3, Remaining: 2, Reset: time.Minute}, status)
+
+	status, err = rl.CheckLimits(testUserID, 60, limits)
+	var quotaErr *QuotaExceededError
+	require.ErrorAs(t, err, &quotaErr)
+	assert.Equal(t, "data", quotaErr.Type)
+	assert.Equal(t, 2, status.Remaining, "rejected requests are not counted")
+
+	// Without request limits there is nothing to report.
+	status, err = rl.CheckLimits("other", 0, RateLimits{MaxDataPerDay: 100})
+	require.NoError(t, err)
+	assert.Zero(t, status)
+
+	status, err = rl.CheckLimits("daily", 0, RateLimits{MaxRequestsPerDay: 5})
+	require.NoError(t, err)
+	assert.Equal(t, 4, status.Remaining)
+	assert.Positive(t, status.Reset)
+	assert.LessOrEqual(t, status.Reset, 24*time.Hour)
+}
+
+func TestRateLimiter_ChargeData(t *testing.T) {
+	rl := NewRateLimiter(1, 0, 0, 0)
+	limits := RateLimits{RequestsPerMinute: 1, MaxDataPerDay: 100}
+
+	require.NoError(t, rl.ChargeData(testUserID, 60, limits))
+	err := rl.ChargeData(testUserID, 60, limits)
+	var quotaErr *QuotaExceededError
+	require.ErrorAs(t, err, &quotaErr)
+	assert.Equal(t, "data", quotaErr.Type)
+
+	usage := rl.GetUsage(testUserID)
+	assert.Equal(t, int64(60), usage.dataToday, "rejected data is not counted")
+	assert.Zero(t, usage.requestsToday, "charging data does not count a request")
+
+	// Charged data counts against the quota of later requests.
+	_, err = rl.CheckLimits(testUserID, 50, limits)
+	require.ErrorAs(t, err, &quotaErr)
+}
+
 func TestRateLimitError_Error(t *testing.T) {
 	err := &RateLimitError{
 		Type:       "minute",
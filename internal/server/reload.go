@@ -0,0 +1,303 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+)
+
+var (
+	errReloadDisabled = errors.New("configuration reload not enabled")
+	errPipelineClosed = errors.New("OCR pipeline closed")
+)
+
+// ReloadStatus describes the default pipeline and its reloads.
+type ReloadStatus struct {
+	// Generation counts the default pipelines: 1 is the one built at
+	// startup, each successful reload adds one.
+	Generation int            `json:"generation"`
+	LoadedAt   *time.Time     `json:"loaded_at,omitempty"`
+	Reloads    int            `json:"reloads"`
+	Failures   int            `json:"failures"`
+	LastReload *ReloadAttempt `json:"last_reload,omitempty"`
+}
+
+// ReloadAttempt is the outcome of a reload.
+type ReloadAttempt struct {
+	At         time.Time `json:"at"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	WarmupMs   int64     `json:"warmup_ms"`
+}
+
+// ReloadResponse is the response of POST /admin/reload.
+type ReloadResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	ReloadStatus
+}
+
+// servedPipeline is a generation of the default pipeline. Each call holds
+// it, so that a reload closes it only once running calls return; requests
+// that got it before the reload continue with the current one.
+type servedPipeline struct {
+	s          *Server
+	pipeline   pipelineInterface
+	generation int
+	loadedAt   time.Time
+
+	mu sync.Mutex
+	// active counts the calls using the pipeline; a retired pipeline is
+	// closed when the last one returns.
+	active  int
+	retired bool
+	closed  bool
+}
+
+// acquire holds the pipeline for a call and returns it with the function
+// ending the call. A retired pipeline hands the call to the current one.
+func (p *servedPipeline) acquire() (pipelineInterface, func(), error) {
+	p.mu.Lock()
+	if !p.retired {
+		p.active++
+		p.mu.Unlock()
+		return p.pipeline, p.release, nil
+	}
+	p.mu.Unlock()
+
+	switch current := p.s.defaultPipeline().(type) {
+	case nil:
+		return nil, nil, errPipelineClosed
+	case *servedPipeline:
+		if current == p {
+			return nil, nil, errPipelineClosed
+		}
+		return current.acquire()
+	default:
+		return current, func() {}, nil
+	}
+}
+
+func (p *servedPipeline) release() {
+	p.mu.Lock()
+	p.active--
+	closeNow := p.retired && p.active == 0 && !p.closed
+	p.closed = p.closed || closeNow
+	p.mu.Unlock()
+	if closeNow {
+		if err := p.pipeline.Close(); err != nil {
+			slog.Warn("Failed to close replaced pipeline", "generation", p.generation, "error", err)
+			return
+		}
+		slog.Info("Replaced pipeline closed after its requests finished", "generation", p.generation)
+	}
+}
+
+// Close retires the pipeline. It is closed right away if no call is using
+// it, otherwise when the last one returns.
+func (p *servedPipeline) Close() error {
+	p.mu.Lock()
+	p.retired = true
+	closeNow := p.active == 0 && !p.closed
+	p.closed = p.closed || closeNow
+	active := p.active
+	p.mu.Unlock()
+	if !closeNow {
+		if active > 0 {
+			slog.Info("Draining replaced pipeline", "generation", p.generation, "active_calls", active)
+		}
+		return nil
+	}
+	return p.pipeline.Close()
+}
+
+func (p *servedPipeline) ProcessImageContext(ctx context.Context, img image.Image) (*pipeline.OCRImageResult, error) {
+	pl, release, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return pl.ProcessImageContext(ctx, img)
+}
+
+func (p *servedPipeline) ProcessPDFContext(ctx context.Context, filename string, pageRange string) (*pipeline.OCRPDFResult, error) {
+	pl, release, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return pl.ProcessPDFContext(ctx, filename, pageRange)
+}
+
+func (p *servedPipeline) ProcessPDFStream(ctx context.Context, filename string, pageRange string,
+	emit func(*pipeline.OCRPDFPageResult) error,
+) (*pipeline.OCRPDFResult, error) {
+	pl, release, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return pl.ProcessPDFStream(ctx, filename, pageRange, emit)
+}
+
+// Reload re-reads the pipeline configuration and replaces the default
+// pipeline with one built from it, warmed up and self-tested like at
+// startup. Requests keep using the previous pipeline until the new one is
+// ready, and it is closed once the calls using it return. If the
+// configuration is invalid or the new pipeline fails, the previous one stays
+// in place. Reloads run one at a time.
+func (s *Server) Reload(ctx context.Context) (ReloadStatus, error) {
+	if s.loadPipelineConfig == nil {
+		return s.reloadStatus(), errReloadDisabled
+	}
+	if err := s.probes.checkStarted(); err != nil {
+		return s.reloadStatus(), err
+	}
+
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	start := time.Now()
+	warmup, err := s.reloadPipeline(ctx)
+	attempt := &ReloadAttempt{
+		At:         start,
+		Success:    err == nil,
+		DurationMs: time.Since(start).Milliseconds(),
+		WarmupMs:   warmup.Milliseconds(),
+	}
+	result := "success"
+	if err != nil {
+		attempt.Error = err.Error()
+		result = "failure"
+	}
+	pipelineReloadsTotal.WithLabelValues(result).Inc()
+
+	s.pipelineMu.Lock()
+	s.reload.LastReload = attempt
+	if err != nil {
+		s.reload.Failures++
+	} else {
+		s.reload.Reloads++
+	}
+	s.pipelineMu.Unlock()
+
+	status := s.reloadStatus()
+	if err != nil {
+		slog.Error("Pipeline reload failed, keeping the running pipeline", "error", err)
+		return status, err
+	}
+	slog.Info("Pipeline reloaded", "generation", status.Generation, "duration", time.Since(start))
+	return status, nil
+}
+
+// reloadPipeline builds, warms up and checks a pipeline from the reloaded
+// configuration and swaps it in. It returns how long warmup took.
+func (s *Server) reloadPipeline(ctx context.Context) (time.Duration, error) {
+	config, err := s.loadPipelineConfig()
+	if err != nil {
+		return 0, fmt.Errorf("invalid configuration: %w", err)
+	}
+	build := s.newPipeline
+	if build == nil {
+		build = func(config pipeline.Config) (pipelineInterface, error) {
+			pl, err := buildDefaultPipeline(config)
+			if err != nil {
+				return nil, err
+			}
+			return pl, nil
+		}
+	}
+	pl, err := build(config)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build pipeline: %w", err)
+	}
+
+	var iterations int
+	var selfTestTimeout time.Duration
+	if s.probes != nil {
+		iterations = s.probes.config.WarmupIterations
+		if s.probes.config.SelfTestInterval > 0 {
+			selfTestTimeout = s.probes.config.SelfTestTimeout
+		}
+	}
+	warmup, err := warmupPipeline(pl, iterations)
+	if err != nil {
+		_ = pl.Close()
+		return warmup, fmt.Errorf("warmup failed: %w", err)
+	}
+	if selfTestTimeout > 0 {
+		testCtx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+		start := time.Now()
+		text, err := selfTestOCR(testCtx, pl)
+		cancel()
+		if err != nil {
+			_ = pl.Close()
+			return warmup, fmt.Errorf("self-test failed: %w", err)
+		}
+		s.probes.recordSelfTest(start, time.Since(start), text, nil)
+	}
+	if err := ctx.Err(); err != nil {
+		_ = pl.Close()
+		return warmup, err
+	}
+
+	if old := s.setPipeline(pl, config); old != nil {
+		if err := old.Close(); err != nil {
+			slog.Warn("Failed to close replaced pipeline", "error", err)
+		}
+	}
+	// Cached pipelines were derived from the previous configuration
+	if s.pipelineCache != nil {
+		if n := s.pipelineCache.EvictAll(); n > 0 {
+			slog.Info("Evicted cached pipelines of the previous configuration", "count", n)
+		}
+	}
+	return warmup, nil
+}
+
+// reloadStatus returns the generation of the default pipeline and the
+// reload counts.
+func (s *Server) reloadStatus() ReloadStatus {
+	s.pipelineMu.RLock()
+	defer s.pipelineMu.RUnlock()
+	status := s.reload
+	if sp, ok := s.pipeline.(*servedPipeline); ok {
+		status.Generation = sp.generation
+		loadedAt := sp.loadedAt
+		status.LoadedAt = &loadedAt
+	}
+	return status
+}
+
+// adminReloadHandler reports the reload status (GET) or reloads the
+// configuration (POST).
+func (s *Server) adminReloadHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.writeAdminJSON(w, s.reloadStatus())
+	case http.MethodPost:
+		// A client going away does not abort the reload
+		status, err := s.Reload(context.WithoutCancel(r.Context()))
+		response := ReloadResponse{Success: err == nil, ReloadStatus: status}
+		if err != nil {
+			response.Error = err.Error()
+			code := http.StatusUnprocessableEntity
+			if errors.Is(err, errReloadDisabled) || s.probes.checkStarted() != nil {
+				code = http.StatusServiceUnavailable
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(code)
+		}
+		s.writeAdminJSON(w, response)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reloadMockPipeline recognizes text and reports when it is closed. If
+// block is set, calls wait for it after signalling entered.
+type reloadMockPipeline struct {
+	mockPipelineForTesting
+	entered chan struct{}
+	block   chan struct{}
+	closed  atomic.Bool
+}
+
+func newReloadMock(text string) *reloadMockPipeline {
+	return &reloadMockPipeline{mockPipelineForTesting: mockPipelineForTesting{
+		processImageResult: &pipeline.OCRImageResult{Regions: []pipeline.OCRRegionResult{{Text: text}}},
+	}}
+}
+
+func (m *reloadMockPipeline) ProcessImageContext(ctx context.Context, img image.Image) (*pipeline.OCRImageResult, error) {
+	if m.closed.Load() {
+		return nil, errors.New("pipeline used after close")
+	}
+	if m.block != nil {
+		m.entered <- struct{}{}
+		<-m.block
+	}
+	return m.mockPipelineForTesting.ProcessImageContext(ctx, img)
+}
+
+func (m *reloadMockPipeline) Close() error {
+	m.closed.Store(true)
+	return nil
+}
+
+func recognize(t *testing.T, pl pipelineInterface) string {
+	t.Helper()
+	res, err := pl.ProcessImageContext(context.Background(), createTestImage(10, 10))
+	require.NoError(t, err)
+	return res.Regions[0].Text
+}
+
+func postReload(t *testing.T, mux *http.ServeMux) (int, ReloadResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/reload", nil))
+	var resp ReloadResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	return w.Code, resp
+}
+
+func TestReload_SwapsAndDrains(t *testing.T) {
+	first, second := newReloadMock("first"), newReloadMock("second")
+	first.entered, first.block = make(chan struct{}), make(chan struct{})
+	s, mux := newProbeTestServer(t, ProbeConfig{SelfTestInterval: -1},
+		func() (pipelineInterface, error) { return first, nil })
+	require.NoError(t, s.WaitStarted(context.Background()))
+	s.loadPipelineConfig = func() (pipeline.Config, error) { return languageConfig("de"), nil }
+	var built pipeline.Config
+	s.newPipeline = func(config pipeline.Config) (pipelineInterface, error) {
+		built = config
+		return second, nil
+	}
+
+	// A request in flight on the first pipeline
+	old := s.defaultPipeline()
+	inFlight := make(chan string)
+	go func() {
+		res, err := old.ProcessImageContext(context.Background(), createTestImage(10, 10))
+		if err != nil {
+			inFlight <- err.Error()
+			return
+		}
+		inFlight <- res.Regions[0].Text
+	}()
+	<-first.entered
+
+	code, resp := postReload(t, mux)
+	require.Equal(t, http.StatusOK, code, resp.Error)
+	assert.True(t, resp.Success)
+	assert.Equal(t, 2, resp.Generation)
+	assert.Equal(t, 1, resp.Reloads)
+	assert.Equal(t, "de", built.Recognizer.Language)
+	_, baseConfig := s.defaultPipelineConfig()
+	assert.Equal(t, "de", baseConfig.Recognizer.Language)
+
+	// New calls use the new pipeline, also through the old handle, while
+	// the first one is not closed before its call returns
+	assert.Equal(t, "second", recognize(t, s.defaultPipeline()))
+	assert.Equal(t, "second", recognize(t, old))
+	assert.False(t, first.closed.Load())
+
+	close(first.block)
+	assert.Equal(t, "first", <-inFlight)
+	assert.Eventually(t, first.closed.Load, time.Second, time.Millisecond)
+	assert.False(t, second.closed.Load())
+
+	require.NoError(t, s.Close())
+	assert.True(t, second.closed.Load())
+}
+
+func TestReload_Rejected(t *testing.T) {
+	current := newReloadMock("current")
+	s, mux := newProbeTestServer(t, ProbeConfig{SelfTestInterval: time.Hour},
+		func() (pipelineInterface, error) { return current, nil })
+	require.NoError(t, s.WaitStarted(context.Background()))
+
+	broken := newReloadMock("")
+	broken.processImageError = errors.New("recognizer returned garbage")
+	garbage := newReloadMock("lorem ipsum")
+	tests := []struct {
+		name    string
+		load    func() (pipeline.Config, error)
+		build   func(pipeline.Config) (pipelineInterface, error)
+		wantErr string
+	}{
+		{
+			name: "invalid config",
+			load: func() (pipeline.Config, error) {
+				return pipeline.Config{}, errors.New("det_db_thresh must be between 0 and 1")
+			},
+			wantErr: "invalid configuration: det_db_thresh must be between 0 and 1",
+		},
+		{
+			name:    "build failure",
+			build:   func(pipeline.Config) (pipelineInterface, error) { return nil, errors.New("model not found") },
+			wantErr: "failed to build pipeline: model not found",
+		},
+		{
+			name:    "self-test failure",
+			build:   func(pipeline.Config) (pipelineInterface, error) { return broken, nil },
+			wantErr: "self-test failed: recognizer returned garbage",
+		},
+		{
+			name:    "self-test wrong text",
+			build:   func(pipeline.Config) (pipelineInterface, error) { return garbage, nil },
+			wantErr: `self-test failed: self-test recognized "lorem ipsum" instead of "POGO 0123456789"`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.loadPipelineConfig = tt.load
+			if s.loadPipelineConfig == nil {
+				s.loadPipelineConfig = func() (pipeline.Config, error) { return languageConfig("de"), nil }
+			}
+			s.newPipeline = tt.build
+
+			code, resp := postReload(t, mux)
+			assert.Equal(t, http.StatusUnprocessableEntity, code)
+			assert.False(t, resp.Success)
+			assert.Equal(t, tt.wantErr, resp.Error)
+			assert.Equal(t, 1, resp.Generation, "the running pipeline stays")
+			assert.Equal(t, i+1, resp.Failures)
+			require.NotNil(t, resp.LastReload)
+			assert.False(t, resp.LastReload.Success)
+
+			assert.Equal(t, "current", recognize(t, s.defaultPipeline()))
+			assert.False(t, current.closed.Load())
+		})
+	}
+	assert.True(t, broken.closed.Load(), "a rejected pipeline is closed")
+	assert.True(t, garbage.closed.Load())
+}
+
+func TestReload_Disabled(t *testing.T) {
+	s := &Server{pipeline: &mockPipelineForTesting{}}
+	mux := http.NewServeMux()
+	s.SetupRoutes(mux)
+
+	code, resp := postReload(t, mux)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, errReloadDisabled.Error(), resp.Error)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/reload", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/admin/reload", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestServedPipeline_Close(t *testing.T) {
+	mock := newReloadMock("text")
+	s := &Server{}
+	s.setPipeline(mock, pipeline.Config{})
+	pl := s.defaultPipeline()
+
+	require.NoError(t, pl.Close())
+	assert.True(t, mock.closed.Load())
+	_, err := pl.ProcessImageContext(context.Background(), createTestImage(10, 10))
+	assert.ErrorIs(t, err, errPipelineClosed)
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/MeKo-Tech/pogo/internal/pdf"
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+)
+
+// requestFormat returns the requested output format from the form or query (empty means json).
+// Without one, an Accept header asking for NDJSON selects the ndjson format.
+func requestFormat(r *http.Request) string {
+	if format := r.FormValue("format"); format != "" {
+		return format
+	}
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	if acceptsNDJSON(r) {
+		return formatNDJSON
+	}
+	return ""
+}
+
+// acceptsNDJSON reports whether the Accept header of r asks for
+// application/x-ndjson.
+func acceptsNDJSON(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			if mediaType, _, err := mime.ParseMediaType(part); err == nil && mediaType == ndjsonContentType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// searchableOptionsFromRequest reads the searchable PDF options ("pdfa", "dpi") from the request.
+func searchableOptionsFromRequest(r *http.Request) pdf.SearchableOptions {
+	var opts pdf.SearchableOptions
+	if v := r.FormValue("pdfa"); v == "1" || strings.ToLower(v) == stringTrue {
+		opts.PDFA = true
+	}
+	if v := r.FormValue("dpi"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			opts.DPI = n
+		}
+	}
+	return opts
+}
+
+// writeSearchableImageResponse returns a single-page PDF showing img with an invisible text layer.
+func (s *Server) writeSearchableImageResponse(w http.ResponseWriter, r *http.Request, img image.Image, res *pipeline.OCRImageResult) {
+	var buf bytes.Buffer
+	pages := []pdf.TextLayerPage{pipeline.ToTextLayerImage(res, 1)}
+	if err := pdf.WriteSearchablePDFFromImages(&buf, []image.Image{img}, pages, searchableOptionsFromRequest(r)); err != nil {
+		s.writeErrorResponse(w, fmt.Sprintf("Failed to create searchable PDF: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writePDFBytes(w, buf.Bytes(), "ocr.pdf")
+}
+
+// writeSearchablePDFResponse returns the uploaded PDF with an invisible text layer added.
+func (s *Server) writeSearchablePDFResponse(w http.ResponseWriter, r *http.Request, res *pipeline.OCRPDFResult, sourceFile string) {
+	var buf bytes.Buffer
+	if err := pdf.WriteSearchablePDF(&buf, sourceFile, pipeline.ToTextLayerPDF(res), searchableOptionsFromRequest(r)); err != nil {
+		s.writeErrorResponse(w, fmt.Sprintf("Failed to create searchable PDF: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writePDFBytes(w, buf.Bytes(), "ocr.pdf")
+}
+
+func writePDFBytes(w http.ResponseWriter, data []byte, filename string) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_ocrImageHandler_SearchablePDF(t *testing.T) {
+	res := &pipeline.OCRImageResult{Width: 100, Height: 50}
+	res.Regions = []pipeline.OCRRegionResult{{Text: "Hello", Box: struct{ X, Y, W, H int }{X: 5, Y: 5, W: 50, H: 20}}}
+	server := &Server{pipeline: &mockPipelineForTesting{processImageResult: res}, maxUploadMB: 10}
+
+	imgData, err := encodeImageToPNG(createTestImage(100, 50))
+	require.NoError(t, err)
+	req, err := createMultipartFormRequest(imgData, "test.png", map[string]string{"format": "searchable-pdf", "pdfa": "true"})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+
+	server.ocrImageHandler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")))
+	assert.NoError(t, api.Validate(bytes.NewReader(w.Body.Bytes()), nil))
+}
+
+func TestServer_ocrPdfHandler_SearchablePDF(t *testing.T) {
+	input, err := os.ReadFile(filepath.Join("..", "..", "testdata", "documents", "sample.pdf"))
+	if err != nil {
+		t.Skip("sample.pdf test document not available")
+	}
+	res := &pipeline.OCRPDFResult{TotalPages: 1, Pages: []pipeline.OCRPDFPageResult{{
+		PageNumber: 1, Width: 100, Height: 100,
+		Images: []pipeline.OCRPDFImageResult{{Width: 100, Height: 100, Regions: []pipeline.OCRRegionResult{
+			{Text: "Hello World", Box: struct{ X, Y, W, H int }{X: 10, Y: 10, W: 80, H: 10}},
+		}}},
+	}}}
+	server := &Server{pipeline: &mockPipelineForTesting{processPDFResult: res}, maxUploadMB: 10}
+
+	// Vector text is enabled by default; searchable output must still use the OCR pipeline.
+	req, err := createMultipartPDFFormRequest(input, "sample.pdf", map[string]string{"format": "searchable-pdf"})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+
+	server.ocrPdfHandler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.NoError(t, api.Validate(bytes.NewReader(w.Body.Bytes()), nil))
+}
+
+func TestServer_ocrPdfHandler_SearchablePDF_InvalidSource(t *testing.T) {
+	res := &pipeline.OCRPDFResult{Pages: []pipeline.OCRPDFPageResult{{PageNumber: 1}}}
+	server := &Server{pipeline: &mockPipelineForTesting{processPDFResult: res}, maxUploadMB: 10}
+
+	req, err := createMultipartPDFFormRequest([]byte("not a pdf"), "bad.pdf", map[string]string{
+		"format":             "searchable-pdf",
+		"enable-vector-text": "false",
+	})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+
+	server.ocrPdfHandler(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestServer_ocrPdfHandler_SearchablePDF_RejectsPasswords(t *testing.T) {
+	server := &Server{pipeline: &mockPipelineForTesting{}, maxUploadMB: 10}
+
+	req, err := createMultipartPDFFormRequest([]byte("%PDF-1.4"), "locked.pdf", map[string]string{
+		"format":        "searchable-pdf",
+		"user-password": "secret",
+	})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+
+	server.ocrPdfHandler(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+)
+
+// DefaultSSEHeartbeat is how often an event stream sends a comment line, so
+// that proxies do not close it while a page takes long.
+const DefaultSSEHeartbeat = 15 * time.Second
+
+// PDF progress events of a Server-Sent Events stream.
+const (
+	SSEEventStarted       = "started"
+	SSEEventPageStarted   = "page-started"
+	SSEEventPageCompleted = "page-completed"
+	SSEEventFinished      = "finished"
+	SSEEventError         = "error"
+)
+
+const sseContentType = "text/event-stream"
+
+// SSEProgressEvent is the data of an SSE progress event. "started" carries
+// the number of pages, "page-started" the page number, "page-completed" the
+// page result and the pages done so far, "finished" the document totals
+// (without pages) and "error" the error that ended processing.
+type SSEProgressEvent struct {
+	Filename   string                     `json:"filename,omitempty"`
+	PageNumber int                        `json:"page_number,omitempty"`
+	Completed  int                        `json:"completed,omitempty"`
+	TotalPages int                        `json:"total_pages,omitempty"`
+	Page       *pipeline.OCRPDFPageResult `json:"page,omitempty"`
+	Document   *pipeline.OCRPDFResult     `json:"document,omitempty"`
+	Error      string                     `json:"error,omitempty"`
+}
+
+// requestsSSE reports whether r asks for a progress event stream, with
+// stream=sse or an Accept header for text/event-stream.
+func requestsSSE(r *http.Request) bool {
+	if r.FormValue("stream") == "sse" {
+		return true
+	}
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			if mediaType, _, err := mime.ParseMediaType(part); err == nil && mediaType == sseContentType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sseWriter writes Server-Sent Events to a response. It is safe for
+// concurrent use; after a failed write it drops further events.
+type sseWriter struct {
+	mu  sync.Mutex
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	err error
+}
+
+// newSSEWriter starts an event stream response on w.
+func newSSEWriter(w http.ResponseWriter) *sseWriter {
+	w.Header().Set("Content-Type", sseContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	// Keep nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	return &sseWriter{w: w, rc: http.NewResponseController(w)}
+}
+
+// event sends an event with data encoded as JSON.
+func (s *sseWriter) event(name string, data any) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding %s event: %v\n", name, err)
+		return
+	}
+	s.write("event: " + name + "\ndata: " + string(b) + "\n\n")
+}
+
+// heartbeat sends a comment line every interval until ctx ends.
+func (s *sseWriter) heartbeat(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.write(": heartbeat\n\n")
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *sseWriter) write(msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return
+	}
+	if _, s.err = s.w.Write([]byte(msg)); s.err == nil {
+		if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			s.err = err
+		}
+	}
+	if s.err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing event stream: %v\n", s.err)
+	}
+}
+
+// sseProgress reports the progress of a PDF as SSE events. Completed pages
+// are sent by the stream's emit function, which has their results.
+type sseProgress struct {
+	pipeline.NoOpProgressCallback
+	sse      *sseWriter
+	filename string
+	total    int
+}
+
+func (p *sseProgress) OnStart(total int) {
+	p.total = total
+	p.sse.event(SSEEventStarted, SSEProgressEvent{Filename: p.filename, TotalPages: total})
+}
+
+func (p *sseProgress) OnPageStart(pageNum int) {
+	p.sse.event(SSEEventPageStarted, SSEProgressEvent{PageNumber: pageNum, TotalPages: p.total})
+}
+
+// streamPdfEvents OCRs the PDF page by page and reports its progress as
+// Server-Sent Events: "started", then "page-started" and "page-completed"
+// (with the page result) per page, and finally "finished" with the totals,
+// or "error". Comment lines are sent between events every heartbeat.
+func (s *Server) streamPdfEvents(ctx context.Context, w http.ResponseWriter, pl pipelineInterface,
+	tempFile, filename, pageRange string,
+) {
+	sse := newSSEWriter(w)
+	interval := s.sseHeartbeat
+	if interval <= 0 {
+		interval = DefaultSSEHeartbeat
+	}
+	heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sse.heartbeat(heartbeatCtx, interval)
+	}()
+	// The response must not be written once the handler returns
+	defer wg.Wait()
+	defer stopHeartbeat()
+
+	progress := &sseProgress{sse: sse, filename: filename}
+	var completed, textLength, regions int
+	start := time.Now()
+	res, err := pl.ProcessPDFStream(pipeline.ContextWithProgress(ctx, progress), tempFile, pageRange,
+		func(page *pipeline.OCRPDFPageResult) error {
+			completed++
+			for _, img := range page.Images {
+				regions += len(img.Regions)
+				for _, region := range img.Regions {
+					textLength += len(region.Text)
+				}
+			}
+			sse.event(SSEEventPageCompleted, SSEProgressEvent{
+				PageNumber: page.PageNumber,
+				Completed:  completed,
+				TotalPages: progress.total,
+				Page:       page,
+			})
+			return nil
+		})
+	if err != nil && (res == nil || !res.Truncated) {
+		ocrRequestsTotal.WithLabelValues("pdf", "error").Inc()
+		sse.event(SSEEventError, SSEProgressEvent{Filename: filename, Error: err.Error()})
+		return
+	}
+
+	if res.Truncated {
+		ocrRequestsTotal.WithLabelValues("pdf", "truncated").Inc()
+	} else {
+		ocrRequestsTotal.WithLabelValues("pdf", "success").Inc()
+	}
+	ocrProcessingDuration.WithLabelValues("pdf").Observe(time.Since(start).Seconds())
+	ocrTextLength.WithLabelValues("pdf").Observe(float64(textLength))
+	ocrRegionsDetected.WithLabelValues("pdf").Observe(float64(regions))
+	doc := *res
+	doc.Filename = filename
+	doc.Pages = nil
+	sse.event(SSEEventFinished, SSEProgressEvent{Filename: filename, Completed: completed, Document: &doc})
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sseTestEvent struct {
+	name string
+	data SSEProgressEvent
+}
+
+// parseSSE splits an event stream into its events and counts its comments.
+func parseSSE(t *testing.T, body string) ([]sseTestEvent, int) {
+	t.Helper()
+	var events []sseTestEvent
+	var comments int
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		if strings.HasPrefix(block, ":") {
+			comments++
+			continue
+		}
+		var ev sseTestEvent
+		for _, line := range strings.Split(block, "\n") {
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				ev.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev.data))
+			}
+		}
+		events = append(events, ev)
+	}
+	return events, comments
+}
+
+// slowPDFPipeline delays every page.
+type slowPDFPipeline struct {
+	mockPipelineForTesting
+	delay time.Duration
+}
+
+func (p *slowPDFPipeline) ProcessPDFStream(ctx context.Context, filename, pageRange string,
+	emit func(*pipeline.OCRPDFPageResult) error,
+) (*pipeline.OCRPDFResult, error) {
+	return p.mockPipelineForTesting.ProcessPDFStream(ctx, filename, pageRange, func(page *pipeline.OCRPDFPageResult) error {
+		time.Sleep(p.delay)
+		return emit(page)
+	})
+}
+
+func TestServer_ocrPdfHandler_SSE(t *testing.T) {
+	// The pipeline names the PDF by the temporary upload file
+	res := &pipeline.OCRPDFResult{Filename: "/tmp/upload-1.pdf", TotalPages: 2, Pages: []pipeline.OCRPDFPageResult{
+		{PageNumber: 1, Images: []pipeline.OCRPDFImageResult{{Regions: []pipeline.OCRRegionResult{{Text: "one"}}}}},
+		{PageNumber: 2},
+	}}
+
+	t.Run("events", func(t *testing.T) {
+		server := &Server{pipeline: &mockPipelineForTesting{processPDFResult: res}, maxUploadMB: 10}
+		req, err := createMultipartPDFFormRequest([]byte("%PDF-1.4"), "doc.pdf", map[string]string{
+			"stream":             "sse",
+			"enable-vector-text": "false",
+		})
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+
+		server.ocrPdfHandler(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+		assert.True(t, w.Flushed)
+		events, _ := parseSSE(t, w.Body.String())
+		var names []string
+		for _, ev := range events {
+			names = append(names, ev.name)
+		}
+		assert.Equal(t, []string{
+			SSEEventStarted,
+			SSEEventPageStarted, SSEEventPageCompleted,
+			SSEEventPageStarted, SSEEventPageCompleted,
+			SSEEventFinished,
+		}, names)
+		assert.Equal(t, 2, events[0].data.TotalPages)
+		assert.Equal(t, "doc.pdf", events[0].data.Filename)
+		assert.Equal(t, 2, events[3].data.PageNumber)
+		require.NotNil(t, events[2].data.Page)
+		assert.Equal(t, "one", events[2].data.Page.Images[0].Regions[0].Text)
+		assert.Equal(t, 1, events[2].data.Completed)
+		require.NotNil(t, events[5].data.Document)
+		assert.Empty(t, events[5].data.Document.Pages)
+		assert.Equal(t, 2, events[5].data.Completed)
+		assert.Equal(t, "doc.pdf", events[5].data.Filename)
+		assert.Equal(t, "doc.pdf", events[5].data.Document.Filename)
+		assert.NotContains(t, w.Body.String(), "/tmp/")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		server := &Server{pipeline: &mockPipelineForTesting{processPDFError: errors.New("broken")}, maxUploadMB: 10}
+		req, err := createMultipartPDFFormRequest([]byte("%PDF-1.4"), "doc.pdf", map[string]string{
+			"enable-vector-text": "false",
+		})
+		require.NoError(t, err)
+		req.Header.Set("Accept", "text/event-stream")
+		w := httptest.NewRecorder()
+
+		server.ocrPdfHandler(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		events, _ := parseSSE(t, w.Body.String())
+		require.Len(t, events, 1)
+		assert.Equal(t, SSEEventError, events[0].name)
+		assert.Equal(t, "broken", events[0].data.Error)
+		assert.Equal(t, "doc.pdf", events[0].data.Filename)
+	})
+
+	t.Run("heartbeat", func(t *testing.T) {
+		server := &Server{
+			pipeline:     &slowPDFPipeline{mockPipelineForTesting{processPDFResult: res}, 50 * time.Millisecond},
+			maxUploadMB:  10,
+			sseHeartbeat: 5 * time.Millisecond,
+		}
+		req, err := createMultipartPDFFormRequest([]byte("%PDF-1.4"), "doc.pdf", map[string]string{
+			"stream":             "sse",
+			"enable-vector-text": "false",
+		})
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+
+		server.ocrPdfHandler(w, req)
+
+		events, comments := parseSSE(t, w.Body.String())
+		assert.Positive(t, comments)
+		assert.Equal(t, SSEEventFinished, events[len(events)-1].name)
+	})
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"image"
 	"image/color"
 	"image/png"
@@ -15,8 +16,8 @@ import (
 // mockPipeline is a simple mock implementation of the pipeline for testing.
 type mockPipeline struct{}
 
-// ProcessImage returns a mock OCR result for testing.
-func (m *mockPipeline) ProcessImage(img image.Image) (*pipeline.OCRImageResult, error) {
+// ProcessImageContext returns a mock OCR result for testing.
+func (m *mockPipeline) ProcessImageContext(_ context.Context, img image.Image) (*pipeline.OCRImageResult, error) {
 	bounds := img.Bounds()
 	return &pipeline.OCRImageResult{
 		Width:  bounds.Dx(),
@@ -61,8 +62,8 @@ func (m *mockPipeline) ProcessImage(img image.Image) (*pipeline.OCRImageResult,
 	}, nil
 }
 
-// ProcessPDF returns a mock PDF OCR result for testing.
-func (m *mockPipeline) ProcessPDF(filename string, pageRange string) (*pipeline.OCRPDFResult, error) {
+// ProcessPDFContext returns a mock PDF OCR result for testing.
+func (m *mockPipeline) ProcessPDFContext(_ context.Context, filename string, pageRange string) (*pipeline.OCRPDFResult, error) {
 	return &pipeline.OCRPDFResult{
 		Filename:   filename,
 		TotalPages: 1,
@@ -98,6 +99,14 @@ func (m *mockPipeline) ProcessPDF(filename string, pageRange string) (*pipeline.
 	}, nil
 }
 
+// ProcessPDFStream emits the pages of the mock PDF result one by one.
+func (m *mockPipeline) ProcessPDFStream(ctx context.Context, filename string, pageRange string,
+	emit func(*pipeline.OCRPDFPageResult) error,
+) (*pipeline.OCRPDFResult, error) {
+	res, _ := m.ProcessPDFContext(ctx, filename, pageRange)
+	return streamMockPDF(res, nil, emit)
+}
+
 func (m *mockPipeline) Close() error {
 	return nil
 }
@@ -110,14 +119,66 @@ type mockPipelineForTesting struct {
 	processPDFError    error
 }
 
-func (m *mockPipelineForTesting) ProcessImage(img image.Image) (*pipeline.OCRImageResult, error) {
+func (m *mockPipelineForTesting) ProcessImageContext(_ context.Context, _ image.Image) (*pipeline.OCRImageResult, error) {
 	return m.processImageResult, m.processImageError
 }
 
-func (m *mockPipelineForTesting) ProcessPDF(filename string, pageRange string) (*pipeline.OCRPDFResult, error) {
+func (m *mockPipelineForTesting) ProcessPDFContext(_ context.Context, _ string, _ string) (*pipeline.OCRPDFResult, error) {
 	return m.processPDFResult, m.processPDFError
 }
 
+// ProcessPDFStream streams the mock result, reporting progress to a
+// callback set with pipeline.ContextWithProgress like the real pipeline.
+func (m *mockPipelineForTesting) ProcessPDFStream(ctx context.Context, _ string, _ string,
+	emit func(*pipeline.OCRPDFPageResult) error,
+) (*pipeline.OCRPDFResult, error) {
+	progress := pipeline.ProgressFromContext(ctx)
+	if progress == nil || m.processPDFResult == nil {
+		return streamMockPDF(m.processPDFResult, m.processPDFError, emit)
+	}
+	total := len(m.processPDFResult.Pages)
+	progress.OnStart(total)
+	count := 0
+	res, err := streamMockPDF(m.processPDFResult, m.processPDFError, func(page *pipeline.OCRPDFPageResult) error {
+		if pp, ok := progress.(pipeline.PageProgressCallback); ok {
+			pp.OnPageStart(page.PageNumber)
+		}
+		count++
+		if err := emit(page); err != nil {
+			return err
+		}
+		progress.OnProgress(count, total)
+		return nil
+	})
+	if err != nil {
+		progress.OnError(count, err)
+	} else {
+		progress.OnComplete()
+	}
+	return res, err
+}
+
+// streamMockPDF emits the pages of res and returns it without pages. If err
+// is set, it is returned after the pages, together with res only if res is
+// marked as truncated.
+func streamMockPDF(res *pipeline.OCRPDFResult, err error,
+	emit func(*pipeline.OCRPDFPageResult) error,
+) (*pipeline.OCRPDFResult, error) {
+	if res != nil {
+		for i := range res.Pages {
+			if emitErr := emit(&res.Pages[i]); emitErr != nil {
+				return nil, emitErr
+			}
+		}
+	}
+	if err != nil && (res == nil || !res.Truncated) {
+		return nil, err
+	}
+	doc := *res
+	doc.Pages = nil
+	return &doc, err
+}
+
 func (m *mockPipelineForTesting) Close() error {
 	return nil
 }
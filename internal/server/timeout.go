@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// processingTimeout returns the configured processing timeout, defaulting to
+// 90% of the request timeout.
+func processingTimeout(config Config) time.Duration {
+	if config.ProcessingTimeout > 0 {
+		return config.ProcessingTimeout
+	}
+	return time.Duration(config.TimeoutSec) * time.Second * 9 / 10
+}
+
+// processingContext returns the context the OCR work of a request runs
+// under. It ends with parent, e.g. when the client disconnects, or when the
+// server's processing timeout expires. Time spent waiting for admission
+// counts against the timeout, which must fit the request timeout.
+func (s *Server) processingContext(parent context.Context) (context.Context, context.CancelFunc) {
+	if s.processingTimeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, s.processingTimeout-queueWait(parent))
+}
+
+// processingErrorStatus maps an OCR error to a response status: running out
+// of time is reported as a gateway timeout, anything else as a server error.
+func processingErrorStatus(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// truncatedHeader flags partial results for every output format, including
+// those without a place for a marker in the body.
+const truncatedHeader = "X-OCR-Truncated"
+
+// markTruncated sets the truncated header; it must be called before the
+// response body is written.
+func markTruncated(w http.ResponseWriter) {
+	w.Header().Set(truncatedHeader, "true")
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessingTimeout(t *testing.T) {
+	assert.Equal(t, 27*time.Second, processingTimeout(Config{TimeoutSec: 30}))
+	assert.Equal(t, 5*time.Second, processingTimeout(Config{TimeoutSec: 30, ProcessingTimeout: 5 * time.Second}))
+	assert.Zero(t, processingTimeout(Config{}))
+
+	s := &Server{processingTimeout: time.Minute}
+	ctx, cancel := s.processingContext(context.Background())
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	ctx, cancel = (&Server{}).processingContext(context.Background())
+	defer cancel()
+	_, ok = ctx.Deadline()
+	assert.False(t, ok)
+}
+
+func TestProcessingErrorStatus(t *testing.T) {
+	assert.Equal(t, http.StatusGatewayTimeout, processingErrorStatus(fmt.Errorf("page 2: %w", context.DeadlineExceeded)))
+	assert.Equal(t, http.StatusInternalServerError, processingErrorStatus(errors.New("boom")))
+}
+
+func TestServer_ocrImageHandler_Truncated(t *testing.T) {
+	res := &pipeline.OCRImageResult{Width: 100, Height: 50, Truncated: true,
+		Regions: []pipeline.OCRRegionResult{{DetConfidence: 0.9}}}
+	server := &Server{pipeline: &mockPipelineForTesting{processImageResult: res, processImageError: context.DeadlineExceeded},
+		maxUploadMB: 10}
+
+	imgData, err := encodeImageToPNG(createTestImage(100, 50))
+	require.NoError(t, err)
+	req, err := createMultipartFormRequest(imgData, "test.png", nil)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+
+	server.ocrImageHandler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "true", w.Header().Get(truncatedHeader))
+	assert.Contains(t, w.Body.String(), `"truncated":true`)
+
+	// Without a partial result the deadline is an error.
+	server.pipeline = &mockPipelineForTesting{processImageError: context.DeadlineExceeded}
+	req, err = createMultipartFormRequest(imgData, "test.png", nil)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+
+	server.ocrImageHandler(w, req)
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Empty(t, w.Header().Get(truncatedHeader))
+}
+
+func TestServer_ocrPdfHandler_Truncated(t *testing.T) {
+	res := &pipeline.OCRPDFResult{Filename: "doc.pdf", TotalPages: 1, Truncated: true,
+		Pages: []pipeline.OCRPDFPageResult{{PageNumber: 1}}}
+
+	for _, format := range []string{"json", "ndjson"} {
+		t.Run(format, func(t *testing.T) {
+			server := &Server{pipeline: &mockPipelineForTesting{processPDFResult: res, processPDFError: context.DeadlineExceeded},
+				maxUploadMB: 10}
+			req, err := createMultipartPDFFormRequest([]byte("%PDF-1.4"), "doc.pdf", map[string]string{
+				"format":             format,
+				"enable-vector-text": "false",
+			})
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+
+			server.ocrPdfHandler(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			body := w.Body.String()
+			assert.Contains(t, body, `"page_number":1`)
+			assert.Contains(t, body, `"truncated":true`)
+			assert.NotContains(t, body, `"type":"error"`)
+		})
+	}
+}
+
+func TestServer_ocrBatchHandler_Canceled(t *testing.T) {
+	imgData, err := encodeImageToPNG(createTestImage(20, 20))
+	require.NoError(t, err)
+	body, err := json.Marshal(BatchOCRRequest{Images: []BatchImageRequest{{Name: "a.png", Data: imgData}}})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodPost, "/ocr/batch", bytes.NewReader(body)).WithContext(ctx)
+	w := httptest.NewRecorder()
+	server := &Server{pipeline: &mockPipelineForTesting{processImageResult: &pipeline.OCRImageResult{}}}
+
+	server.ocrBatchHandler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp BatchOCRResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Truncated)
+	assert.Equal(t, "true", w.Header().Get(truncatedHeader))
+	require.Len(t, resp.Results, 1)
+	assert.False(t, resp.Results[0].Success)
+	assert.Equal(t, "Not processed: context canceled", resp.Results[0].Error)
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// tracerName is the instrumentation scope of the server's own spans.
+const tracerName = "github.com/MeKo-Tech/pogo/internal/server"
+
+// tracingMiddleware starts a server span for each request to route. The
+// span continues the caller's trace if the request carries W3C trace
+// context headers, and the handler's context carries it on into the
+// pipeline.
+func (s *Server) tracingMiddleware(route string, next http.HandlerFunc) http.HandlerFunc {
+	return otelhttp.NewHandler(next, route).ServeHTTP
+}
+
+// decodeImage decodes an uploaded image in a span of its own.
+func decodeImage(ctx context.Context, data []byte) (image.Image, error) {
+	_, span := otel.Tracer(tracerName).Start(ctx, "decode")
+	defer span.End()
+	span.SetAttributes(attribute.Int("image.bytes", len(data)))
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	b := img.Bounds()
+	span.SetAttributes(attribute.String("image.format", format),
+		attribute.Int("image.width", b.Dx()), attribute.Int("image.height", b.Dy()))
+	return img, nil
+}
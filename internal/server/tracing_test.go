@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pogov1 "github.com/MeKo-Tech/pogo/api/pogo/v1"
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
+	"github.com/MeKo-Tech/pogo/internal/tracing/tracingtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// Trace context of a caller, as sent in a traceparent header.
+const (
+	testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	testTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testParentID    = "00f067aa0ba902b7"
+)
+
+func TestTracing_HTTP(t *testing.T) {
+	spans := tracingtest.Install(t)
+	s := &Server{
+		pipeline:    &mockPipelineForTesting{processImageResult: &pipeline.OCRImageResult{Width: 20, Height: 20}},
+		maxUploadMB: 10,
+	}
+	mux := http.NewServeMux()
+	s.SetupRoutes(mux)
+
+	imgData, err := encodeImageToPNG(createTestImage(20, 20))
+	require.NoError(t, err)
+	req, err := createMultipartFormRequest(imgData, "a.png", nil)
+	require.NoError(t, err)
+	req.Header.Set("traceparent", testTraceParent)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	got := spans.GetSpans()
+	server := tracingtest.Find(got, "/ocr/image")
+	require.NotNil(t, server, "spans: %v", tracingtest.Names(got))
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+	assert.Equal(t, testTraceID, server.SpanContext.TraceID().String())
+	assert.Equal(t, testParentID, server.Parent.SpanID().String())
+	assert.True(t, server.Parent.IsRemote())
+
+	decode := tracingtest.Find(got, "decode")
+	require.NotNil(t, decode)
+	assert.Equal(t, server.SpanContext.SpanID(), decode.Parent.SpanID())
+
+	// A request without trace context starts a new trace
+	req, err = createMultipartFormRequest([]byte("not an image"), "a.png", nil)
+	require.NoError(t, err)
+	spans.Reset()
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+	got = spans.GetSpans()
+	decode = tracingtest.Find(got, "decode")
+	require.NotNil(t, decode)
+	assert.Equal(t, codes.Error, decode.Status.Code)
+	server = tracingtest.Find(got, "/ocr/image")
+	require.NotNil(t, server)
+	assert.False(t, server.Parent.IsValid())
+	assert.Equal(t, server.SpanContext.TraceID(), decode.SpanContext.TraceID())
+}
+
+func TestTracing_GRPC(t *testing.T) {
+	spans := tracingtest.Install(t)
+	client := pogov1.NewOCRServiceClient(newGRPCTestClient(t, newGRPCTestServer()))
+	imgData, err := encodeImageToPNG(createTestImage(20, 20))
+	require.NoError(t, err)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "traceparent", testTraceParent)
+	_, err = client.RecognizeImage(ctx, &pogov1.RecognizeImageRequest{Image: imgData})
+	require.NoError(t, err)
+
+	got := spans.GetSpans()
+	server := tracingtest.Find(got, pogov1.OCRService_RecognizeImage_FullMethodName[1:])
+	require.NotNil(t, server, "spans: %v", tracingtest.Names(got))
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+	assert.Equal(t, testTraceID, server.SpanContext.TraceID().String())
+	assert.Equal(t, testParentID, server.Parent.SpanID().String())
+
+	decode := tracingtest.Find(got, "decode")
+	require.NotNil(t, decode)
+	assert.Equal(t, server.SpanContext.SpanID(), decode.Parent.SpanID())
+}
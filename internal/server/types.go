@@ -1,140 +1,50 @@
 package server
 
 import (
+	"context"
 	"fmt"
-	"hash/fnv"
 	"image"
+	"log/slog"
 	"net/http"
-	"strconv"
 	"sync"
+	"time"
 
+	"github.com/MeKo-Tech/pogo/internal/jobs"
 	"github.com/MeKo-Tech/pogo/internal/pipeline"
 )
 
 // pipelineInterface defines the methods needed by the server from a pipeline.
 type pipelineInterface interface {
-	ProcessImage(img image.Image) (*pipeline.OCRImageResult, error)
-	ProcessPDF(filename string, pageRange string) (*pipeline.OCRPDFResult, error)
+	ProcessImageContext(ctx context.Context, img image.Image) (*pipeline.OCRImageResult, error)
+	ProcessPDFContext(ctx context.Context, filename string, pageRange string) (*pipeline.OCRPDFResult, error)
+	ProcessPDFStream(ctx context.Context, filename string, pageRange string,
+		emit func(*pipeline.OCRPDFPageResult) error) (*pipeline.OCRPDFResult, error)
 	Close() error
 }
 
-// PipelineCache caches pipelines by configuration to avoid recreating them.
-type PipelineCache struct {
-	mu        sync.RWMutex
-	pipelines map[string]pipelineInterface
-}
-
-// NewPipelineCache creates a new pipeline cache.
-func NewPipelineCache() *PipelineCache {
-	return &PipelineCache{
-		pipelines: make(map[string]pipelineInterface),
-	}
-}
-
-// GetOrCreate gets a cached pipeline or creates a new one for the given config.
-func (c *PipelineCache) GetOrCreate(config pipeline.Config) (pipelineInterface, error) {
-	// Create a hash of the configuration for caching
-	configHash := c.hashConfig(config)
-
-	c.mu.RLock()
-	if pipeline, exists := c.pipelines[configHash]; exists {
-		c.mu.RUnlock()
-		return pipeline, nil
-	}
-	c.mu.RUnlock()
-
-	// Create new pipeline
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// Double-check in case another goroutine created it
-	if pipeline, exists := c.pipelines[configHash]; exists {
-		return pipeline, nil
-	}
-
-	pipeline, err := c.createPipeline(config)
-	if err != nil {
-		return nil, err
-	}
-
-	c.pipelines[configHash] = pipeline
-	return pipeline, nil
-}
-
-// hashConfig creates a hash of the pipeline configuration for caching.
-func (c *PipelineCache) hashConfig(config pipeline.Config) string {
-	// Create a string representation of key configuration fields
-	key := fmt.Sprintf("%s|%s|%s|%s|%s",
-		config.ModelsDir,
-		config.Detector.ModelPath,
-		config.Recognizer.ModelPath,
-		fmt.Sprintf("%v", config.Recognizer.DictPaths),
-		config.Recognizer.Language,
-	)
-
-	h := fnv.New64a()
-	_, err := h.Write([]byte(key))
-	if err != nil {
-		// FNV hash write should never fail, but handle it gracefully
-		return fmt.Sprintf("%x", 0)
-	}
-	return strconv.FormatUint(h.Sum64(), 16)
-}
-
-// createPipeline creates a new pipeline with the given configuration.
-func (c *PipelineCache) createPipeline(config pipeline.Config) (pipelineInterface, error) {
-	builder := pipeline.NewBuilder().
-		WithModelsDir(config.ModelsDir).
-		WithDetectorModelPath(config.Detector.ModelPath).
-		WithRecognizerModelPath(config.Recognizer.ModelPath).
-		WithLanguage(config.Recognizer.Language)
-
-	// Set dictionary paths if specified
-	if len(config.Recognizer.DictPaths) > 0 {
-		builder = builder.WithDictionaryPaths(config.Recognizer.DictPaths)
-	} else if config.Recognizer.DictPath != "" {
-		builder = builder.WithDictionaryPath(config.Recognizer.DictPath)
-	}
-
-	// Apply other configuration options
-	builder = builder.WithThreads(config.Detector.NumThreads)
-	builder = builder.WithDetectorThresholds(config.Detector.DbThresh, config.Detector.DbBoxThresh)
-	if config.Detector.UseNMS {
-		builder = builder.WithDetectorNMS(true, config.Detector.NMSThreshold)
-	}
-	// Multi-scale detection
-	if config.Detector.MultiScale.Enabled {
-		builder = builder.WithDetectorMultiScale(config.Detector.MultiScale.Scales)
-		if config.Detector.MultiScale.MergeIoU > 0 {
-			builder = builder.WithDetectorMultiScaleIoU(config.Detector.MultiScale.MergeIoU)
-		}
-	}
-	builder = builder.WithImageHeight(config.Recognizer.ImageHeight)
-	builder = builder.WithRecognizeWidthPadding(config.Recognizer.MaxWidth, config.Recognizer.PadWidthMultiple)
-
-	return builder.Build()
-}
-
-// Close closes all cached pipelines.
-func (c *PipelineCache) Close() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	var firstErr error
-	for _, pipeline := range c.pipelines {
-		if err := pipeline.Close(); err != nil && firstErr == nil {
-			firstErr = err
-		}
-	}
-	c.pipelines = nil
-	return firstErr
-}
-
 // Server holds the HTTP server state and dependencies.
 type Server struct {
+	// pipeline is the default pipeline, a *servedPipeline unless set
+	// directly. It is replaced on reload; use defaultPipeline. pipelineMu
+	// guards it, baseConfig and reload.
 	pipeline         pipelineInterface
+	pipelineMu       sync.RWMutex
+	reload           ReloadStatus
+	// reloadMu makes reloads run one at a time.
+	reloadMu sync.Mutex
+	// loadPipelineConfig re-reads the configuration on reload, nil if
+	// reloading is disabled.
+	loadPipelineConfig func() (pipeline.Config, error)
+	// newPipeline builds the default pipeline on reload
+	// (nil = buildDefaultPipeline); replaced in tests.
+	newPipeline func(pipeline.Config) (pipelineInterface, error)
+	// probes tracks startup and the self-test, nil if the pipeline was set
+	// directly.
+	probes *probeState
 	corsOrigin       string
 	maxUploadMB      int64
+	// maxBatchItems limits the items of a batch request (0 = DefaultMaxBatchItems).
+	maxBatchItems    int
 	timeoutSec       int
 	overlayEnabled   bool
 	overlayBoxColor  string
@@ -144,6 +54,28 @@ type Server struct {
     baseConfig       pipeline.Config // Base configuration for creating custom pipelines
     barcodeDPI       int
     pdfWorkers       int
+	// processingTimeout bounds the OCR work of a request (0 = no limit).
+	processingTimeout time.Duration
+	// jobs is the asynchronous job queue, nil if disabled.
+	jobs *jobs.Queue
+	// webhooks notifies job callback URLs, nil if jobs are disabled.
+	webhooks *webhookNotifier
+	// apiKeys authenticates clients, nil if authentication is disabled.
+	apiKeys *APIKeys
+	// profiles are the model profiles requests select by name, nil if none.
+	profiles *ModelProfiles
+	// allowModelPaths lets requests name model and dictionary files.
+	allowModelPaths bool
+	// admission bounds concurrent OCR requests, nil if disabled.
+	admission *admissionController
+	// resources reports memory pressure to admission, nil without a
+	// memory limit.
+	resources *pipeline.ResourceManager
+	// fetcher downloads inputs referenced by URL, nil if disabled.
+	fetcher *remoteFetcher
+	// sseHeartbeat is the interval of event stream heartbeats
+	// (0 = DefaultSSEHeartbeat).
+	sseHeartbeat time.Duration
 }
 
 // Config holds server configuration.
@@ -152,6 +84,10 @@ type Config struct {
 	Port             int
 	CORSOrigin       string
 	MaxUploadMB      int64
+	// MaxBatchItems limits the images and PDFs of a batch request
+	// (0 = DefaultMaxBatchItems). Large batches are best streamed as NDJSON,
+	// which does not hold all results in memory.
+	MaxBatchItems    int
 	TimeoutSec       int
 	PipelineConfig   pipeline.Config
 	OverlayEnabled   bool
@@ -162,6 +98,44 @@ type Config struct {
     BarcodeDPI       int
     // Default PDF page processing workers (0=NumCPU)
     PDFWorkers       int
+	// ProcessingTimeout bounds the OCR work of a request; when it expires the
+	// finished part is returned marked as truncated. 0 = 90% of TimeoutSec,
+	// which leaves time to send the partial result.
+	ProcessingTimeout time.Duration
+	// Jobs configures the asynchronous job queue; it is enabled when
+	// Jobs.Dir is set.
+	Jobs jobs.Config
+	// Webhooks configures the callbacks of jobs that set a callback_url.
+	Webhooks WebhookConfig
+	// APIKeys, if set, requires clients of the OCR and job endpoints to
+	// authenticate with one of the keys.
+	APIKeys *APIKeys
+	// ModelProfiles are the model setups requests may select by name.
+	ModelProfiles *ModelProfiles
+	// Admission bounds the OCR requests processed at once and sheds load
+	// beyond it.
+	Admission AdmissionConfig
+	// PipelineCache bounds the pipelines built for requests with their own
+	// models or options.
+	PipelineCache PipelineCacheConfig
+	// Fetch lets requests reference their input by URL and sets the
+	// policy for fetching it.
+	Fetch FetchConfig
+	// AllowModelPaths lets requests pass model and dictionary file paths
+	// (det-model, rec-model, dict) instead of selecting a profile. Off by
+	// default, as it exposes the server's file system to clients.
+	AllowModelPaths bool
+	// Probes configures warmup, the self-test and the probe endpoints.
+	Probes ProbeConfig
+	// ReloadPipelineConfig returns the pipeline configuration re-read from
+	// its sources. If set, Reload and POST /admin/reload replace the
+	// default pipeline with one built from it, without dropping requests.
+	ReloadPipelineConfig func() (pipeline.Config, error)
+	// StartInBackground makes NewServer return before the default pipeline
+	// is built and warmed up, so that the probe endpoints can be served
+	// while models load. OCR requests are rejected with 503 until then, and
+	// a failed startup is reported by WaitStarted instead of NewServer.
+	StartInBackground bool
 }
 
 // RateLimitConfig holds rate limiting configuration.
@@ -171,6 +145,11 @@ type RateLimitConfig struct {
 	RequestsPerHour   int
 	MaxRequestsPerDay int
 	MaxDataPerDay     int64 // in bytes
+	// StatePath, if set, is a file usage is saved to every SnapshotInterval
+	// and on shutdown, and restored from on startup, so that daily quotas
+	// survive restarts. Usage is kept in memory only if empty.
+	StatePath        string
+	SnapshotInterval time.Duration
 }
 
 // Response types for API endpoints.
@@ -191,6 +170,16 @@ type ModelInfo struct {
 type ModelsResponse struct {
 	Models []ModelInfo `json:"models"`
 	Count  int         `json:"count"`
+	// Profiles lists the model profiles requests can select.
+	Profiles []ProfileInfo `json:"profiles"`
+}
+
+// ProfileInfo describes a model profile without revealing its file paths.
+type ProfileInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Language    string   `json:"language,omitempty"`
+	DictLangs   []string `json:"dict_langs,omitempty"`
 }
 
 type DetectionBox struct {
@@ -222,8 +211,105 @@ type OCRResponse struct {
 
 // NewServer creates a new OCR server instance.
 func NewServer(config Config) (*Server, error) {
-	// Build pipeline from provided config
-	cfg := config.PipelineConfig
+	var err error
+	// Initialize rate limiter if enabled. API keys may set their own limits
+	// even without server-wide ones.
+	var rateLimiter *RateLimiter
+	if config.RateLimit.Enabled || config.APIKeys != nil {
+		var limits RateLimits
+		if config.RateLimit.Enabled {
+			limits = RateLimits{
+				RequestsPerMinute: config.RateLimit.RequestsPerMinute,
+				RequestsPerHour:   config.RateLimit.RequestsPerHour,
+				MaxRequestsPerDay: config.RateLimit.MaxRequestsPerDay,
+				MaxDataPerDay:     config.RateLimit.MaxDataPerDay,
+			}
+		}
+		var store UsageStore = NewMemoryUsageStore(DefaultUsageSweepInterval)
+		if config.RateLimit.StatePath != "" {
+			store, err = OpenFileUsageStore(config.RateLimit.StatePath, config.RateLimit.SnapshotInterval)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open rate limit state: %w", err)
+			}
+		}
+		rateLimiter = NewRateLimiterWithStore(limits, store)
+	}
+
+    s := &Server{
+		corsOrigin:       config.CORSOrigin,
+		maxUploadMB:      config.MaxUploadMB,
+		maxBatchItems:    config.MaxBatchItems,
+		timeoutSec:       config.TimeoutSec,
+		overlayEnabled:   config.OverlayEnabled,
+		overlayBoxColor:  config.OverlayBoxColor,
+		overlayPolyColor: config.OverlayPolyColor,
+		rateLimiter:      rateLimiter,
+		pipelineCache:    NewPipelineCache(config.PipelineCache),
+        baseConfig:       config.PipelineConfig,
+        barcodeDPI:       config.BarcodeDPI,
+        pdfWorkers:       config.PDFWorkers,
+		processingTimeout: processingTimeout(config),
+		apiKeys:           config.APIKeys,
+		profiles:          config.ModelProfiles,
+		allowModelPaths:   config.AllowModelPaths,
+		probes:            newProbeState(config.Probes),
+		loadPipelineConfig: config.ReloadPipelineConfig,
+	}
+
+	if config.Fetch.Enabled {
+		s.fetcher = newRemoteFetcher(config.Fetch, config.MaxUploadMB*1024*1024)
+	}
+
+	if config.Admission.MaxConcurrent > 0 || config.Admission.MemoryLimitBytes > 0 {
+		if config.Admission.MemoryLimitBytes > 0 {
+			s.resources = pipeline.NewResourceManager(pipeline.ResourceConfig{
+				MaxMemoryBytes:  config.Admission.MemoryLimitBytes,
+				MemoryThreshold: pipeline.DefaultResourceConfig().MemoryThreshold,
+				MonitorInterval: pressureCheckInterval,
+			})
+			s.resources.Start()
+		}
+		s.admission = newAdmissionController(config.Admission, s.resources)
+	}
+
+	if config.Jobs.Dir != "" {
+		s.webhooks = newWebhookNotifier(config.Webhooks)
+		jobsConfig := config.Jobs
+		jobsConfig.OnFinish = s.webhooks.jobFinished
+		s.jobs, err = jobs.Open(jobsConfig, s.runJob)
+		if err != nil {
+			s.webhooks.Close()
+			if s.resources != nil {
+				s.resources.Stop()
+			}
+			if rateLimiter != nil {
+				_ = rateLimiter.Close()
+			}
+			return nil, fmt.Errorf("failed to open job queue: %w", err)
+		}
+	}
+
+	build := func() (pipelineInterface, error) { return buildDefaultPipeline(config.PipelineConfig) }
+	if config.StartInBackground {
+		go func() {
+			if err := s.start(build); err != nil {
+				slog.Error("Failed to initialize OCR pipeline", "error", err)
+				return
+			}
+			slog.Info("OCR pipeline ready")
+		}()
+		return s, nil
+	}
+	if err := s.start(build); err != nil {
+		_ = s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// buildDefaultPipeline builds the pipeline of requests that select no
+// models or options of their own.
+func buildDefaultPipeline(cfg pipeline.Config) (*pipeline.Pipeline, error) {
 	cfg.Detector.UpdateModelPath(cfg.ModelsDir)
 	cfg.Recognizer.UpdateModelPath(cfg.ModelsDir)
 
@@ -242,6 +328,7 @@ func NewServer(config Config) (*Server, error) {
 	}
 	nb = nb.WithImageHeight(cfg.Recognizer.ImageHeight)
 	nb = nb.WithRecognizeWidthPadding(cfg.Recognizer.MaxWidth, cfg.Recognizer.PadWidthMultiple)
+	nb = nb.WithPDFMaxInFlightPages(cfg.PDF.MaxInFlightPages)
 	if cfg.Detector.ModelPath != "" {
 		nb = nb.WithDetectorModelPath(cfg.Detector.ModelPath)
 	}
@@ -251,45 +338,71 @@ func NewServer(config Config) (*Server, error) {
 	if cfg.Recognizer.DictPath != "" {
 		nb = nb.WithDictionaryPath(cfg.Recognizer.DictPath)
 	}
+	return nb.Build()
+}
 
-	pl, err := nb.Build()
-	if err != nil {
-		return nil, err
-	}
+// defaultPipeline returns the default pipeline, nil before it is built.
+func (s *Server) defaultPipeline() pipelineInterface {
+	s.pipelineMu.RLock()
+	defer s.pipelineMu.RUnlock()
+	return s.pipeline
+}
 
-	// Initialize rate limiter if enabled
-	var rateLimiter *RateLimiter
-	if config.RateLimit.Enabled {
-		rateLimiter = NewRateLimiter(
-			config.RateLimit.RequestsPerMinute,
-			config.RateLimit.RequestsPerHour,
-			config.RateLimit.MaxRequestsPerDay,
-			config.RateLimit.MaxDataPerDay,
-		)
+// defaultPipelineConfig returns the default pipeline and the configuration
+// it was built from.
+func (s *Server) defaultPipelineConfig() (pipelineInterface, pipeline.Config) {
+	s.pipelineMu.RLock()
+	defer s.pipelineMu.RUnlock()
+	return s.pipeline, s.baseConfig
+}
+
+// setPipeline makes pl, built from config, the next generation of the
+// default pipeline and returns the previous one, nil if none.
+func (s *Server) setPipeline(pl pipelineInterface, config pipeline.Config) pipelineInterface {
+	s.pipelineMu.Lock()
+	defer s.pipelineMu.Unlock()
+	generation := 1
+	if sp, ok := s.pipeline.(*servedPipeline); ok {
+		generation = sp.generation + 1
 	}
+	old := s.pipeline
+	s.pipeline = &servedPipeline{s: s, pipeline: pl, generation: generation, loadedAt: time.Now()}
+	s.baseConfig = config
+	return old
+}
 
-    return &Server{
-        pipeline:         pl,
-		corsOrigin:       config.CORSOrigin,
-		maxUploadMB:      config.MaxUploadMB,
-		timeoutSec:       config.TimeoutSec,
-		overlayEnabled:   config.OverlayEnabled,
-		overlayBoxColor:  config.OverlayBoxColor,
-		overlayPolyColor: config.OverlayPolyColor,
-		rateLimiter:      rateLimiter,
-		pipelineCache:    NewPipelineCache(),
-        baseConfig:       config.PipelineConfig,
-        barcodeDPI:       config.BarcodeDPI,
-        pdfWorkers:       config.PDFWorkers,
-    }, nil
+// WaitStarted waits until the default pipeline is built and warmed up and
+// returns the error if that failed.
+func (s *Server) WaitStarted(ctx context.Context) error {
+	return s.probes.waitStarted(ctx)
 }
 
 // Close releases server resources.
 func (s *Server) Close() error {
 	var firstErr error
 
-	if s.pipeline != nil {
-		if err := s.pipeline.Close(); err != nil && firstErr == nil {
+	// Stop running jobs first; they use the pipelines closed below.
+	if s.jobs != nil {
+		if err := s.jobs.Close(); err != nil {
+			firstErr = err
+		}
+	}
+	if s.webhooks != nil {
+		s.webhooks.Close()
+	}
+	if s.rateLimiter != nil {
+		if err := s.rateLimiter.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if s.resources != nil {
+		s.resources.Stop()
+	}
+
+	// Wait for a background startup, so that its pipeline is closed too.
+	s.probes.close()
+	if pl := s.defaultPipeline(); pl != nil {
+		if err := pl.Close(); err != nil && firstErr == nil {
 			firstErr = err
 		}
 	}
@@ -306,10 +419,26 @@ func (s *Server) Close() error {
 // SetupRoutes configures the HTTP routes.
 func (s *Server) SetupRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/health", s.corsMiddleware(s.healthHandler))
+	mux.HandleFunc("/livez", s.corsMiddleware(s.probeHandler("alive", "dead", s.livenessChecks)))
+	mux.HandleFunc("/readyz", s.corsMiddleware(s.probeHandler("ready", "not_ready", s.readinessChecks)))
+	mux.HandleFunc("/startupz", s.corsMiddleware(s.probeHandler("started", "not_started", s.startupChecks)))
 	mux.HandleFunc("/models", s.corsMiddleware(s.modelsHandler))
 	mux.HandleFunc("/metrics", s.corsMiddleware(s.metricsHandler))
-	mux.HandleFunc("/ws/ocr", s.corsMiddleware(s.ocrWebSocketHandler))
-	mux.HandleFunc("/ocr/batch", s.corsMiddleware(s.rateLimitMiddleware(s.ocrBatchHandler)))
-	mux.HandleFunc("/ocr/image", s.corsMiddleware(s.rateLimitMiddleware(s.ocrImageHandler)))
-	mux.HandleFunc("/ocr/pdf", s.corsMiddleware(s.rateLimitMiddleware(s.ocrPdfHandler)))
+	mux.HandleFunc("/usage", s.corsMiddleware(s.authMiddleware("", s.usageHandler)))
+	// WebSocket messages and jobs are checked against the scope of their type
+	mux.HandleFunc("/ws/ocr", s.tracingMiddleware("/ws/ocr",
+		s.corsMiddleware(s.authMiddleware("", s.rateLimitMiddleware(s.ocrWebSocketHandler)))))
+	mux.HandleFunc("/ocr/batch", s.tracingMiddleware("/ocr/batch", s.corsMiddleware(s.authMiddleware(ScopeBatch,
+		s.rateLimitMiddleware(s.admissionMiddleware(LaneBulk, s.ocrBatchHandler))))))
+	mux.HandleFunc("/ocr/image", s.tracingMiddleware("/ocr/image", s.corsMiddleware(s.authMiddleware(ScopeImage,
+		s.rateLimitMiddleware(s.admissionMiddleware(LaneInteractive, s.ocrImageHandler))))))
+	mux.HandleFunc("/ocr/pdf", s.tracingMiddleware("/ocr/pdf", s.corsMiddleware(s.authMiddleware(ScopePDF,
+		s.rateLimitMiddleware(s.admissionMiddleware(LaneBulk, s.ocrPdfHandler))))))
+	mux.HandleFunc("/jobs", s.tracingMiddleware("/jobs",
+		s.corsMiddleware(s.authMiddleware("", s.rateLimitMiddleware(s.jobsHandler)))))
+	mux.HandleFunc("/jobs/{id}", s.corsMiddleware(s.authMiddleware("", s.jobHandler)))
+	mux.HandleFunc("/jobs/{id}/result", s.corsMiddleware(s.authMiddleware("", s.jobResultHandler)))
+	mux.HandleFunc("/admin/pipelines", s.corsMiddleware(s.authMiddleware(ScopeAdmin, s.adminPipelinesHandler)))
+	mux.HandleFunc("/admin/pipelines/{id}", s.corsMiddleware(s.authMiddleware(ScopeAdmin, s.adminPipelineHandler)))
+	mux.HandleFunc("/admin/reload", s.corsMiddleware(s.authMiddleware(ScopeAdmin, s.adminReloadHandler)))
 }
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+)
+
+// APIKeyUsage reports the limits and current usage of an API key.
+type APIKeyUsage struct {
+	Key                string   `json:"key"`
+	Scopes             []string `json:"scopes"`
+	RequestsPerMinute  int      `json:"requests_per_minute,omitempty"`
+	RequestsPerHour    int      `json:"requests_per_hour,omitempty"`
+	MaxRequestsPerDay  int      `json:"max_requests_per_day,omitempty"`
+	MaxDataPerDay      int64    `json:"max_data_per_day,omitempty"`
+	RequestsLastMinute int      `json:"requests_last_minute"`
+	RequestsLastHour   int      `json:"requests_last_hour"`
+	RequestsToday      int      `json:"requests_today"`
+	DataToday          int64    `json:"data_today"`
+}
+
+// UsageResponse is the response of the usage endpoint.
+type UsageResponse struct {
+	Keys []APIKeyUsage `json:"keys"`
+}
+
+// usageHandler reports the usage of the caller's API key, or of all keys for
+// admins.
+func (s *Server) usageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.apiKeys == nil {
+		s.writeErrorResponse(w, "API keys not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	caller := apiKeyFromContext(r.Context())
+	keys := []*APIKey{caller}
+	if caller.HasScope(ScopeAdmin) {
+		keys = keys[:0]
+		for _, key := range s.apiKeys.byHash {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	}
+
+	var defaults RateLimits
+	if s.rateLimiter != nil {
+		defaults = s.rateLimiter.Limits()
+	}
+	resp := UsageResponse{Keys: make([]APIKeyUsage, 0, len(keys))}
+	for _, key := range keys {
+		limits := key.limits(defaults)
+		u := APIKeyUsage{
+			Key:               key.Name,
+			Scopes:            key.Scopes,
+			RequestsPerMinute: limits.RequestsPerMinute,
+			RequestsPerHour:   limits.RequestsPerHour,
+			MaxRequestsPerDay: limits.MaxRequestsPerDay,
+			MaxDataPerDay:     limits.MaxDataPerDay,
+		}
+		if s.rateLimiter != nil {
+			usage := s.rateLimiter.GetUsage("key:" + key.Name)
+			// Report expired windows as reset, as the next request would see them
+			s.rateLimiter.resetCountersIfNeeded(usage, time.Now())
+			u.RequestsLastMinute = usage.requestsLastMinute
+			u.RequestsLastHour = usage.requestsLastHour
+			u.RequestsToday = usage.requestsToday
+			u.DataToday = usage.dataToday
+		}
+		resp.Keys = append(resp.Keys, u)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding usage response: %v\n", err)
+	}
+}
@@ -0,0 +1,277 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Usage store defaults.
+const (
+	// DefaultUsageSweepInterval is how often expired usage is evicted.
+	DefaultUsageSweepInterval = time.Minute
+	// DefaultUsageSnapshotInterval is how often a file store saves usage.
+	DefaultUsageSnapshotInterval = 30 * time.Second
+)
+
+// usageSnapshotVersion is the format version of usage snapshot files.
+const usageSnapshotVersion = 1
+
+// UsageStore holds the usage of rate-limited clients. The rate limiter
+// serializes updates; stores must allow concurrent reads.
+type UsageStore interface {
+	// Get returns a copy of the usage of userID, if any.
+	Get(userID string) (UserUsage, bool)
+	// Put stores the usage of userID.
+	Put(userID string, usage UserUsage)
+	// Close releases the store.
+	Close() error
+}
+
+// MemoryUsageStore keeps usage in memory. Entries are evicted once idle long
+// enough that all their counters have expired, which bounds the store by the
+// number of clients seen in a day.
+type MemoryUsageStore struct {
+	mu            sync.RWMutex
+	users         map[string]UserUsage
+	sweepInterval time.Duration
+	lastSweep     time.Time
+
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+// NewMemoryUsageStore creates an in-memory store that evicts expired entries
+// at most every sweepInterval (never if 0).
+func NewMemoryUsageStore(sweepInterval time.Duration) *MemoryUsageStore {
+	return &MemoryUsageStore{
+		users:         make(map[string]UserUsage),
+		sweepInterval: sweepInterval,
+		lastSweep:     time.Now(),
+		now:           time.Now,
+	}
+}
+
+// Get returns a copy of the usage of userID, if any.
+func (m *MemoryUsageStore) Get(userID string) (UserUsage, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	usage, ok := m.users[userID]
+	return usage, ok
+}
+
+// Put stores the usage of userID. Expired entries are swept along the way,
+// so that no background goroutine is needed.
+func (m *MemoryUsageStore) Put(userID string, usage UserUsage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users[userID] = usage
+
+	now := m.now()
+	if m.sweepInterval > 0 && now.Sub(m.lastSweep) >= m.sweepInterval {
+		m.sweepLocked(now)
+	}
+}
+
+// Len returns the number of tracked clients.
+func (m *MemoryUsageStore) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.users)
+}
+
+// Sweep evicts expired entries.
+func (m *MemoryUsageStore) Sweep() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweepLocked(m.now())
+}
+
+func (m *MemoryUsageStore) sweepLocked(now time.Time) {
+	for id, usage := range m.users {
+		if usage.expired(now) {
+			delete(m.users, id)
+		}
+	}
+	m.lastSweep = now
+}
+
+// Close is a no-op; memory stores hold no resources.
+func (m *MemoryUsageStore) Close() error {
+	return nil
+}
+
+// usageRecord is the stored form of a UserUsage.
+type usageRecord struct {
+	RequestsLastMinute int       `json:"requests_last_minute"`
+	RequestsLastHour   int       `json:"requests_last_hour"`
+	RequestsToday      int       `json:"requests_today"`
+	DataToday          int64     `json:"data_today"`
+	LastRequest        time.Time `json:"last_request"`
+	DayStart           time.Time `json:"day_start"`
+}
+
+// usageSnapshot is the content of a usage snapshot file.
+type usageSnapshot struct {
+	Version int                    `json:"version"`
+	SavedAt time.Time              `json:"saved_at"`
+	Users   map[string]usageRecord `json:"users"`
+}
+
+// FileUsageStore is a memory store that is restored from a snapshot file on
+// open and saved to it periodically and on Close, so that quotas survive
+// restarts. Usage of the last snapshot interval is lost on a crash.
+type FileUsageStore struct {
+	*MemoryUsageStore
+	path string
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	// saveMu serializes snapshot writes.
+	saveMu sync.Mutex
+}
+
+// OpenFileUsageStore opens a store backed by the snapshot file at path,
+// which is created on the first save, and saves it every interval
+// (DefaultUsageSnapshotInterval if 0).
+func OpenFileUsageStore(path string, interval time.Duration) (*FileUsageStore, error) {
+	if interval <= 0 {
+		interval = DefaultUsageSnapshotInterval
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create usage state directory: %w", err)
+	}
+
+	f := &FileUsageStore{
+		MemoryUsageStore: NewMemoryUsageStore(DefaultUsageSweepInterval),
+		path:             path,
+		stop:             make(chan struct{}),
+		done:             make(chan struct{}),
+	}
+	if err := f.restore(); err != nil {
+		return nil, err
+	}
+	// Fail early rather than losing usage at the first snapshot.
+	if err := f.Save(); err != nil {
+		return nil, err
+	}
+
+	go f.run(interval)
+	return f, nil
+}
+
+// restore loads the snapshot file, skipping entries that expired while the
+// server was down.
+func (f *FileUsageStore) restore() error {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read usage state: %w", err)
+	}
+	var snap usageSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("failed to parse usage state %s: %w", f.path, err)
+	}
+	if snap.Version != usageSnapshotVersion {
+		return fmt.Errorf("unsupported usage state version %d in %s", snap.Version, f.path)
+	}
+
+	now := f.now()
+	for id, rec := range snap.Users {
+		usage := UserUsage{
+			requestsLastMinute: rec.RequestsLastMinute,
+			requestsLastHour:   rec.RequestsLastHour,
+			requestsToday:      rec.RequestsToday,
+			dataToday:          rec.DataToday,
+			lastRequestTime:    rec.LastRequest,
+			dayStartTime:       rec.DayStart,
+		}
+		if !usage.expired(now) {
+			f.users[id] = usage
+		}
+	}
+	slog.Info("Restored rate limit usage", "path", f.path, "clients", len(f.users), "saved_at", snap.SavedAt)
+	return nil
+}
+
+func (f *FileUsageStore) run(interval time.Duration) {
+	defer close(f.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := f.Save(); err != nil {
+				slog.Error("Failed to save rate limit usage", "path", f.path, "error", err)
+			}
+		case <-f.stop:
+			return
+		}
+	}
+}
+
+// Save writes a snapshot of the current usage, replacing the file atomically.
+func (f *FileUsageStore) Save() error {
+	f.saveMu.Lock()
+	defer f.saveMu.Unlock()
+
+	f.Sweep()
+	snap := usageSnapshot{Version: usageSnapshotVersion, SavedAt: f.now().UTC()}
+	f.mu.RLock()
+	snap.Users = make(map[string]usageRecord, len(f.users))
+	for id, u := range f.users {
+		snap.Users[id] = usageRecord{
+			RequestsLastMinute: u.requestsLastMinute,
+			RequestsLastHour:   u.requestsLastHour,
+			RequestsToday:      u.requestsToday,
+			DataToday:          u.dataToday,
+			LastRequest:        u.lastRequestTime,
+			DayStart:           u.dayStartTime,
+		}
+	}
+	f.mu.RUnlock()
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("failed to encode usage state: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), ".usage-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save usage state: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to save usage state: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to save usage state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save usage state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to save usage state: %w", err)
+	}
+	return nil
+}
+
+// Close stops periodic snapshots and saves a final one.
+func (f *FileUsageStore) Close() error {
+	var err error
+	f.closeOnce.Do(func() {
+		close(f.stop)
+		<-f.done
+		err = f.Save()
+	})
+	return err
+}
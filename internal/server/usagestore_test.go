@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryUsageStore_EvictsExpiredUsage(t *testing.T) {
+	now := time.Date(2026, 3, 10, 23, 30, 0, 0, time.UTC)
+	m := NewMemoryUsageStore(time.Minute)
+	m.now = func() time.Time { return now }
+	m.lastSweep = now
+
+	m.Put("idle", UserUsage{requestsToday: 3, lastRequestTime: now.Add(-2 * time.Hour), dayStartTime: now.Add(-2 * time.Hour)})
+	m.Put("active", UserUsage{requestsToday: 1, lastRequestTime: now, dayStartTime: now})
+	require.Equal(t, 2, m.Len())
+
+	// The next day the idle client has nothing left to remember, while the
+	// active one is still within its hourly window.
+	now = now.Add(45 * time.Minute)
+	m.Put("new", UserUsage{lastRequestTime: now, dayStartTime: now})
+	_, ok := m.Get("idle")
+	assert.False(t, ok, "expired usage is evicted")
+	assert.Equal(t, 2, m.Len())
+
+	// Idle clients keep their daily quota until the day is over.
+	m.Put("active", UserUsage{requestsToday: 1, lastRequestTime: now.Add(-2 * time.Hour), dayStartTime: now})
+	m.Sweep()
+	_, ok = m.Get("active")
+	assert.True(t, ok)
+}
+
+func TestFileUsageStore_SurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "usage.json")
+	store, err := OpenFileUsageStore(path, time.Hour)
+	require.NoError(t, err)
+	assert.FileExists(t, path, "the state file is created on open")
+
+	rl := NewRateLimiterWithStore(RateLimits{MaxRequestsPerDay: 2, MaxDataPerDay: 1000}, store)
+	require.NoError(t, rl.CheckRateLimit("key:alice", 400))
+	require.NoError(t, rl.CheckRateLimit("key:alice", 400))
+	require.NoError(t, rl.Close())
+	require.NoError(t, rl.Close(), "closing twice is harmless")
+
+	store, err = OpenFileUsageStore(path, time.Hour)
+	require.NoError(t, err)
+	rl = NewRateLimiterWithStore(RateLimits{MaxRequestsPerDay: 2, MaxDataPerDay: 1000}, store)
+	defer func() { _ = rl.Close() }()
+
+	usage := rl.GetUsage("key:alice")
+	assert.Equal(t, 2, usage.requestsToday)
+	assert.Equal(t, int64(800), usage.dataToday)
+
+	var quotaErr *QuotaExceededError
+	require.ErrorAs(t, rl.CheckRateLimit("key:alice", 0), &quotaErr, "the daily quota is not reset by a restart")
+	assert.Equal(t, "requests", quotaErr.Type)
+}
+
+func TestFileUsageStore_Restore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "usage.json")
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1)
+	snap := usageSnapshot{Version: usageSnapshotVersion, Users: map[string]usageRecord{
+		"fresh":   {RequestsToday: 5, LastRequest: now, DayStart: now},
+		"expired": {RequestsToday: 9, LastRequest: yesterday, DayStart: yesterday},
+	}}
+	data, err := json.Marshal(snap)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	store, err := OpenFileUsageStore(path, time.Hour)
+	require.NoError(t, err)
+	usage, ok := store.Get("fresh")
+	require.True(t, ok)
+	assert.Equal(t, 5, usage.requestsToday)
+	_, ok = store.Get("expired")
+	assert.False(t, ok, "usage that expired while stopped is dropped")
+	require.NoError(t, store.Close())
+
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0o600))
+	_, err = OpenFileUsageStore(path, time.Hour)
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"version":99}`), 0o600))
+	_, err = OpenFileUsageStore(path, time.Hour)
+	require.ErrorContains(t, err, "version")
+}
@@ -0,0 +1,300 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MeKo-Tech/pogo/internal/jobs"
+)
+
+// Webhook defaults for zero WebhookConfig fields.
+const (
+	defaultWebhookAttempts = 5
+	defaultWebhookBackoff  = time.Second
+	defaultWebhookTimeout  = 10 * time.Second
+	maxWebhookBackoff      = 5 * time.Minute
+	// maxInlineWebhookResult is the largest job result embedded in a
+	// notification; larger results are only linked.
+	maxInlineWebhookResult = 1 << 20
+)
+
+// Webhook request headers.
+const (
+	webhookSignatureHeader = "X-Pogo-Signature"
+	webhookEventHeader     = "X-Pogo-Event"
+	webhookDeliveryHeader  = "X-Pogo-Delivery"
+)
+
+// WebhookConfig configures job completion callbacks.
+type WebhookConfig struct {
+	// Secret signs notification bodies with HMAC-SHA256; unsigned if empty.
+	Secret string
+	// PublicURL is the externally visible base URL of the server, used to
+	// build absolute result links. Links are relative paths if empty.
+	PublicURL string
+	// MaxAttempts is the number of delivery attempts before a notification
+	// is written to the dead-letter log.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry; it doubles with
+	// every further attempt.
+	InitialBackoff time.Duration
+	// Timeout bounds a single delivery attempt.
+	Timeout time.Duration
+	// DeadLetterPath is the file undeliverable notifications are appended
+	// to as JSON lines. They are only logged if empty.
+	DeadLetterPath string
+	// AllowedHosts, DeniedHosts and AllowedNetworks restrict callback URLs
+	// like the FetchConfig fields of the same name: by default callbacks
+	// only go to public addresses, checked for every connection and
+	// redirect.
+	AllowedHosts    []string
+	DeniedHosts     []string
+	AllowedNetworks []netip.Prefix
+}
+
+// JobNotification is the body POSTed to a job's callback URL.
+type JobNotification struct {
+	// Event is "job.succeeded", "job.failed" or "job.canceled".
+	Event string   `json:"event"`
+	Job   jobs.Job `json:"job"`
+	// ResultURL links to the result of a succeeded job.
+	ResultURL string `json:"result_url,omitempty"`
+	// Result holds the result of a succeeded job unless it is too large.
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// deadLetter is an entry of the dead-letter log.
+type deadLetter struct {
+	Time     time.Time       `json:"time"`
+	JobID    string          `json:"job_id"`
+	URL      string          `json:"url"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// webhookNotifier delivers job notifications in the background.
+type webhookNotifier struct {
+	cfg WebhookConfig
+	// policy checks callback URLs and sends the notifications with the
+	// address checks and redirect policy of URL inputs.
+	policy *remoteFetcher
+
+	ctx  context.Context
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+	// mu serializes dead-letter writes and orders new deliveries before
+	// Close.
+	mu sync.Mutex
+}
+
+func newWebhookNotifier(cfg WebhookConfig) *webhookNotifier {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultWebhookAttempts
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = defaultWebhookBackoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultWebhookTimeout
+	}
+	policy := newRemoteFetcher(FetchConfig{
+		AllowedHosts:    cfg.AllowedHosts,
+		DeniedHosts:     cfg.DeniedHosts,
+		AllowedNetworks: cfg.AllowedNetworks,
+		Timeout:         cfg.Timeout,
+	}, 0)
+	ctx, stop := context.WithCancel(context.Background())
+	return &webhookNotifier{
+		cfg:    cfg,
+		policy: policy,
+		ctx:    ctx,
+		stop:   stop,
+	}
+}
+
+// validateCallbackURL checks that a callback URL is an absolute HTTP(S) URL
+// the callback policy allows. Host names are checked against the network
+// policy as they are dialed.
+func (n *webhookNotifier) validateCallbackURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid callback_url %q: must be an absolute http or https URL", raw)
+	}
+	if err := n.policy.checkURL(u); err != nil {
+		return fmt.Errorf("invalid callback_url: %w", err)
+	}
+	return nil
+}
+
+// jobFinished is the job queue's OnFinish hook. It notifies the callback URL
+// of the job, if any.
+func (n *webhookNotifier) jobFinished(job jobs.Job, result []byte) {
+	if job.CallbackURL == "" {
+		return
+	}
+	notification := JobNotification{
+		Event: "job." + string(job.Status),
+		Job:   job,
+	}
+	if job.Status == jobs.StatusSucceeded {
+		notification.ResultURL = strings.TrimSuffix(n.cfg.PublicURL, "/") + "/jobs/" + job.ID + "/result"
+		if len(result) <= maxInlineWebhookResult && json.Valid(result) {
+			notification.Result = result
+		}
+	}
+	body, err := json.Marshal(notification)
+	if err != nil {
+		slog.Error("Failed to encode job notification", "job", job.ID, "error", err)
+		return
+	}
+
+	n.mu.Lock()
+	if err := n.ctx.Err(); err != nil {
+		n.mu.Unlock()
+		n.deadLetter(job.ID, job.CallbackURL, 0, fmt.Errorf("delivery aborted: %w", err), body)
+		return
+	}
+	n.wg.Add(1)
+	n.mu.Unlock()
+	go func() {
+		defer n.wg.Done()
+		n.deliver(job.ID, job.CallbackURL, notification.Event, body)
+	}()
+}
+
+// deliver POSTs body to target, retrying with exponential backoff. Notifications
+// that cannot be delivered end up in the dead-letter log.
+func (n *webhookNotifier) deliver(jobID, target, event string, body []byte) {
+	backoff := n.cfg.InitialBackoff
+	var err error
+	attempts := 0
+	for attempts < n.cfg.MaxAttempts {
+		if attempts > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-n.ctx.Done():
+				err = fmt.Errorf("delivery aborted: %w (last error: %w)", n.ctx.Err(), err)
+				n.deadLetter(jobID, target, attempts, err, body)
+				return
+			}
+			backoff = min(2*backoff, maxWebhookBackoff)
+		}
+		attempts++
+
+		var retry bool
+		retry, err = n.attempt(jobID, target, event, body)
+		if err == nil {
+			webhookDeliveriesTotal.WithLabelValues("delivered").Inc()
+			return
+		}
+		slog.Warn("Webhook delivery failed", "job", jobID, "attempt", attempts, "error", err)
+		if !retry {
+			break
+		}
+	}
+	n.deadLetter(jobID, target, attempts, err, body)
+}
+
+// attempt makes a single delivery attempt. It reports whether a failure is
+// worth retrying: client errors other than 408 and 429 are not.
+func (n *webhookNotifier) attempt(jobID, target, event string, body []byte) (bool, error) {
+	start := time.Now()
+	defer func() { webhookAttemptDuration.Observe(time.Since(start).Seconds()) }()
+
+	req, err := http.NewRequestWithContext(n.ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		webhookAttemptsTotal.WithLabelValues("error").Inc()
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, event)
+	req.Header.Set(webhookDeliveryHeader, jobID)
+	if n.cfg.Secret != "" {
+		req.Header.Set(webhookSignatureHeader, signWebhookPayload(n.cfg.Secret, body))
+	}
+
+	resp, err := n.policy.client.Do(req)
+	if err != nil {
+		webhookAttemptsTotal.WithLabelValues("error").Inc()
+		// A blocked address or redirect stays blocked
+		return !errors.Is(err, errFetchBlocked), err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		webhookAttemptsTotal.WithLabelValues("success").Inc()
+		return false, nil
+	}
+	webhookAttemptsTotal.WithLabelValues("error").Inc()
+	err = fmt.Errorf("callback returned %s", resp.Status)
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout ||
+		resp.StatusCode == http.StatusTooManyRequests
+	return retry, err
+}
+
+// signWebhookPayload returns the signature header value for body:
+// "sha256=" followed by the hex HMAC-SHA256 of body keyed with secret.
+func signWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (n *webhookNotifier) deadLetter(jobID, target string, attempts int, err error, body []byte) {
+	webhookDeliveriesTotal.WithLabelValues("dead_lettered").Inc()
+	slog.Error("Webhook notification undeliverable", "job", jobID, "url", target, "attempts", attempts, "error", err)
+	if n.cfg.DeadLetterPath == "" {
+		return
+	}
+
+	line, encErr := json.Marshal(deadLetter{
+		Time:     time.Now().UTC(),
+		JobID:    jobID,
+		URL:      target,
+		Attempts: attempts,
+		Error:    err.Error(),
+		Payload:  body,
+	})
+	if encErr != nil {
+		slog.Error("Failed to encode dead letter", "job", jobID, "error", encErr)
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f, openErr := os.OpenFile(n.cfg.DeadLetterPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if openErr == nil {
+		_, openErr = f.Write(append(line, '\n'))
+		if closeErr := f.Close(); openErr == nil {
+			openErr = closeErr
+		}
+	}
+	if openErr != nil {
+		slog.Error("Failed to write dead letter", "job", jobID, "path", n.cfg.DeadLetterPath, "error", openErr)
+	}
+}
+
+// Close aborts pending deliveries, which are dead-lettered, and waits for
+// them to finish.
+func (n *webhookNotifier) Close() {
+	n.mu.Lock()
+	n.stop()
+	n.mu.Unlock()
+	n.wg.Wait()
+}
@@ -1,16 +1,19 @@
 package server
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"image"
 	"log/slog"
+	"math"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
 	"github.com/gorilla/websocket"
 )
 
@@ -36,8 +39,10 @@ type WebSocketOCRRequest struct {
 	Type     string                 `json:"type"` // "image" or "pdf"
 	Image    []byte                 `json:"image,omitempty"`
 	Filename string                 `json:"filename,omitempty"`
+	URL      string                 `json:"url,omitempty"` // fetched instead of image or filename
 	Pages    string                 `json:"pages,omitempty"`
 	Format   string                 `json:"format,omitempty"`
+	Stream   bool                   `json:"stream,omitempty"` // pdf: send each page as it is done
 	Options  map[string]interface{} `json:"options,omitempty"`
 }
 
@@ -54,7 +59,10 @@ type WebSocketOCRResponse struct {
 	Result    interface{} `json:"result,omitempty"`
 	Error     string      `json:"error,omitempty"`
 	ErrorType string      `json:"error_type,omitempty"`
-	RequestID string      `json:"request_id,omitempty"`
+	// RetryAfter is the number of seconds to wait before sending a
+	// request refused by a limit again.
+	RetryAfter float64 `json:"retry_after,omitempty"`
+	RequestID  string  `json:"request_id,omitempty"`
 }
 
 // ocrWebSocketHandler handles WebSocket connections for real-time OCR.
@@ -76,11 +84,12 @@ func (s *Server) ocrWebSocketHandler(w http.ResponseWriter, r *http.Request) {
 	slog.Info("WebSocket connection established", "remote_addr", r.RemoteAddr)
 
 	// Handle the WebSocket connection
-	s.handleWebSocketConnection(conn)
+	s.handleWebSocketConnection(conn, getClientIP(r), apiKeyFromContext(r.Context()))
 }
 
-// handleWebSocketConnection processes messages from a WebSocket connection.
-func (s *Server) handleWebSocketConnection(conn *websocket.Conn) {
+// handleWebSocketConnection processes messages from a WebSocket connection
+// of clientID, authenticated with key (nil if authentication is disabled).
+func (s *Server) handleWebSocketConnection(conn *websocket.Conn, clientID string, key *APIKey) {
 	// Set read deadline to prevent hanging connections
 	_ = conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 	conn.SetPongHandler(func(string) error {
@@ -113,18 +122,45 @@ func (s *Server) handleWebSocketConnection(conn *websocket.Conn) {
 		websocketMessagesTotal.WithLabelValues("received").Inc()
 
 		if messageType == websocket.TextMessage {
-			s.handleWebSocketMessage(conn, data)
+			s.handleWebSocketMessage(conn, data, clientID, key)
 		}
 	}
 }
 
-// handleWebSocketMessage processes a WebSocket message.
-func (s *Server) handleWebSocketMessage(conn *websocket.Conn, data []byte) {
+// handleWebSocketMessage processes a WebSocket message. Its type ("image" or
+// "pdf") must be within the scopes of the connection's API key, and it counts
+// against the rate limits and quotas of the key or client and goes through
+// admission control like a request.
+func (s *Server) handleWebSocketMessage(conn *websocket.Conn, data []byte, clientID string, key *APIKey) {
 	var req WebSocketOCRRequest
 	if err := json.Unmarshal(data, &req); err != nil {
 		s.sendWebSocketError(conn, "invalid_request", fmt.Sprintf("Failed to parse request: %v", err))
 		return
 	}
+	if req.Type != ScopeImage && req.Type != ScopePDF {
+		s.sendWebSocketError(conn, "invalid_request", "Unsupported request type: "+req.Type)
+		return
+	}
+	if !key.HasScope(req.Type) {
+		apiKeyRejections.WithLabelValues(key.Name, "scope").Inc()
+		s.sendWebSocketError(conn, "forbidden", fmt.Sprintf("API key lacks the %q scope", req.Type))
+		return
+	}
+	if s.rateLimiter != nil {
+		if _, err := s.checkClientLimits(clientID, key, int64(len(data))); err != nil {
+			s.sendWebSocketLimitError(conn, err)
+			return
+		}
+	}
+	admitted, release, err := s.admitWebSocketMessage(req.Type)
+	if err != nil {
+		var ae *admissionError
+		if errors.As(err, &ae) {
+			s.sendWebSocketRetryError(conn, "unavailable", ae.Error(), ae.retryAfter)
+		}
+		return
+	}
+	defer release()
 
 	// Generate a request ID for tracking
 	requestID := strconv.FormatInt(time.Now().UnixNano(), 10)
@@ -137,26 +173,64 @@ func (s *Server) handleWebSocketMessage(conn *websocket.Conn, data []byte) {
 		RequestID: requestID,
 	})
 
+	// Bound the OCR work by the server's processing timeout; inputs fetched
+	// by URL are counted against the client like the message itself
+	if s.rateLimiter != nil {
+		admitted = withClient(context.WithValue(admitted, apiKeyContextKey{}, key), clientID)
+	}
+	ctx, cancel := s.processingContext(admitted)
+	defer cancel()
+
 	// Process the request based on type
 	switch req.Type {
-	case "image":
-		s.processWebSocketImage(conn, req, requestID)
-	case "pdf":
-		s.processWebSocketPDF(conn, req, requestID)
-	default:
-		s.sendWebSocketError(conn, "invalid_request", "Unsupported request type: "+req.Type)
+	case ScopeImage:
+		s.processWebSocketImage(ctx, conn, req, requestID)
+	case ScopePDF:
+		s.processWebSocketPDF(ctx, conn, req, requestID)
+	}
+}
+
+// admitWebSocketMessage admits an OCR message of type typ through admission
+// control, images in the interactive lane and PDFs in the bulk lane. It
+// returns the context for processing the message and the function ending
+// its admission; shed messages fail with an *admissionError.
+func (s *Server) admitWebSocketMessage(typ string) (context.Context, func(), error) {
+	ctx := context.Background()
+	if err := s.probes.checkStarted(); err != nil {
+		return nil, nil, &admissionError{err: err, retryAfter: startupRetryAfter}
 	}
+	if s.admission == nil {
+		return ctx, func() {}, nil
+	}
+	lane := LaneInteractive
+	if typ == ScopePDF {
+		lane = LaneBulk
+	}
+	start := time.Now()
+	release, err := s.admission.admit(ctx, lane)
+	if err != nil {
+		return nil, nil, err
+	}
+	return context.WithValue(ctx, queueWaitContextKey{}, time.Since(start)), release, nil
 }
 
 // processWebSocketImage processes an image OCR request via WebSocket.
-func (s *Server) processWebSocketImage(conn *websocket.Conn, req WebSocketOCRRequest, requestID string) {
+func (s *Server) processWebSocketImage(ctx context.Context, conn *websocket.Conn, req WebSocketOCRRequest, requestID string) {
+	if len(req.Image) == 0 && req.URL != "" {
+		fetched, err := s.fetchInput(ctx, req.URL)
+		if err != nil {
+			s.sendWebSocketFetchError(conn, err)
+			return
+		}
+		req.Image = fetched.Data
+	}
 	if len(req.Image) == 0 {
 		s.sendWebSocketError(conn, "invalid_request", "No image data provided")
 		return
 	}
 
 	// Decode image
-	img, _, err := image.Decode(bytes.NewReader(req.Image))
+	img, err := decodeImage(ctx, req.Image)
 	if err != nil {
 		s.sendWebSocketError(conn, "processing_error", fmt.Sprintf("Failed to decode image: %v", err))
 		return
@@ -182,17 +256,21 @@ func (s *Server) processWebSocketImage(conn *websocket.Conn, req WebSocketOCRReq
 
 	// Process image
 	start := time.Now()
-	res, err := pipeline.ProcessImage(img)
+	res, err := pipeline.ProcessImageContext(ctx, img)
 	duration := time.Since(start)
 
-	if err != nil {
+	if err != nil && (res == nil || !res.Truncated) {
 		ocrRequestsTotal.WithLabelValues("websocket_image", "error").Inc()
 		s.sendWebSocketError(conn, "processing_error", fmt.Sprintf("OCR processing failed: %v", err))
 		return
 	}
 
-	// Record metrics
-	ocrRequestsTotal.WithLabelValues("websocket_image", "success").Inc()
+	// Record metrics; a truncated result completes the request as well
+	if res.Truncated {
+		ocrRequestsTotal.WithLabelValues("websocket_image", "truncated").Inc()
+	} else {
+		ocrRequestsTotal.WithLabelValues("websocket_image", "success").Inc()
+	}
 	ocrProcessingDuration.WithLabelValues("websocket_image").Observe(duration.Seconds())
 
 	var totalTextLength int
@@ -213,7 +291,16 @@ func (s *Server) processWebSocketImage(conn *websocket.Conn, req WebSocketOCRReq
 }
 
 // processWebSocketPDF processes a PDF OCR request via WebSocket.
-func (s *Server) processWebSocketPDF(conn *websocket.Conn, req WebSocketOCRRequest, requestID string) {
+func (s *Server) processWebSocketPDF(ctx context.Context, conn *websocket.Conn, req WebSocketOCRRequest, requestID string) {
+	if req.URL != "" {
+		tempFile, _, err := s.fetchPDFToTempFile(ctx, req.URL)
+		if err != nil {
+			s.sendWebSocketFetchError(conn, err)
+			return
+		}
+		defer func() { _ = os.Remove(tempFile) }()
+		req.Filename = tempFile
+	}
 	if req.Filename == "" {
 		s.sendWebSocketError(conn, "invalid_request", "No PDF filename provided")
 		return
@@ -231,36 +318,52 @@ func (s *Server) processWebSocketPDF(conn *websocket.Conn, req WebSocketOCRReque
 	reqConfig := s.extractWebSocketConfig(req.Options)
 
 	// Get pipeline for this request
-	pipeline, err := s.getPipelineForRequest(reqConfig)
+	pl, err := s.getPipelineForRequest(reqConfig)
 	if err != nil {
 		s.sendWebSocketError(conn, "processing_error", fmt.Sprintf("Failed to create pipeline: %v", err))
 		return
 	}
 
-	// Process PDF
+	// Process PDF page by page. Streaming clients get every page as soon as
+	// it is recognized and a completion message without pages.
+	var totalTextLength, totalRegions int
+	var pages []pipeline.OCRPDFPageResult
 	start := time.Now()
-	res, err := pipeline.ProcessPDF(req.Filename, req.Pages)
+	res, err := pl.ProcessPDFStream(ctx, req.Filename, req.Pages, func(page *pipeline.OCRPDFPageResult) error {
+		for _, img := range page.Images {
+			totalRegions += len(img.Regions)
+			for _, region := range img.Regions {
+				totalTextLength += len(region.Text)
+			}
+		}
+		if !req.Stream {
+			pages = append(pages, *page)
+			return nil
+		}
+		// A failed write means the client is gone, which stops the work.
+		return s.writeWebSocketResponse(conn, WebSocketOCRResponse{
+			Type:      "ocr_page",
+			Status:    "processing",
+			Result:    page,
+			RequestID: requestID,
+		})
+	})
 	duration := time.Since(start)
 
-	if err != nil {
+	if err != nil && (res == nil || !res.Truncated) {
 		ocrRequestsTotal.WithLabelValues("websocket_pdf", "error").Inc()
 		s.sendWebSocketError(conn, "processing_error", fmt.Sprintf("PDF OCR processing failed: %v", err))
 		return
 	}
+	res.Pages = pages
 
-	// Record metrics
-	ocrRequestsTotal.WithLabelValues("websocket_pdf", "success").Inc()
-	ocrProcessingDuration.WithLabelValues("websocket_pdf").Observe(duration.Seconds())
-
-	var totalTextLength, totalRegions int
-	for _, page := range res.Pages {
-		for _, img := range page.Images {
-			totalRegions += len(img.Regions)
-			for _, region := range img.Regions {
-				totalTextLength += len(region.Text)
-			}
-		}
+	// Record metrics; a truncated result completes the request as well
+	if res.Truncated {
+		ocrRequestsTotal.WithLabelValues("websocket_pdf", "truncated").Inc()
+	} else {
+		ocrRequestsTotal.WithLabelValues("websocket_pdf", "success").Inc()
 	}
+	ocrProcessingDuration.WithLabelValues("websocket_pdf").Observe(duration.Seconds())
 	ocrTextLength.WithLabelValues("websocket_pdf").Observe(float64(totalTextLength))
 	ocrRegionsDetected.WithLabelValues("websocket_pdf").Observe(float64(totalRegions))
 
@@ -276,18 +379,25 @@ func (s *Server) processWebSocketPDF(conn *websocket.Conn, req WebSocketOCRReque
 
 // sendWebSocketResponse sends a response message over WebSocket.
 func (s *Server) sendWebSocketResponse(conn WebSocketConnWriter, response WebSocketOCRResponse) {
+	if err := s.writeWebSocketResponse(conn, response); err != nil {
+		slog.Error("Failed to send WebSocket message", "error", err)
+	}
+}
+
+// writeWebSocketResponse sends a response message over WebSocket and
+// returns any marshaling or write error.
+func (s *Server) writeWebSocketResponse(conn WebSocketConnWriter, response WebSocketOCRResponse) error {
 	data, err := json.Marshal(response)
 	if err != nil {
-		slog.Error("Failed to marshal WebSocket response", "error", err)
-		return
+		return fmt.Errorf("failed to marshal WebSocket response: %w", err)
 	}
 
 	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
-		slog.Error("Failed to send WebSocket message", "error", err)
-		return
+		return err
 	}
 
 	websocketMessagesTotal.WithLabelValues("sent").Inc()
+	return nil
 }
 
 // extractWebSocketConfig extracts RequestConfig from WebSocket options.
@@ -300,6 +410,7 @@ func (s *Server) extractWebSocketConfig(options map[string]interface{}) *Request
 
 	// Extract string values
 	stringFields := map[string]*string{
+		"profile":   &config.Profile,
 		"language":  &config.Language,
 		"dict":      &config.DictPath,
 		"det-model": &config.DetModel,
@@ -335,13 +446,47 @@ func (s *Server) extractWebSocketDictLangs(options map[string]interface{}, confi
 	}
 }
 
+// sendWebSocketLimitError reports a rate limit or quota error over
+// WebSocket, with the time until the request may be sent again.
+func (s *Server) sendWebSocketLimitError(conn WebSocketConnWriter, err error) {
+	var retryAfter time.Duration
+	errorType := "rate_limit_exceeded"
+	var e *RateLimitError
+	var e1 *QuotaExceededError
+	switch {
+	case errors.As(err, &e):
+		retryAfter = e.RetryAfter
+	case errors.As(err, &e1):
+		errorType = "quota_exceeded"
+		retryAfter = time.Until(e1.Resets)
+	}
+	s.sendWebSocketRetryError(conn, errorType, err.Error(), retryAfter)
+}
+
+// sendWebSocketFetchError reports a failed fetch over WebSocket.
+func (s *Server) sendWebSocketFetchError(conn WebSocketConnWriter, err error) {
+	var quota *QuotaExceededError
+	if errors.As(err, &quota) {
+		s.sendWebSocketLimitError(conn, err)
+		return
+	}
+	s.sendWebSocketError(conn, "invalid_request", err.Error())
+}
+
 // sendWebSocketError sends an error message over WebSocket.
 func (s *Server) sendWebSocketError(conn WebSocketConnWriter, errorType, message string) {
+	s.sendWebSocketRetryError(conn, errorType, message, 0)
+}
+
+// sendWebSocketRetryError sends an error message over WebSocket with the
+// time after which the request may be retried (none if 0).
+func (s *Server) sendWebSocketRetryError(conn WebSocketConnWriter, errorType, message string, retryAfter time.Duration) {
 	response := WebSocketOCRResponse{
-		Type:      "error",
-		Status:    "error",
-		Error:     message,
-		ErrorType: errorType,
+		Type:       "error",
+		Status:     "error",
+		Error:      message,
+		ErrorType:  errorType,
+		RetryAfter: math.Ceil(max(retryAfter, 0).Seconds()),
 	}
 
 	data, err := json.Marshal(response)
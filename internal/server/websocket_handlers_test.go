@@ -1,10 +1,15 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/MeKo-Tech/pogo/internal/pipeline"
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -197,3 +202,93 @@ func TestWebSocketUpgrader(t *testing.T) {
 		assert.Equal(t, 1024, upgrader.WriteBufferSize)
 	})
 }
+
+// dialWebSocket connects to the /ws/ocr endpoint of mux with an API key.
+func dialWebSocket(t *testing.T, mux *http.ServeMux, apiKey string) *websocket.Conn {
+	t.Helper()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	header := http.Header{apiKeyHeader: {apiKey}}
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws/ocr", header)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// recognizeOverWebSocket sends an image request and returns the final
+// response, skipping progress messages.
+func recognizeOverWebSocket(t *testing.T, conn *websocket.Conn, imgData []byte) WebSocketOCRResponse {
+	t.Helper()
+	require.NoError(t, conn.WriteJSON(WebSocketOCRRequest{Type: "image", Image: imgData}))
+	for {
+		var resp WebSocketOCRResponse
+		require.NoError(t, conn.ReadJSON(&resp))
+		if resp.Status != "processing" {
+			return resp
+		}
+	}
+}
+
+func TestWebSocket_RateLimits(t *testing.T) {
+	keys, err := NewAPIKeys([]APIKey{
+		{Name: "img", Key: "img-secret", Scopes: []string{ScopeImage}, RequestsPerMinute: 3},
+		{Name: "small", Key: "small-secret", Scopes: []string{ScopeImage}, MaxDataPerDay: 1024},
+	})
+	require.NoError(t, err)
+	s := &Server{
+		pipeline:    &mockPipelineForTesting{processImageResult: &pipeline.OCRImageResult{Width: 20, Height: 20}},
+		maxUploadMB: 10,
+		apiKeys:     keys,
+		rateLimiter: NewRateLimiter(0, 0, 0, 0),
+	}
+	mux := http.NewServeMux()
+	s.SetupRoutes(mux)
+	imgData, err := encodeImageToPNG(createTestImage(20, 20))
+	require.NoError(t, err)
+
+	// The connection counts as a request, then every OCR message
+	conn := dialWebSocket(t, mux, "img-secret")
+	for range 2 {
+		assert.Equal(t, "completed", recognizeOverWebSocket(t, conn, imgData).Status)
+	}
+	resp := recognizeOverWebSocket(t, conn, imgData)
+	assert.Equal(t, "error", resp.Status)
+	assert.Equal(t, "rate_limit_exceeded", resp.ErrorType)
+	assert.Positive(t, resp.RetryAfter)
+
+	// Payload bytes count against the data quota
+	conn = dialWebSocket(t, mux, "small-secret")
+	resp = recognizeOverWebSocket(t, conn, make([]byte, 1024))
+	assert.Equal(t, "quota_exceeded", resp.ErrorType)
+	assert.Positive(t, resp.RetryAfter)
+}
+
+func TestWebSocket_Admission(t *testing.T) {
+	s := &Server{
+		pipeline:    &mockPipelineForTesting{processImageResult: &pipeline.OCRImageResult{Width: 20, Height: 20}},
+		maxUploadMB: 10,
+		admission:   newAdmissionController(AdmissionConfig{MaxConcurrent: 1, InteractiveMaxWait: time.Second}, nil),
+	}
+	mux := http.NewServeMux()
+	s.SetupRoutes(mux)
+	imgData, err := encodeImageToPNG(createTestImage(20, 20))
+	require.NoError(t, err)
+	conn := dialWebSocket(t, mux, "")
+
+	// With the only slot taken and no queue, the message is shed
+	release, err := s.admission.admit(context.Background(), LaneInteractive)
+	require.NoError(t, err)
+	resp := recognizeOverWebSocket(t, conn, imgData)
+	assert.Equal(t, "unavailable", resp.ErrorType)
+	assert.Equal(t, errQueueFull.Error(), resp.Error)
+	assert.InDelta(t, 1, resp.RetryAfter, 1e-9)
+
+	// Once it is free, the message takes the slot and releases it
+	release()
+	assert.Equal(t, "completed", recognizeOverWebSocket(t, conn, imgData).Status)
+	assert.Eventually(t, func() bool {
+		lanes, _ := s.admission.status()
+		return lanes[LaneInteractive] == LaneStatus{}
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,363 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultS3Region is the region used when none is configured.
+const DefaultS3Region = "us-east-1"
+
+const (
+	s3Algorithm   = "AWS4-HMAC-SHA256"
+	s3DateFormat  = "20060102T150405Z"
+	s3EmptyDigest = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+var errS3Request = errors.New("s3 request failed")
+
+// S3Config configures access to S3 or a compatible object store such as
+// MinIO.
+type S3Config struct {
+	// Endpoint is the base URL of an S3-compatible service; buckets are
+	// then addressed path-style. Empty means AWS, with virtual-hosted
+	// addressing.
+	Endpoint string
+	// Region signs requests (default DefaultS3Region).
+	Region string
+	// AccessKeyID and SecretAccessKey sign requests; without them requests
+	// are anonymous, which works for public buckets only.
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken accompanies temporary credentials.
+	SessionToken string
+}
+
+// S3ConfigFromEnv reads the S3 configuration from the environment variables
+// the AWS tools use: AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL, AWS_REGION or
+// AWS_DEFAULT_REGION, AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN.
+func S3ConfigFromEnv() S3Config {
+	firstEnv := func(names ...string) string {
+		for _, name := range names {
+			if v := os.Getenv(name); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	return S3Config{
+		Endpoint:        firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"),
+		Region:          firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// S3 is a bucket of S3-compatible object storage. Directories are key
+// prefixes ending in a slash. Objects are written with a single PUT, so
+// Create buffers them in memory.
+type S3 struct {
+	bucket string
+	config S3Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3 returns the storage for bucket.
+func NewS3(bucket string, config S3Config) *S3 {
+	if config.Region == "" {
+		config.Region = DefaultS3Region
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	return &S3{bucket: bucket, config: config, client: http.DefaultClient, now: time.Now}
+}
+
+// Open downloads the object key.
+func (s *S3) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, "")
+	if err != nil {
+		return nil, s.pathError("open", key, err)
+	}
+	return resp.Body, nil
+}
+
+// List returns the keys of the objects under the prefix dir, which is
+// treated as a directory. Without recursive, objects in "subdirectories"
+// (keys with a further slash) are left out.
+func (s *S3) List(ctx context.Context, dir string, recursive bool) ([]string, error) {
+	prefix := dir
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	if !recursive {
+		query.Set("delimiter", "/")
+	}
+
+	var keys []string
+	for {
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, "")
+		if err != nil {
+			return nil, s.pathError("list", dir, err)
+		}
+		var result struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, s.pathError("list", dir, fmt.Errorf("%w: invalid listing: %w", errS3Request, err))
+		}
+		for _, obj := range result.Contents {
+			// Keys ending in a slash are folder placeholders
+			if !strings.HasSuffix(obj.Key, "/") {
+				keys = append(keys, obj.Key)
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Create returns a writer that uploads the object key when closed.
+func (s *S3) Create(ctx context.Context, key string) (io.WriteCloser, error) {
+	if key == "" || strings.HasSuffix(key, "/") {
+		return nil, s.pathError("create", key, errors.New("not an object key"))
+	}
+	return &s3Writer{ctx: ctx, s3: s, key: key}, nil
+}
+
+// s3Writer collects an object and uploads it on Close.
+type s3Writer struct {
+	bytes.Buffer
+	ctx    context.Context
+	s3     *S3
+	key    string
+	closed bool
+}
+
+func (w *s3Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	contentType := mime.TypeByExtension(path.Ext(w.key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	resp, err := w.s3.do(w.ctx, http.MethodPut, w.key, nil, w.Bytes(), contentType)
+	if err != nil {
+		return w.s3.pathError("create", w.key, err)
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+// location returns the s3:// URL of key.
+func (s *S3) location(key string) string {
+	return S3Scheme + s.bucket + "/" + key
+}
+
+func (s *S3) pathError(op, key string, err error) error {
+	return &fs.PathError{Op: op, Path: s.location(key), Err: err}
+}
+
+// do sends a signed request for key, or for the bucket if key is empty. It
+// returns the response of a successful request and an error, wrapping
+// fs.ErrNotExist for missing objects and buckets, otherwise.
+func (s *S3) do(ctx context.Context, method, key string, query url.Values, body []byte,
+	contentType string,
+) (*http.Response, error) {
+	u, err := s.objectURL(key, query)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errS3Request, err)
+	}
+	if body == nil {
+		req.Body, req.GetBody, req.ContentLength = http.NoBody, nil, 0
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, payloadDigest(body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errS3Request, err)
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var apiErr struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	_ = xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&apiErr)
+	if apiErr.Code == "" {
+		apiErr.Code = resp.Status
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w (%s)", fs.ErrNotExist, apiErr.Code)
+	case apiErr.Message != "":
+		return nil, fmt.Errorf("%w: %s: %s", errS3Request, apiErr.Code, apiErr.Message)
+	default:
+		return nil, fmt.Errorf("%w: %s", errS3Request, apiErr.Code)
+	}
+}
+
+// objectURL returns the URL of key in the bucket, path-style for custom
+// endpoints and virtual-hosted for AWS. Its path is escaped the way
+// requests are signed.
+func (s *S3) objectURL(key string, query url.Values) (*url.URL, error) {
+	var base, p string
+	switch {
+	case s.config.Endpoint != "":
+		base, p = s.config.Endpoint, "/"+s.bucket+"/"+key
+	case strings.Contains(s.bucket, "."):
+		// Dotted bucket names do not match the wildcard certificate
+		base, p = "https://s3."+s.config.Region+".amazonaws.com", "/"+s.bucket+"/"+key
+	default:
+		base, p = "https://"+s.bucket+".s3."+s.config.Region+".amazonaws.com", "/"+key
+	}
+	u, err := url.Parse(base)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("%w: invalid endpoint %q", errS3Request, s.config.Endpoint)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + p
+	u.RawPath = s3Escape(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+	return u, nil
+}
+
+// sign adds AWS Signature Version 4 headers to req. Every header set on req
+// so far is signed, together with the host.
+func (s *S3) sign(req *http.Request, digest string) {
+	now := s.now().UTC()
+	req.Header.Set("X-Amz-Date", now.Format(s3DateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", digest)
+	if s.config.AccessKeyID == "" {
+		return
+	}
+	if s.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.config.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		digest,
+	}, "\n")
+	date := now.Format("20060102")
+	scope := date + "/" + s.config.Region + "/s3/aws4_request"
+	stringToSign := s3Algorithm + "\n" + now.Format(s3DateFormat) + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), date)
+	for _, part := range []string{s.config.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes query sorted by key, as signing requires.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, s3Escape(key, true)+"="+s3Escape(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes all but the unreserved characters, and slashes
+// unless escapeSlash is set.
+func s3Escape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func payloadDigest(body []byte) string {
+	if len(body) == 0 {
+		return s3EmptyDigest
+	}
+	return sha256Hex(body)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
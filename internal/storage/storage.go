@@ -0,0 +1,179 @@
+// Package storage reads and writes the inputs and outputs of OCR runs in the
+// local file system or an S3-compatible object store.
+//
+// Locations are local paths or URLs of the form s3://bucket/key. A location
+// ending in a slash, or naming only a bucket, is a directory.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// S3Scheme is the URL scheme of locations in S3-compatible storage.
+const S3Scheme = "s3://"
+
+// Storage is a place files are read from and written to. Names are paths
+// for Local and object keys for S3.
+type Storage interface {
+	// Open opens the named file for reading.
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	// List returns the names of the files in the directory dir, with those
+	// of subdirectories if recursive, in lexical order.
+	List(ctx context.Context, dir string, recursive bool) ([]string, error)
+	// Create creates or truncates the named file. The file is complete once
+	// Close returns without error.
+	Create(ctx context.Context, name string) (io.WriteCloser, error)
+}
+
+// IsRemote reports whether location refers to object storage rather than a
+// local path.
+func IsRemote(location string) bool {
+	return strings.HasPrefix(location, S3Scheme)
+}
+
+// IsDir reports whether a remote location names a directory. Local paths
+// need a stat for that.
+func IsDir(location string) bool {
+	if !IsRemote(location) {
+		return false
+	}
+	rest := strings.TrimPrefix(location, S3Scheme)
+	return !strings.Contains(rest, "/") || strings.HasSuffix(rest, "/")
+}
+
+// Resolve returns the storage location is in and the name of location
+// within it. S3 locations use the configuration from the environment (see
+// S3ConfigFromEnv).
+func Resolve(location string) (Storage, string, error) {
+	if !IsRemote(location) {
+		return Local{}, location, nil
+	}
+	bucket, key, err := parseS3Location(location)
+	if err != nil {
+		return nil, "", err
+	}
+	return NewS3(bucket, S3ConfigFromEnv()), key, nil
+}
+
+// Open opens the file at location for reading.
+func Open(ctx context.Context, location string) (io.ReadCloser, error) {
+	st, name, err := Resolve(location)
+	if err != nil {
+		return nil, err
+	}
+	return st.Open(ctx, name)
+}
+
+// Create creates the file at location for writing; see Storage.Create.
+func Create(ctx context.Context, location string) (io.WriteCloser, error) {
+	st, name, err := Resolve(location)
+	if err != nil {
+		return nil, err
+	}
+	return st.Create(ctx, name)
+}
+
+// List returns the locations of the files in the directory at location; see
+// Storage.List.
+func List(ctx context.Context, location string, recursive bool) ([]string, error) {
+	st, name, err := Resolve(location)
+	if err != nil {
+		return nil, err
+	}
+	names, err := st.List(ctx, name, recursive)
+	if err != nil {
+		return nil, err
+	}
+	if s3, ok := st.(*S3); ok {
+		for i, key := range names {
+			names[i] = s3.location(key)
+		}
+	}
+	return names, nil
+}
+
+// WriteFile writes data to the file at location.
+func WriteFile(ctx context.Context, location string, data []byte) error {
+	w, err := Create(ctx, location)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// Join joins a directory location and a file name.
+func Join(dir, name string) string {
+	if !IsRemote(dir) {
+		return filepath.Join(dir, name)
+	}
+	return strings.TrimSuffix(dir, "/") + "/" + name
+}
+
+// Base returns the last element of location.
+func Base(location string) string {
+	if !IsRemote(location) {
+		return filepath.Base(location)
+	}
+	location = strings.TrimSuffix(location, "/")
+	return location[strings.LastIndex(location, "/")+1:]
+}
+
+// parseS3Location splits s3://bucket/key into bucket and key.
+func parseS3Location(location string) (string, string, error) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(location, S3Scheme), "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("invalid storage location %q: missing bucket", location)
+	}
+	return bucket, key, nil
+}
+
+// Local is the local file system.
+type Local struct{}
+
+// Open opens the named file.
+func (Local) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(name) //nolint:gosec // G304: reading user-provided paths is expected
+}
+
+// List walks dir and returns the paths of its files.
+func (Local) List(ctx context.Context, dir string, recursive bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if !recursive && path != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Create creates the named file; its directory must exist.
+func (Local) Create(_ context.Context, name string) (io.WriteCloser, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600) //nolint:gosec // G304: user-provided output path
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
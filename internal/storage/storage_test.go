@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out", "result.txt")
+	require.Error(t, WriteFile(t.Context(), out, []byte("hello")), "directories are not created")
+	require.NoError(t, os.Mkdir(filepath.Dir(out), 0o750))
+	require.NoError(t, WriteFile(t.Context(), out, []byte("hello")))
+	data, err := os.ReadFile(out) //nolint:gosec // test file
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	require.NoError(t, WriteFile(t.Context(), filepath.Join(dir, "a.png"), nil))
+	files, err := List(t.Context(), dir, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "a.png")}, files)
+	files, err = List(t.Context(), dir, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "a.png"), out}, files)
+
+	r, err := Open(t.Context(), out)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+}
+
+func TestLocations(t *testing.T) {
+	assert.True(t, IsRemote("s3://bucket/key"))
+	assert.False(t, IsRemote("images/s3://x"))
+
+	assert.True(t, IsDir("s3://bucket"))
+	assert.True(t, IsDir("s3://bucket/prefix/"))
+	assert.False(t, IsDir("s3://bucket/prefix/a.png"))
+	assert.False(t, IsDir("/tmp/"))
+
+	assert.Equal(t, "s3://bucket/out/a.png", Join("s3://bucket/out/", "a.png"))
+	assert.Equal(t, "s3://bucket/a.png", Join("s3://bucket", "a.png"))
+	assert.Equal(t, filepath.Join("out", "a.png"), Join("out", "a.png"))
+	assert.Equal(t, "a.png", Base("s3://bucket/in/a.png"))
+	assert.Equal(t, "a.png", Base(filepath.Join("in", "a.png")))
+
+	st, name, err := Resolve("s3://bucket/in/a.png")
+	require.NoError(t, err)
+	assert.IsType(t, &S3{}, st)
+	assert.Equal(t, "in/a.png", name)
+	_, _, err = Resolve("s3:///key")
+	require.Error(t, err)
+}
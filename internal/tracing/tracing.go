@@ -0,0 +1,139 @@
+// Package tracing configures OpenTelemetry tracing: the OTLP exporter the
+// spans of the server and the OCR pipeline are sent to, and the W3C trace
+// context propagation of incoming requests.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Exporters.
+const (
+	ExporterNone     = "none"
+	ExporterOTLPGRPC = "otlp-grpc"
+	ExporterOTLPHTTP = "otlp-http"
+)
+
+// DefaultServiceName is the service name spans are reported under unless
+// OTEL_SERVICE_NAME says otherwise.
+const DefaultServiceName = "pogo"
+
+// Config selects where spans are exported to.
+type Config struct {
+	// Exporter is ExporterOTLPGRPC, ExporterOTLPHTTP, or ExporterNone (or
+	// empty) to disable tracing.
+	Exporter string
+	// Endpoint is the collector as host:port or URL. Empty uses the
+	// OTEL_EXPORTER_OTLP_* environment variables or the exporter default
+	// (localhost:4317 for gRPC, localhost:4318 for HTTP).
+	Endpoint string
+	// Insecure disables TLS for a host:port endpoint; URLs choose by scheme.
+	Insecure bool
+	// SampleRatio is the fraction of new traces sampled (0 or above 1 = all).
+	// Requests that carry trace context follow the caller's decision.
+	SampleRatio float64
+	// ServiceName defaults to DefaultServiceName.
+	ServiceName string
+}
+
+// Enabled reports whether c exports spans.
+func (c Config) Enabled() bool {
+	return c.Exporter != "" && c.Exporter != ExporterNone
+}
+
+// Setup installs the global propagator for W3C trace context and baggage
+// and, if cfg is enabled, a tracer provider exporting to the configured
+// collector. The returned function flushes pending spans and shuts the
+// provider down; it is a no-op if tracing is disabled.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+	if !cfg.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+	// Detectors later in the list take precedence, so OTEL_SERVICE_NAME and
+	// OTEL_RESOURCE_ATTRIBUTES override the default service name.
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil && !errors.Is(err, resource.ErrPartialResource) {
+		_ = exporter.Shutdown(ctx)
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	sampler := sdktrace.AlwaysSample()
+	if cfg.SampleRatio > 0 && cfg.SampleRatio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(cfg.SampleRatio)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// newExporter creates the OTLP exporter of cfg.
+func newExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	isURL := strings.Contains(cfg.Endpoint, "://")
+	switch cfg.Exporter {
+	case ExporterOTLPGRPC:
+		var opts []otlptracegrpc.Option
+		switch {
+		case isURL:
+			opts = append(opts, otlptracegrpc.WithEndpointURL(cfg.Endpoint))
+		case cfg.Endpoint != "":
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure && !isURL {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exp, err := otlptracegrpc.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP gRPC exporter: %w", err)
+		}
+		return exp, nil
+	case ExporterOTLPHTTP:
+		var opts []otlptracehttp.Option
+		switch {
+		case isURL:
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		case cfg.Endpoint != "":
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure && !isURL {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exp, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP HTTP exporter: %w", err)
+		}
+		return exp, nil
+	default:
+		return nil, fmt.Errorf("invalid trace exporter %q (want %s, %s or %s)",
+			cfg.Exporter, ExporterOTLPGRPC, ExporterOTLPHTTP, ExporterNone)
+	}
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// restoreGlobals puts back the global tracer provider and propagator that
+// Setup replaces.
+func restoreGlobals(t *testing.T) {
+	t.Helper()
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	})
+}
+
+func TestSetup_Disabled(t *testing.T) {
+	restoreGlobals(t)
+	tp := otel.GetTracerProvider()
+
+	for _, exporter := range []string{"", ExporterNone} {
+		shutdown, err := Setup(t.Context(), Config{Exporter: exporter})
+		require.NoError(t, err)
+		require.NoError(t, shutdown(t.Context()))
+		assert.Equal(t, tp, otel.GetTracerProvider(), "no provider is installed")
+	}
+
+	// Trace context is still propagated
+	carrier := propagation.MapCarrier{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	ctx := otel.GetTextMapPropagator().Extract(t.Context(), carrier)
+	out := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, out)
+	assert.Equal(t, carrier["traceparent"], out["traceparent"])
+}
+
+func TestSetup_InvalidExporter(t *testing.T) {
+	restoreGlobals(t)
+	_, err := Setup(t.Context(), Config{Exporter: "jaeger"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid trace exporter "jaeger"`)
+}
+
+func TestSetup_OTLPHTTP(t *testing.T) {
+	restoreGlobals(t)
+	var requests atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/v1/traces" {
+			requests.Add(1)
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer collector.Close()
+
+	shutdown, err := Setup(t.Context(), Config{Exporter: ExporterOTLPHTTP, Endpoint: collector.URL})
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "work")
+	span.End()
+	assert.True(t, span.SpanContext().IsSampled())
+
+	// Shutting down flushes the batch to the collector
+	require.NoError(t, shutdown(t.Context()))
+	assert.Equal(t, int32(1), requests.Load())
+}